@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"dynamiccontrol/internal/ingress"
+	"dynamiccontrol/internal/types"
+)
+
+func main() {
+	manifestPath := flag.String("manifest", "", "Path to Ingress or HTTPRoute manifests (JSON or YAML), e.g. from kubectl get -o yaml")
+	outPath := flag.String("out", "", "Write the generated routes to this file instead of stdout")
+	mergePath := flag.String("merge", "", "Existing routes file to merge the generated routes into")
+	namespace := flag.String("namespace", "default", "Namespace of resources that do not declare one")
+	clusterDomain := flag.String("cluster-domain", "cluster.local", "Cluster domain of backend service hostnames")
+	methods := flag.String("methods", "", "Comma-separated methods for paths that do not restrict them")
+	policies := flag.String("policies", "", "Comma-separated policies for every imported route")
+	flag.Parse()
+
+	if *manifestPath == "" {
+		log.Fatal("Missing required -manifest flag")
+	}
+
+	document, err := ioutil.ReadFile(*manifestPath)
+	if err != nil {
+		log.Fatalf("Failed to read manifests: %v", err)
+	}
+
+	options := ingress.Options{
+		Namespace:     *namespace,
+		ClusterDomain: *clusterDomain,
+	}
+	if *methods != "" {
+		options.Methods = strings.Split(strings.ToUpper(*methods), ",")
+	}
+	if *policies != "" {
+		options.DefaultPolicies = strings.Split(*policies, ",")
+	}
+
+	config, err := ingress.Import(document, options)
+	if err != nil {
+		log.Fatalf("Failed to import manifests: %v", err)
+	}
+
+	if *mergePath != "" {
+		existingBytes, err := ioutil.ReadFile(*mergePath)
+		if err != nil {
+			log.Fatalf("Failed to read routes file: %v", err)
+		}
+		var existing types.RoutesConfig
+		if err := json.Unmarshal(existingBytes, &existing); err != nil {
+			log.Fatalf("Failed to parse routes file: %v", err)
+		}
+		config = mergeRoutes(&existing, config)
+	}
+
+	output, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode routes: %v", err)
+	}
+
+	if *outPath == "" {
+		os.Stdout.Write(append(output, '\n'))
+		return
+	}
+	if err := ioutil.WriteFile(*outPath, output, 0644); err != nil {
+		log.Fatalf("Failed to write routes: %v", err)
+	}
+	log.Printf("Wrote %d routes to %s", len(config.Routes), *outPath)
+}
+
+// mergeRoutes replaces the routes previously imported from the same
+// resources and adds the imported routes that do not conflict with the
+// remaining hand-written ones
+func mergeRoutes(existing, imported *types.RoutesConfig) *types.RoutesConfig {
+	sources := make(map[string]bool)
+	for _, route := range imported.Routes {
+		sources[route.Labels[ingress.SourceLabel]] = true
+	}
+
+	var kept []types.RouteConfig
+	for _, route := range existing.Routes {
+		if source := route.Labels[ingress.SourceLabel]; source == "" || !sources[source] {
+			kept = append(kept, route)
+		}
+	}
+	return &types.RoutesConfig{Routes: ingress.Merge(kept, imported.Routes)}
+}
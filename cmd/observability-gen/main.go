@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"dynamiccontrol/internal/configstore"
+	"dynamiccontrol/internal/observability"
+
+	"sigs.k8s.io/yaml"
+)
+
+func main() {
+	routesPath := flag.String("routes", "config/routes.json", "Routes file to generate from")
+	primary := flag.String("primary", "", "Generate from the configuration of a running server at this address instead of -routes")
+	token := flag.String("token", os.Getenv("ADMIN_TOKEN"), "Admin token of the -primary server")
+	outDir := flag.String("out", "observability", "Directory to write dashboard.json and alerts.yaml to")
+	title := flag.String("title", observability.DefaultTitle, "Dashboard title")
+	datasource := flag.String("datasource", observability.DefaultDatasource, "Default Prometheus datasource of the dashboard")
+	denyRate := flag.Float64("deny-rate", observability.DefaultDenyRate, "Fraction of denied requests alerting on routes without slo.maxDenyRate")
+	watch := flag.Bool("watch", false, "Keep running and regenerate whenever the configuration changes")
+	flag.Parse()
+
+	var store configstore.ConfigStore = configstore.NewFileStore(*routesPath, "")
+	if *primary != "" {
+		store = configstore.NewPrimaryStore(*primary, *token, configstore.DefaultPrimaryInterval)
+	}
+	options := observability.Options{Title: *title, Datasource: *datasource, DenyRate: *denyRate}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := generate(ctx, store, options, *outDir); err != nil {
+		log.Fatal(err)
+	}
+	if !*watch {
+		return
+	}
+
+	log.Printf("Watching %s for configuration changes", store.Name())
+	err := store.Watch(ctx, func() {
+		if err := generate(ctx, store, options, *outDir); err != nil {
+			log.Print(err)
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Fatalf("Failed to watch configuration: %v", err)
+	}
+}
+
+// generate writes the dashboard and alert rules of the store's current
+// configuration to outDir
+func generate(ctx context.Context, store configstore.ConfigStore, options observability.Options, outDir string) error {
+	config, err := store.LoadRoutes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load routes: %w", err)
+	}
+
+	dashboard, err := json.MarshalIndent(observability.GenerateDashboard(config, options), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode dashboard: %w", err)
+	}
+	rules, err := yaml.Marshal(observability.GenerateAlertRules(config, options))
+	if err != nil {
+		return fmt.Errorf("failed to encode alert rules: %w", err)
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", outDir, err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "dashboard.json"), append(dashboard, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write dashboard: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "alerts.yaml"), rules, 0644); err != nil {
+		return fmt.Errorf("failed to write alert rules: %w", err)
+	}
+	log.Printf("Wrote dashboard and alert rules for %d routes to %s", len(config.Routes), outDir)
+	return nil
+}
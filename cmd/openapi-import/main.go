@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"dynamiccontrol/internal/openapi"
+	"dynamiccontrol/internal/types"
+)
+
+func main() {
+	specPath := flag.String("spec", "", "Path to the OpenAPI 3 document (JSON or YAML)")
+	outPath := flag.String("out", "", "Write the generated routes to this file instead of stdout")
+	mergePath := flag.String("merge", "", "Existing routes file to merge the generated routes into")
+	policies := flag.String("policies", "", "Comma-separated policies for operations without x-policies")
+	flag.Parse()
+
+	if *specPath == "" {
+		log.Fatal("Missing required -spec flag")
+	}
+
+	document, err := ioutil.ReadFile(*specPath)
+	if err != nil {
+		log.Fatalf("Failed to read OpenAPI document: %v", err)
+	}
+
+	options := openapi.Options{}
+	if *policies != "" {
+		options.DefaultPolicies = strings.Split(*policies, ",")
+	}
+
+	config, err := openapi.Import(document, options)
+	if err != nil {
+		log.Fatalf("Failed to import OpenAPI document: %v", err)
+	}
+
+	if *mergePath != "" {
+		existingBytes, err := ioutil.ReadFile(*mergePath)
+		if err != nil {
+			log.Fatalf("Failed to read routes file: %v", err)
+		}
+		var existing types.RoutesConfig
+		if err := json.Unmarshal(existingBytes, &existing); err != nil {
+			log.Fatalf("Failed to parse routes file: %v", err)
+		}
+		config = mergeRoutes(&existing, config)
+	}
+
+	output, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode routes: %v", err)
+	}
+
+	if *outPath == "" {
+		os.Stdout.Write(append(output, '\n'))
+		return
+	}
+	if err := ioutil.WriteFile(*outPath, output, 0644); err != nil {
+		log.Fatalf("Failed to write routes: %v", err)
+	}
+	log.Printf("Wrote %d routes to %s", len(config.Routes), *outPath)
+}
+
+// mergeRoutes replaces existing routes with imported routes of the same
+// method and path and appends the rest, keeping hand-written settings of
+// routes the document does not describe
+func mergeRoutes(existing, imported *types.RoutesConfig) *types.RoutesConfig {
+	index := make(map[string]int, len(existing.Routes))
+	for i, route := range existing.Routes {
+		index[route.Method+" "+route.RouteName] = i
+	}
+
+	merged := &types.RoutesConfig{Routes: append([]types.RouteConfig{}, existing.Routes...)}
+	for _, route := range imported.Routes {
+		if i, exists := index[route.Method+" "+route.RouteName]; exists {
+			merged.Routes[i] = route
+			continue
+		}
+		merged.Routes = append(merged.Routes, route)
+	}
+	return merged
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+)
+
+const help = `Enter a Rego query, such as data.orders.allow, or one of:
+  :input <json>   set the input of later queries (:input alone clears it)
+  :input @<file>  read the input from a JSON file
+  :load <file>    add a Rego module, replacing a loaded policy of the same name
+  :unload <name>  remove a module added with :load
+  :reset          clear the input and the added modules
+  :quit           leave the REPL
+`
+
+// session evaluates REPL queries either locally or on a running server
+type session struct {
+	policies map[string]string
+	data     map[string]interface{}
+	server   string
+	token    string
+	client   *http.Client
+
+	input   interface{}
+	modules map[string]string
+}
+
+func main() {
+	dir := flag.String("dir", "policies", "Policy bundle directory holding the policies and data documents")
+	server := flag.String("server", "", "Evaluate against the policies and data of a running server at this address instead of -dir")
+	token := flag.String("token", os.Getenv("ADMIN_TOKEN"), "Admin token of the -server")
+	inputPath := flag.String("input", "", "JSON file holding the input of the queries")
+	query := flag.String("query", "", "Evaluate a single query and exit instead of starting the REPL")
+	flag.Parse()
+
+	s := &session{
+		server:  strings.TrimSuffix(*server, "/"),
+		token:   *token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		modules: make(map[string]string),
+	}
+	if s.server == "" {
+		bundle, err := opa.ReadBundleDir(*dir, nil)
+		if err != nil {
+			log.Fatalf("Failed to load policies: %v", err)
+		}
+		s.policies, s.data = bundle.Policies, bundle.Data
+	}
+	if *inputPath != "" {
+		if err := s.setInput("@" + *inputPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *query != "" {
+		if !s.evaluate(*query) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Print(help)
+	scanner := bufio.NewScanner(os.Stdin)
+	for fmt.Print("> "); scanner.Scan(); fmt.Print("> ") {
+		line := strings.TrimSpace(scanner.Text())
+		command, argument, _ := strings.Cut(line, " ")
+		argument = strings.TrimSpace(argument)
+
+		switch command {
+		case "":
+		case ":quit", ":exit":
+			return
+		case ":help":
+			fmt.Print(help)
+		case ":input":
+			if err := s.setInput(argument); err != nil {
+				fmt.Println(err)
+			}
+		case ":load":
+			if err := s.load(argument); err != nil {
+				fmt.Println(err)
+			}
+		case ":unload":
+			delete(s.modules, strings.TrimSuffix(argument, ".rego"))
+		case ":reset":
+			s.input = nil
+			s.modules = make(map[string]string)
+		default:
+			s.evaluate(line)
+		}
+	}
+	fmt.Println()
+}
+
+// setInput sets the input from inline JSON or, prefixed with @, a JSON file
+func (s *session) setInput(argument string) error {
+	if argument == "" {
+		s.input = nil
+		return nil
+	}
+	content := []byte(argument)
+	if path, ok := strings.CutPrefix(argument, "@"); ok {
+		var err error
+		if content, err = os.ReadFile(path); err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+	}
+	var input interface{}
+	if err := json.Unmarshal(content, &input); err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+	s.input = input
+	return nil
+}
+
+// load adds a Rego module named after its file
+func (s *session) load(path string) error {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read module: %w", err)
+	}
+	s.modules[strings.TrimSuffix(filepath.Base(path), ".rego")] = string(source)
+	return nil
+}
+
+// evaluate prints the results of a query and reports whether it succeeded
+func (s *session) evaluate(query string) bool {
+	request := types.PolicyREPLRequest{Query: query, Modules: s.modules, Input: s.input}
+	var result *types.PolicyREPLResult
+	var err error
+	if s.server != "" {
+		result, err = s.evaluateRemote(request)
+	} else {
+		result, err = opa.EvaluateQuery(context.Background(), s.policies, s.data, request)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	fmt.Print(result.Output)
+	if !result.Defined {
+		fmt.Println("undefined")
+		return true
+	}
+	for _, row := range result.Results {
+		var value interface{} = row.Bindings
+		if len(row.Bindings) == 0 {
+			value = row.Expressions
+			if len(row.Expressions) == 1 {
+				value = row.Expressions[0]
+			}
+		}
+		output, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			fmt.Printf("failed to encode result: %v\n", err)
+			return false
+		}
+		fmt.Println(string(output))
+	}
+	return true
+}
+
+// evaluateRemote evaluates a query with the REPL endpoint of the server
+func (s *session) evaluateRemote(request types.PolicyREPLRequest) (*types.PolicyREPLResult, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.server+"/admin/policies/repl", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach server: %w", err)
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error   string `json:"error"`
+			Details string `json:"details"`
+		}
+		if json.Unmarshal(content, &failure) == nil && failure.Error != "" {
+			return nil, fmt.Errorf("%s: %s", failure.Error, failure.Details)
+		}
+		return nil, fmt.Errorf("server responded %d", resp.StatusCode)
+	}
+
+	var result types.PolicyREPLResult
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
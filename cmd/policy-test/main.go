@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+)
+
+func main() {
+	dir := flag.String("dir", "policies", "Directory holding the policies and their *_test.rego or *.rego.test files")
+	verbose := flag.Bool("v", false, "Report every test, not only failures")
+	jsonOutput := flag.Bool("json", false, "Print the report as JSON")
+	flag.Parse()
+
+	policies, tests, err := opa.LoadTestDir(*dir)
+	if err != nil {
+		log.Fatalf("Failed to load policies: %v", err)
+	}
+	if len(tests) == 0 {
+		log.Fatalf("No test files found in %s", *dir)
+	}
+
+	report, err := opa.RunTests(context.Background(), policies, tests)
+	if err != nil {
+		log.Fatalf("Failed to run policy tests: %v", err)
+	}
+
+	if *jsonOutput {
+		output, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+		os.Stdout.Write(append(output, '\n'))
+	} else {
+		for _, result := range report.Results {
+			if result.Status == types.PolicyTestPass && !*verbose {
+				continue
+			}
+			if result.Name == "" {
+				fmt.Printf("%-5s %s\n", result.Status, result.File)
+			} else {
+				fmt.Printf("%-5s %s.%s (%s:%d)\n", result.Status, result.Package, result.Name, result.File, result.Row)
+			}
+			if result.Error != "" {
+				fmt.Printf("      %s\n", result.Error)
+			}
+			if result.Output != "" {
+				fmt.Printf("      %s", result.Output)
+			}
+		}
+		fmt.Printf("PASS: %d  FAIL: %d  ERROR: %d  SKIP: %d\n", report.Passed, report.Failed, report.Errors, report.Skipped)
+	}
+
+	if report.Failed > 0 || report.Errors > 0 {
+		os.Exit(1)
+	}
+}
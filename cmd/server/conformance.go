@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"dynamiccontrol/internal/conformance"
+)
+
+// runConformance implements `dynamiccontrol conformance [flags]`, which runs
+// the black-box conformance suite against a deployment and exits non-zero
+// when a case fails
+func runConformance(args []string) {
+	flags := flag.NewFlagSet("conformance", flag.ExitOnError)
+	target := flags.String("target", "http://localhost:8080", "base URL of the data plane under test")
+	adminURL := flags.String("admin", "", "base URL serving /admin when it has a listener of its own (default: target)")
+	token := flags.String("token", os.Getenv("ADMIN_TOKEN"), "admin token (default $ADMIN_TOKEN)")
+	format := flags.String("format", conformance.FormatText, "report format: text, json or junit")
+	output := flags.String("output", "", "write the report to a file instead of stdout")
+	timeout := flags.Duration("timeout", conformance.DefaultTimeout, "timeout of each request")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: dynamiccontrol conformance [-target url] [-admin url] [-token token] [-format text|json|junit] [-output file]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *format != conformance.FormatText && *format != conformance.FormatJSON && *format != conformance.FormatJUnit {
+		fmt.Fprintf(os.Stderr, "Unsupported report format %q, expected text, json or junit\n", *format)
+		os.Exit(2)
+	}
+
+	report := conformance.Run(context.Background(), conformance.Config{
+		Target:  *target,
+		Admin:   *adminURL,
+		Token:   *token,
+		Timeout: *timeout,
+	})
+
+	if err := writeConformanceReport(report, *format, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+		os.Exit(2)
+	}
+	if *output != "" {
+		fmt.Printf("PASS: %d  FAIL: %d  SKIP: %d\n", report.Passed, report.Failed, report.Skipped)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// writeConformanceReport writes a report to a file, or to stdout without one
+func writeConformanceReport(report conformance.Report, format, output string) error {
+	if output == "" {
+		return conformance.Write(os.Stdout, report, format)
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := conformance.Write(file, report, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"dynamiccontrol/internal/scaffold"
+)
+
+// runInit implements `dynamiccontrol init [-force] [dir]`, which scaffolds a
+// runnable project with example routes, policies and a monitoring stack
+func runInit(args []string) {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	force := flags.Bool("force", false, "overwrite existing files")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: dynamiccontrol init [-force] [dir]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	dir := "."
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+
+	files, err := scaffold.Write(dir, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to scaffold project: %v\n", err)
+		os.Exit(1)
+	}
+	for _, file := range files {
+		fmt.Printf("created %s\n", file)
+	}
+	fmt.Printf(`
+Next steps:
+  cd %s
+  dynamiccontrol             # serve the example API on :8080
+  policy-test -dir policies  # run the Rego unit tests
+  docker compose up          # or run it with Prometheus and Grafana
+
+  curl localhost:8080/v1/status
+  curl -X POST localhost:8080/v1/orders -H 'X-Api-Key: demo-key' -d '{"item": "widget", "quantity": 2}'
+`, dir)
+}
@@ -1,76 +1,706 @@
 package main
 
 import (
-	"log"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"dynamiccontrol/internal/accounts"
+	"dynamiccontrol/internal/admin"
+	"dynamiccontrol/internal/audit"
+	"dynamiccontrol/internal/buildinfo"
+	"dynamiccontrol/internal/cache"
+	"dynamiccontrol/internal/chaos"
+	"dynamiccontrol/internal/configstore"
+	"dynamiccontrol/internal/decisions"
+	"dynamiccontrol/internal/events"
+	"dynamiccontrol/internal/graphqlapi"
+	"dynamiccontrol/internal/grpcapi"
+	"dynamiccontrol/internal/guardrails"
+	"dynamiccontrol/internal/health"
+	"dynamiccontrol/internal/identity"
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/memory"
 	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/ratelimit"
+	"dynamiccontrol/internal/reconcile"
+	"dynamiccontrol/internal/redis"
+	"dynamiccontrol/internal/registry"
+	"dynamiccontrol/internal/reqctx"
+	"dynamiccontrol/internal/responsecache"
+	"dynamiccontrol/internal/rollout"
 	"dynamiccontrol/internal/router"
+	"dynamiccontrol/internal/routestore"
+	"dynamiccontrol/internal/sideeffects"
+	"dynamiccontrol/internal/store"
+	"dynamiccontrol/internal/tracing"
+	"dynamiccontrol/internal/types"
 	"dynamiccontrol/internal/validator"
+	"dynamiccontrol/internal/versions"
+	"dynamiccontrol/internal/watchdog"
+	"dynamiccontrol/internal/xds"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "init" {
+		runInit(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "conformance" {
+		runConformance(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "store" {
+		runStore(os.Args[2:])
+		return
+	}
+
+	showVersion := flag.Bool("version", false, "print version information and exit")
+	flag.Parse()
+	build := buildinfo.Get()
+	if *showVersion {
+		fmt.Println(build)
+		return
+	}
+
 	// Set up logging
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	log.Println("Starting Dynamic Control Plane Server...")
+	logging.Setup(os.Stderr, os.Getenv("LOG_FORMAT"), os.Getenv("LOG_LEVEL"))
+	slog.Info("Starting Dynamic Control Plane Server", "version", build.Version, "commit", build.Commit)
 
 	// Initialize components
 	policyManager := opa.NewPolicyManager()
 	schemaValidator := validator.NewSchemaValidator()
 	routeManager := router.NewRouteManager(policyManager, schemaValidator)
+	emitter := events.NewWebhookEmitter(os.Getenv("WEBHOOK_URL"), os.Getenv("WEBHOOK_SECRET"))
+	routeManager.SetWebhookEmitter(emitter)
+	// Run request side effects, such as webhook deliveries and async
+	// aggregates, in bounded goroutines that are canceled on shutdown
+	sideEffectLimit, _ := strconv.Atoi(os.Getenv("SIDE_EFFECT_LIMIT"))
+	sideEffects := sideeffects.NewGroup(sideEffectLimit)
+	routeManager.SetSideEffects(sideEffects)
+	emitter.SetSideEffects(sideEffects)
 
-	// Load policies
-	policiesDir := "policies"
-	if err := policyManager.LoadPolicies(policiesDir); err != nil {
-		log.Printf("Warning: Failed to load policies: %v", err)
-	} else {
-		log.Printf("Loaded policies: %v", policyManager.ListLoadedPolicies())
+	// Keep control plane state in postgres when configured; the schema is
+	// migrated at startup unless STORE_MIGRATE is false
+	var database *store.Postgres
+	if url := os.Getenv("DATABASE_URL"); url != "" {
+		var err error
+		database, err = store.Open(context.Background(), url)
+		if err != nil {
+			fatal("Failed to connect to the database", err)
+		}
+		defer database.Close()
+		if os.Getenv("STORE_MIGRATE") != "false" {
+			if _, err := database.Migrate(context.Background()); err != nil {
+				fatal("Failed to migrate the database", err)
+			}
+		}
 	}
 
-	// Load route configuration
-	configPath := "config/routes.json"
-	if err := routeManager.LoadConfig(configPath); err != nil {
-		log.Fatalf("Failed to load route configuration: %v", err)
+	// Persist admin changes and configuration reloads outside the process
+	auditLog := routeManager.GetAuditLog()
+	if path := os.Getenv("AUDIT_LOG_FILE"); path != "" {
+		sink, err := audit.NewFileSink(path)
+		if err != nil {
+			fatal("Failed to open audit log", err)
+		}
+		defer sink.Close()
+		auditLog.AddSink(sink)
+		slog.Info("Audit log file enabled", "path", path)
+	}
+	if url := os.Getenv("AUDIT_LOG_URL"); url != "" {
+		auditLog.AddSink(audit.NewHTTPSink(url, os.Getenv("AUDIT_LOG_TOKEN")))
+		slog.Info("Audit log shipping enabled", "url", url)
+	}
+	if database != nil {
+		auditLog.AddSink(database.AuditSink())
+	}
+	defer auditLog.Close()
+	routeManager.SetLazy(os.Getenv("LAZY_ROUTES") == "true")
+	routeManager.SetTenantHeader(os.Getenv("TENANT_HEADER"))
+	routeManager.SetEnforcePolicySunset(os.Getenv("POLICY_SUNSET_ENFORCE") == "true")
+	// Keep more or fewer applied configurations available for rollbacks
+	if size, err := strconv.Atoi(os.Getenv("CONFIG_HISTORY_SIZE")); err == nil && size > 0 {
+		routeManager.SetHistory(versions.NewHistory(size))
+	}
+	// Serve configuration changes to a share of the traffic before all of it
+	if percent, err := strconv.ParseFloat(os.Getenv("CANARY_PERCENT"), 64); err == nil && percent > 0 {
+		canary := router.CanaryConfig{
+			Percent:     percent,
+			Header:      os.Getenv("CANARY_HEADER"),
+			HashHeader:  os.Getenv("CANARY_HASH_HEADER"),
+			AutoPromote: os.Getenv("CANARY_AUTO_PROMOTE") != "false",
+		}
+		canary.BakeTime, _ = time.ParseDuration(os.Getenv("CANARY_BAKE_TIME"))
+		canary.MaxErrorRate, _ = strconv.ParseFloat(os.Getenv("CANARY_MAX_ERROR_RATE"), 64)
+		canary.MinRequests, _ = strconv.ParseInt(os.Getenv("CANARY_MIN_REQUESTS"), 10, 64)
+		routeManager.SetCanary(canary)
+		slog.Info("Canary rollouts enabled", "percent", percent, "auto_promote", canary.AutoPromote)
+	}
+	// Activate and deactivate scheduled traffic rules as their windows open and close
+	trafficController := routeManager.GetTrafficController()
+	trafficController.Start()
+	defer trafficController.Stop()
+	// Reconcile accepted traffic intents against the observed traffic of services
+	if mode := os.Getenv("TRAFFIC_RECONCILE"); mode != "" {
+		reconcileConfig := reconcile.Config{Mode: mode}
+		reconcileConfig.Interval, _ = time.ParseDuration(os.Getenv("TRAFFIC_RECONCILE_INTERVAL"))
+		reconcileConfig.Tolerance, _ = strconv.ParseFloat(os.Getenv("TRAFFIC_RECONCILE_TOLERANCE"), 64)
+		if err := reconcile.ValidateConfig(reconcileConfig); err != nil {
+			fatal("Invalid traffic reconciliation settings", err)
+		}
+		reconciler := reconcile.New(reconcileConfig, routeManager.TrafficIntents)
+		routeManager.SetReconciler(reconciler)
+		reconciler.Start()
+		defer reconciler.Stop()
+		slog.Info("Traffic reconciliation enabled", "mode", reconciler.Config().Mode, "interval", reconciler.Config().Interval)
+	}
+	// Resolve upstream targets naming a service through the service registry
+	var services *registry.Registry
+	if os.Getenv("REGISTRY_ENABLED") == "true" {
+		registryConfig := registry.Config{}
+		registryConfig.DefaultTTL, _ = time.ParseDuration(os.Getenv("REGISTRY_DEFAULT_TTL"))
+		registryConfig.HealthInterval, _ = time.ParseDuration(os.Getenv("REGISTRY_HEALTH_INTERVAL"))
+		services = registry.New(registryConfig)
+		routeManager.SetRegistry(services)
+		services.Start()
+		defer services.Stop()
+		slog.Info("Service registry enabled")
+	}
+	if percent, err := strconv.ParseFloat(os.Getenv("SCHEMA_PROFILE_PERCENT"), 64); err == nil {
+		routeManager.SetSchemaProfiling(percent)
+	}
+	if percent, err := strconv.ParseFloat(os.Getenv("RESPONSE_SAMPLE_PERCENT"), 64); err == nil {
+		routeManager.SetResponseSampling(percent)
 	}
 
+	// Share cached responses between replicas through Redis
+	if redisURL := os.Getenv("RESPONSE_CACHE_REDIS_URL"); redisURL != "" {
+		client, err := redis.NewClient(redisURL)
+		if err != nil {
+			fatal("Failed to configure response cache", err)
+		}
+		routeManager.SetResponseCache(responsecache.NewRedisStore(client, os.Getenv("RESPONSE_CACHE_REDIS_PREFIX")))
+		routeManager.SetIdempotencyStore(responsecache.NewRedisStore(client, os.Getenv("RESPONSE_CACHE_REDIS_PREFIX")))
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL")); err == nil {
+		routeManager.SetIdempotencyTTL(ttl)
+	}
+	if maxBodyBytes, err := strconv.ParseInt(os.Getenv("MAX_BODY_BYTES"), 10, 64); err == nil {
+		routeManager.SetMaxBodyBytes(maxBodyBytes)
+	}
+
+	// Enforce rate limits across replicas through Redis
+	if redisURL := os.Getenv("RATE_LIMIT_REDIS_URL"); redisURL != "" {
+		client, err := redis.NewClient(redisURL)
+		if err != nil {
+			fatal("Failed to configure rate limiting", err)
+		}
+		routeManager.SetRateLimiter(ratelimit.NewRedisLimiter(client, os.Getenv("RATE_LIMIT_REDIS_PREFIX")))
+	}
+
+	// Combine the policies of a route, optionally evaluating all of them
+	// concurrently to report every denial reason
+	combination := opa.Combination{
+		Mode:           os.Getenv("POLICY_COMBINATION"),
+		CollectReasons: os.Getenv("POLICY_COLLECT_REASONS") == "true",
+	}
+	if err := policyManager.SetCombination(combination); err != nil {
+		fatal("Invalid POLICY_COMBINATION", err)
+	}
+
+	// Delegate policy decisions to an external OPA server, such as a sidecar
+	if address := os.Getenv("OPA_URL"); address != "" {
+		timeout, _ := time.ParseDuration(os.Getenv("OPA_TIMEOUT"))
+		remote := opa.NewRemoteEvaluator(address, os.Getenv("OPA_TOKEN"), timeout)
+		remote.SetCombination(combination)
+		routeManager.SetPolicyEvaluator(remote)
+		slog.Info("Evaluating policies with remote OPA", "address", address)
+	}
+
+	// Load external data documents policies can reference under data
+	if dir := os.Getenv("POLICY_DATA_DIR"); dir != "" {
+		if err := policyManager.LoadDataFiles(dir); err != nil {
+			fatal("Failed to load policy data", err)
+		}
+		slog.Info("Loaded policy data", "dir", dir, "documents", len(policyManager.Documents()))
+	}
+
+	// Resolve $ref and schema references against shared and registered schemas
+	if dir := os.Getenv("SCHEMA_DIR"); dir != "" {
+		if err := schemaValidator.SetSchemaDir(dir); err != nil {
+			fatal("Failed to set schema directory", err)
+		}
+		slog.Info("Using schema directory", "dir", dir)
+	} else if err := schemaValidator.SetSchemaDir("schemas"); err == nil {
+		slog.Info("Using schema directory", "dir", "schemas")
+	}
+
+	// Assert the caller's identity to upstreams with a signed header
+	if key := os.Getenv("IDENTITY_ASSERTION_KEY"); key != "" {
+		options := identity.Options{
+			Issuer: os.Getenv("IDENTITY_ASSERTION_ISSUER"),
+			Header: os.Getenv("IDENTITY_ASSERTION_HEADER"),
+		}
+		if ttl, err := time.ParseDuration(os.Getenv("IDENTITY_ASSERTION_TTL")); err == nil {
+			options.TTL = ttl
+		}
+		signer, err := identity.NewSigner([]byte(key), options)
+		if err != nil {
+			fatal("Failed to enable identity assertions", err)
+		}
+		routeManager.SetIdentitySigner(signer)
+	}
+
+	// Enforce the memory budget across caches
+	memoryConfig := memory.Config{}
+	if budgetMB, err := strconv.ParseInt(os.Getenv("MEMORY_BUDGET_MB"), 10, 64); err == nil {
+		memoryConfig.BudgetBytes = budgetMB * 1024 * 1024
+	}
+	if gcPercent, err := strconv.Atoi(os.Getenv("GC_PERCENT")); err == nil {
+		memoryConfig.GCPercent = gcPercent
+	}
+	memoryManager := memory.NewManager(memoryConfig)
+	memoryManager.Register(routeManager.GetOperations())
+	memoryManager.Register(routeManager.GetDecisions())
+	memoryManager.Register(routeManager.GetSchemaValidator())
+	memoryManager.Register(routeManager.GetSchemaLearners())
+	if cache, ok := routeManager.GetResponseCache().(memory.Consumer); ok {
+		memoryManager.Register(cache)
+	}
+	memoryManager.Start()
+	defer memoryManager.Stop()
+
+	// Watch goroutines, file descriptors and pending queues for leaks
+	watchdogConfig := watchdog.Config{}
+	if maxGoroutines, err := strconv.Atoi(os.Getenv("WATCHDOG_MAX_GOROUTINES")); err == nil {
+		watchdogConfig.MaxGoroutines = maxGoroutines
+	}
+	if maxFDs, err := strconv.Atoi(os.Getenv("WATCHDOG_MAX_FDS")); err == nil {
+		watchdogConfig.MaxOpenFDs = maxFDs
+	}
+	if maxQueueDepth, err := strconv.Atoi(os.Getenv("WATCHDOG_MAX_QUEUE_DEPTH")); err == nil {
+		watchdogConfig.MaxQueueDepth = maxQueueDepth
+	}
+	resourceWatchdog := watchdog.New(watchdogConfig)
+	resourceWatchdog.RegisterQueue("operations", routeManager.GetOperations().Pending)
+	resourceWatchdog.RegisterQueue("webhooks", emitter.Pending)
+	resourceWatchdog.RegisterQueue("lazy_compile", routeManager.PendingCompilations)
+	// Ship policy decisions to an external sink when configured
+	if sinkURL := os.Getenv("DECISION_LOG_URL"); sinkURL != "" {
+		spoolDir := os.Getenv("DECISION_LOG_DIR")
+		if spoolDir == "" {
+			spoolDir = "data/decisions"
+		}
+		shipper, err := decisions.NewShipper(sinkURL, spoolDir)
+		if err != nil {
+			fatal("Failed to start decision log shipping", err)
+		}
+		routeManager.GetDecisions().AddSink(shipper)
+		resourceWatchdog.RegisterQueue("decision_log", shipper.Pending)
+		shipper.Start()
+		defer shipper.Stop()
+	}
+	if database != nil {
+		decisionWriter := database.NewDecisionWriter()
+		routeManager.GetDecisions().AddSink(decisionWriter)
+		resourceWatchdog.RegisterQueue("decision_store", decisionWriter.Pending)
+		decisionWriter.Start()
+		defer decisionWriter.Stop()
+	}
+	resourceWatchdog.Start()
+	defer resourceWatchdog.Stop()
+
+	// Persist parsed policy modules to disk when configured
+	if cacheDir := os.Getenv("CACHE_DIR"); cacheDir != "" {
+		diskCache, err := cache.NewDiskCache(cacheDir)
+		if err != nil {
+			slog.Warn("Failed to enable cache", "error", err)
+		} else {
+			policyManager.SetCache(diskCache)
+		}
+	}
+
+	// Select the configuration backend
+	configStore, err := newConfigStore(database)
+	if err != nil {
+		fatal("Failed to create configuration store", err)
+	}
+
+	// Replicas in a rollout ring report their ring and traffic to the primary
+	if primary, ok := configStore.(*configstore.PrimaryStore); ok {
+		if ring, err := strconv.Atoi(os.Getenv("ROLLOUT_RING")); err == nil {
+			hostname, _ := os.Hostname()
+			primary.SetRollout(envOr("REPLICA_ID", hostname), ring, routeManager.TrafficSinceApply)
+			slog.Info("Joined rollout ring", "ring", ring)
+		}
+	}
+
+	// Load policies from an OPA bundle instead of the store's own policies
+	if source := os.Getenv("OPA_BUNDLE"); source != "" {
+		var verification *opa.BundleVerification
+		if key := os.Getenv("OPA_BUNDLE_VERIFICATION_KEY"); key != "" {
+			verification = &opa.BundleVerification{
+				Key:       key,
+				KeyID:     os.Getenv("OPA_BUNDLE_VERIFICATION_KEY_ID"),
+				Algorithm: os.Getenv("OPA_BUNDLE_VERIFICATION_ALG"),
+			}
+		}
+		interval, _ := time.ParseDuration(os.Getenv("OPA_BUNDLE_POLL_INTERVAL"))
+		configStore = configstore.NewBundleStore(configStore, source, os.Getenv("OPA_BUNDLE_TOKEN"), verification, interval)
+		slog.Info("Loading policies from OPA bundle", "bundle", source, "verified", verification != nil)
+	}
+
+	// Allow operators to rehearse control plane failures outside production
+	var chaosInjector *chaos.Injector
+	if os.Getenv("CHAOS_ENABLED") == "true" {
+		if os.Getenv("ENVIRONMENT") == "production" {
+			fatal("CHAOS_ENABLED must not be set in production", nil)
+		}
+		chaosInjector = chaos.NewInjector()
+		routeManager.SetChaos(chaosInjector)
+		configStore = chaos.WrapStore(configStore, chaosInjector)
+		slog.Warn("Chaos fault injection enabled")
+	}
+
+	// Limit the blast radius of configuration changes
+	guardrailConfig := guardrails.Config{}
+	if maxRoutes, err := strconv.Atoi(os.Getenv("GUARDRAIL_MAX_ROUTE_CHANGES")); err == nil {
+		guardrailConfig.MaxRouteChanges = maxRoutes
+	}
+	if maxPolicies, err := strconv.Atoi(os.Getenv("GUARDRAIL_MAX_POLICY_CHANGES")); err == nil {
+		guardrailConfig.MaxPolicyChanges = maxPolicies
+	}
+	if cooldown, err := time.ParseDuration(os.Getenv("GUARDRAIL_WEIGHT_COOLDOWN")); err == nil {
+		guardrailConfig.WeightCooldown = cooldown
+	}
+	// Replicas mirror their primary, which already enforced the guardrails
+	replica := os.Getenv("REPLICA_MODE") == "true"
+	if !replica {
+		routeManager.SetGuardrails(guardrails.New(guardrailConfig))
+	}
+
+	// Load policies and route configuration
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Routes created through the admin API are persisted and overlaid on the
+	// configuration, so they survive restarts
+	if location := os.Getenv("RUNTIME_ROUTES_STORE"); location != "" {
+		routeStore, err := routestore.Open(location)
+		if err != nil {
+			fatal("Failed to open runtime route store", err)
+		}
+		defer routeStore.Close()
+		if err := routeManager.SetRouteStore(ctx, routeStore); err != nil {
+			fatal("Failed to load runtime routes", err)
+		}
+	}
+	if err := routeManager.LoadFromStore(ctx, configStore); err != nil {
+		fatal("Failed to load configuration", err)
+	}
+	slog.Info("Loaded policies", "policies", policyManager.ListLoadedPolicies())
+
 	// Set up Gin router
 	gin.SetMode(gin.ReleaseMode)
-	router := gin.Default()
+	router := gin.New()
+
+	// Export traces when an OTLP endpoint is configured; trace context and
+	// baggage are propagated either way
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+		shutdown, err := tracing.Setup(ctx, envOr("OTEL_SERVICE_NAME", "dynamiccontrol"), build.Version)
+		if err != nil {
+			fatal("Failed to set up tracing", err)
+		}
+		defer shutdown(context.Background())
+		slog.Info("Trace export enabled")
+	}
 
 	// Add middleware
-	router.Use(gin.Logger())
+	router.Use(logging.Middleware())
 	router.Use(gin.Recovery())
+	router.Use(tracing.Middleware())
+	router.Use(reqctx.Middleware())
 
-	// Add health check endpoint
-	router.GET("/health", func(c *gin.Context) {
+	// Add health check endpoints. Liveness only reports that the process is
+	// serving; readiness checks the dependencies needed to serve traffic.
+	liveness := func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status":  "healthy",
 			"service": "dynamic-control-plane",
 		})
+	}
+	router.GET("/health", liveness)
+	router.GET("/health/live", liveness)
+
+	readiness := health.NewChecker(0)
+	readiness.Register("policies", true, routeManager.CheckPolicies)
+	readiness.Register("configStore", true, func(ctx context.Context) error {
+		_, err := configStore.LoadRoutes(ctx)
+		return err
+	})
+	readiness.Register("upstreams", os.Getenv("READINESS_REQUIRE_UPSTREAMS") == "true", routeManager.CheckUpstreams)
+	router.GET("/health/ready", func(c *gin.Context) {
+		report := readiness.Run(c.Request.Context())
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
 	})
 
+	// Serve admin endpoints and metrics on a dedicated listener when configured,
+	// so they are not exposed on the public data-plane port
+	adminRouter := router
+	adminPort := os.Getenv("ADMIN_PORT")
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	// Issue scoped tokens to machine clients alongside the admin token
+	var serviceAccounts *accounts.Store
+	if adminToken != "" {
+		serviceAccounts, err = accounts.NewStore(os.Getenv("SERVICE_ACCOUNTS_FILE"))
+		if err != nil {
+			fatal("Failed to load service accounts", err)
+		}
+	}
+	if adminPort != "" {
+		// The dedicated listener has its own authentication, so it never
+		// serves admin endpoints to anonymous callers
+		if adminToken == "" {
+			fatal("ADMIN_PORT requires ADMIN_TOKEN", nil)
+		}
+		adminRouter = gin.New()
+		adminRouter.Use(logging.Middleware())
+		adminRouter.Use(gin.Recovery())
+		adminRouter.Use(admin.Authenticate(adminToken, serviceAccounts))
+	}
+
+	// Add metrics endpoint
+	adminRouter.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// Publish the route table to Envoy when running as an xDS control plane
+	if xdsPort := os.Getenv("XDS_PORT"); xdsPort != "" {
+		xdsServer, err := newXDSServer(xdsPort)
+		if err != nil {
+			fatal("Failed to start xDS server", err)
+		}
+		routeManager.OnApply(func(config *types.RoutesConfig) {
+			if err := xdsServer.Update(config); err != nil {
+				slog.Error("Failed to publish xDS snapshot", "error", err)
+			}
+		})
+		defer xdsServer.Stop()
+	}
+
 	// Register dynamic routes
 	if err := routeManager.RegisterRoutes(router); err != nil {
-		log.Fatalf("Failed to register routes: %v", err)
+		fatal("Failed to register routes", err)
+	}
+	defer routeManager.Stop()
+
+	// Apply configuration changes without a restart
+	go routeManager.WatchStore(ctx, configStore)
+
+	// Add operation status endpoint for async and saga routes
+	router.GET("/v1/operations/:operationId", func(c *gin.Context) {
+		operation, exists := routeManager.GetOperations().Get(c.Param("operationId"))
+		if !exists {
+			c.JSON(404, gin.H{
+				"error": "Operation not found",
+			})
+			return
+		}
+		c.JSON(200, operation)
+	})
+
+	// Stream route changes, policy reloads and denials to dashboards. Events
+	// carry denial reasons and route changes of every tenant, so the stream
+	// is served next to the admin API, behind the admin token, and service
+	// accounts need the events:read scope.
+	if adminToken != "" {
+		eventsGroup := adminRouter.Group("/v1/events")
+		if adminPort == "" {
+			eventsGroup.Use(admin.Authenticate(adminToken, serviceAccounts))
+		}
+		eventsGroup.Use(admin.RequireScope("events:read"))
+		eventsGroup.GET("", events.StreamHandler(routeManager.GetEventBroker()))
+	}
+
+	// Let backend services register themselves for discovery
+	if services != nil {
+		registry.RegisterRoutes(router, services, os.Getenv("REGISTRY_TOKEN"))
+	}
+
+	// Register admin endpoints
+	adminHandler := admin.NewHandler()
+	adminHandler.SetReadOnly(replica)
+	adminHandler.SetWatchdog(resourceWatchdog)
+	adminHandler.SetRouteManager(routeManager)
+	adminHandler.SetPolicyManager(policyManager)
+	if chaosInjector != nil {
+		adminHandler.SetChaos(chaosInjector)
+	}
+	// Stage new revisions across the rings of replicas syncing from this primary
+	if rings, err := strconv.Atoi(os.Getenv("ROLLOUT_RINGS")); err == nil && rings > 1 && !replica {
+		rolloutConfig := rollout.Config{
+			Rings:       rings,
+			AutoPromote: os.Getenv("ROLLOUT_AUTO_PROMOTE") != "false",
+		}
+		rolloutConfig.BakeTime, _ = time.ParseDuration(os.Getenv("ROLLOUT_BAKE_TIME"))
+		rolloutConfig.MaxErrorRate, _ = strconv.ParseFloat(os.Getenv("ROLLOUT_MAX_ERROR_RATE"), 64)
+		rolloutConfig.MinRequests, _ = strconv.ParseInt(os.Getenv("ROLLOUT_MIN_REQUESTS"), 10, 64)
+		adminHandler.SetRollout(rollout.New(rolloutConfig))
+		slog.Info("Rollout rings enabled", "rings", rings, "auto_promote", rolloutConfig.AutoPromote)
+	}
+	if serviceAccounts != nil {
+		adminHandler.SetServiceAccounts(serviceAccounts)
+	}
+	// The admin API changes policies and routes, so it is never served
+	// without authentication
+	if adminToken != "" {
+		adminGroup := adminRouter.Group("/admin")
+		if adminPort == "" {
+			adminGroup.Use(admin.Authenticate(adminToken, serviceAccounts))
+		}
+		// Profiles and goroutine dumps expose internals, so they are only
+		// served on the dedicated admin listener
+		if adminPort != "" && os.Getenv("DIAGNOSTICS_ENABLED") != "false" {
+			adminHandler.SetDiagnostics(true)
+			slog.Info("Runtime diagnostics enabled", "port", adminPort)
+		}
+		adminHandler.Register(adminGroup)
+	} else {
+		slog.Warn("Admin API disabled, set ADMIN_TOKEN to enable it")
+	}
+
+	// Query routes, policies, traffic rules and GET routes in one round trip.
+	// The endpoint exposes control plane state of every tenant, so it is
+	// served next to the admin API, always behind the admin token, and
+	// service accounts need the graphql:read scope.
+	if os.Getenv("GRAPHQL_ENABLED") == "true" {
+		if adminToken == "" {
+			fatal("GRAPHQL_ENABLED requires ADMIN_TOKEN", nil)
+		}
+		gateway := graphqlapi.NewHandler(router, policyManager, routeManager.GetTrafficController())
+		if err := gateway.Update(routeManager.GetConfig()); err != nil {
+			fatal("Failed to configure GraphQL", err)
+		}
+		routeManager.OnApply(func(config *types.RoutesConfig) {
+			if err := gateway.Update(config); err != nil {
+				slog.Error("Failed to regenerate GraphQL schema", "error", err)
+			}
+		})
+		graphqlGroup := adminRouter.Group("/graphql")
+		if adminPort == "" {
+			graphqlGroup.Use(admin.Authenticate(adminToken, serviceAccounts))
+		}
+		graphqlGroup.Use(admin.RequireScope("graphql:read"))
+		gateway.Register(graphqlGroup)
+		slog.Info("GraphQL endpoint enabled")
 	}
 
 	// Add info endpoint
 	router.GET("/info", func(c *gin.Context) {
 		config := routeManager.GetConfig()
 		policies := policyManager.ListLoadedPolicies()
+		configHash, _ := adminHandler.ConfigHash()
 
 		c.JSON(200, gin.H{
-			"service":  "Dynamic Control Plane",
-			"version":  "1.0.0",
-			"routes":   len(config.Routes),
-			"policies": policies,
+			"service":    "Dynamic Control Plane",
+			"role":       role(replica),
+			"version":    build.Version,
+			"build":      build,
+			"configHash": configHash,
+			"routes":     len(config.Routes),
+			"policies":   policies,
+			"upstreams":  routeManager.GetUpstreamStatus(),
 			"endpoints": []string{
 				"GET /health - Health check",
+				"GET /health/live - Liveness probe",
+				"GET /health/ready - Readiness probe with individual dependency checks",
 				"GET /info - Service information",
+				"GET /metrics - Prometheus metrics",
 				"GET /v1/status - Service status",
 				"POST /v1/services/:serviceId/traffic - Traffic management",
+				"GET /v1/services/:serviceId/traffic - Traffic rules of a service",
+				"DELETE /v1/services/:serviceId/traffic - Delete the traffic rules of a service",
+				"GET /v1/services/:serviceId/traffic/:ruleId - Traffic rule",
+				"DELETE /v1/services/:serviceId/traffic/:ruleId - Delete a traffic rule",
+				"GET /v1/operations/:operationId - Operation status",
+				"GET /v1/events - Event stream (WebSocket or SSE)",
+				"POST /graphql - GraphQL queries over routes, policies and traffic rules (when GRAPHQL_ENABLED=true)",
+				"POST /v1/services - Register a service instance (when REGISTRY_ENABLED=true)",
+				"GET /v1/services - Registered services and instances",
+				"GET /v1/services/:serviceId - Registered instances of a service",
+				"PUT /v1/services/:serviceId/instances/:instanceId - Renew a registration",
+				"DELETE /v1/services/:serviceId/instances/:instanceId - Deregister an instance",
+				"POST /admin/transform/playground - Mapping template playground",
+				"GET /admin/watchdog - Resource watchdog snapshot",
+				"GET /admin/debug/runtime - Goroutine, memory and GC statistics (admin listener only)",
+				"GET /admin/debug/goroutines - Goroutine dump (admin listener only)",
+				"GET /admin/debug/pprof/ - pprof profiles (admin listener only)",
+				"GET /admin/routes - List routes",
+				"GET /admin/tenants - List tenants",
+				"GET /admin/policies - List policies",
+				"PUT /admin/policies/:name - Upload a policy (?dryRun=true to replay recorded traffic)",
+				"POST /admin/policies/test - Run Rego unit tests",
+				"POST /admin/policies/evaluate - Evaluate policies against an input",
+				"POST /admin/policies/repl - Evaluate a Rego query or snippet against the loaded policies and data",
+				"GET /admin/policies/deprecated - Deprecated policies and the routes still using them",
+				"GET /admin/policies/contract - JSON Schemas of the policy input and result (?route=METHOD%20/path)",
+				"GET /admin/data - Data documents available to policies",
+				"PUT /admin/data/*path - Set a data document",
+				"DELETE /admin/data/*path - Remove a data document",
+				"POST /admin/schemas/profile - Profile schema validation by keyword",
+				"GET /admin/schemas - List registered schemas and their versions",
+				"GET /admin/schemas/:name/:version - Get a registered schema version",
+				"PUT /admin/schemas/:name/:version - Register a new schema version",
+				"GET /admin/schema-learning - List routes in schema learning mode",
+				"GET /admin/schema-learning/schema?route= - Get the request schema inferred for a route",
+				"DELETE /admin/schema-learning?route= - Discard the samples of a route",
+				"GET /admin/dependencies - List dependency outages and affected routes",
+				"PUT /admin/dependencies - Mark a dependency down or up",
+				"GET /admin/decisions - List policy decisions",
+				"GET /admin/audit - List configuration changes",
+				"GET /admin/openapi - OpenAPI document of the route table",
+				"GET /admin/chaos - Active chaos faults",
+				"GET /admin/guardrails - Change limits, cooldowns and break-glass state",
+				"GET /admin/snapshot - Active configuration for read replicas",
+				"GET /admin/config/versions - History of applied route and policy configurations",
+				"GET /admin/config/versions/:version - Routes and policies of a configuration version",
+				"POST /admin/config/rollback/:version - Restore the routes and policies of a configuration version",
+				"POST /admin/config/diff - Review the changes a candidate configuration would make before applying it",
+				"GET /admin/rollout - Progress of the configuration rollout across replica rings",
+				"POST /admin/rollout/promote - Promote the candidate revision to the next ring",
+				"POST /admin/rollout/abort - Return every ring to the stable revision",
+				"GET /admin/canary - Progress of the canary rollout of the latest configuration change",
+				"POST /admin/canary/promote - Apply the canary configuration to all traffic",
+				"POST /admin/canary/abort - Return all traffic to the active configuration",
+				"PUT /admin/routes - Create or replace a route at runtime",
+				"DELETE /admin/routes - Delete a route created at runtime",
+				"PUT /admin/weights - Change the upstream weights of a route",
+				"GET /admin/variables - Template variables of routes, without secret values",
+				"PUT /admin/variables - Replace the template variables of a route",
+				"GET /admin/traffic/reconcile - Accepted traffic intents against observed traffic per service",
+				"POST /admin/traffic/reconcile - Reconcile traffic intents immediately",
+				"GET /admin/quotas - Quotas of services with their usage",
+				"GET /admin/quotas/:service - Quota of a service with its usage",
+				"PUT /admin/quotas/:service - Set the quota of a service",
+				"DELETE /admin/quotas/:service - Remove the quota of a service",
+				"GET /admin/service-accounts - List service accounts",
+				"POST /admin/service-accounts - Issue a scoped service account token",
+				"POST /admin/service-accounts/:name/rotate - Rotate a service account token",
+				"DELETE /admin/service-accounts/:name - Revoke a service account",
 			},
 		})
 	})
@@ -81,10 +711,184 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
+	// Serve the gRPC API when configured. It is a data-plane listener, so it
+	// only reaches admin collections when they are served on the data plane.
+	if grpcPort := os.Getenv("GRPC_PORT"); grpcPort != "" {
+		listener, err := net.Listen("tcp", ":"+grpcPort)
+		if err != nil {
+			fatal("Failed to listen for gRPC", err)
+		}
+		grpcServer := grpcapi.NewServer(router)
+		go func() {
+			slog.Info("gRPC server starting", "port", grpcPort)
+			if err := grpcServer.Serve(listener); err != nil {
+				slog.Error("gRPC server stopped", "error", err)
+			}
+		}()
+		defer grpcServer.Stop()
+	}
+
+	var adminServer *http.Server
+	if adminPort != "" {
+		adminServer = &http.Server{Addr: ":" + adminPort, Handler: adminRouter.Handler()}
+		go func() {
+			slog.Info("Admin server starting", "port", adminPort)
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("Failed to start admin server", err)
+			}
+		}()
+	}
+
+	slog.Info("Server starting", "port", port)
 
 	// Start server
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{Addr: ":" + port, Handler: router.Handler()}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Failed to start server", err)
+		}
+	}()
+
+	// Drain requests and their side effects on SIGINT or SIGTERM
+	signals, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-signals.Done()
+	shutdownTimeout := 10 * time.Second
+	if timeout, err := time.ParseDuration(os.Getenv("SHUTDOWN_TIMEOUT")); err == nil {
+		shutdownTimeout = timeout
+	}
+	slog.Info("Shutting down", "timeout", shutdownTimeout)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to drain requests", "error", err)
+	}
+	if adminServer != nil {
+		if err := adminServer.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Failed to drain admin requests", "error", err)
+		}
+	}
+	if err := sideEffects.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Canceled side effects on shutdown", "error", err)
+	}
+}
+
+// fatal logs an unrecoverable startup error and exits
+func fatal(message string, err error) {
+	if err != nil {
+		slog.Error(message, "error", err)
+	} else {
+		slog.Error(message)
+	}
+	os.Exit(1)
+}
+
+// role describes whether the instance accepts configuration changes
+func role(replica bool) string {
+	if replica {
+		return "replica"
+	}
+	return "primary"
+}
+
+// newXDSServer starts the xDS server on the given port. Envoy is pointed
+// back at this server's HTTP port for routes the control plane executes.
+func newXDSServer(port string) (*xds.Server, error) {
+	options := xds.Options{
+		ControlPlaneAddress: os.Getenv("XDS_CONTROL_PLANE_ADDRESS"),
+		IdentityAssertions:  os.Getenv("IDENTITY_ASSERTION_KEY") != "",
+	}
+	if options.ControlPlaneAddress == "" {
+		httpPort := os.Getenv("PORT")
+		if httpPort == "" {
+			httpPort = "8080"
+		}
+		options.ControlPlaneAddress = "127.0.0.1:" + httpPort
+	}
+	if listenPort, err := strconv.ParseUint(os.Getenv("XDS_LISTENER_PORT"), 10, 32); err == nil {
+		options.ListenPort = uint32(listenPort)
+	}
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for xDS: %w", err)
+	}
+	server := xds.NewServer(options)
+	go func() {
+		slog.Info("xDS server starting", "port", port)
+		if err := server.Serve(listener); err != nil {
+			slog.Error("xDS server stopped", "error", err)
+		}
+	}()
+	return server, nil
+}
+
+// envOr returns the value of an environment variable, or fallback when unset
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// defaultRoutesFile returns config/routes.json, or config/routes.yaml when
+// only the YAML file exists, as in projects created by `dynamiccontrol init`
+func defaultRoutesFile() string {
+	if _, err := os.Stat("config/routes.json"); os.IsNotExist(err) {
+		if _, err := os.Stat("config/routes.yaml"); err == nil {
+			return "config/routes.yaml"
+		}
+	}
+	return "config/routes.json"
+}
+
+// newConfigStore creates the configuration store selected by CONFIG_BACKEND.
+// When unset, the backend is inferred from CONTROLLER_MODE, ETCD_ENDPOINTS and
+// PRIMARY_URL and defaults to the local files. The postgres backend reads the
+// database opened from DATABASE_URL.
+func newConfigStore(database *store.Postgres) (configstore.ConfigStore, error) {
+	backend := os.Getenv("CONFIG_BACKEND")
+	if backend == "" {
+		switch {
+		case os.Getenv("CONTROLLER_MODE") == "kubernetes":
+			backend = "kubernetes"
+		case os.Getenv("ETCD_ENDPOINTS") != "":
+			backend = "etcd"
+		case os.Getenv("PRIMARY_URL") != "":
+			backend = "primary"
+		default:
+			backend = "file"
+		}
+	}
+
+	switch backend {
+	case "file":
+		return configstore.NewFileStore(envOr("ROUTES_FILE", defaultRoutesFile()), envOr("POLICIES_DIR", "policies")), nil
+	case "etcd":
+		return configstore.NewEtcdStore(strings.Split(os.Getenv("ETCD_ENDPOINTS"), ","), os.Getenv("ETCD_PREFIX")), nil
+	case "consul":
+		return configstore.NewConsulStore(os.Getenv("CONSUL_HTTP_ADDR"), os.Getenv("CONSUL_PREFIX"), os.Getenv("CONSUL_HTTP_TOKEN")), nil
+	case "postgres":
+		if database == nil {
+			return nil, fmt.Errorf("DATABASE_URL is required for the postgres backend")
+		}
+		return database, nil
+	case "primary":
+		if os.Getenv("PRIMARY_URL") == "" {
+			return nil, fmt.Errorf("PRIMARY_URL is required for the primary backend")
+		}
+		interval, _ := time.ParseDuration(os.Getenv("PRIMARY_SYNC_INTERVAL"))
+		return configstore.NewPrimaryStore(os.Getenv("PRIMARY_URL"), os.Getenv("PRIMARY_TOKEN"), interval), nil
+	case "kubernetes":
+		kubernetes, err := configstore.NewKubernetesStore(os.Getenv("KUBERNETES_API_SERVER"), os.Getenv("KUBERNETES_NAMESPACE"))
+		if err != nil {
+			return nil, err
+		}
+		if err := kubernetes.SetImports(strings.Split(os.Getenv("KUBERNETES_IMPORT"), ",")); err != nil {
+			return nil, err
+		}
+		return kubernetes, nil
+	default:
+		return nil, fmt.Errorf("unknown config backend %q", backend)
 	}
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"dynamiccontrol/internal/configstore"
+	"dynamiccontrol/internal/store"
+)
+
+// runStore implements `dynamiccontrol store migrate|import [flags]`, which
+// prepares the postgres database of the store-backed startup mode
+func runStore(args []string) {
+	usage := "Usage: dynamiccontrol store migrate [-database url]\n       dynamiccontrol store import [-database url] [-routes file] [-policies dir]"
+	if len(args) == 0 || (args[0] != "migrate" && args[0] != "import") {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command := args[0]
+
+	flags := flag.NewFlagSet("store "+command, flag.ExitOnError)
+	database := flags.String("database", os.Getenv("DATABASE_URL"), "postgres URL (default $DATABASE_URL)")
+	routesFile := flags.String("routes", envOr("ROUTES_FILE", defaultRoutesFile()), "routes file to import")
+	policiesDir := flags.String("policies", envOr("POLICIES_DIR", "policies"), "directory of the policies to import")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args[1:])
+	if *database == "" {
+		fmt.Fprintln(os.Stderr, "A database URL is required, with -database or DATABASE_URL")
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	postgres, err := store.Open(ctx, *database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	defer postgres.Close()
+
+	applied, err := postgres.Migrate(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	for _, name := range applied {
+		fmt.Printf("Applied migration %s\n", name)
+	}
+	if command == "migrate" {
+		if len(applied) == 0 {
+			fmt.Println("Database schema is up to date")
+		}
+		return
+	}
+
+	files := configstore.NewFileStore(*routesFile, *policiesDir)
+	config, err := files.LoadRoutes(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	policies, err := files.LoadPolicies(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if err := postgres.ImportConfig(ctx, config, policies); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Imported %d routes and %d policies\n", len(config.Routes), len(policies))
+}
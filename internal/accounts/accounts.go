@@ -0,0 +1,327 @@
+package accounts
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenPrefix starts every service account token, so leaked tokens are easy
+// to recognize
+const TokenPrefix = "dcsa_"
+
+// accountName matches service account names
+var accountName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Scope grants actions such as "policies:write" or "weights:write". An action
+// is a resource and a verb, either of which may be "*". RouteLabels limits
+// actions on individual routes to routes carrying all of the labels; a scope
+// with RouteLabels grants nothing beyond route actions.
+type Scope struct {
+	Actions     []string          `json:"actions"`
+	RouteLabels map[string]string `json:"routeLabels,omitempty"`
+}
+
+// Account is a machine principal of the admin API
+type Account struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Scopes      []Scope `json:"scopes"`
+	// Tenant confines the account to the routes, policies and schemas of
+	// one tenant
+	Tenant    string     `json:"tenant,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RotatedAt *time.Time `json:"rotatedAt,omitempty"`
+}
+
+// record is an account with the hashes of its tokens, as persisted
+type record struct {
+	Account
+	TokenHash         string    `json:"tokenHash"`
+	PreviousTokenHash string    `json:"previousTokenHash,omitempty"`
+	PreviousExpires   time.Time `json:"previousExpires,omitempty"`
+}
+
+// Store issues service accounts and authenticates their tokens. Only token
+// hashes are kept; a token is shown once, when it is issued.
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	accounts map[string]*record
+	now      func() time.Time
+}
+
+// NewStore creates a store persisting accounts to path, loading the accounts
+// already stored there. An empty path keeps accounts in memory only.
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:     path,
+		accounts: make(map[string]*record),
+		now:      time.Now,
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service accounts: %w", err)
+	}
+	var records []*record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse service accounts: %w", err)
+	}
+	for _, r := range records {
+		s.accounts[r.Name] = r
+	}
+	return s, nil
+}
+
+// Create issues a new service account with the name, description, scopes and
+// tenant of spec and returns its token
+func (s *Store) Create(spec Account) (Account, string, error) {
+	name := spec.Name
+	if !accountName.MatchString(name) {
+		return Account{}, "", fmt.Errorf("invalid service account name %q", name)
+	}
+	if err := ValidateScopes(spec.Scopes); err != nil {
+		return Account{}, "", err
+	}
+	token, hash, err := newToken()
+	if err != nil {
+		return Account{}, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.accounts[name]; exists {
+		return Account{}, "", fmt.Errorf("service account %s already exists", name)
+	}
+	r := &record{
+		Account: Account{
+			Name:        name,
+			Description: spec.Description,
+			Scopes:      spec.Scopes,
+			Tenant:      spec.Tenant,
+			CreatedAt:   s.now(),
+		},
+		TokenHash: hash,
+	}
+	s.accounts[name] = r
+	if err := s.save(); err != nil {
+		delete(s.accounts, name)
+		return Account{}, "", err
+	}
+	return r.Account, token, nil
+}
+
+// Rotate issues a new token for an account. The previous token stays valid
+// for grace, so running pipelines can pick up the new one.
+func (s *Store) Rotate(name string, grace time.Duration) (Account, string, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return Account{}, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, exists := s.accounts[name]
+	if !exists {
+		return Account{}, "", fmt.Errorf("service account %s not found", name)
+	}
+	previous := *r
+	now := s.now()
+	r.PreviousTokenHash, r.PreviousExpires = "", time.Time{}
+	if grace > 0 {
+		r.PreviousTokenHash, r.PreviousExpires = r.TokenHash, now.Add(grace)
+	}
+	r.TokenHash = hash
+	r.RotatedAt = &now
+	if err := s.save(); err != nil {
+		*r = previous
+		return Account{}, "", err
+	}
+	return r.Account, token, nil
+}
+
+// Delete removes an account, revoking its tokens
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, exists := s.accounts[name]
+	if !exists {
+		return fmt.Errorf("service account %s not found", name)
+	}
+	delete(s.accounts, name)
+	if err := s.save(); err != nil {
+		s.accounts[name] = r
+		return err
+	}
+	return nil
+}
+
+// List returns the accounts ordered by name
+func (s *Store) List() []Account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accounts := make([]Account, 0, len(s.accounts))
+	for _, r := range s.accounts {
+		accounts = append(accounts, r.Account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Name < accounts[j].Name })
+	return accounts
+}
+
+// Authenticate returns the account a token belongs to
+func (s *Store) Authenticate(token string) (Account, bool) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return Account{}, false
+	}
+	hash := hashToken(token)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.accounts {
+		if r.TokenHash == hash {
+			return r.Account, true
+		}
+		if r.PreviousTokenHash == hash && s.now().Before(r.PreviousExpires) {
+			return r.Account, true
+		}
+	}
+	return Account{}, false
+}
+
+// Allows reports whether the account may perform an action that does not
+// target an individual route
+func (a Account) Allows(action string) bool {
+	for _, scope := range a.Scopes {
+		if len(scope.RouteLabels) == 0 && scope.grants(action) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsRoute reports whether the account may perform an action on a route
+// with the given labels
+func (a Account) AllowsRoute(action string, labels map[string]string) bool {
+	for _, scope := range a.Scopes {
+		if scope.grants(action) && matchLabels(scope.RouteLabels, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsAny reports whether any scope grants the action, on some routes at least
+func (a Account) AllowsAny(action string) bool {
+	for _, scope := range a.Scopes {
+		if scope.grants(action) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateScopes checks that every scope grants well-formed actions
+func ValidateScopes(scopes []Scope) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("a service account requires at least one scope")
+	}
+	for i, scope := range scopes {
+		if len(scope.Actions) == 0 {
+			return fmt.Errorf("scope %d grants no actions", i)
+		}
+		for _, action := range scope.Actions {
+			resource, verb, ok := strings.Cut(action, ":")
+			if !ok || resource == "" || (verb != "read" && verb != "write" && verb != "*") {
+				return fmt.Errorf("scope %d: invalid action %q, expected <resource>:read, <resource>:write or <resource>:*", i, action)
+			}
+		}
+	}
+	return nil
+}
+
+// grants reports whether the scope includes an action
+func (scope Scope) grants(action string) bool {
+	resource, verb, _ := strings.Cut(action, ":")
+	for _, granted := range scope.Actions {
+		grantedResource, grantedVerb, _ := strings.Cut(granted, ":")
+		if (grantedResource == "*" || grantedResource == resource) && (grantedVerb == "*" || grantedVerb == verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLabels reports whether labels contain every required label
+func matchLabels(required, labels map[string]string) bool {
+	for key, value := range required {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// save writes the accounts to the store's file; callers must hold mu
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	records := make([]*record, 0, len(s.accounts))
+	for _, r := range s.accounts {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Name < records[j].Name })
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode service accounts: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create service account directory: %w", err)
+	}
+	temp := s.path + ".tmp"
+	if err := os.WriteFile(temp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write service accounts: %w", err)
+	}
+	if err := os.Rename(temp, s.path); err != nil {
+		return fmt.Errorf("failed to write service accounts: %w", err)
+	}
+	return nil
+}
+
+// newToken generates a token and its hash
+func newToken() (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := TokenPrefix + hex.EncodeToString(secret)
+	return token, hashToken(token), nil
+}
+
+// hashToken returns the hex SHA-256 of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
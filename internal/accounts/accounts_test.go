@@ -0,0 +1,96 @@
+package accounts
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScopes(t *testing.T) {
+	account := Account{Name: "ci", Scopes: []Scope{
+		{Actions: []string{"policies:*", "routes:read"}},
+		{Actions: []string{"weights:write"}, RouteLabels: map[string]string{"team": "payments"}},
+	}}
+
+	cases := []struct {
+		action string
+		want   bool
+	}{
+		{"policies:write", true},
+		{"policies:read", true},
+		{"routes:read", true},
+		{"routes:write", false},
+		{"weights:write", false},
+	}
+	for _, tc := range cases {
+		if got := account.Allows(tc.action); got != tc.want {
+			t.Errorf("Allows(%s) = %v, expected %v", tc.action, got, tc.want)
+		}
+	}
+	if !account.AllowsAny("weights:write") {
+		t.Error("Expected weights to be granted on some routes")
+	}
+	if !account.AllowsRoute("weights:write", map[string]string{"team": "payments", "tier": "1"}) {
+		t.Error("Expected weights to be granted on payments routes")
+	}
+	if account.AllowsRoute("weights:write", map[string]string{"team": "search"}) {
+		t.Error("Expected weights not to be granted on other routes")
+	}
+
+	if err := ValidateScopes([]Scope{{Actions: []string{"policies:delete"}}}); err == nil {
+		t.Error("Expected an unknown verb to be rejected")
+	}
+	if err := ValidateScopes(nil); err == nil {
+		t.Error("Expected an account without scopes to be rejected")
+	}
+}
+
+func TestStoreIssuesAndRotatesTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+
+	scopes := []Scope{{Actions: []string{"weights:write"}}}
+	_, token, err := store.Create(Account{Name: "deploy-bot", Scopes: scopes})
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	if _, _, err := store.Create(Account{Name: "deploy-bot", Scopes: scopes}); err == nil {
+		t.Error("Expected a duplicate account to be rejected")
+	}
+	if account, ok := store.Authenticate(token); !ok || account.Name != "deploy-bot" {
+		t.Fatalf("Expected the issued token to authenticate, got %v %v", account, ok)
+	}
+
+	_, rotated, err := store.Rotate("deploy-bot", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to rotate token: %v", err)
+	}
+	if _, ok := store.Authenticate(token); !ok {
+		t.Error("Expected the previous token to stay valid during the grace period")
+	}
+	now = now.Add(2 * time.Hour)
+	if _, ok := store.Authenticate(token); ok {
+		t.Error("Expected the previous token to expire after the grace period")
+	}
+
+	// Accounts survive a restart; only token hashes are stored
+	reloaded, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reload store: %v", err)
+	}
+	if _, ok := reloaded.Authenticate(rotated); !ok {
+		t.Error("Expected the rotated token to authenticate after a reload")
+	}
+
+	if err := reloaded.Delete("deploy-bot"); err != nil {
+		t.Fatalf("Failed to delete account: %v", err)
+	}
+	if _, ok := reloaded.Authenticate(rotated); ok {
+		t.Error("Expected deleted accounts to be revoked")
+	}
+}
@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dynamiccontrol/internal/audit"
+	"dynamiccontrol/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditKey is the context key of the audit entry of an admin write
+const auditKey = "admin.audit"
+
+// maxAuditedResponse bounds the error response kept to describe a failure
+const maxAuditedResponse = 4096
+
+// auditWriter keeps the start of the response to describe failed writes
+type auditWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write implements io.Writer
+func (w *auditWriter) Write(data []byte) (int, error) {
+	if remaining := maxAuditedResponse - w.body.Len(); remaining > 0 {
+		w.body.Write(data[:min(len(data), remaining)])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// auditWrites records every admin request that changes state in the audit
+// log with its actor and outcome, including requests that are rejected.
+// Handlers name the action and add details and a diff with recordAudit.
+func (h *Handler) auditWrites(group *gin.RouterGroup) gin.HandlerFunc {
+	prefix := strings.TrimSuffix(group.BasePath(), "/")
+	return func(c *gin.Context) {
+		path := strings.TrimPrefix(c.FullPath(), prefix)
+		if h.routeManager == nil || isReadOnlyRequest(c, path) {
+			c.Next()
+			return
+		}
+
+		entry := &types.AuditEntry{
+			Action:   c.Request.Method + " " + path,
+			Resource: strings.TrimPrefix(c.Request.URL.Path, prefix),
+		}
+		c.Set(auditKey, entry)
+		writer := &auditWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		entry.Actor = auditActor(c)
+		entry.Outcome = audit.OutcomeSuccess
+		if status := c.Writer.Status(); status >= http.StatusBadRequest {
+			entry.Outcome = audit.OutcomeFailure
+			entry.Status = status
+			entry.Error = responseError(writer.body.Bytes())
+		}
+		h.routeManager.GetAuditLog().Record(*entry)
+	}
+}
+
+// recordAudit names the action and resource of an admin change and adds
+// details and the changes made
+func (h *Handler) recordAudit(c *gin.Context, action, resource string, details map[string]interface{}, diff ...types.AuditChange) {
+	value, exists := c.Get(auditKey)
+	if !exists {
+		if h.routeManager != nil {
+			h.routeManager.GetAuditLog().Record(types.AuditEntry{
+				Actor: auditActor(c), Action: action, Resource: resource, Details: details, Diff: diff,
+			})
+		}
+		return
+	}
+	entry := value.(*types.AuditEntry)
+	entry.Action = action
+	entry.Resource = resource
+	entry.Details = details
+	entry.Diff = diff
+}
+
+// auditActor identifies the caller of an admin request
+func auditActor(c *gin.Context) string {
+	if account, ok := serviceAccount(c); ok {
+		return "service-account/" + account.Name
+	}
+	return "admin/" + c.ClientIP()
+}
+
+// responseError extracts the error of a JSON error response
+func responseError(body []byte) string {
+	var response struct {
+		Error   string      `json:"error"`
+		Details interface{} `json:"details"`
+	}
+	if json.Unmarshal(body, &response) != nil || response.Error == "" {
+		return strings.TrimSpace(string(body))
+	}
+	if response.Details != nil {
+		return fmt.Sprintf("%s: %v", response.Error, response.Details)
+	}
+	return response.Error
+}
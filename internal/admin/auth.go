@@ -0,0 +1,55 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"dynamiccontrol/internal/accounts"
+
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey is the context key of the service account making a request
+const principalKey = "admin.serviceAccount"
+
+// RequireToken rejects requests that do not carry the admin token as a
+// bearer token in the Authorization header
+func RequireToken(token string) gin.HandlerFunc {
+	return Authenticate(token, nil)
+}
+
+// Authenticate rejects requests that carry neither the admin token nor the
+// token of a service account as a bearer token. Requests of service accounts
+// are limited to the account's scopes.
+func Authenticate(token string, serviceAccounts *accounts.Store) gin.HandlerFunc {
+	expected := []byte(token)
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(provided), expected) == 1 {
+			c.Next()
+			return
+		}
+		if ok && serviceAccounts != nil {
+			if account, found := serviceAccounts.Authenticate(provided); found {
+				c.Set(principalKey, account)
+				c.Next()
+				return
+			}
+		}
+		c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Admin authentication required",
+		})
+	}
+}
+
+// serviceAccount returns the service account making a request, if any
+func serviceAccount(c *gin.Context) (accounts.Account, bool) {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return accounts.Account{}, false
+	}
+	account, ok := value.(accounts.Account)
+	return account, ok
+}
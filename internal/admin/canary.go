@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getCanary reports the canary rollout settings and progress
+func (h *Handler) getCanary(c *gin.Context) {
+	if !h.requireCanary(c) {
+		return
+	}
+	settings, _ := h.routeManager.CanarySettings()
+	c.JSON(http.StatusOK, gin.H{
+		"config": gin.H{
+			"percent":      settings.Percent,
+			"header":       settings.Header,
+			"hashHeader":   settings.HashHeader,
+			"bakeTime":     settings.BakeTime.String(),
+			"maxErrorRate": settings.MaxErrorRate,
+			"minRequests":  settings.MinRequests,
+			"autoPromote":  settings.AutoPromote,
+		},
+		"status": h.routeManager.CanaryStatus(),
+	})
+}
+
+// promoteCanary applies the canary configuration to all traffic
+func (h *Handler) promoteCanary(c *gin.Context) {
+	if !h.requireCanary(c) {
+		return
+	}
+	status := h.routeManager.CanaryStatus()
+	if err := h.routeManager.PromoteCanary(); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordAudit(c, "canary.promote", "routes", map[string]interface{}{
+		"routes":   status.Routes,
+		"requests": status.Requests,
+		"errors":   status.Errors,
+	})
+	c.JSON(http.StatusOK, h.routeManager.CanaryStatus())
+}
+
+// abortCanary returns all traffic to the active configuration
+func (h *Handler) abortCanary(c *gin.Context) {
+	if !h.requireCanary(c) {
+		return
+	}
+	var request abortRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid abort request",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	if request.Reason == "" {
+		request.Reason = "aborted by operator"
+	}
+
+	if err := h.routeManager.AbortCanary(request.Reason); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordAudit(c, "canary.abort", "routes", map[string]interface{}{
+		"reason": request.Reason,
+	})
+	c.JSON(http.StatusOK, h.routeManager.CanaryStatus())
+}
+
+// requireCanary writes an error response when canary rollouts are not enabled
+func (h *Handler) requireCanary(c *gin.Context) bool {
+	if !h.requireRouteManager(c) {
+		return false
+	}
+	if _, enabled := h.routeManager.CanarySettings(); !enabled {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Canary rollouts are not enabled",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"dynamiccontrol/internal/chaos"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChaosFaultRequest enables a control plane fault through the admin API
+type ChaosFaultRequest struct {
+	Percentage      float64 `json:"percentage"`
+	DelayMs         int     `json:"delayMs"`
+	DurationSeconds int     `json:"durationSeconds"`
+}
+
+// listChaosFaults returns the active control plane faults
+func (h *Handler) listChaosFaults(c *gin.Context) {
+	if !h.requireChaos(c) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"kinds":  chaos.Kinds,
+		"faults": h.chaos.Faults(),
+	})
+}
+
+// enableChaosFault activates a control plane fault
+func (h *Handler) enableChaosFault(c *gin.Context) {
+	if !h.requireChaos(c) {
+		return
+	}
+
+	var request ChaosFaultRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid JSON",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	fault, err := h.chaos.Enable(chaos.Fault{
+		Kind:       chaos.Kind(c.Param("kind")),
+		Percentage: request.Percentage,
+		DelayMs:    request.DelayMs,
+	}, time.Duration(request.DurationSeconds)*time.Second)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid fault",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.recordAudit(c, "chaos.enable", string(fault.Kind), map[string]interface{}{
+		"percentage": fault.Percentage,
+		"delayMs":    fault.DelayMs,
+		"expiresAt":  fault.ExpiresAt,
+	})
+	c.JSON(http.StatusOK, fault)
+}
+
+// disableChaosFault deactivates a control plane fault
+func (h *Handler) disableChaosFault(c *gin.Context) {
+	if !h.requireChaos(c) {
+		return
+	}
+
+	kind := c.Param("kind")
+	if !h.chaos.Disable(chaos.Kind(kind)) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Fault is not active",
+		})
+		return
+	}
+
+	h.recordAudit(c, "chaos.disable", kind, nil)
+	c.Status(http.StatusNoContent)
+}
+
+// requireChaos writes an error response when chaos injection is disabled
+func (h *Handler) requireChaos(c *gin.Context) bool {
+	if h.chaos == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Chaos injection is not enabled",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dynamiccontrol/internal/configstore"
+
+	"github.com/gin-gonic/gin"
+)
+
+// configReads are GET endpoints serving the active configuration; their
+// responses carry the configuration hash as ETag
+var configReads = map[string]bool{
+	"/routes":     true,
+	"/policies":   true,
+	"/data":       true,
+	"/data/*path": true,
+	"/openapi":    true,
+}
+
+// ConfigHash returns the stable hash of the active routes, policies and
+// policy data
+func (h *Handler) ConfigHash() (string, error) {
+	if h.routeManager == nil || h.policyManager == nil {
+		return "", fmt.Errorf("configuration is not available")
+	}
+	return configstore.ConfigHash(h.routeManager.GetConfig(), h.policyManager.PolicySources(), h.policyManager.Documents())
+}
+
+// conditionalRequests answers configuration reads whose If-None-Match names
+// the current configuration with 304, and rejects writes whose If-Match does
+// not with 412. Writes are serialized, so two clients holding the same ETag
+// cannot both apply a change.
+func (h *Handler) conditionalRequests(group *gin.RouterGroup) gin.HandlerFunc {
+	prefix := strings.TrimSuffix(group.BasePath(), "/")
+	return func(c *gin.Context) {
+		path := strings.TrimPrefix(c.FullPath(), prefix)
+		if isReadOnlyRequest(c, path) {
+			if configReads[path] {
+				if hash, err := h.ConfigHash(); err == nil {
+					etag := `"` + hash + `"`
+					c.Header("ETag", etag)
+					if matchesETag(c.GetHeader("If-None-Match"), etag) {
+						c.AbortWithStatus(http.StatusNotModified)
+						return
+					}
+				}
+			}
+			c.Next()
+			return
+		}
+
+		h.writeMu.Lock()
+		defer h.writeMu.Unlock()
+		if expected := c.GetHeader("If-Match"); expected != "" {
+			hash, err := h.ConfigHash()
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			etag := `"` + hash + `"`
+			if !matchesETag(expected, etag) {
+				c.Header("ETag", etag)
+				c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{
+					"error":   "Configuration changed",
+					"details": fmt.Sprintf("If-Match %s does not match the current configuration %s", expected, etag),
+				})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// matchesETag reports whether an If-Match or If-None-Match header names the
+// given ETag, comparing weak and strong tags alike
+func matchesETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
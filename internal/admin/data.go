@@ -0,0 +1,120 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"dynamiccontrol/internal/audit"
+	"dynamiccontrol/internal/opa"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listData returns the documents set through data files or the admin API
+// along with the complete data document policies are evaluated against
+func (h *Handler) listData(c *gin.Context) {
+	if !h.requirePolicyManager(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"documents": h.policyManager.Documents(),
+		"data":      h.policyManager.Data(),
+	})
+}
+
+// getDocument returns the data available to policies at the requested path
+func (h *Handler) getDocument(c *gin.Context) {
+	if !h.requirePolicyManager(c) {
+		return
+	}
+	path, ok := documentPath(c)
+	if !ok {
+		return
+	}
+
+	var current interface{} = h.policyManager.Data()
+	for _, segment := range strings.Split(path, "/") {
+		object, isObject := current.(map[string]interface{})
+		if current, ok = object[segment]; !isObject || !ok {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Document not found",
+				"details": "no data at data." + strings.ReplaceAll(path, "/", "."),
+			})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, current)
+}
+
+// setDocument installs the JSON request body as a data document. Policies
+// see it on their next evaluation, without being recompiled.
+func (h *Handler) setDocument(c *gin.Context) {
+	if !h.requirePolicyManager(c) {
+		return
+	}
+	path, ok := documentPath(c)
+	if !ok {
+		return
+	}
+
+	var value interface{}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPolicySize)
+	if err := c.ShouldBindJSON(&value); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid document",
+			"details": err.Error(),
+		})
+		return
+	}
+	previous := h.document(path)
+	if err := h.policyManager.SetDocument(path, value); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to set document",
+			"details": err.Error(),
+		})
+		return
+	}
+	h.recordAudit(c, "data.set", path, nil, audit.Diff("", previous, value)...)
+	c.JSON(http.StatusOK, value)
+}
+
+// deleteDocument removes a data document set through a data file or the admin API
+func (h *Handler) deleteDocument(c *gin.Context) {
+	if !h.requirePolicyManager(c) {
+		return
+	}
+	path, ok := documentPath(c)
+	if !ok {
+		return
+	}
+
+	previous := h.document(path)
+	if !h.policyManager.DeleteDocument(path) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Document not found",
+			"details": "only documents set through data files or the admin API can be deleted",
+		})
+		return
+	}
+	h.recordAudit(c, "data.delete", path, nil, audit.Diff("", previous, nil)...)
+	c.Status(http.StatusNoContent)
+}
+
+// documentPath returns the validated document path of the request,
+// responding with an error when it is invalid
+func documentPath(c *gin.Context) (string, bool) {
+	path, err := opa.CleanDocumentPath(c.Param("path"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid document path",
+			"details": err.Error(),
+		})
+		return "", false
+	}
+	return path, true
+}
+
+// document returns the data document at a cleaned path, or nil
+func (h *Handler) document(path string) interface{} {
+	return h.policyManager.Documents()[path]
+}
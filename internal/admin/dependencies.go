@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dependencyRequest marks a dependency down or up
+type dependencyRequest struct {
+	Name   string `json:"name" binding:"required"`
+	Down   bool   `json:"down"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// listDependencies returns the dependencies marked down and the dependency
+// availability of every route declaring dependencies
+func (h *Handler) listDependencies(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"outages": h.routeManager.GetDependencies().Outages(),
+		"routes":  h.routeManager.DependencyStates(),
+	})
+}
+
+// setDependency marks a service or route down, so routes depending on it
+// serve their fallback or 503, or marks it up again
+func (h *Handler) setDependency(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	var request dependencyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid dependency request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	registry := h.routeManager.GetDependencies()
+	if request.Down {
+		outage := registry.MarkDown(request.Name, request.Reason, "admin/"+c.ClientIP())
+		h.recordAudit(c, "dependency.down", request.Name, map[string]interface{}{
+			"reason": request.Reason,
+		})
+		c.JSON(http.StatusOK, outage)
+		return
+	}
+
+	if registry.MarkUp(request.Name) {
+		h.recordAudit(c, "dependency.up", request.Name, nil)
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,150 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	rpprof "runtime/pprof"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recentPauses is how many of the latest GC pauses the runtime endpoint reports
+const recentPauses = 10
+
+// startTime is when the process started serving, for the uptime of runtime stats
+var startTime = time.Now()
+
+// RuntimeStats describes the Go runtime of the process
+type RuntimeStats struct {
+	GoVersion  string  `json:"goVersion"`
+	GOMAXPROCS int     `json:"gomaxprocs"`
+	NumCPU     int     `json:"numCPU"`
+	Goroutines int     `json:"goroutines"`
+	Uptime     string  `json:"uptime"`
+	Memory     Memory  `json:"memory"`
+	GC         GCStats `json:"gc"`
+}
+
+// Memory summarizes the memory statistics of the runtime, in bytes
+type Memory struct {
+	HeapAlloc    uint64 `json:"heapAlloc"`
+	HeapInuse    uint64 `json:"heapInuse"`
+	HeapIdle     uint64 `json:"heapIdle"`
+	HeapReleased uint64 `json:"heapReleased"`
+	HeapObjects  uint64 `json:"heapObjects"`
+	StackInuse   uint64 `json:"stackInuse"`
+	Sys          uint64 `json:"sys"`
+	TotalAlloc   uint64 `json:"totalAlloc"`
+	Mallocs      uint64 `json:"mallocs"`
+	Frees        uint64 `json:"frees"`
+}
+
+// GCStats summarizes garbage collection
+type GCStats struct {
+	NumGC         int64     `json:"numGC"`
+	LastGC        time.Time `json:"lastGC"`
+	PauseTotal    string    `json:"pauseTotal"`
+	RecentPauses  []string  `json:"recentPauses"`
+	NextGC        uint64    `json:"nextGC"`
+	GCCPUFraction float64   `json:"gcCPUFraction"`
+	// GCPercent is the garbage collector target percentage
+	GCPercent int `json:"gcPercent"`
+	// MemoryLimit is the soft memory limit in bytes
+	MemoryLimit int64 `json:"memoryLimit"`
+}
+
+// SetDiagnostics exposes pprof profiles, runtime statistics and goroutine
+// dumps under /admin/debug. Only enable it on an authenticated listener.
+func (h *Handler) SetDiagnostics(enabled bool) {
+	h.diagnostics = enabled
+}
+
+// registerDiagnostics mounts the diagnostics endpoints
+func (h *Handler) registerDiagnostics(group *gin.RouterGroup) {
+	group.GET("/debug/runtime", h.getRuntimeStats)
+	group.GET("/debug/goroutines", h.dumpGoroutines)
+	group.GET("/debug/pprof/", gin.WrapF(pprof.Index))
+	group.GET("/debug/pprof/:profile", h.getProfile)
+	group.POST("/debug/pprof/symbol", gin.WrapF(pprof.Symbol))
+}
+
+// getRuntimeStats reports goroutines, memory and garbage collection statistics
+func (h *Handler) getRuntimeStats(c *gin.Context) {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+	var gc debug.GCStats
+	gc.Pause = make([]time.Duration, recentPauses)
+	debug.ReadGCStats(&gc)
+
+	pauses := make([]string, 0, len(gc.Pause))
+	for _, pause := range gc.Pause {
+		pauses = append(pauses, pause.String())
+	}
+	settings := []metrics.Sample{{Name: "/gc/gogc:percent"}, {Name: "/gc/gomemlimit:bytes"}}
+	metrics.Read(settings)
+
+	c.JSON(http.StatusOK, RuntimeStats{
+		GoVersion:  runtime.Version(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		Goroutines: runtime.NumGoroutine(),
+		Uptime:     time.Since(startTime).Round(time.Second).String(),
+		Memory: Memory{
+			HeapAlloc:    memory.HeapAlloc,
+			HeapInuse:    memory.HeapInuse,
+			HeapIdle:     memory.HeapIdle,
+			HeapReleased: memory.HeapReleased,
+			HeapObjects:  memory.HeapObjects,
+			StackInuse:   memory.StackInuse,
+			Sys:          memory.Sys,
+			TotalAlloc:   memory.TotalAlloc,
+			Mallocs:      memory.Mallocs,
+			Frees:        memory.Frees,
+		},
+		GC: GCStats{
+			NumGC:         gc.NumGC,
+			LastGC:        gc.LastGC,
+			PauseTotal:    gc.PauseTotal.String(),
+			RecentPauses:  pauses,
+			NextGC:        memory.NextGC,
+			GCCPUFraction: memory.GCCPUFraction,
+			GCPercent:     int(settings[0].Value.Uint64()),
+			MemoryLimit:   int64(settings[1].Value.Uint64()),
+		},
+	})
+}
+
+// dumpGoroutines writes the stack of every goroutine as text
+func (h *Handler) dumpGoroutines(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Status(http.StatusOK)
+	rpprof.Lookup("goroutine").WriteTo(c.Writer, 2)
+}
+
+// getProfile serves a pprof profile by name, such as profile (CPU), heap,
+// goroutine, mutex, block or trace
+func (h *Handler) getProfile(c *gin.Context) {
+	switch name := c.Param("profile"); name {
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		if rpprof.Lookup(name) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Profile not found",
+				"details": "see /admin/debug/pprof/ for the available profiles",
+			})
+			return
+		}
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,125 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"dynamiccontrol/internal/guardrails"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBreakGlassDuration bounds how long guardrails can be bypassed
+const maxBreakGlassDuration = 4 * time.Hour
+
+// breakGlassRequest opens a break-glass window
+type breakGlassRequest struct {
+	Reason          string `json:"reason" binding:"required"`
+	DurationSeconds int    `json:"durationSeconds" binding:"required"`
+}
+
+// getGuardrails returns the guardrail limits, active cooldowns and break-glass state
+func (h *Handler) getGuardrails(c *gin.Context) {
+	guard, ok := h.requireGuardrails(c)
+	if !ok {
+		return
+	}
+
+	cooldowns := make(map[string]string)
+	for route, remaining := range guard.Cooldowns() {
+		cooldowns[route] = remaining.Round(time.Second).String()
+	}
+	config := guard.Config()
+	response := gin.H{
+		"maxRouteChanges":  config.MaxRouteChanges,
+		"maxPolicyChanges": config.MaxPolicyChanges,
+		"weightCooldown":   config.WeightCooldown.String(),
+		"cooldowns":        cooldowns,
+	}
+	if breakGlass, open := guard.ActiveBreakGlass(); open {
+		response["breakGlass"] = breakGlass
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// openBreakGlass bypasses the guardrails for a limited time. The reason is
+// mandatory and recorded in the audit log.
+func (h *Handler) openBreakGlass(c *gin.Context) {
+	guard, ok := h.requireGuardrails(c)
+	if !ok {
+		return
+	}
+
+	var request breakGlassRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid break-glass request",
+			"details": err.Error(),
+		})
+		return
+	}
+	duration := time.Duration(request.DurationSeconds) * time.Second
+	if duration <= 0 || duration > maxBreakGlassDuration {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid break-glass request",
+			"details": "durationSeconds must be between 1 and 14400",
+		})
+		return
+	}
+
+	breakGlass := guard.OpenBreakGlass("admin/"+c.ClientIP(), request.Reason, duration)
+	h.recordAudit(c, "guardrails.break-glass.open", "guardrails", map[string]interface{}{
+		"reason":    request.Reason,
+		"expiresAt": breakGlass.ExpiresAt,
+	})
+	c.JSON(http.StatusOK, breakGlass)
+}
+
+// closeBreakGlass ends the break-glass window early
+func (h *Handler) closeBreakGlass(c *gin.Context) {
+	guard, ok := h.requireGuardrails(c)
+	if !ok {
+		return
+	}
+
+	if !guard.CloseBreakGlass() {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No break-glass window is open",
+		})
+		return
+	}
+	h.recordAudit(c, "guardrails.break-glass.close", "guardrails", nil)
+	c.Status(http.StatusNoContent)
+}
+
+// requireGuardrails writes an error response when guardrails are not configured
+func (h *Handler) requireGuardrails(c *gin.Context) (*guardrails.Guard, bool) {
+	if !h.requireRouteManager(c) {
+		return nil, false
+	}
+	guard := h.routeManager.GetGuardrails()
+	if guard == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Guardrails are not enabled",
+		})
+		return nil, false
+	}
+	return guard, true
+}
+
+// respondGuardrailError writes a change rejected by the guardrails as 409
+func respondGuardrailError(c *gin.Context, err error) {
+	var rejected *guardrails.Error
+	if errors.As(err, &rejected) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Change rejected by guardrails",
+			"details": rejected.Violations,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "Failed to check guardrails",
+		"details": err.Error(),
+	})
+}
@@ -0,0 +1,123 @@
+package admin
+
+import (
+	"sync"
+
+	"dynamiccontrol/internal/accounts"
+	"dynamiccontrol/internal/chaos"
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/rollout"
+	"dynamiccontrol/internal/router"
+	"dynamiccontrol/internal/watchdog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the administrative API
+type Handler struct {
+	watchdog      *watchdog.Watchdog
+	routeManager  *router.RouteManager
+	policyManager *opa.PolicyManager
+	chaos         *chaos.Injector
+	readOnly      bool
+	rollout       *rollout.Controller
+	accounts      *accounts.Store
+	diagnostics   bool
+	// writeMu serializes state-changing requests for If-Match checks
+	writeMu sync.Mutex
+}
+
+// NewHandler creates a new admin handler
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+// SetWatchdog exposes the resource watchdog through the admin API
+func (h *Handler) SetWatchdog(w *watchdog.Watchdog) {
+	h.watchdog = w
+}
+
+// SetRouteManager exposes routes, decisions and the audit log through the admin API
+func (h *Handler) SetRouteManager(routeManager *router.RouteManager) {
+	h.routeManager = routeManager
+}
+
+// SetPolicyManager exposes loaded policies through the admin API
+func (h *Handler) SetPolicyManager(policyManager *opa.PolicyManager) {
+	h.policyManager = policyManager
+}
+
+// SetChaos enables the chaos fault endpoints; leave unset in production
+func (h *Handler) SetChaos(injector *chaos.Injector) {
+	h.chaos = injector
+}
+
+// Register mounts the admin endpoints on the given router group
+func (h *Handler) Register(group *gin.RouterGroup) {
+	group.Use(h.auditWrites(group))
+	group.Use(h.rejectWrites(group))
+	group.Use(h.authorize(group))
+	group.Use(h.conditionalRequests(group))
+	group.GET("/snapshot", h.getSnapshot)
+	group.GET("/config/versions", h.listVersions)
+	group.GET("/config/versions/:version", h.getVersion)
+	group.POST("/config/rollback/:version", h.rollbackConfig)
+	group.POST("/config/diff", h.diffConfig)
+	group.POST("/transform/playground", h.transformPlayground)
+	group.GET("/watchdog", h.getWatchdog)
+	group.GET("/routes", h.listRoutes)
+	group.PUT("/routes", h.putRoute)
+	group.DELETE("/routes", h.deleteRoute)
+	group.GET("/tenants", h.listTenants)
+	group.GET("/policies", h.listPolicies)
+	group.PUT("/policies/:name", h.uploadPolicy)
+	group.POST("/policies/test", h.testPolicies)
+	group.POST("/policies/evaluate", h.evaluatePolicies)
+	group.POST("/policies/repl", h.evaluatePolicyREPL)
+	group.GET("/policies/contract", h.getPolicyContract)
+	group.GET("/policies/deprecated", h.listDeprecatedPolicies)
+	group.GET("/data", h.listData)
+	group.GET("/data/*path", h.getDocument)
+	group.PUT("/data/*path", h.setDocument)
+	group.DELETE("/data/*path", h.deleteDocument)
+	group.GET("/decisions", h.listDecisions)
+	group.GET("/audit", h.listAudit)
+	group.GET("/openapi", h.getOpenAPI)
+	group.POST("/schemas/profile", h.profileSchema)
+	group.GET("/schemas", h.listSchemas)
+	group.GET("/schemas/:name/:version", h.getSchema)
+	group.PUT("/schemas/:name/:version", h.registerSchema)
+	group.GET("/schema-learning", h.listLearnedSchemas)
+	group.GET("/schema-learning/schema", h.getLearnedSchema)
+	group.DELETE("/schema-learning", h.resetLearnedSchema)
+	group.GET("/chaos", h.listChaosFaults)
+	group.PUT("/chaos/:kind", h.enableChaosFault)
+	group.DELETE("/chaos/:kind", h.disableChaosFault)
+	group.GET("/dependencies", h.listDependencies)
+	group.PUT("/dependencies", h.setDependency)
+	group.GET("/guardrails", h.getGuardrails)
+	group.POST("/guardrails/break-glass", h.openBreakGlass)
+	group.DELETE("/guardrails/break-glass", h.closeBreakGlass)
+	group.GET("/rollout", h.getRollout)
+	group.POST("/rollout/promote", h.promoteRollout)
+	group.POST("/rollout/abort", h.abortRollout)
+	group.GET("/canary", h.getCanary)
+	group.POST("/canary/promote", h.promoteCanary)
+	group.POST("/canary/abort", h.abortCanary)
+	group.PUT("/weights", h.setWeights)
+	group.GET("/variables", h.listVariables)
+	group.PUT("/variables", h.setVariables)
+	group.GET("/traffic/reconcile", h.getReconcile)
+	group.POST("/traffic/reconcile", h.runReconcile)
+	group.GET("/quotas", h.listQuotas)
+	group.GET("/quotas/:service", h.getQuota)
+	group.PUT("/quotas/:service", h.setQuota)
+	group.DELETE("/quotas/:service", h.deleteQuota)
+	group.GET("/service-accounts", h.listServiceAccounts)
+	group.POST("/service-accounts", h.createServiceAccount)
+	group.POST("/service-accounts/:name/rotate", h.rotateServiceAccount)
+	group.DELETE("/service-accounts/:name", h.deleteServiceAccount)
+	if h.diagnostics {
+		h.registerDiagnostics(group)
+	}
+}
@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"net/http"
+	"sort"
+
+	"dynamiccontrol/internal/listquery"
+	"dynamiccontrol/internal/router"
+	"dynamiccontrol/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PolicySummary describes a loaded policy in admin listings
+type PolicySummary struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+// listRoutes lists the active route configuration, marking the routes
+// created at runtime
+func (h *Handler) listRoutes(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	routes := h.routeManager.GetConfig().Routes
+	if tenant := accountTenant(c); tenant != "" {
+		var owned []types.RouteConfig
+		for _, route := range routes {
+			if route.Tenant == tenant {
+				owned = append(owned, route)
+			}
+		}
+		routes = owned
+	}
+	items, err := listquery.ToItems(routes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i, item := range items {
+		item["id"] = router.RouteKey(routes[i])
+		item["runtime"] = h.routeManager.IsRuntimeRoute(router.RouteKey(routes[i]))
+	}
+	respondList(c, items, "id")
+}
+
+// listPolicies lists the loaded policies with their Rego source
+func (h *Handler) listPolicies(c *gin.Context) {
+	if !h.requirePolicyManager(c) {
+		return
+	}
+
+	sources := h.policyManager.PolicySources()
+	policies := make([]PolicySummary, 0, len(sources))
+	for name, source := range sources {
+		if h.visibleName(c, name) {
+			policies = append(policies, PolicySummary{Name: name, Source: source})
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+
+	items, err := listquery.ToItems(policies)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondList(c, items, "name")
+}
+
+// listDecisions lists recent policy decisions
+func (h *Handler) listDecisions(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	items, err := listquery.ToItems(h.routeManager.GetDecisions().List())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondList(c, items, "id")
+}
+
+// listAudit lists recent configuration changes
+func (h *Handler) listAudit(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	items, err := listquery.ToItems(h.routeManager.GetAuditLog().List())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondList(c, items, "id")
+}
+
+// requireRouteManager writes an error response when no route manager is configured
+func (h *Handler) requireRouteManager(c *gin.Context) bool {
+	if h.routeManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Route manager is not available",
+		})
+		return false
+	}
+	return true
+}
+
+// requirePolicyManager writes an error response when no policy manager is configured
+func (h *Handler) requirePolicyManager(c *gin.Context) bool {
+	if h.policyManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Policy manager is not available",
+		})
+		return false
+	}
+	return true
+}
+
+// respondList applies the request's list query to items and writes the page
+func respondList(c *gin.Context, items []map[string]interface{}, idField string) {
+	query, err := listquery.Parse(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid list query",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	page, err := listquery.Apply(items, idField, query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid list query",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,38 @@
+package admin
+
+import (
+	"net/http"
+
+	"dynamiccontrol/internal/buildinfo"
+	"dynamiccontrol/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+	"sigs.k8s.io/yaml"
+)
+
+// getOpenAPI renders the live route table as an OpenAPI document, in JSON or,
+// with ?format=yaml, in YAML
+func (h *Handler) getOpenAPI(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	document := openapi.Export(h.routeManager.GetConfig(), openapi.Info{
+		Title:   "Dynamic Control Plane",
+		Version: buildinfo.Get().Version,
+	})
+
+	if c.Query("format") != "yaml" {
+		c.JSON(http.StatusOK, document)
+		return
+	}
+
+	output, err := yaml.Marshal(document)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to render OpenAPI document",
+		})
+		return
+	}
+	c.Data(http.StatusOK, "application/yaml", output)
+}
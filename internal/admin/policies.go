@@ -0,0 +1,273 @@
+package admin
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"dynamiccontrol/internal/audit"
+	"dynamiccontrol/internal/decisions"
+	"dynamiccontrol/internal/events"
+	"dynamiccontrol/internal/listquery"
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/router"
+	"dynamiccontrol/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPolicySize bounds the size of uploaded policies
+const maxPolicySize = 1 << 20
+
+// policyName matches names usable as a Rego package
+var policyName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// uploadPolicy installs the Rego policy in the request body. With
+// ?dryRun=true the policy is only evaluated against recorded traffic of the
+// routes using it and compared with the live policy.
+func (h *Handler) uploadPolicy(c *gin.Context) {
+	if !h.requirePolicyManager(c) || !h.requireRouteManager(c) {
+		return
+	}
+
+	name := c.Param("name")
+	if !policyName.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid policy name",
+			"details": "policy names must be valid Rego package names",
+		})
+		return
+	}
+	if !h.requireOwnedName(c, name) {
+		return
+	}
+	source, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPolicySize+1))
+	if err != nil || len(source) == 0 || len(source) > maxPolicySize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid policy body",
+			"details": "the request body must hold the Rego source, up to 1 MiB",
+		})
+		return
+	}
+
+	if c.Query("dryRun") == "true" {
+		limit := decisions.DefaultSampleCapacity
+		if value := c.Query("samples"); value != "" {
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid samples parameter",
+					"details": "samples must be a positive integer",
+				})
+				return
+			}
+		}
+
+		result, err := h.routeManager.DryRunPolicy(name, string(source), limit)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to compile policy",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	if err := h.routeManager.CheckPolicyChange(name, string(source)); err != nil {
+		respondGuardrailError(c, err)
+		return
+	}
+	previous := h.policyManager.PolicySources()[name]
+	if err := h.policyManager.SetPolicy(name, string(source)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to compile policy",
+			"details": err.Error(),
+		})
+		return
+	}
+	var diff []types.AuditChange
+	if change, changed := audit.TextChange("/policies/"+name, previous, string(source)); changed {
+		diff = append(diff, change)
+	}
+	h.recordAudit(c, "policy.upload", name, map[string]interface{}{
+		"size": len(source),
+	}, diff...)
+	h.routeManager.RecordVersion()
+	h.routeManager.GetEventBroker().Publish(events.NewEvent(types.EventPolicyReloaded, "", "", map[string]interface{}{
+		"store":    "admin",
+		"policies": []string{name},
+	}))
+	c.JSON(http.StatusOK, PolicySummary{Name: name, Source: string(source)})
+}
+
+// policyTestRequest carries Rego test files and optional policy overrides,
+// both keyed by file or policy name
+type policyTestRequest struct {
+	Tests    map[string]string `json:"tests" binding:"required"`
+	Policies map[string]string `json:"policies,omitempty"`
+}
+
+// testPolicies runs Rego unit tests against the loaded policies, with the
+// request's policies replacing loaded ones of the same name
+func (h *Handler) testPolicies(c *gin.Context) {
+	if !h.requirePolicyManager(c) {
+		return
+	}
+
+	var request policyTestRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid policy test request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	policies := make(map[string]string)
+	for name, source := range h.policyManager.PolicySources() {
+		if h.visibleName(c, name) {
+			policies[name+".rego"] = source
+		}
+	}
+	for name, source := range request.Policies {
+		policies[strings.TrimSuffix(name, ".rego")+".rego"] = source
+	}
+
+	report, err := opa.RunTests(c.Request.Context(), policies, request.Tests, h.policyManager.RegisteredBuiltins()...)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to run policy tests",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// policyEvaluateRequest carries the policies to evaluate and their input
+type policyEvaluateRequest struct {
+	Policies []string               `json:"policies" binding:"required"`
+	Input    map[string]interface{} `json:"input"`
+}
+
+// evaluatePolicies evaluates loaded policies against an input exactly as the
+// authorize stage would, without calling any upstream, and reports the
+// combined decision along with the result of each policy
+func (h *Handler) evaluatePolicies(c *gin.Context) {
+	if !h.requirePolicyManager(c) {
+		return
+	}
+
+	var request policyEvaluateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid policy evaluation request",
+			"details": err.Error(),
+		})
+		return
+	}
+	if request.Input == nil {
+		request.Input = map[string]interface{}{}
+	}
+	for _, name := range request.Policies {
+		if !h.visibleName(c, name) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Action not permitted",
+				"details": fmt.Sprintf("policy %s belongs to another tenant", name),
+			})
+			return
+		}
+	}
+
+	var evaluator opa.PolicyEvaluator = h.policyManager
+	if h.routeManager != nil {
+		evaluator = h.routeManager.GetPolicyEvaluator()
+	}
+	decision, err := evaluator.EvaluatePolicies(request.Policies, request.Input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to evaluate policies",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	evaluation := types.PolicyEvaluation{
+		Allowed: decision.Allowed,
+		Error:   decision.Error,
+		Reasons: decision.Reasons,
+		Results: make([]types.PolicyEvaluationItem, 0, len(request.Policies)),
+	}
+	for _, name := range request.Policies {
+		item := types.PolicyEvaluationItem{Policy: name}
+		result, err := evaluator.EvaluatePolicy(name, request.Input)
+		if err != nil {
+			item.Error = err.Error()
+		} else {
+			item.Allowed = result.Allowed
+			item.Error = result.Error
+		}
+		evaluation.Results = append(evaluation.Results, item)
+	}
+	c.JSON(http.StatusOK, evaluation)
+}
+
+// evaluatePolicyREPL evaluates a Rego query for policy authors against the
+// loaded policies and data, with the request's modules and data applying to
+// this query only, so live policies are never affected
+func (h *Handler) evaluatePolicyREPL(c *gin.Context) {
+	if !h.requirePolicyManager(c) {
+		return
+	}
+
+	var request types.PolicyREPLRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid policy REPL request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	policies := make(map[string]string)
+	for name, source := range h.policyManager.PolicySources() {
+		if h.visibleName(c, name) {
+			policies[name] = source
+		}
+	}
+	result, err := opa.EvaluateQuery(c.Request.Context(), policies, h.policyManager.Data(), request, h.policyManager.RegisteredBuiltins()...)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to evaluate policy query",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// listDeprecatedPolicies lists the policies marked deprecated with their
+// replacement, sunset date and the routes still using them
+func (h *Handler) listDeprecatedPolicies(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	var deprecated []router.DeprecatedPolicy
+	for _, policy := range h.routeManager.DeprecatedPolicies() {
+		if h.visibleName(c, policy.Policy) {
+			deprecated = append(deprecated, policy)
+		}
+	}
+	items, err := listquery.ToItems(deprecated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondList(c, items, "policy")
+}
@@ -0,0 +1,214 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+// policyRoutes serve a mock response to requests allowed by allow_all
+var policyRoutes = &types.RoutesConfig{Routes: []types.RouteConfig{{
+	RouteName:    "/v1/status",
+	Method:       "GET",
+	Policies:     []string{"allow_all"},
+	MockResponse: &types.MockResponseConfig{StatusCode: http.StatusOK, Template: `{"status": "ok"}`},
+}}}
+
+// ordersPolicy only allows reads
+const ordersPolicy = "package orders_policy\n\ndefault allow = false\n\nallow { input.method == \"GET\" }\n"
+
+// decode decodes a JSON response into value
+func decode(t *testing.T, recorder *httptest.ResponseRecorder, value interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(recorder.Body.Bytes(), value); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", recorder.Body.String(), err)
+	}
+}
+
+// serveStatus serves a request to the status route
+func (a *testAdmin) serveStatus() int {
+	recorder := httptest.NewRecorder()
+	a.routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/status", nil))
+	return recorder.Code
+}
+
+func TestUploadPolicyDryRunDoesNotPersist(t *testing.T) {
+	admin := newTestAdmin(t, policyRoutes)
+	for i := 0; i < 3; i++ {
+		if status := admin.serveStatus(); status != http.StatusOK {
+			t.Fatalf("Expected the route to allow requests, got %d", status)
+		}
+	}
+	live := admin.policies.PolicySources()["allow_all"]
+	denyAll := "package allow_all\n\ndefault allow = false\n"
+
+	recorder := admin.do(testAdminToken, http.MethodPut, "/admin/policies/allow_all?dryRun=true", denyAll)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the dry run to succeed, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var result types.PolicyDryRun
+	decode(t, recorder, &result)
+	if result.Samples != 3 || result.Changed != 3 || result.Current.Allowed != 3 || result.Candidate.Denied != 3 {
+		t.Errorf("Expected the recorded requests to be denied by the candidate, got %+v", result)
+	}
+	if source := admin.policies.PolicySources()["allow_all"]; source != live {
+		t.Errorf("Expected the live policy to be kept, got %q", source)
+	}
+	if status := admin.serveStatus(); status != http.StatusOK {
+		t.Errorf("Expected the live policy to keep deciding requests, got %d", status)
+	}
+
+	// Dry runs neither create policies nor accept invalid ones
+	if recorder := admin.do(testAdminToken, http.MethodPut, "/admin/policies/new_policy?dryRun=true", "package new_policy\n\nallow = true\n"); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the dry run of a new policy to succeed, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if _, found := admin.policies.PolicySources()["new_policy"]; found {
+		t.Error("Expected the dry run not to load the new policy")
+	}
+	if recorder := admin.do(testAdminToken, http.MethodPut, "/admin/policies/allow_all?dryRun=true", "package allow_all\n\nallow {"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid candidate to be rejected, got %d", recorder.Code)
+	}
+
+	// Without dryRun the upload takes effect
+	if recorder := admin.do(testAdminToken, http.MethodPut, "/admin/policies/allow_all", denyAll); recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the upload to succeed, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if status := admin.serveStatus(); status != http.StatusForbidden {
+		t.Errorf("Expected the uploaded policy to deny requests, got %d", status)
+	}
+}
+
+func TestTestPolicies(t *testing.T) {
+	admin := newTestAdmin(t, policyRoutes)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"tests": map[string]string{
+			"allow_all_test.rego":     "package allow_all\n\ntest_allows { allow }\n",
+			"orders_policy_test.rego": "package orders_policy\n\ntest_get { allow with input as {\"method\": \"GET\"} }\n\ntest_post { allow with input as {\"method\": \"POST\"} }\n",
+		},
+		"policies": map[string]string{"orders_policy": ordersPolicy},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+	recorder := admin.do(testAdminToken, http.MethodPost, "/admin/policies/test", string(body))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the tests to run, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var report types.PolicyTestReport
+	decode(t, recorder, &report)
+	if report.Passed != 2 || report.Failed != 1 || report.Errors != 0 {
+		t.Errorf("Expected 2 passed and 1 failed test, got %+v", report)
+	}
+	if _, found := admin.policies.PolicySources()["orders_policy"]; found {
+		t.Error("Expected the request's policies not to be loaded")
+	}
+
+	if recorder := admin.do(testAdminToken, http.MethodPost, "/admin/policies/test", `{}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected a request without tests to be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestEvaluatePolicies(t *testing.T) {
+	admin := newTestAdmin(t, policyRoutes)
+	if err := admin.policies.SetPolicy("orders_policy", ordersPolicy); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	evaluate := func(body string) types.PolicyEvaluation {
+		t.Helper()
+		recorder := admin.do(testAdminToken, http.MethodPost, "/admin/policies/evaluate", body)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected the evaluation to succeed, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		var evaluation types.PolicyEvaluation
+		decode(t, recorder, &evaluation)
+		return evaluation
+	}
+
+	evaluation := evaluate(`{"policies": ["allow_all", "orders_policy"], "input": {"method": "POST"}}`)
+	expected := types.PolicyEvaluation{
+		Allowed: false,
+		Error:   "Policy orders_policy denied the request",
+		Results: []types.PolicyEvaluationItem{{Policy: "allow_all", Allowed: true}, {Policy: "orders_policy"}},
+	}
+	if !reflect.DeepEqual(evaluation, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, evaluation)
+	}
+	if evaluation := evaluate(`{"policies": ["allow_all", "orders_policy"], "input": {"method": "GET"}}`); !evaluation.Allowed {
+		t.Errorf("Expected reads to be allowed, got %+v", evaluation)
+	}
+	evaluation = evaluate(`{"policies": ["missing_policy"]}`)
+	if evaluation.Allowed || evaluation.Results[0].Error != "Policy missing_policy not found" {
+		t.Errorf("Expected an unknown policy to be reported, got %+v", evaluation)
+	}
+
+	if recorder := admin.do(testAdminToken, http.MethodPost, "/admin/policies/evaluate", `{"input": {}}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected a request without policies to be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestEvaluatePolicyREPL(t *testing.T) {
+	admin := newTestAdmin(t, policyRoutes)
+	if err := admin.policies.SetPolicy("orders_policy", ordersPolicy); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	repl := func(request types.PolicyREPLRequest) *httptest.ResponseRecorder {
+		body, err := json.Marshal(request)
+		if err != nil {
+			t.Fatalf("Failed to encode request: %v", err)
+		}
+		return admin.do(testAdminToken, http.MethodPost, "/admin/policies/repl", string(body))
+	}
+
+	recorder := repl(types.PolicyREPLRequest{Query: "data.orders_policy.allow", Input: map[string]interface{}{"method": "GET"}})
+	var result types.PolicyREPLResult
+	decode(t, recorder, &result)
+	if recorder.Code != http.StatusOK || !result.Defined || result.Results[0].Expressions[0] != true {
+		t.Errorf("Expected the loaded policy to allow reads, got %d %+v", recorder.Code, result)
+	}
+
+	// Request modules replace the loaded policy for this query only
+	recorder = repl(types.PolicyREPLRequest{
+		Query:   "data.orders_policy.allow",
+		Input:   map[string]interface{}{"method": "POST"},
+		Modules: map[string]string{"orders_policy": "package orders_policy\n\nallow = true\n"},
+	})
+	result = types.PolicyREPLResult{}
+	decode(t, recorder, &result)
+	if recorder.Code != http.StatusOK || result.Results[0].Expressions[0] != true {
+		t.Errorf("Expected the request module to allow writes, got %d %+v", recorder.Code, result)
+	}
+	if source := admin.policies.PolicySources()["orders_policy"]; source != ordersPolicy {
+		t.Errorf("Expected the loaded policy to be kept, got %q", source)
+	}
+
+	// Built-ins reaching the network are refused in queries and modules
+	var calls atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer backend.Close()
+	send := `http.send({"method": "GET", "url": "` + backend.URL + `"})`
+	for _, request := range []types.PolicyREPLRequest{
+		{Query: send},
+		{Query: "data.exfiltrate.response", Modules: map[string]string{"exfiltrate": "package exfiltrate\n\nresponse := " + send + "\n"}},
+	} {
+		recorder := repl(request)
+		var body map[string]string
+		decode(t, recorder, &body)
+		if recorder.Code != http.StatusBadRequest || !strings.Contains(body["details"], "http.send") {
+			t.Errorf("Expected http.send to be refused, got %d %v", recorder.Code, body)
+		}
+	}
+	if calls.Load() != 0 {
+		t.Errorf("Expected no request to reach the network, got %d", calls.Load())
+	}
+}
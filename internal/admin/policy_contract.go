@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"dynamiccontrol/internal/router"
+	"dynamiccontrol/pkg/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// policyContract is the versioned contract between the control plane and its
+// policies
+type policyContract struct {
+	Version string        `json:"version"`
+	Route   string        `json:"route,omitempty"`
+	Input   policy.Schema `json:"input"`
+	Result  policy.Schema `json:"result"`
+}
+
+// getPolicyContract returns the JSON Schemas of the policy input and result
+// documents. With ?route=POST%20/v1/orders the input schema is specific to
+// that route's method, path parameters, query, headers and body.
+func (h *Handler) getPolicyContract(c *gin.Context) {
+	contract := policyContract{
+		Version: policy.Version,
+		Input:   policy.InputSchema(),
+		Result:  policy.ResultSchema(),
+	}
+	if key := c.Query("route"); key != "" {
+		if !h.requireRouteManager(c) {
+			return
+		}
+		method, pattern, _ := strings.Cut(key, " ")
+		route, found := h.findRoute(requestTenant(c, c.Query("tenant")), c.Query("host"), method, pattern)
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Route not found",
+				"details": "route must name a configured route, such as POST /v1/orders",
+			})
+			return
+		}
+		contract.Route = router.RouteKey(route)
+		contract.Input = policy.RouteInputSchema(route)
+	}
+	c.JSON(http.StatusOK, contract)
+}
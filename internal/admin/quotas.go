@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"net/http"
+
+	"dynamiccontrol/internal/listquery"
+	"dynamiccontrol/internal/quota"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuotaRequest sets the quota of a service through the admin API
+type QuotaRequest struct {
+	Requests      int     `json:"requests"`
+	Volume        float64 `json:"volume"`
+	WindowSeconds int     `json:"windowSeconds"`
+}
+
+// listQuotas lists the quotas of services with their usage in the current
+// window
+func (h *Handler) listQuotas(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	items, err := listquery.ToItems(h.routeManager.GetQuotas().List())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondList(c, items, "service")
+}
+
+// getQuota returns the quota of a service with its usage
+func (h *Handler) getQuota(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	usage, exists := h.routeManager.GetQuotas().Get(c.Param("service"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Service has no quota",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// setQuota sets the quota of a service, restarting its window
+func (h *Handler) setQuota(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	var request QuotaRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid JSON",
+			"details": err.Error(),
+		})
+		return
+	}
+	quotas := h.routeManager.GetQuotas()
+	service := c.Param("service")
+	err := quotas.Set(quota.Quota{
+		Service:       service,
+		Requests:      request.Requests,
+		Volume:        request.Volume,
+		WindowSeconds: request.WindowSeconds,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid quota",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	usage, _ := quotas.Get(service)
+	h.recordAudit(c, "quota.set", service, map[string]interface{}{
+		"requests":      usage.Requests,
+		"volume":        usage.Volume,
+		"windowSeconds": int(usage.Window().Seconds()),
+	})
+	c.JSON(http.StatusOK, usage)
+}
+
+// deleteQuota removes the quota of a service
+func (h *Handler) deleteQuota(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	service := c.Param("service")
+	if !h.routeManager.GetQuotas().Delete(service) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Service has no quota",
+		})
+		return
+	}
+	h.recordAudit(c, "quota.delete", service, nil)
+	c.Status(http.StatusNoContent)
+}
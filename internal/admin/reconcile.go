@@ -0,0 +1,64 @@
+package admin
+
+import (
+	"net/http"
+
+	"dynamiccontrol/internal/reconcile"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getReconcile reports the reconciler settings and the traffic status of
+// every service with accepted intents as of the last reconcile
+func (h *Handler) getReconcile(c *gin.Context) {
+	reconciler, ok := h.requireReconciler(c)
+	if !ok {
+		return
+	}
+	config := reconciler.Config()
+	c.JSON(http.StatusOK, gin.H{
+		"config": gin.H{
+			"interval":  config.Interval.String(),
+			"tolerance": config.Tolerance,
+			"mode":      config.Mode,
+		},
+		"services": reconciler.Status(),
+	})
+}
+
+// runReconcile reconciles the traffic intents of services immediately,
+// restarting the observation window
+func (h *Handler) runReconcile(c *gin.Context) {
+	reconciler, ok := h.requireReconciler(c)
+	if !ok {
+		return
+	}
+	statuses := reconciler.Reconcile()
+	diverged := 0
+	for _, status := range statuses {
+		if status.State != reconcile.StateInSync {
+			diverged++
+		}
+	}
+	h.recordAudit(c, "traffic.reconcile", "traffic", map[string]interface{}{
+		"services": len(statuses),
+		"diverged": diverged,
+	})
+	c.JSON(http.StatusOK, gin.H{"services": statuses})
+}
+
+// requireReconciler writes an error response when traffic reconciliation is
+// not enabled
+func (h *Handler) requireReconciler(c *gin.Context) (*reconcile.Reconciler, bool) {
+	if !h.requireRouteManager(c) {
+		return nil, false
+	}
+	reconciler := h.routeManager.GetReconciler()
+	if reconciler == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Traffic reconciliation is not enabled",
+		})
+		return nil, false
+	}
+	return reconciler, true
+}
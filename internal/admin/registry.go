@@ -0,0 +1,127 @@
+package admin
+
+import (
+	"net/http"
+	"sort"
+
+	"dynamiccontrol/internal/audit"
+	"dynamiccontrol/internal/listquery"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SchemaSummary describes a registered schema in admin listings
+type SchemaSummary struct {
+	Name     string   `json:"name"`
+	Versions []string `json:"versions"`
+	Latest   string   `json:"latest"`
+}
+
+// listSchemas lists the registered schemas with their versions
+func (h *Handler) listSchemas(c *gin.Context) {
+	if !h.requireSchemaRegistry(c) {
+		return
+	}
+
+	registered, err := h.routeManager.GetSchemaValidator().RegisteredSchemas()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	schemas := make([]SchemaSummary, 0, len(registered))
+	for name, versions := range registered {
+		if !h.visibleName(c, name) {
+			continue
+		}
+		schemas = append(schemas, SchemaSummary{Name: name, Versions: versions, Latest: versions[len(versions)-1]})
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Name < schemas[j].Name })
+
+	items, err := listquery.ToItems(schemas)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondList(c, items, "name")
+}
+
+// getSchema returns a version of a registered schema; the version "latest"
+// selects the newest one
+func (h *Handler) getSchema(c *gin.Context) {
+	if !h.requireSchemaRegistry(c) {
+		return
+	}
+
+	ref := c.Param("name")
+	if !h.visibleName(c, ref) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Schema not found",
+		})
+		return
+	}
+	if version := c.Param("version"); version != "latest" {
+		ref += "@" + version
+	}
+	schema, err := h.routeManager.GetSchemaValidator().ResolveSchemaRef(ref)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Schema not found",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, schema)
+}
+
+// registerSchema stores the JSON schema in the request body as a new version.
+// Versions are immutable, so uploading an existing version is a conflict.
+func (h *Handler) registerSchema(c *gin.Context) {
+	if !h.requireSchemaRegistry(c) {
+		return
+	}
+
+	name, version := c.Param("name"), c.Param("version")
+	if !h.requireOwnedName(c, name) {
+		return
+	}
+	schemaValidator := h.routeManager.GetSchemaValidator()
+	if _, err := schemaValidator.RegisteredSchema(name, version); err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Schema version already registered",
+			"details": "register changes under a new version",
+		})
+		return
+	}
+
+	var schema map[string]interface{}
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid schema body",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := schemaValidator.RegisterSchema(name, version, schema); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to register schema",
+			"details": err.Error(),
+		})
+		return
+	}
+	h.recordAudit(c, "schema.register", name+"@"+version, nil, audit.Diff("", nil, schema)...)
+	c.JSON(http.StatusCreated, gin.H{"name": name, "version": version})
+}
+
+// requireSchemaRegistry writes an error response when no schema directory is configured
+func (h *Handler) requireSchemaRegistry(c *gin.Context) bool {
+	if !h.requireRouteManager(c) {
+		return false
+	}
+	if h.routeManager.GetSchemaValidator().GetSchemaDir() == "" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Schema registry is not enabled",
+		})
+		return false
+	}
+	return true
+}
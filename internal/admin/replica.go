@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"dynamiccontrol/internal/configstore"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readOnlyPosts are POST endpoints that do not change state and stay
+// available on read-only replicas
+var readOnlyPosts = map[string]bool{
+	"/transform/playground": true,
+	"/policies/test":        true,
+	"/policies/evaluate":    true,
+	"/policies/repl":        true,
+	"/config/diff":          true,
+	"/schemas/profile":      true,
+	"/debug/pprof/symbol":   true,
+}
+
+// SetReadOnly turns the admin API read-only, as on read replicas whose
+// configuration is owned by a primary
+func (h *Handler) SetReadOnly(readOnly bool) {
+	h.readOnly = readOnly
+}
+
+// rejectWrites refuses admin requests that would change state on a read-only instance
+func (h *Handler) rejectWrites(group *gin.RouterGroup) gin.HandlerFunc {
+	prefix := strings.TrimSuffix(group.BasePath(), "/")
+	return func(c *gin.Context) {
+		if !h.readOnly || isReadOnlyRequest(c, strings.TrimPrefix(c.FullPath(), prefix)) {
+			c.Next()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":   "Instance is a read-only replica",
+			"details": "apply changes on the primary",
+		})
+	}
+}
+
+// isReadOnlyRequest reports whether an admin request leaves state unchanged
+func isReadOnlyRequest(c *gin.Context, path string) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		return readOnlyPosts[path]
+	case http.MethodPut:
+		return path == "/policies/:name" && c.Query("dryRun") == "true"
+	default:
+		return false
+	}
+}
+
+// getSnapshot serves the complete active configuration to read replicas.
+// Requests with a matching If-None-Match revision get 304. During a rollout,
+// replicas receive the revision of their ring.
+func (h *Handler) getSnapshot(c *gin.Context) {
+	if !h.requireRouteManager(c) || !h.requirePolicyManager(c) {
+		return
+	}
+
+	snapshot, err := configstore.NewSnapshot(h.routeManager.GetConfig(), h.policyManager.PolicySources())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if h.rollout != nil {
+		snapshot = h.rolloutSnapshot(c, snapshot)
+	}
+	etag := `"` + snapshot.Revision + `"`
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
@@ -0,0 +1,122 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"dynamiccontrol/internal/configstore"
+	"dynamiccontrol/internal/rollout"
+
+	"github.com/gin-gonic/gin"
+)
+
+// abortRequest optionally explains why a rollout is aborted
+type abortRequest struct {
+	Reason string `json:"reason"`
+}
+
+// SetRollout stages new revisions across the rings of replicas syncing
+// from this primary
+func (h *Handler) SetRollout(controller *rollout.Controller) {
+	h.rollout = controller
+}
+
+// rolloutSnapshot records the rollout report sent with a replica's snapshot
+// request and returns the snapshot for the replica's ring. Replicas that do
+// not report a ring receive the stable revision.
+func (h *Handler) rolloutSnapshot(c *gin.Context, current *configstore.Snapshot) *configstore.Snapshot {
+	h.rollout.Observe(current)
+
+	ring, err := strconv.Atoi(c.GetHeader(configstore.RolloutRingHeader))
+	if err != nil {
+		return h.rollout.SnapshotFor(-1)
+	}
+	requests, _ := strconv.ParseInt(c.GetHeader(configstore.RolloutRequestsHeader), 10, 64)
+	errors, _ := strconv.ParseInt(c.GetHeader(configstore.RolloutErrorsHeader), 10, 64)
+	h.rollout.Record(rollout.Report{
+		Replica:  c.GetHeader(configstore.RolloutReplicaHeader),
+		Ring:     ring,
+		Revision: c.GetHeader(configstore.RolloutRevisionHeader),
+		Requests: requests,
+		Errors:   errors,
+	})
+	return h.rollout.SnapshotFor(ring)
+}
+
+// getRollout reports the progress of the current rollout
+func (h *Handler) getRollout(c *gin.Context) {
+	if !h.requireRollout(c) {
+		return
+	}
+	config := h.rollout.Config()
+	c.JSON(http.StatusOK, gin.H{
+		"config": gin.H{
+			"rings":        config.Rings,
+			"bakeTime":     config.BakeTime.String(),
+			"maxErrorRate": config.MaxErrorRate,
+			"minRequests":  config.MinRequests,
+			"autoPromote":  config.AutoPromote,
+		},
+		"status": h.rollout.Status(),
+	})
+}
+
+// promoteRollout moves the candidate revision to the next ring
+func (h *Handler) promoteRollout(c *gin.Context) {
+	if !h.requireRollout(c) {
+		return
+	}
+	if err := h.rollout.Promote(); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	status := h.rollout.Status()
+	h.recordAudit(c, "rollout.promote", "rollout", map[string]interface{}{
+		"revision":     status.CandidateRevision,
+		"promotedRing": status.PromotedRing,
+		"state":        status.State,
+	})
+	c.JSON(http.StatusOK, status)
+}
+
+// abortRollout returns every ring to the stable revision
+func (h *Handler) abortRollout(c *gin.Context) {
+	if !h.requireRollout(c) {
+		return
+	}
+	var request abortRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid abort request",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	if request.Reason == "" {
+		request.Reason = "aborted by operator"
+	}
+
+	revision := h.rollout.Status().CandidateRevision
+	if err := h.rollout.Abort(request.Reason); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordAudit(c, "rollout.abort", "rollout", map[string]interface{}{
+		"revision": revision,
+		"reason":   request.Reason,
+	})
+	c.JSON(http.StatusOK, h.rollout.Status())
+}
+
+// requireRollout writes an error response when rollouts are not enabled
+func (h *Handler) requireRollout(c *gin.Context) bool {
+	if h.rollout == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Rollout rings are not enabled",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"dynamiccontrol/internal/audit"
+	"dynamiccontrol/internal/guardrails"
+	"dynamiccontrol/internal/router"
+	"dynamiccontrol/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deleteRouteRequest names a runtime route to delete
+type deleteRouteRequest struct {
+	Method string `json:"method" binding:"required"`
+	Route  string `json:"route" binding:"required"`
+	Tenant string `json:"tenant,omitempty"`
+	Host   string `json:"host,omitempty"`
+}
+
+// putRoute creates or replaces a route at runtime. The route is persisted
+// when a route store is configured, and replaces a configured route with the
+// same method, path, host and tenant until it is deleted.
+func (h *Handler) putRoute(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	var route types.RouteConfig
+	if err := c.ShouldBindJSON(&route); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid route",
+			"details": err.Error(),
+		})
+		return
+	}
+	route.Method = strings.ToUpper(route.Method)
+	route.Tenant = requestTenant(c, route.Tenant)
+	previous, existed := h.findRoute(route.Tenant, route.Host, route.Method, route.RouteName)
+
+	created, err := h.routeManager.PutRuntimeRoute(c.Request.Context(), route)
+	if err != nil {
+		var rejected *guardrails.Error
+		if errors.As(err, &rejected) {
+			respondGuardrailError(c, err)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to apply route",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	key := router.RouteKey(route)
+	if created {
+		h.recordAudit(c, "route.create", key, nil, audit.Diff("", nil, route)...)
+		c.JSON(http.StatusCreated, route)
+		return
+	}
+	var before interface{}
+	if existed {
+		before = previous
+	}
+	h.recordAudit(c, "route.update", key, nil, audit.Diff("", before, route)...)
+	c.JSON(http.StatusOK, route)
+}
+
+// deleteRoute deletes a route created at runtime. Routes of the config store
+// cannot be deleted; a configured route replaced at runtime is restored.
+func (h *Handler) deleteRoute(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	var request deleteRouteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid route request",
+			"details": err.Error(),
+		})
+		return
+	}
+	key := router.RouteKey(types.RouteConfig{
+		Method:    strings.ToUpper(request.Method),
+		RouteName: request.Route,
+		Tenant:    requestTenant(c, request.Tenant),
+		Host:      request.Host,
+	})
+
+	route, err := h.routeManager.DeleteRuntimeRoute(c.Request.Context(), key)
+	if err != nil {
+		var rejected *guardrails.Error
+		switch {
+		case errors.Is(err, router.ErrNotRuntimeRoute):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Route not found",
+				"details": "only routes created through the admin API can be deleted",
+			})
+		case errors.As(err, &rejected):
+			respondGuardrailError(c, err)
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to delete route",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+	h.recordAudit(c, "route.delete", key, nil, audit.Diff("", route, nil)...)
+	c.JSON(http.StatusOK, route)
+}
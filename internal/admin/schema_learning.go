@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"dynamiccontrol/internal/listquery"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listLearnedSchemas lists the routes in schema learning mode with their
+// sample counts and inferred schemas
+func (h *Handler) listLearnedSchemas(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	items, err := listquery.ToItems(h.routeManager.LearnedSchemas())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondList(c, items, "route")
+}
+
+// getLearnedSchema returns the candidate request schema inferred for the
+// route given as ?route=POST%20/v1/orders, ready to use as its requestSchema
+func (h *Handler) getLearnedSchema(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	learned, found := h.routeManager.LearnedSchema(c.Query("route"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Route not found",
+			"details": "route must name a route in schema learning mode, such as POST /v1/orders",
+		})
+		return
+	}
+	c.Header("X-Schema-Samples", strconv.Itoa(learned.Samples))
+	c.JSON(http.StatusOK, learned.Schema)
+}
+
+// resetLearnedSchema discards the samples recorded for the route given as
+// ?route=POST%20/v1/orders, restarting inference
+func (h *Handler) resetLearnedSchema(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	route := c.Query("route")
+	if !h.routeManager.ResetLearnedSchema(route) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Route not found",
+			"details": "route must name a route in schema learning mode, such as POST /v1/orders",
+		})
+		return
+	}
+	h.recordAudit(c, "schema_learning.reset", route, nil)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// schemaProfileRequest selects a schema, either inline or by route, and the
+// document to profile its validation with
+type schemaProfileRequest struct {
+	Method string `json:"method,omitempty"`
+	Route  string `json:"route,omitempty"`
+	Tenant string `json:"tenant,omitempty"`
+	Host   string `json:"host,omitempty"`
+	// Kind selects the request (default) or response schema of the route
+	Kind     string                 `json:"kind,omitempty"`
+	Schema   map[string]interface{} `json:"schema,omitempty"`
+	Input    interface{}            `json:"input"`
+	Hotspots int                    `json:"hotspots,omitempty"`
+}
+
+// profileSchema reports the most expensive keywords of validating the input
+// against a schema, with suggestions for restructuring them
+func (h *Handler) profileSchema(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	var request schemaProfileRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid schema profile request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	schema := request.Schema
+	if schema == nil {
+		route, found := h.findRoute(requestTenant(c, request.Tenant), request.Host, request.Method, request.Route)
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Route not found",
+				"details": "provide a schema, or the method and route of a configured route",
+			})
+			return
+		}
+		switch request.Kind {
+		case "", "request":
+			schema = route.RequestSchema
+		case "response":
+			schema = route.ResponseSchema
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid schema kind",
+				"details": "kind must be request or response",
+			})
+			return
+		}
+	}
+
+	profile, err := h.routeManager.GetSchemaValidator().Profile(schema, request.Input, request.Hotspots)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to profile schema",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// findRoute returns the configured route of a tenant and host with the given
+// method and pattern, where an empty tenant or host selects shared routes and
+// routes serving every host
+func (h *Handler) findRoute(tenant, host, method, pattern string) (types.RouteConfig, bool) {
+	config := h.routeManager.GetConfig()
+	if config == nil {
+		return types.RouteConfig{}, false
+	}
+	for _, route := range config.Routes {
+		if route.Tenant == tenant && route.Host == host && strings.EqualFold(route.Method, method) && route.RouteName == pattern {
+			return route, true
+		}
+	}
+	return types.RouteConfig{}, false
+}
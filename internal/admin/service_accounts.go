@@ -0,0 +1,244 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/accounts"
+	"dynamiccontrol/internal/listquery"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Resources whose actions are authorized per request
+const (
+	// serviceAccountsResource is never granted to service accounts, so an
+	// account cannot extend its own scopes
+	serviceAccountsResource = "service-accounts"
+	// weightsAction targets a single route and honors scope route labels
+	weightsAction = "weights:write"
+)
+
+// serviceAccountRequest creates a service account
+type serviceAccountRequest struct {
+	Name        string           `json:"name" binding:"required"`
+	Description string           `json:"description,omitempty"`
+	Scopes      []accounts.Scope `json:"scopes" binding:"required"`
+	Tenant      string           `json:"tenant,omitempty"`
+}
+
+// rotateRequest rotates a service account token
+type rotateRequest struct {
+	// GracePeriod keeps the previous token valid, as a duration such as "1h"
+	GracePeriod string `json:"gracePeriod,omitempty"`
+}
+
+// issuedToken returns an account with its token, shown only once
+type issuedToken struct {
+	accounts.Account
+	Token string `json:"token"`
+}
+
+// SetServiceAccounts enables scoped service accounts for machine clients
+func (h *Handler) SetServiceAccounts(store *accounts.Store) {
+	h.accounts = store
+}
+
+// authorize limits requests of service accounts to the actions their scopes
+// grant. The action of a request is its first path segment and "read" or
+// "write", such as "policies:write".
+func (h *Handler) authorize(group *gin.RouterGroup) gin.HandlerFunc {
+	prefix := strings.TrimSuffix(group.BasePath(), "/")
+	return func(c *gin.Context) {
+		account, ok := serviceAccount(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		path := strings.TrimPrefix(c.FullPath(), prefix)
+		resource, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+		verb := "write"
+		if isReadOnlyRequest(c, path) {
+			verb = "read"
+		}
+		action := resource + ":" + verb
+
+		if account.Tenant != "" && !tenantResources[resource] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Action not permitted",
+				"details": fmt.Sprintf("%s is not available to service accounts of tenant %s", resource, account.Tenant),
+			})
+			return
+		}
+		allowed := resource != serviceAccountsResource && account.Allows(action)
+		if action == weightsAction {
+			// Route labels are checked once the route is known
+			allowed = account.AllowsAny(action)
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Action not permitted",
+				"details": fmt.Sprintf("service account %s is not granted %s", account.Name, action),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope limits requests of service accounts to accounts granted an
+// action, for endpoints served outside the admin API such as /graphql.
+// Tenant accounts are rejected: such endpoints read the state of every
+// tenant.
+func RequireScope(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		account, ok := serviceAccount(c)
+		if !ok {
+			c.Next()
+			return
+		}
+		if account.Tenant != "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Action not permitted",
+				"details": fmt.Sprintf("%s is not available to service accounts of tenant %s", action, account.Tenant),
+			})
+			return
+		}
+		if !account.Allows(action) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Action not permitted",
+				"details": fmt.Sprintf("service account %s is not granted %s", account.Name, action),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// listServiceAccounts lists the service accounts and their scopes
+func (h *Handler) listServiceAccounts(c *gin.Context) {
+	if !h.requireServiceAccounts(c) {
+		return
+	}
+	items, err := listquery.ToItems(h.accounts.List())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondList(c, items, "name")
+}
+
+// createServiceAccount issues a service account and returns its token
+func (h *Handler) createServiceAccount(c *gin.Context) {
+	if !h.requireServiceAccounts(c) {
+		return
+	}
+
+	var request serviceAccountRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid service account request",
+			"details": err.Error(),
+		})
+		return
+	}
+	if request.Tenant != "" && !h.tenants().Exists(request.Tenant) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to create service account",
+			"details": fmt.Sprintf("tenant %s is not declared", request.Tenant),
+		})
+		return
+	}
+	account, token, err := h.accounts.Create(accounts.Account{
+		Name:        request.Name,
+		Description: request.Description,
+		Scopes:      request.Scopes,
+		Tenant:      request.Tenant,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to create service account",
+			"details": err.Error(),
+		})
+		return
+	}
+	h.recordAudit(c, "service_account.create", account.Name, map[string]interface{}{
+		"scopes": account.Scopes,
+		"tenant": account.Tenant,
+	})
+	c.JSON(http.StatusCreated, issuedToken{Account: account, Token: token})
+}
+
+// rotateServiceAccount issues a new token for a service account
+func (h *Handler) rotateServiceAccount(c *gin.Context) {
+	if !h.requireServiceAccounts(c) {
+		return
+	}
+
+	var request rotateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid rotate request",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	var grace time.Duration
+	if request.GracePeriod != "" {
+		var err error
+		if grace, err = time.ParseDuration(request.GracePeriod); err != nil || grace < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid grace period",
+				"details": "gracePeriod must be a duration such as 1h",
+			})
+			return
+		}
+	}
+
+	account, token, err := h.accounts.Rotate(c.Param("name"), grace)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Failed to rotate service account",
+			"details": err.Error(),
+		})
+		return
+	}
+	h.recordAudit(c, "service_account.rotate", account.Name, map[string]interface{}{
+		"gracePeriod": grace.String(),
+	})
+	c.JSON(http.StatusOK, issuedToken{Account: account, Token: token})
+}
+
+// deleteServiceAccount removes a service account, revoking its tokens
+func (h *Handler) deleteServiceAccount(c *gin.Context) {
+	if !h.requireServiceAccounts(c) {
+		return
+	}
+	name := c.Param("name")
+	if err := h.accounts.Delete(name); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Failed to delete service account",
+			"details": err.Error(),
+		})
+		return
+	}
+	h.recordAudit(c, "service_account.delete", name, nil)
+	c.Status(http.StatusNoContent)
+}
+
+// requireServiceAccounts writes an error response when service accounts are not enabled
+func (h *Handler) requireServiceAccounts(c *gin.Context) bool {
+	if h.accounts == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Service accounts are not enabled",
+			"details": "service accounts require ADMIN_TOKEN",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,279 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/accounts"
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/router"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testAdminToken = "admin-secret"
+
+// testAdmin is an admin API served with the admin token and service accounts
+type testAdmin struct {
+	engine   *gin.Engine
+	routes   *router.RouteManager
+	policies *opa.PolicyManager
+	accounts *accounts.Store
+}
+
+func newTestAdmin(t *testing.T, config *types.RoutesConfig) *testAdmin {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	policies := opa.NewPolicyManager()
+	if err := policies.SetPolicy("allow_all", "package allow_all\n\ndefault allow = true\n"); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	schemas := validator.NewSchemaValidator()
+	if err := schemas.SetSchemaDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to set schema directory: %v", err)
+	}
+	routes := router.NewRouteManager(policies, schemas)
+	t.Cleanup(routes.Stop)
+	if err := routes.ApplyConfig(config); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	store, err := accounts.NewStore("")
+	if err != nil {
+		t.Fatalf("Failed to create account store: %v", err)
+	}
+
+	handler := NewHandler()
+	handler.SetRouteManager(routes)
+	handler.SetPolicyManager(policies)
+	handler.SetServiceAccounts(store)
+	engine := gin.New()
+	group := engine.Group("/admin")
+	group.Use(Authenticate(testAdminToken, store))
+	handler.Register(group)
+	return &testAdmin{engine: engine, routes: routes, policies: policies, accounts: store}
+}
+
+// issue creates a service account and returns its token
+func (a *testAdmin) issue(t *testing.T, account accounts.Account) string {
+	t.Helper()
+	_, token, err := a.accounts.Create(account)
+	if err != nil {
+		t.Fatalf("Failed to create service account: %v", err)
+	}
+	return token
+}
+
+// do sends an admin request with a bearer token
+func (a *testAdmin) do(token, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	a.engine.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// listNames returns the values of a field of the items of a list response
+func listNames(t *testing.T, recorder *httptest.ResponseRecorder, field string) []string {
+	t.Helper()
+	var page struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to decode list: %v: %s", err, recorder.Body.String())
+	}
+	names := make([]string, 0, len(page.Items))
+	for _, item := range page.Items {
+		name, _ := item[field].(string)
+		names = append(names, name)
+	}
+	return names
+}
+
+// labeledProxyRoutes are two proxy routes owned by different teams
+var labeledProxyRoutes = &types.RoutesConfig{Routes: []types.RouteConfig{
+	{
+		RouteName: "/v1/payments",
+		Method:    "POST",
+		Handler:   types.HandlerProxy,
+		Policies:  []string{"allow_all"},
+		Labels:    map[string]string{"team": "payments"},
+		Upstreams: []types.UpstreamTarget{
+			{Name: "stable", URL: "http://payments-v1:8080", Weight: 100},
+			{Name: "canary", URL: "http://payments-v2:8080", Weight: 1},
+		},
+	},
+	{
+		RouteName: "/v1/search",
+		Method:    "GET",
+		Handler:   types.HandlerProxy,
+		Policies:  []string{"allow_all"},
+		Labels:    map[string]string{"team": "search"},
+		Upstreams: []types.UpstreamTarget{
+			{Name: "stable", URL: "http://search-v1:8080", Weight: 100},
+			{Name: "canary", URL: "http://search-v2:8080", Weight: 1},
+		},
+	},
+}}
+
+func TestServiceAccountWithoutScopeIsForbidden(t *testing.T) {
+	admin := newTestAdmin(t, labeledProxyRoutes)
+	token := admin.issue(t, accounts.Account{Name: "reader", Scopes: []accounts.Scope{{Actions: []string{"policies:read"}}}})
+
+	for _, tc := range []struct {
+		method, path, body string
+		expected           int
+	}{
+		{http.MethodGet, "/admin/policies", "", http.StatusOK},
+		{http.MethodPut, "/admin/policies/other", "package other\n\ndefault allow = true\n", http.StatusForbidden},
+		{http.MethodGet, "/admin/routes", "", http.StatusForbidden},
+		{http.MethodGet, "/admin/audit", "", http.StatusForbidden},
+	} {
+		if recorder := admin.do(token, tc.method, tc.path, tc.body); recorder.Code != tc.expected {
+			t.Errorf("%s %s: expected %d, got %d: %s", tc.method, tc.path, tc.expected, recorder.Code, recorder.Body.String())
+		}
+	}
+	if recorder := admin.do("dcsa_unknown", http.MethodGet, "/admin/policies", ""); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an unknown token to be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, _ := accounts.NewStore("")
+	_, granted, _ := store.Create(accounts.Account{Name: "dashboard", Scopes: []accounts.Scope{{Actions: []string{"graphql:read"}}}})
+	_, other, _ := store.Create(accounts.Account{Name: "ci", Scopes: []accounts.Scope{{Actions: []string{"policies:*"}}}})
+	_, tenant, _ := store.Create(accounts.Account{Name: "acme", Tenant: "acme", Scopes: []accounts.Scope{{Actions: []string{"graphql:read"}}}})
+
+	engine := gin.New()
+	engine.GET("/graphql", Authenticate(testAdminToken, store), RequireScope("graphql:read"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	for _, tc := range []struct {
+		name, token string
+		expected    int
+	}{
+		{"admin token", testAdminToken, http.StatusNoContent},
+		{"granted account", granted, http.StatusNoContent},
+		{"account without the scope", other, http.StatusForbidden},
+		{"tenant account", tenant, http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/graphql", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		if recorder.Code != tc.expected {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.expected, recorder.Code)
+		}
+	}
+}
+
+func TestServiceAccountRouteLabels(t *testing.T) {
+	admin := newTestAdmin(t, labeledProxyRoutes)
+	token := admin.issue(t, accounts.Account{Name: "payments-deploy", Scopes: []accounts.Scope{{
+		Actions:     []string{"weights:write", "routes:write"},
+		RouteLabels: map[string]string{"team": "payments"},
+	}}})
+
+	recorder := admin.do(token, http.MethodPut, "/admin/weights", `{"method": "POST", "route": "/v1/payments", "weights": {"stable": 90, "canary": 10}}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected weights of a labeled route to change, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if upstreams := admin.routes.GetConfig().Routes[0].Upstreams; upstreams[1].Weight != 10 {
+		t.Errorf("Expected the canary weight to be applied, got %+v", upstreams)
+	}
+
+	recorder = admin.do(token, http.MethodPut, "/admin/weights", `{"method": "GET", "route": "/v1/search", "weights": {"stable": 50, "canary": 50}}`)
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected weights of another team's route to be forbidden, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if upstreams := admin.routes.GetConfig().Routes[1].Upstreams; upstreams[1].Weight != 1 {
+		t.Errorf("Expected the search weights to be unchanged, got %+v", upstreams)
+	}
+
+	// Label scopes only grant actions on individual routes, not route changes
+	recorder = admin.do(token, http.MethodPut, "/admin/routes", `{"routeName": "/v1/payments/refunds", "method": "POST", "labels": {"team": "payments"}}`)
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected a label scope not to grant route changes, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if len(admin.routes.GetConfig().Routes) != 2 {
+		t.Error("Expected no route to be added")
+	}
+}
+
+func TestServiceAccountsCannotManageServiceAccounts(t *testing.T) {
+	admin := newTestAdmin(t, &types.RoutesConfig{})
+	token := admin.issue(t, accounts.Account{Name: "operator", Scopes: []accounts.Scope{{Actions: []string{"*:*"}}}})
+
+	for _, tc := range []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/admin/service-accounts", ""},
+		{http.MethodPost, "/admin/service-accounts", `{"name": "escalated", "scopes": [{"actions": ["*:*"]}]}`},
+		{http.MethodPost, "/admin/service-accounts/operator/rotate", ""},
+		{http.MethodDelete, "/admin/service-accounts/operator", ""},
+	} {
+		if recorder := admin.do(token, tc.method, tc.path, tc.body); recorder.Code != http.StatusForbidden {
+			t.Errorf("%s %s: expected 403, got %d: %s", tc.method, tc.path, recorder.Code, recorder.Body.String())
+		}
+	}
+	if len(admin.accounts.List()) != 1 {
+		t.Errorf("Expected the accounts to be unchanged, got %+v", admin.accounts.List())
+	}
+
+	recorder := admin.do(testAdminToken, http.MethodPost, "/admin/service-accounts", `{"name": "ci", "scopes": [{"actions": ["policies:write"]}]}`)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected the admin token to create accounts, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var issued issuedToken
+	if err := json.Unmarshal(recorder.Body.Bytes(), &issued); err != nil || !strings.HasPrefix(issued.Token, accounts.TokenPrefix) {
+		t.Fatalf("Expected an issued token, got %s", recorder.Body.String())
+	}
+	if recorder := admin.do(testAdminToken, http.MethodDelete, "/admin/service-accounts/ci", ""); recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected the admin token to delete accounts, got %d", recorder.Code)
+	}
+	if recorder := admin.do(issued.Token, http.MethodGet, "/admin/policies", ""); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the token of a deleted account to be revoked, got %d", recorder.Code)
+	}
+}
+
+func TestRotatedTokenGracePeriod(t *testing.T) {
+	admin := newTestAdmin(t, &types.RoutesConfig{})
+	previous := admin.issue(t, accounts.Account{Name: "ci", Scopes: []accounts.Scope{{Actions: []string{"policies:read"}}}})
+
+	grace := 300 * time.Millisecond
+	recorder := admin.do(testAdminToken, http.MethodPost, "/admin/service-accounts/ci/rotate", `{"gracePeriod": "`+grace.String()+`"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Failed to rotate token: %d %s", recorder.Code, recorder.Body.String())
+	}
+	rotated := time.Now()
+	var issued issuedToken
+	if err := json.Unmarshal(recorder.Body.Bytes(), &issued); err != nil || issued.Token == previous {
+		t.Fatalf("Expected a new token, got %s", recorder.Body.String())
+	}
+
+	if recorder := admin.do(issued.Token, http.MethodGet, "/admin/policies", ""); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the new token to work, got %d", recorder.Code)
+	}
+	recorder = admin.do(previous, http.MethodGet, "/admin/policies", "")
+	if time.Since(rotated) < grace && recorder.Code != http.StatusOK {
+		t.Errorf("Expected the previous token to work within the grace period, got %d", recorder.Code)
+	}
+
+	time.Sleep(time.Until(rotated.Add(grace + 50*time.Millisecond)))
+	if recorder := admin.do(previous, http.MethodGet, "/admin/policies", ""); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the previous token to fail after the grace period, got %d", recorder.Code)
+	}
+	if recorder := admin.do(issued.Token, http.MethodGet, "/admin/policies", ""); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the new token to keep working, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+
+	"dynamiccontrol/internal/listquery"
+	"dynamiccontrol/internal/tenancy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tenantResources are the admin resources available to service accounts of a
+// tenant, limited to the tenant's routes, policies and schemas
+var tenantResources = map[string]bool{
+	"routes":   true,
+	"policies": true,
+	"schemas":  true,
+	"weights":  true,
+}
+
+// TenantSummary describes a tenant in admin listings
+type TenantSummary struct {
+	Name   string   `json:"name"`
+	Hosts  []string `json:"hosts,omitempty"`
+	Routes int      `json:"routes"`
+}
+
+// listTenants lists the declared tenants with their hosts and route counts
+func (h *Handler) listTenants(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	config := h.routeManager.GetConfig()
+	if config == nil {
+		respondList(c, nil, "name")
+		return
+	}
+	routes := make(map[string]int)
+	for _, route := range config.Routes {
+		routes[route.Tenant]++
+	}
+	tenants := make([]TenantSummary, 0, len(config.Tenants))
+	for _, tenant := range config.Tenants {
+		tenants = append(tenants, TenantSummary{Name: tenant.Name, Hosts: tenant.Hosts, Routes: routes[tenant.Name]})
+	}
+
+	items, err := listquery.ToItems(tenants)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondList(c, items, "name")
+}
+
+// accountTenant returns the tenant the service account making a request is
+// confined to, or "" for unconfined callers
+func accountTenant(c *gin.Context) string {
+	account, _ := serviceAccount(c)
+	return account.Tenant
+}
+
+// requestTenant returns the tenant an admin request targets: the tenant of a
+// tenant service account, or the tenant the request names otherwise
+func requestTenant(c *gin.Context, named string) string {
+	if tenant := accountTenant(c); tenant != "" {
+		return tenant
+	}
+	return named
+}
+
+// tenants returns the resolver of the declared tenants
+func (h *Handler) tenants() *tenancy.Resolver {
+	if h.routeManager != nil {
+		return h.routeManager.Tenants()
+	}
+	resolver, _ := tenancy.NewResolver(nil, "")
+	return resolver
+}
+
+// visibleName reports whether the caller may read a policy or schema: tenant
+// accounts see their own names and shared ones
+func (h *Handler) visibleName(c *gin.Context, name string) bool {
+	return h.tenants().Visible(accountTenant(c), name)
+}
+
+// requireOwnedName writes an error response when a tenant account changes a
+// policy or schema its tenant does not own
+func (h *Handler) requireOwnedName(c *gin.Context, name string) bool {
+	tenant := accountTenant(c)
+	if tenant == "" || h.tenants().Owner(name) == tenant {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":   "Action not permitted",
+		"details": fmt.Sprintf("names of tenant %s start with %s_", tenant, tenant),
+	})
+	return false
+}
@@ -0,0 +1,175 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"dynamiccontrol/internal/accounts"
+	"dynamiccontrol/internal/types"
+)
+
+// tenantRoutes declares the acme and globex tenants with a route each and a
+// shared route
+var tenantRoutes = &types.RoutesConfig{
+	Tenants: []types.TenantConfig{
+		{Name: "acme", Hosts: []string{"acme.example.com"}},
+		{Name: "globex", Hosts: []string{"globex.example.com"}},
+	},
+	Routes: []types.RouteConfig{
+		{RouteName: "/v1/orders", Method: "GET", Tenant: "acme", Policies: []string{"allow_all"}},
+		{RouteName: "/v1/invoices", Method: "GET", Tenant: "globex", Policies: []string{"allow_all"}},
+		{RouteName: "/v1/status", Method: "GET", Policies: []string{"allow_all"}},
+	},
+}
+
+// tenantScopes grant a tenant account every tenant resource
+var tenantScopes = []accounts.Scope{{Actions: []string{"routes:*", "policies:*", "schemas:*", "weights:*"}}}
+
+// newTenantAdmin returns an admin API with tokens of an acme and a globex
+// account
+func newTenantAdmin(t *testing.T) (*testAdmin, string, string) {
+	t.Helper()
+	admin := newTestAdmin(t, tenantRoutes)
+	acme := admin.issue(t, accounts.Account{Name: "acme-deploy", Tenant: "acme", Scopes: tenantScopes})
+	globex := admin.issue(t, accounts.Account{Name: "globex-deploy", Tenant: "globex", Scopes: tenantScopes})
+	return admin, acme, globex
+}
+
+func TestTenantAccountsChangeOwnPolicies(t *testing.T) {
+	admin, acme, globex := newTenantAdmin(t)
+
+	for _, tc := range []struct {
+		token, name string
+		expected    int
+	}{
+		{acme, "acme_orders", http.StatusOK},
+		{globex, "globex_invoices", http.StatusOK},
+		{acme, "globex_invoices", http.StatusForbidden},
+		{globex, "acme_orders", http.StatusForbidden},
+		{acme, "allow_all", http.StatusForbidden},
+	} {
+		source := "package " + tc.name + "\n\ndefault allow = false\n"
+		recorder := admin.do(tc.token, http.MethodPut, "/admin/policies/"+tc.name, source)
+		if recorder.Code != tc.expected {
+			t.Errorf("PUT policy %s: expected %d, got %d: %s", tc.name, tc.expected, recorder.Code, recorder.Body.String())
+		}
+	}
+	sources := admin.policies.PolicySources()
+	if sources["globex_invoices"] != "package globex_invoices\n\ndefault allow = false\n" {
+		t.Errorf("Expected acme not to overwrite a globex policy, got %q", sources["globex_invoices"])
+	}
+	if sources["allow_all"] != "package allow_all\n\ndefault allow = true\n" {
+		t.Errorf("Expected tenants not to overwrite shared policies, got %q", sources["allow_all"])
+	}
+
+	expected := map[string][]string{
+		acme:           {"acme_orders", "allow_all"},
+		globex:         {"allow_all", "globex_invoices"},
+		testAdminToken: {"acme_orders", "allow_all", "globex_invoices"},
+	}
+	for token, names := range expected {
+		recorder := admin.do(token, http.MethodGet, "/admin/policies", "")
+		if got := listNames(t, recorder, "name"); !reflect.DeepEqual(got, names) {
+			t.Errorf("Expected policies %v, got %v", names, got)
+		}
+	}
+}
+
+func TestTenantAccountsSeeOwnRoutes(t *testing.T) {
+	admin, acme, globex := newTenantAdmin(t)
+
+	if names := listNames(t, admin.do(acme, http.MethodGet, "/admin/routes", ""), "routeName"); !reflect.DeepEqual(names, []string{"/v1/orders"}) {
+		t.Errorf("Expected acme to list its own route, got %v", names)
+	}
+	if names := listNames(t, admin.do(globex, http.MethodGet, "/admin/routes", ""), "routeName"); !reflect.DeepEqual(names, []string{"/v1/invoices"}) {
+		t.Errorf("Expected globex to list its own route, got %v", names)
+	}
+	if names := listNames(t, admin.do(testAdminToken, http.MethodGet, "/admin/routes", ""), "routeName"); len(names) != 3 {
+		t.Errorf("Expected the admin token to list every route, got %v", names)
+	}
+
+	// Routes created by a tenant account belong to its tenant, whatever they name
+	recorder := admin.do(acme, http.MethodPut, "/admin/routes", `{"routeName": "/v1/refunds", "method": "POST", "tenant": "globex", "policies": ["allow_all"]}`)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Failed to create route: %d %s", recorder.Code, recorder.Body.String())
+	}
+	var created types.RouteConfig
+	if err := json.Unmarshal(recorder.Body.Bytes(), &created); err != nil || created.Tenant != "acme" {
+		t.Errorf("Expected the route to belong to acme, got %s", recorder.Body.String())
+	}
+	if names := listNames(t, admin.do(globex, http.MethodGet, "/admin/routes", ""), "routeName"); !reflect.DeepEqual(names, []string{"/v1/invoices"}) {
+		t.Errorf("Expected the acme route to stay hidden from globex, got %v", names)
+	}
+
+	recorder = admin.do(acme, http.MethodPut, "/admin/routes", `{"routeName": "/v1/payouts", "method": "POST", "policies": ["globex_invoices"]}`)
+	if recorder.Code == http.StatusCreated || recorder.Code == http.StatusOK {
+		t.Errorf("Expected acme not to use a globex policy, got %d", recorder.Code)
+	}
+}
+
+func TestTenantAccountsSeeOwnSchemas(t *testing.T) {
+	admin, acme, globex := newTenantAdmin(t)
+
+	schema := `{"type": "object"}`
+	for _, path := range []string{"/admin/schemas/acme_order/1", "/admin/schemas/globex_invoice/1", "/admin/schemas/address/1"} {
+		if recorder := admin.do(testAdminToken, http.MethodPut, path, schema); recorder.Code != http.StatusCreated {
+			t.Fatalf("Failed to register %s: %d %s", path, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	if names := listNames(t, admin.do(acme, http.MethodGet, "/admin/schemas", ""), "name"); !reflect.DeepEqual(names, []string{"acme_order", "address"}) {
+		t.Errorf("Expected acme to list its own and shared schemas, got %v", names)
+	}
+	if names := listNames(t, admin.do(globex, http.MethodGet, "/admin/schemas", ""), "name"); !reflect.DeepEqual(names, []string{"address", "globex_invoice"}) {
+		t.Errorf("Expected globex to list its own and shared schemas, got %v", names)
+	}
+
+	for _, tc := range []struct {
+		token, method, path string
+		expected            int
+	}{
+		{acme, http.MethodGet, "/admin/schemas/acme_order/1", http.StatusOK},
+		{acme, http.MethodGet, "/admin/schemas/address/latest", http.StatusOK},
+		{acme, http.MethodGet, "/admin/schemas/globex_invoice/1", http.StatusNotFound},
+		{globex, http.MethodGet, "/admin/schemas/acme_order/latest", http.StatusNotFound},
+		{acme, http.MethodPut, "/admin/schemas/acme_order/2", http.StatusCreated},
+		{acme, http.MethodPut, "/admin/schemas/globex_invoice/2", http.StatusForbidden},
+		{globex, http.MethodPut, "/admin/schemas/address/2", http.StatusForbidden},
+	} {
+		body := ""
+		if tc.method == http.MethodPut {
+			body = schema
+		}
+		if recorder := admin.do(tc.token, tc.method, tc.path, body); recorder.Code != tc.expected {
+			t.Errorf("%s %s: expected %d, got %d: %s", tc.method, tc.path, tc.expected, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+func TestListTenants(t *testing.T) {
+	admin, acme, _ := newTenantAdmin(t)
+
+	recorder := admin.do(testAdminToken, http.MethodGet, "/admin/tenants", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Failed to list tenants: %d %s", recorder.Code, recorder.Body.String())
+	}
+	var page struct {
+		Items []TenantSummary `json:"items"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to decode tenants: %v", err)
+	}
+	expected := []TenantSummary{
+		{Name: "acme", Hosts: []string{"acme.example.com"}, Routes: 1},
+		{Name: "globex", Hosts: []string{"globex.example.com"}, Routes: 1},
+	}
+	if !reflect.DeepEqual(page.Items, expected) {
+		t.Errorf("Expected tenants %+v, got %+v", expected, page.Items)
+	}
+
+	if recorder := admin.do(acme, http.MethodGet, "/admin/tenants", ""); recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected tenant accounts not to list tenants, got %d", recorder.Code)
+	}
+}
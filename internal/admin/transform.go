@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+
+	"dynamiccontrol/internal/transform"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlaygroundRequest is the payload accepted by the transform playground.
+// Either a mapping template with its input, or a mock response template
+// with its template data, must be provided.
+type PlaygroundRequest struct {
+	Template     interface{}            `json:"template,omitempty"`
+	Input        map[string]interface{} `json:"input,omitempty"`
+	MockTemplate string                 `json:"mockTemplate,omitempty"`
+	TemplateData transform.TemplateData `json:"templateData,omitempty"`
+}
+
+// PlaygroundResponse is the result of rendering a template in the playground
+type PlaygroundResponse struct {
+	Output interface{} `json:"output"`
+	Errors []string    `json:"errors,omitempty"`
+}
+
+// transformPlayground renders a mapping template against a sample input
+func (h *Handler) transformPlayground(c *gin.Context) {
+	var request PlaygroundRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid JSON: %v", err),
+		})
+		return
+	}
+
+	if request.MockTemplate != "" {
+		h.mockTemplatePlayground(c, request)
+		return
+	}
+
+	if request.Template == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "template or mockTemplate is required",
+		})
+		return
+	}
+
+	output, errs := transform.Apply(request.Template, request.Input)
+	response := PlaygroundResponse{Output: output}
+	for _, err := range errs {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// mockTemplatePlayground renders a mock response template against sample template data
+func (h *Handler) mockTemplatePlayground(c *gin.Context, request PlaygroundRequest) {
+	tmpl, err := transform.ParseTemplate("playground", request.MockTemplate)
+	if err != nil {
+		c.JSON(http.StatusOK, PlaygroundResponse{Errors: []string{err.Error()}})
+		return
+	}
+
+	output, err := transform.RenderTemplate(tmpl, request.TemplateData)
+	if err != nil {
+		c.JSON(http.StatusOK, PlaygroundResponse{Errors: []string{err.Error()}})
+		return
+	}
+
+	c.JSON(http.StatusOK, PlaygroundResponse{Output: output})
+}
@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"net/http"
+	"sort"
+
+	"dynamiccontrol/internal/listquery"
+	"dynamiccontrol/internal/router"
+	"dynamiccontrol/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// variablesRequest replaces the template variables of a route
+type variablesRequest struct {
+	Method    string                         `json:"method" binding:"required"`
+	Route     string                         `json:"route" binding:"required"`
+	Tenant    string                         `json:"tenant,omitempty"`
+	Host      string                         `json:"host,omitempty"`
+	Variables map[string]types.RouteVariable `json:"variables"`
+}
+
+// listVariables lists the template variables of every route, without the
+// values of secrets
+func (h *Handler) listVariables(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	items, err := listquery.ToItems(h.routeManager.ListRouteVariables())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondList(c, items, "route")
+}
+
+// setVariables replaces the template variables of a route. Templates see
+// the new values on their next render; an empty set removes them.
+func (h *Handler) setVariables(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	var request variablesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid variables request",
+			"details": err.Error(),
+		})
+		return
+	}
+	route, found := h.findRoute(requestTenant(c, request.Tenant), request.Host, request.Method, request.Route)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Route not found",
+		})
+		return
+	}
+
+	key := router.RouteKey(route)
+	if err := h.routeManager.SetRouteVariables(key, request.Variables); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to set variables",
+			"details": err.Error(),
+		})
+		return
+	}
+	// Values are left out of the audit log, since they may be secrets
+	names := make([]string, 0, len(request.Variables))
+	for name := range request.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h.recordAudit(c, "route.variables", key, map[string]interface{}{
+		"variables": names,
+	})
+	c.JSON(http.StatusOK, router.RouteVariables{Route: key, Variables: router.RedactVariables(request.Variables)})
+}
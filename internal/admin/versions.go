@@ -0,0 +1,132 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"dynamiccontrol/internal/audit"
+	"dynamiccontrol/internal/listquery"
+	"dynamiccontrol/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listVersions lists the applied configuration versions, newest first
+func (h *Handler) listVersions(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	items, err := listquery.ToItems(h.routeManager.GetHistory().List())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondList(c, items, "id")
+}
+
+// getVersion returns the routes and policies of a configuration version
+func (h *Handler) getVersion(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+	id, ok := versionParam(c)
+	if !ok {
+		return
+	}
+
+	version, exists := h.routeManager.GetHistory().Get(id)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Configuration version not found",
+			"details": c.Param("version"),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, version)
+}
+
+// rollbackConfig restores the routes and policies of a configuration version
+func (h *Handler) rollbackConfig(c *gin.Context) {
+	if !h.requireRouteManager(c) || !h.requirePolicyManager(c) {
+		return
+	}
+	id, ok := versionParam(c)
+	if !ok {
+		return
+	}
+	if _, exists := h.routeManager.GetHistory().Get(id); !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Configuration version not found",
+			"details": c.Param("version"),
+		})
+		return
+	}
+
+	previous, previousPolicies := h.routeManager.GetConfig(), h.policyManager.PolicySources()
+	version, err := h.routeManager.Rollback(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to roll back configuration",
+			"details": err.Error(),
+		})
+		return
+	}
+	h.recordAudit(c, "config.rollback", "routes", map[string]interface{}{
+		"version":  version.ID,
+		"revision": version.Revision,
+	}, audit.ConfigDiff(previous, h.routeManager.GetConfig(), previousPolicies, h.policyManager.PolicySources())...)
+
+	summaries := h.routeManager.GetHistory().List()
+	c.JSON(http.StatusOK, gin.H{
+		"restored": version.ID,
+		"current":  summaries[0],
+	})
+}
+
+// configDiffRequest carries a candidate configuration in the format of
+// snapshots and configuration versions; without policies the loaded
+// policies are kept
+type configDiffRequest struct {
+	Routes   *types.RoutesConfig `json:"routes" binding:"required"`
+	Policies map[string]string   `json:"policies,omitempty"`
+}
+
+// diffConfig reviews a candidate configuration against the active one
+// without applying it
+func (h *Handler) diffConfig(c *gin.Context) {
+	if !h.requireRouteManager(c) || !h.requirePolicyManager(c) {
+		return
+	}
+
+	var request configDiffRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid configuration diff request",
+			"details": err.Error(),
+		})
+		return
+	}
+	diff, err := h.routeManager.DiffConfig(request.Routes, request.Policies)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to diff configuration",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// versionParam parses the version path parameter
+func versionParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("version"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid configuration version",
+			"details": c.Param("version"),
+		})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,19 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getWatchdog runs a fresh resource check and returns its snapshot
+func (h *Handler) getWatchdog(c *gin.Context) {
+	if h.watchdog == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Watchdog is not enabled",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.watchdog.Check())
+}
@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"dynamiccontrol/internal/audit"
+	"dynamiccontrol/internal/guardrails"
+	"dynamiccontrol/internal/router"
+
+	"github.com/gin-gonic/gin"
+)
+
+// weightsRequest sets the upstream weights of a route, keyed by upstream
+// name or URL
+type weightsRequest struct {
+	Method  string         `json:"method" binding:"required"`
+	Route   string         `json:"route" binding:"required"`
+	Tenant  string         `json:"tenant,omitempty"`
+	Host    string         `json:"host,omitempty"`
+	Weights map[string]int `json:"weights" binding:"required"`
+}
+
+// setWeights changes the upstream weights of a route. Service accounts may
+// only change routes matching the route labels of their scopes.
+func (h *Handler) setWeights(c *gin.Context) {
+	if !h.requireRouteManager(c) {
+		return
+	}
+
+	var request weightsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid weights request",
+			"details": err.Error(),
+		})
+		return
+	}
+	route, found := h.findRoute(requestTenant(c, request.Tenant), request.Host, request.Method, request.Route)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Route not found",
+		})
+		return
+	}
+	if account, ok := serviceAccount(c); ok && !account.AllowsRoute(weightsAction, route.Labels) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Action not permitted",
+			"details": fmt.Sprintf("service account %s may not change weights of %s %s", account.Name, route.Method, route.RouteName),
+		})
+		return
+	}
+
+	updated, err := h.routeManager.SetRouteUpstreamWeights(router.RouteKey(route), request.Weights)
+	if err != nil {
+		var rejected *guardrails.Error
+		if errors.As(err, &rejected) {
+			respondGuardrailError(c, err)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to set weights",
+			"details": err.Error(),
+		})
+		return
+	}
+	h.recordAudit(c, "route.weights", router.RouteKey(route), map[string]interface{}{
+		"weights": request.Weights,
+	}, audit.Diff("/upstreams", route.Upstreams, updated.Upstreams)...)
+	c.JSON(http.StatusOK, updated)
+}
@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestConfigDiff(t *testing.T) {
+	before := &types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/items", Method: "GET", Policies: []string{"items"}},
+		{RouteName: "/v1/legacy", Method: "GET"},
+	}}
+	after := &types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/items", Method: "GET", Policies: []string{"items_v2"}},
+	}}
+	changes := ConfigDiff(before, after,
+		map[string]string{"items": "package items\n\ndefault allow = false\n"},
+		map[string]string{"items": "package items\n\ndefault allow = true\n"})
+
+	if len(changes) != 3 {
+		t.Fatalf("Expected three changes, got %+v", changes)
+	}
+	if changes[0].Path != "/routes/GET ~1v1~1items/policies/0" || changes[0].Op != OpReplace || changes[0].After != "items_v2" {
+		t.Errorf("Unexpected policy reference change %+v", changes[0])
+	}
+	if changes[1].Path != "/routes/GET ~1v1~1legacy" || changes[1].Op != OpRemove {
+		t.Errorf("Unexpected route removal %+v", changes[1])
+	}
+	expected := "@@ -1,3 +1,3 @@\n package items\n \n-default allow = false\n+default allow = true\n"
+	if changes[2].Path != "/policies/items" || changes[2].Patch != expected {
+		t.Errorf("Unexpected policy change %+v", changes[2])
+	}
+}
+
+func TestCompareConfigs(t *testing.T) {
+	schema := func(maximum int) map[string]interface{} {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+			"limit": map[string]interface{}{"type": "integer", "maximum": maximum},
+		}}
+	}
+	before := &types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/items", Method: "GET", Policies: []string{"items", "audit"}, RequestSchema: schema(10)},
+		{RouteName: "/v1/orders", Method: "GET", Policies: []string{"orders"}},
+		{RouteName: "/v1/legacy", Method: "GET", Policies: []string{"legacy"}},
+		{RouteName: "/v1/status", Method: "GET", Handler: "mock"},
+	}}
+	after := &types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/items", Method: "GET", Policies: []string{"items_v2", "audit"}, RequestSchema: schema(100), Handler: types.HandlerProxy},
+		{RouteName: "/v1/orders", Method: "GET", Policies: []string{"orders"}},
+		{RouteName: "/v1/orders", Method: "GET", Host: "api.example.com", Policies: []string{"orders", "partners"}},
+		{RouteName: "/v1/status", Method: "GET", Handler: "mock"},
+	}}
+	policies := map[string]string{"items": "package items\n", "audit": "package audit\n", "orders": "package orders\n"}
+	candidatePolicies := map[string]string{"items_v2": "package items_v2\n", "audit": "package audit\n", "orders": "package orders\n"}
+
+	diff := CompareConfigs(before, after, policies, candidatePolicies)
+	if len(diff.Added) != 1 || diff.Added[0].Route != "GET api.example.com/v1/orders" {
+		t.Errorf("Expected the host route to be added, got %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Route != "GET /v1/legacy" || diff.Removed[0].PoliciesRemoved[0] != "legacy" {
+		t.Errorf("Expected the legacy route to be removed, got %+v", diff.Removed)
+	}
+	if diff.Unchanged != 2 {
+		t.Errorf("Expected two unchanged routes, got %d", diff.Unchanged)
+	}
+	if len(diff.Changed) != 1 {
+		t.Fatalf("Expected one changed route, got %+v", diff.Changed)
+	}
+
+	items := diff.Changed[0]
+	if strings.Join(items.PoliciesAdded, ",") != "items_v2" || strings.Join(items.PoliciesRemoved, ",") != "items" {
+		t.Errorf("Unexpected policy references %+v", items)
+	}
+	if len(items.SchemaChanges) != 1 || items.SchemaChanges[0].Path != "/requestSchema/properties/limit/maximum" {
+		t.Errorf("Unexpected schema changes %+v", items.SchemaChanges)
+	}
+	if len(items.Changes) != 1 || items.Changes[0].Path != "/handler" {
+		t.Errorf("Unexpected changes %+v", items.Changes)
+	}
+
+	if len(diff.Policies) != 2 || diff.Policies[0].Path != "/policies/items" || diff.Policies[1].Path != "/policies/items_v2" {
+		t.Errorf("Unexpected policy source changes %+v", diff.Policies)
+	}
+	if strings.Join(diff.UnknownPolicies, ",") != "partners" {
+		t.Errorf("Expected partners to be unknown, got %v", diff.UnknownPolicies)
+	}
+}
+
+func TestLogWritesToSinks(t *testing.T) {
+	var mu sync.Mutex
+	var received []types.AuditEntry
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var entry types.AuditEntry
+		json.NewDecoder(r.Body).Decode(&entry)
+		mu.Lock()
+		received = append(received, entry)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	fileSink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("Failed to open file sink: %v", err)
+	}
+	defer fileSink.Close()
+
+	log := NewLog(1)
+	log.AddSink(fileSink)
+	log.AddSink(NewHTTPSink(server.URL, "s3cret"))
+	log.Record(types.AuditEntry{Actor: "admin/127.0.0.1", Action: "policy.upload", Resource: "items"})
+	log.Record(types.AuditEntry{Actor: "admin/127.0.0.1", Action: "data.set", Resource: "limits", Outcome: OutcomeFailure, Status: 400})
+	log.Close()
+
+	if entries := log.List(); len(entries) != 1 || entries[0].Action != "data.set" {
+		t.Errorf("Expected only the latest entry in memory, got %+v", entries)
+	}
+	file, _ := os.Open(path)
+	defer file.Close()
+	var lines []types.AuditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry types.AuditEntry
+		json.Unmarshal(scanner.Bytes(), &entry)
+		lines = append(lines, entry)
+	}
+	if len(lines) != 2 || lines[0].Outcome != OutcomeSuccess || lines[1].Outcome != OutcomeFailure || lines[0].ID == "" {
+		t.Errorf("Expected both entries appended in order, got %+v", lines)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[1].Status != 400 {
+		t.Errorf("Expected both entries posted, got %+v", received)
+	}
+}
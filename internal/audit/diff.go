@@ -0,0 +1,350 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Change operations
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+)
+
+// maxDiffCells bounds the line comparison of a text diff; larger texts are
+// summarized by their line counts
+const maxDiffCells = 4_000_000
+
+// diffContext is the number of unchanged lines around each hunk of a text diff
+const diffContext = 2
+
+// Diff lists the changes between two values by JSON Pointer, below prefix.
+// Objects are compared by key and arrays by index, so callers should key
+// collections by a stable identifier.
+func Diff(prefix string, before, after interface{}) []types.AuditChange {
+	var changes []types.AuditChange
+	diffValues(prefix, normalize(before), normalize(after), &changes)
+	return changes
+}
+
+// normalize converts a value to its generic JSON representation
+func normalize(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	var generic interface{}
+	json.Unmarshal(data, &generic)
+	return generic
+}
+
+// diffValues appends the changes between two generic JSON values
+func diffValues(path string, before, after interface{}, changes *[]types.AuditChange) {
+	switch {
+	case before == nil && after == nil:
+		return
+	case before == nil:
+		*changes = append(*changes, types.AuditChange{Path: path, Op: OpAdd, After: after})
+		return
+	case after == nil:
+		*changes = append(*changes, types.AuditChange{Path: path, Op: OpRemove, Before: before})
+		return
+	}
+
+	beforeObject, beforeIsObject := before.(map[string]interface{})
+	afterObject, afterIsObject := after.(map[string]interface{})
+	if beforeIsObject && afterIsObject {
+		keys := make(map[string]bool, len(beforeObject)+len(afterObject))
+		for key := range beforeObject {
+			keys[key] = true
+		}
+		for key := range afterObject {
+			keys[key] = true
+		}
+		for _, key := range sortedKeys(keys) {
+			diffValues(path+"/"+escapePointer(key), beforeObject[key], afterObject[key], changes)
+		}
+		return
+	}
+
+	beforeArray, beforeIsArray := before.([]interface{})
+	afterArray, afterIsArray := after.([]interface{})
+	if beforeIsArray && afterIsArray {
+		for i := 0; i < len(beforeArray) || i < len(afterArray); i++ {
+			var b, a interface{}
+			if i < len(beforeArray) {
+				b = beforeArray[i]
+			}
+			if i < len(afterArray) {
+				a = afterArray[i]
+			}
+			diffValues(fmt.Sprintf("%s/%d", path, i), b, a, changes)
+		}
+		return
+	}
+
+	if !equalJSON(before, after) {
+		*changes = append(*changes, types.AuditChange{Path: path, Op: OpReplace, Before: before, After: after})
+	}
+}
+
+// equalJSON reports whether two generic JSON scalars or mixed values are equal
+func equalJSON(a, b interface{}) bool {
+	left, _ := json.Marshal(a)
+	right, _ := json.Marshal(b)
+	return string(left) == string(right)
+}
+
+// escapePointer escapes a key as a JSON Pointer reference token
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// sortedKeys returns the keys of a set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TextChange describes the change of a text document, such as a policy
+// source, as a unified diff. It returns false when the texts are equal.
+func TextChange(path, before, after string) (types.AuditChange, bool) {
+	if before == after {
+		return types.AuditChange{}, false
+	}
+	change := types.AuditChange{Path: path, Op: OpReplace, Patch: LineDiff(before, after)}
+	switch {
+	case before == "":
+		change.Op = OpAdd
+	case after == "":
+		change.Op = OpRemove
+	}
+	return change, true
+}
+
+// ConfigDiff lists the changes between two configurations: routes by
+// route key, such as "METHOD /path", below /routes, and policy sources by
+// name below /policies
+func ConfigDiff(before, after *types.RoutesConfig, beforePolicies, afterPolicies map[string]string) []types.AuditChange {
+	changes := Diff("/routes", indexRoutes(before), indexRoutes(after))
+	names := make(map[string]bool, len(beforePolicies)+len(afterPolicies))
+	for name := range beforePolicies {
+		names[name] = true
+	}
+	for name := range afterPolicies {
+		names[name] = true
+	}
+	for _, name := range sortedKeys(names) {
+		if change, changed := TextChange("/policies/"+escapePointer(name), beforePolicies[name], afterPolicies[name]); changed {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// schemaFields are the route fields whose changes are schema changes
+var schemaFields = map[string]bool{
+	"requestSchema":          true,
+	"responseSchema":         true,
+	"querySchema":            true,
+	"headerSchema":           true,
+	"requestSchemaRef":       true,
+	"responseSchemaRef":      true,
+	"candidateRequestSchema": true,
+}
+
+// CompareConfigs reviews a candidate configuration against the active one.
+// Routes are matched by key, so a route whose method, host or tenant changes
+// is reported as removed and added.
+func CompareConfigs(before, after *types.RoutesConfig, beforePolicies, afterPolicies map[string]string) types.ConfigDiff {
+	diff := types.ConfigDiff{Added: []types.RouteDiff{}, Removed: []types.RouteDiff{}, Changed: []types.RouteDiff{}}
+	beforeRoutes, afterRoutes := indexRoutes(before), indexRoutes(after)
+	keys := make(map[string]bool, len(beforeRoutes)+len(afterRoutes))
+	for key := range beforeRoutes {
+		keys[key] = true
+	}
+	for key := range afterRoutes {
+		keys[key] = true
+	}
+
+	unknown := make(map[string]bool)
+	for _, key := range sortedKeys(keys) {
+		previous, existed := beforeRoutes[key]
+		route, exists := afterRoutes[key]
+		for _, policy := range route.Policies {
+			if _, defined := afterPolicies[policy]; !defined {
+				unknown[policy] = true
+			}
+		}
+
+		switch {
+		case !existed:
+			diff.Added = append(diff.Added, types.RouteDiff{Route: key, PoliciesAdded: route.Policies})
+		case !exists:
+			diff.Removed = append(diff.Removed, types.RouteDiff{Route: key, PoliciesRemoved: previous.Policies})
+		default:
+			routeDiff := types.RouteDiff{Route: key}
+			routeDiff.PoliciesAdded = missingFrom(route.Policies, previous.Policies)
+			routeDiff.PoliciesRemoved = missingFrom(previous.Policies, route.Policies)
+			// Reordering policies changes the route but no reference
+			reordered := routeDiff.PoliciesAdded == nil && routeDiff.PoliciesRemoved == nil
+			changes := Diff("", previous, route)
+			for _, change := range changes {
+				field, _, _ := strings.Cut(strings.TrimPrefix(change.Path, "/"), "/")
+				switch {
+				case schemaFields[field]:
+					routeDiff.SchemaChanges = append(routeDiff.SchemaChanges, change)
+				case field != "policies" || reordered:
+					routeDiff.Changes = append(routeDiff.Changes, change)
+				}
+			}
+			if len(changes) == 0 {
+				diff.Unchanged++
+				continue
+			}
+			diff.Changed = append(diff.Changed, routeDiff)
+		}
+	}
+
+	diff.Policies = ConfigDiff(nil, nil, beforePolicies, afterPolicies)
+	if len(unknown) > 0 {
+		diff.UnknownPolicies = sortedKeys(unknown)
+	}
+	return diff
+}
+
+// missingFrom returns the values of items that are not in others
+func missingFrom(items, others []string) []string {
+	var missing []string
+	for _, item := range items {
+		found := false
+		for _, other := range others {
+			if item == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, item)
+		}
+	}
+	return missing
+}
+
+// indexRoutes keys the routes of a configuration by method, host and path,
+// prefixed with the tenant of tenant routes
+func indexRoutes(config *types.RoutesConfig) map[string]types.RouteConfig {
+	routes := make(map[string]types.RouteConfig)
+	if config != nil {
+		for _, route := range config.Routes {
+			key := route.Method + " " + route.Host + route.RouteName
+			if route.Tenant != "" {
+				key = route.Tenant + ":" + key
+			}
+			routes[key] = route
+		}
+	}
+	return routes
+}
+
+// LineDiff returns a unified diff of two texts, with hunks of changed lines
+// surrounded by a few unchanged ones
+func LineDiff(before, after string) string {
+	a, b := splitLines(before), splitLines(after)
+	if len(a)*len(b) > maxDiffCells {
+		return fmt.Sprintf("@@ -1,%d +1,%d @@ (too large to compare)\n", len(a), len(b))
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		kind byte
+		text string
+		// a and b are the line indexes in each text before this line
+		a, b int
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', a[i], i, j})
+			i++
+		default:
+			lines = append(lines, line{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	var out strings.Builder
+	for start := 0; start < len(lines); {
+		if lines[start].kind == ' ' {
+			start++
+			continue
+		}
+		// Extend the hunk while changes are within twice the context
+		from := max(start-diffContext, 0)
+		end := start
+		for k := start; k < len(lines) && k <= end+2*diffContext; k++ {
+			if lines[k].kind != ' ' {
+				end = k
+			}
+		}
+		to := min(end+diffContext+1, len(lines))
+
+		oldCount, newCount := 0, 0
+		for _, l := range lines[from:to] {
+			if l.kind != '+' {
+				oldCount++
+			}
+			if l.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", lines[from].a+1, oldCount, lines[from].b+1, newCount)
+		for _, l := range lines[from:to] {
+			out.WriteByte(l.kind)
+			out.WriteString(l.text)
+			out.WriteByte('\n')
+		}
+		start = to
+	}
+	return out.String()
+}
+
+// splitLines splits a text into lines without their terminators
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dynamiccontrol/internal/types"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultCapacity is the number of audit entries kept in memory
+const DefaultCapacity = 1000
+
+// sinkQueueSize bounds the entries waiting for delivery to the sinks;
+// recording blocks while the queue is full, so entries are never dropped
+const sinkQueueSize = 1000
+
+// Outcomes of audited operations
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+var sinkFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "dynamiccontrol_audit_sink_failures_total",
+	Help: "Audit entries that could not be written to a sink",
+}, []string{"sink"})
+
+func init() {
+	prometheus.MustRegister(sinkFailures)
+}
+
+// Log keeps the most recent configuration changes in a fixed-size ring
+// buffer and forwards every entry, in order, to its sinks
+type Log struct {
+	mu       sync.RWMutex
+	entries  []types.AuditEntry
+	next     int
+	full     bool
+	sequence uint64
+
+	sinksMu sync.RWMutex
+	sinks   []Sink
+	// queueMu guards sending to the queue, which blocks while it is full
+	queueMu sync.Mutex
+	queue   chan types.AuditEntry
+	done    chan struct{}
+	closed  bool
+}
+
+// NewLog creates an audit log holding up to capacity entries
+func NewLog(capacity int) *Log {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Log{entries: make([]types.AuditEntry, capacity)}
+}
+
+// AddSink forwards entries recorded from now on to sink
+func (l *Log) AddSink(sink Sink) {
+	l.sinksMu.Lock()
+	l.sinks = append(l.sinks, sink)
+	l.sinksMu.Unlock()
+
+	l.queueMu.Lock()
+	defer l.queueMu.Unlock()
+	if l.queue == nil {
+		l.queue = make(chan types.AuditEntry, sinkQueueSize)
+		l.done = make(chan struct{})
+		go l.deliver()
+	}
+}
+
+// Record adds an audit entry, evicting the oldest one when the log is full.
+// The ID and timestamp are assigned, and the outcome defaults to success.
+func (l *Log) Record(entry types.AuditEntry) types.AuditEntry {
+	now := time.Now().UTC()
+	entry.ID = fmt.Sprintf("aud-%d-%d", now.UnixNano(), atomic.AddUint64(&l.sequence, 1))
+	entry.Timestamp = now
+	if entry.Outcome == "" {
+		entry.Outcome = OutcomeSuccess
+	}
+	slog.Info("Audit", "actor", entry.Actor, "action", entry.Action, "resource", entry.Resource, "outcome", entry.Outcome)
+
+	l.mu.Lock()
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+	l.mu.Unlock()
+
+	l.queueMu.Lock()
+	defer l.queueMu.Unlock()
+	if l.queue != nil && !l.closed {
+		l.queue <- entry
+	}
+	return entry
+}
+
+// deliver writes queued entries to every sink until the log is closed
+func (l *Log) deliver() {
+	defer close(l.done)
+	for entry := range l.queue {
+		l.sinksMu.RLock()
+		sinks := l.sinks
+		l.sinksMu.RUnlock()
+		for _, sink := range sinks {
+			if err := sink.Write(entry); err != nil {
+				sinkFailures.WithLabelValues(sink.Name()).Inc()
+				slog.Error("Failed to write audit entry", "sink", sink.Name(), "id", entry.ID, "error", err)
+			}
+		}
+	}
+}
+
+// Close delivers the queued entries to the sinks and stops forwarding
+func (l *Log) Close() {
+	l.queueMu.Lock()
+	if l.queue == nil || l.closed {
+		l.queueMu.Unlock()
+		return
+	}
+	l.closed = true
+	close(l.queue)
+	l.queueMu.Unlock()
+	<-l.done
+}
+
+// List returns the recorded entries, oldest first
+func (l *Log) List() []types.AuditEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if !l.full {
+		return append([]types.AuditEntry{}, l.entries[:l.next]...)
+	}
+	return append(append([]types.AuditEntry{}, l.entries[l.next:]...), l.entries[:l.next]...)
+}
@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// HTTP sink delivery settings
+const (
+	sinkAttempts = 3
+	sinkTimeout  = 5 * time.Second
+)
+
+// Sink persists audit entries outside the process
+type Sink interface {
+	Name() string
+	Write(entry types.AuditEntry) error
+}
+
+// FileSink appends audit entries to a file as newline-delimited JSON. The
+// file is opened append-only and synced after every entry.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens path for appending, creating it when missing
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Name identifies the sink in logs and metrics
+func (fs *FileSink) Name() string {
+	return "file"
+}
+
+// Write appends an entry
+func (fs *FileSink) Write(entry types.AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, err := fs.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return fs.file.Sync()
+}
+
+// Close closes the file
+func (fs *FileSink) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.file.Close()
+}
+
+// HTTPSink posts every audit entry as JSON to a URL, retrying failures
+type HTTPSink struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHTTPSink creates a sink posting to url, with token as a bearer token
+// when not empty
+func NewHTTPSink(url, token string) *HTTPSink {
+	return &HTTPSink{url: url, token: token, client: &http.Client{Timeout: sinkTimeout}}
+}
+
+// Name identifies the sink in logs and metrics
+func (hs *HTTPSink) Name() string {
+	return "http"
+}
+
+// Write posts an entry. Receivers should dedupe by entry ID, as a retried
+// entry may be delivered twice.
+func (hs *HTTPSink) Write(entry types.AuditEntry) error {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= sinkAttempts; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, hs.url, bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("failed to build audit request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if hs.token != "" {
+			req.Header.Set("Authorization", "Bearer "+hs.token)
+		}
+
+		resp, err := hs.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < http.StatusBadRequest {
+				return nil
+			}
+			err = fmt.Errorf("audit sink returned status %d", resp.StatusCode)
+		}
+		lastErr = err
+		if attempt < sinkAttempts {
+			time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
+		}
+	}
+	return lastErr
+}
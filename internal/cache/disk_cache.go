@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// DiskCache stores parsed artifacts, such as policy modules, on disk keyed by
+// content hash
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache creates a disk cache rooted at dir, creating the directory if needed
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+// Get returns the cached artifact of the given kind and hash
+func (dc *DiskCache) Get(kind, hash string) ([]byte, bool) {
+	data, err := ioutil.ReadFile(dc.path(kind, hash))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Put stores an artifact, writing through a temporary file so readers never see partial data
+func (dc *DiskCache) Put(kind, hash string, data []byte) error {
+	path := dc.path(kind, hash)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store cache file: %w", err)
+	}
+	return nil
+}
+
+// path returns the location of an artifact inside the cache directory
+func (dc *DiskCache) path(kind, hash string) string {
+	return filepath.Join(dc.dir, kind, hash+".json")
+}
+
+// ContentHash returns the hex SHA-256 of the given parts
+func ContentHash(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
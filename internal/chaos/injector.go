@@ -0,0 +1,184 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/metrics"
+)
+
+// Kind identifies a control plane failure mode
+type Kind string
+
+// Supported failure modes
+const (
+	PolicyStoreOutage    Kind = "policy-store-outage"
+	SlowSchemaValidation Kind = "slow-schema-validation"
+	UpstreamDNSFailure   Kind = "upstream-dns-failure"
+)
+
+// DefaultDuration bounds how long a fault stays active when no duration is given,
+// so a forgotten experiment cannot outlive the rehearsal
+const DefaultDuration = 5 * time.Minute
+
+// Kinds lists every supported failure mode
+var Kinds = []Kind{PolicyStoreOutage, SlowSchemaValidation, UpstreamDNSFailure}
+
+// Fault describes an active failure mode
+type Fault struct {
+	Kind       Kind      `json:"kind"`
+	Percentage float64   `json:"percentage"`
+	DelayMs    int       `json:"delayMs,omitempty"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// Injector holds the faults injected into the control plane itself. A nil
+// injector never triggers, so hooks can be wired unconditionally.
+type Injector struct {
+	mu     sync.RWMutex
+	faults map[Kind]Fault
+	now    func() time.Time
+}
+
+// NewInjector creates an injector with no active faults
+func NewInjector() *Injector {
+	return &Injector{
+		faults: make(map[Kind]Fault),
+		now:    time.Now,
+	}
+}
+
+// Enable activates a fault for the given duration, replacing any active
+// fault of the same kind
+func (i *Injector) Enable(fault Fault, duration time.Duration) (Fault, error) {
+	if !validKind(fault.Kind) {
+		return Fault{}, fmt.Errorf("unknown fault kind %q", fault.Kind)
+	}
+	if fault.Percentage == 0 {
+		fault.Percentage = 100
+	}
+	if fault.Percentage < 0 || fault.Percentage > 100 {
+		return Fault{}, fmt.Errorf("fault percentage must be between 0 and 100")
+	}
+	if fault.DelayMs < 0 {
+		return Fault{}, fmt.Errorf("fault delay must not be negative")
+	}
+	if fault.Kind == SlowSchemaValidation && fault.DelayMs == 0 {
+		return Fault{}, fmt.Errorf("%s requires delayMs", SlowSchemaValidation)
+	}
+	if duration <= 0 {
+		duration = DefaultDuration
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	fault.ExpiresAt = i.now().Add(duration)
+	i.faults[fault.Kind] = fault
+	metrics.ChaosFaultActive.WithLabelValues(string(fault.Kind)).Set(1)
+	return fault, nil
+}
+
+// Disable deactivates a fault and reports whether it was active
+func (i *Injector) Disable(kind Kind) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	_, exists := i.faults[kind]
+	delete(i.faults, kind)
+	metrics.ChaosFaultActive.WithLabelValues(string(kind)).Set(0)
+	return exists
+}
+
+// Faults returns the active faults ordered by kind
+func (i *Injector) Faults() []Fault {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	faults := make([]Fault, 0, len(i.faults))
+	now := i.now()
+	for _, fault := range i.faults {
+		if now.Before(fault.ExpiresAt) {
+			faults = append(faults, fault)
+		}
+	}
+	sort.Slice(faults, func(a, b int) bool { return faults[a].Kind < faults[b].Kind })
+	return faults
+}
+
+// Trigger reports whether a fault of the given kind fires for this call
+func (i *Injector) Trigger(kind Kind) (Fault, bool) {
+	if i == nil {
+		return Fault{}, false
+	}
+
+	i.mu.RLock()
+	fault, exists := i.faults[kind]
+	i.mu.RUnlock()
+	if !exists {
+		return Fault{}, false
+	}
+	if !i.now().Before(fault.ExpiresAt) {
+		i.mu.Lock()
+		if current, ok := i.faults[kind]; ok && current.ExpiresAt.Equal(fault.ExpiresAt) {
+			delete(i.faults, kind)
+			metrics.ChaosFaultActive.WithLabelValues(string(kind)).Set(0)
+		}
+		i.mu.Unlock()
+		return Fault{}, false
+	}
+	if fault.Percentage < 100 && rand.Float64()*100 >= fault.Percentage {
+		return Fault{}, false
+	}
+
+	metrics.ChaosFaultsInjected.WithLabelValues(string(kind)).Inc()
+	return fault, true
+}
+
+// Delay sleeps for the fault's delay when a fault of the given kind fires,
+// returning early when ctx is done
+func (i *Injector) Delay(ctx context.Context, kind Kind) {
+	fault, ok := i.Trigger(kind)
+	if !ok || fault.DelayMs <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(time.Duration(fault.DelayMs) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// DialContext wraps a dialer so upstream connections fail name resolution
+// while the upstream DNS fault is active
+func (i *Injector) DialContext(next func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if _, ok := i.Trigger(UpstreamDNSFailure); ok {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				host = addr
+			}
+			return nil, &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{
+				Err:        "no such host (injected)",
+				Name:       host,
+				IsNotFound: true,
+			}}
+		}
+		return next(ctx, network, addr)
+	}
+}
+
+// validKind reports whether kind is a supported failure mode
+func validKind(kind Kind) bool {
+	for _, known := range Kinds {
+		if kind == known {
+			return true
+		}
+	}
+	return false
+}
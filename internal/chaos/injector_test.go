@@ -0,0 +1,109 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestEnableValidatesFaults(t *testing.T) {
+	injector := NewInjector()
+
+	if _, err := injector.Enable(Fault{Kind: "disk-full"}, 0); err == nil {
+		t.Error("Expected unknown kind to be rejected")
+	}
+	if _, err := injector.Enable(Fault{Kind: SlowSchemaValidation}, 0); err == nil {
+		t.Error("Expected slow schema validation without delay to be rejected")
+	}
+	fault, err := injector.Enable(Fault{Kind: PolicyStoreOutage}, 0)
+	if err != nil {
+		t.Fatalf("Enable failed: %v", err)
+	}
+	if fault.Percentage != 100 {
+		t.Errorf("Expected default percentage 100, got %v", fault.Percentage)
+	}
+}
+
+func TestFaultsExpire(t *testing.T) {
+	injector := NewInjector()
+	now := time.Now()
+	injector.now = func() time.Time { return now }
+
+	injector.Enable(Fault{Kind: PolicyStoreOutage}, time.Minute)
+	if _, ok := injector.Trigger(PolicyStoreOutage); !ok {
+		t.Fatal("Expected active fault to trigger")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := injector.Trigger(PolicyStoreOutage); ok {
+		t.Error("Expected expired fault not to trigger")
+	}
+	if len(injector.Faults()) != 0 {
+		t.Errorf("Expected no active faults, got %v", injector.Faults())
+	}
+}
+
+func TestNilInjectorNeverTriggers(t *testing.T) {
+	var injector *Injector
+	if _, ok := injector.Trigger(UpstreamDNSFailure); ok {
+		t.Error("Expected nil injector not to trigger")
+	}
+	injector.Delay(context.Background(), SlowSchemaValidation)
+}
+
+func TestDialContextSimulatesDNSFailure(t *testing.T) {
+	injector := NewInjector()
+	dialed := false
+	dial := injector.DialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = true
+		return nil, errors.New("dialed")
+	})
+
+	injector.Enable(Fault{Kind: UpstreamDNSFailure}, 0)
+	_, err := dial(context.Background(), "tcp", "backend.internal:80")
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || dnsErr.Name != "backend.internal" {
+		t.Errorf("Expected DNS error for backend.internal, got %v", err)
+	}
+	if dialed {
+		t.Error("Expected dial to be skipped while the fault is active")
+	}
+
+	injector.Disable(UpstreamDNSFailure)
+	dial(context.Background(), "tcp", "backend.internal:80")
+	if !dialed {
+		t.Error("Expected dial after the fault was disabled")
+	}
+}
+
+type staticStore struct{}
+
+func (staticStore) Name() string { return "static" }
+func (staticStore) LoadRoutes(ctx context.Context) (*types.RoutesConfig, error) {
+	return &types.RoutesConfig{}, nil
+}
+func (staticStore) LoadPolicies(ctx context.Context) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+func (staticStore) Watch(ctx context.Context, onChange func()) error { return nil }
+
+func TestWrapStoreSimulatesOutage(t *testing.T) {
+	injector := NewInjector()
+	store := WrapStore(staticStore{}, injector)
+
+	if _, err := store.LoadPolicies(context.Background()); err != nil {
+		t.Fatalf("Expected load to succeed, got %v", err)
+	}
+
+	injector.Enable(Fault{Kind: PolicyStoreOutage}, 0)
+	if _, err := store.LoadPolicies(context.Background()); err == nil {
+		t.Error("Expected policy load to fail during outage")
+	}
+	if _, err := store.LoadRoutes(context.Background()); err == nil {
+		t.Error("Expected route load to fail during outage")
+	}
+}
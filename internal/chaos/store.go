@@ -0,0 +1,50 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+
+	"dynamiccontrol/internal/configstore"
+	"dynamiccontrol/internal/types"
+)
+
+// store fails loads from the wrapped config store while the policy store
+// outage fault is active
+type store struct {
+	configstore.ConfigStore
+	injector *Injector
+}
+
+// WrapStore returns a config store subject to the injector's policy store outage fault
+func WrapStore(next configstore.ConfigStore, injector *Injector) configstore.ConfigStore {
+	return &store{ConfigStore: next, injector: injector}
+}
+
+// LoadRoutes loads routes unless an outage is injected
+func (s *store) LoadRoutes(ctx context.Context) (*types.RoutesConfig, error) {
+	if _, ok := s.injector.Trigger(PolicyStoreOutage); ok {
+		return nil, s.outage()
+	}
+	return s.ConfigStore.LoadRoutes(ctx)
+}
+
+// LoadPolicies loads policies unless an outage is injected
+func (s *store) LoadPolicies(ctx context.Context) (map[string]string, error) {
+	if _, ok := s.injector.Trigger(PolicyStoreOutage); ok {
+		return nil, s.outage()
+	}
+	return s.ConfigStore.LoadPolicies(ctx)
+}
+
+// LoadData passes through the data document of stores providing one
+func (s *store) LoadData(ctx context.Context) (map[string]interface{}, error) {
+	if dataStore, ok := s.ConfigStore.(configstore.DataStore); ok {
+		return dataStore.LoadData(ctx)
+	}
+	return nil, nil
+}
+
+// outage returns the error reported during an injected outage
+func (s *store) outage() error {
+	return fmt.Errorf("%s store unavailable (injected %s)", s.ConfigStore.Name(), PolicyStoreOutage)
+}
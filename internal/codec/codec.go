@@ -0,0 +1,182 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Media types of the built-in codecs
+const (
+	JSON     = "application/json"
+	XML      = "application/xml"
+	Protobuf = "application/x-protobuf"
+)
+
+// Codec converts request and response bodies of a media type to and from
+// the generic documents that schemas and policies see: maps, slices,
+// strings, float64 numbers, booleans and nil
+type Codec interface {
+	// ContentType is the media type written in the Content-Type header of
+	// encoded responses
+	ContentType() string
+	Decode(data []byte) (interface{}, error)
+	Encode(document interface{}) ([]byte, error)
+}
+
+// Registry holds the codecs of media types. It is safe for concurrent use.
+type Registry struct {
+	mu     sync.RWMutex
+	codecs map[string]Codec
+}
+
+// NewRegistry creates a registry with the JSON, XML and protobuf codecs
+func NewRegistry() *Registry {
+	r := &Registry{codecs: make(map[string]Codec)}
+	r.Register(jsonCodec{})
+	r.Register(xmlCodec{}, "text/xml")
+	r.Register(protobufCodec{}, "application/protobuf")
+	return r
+}
+
+// Register adds a codec for its content type and any alias media types,
+// replacing codecs registered for them before
+func (r *Registry) Register(codec Codec, aliases ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, mediaType := range append([]string{codec.ContentType()}, aliases...) {
+		r.codecs[strings.ToLower(mediaType)] = codec
+	}
+}
+
+// Lookup returns the codec of a Content-Type header value, ignoring its
+// parameters
+func (r *Registry) Lookup(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	codec, ok := r.codecs[mediaType]
+	return codec, ok
+}
+
+// MediaTypes returns the registered media types, sorted
+func (r *Registry) MediaTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	mediaTypes := make([]string, 0, len(r.codecs))
+	for mediaType := range r.codecs {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+	return mediaTypes
+}
+
+// Negotiate picks the codec of the offered media type the Accept header
+// prefers, by quality and then by the order of the offer. An empty Accept
+// header accepts the first offered media type.
+func (r *Registry) Negotiate(accept string, offered []string) (Codec, bool) {
+	ranges := parseAccept(accept)
+	best, bestQuality := "", 0.0
+	for _, mediaType := range offered {
+		if quality := acceptQuality(ranges, mediaType); quality > bestQuality {
+			best, bestQuality = mediaType, quality
+		}
+	}
+	if best == "" {
+		return nil, false
+	}
+	return r.Lookup(best)
+}
+
+// acceptRange is a media range of an Accept header with its quality
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// parseAccept parses the media ranges of an Accept header. An empty header
+// accepts any media type.
+func parseAccept(accept string) []acceptRange {
+	if strings.TrimSpace(accept) == "" {
+		return []acceptRange{{mediaType: "*/*", quality: 1}}
+	}
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, quality: quality})
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality the most specific matching media range
+// gives a media type, or 0 when none matches
+func acceptQuality(ranges []acceptRange, mediaType string) float64 {
+	mediaType = strings.ToLower(mediaType)
+	kind, _, _ := strings.Cut(mediaType, "/")
+	quality, specificity := 0.0, -1
+	for _, r := range ranges {
+		match := -1
+		switch {
+		case r.mediaType == mediaType:
+			match = 2
+		case r.mediaType == kind+"/*":
+			match = 1
+		case r.mediaType == "*/*":
+			match = 0
+		}
+		if match > specificity {
+			quality, specificity = r.quality, match
+		}
+	}
+	return quality
+}
+
+// Normalize converts a response value, such as a struct, to a generic
+// document through its JSON encoding
+func Normalize(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode document: %w", err)
+	}
+	var document interface{}
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+	return document, nil
+}
+
+// jsonCodec encodes documents as JSON
+type jsonCodec struct{}
+
+// ContentType implements Codec
+func (jsonCodec) ContentType() string {
+	return JSON
+}
+
+// Decode implements Codec
+func (jsonCodec) Decode(data []byte) (interface{}, error) {
+	var document interface{}
+	err := json.Unmarshal(data, &document)
+	return document, err
+}
+
+// Encode implements Codec
+func (jsonCodec) Encode(document interface{}) ([]byte, error) {
+	return json.Marshal(document)
+}
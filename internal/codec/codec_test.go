@@ -0,0 +1,96 @@
+package codec
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNegotiatePrefersQualityThenOffer(t *testing.T) {
+	registry := NewRegistry()
+	offered := []string{JSON, XML, Protobuf}
+
+	cases := map[string]string{
+		"":                JSON,
+		"*/*":             JSON,
+		"application/xml": XML,
+		"text/xml":        "",
+		"application/json;q=0.5, application/xml":           XML,
+		"application/*;q=0.2, application/x-protobuf;q=0.9": Protobuf,
+		"application/*, application/json;q=0":               XML,
+		"text/html":                                         "",
+	}
+	for accept, expected := range cases {
+		codec, ok := registry.Negotiate(accept, offered)
+		if expected == "" {
+			if ok {
+				t.Errorf("Accept %q: expected no codec, got %s", accept, codec.ContentType())
+			}
+			continue
+		}
+		if !ok || codec.ContentType() != expected {
+			t.Errorf("Accept %q: expected %s, got %v", accept, expected, codec)
+		}
+	}
+}
+
+func TestLookupResolvesAliasesAndParameters(t *testing.T) {
+	registry := NewRegistry()
+	if codec, ok := registry.Lookup("text/xml; charset=utf-8"); !ok || codec.ContentType() != XML {
+		t.Errorf("Expected text/xml to resolve to the XML codec, got %v", codec)
+	}
+	if _, ok := registry.Lookup("application/yaml"); ok {
+		t.Error("Expected no codec for an unregistered media type")
+	}
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	codec := xmlCodec{}
+	document, err := codec.Decode([]byte(`<?xml version="1.0"?>
+<traffic>
+  <trafficType>incoming</trafficType>
+  <volume>5</volume>
+  <tag>a</tag>
+  <tag>b</tag>
+  <owner><team>payments</team></owner>
+</traffic>`))
+	if err != nil {
+		t.Fatalf("Failed to decode XML: %v", err)
+	}
+	expected := map[string]interface{}{
+		"trafficType": "incoming",
+		"volume":      "5",
+		"tag":         []interface{}{"a", "b"},
+		"owner":       map[string]interface{}{"team": "payments"},
+	}
+	if !reflect.DeepEqual(document, expected) {
+		t.Errorf("Unexpected document %v", document)
+	}
+
+	encoded, err := codec.Encode(map[string]interface{}{"id": "rule-1", "volume": 5.5, "tags": []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("Failed to encode XML: %v", err)
+	}
+	if !strings.Contains(string(encoded), "<document><id>rule-1</id><tags>a</tags><tags>b</tags><volume>5.5</volume></document>") {
+		t.Errorf("Unexpected XML %s", encoded)
+	}
+	if _, err := codec.Decode([]byte("not xml")); err == nil {
+		t.Error("Expected a document without elements to be rejected")
+	}
+}
+
+func TestProtobufRoundTrip(t *testing.T) {
+	codec := protobufCodec{}
+	encoded, err := codec.Encode([]map[string]interface{}{{"id": "rule-1", "volume": 5}})
+	if err != nil {
+		t.Fatalf("Failed to encode protobuf: %v", err)
+	}
+	document, err := codec.Decode(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode protobuf: %v", err)
+	}
+	expected := []interface{}{map[string]interface{}{"id": "rule-1", "volume": 5.0}}
+	if !reflect.DeepEqual(document, expected) {
+		t.Errorf("Unexpected document %v", document)
+	}
+}
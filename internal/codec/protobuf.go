@@ -0,0 +1,40 @@
+package codec
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// protobufCodec encodes documents as google.protobuf.Value messages, the
+// well-known type holding any JSON value, so that routes need no message
+// definitions of their own
+type protobufCodec struct{}
+
+// ContentType implements Codec
+func (protobufCodec) ContentType() string {
+	return Protobuf
+}
+
+// Decode implements Codec
+func (protobufCodec) Decode(data []byte) (interface{}, error) {
+	var value structpb.Value
+	if err := proto.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to decode protobuf value: %w", err)
+	}
+	return value.AsInterface(), nil
+}
+
+// Encode implements Codec
+func (protobufCodec) Encode(document interface{}) ([]byte, error) {
+	document, err := Normalize(document)
+	if err != nil {
+		return nil, err
+	}
+	value, err := structpb.NewValue(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode protobuf value: %w", err)
+	}
+	return proto.Marshal(value)
+}
@@ -0,0 +1,160 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Element names of XML documents without names of their own
+const (
+	xmlRoot = "document"
+	xmlItem = "item"
+)
+
+// xmlCodec maps XML elements to documents: elements with children become
+// objects keyed by child name, repeated children become arrays, and other
+// elements become their text. Attributes are ignored. Values are decoded as
+// strings, leaving their conversion to the schema of the route.
+type xmlCodec struct{}
+
+// ContentType implements Codec
+func (xmlCodec) ContentType() string {
+	return XML
+}
+
+// Decode implements Codec, returning the content of the root element
+func (xmlCodec) Decode(data []byte) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("XML document has no root element")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return decodeElement(decoder, start)
+		}
+	}
+}
+
+// decodeElement decodes the content of an element up to its end
+func decodeElement(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	var text strings.Builder
+	var children map[string]interface{}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read element %s: %w", start.Name.Local, err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			child, err := decodeElement(decoder, token)
+			if err != nil {
+				return nil, err
+			}
+			if children == nil {
+				children = make(map[string]interface{})
+			}
+			// Elements never decode to arrays, so an array holds repeated children
+			name := token.Name.Local
+			switch existing := children[name].(type) {
+			case nil:
+				children[name] = child
+			case []interface{}:
+				children[name] = append(existing, child)
+			default:
+				children[name] = []interface{}{existing, child}
+			}
+		case xml.CharData:
+			text.Write(token)
+		case xml.EndElement:
+			if children != nil {
+				return children, nil
+			}
+			return strings.TrimSpace(text.String()), nil
+		}
+	}
+}
+
+// Encode implements Codec, wrapping the document in a document element.
+// Array items are repeated elements named after their property, or item
+// elements at the root and in nested arrays.
+func (xmlCodec) Encode(document interface{}) ([]byte, error) {
+	document, err := Normalize(document)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	if items, ok := document.([]interface{}); ok {
+		document = map[string]interface{}{xmlItem: items}
+	}
+	if err := encodeElement(encoder, xmlRoot, document); err != nil {
+		return nil, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeElement writes a value as elements with the given name
+func encodeElement(encoder *xml.Encoder, name string, value interface{}) error {
+	if items, ok := value.([]interface{}); ok {
+		for _, item := range items {
+			if nested, ok := item.([]interface{}); ok {
+				item = map[string]interface{}{xmlItem: nested}
+			}
+			if err := encodeElement(encoder, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return fmt.Errorf("failed to encode element %s: %w", name, err)
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := encodeElement(encoder, key, value[key]); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := encoder.EncodeToken(xml.CharData(scalarText(value))); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// scalarText formats a scalar document value as element text
+func scalarText(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,235 @@
+package configstore
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+)
+
+// DefaultBundleInterval is how often the bundle source is polled for changes
+const DefaultBundleInterval = 30 * time.Second
+
+// BundleStore serves routes from a wrapped store and policies from an OPA
+// bundle. The bundle is a gzipped tarball or directory on disk, or the URL
+// of a bundle server polled with conditional requests.
+type BundleStore struct {
+	routes       ConfigStore
+	source       string
+	token        string
+	verification *opa.BundleVerification
+	interval     time.Duration
+	client       *http.Client
+
+	mu          sync.Mutex
+	bundle      *opa.Bundle
+	fingerprint string
+}
+
+// NewBundleStore creates a store loading policies from the bundle at source.
+// The token is sent as a bearer token to bundle servers, and bundles must be
+// signed when a verification key is given.
+func NewBundleStore(routes ConfigStore, source, token string, verification *opa.BundleVerification, interval time.Duration) *BundleStore {
+	if interval <= 0 {
+		interval = DefaultBundleInterval
+	}
+	return &BundleStore{
+		routes:       routes,
+		source:       source,
+		token:        token,
+		verification: verification,
+		interval:     interval,
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name identifies the backend in logs
+func (bs *BundleStore) Name() string {
+	return bs.routes.Name() + "+bundle"
+}
+
+// LoadRoutes returns the routes of the wrapped store
+func (bs *BundleStore) LoadRoutes(ctx context.Context) (*types.RoutesConfig, error) {
+	return bs.routes.LoadRoutes(ctx)
+}
+
+// LoadPolicies refreshes the bundle and returns its policies keyed by
+// package path
+func (bs *BundleStore) LoadPolicies(ctx context.Context) (map[string]string, error) {
+	if _, err := bs.refresh(ctx); err != nil {
+		return nil, err
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return bs.bundle.Policies, nil
+}
+
+// LoadData returns the data document of the bundle last loaded
+func (bs *BundleStore) LoadData(ctx context.Context) (map[string]interface{}, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.bundle == nil {
+		return nil, fmt.Errorf("no bundle loaded from %s", bs.source)
+	}
+	return bs.bundle.Data, nil
+}
+
+// Watch reports changes of the wrapped store's routes and polls the bundle
+// source for new revisions
+func (bs *BundleStore) Watch(ctx context.Context, onChange func()) error {
+	var notify sync.Mutex
+	serialized := func() {
+		notify.Lock()
+		defer notify.Unlock()
+		onChange()
+	}
+
+	go func() {
+		if err := bs.routes.Watch(ctx, serialized); err != nil && ctx.Err() == nil {
+			slog.Error("Route store watch stopped", "store", bs.routes.Name(), "error", err)
+		}
+	}()
+
+	ticker := time.NewTicker(bs.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		changed, err := bs.refresh(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			slog.Warn("Bundle poll failed, retrying", "bundle", bs.source, "error", err)
+			continue
+		}
+		if changed {
+			serialized()
+		}
+	}
+}
+
+// refresh reloads the bundle when its source changed and reports whether it did
+func (bs *BundleStore) refresh(ctx context.Context) (bool, error) {
+	if strings.HasPrefix(bs.source, "http://") || strings.HasPrefix(bs.source, "https://") {
+		return bs.refreshRemote(ctx)
+	}
+	return bs.refreshLocal()
+}
+
+// refreshRemote downloads the bundle, sending the ETag of the loaded bundle
+// so an unchanged bundle is not transferred again
+func (bs *BundleStore) refreshRemote(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bs.source, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to build bundle request: %w", err)
+	}
+	if bs.token != "" {
+		req.Header.Set("Authorization", "Bearer "+bs.token)
+	}
+	bs.mu.Lock()
+	if bs.bundle != nil && bs.fingerprint != "" {
+		req.Header.Set("If-None-Match", bs.fingerprint)
+	}
+	bs.mu.Unlock()
+
+	resp, err := bs.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to reach bundle server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return false, nil
+	case http.StatusOK:
+	default:
+		return false, fmt.Errorf("bundle server returned status %d", resp.StatusCode)
+	}
+
+	bundle, err := opa.ReadBundle(resp.Body, bs.verification)
+	if err != nil {
+		return false, err
+	}
+	return bs.replace(bundle, resp.Header.Get("ETag")), nil
+}
+
+// refreshLocal reads the bundle file or directory when its modification
+// state changed
+func (bs *BundleStore) refreshLocal() (bool, error) {
+	fingerprint, err := fileFingerprint(bs.source)
+	if err != nil {
+		return false, fmt.Errorf("failed to read bundle %s: %w", bs.source, err)
+	}
+	bs.mu.Lock()
+	unchanged := bs.bundle != nil && bs.fingerprint == fingerprint
+	bs.mu.Unlock()
+	if unchanged {
+		return false, nil
+	}
+
+	var bundle *opa.Bundle
+	if info, statErr := os.Stat(bs.source); statErr == nil && info.IsDir() {
+		bundle, err = opa.ReadBundleDir(bs.source, bs.verification)
+	} else {
+		var file *os.File
+		if file, err = os.Open(bs.source); err != nil {
+			return false, fmt.Errorf("failed to open bundle: %w", err)
+		}
+		defer file.Close()
+		bundle, err = opa.ReadBundle(file, bs.verification)
+	}
+	if err != nil {
+		return false, err
+	}
+	return bs.replace(bundle, fingerprint), nil
+}
+
+// replace installs a freshly read bundle and reports whether its revision
+// or content differs from the loaded one
+func (bs *BundleStore) replace(bundle *opa.Bundle, fingerprint string) bool {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	changed := bs.bundle == nil || bs.fingerprint != fingerprint || bs.bundle.Revision != bundle.Revision
+	bs.bundle = bundle
+	bs.fingerprint = fingerprint
+	if changed {
+		slog.Info("Loaded policy bundle", "bundle", bs.source, "revision", bundle.Revision, "policies", len(bundle.Policies))
+	}
+	return changed
+}
+
+// fileFingerprint summarizes the modification state of a file or of every
+// file below a directory
+func fileFingerprint(path string) (string, error) {
+	var b strings.Builder
+	err := filepath.WalkDir(path, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			fmt.Fprintf(&b, "%s:%d:%d;", name, info.ModTime().UnixNano(), info.Size())
+		}
+		return nil
+	})
+	return b.String(), err
+}
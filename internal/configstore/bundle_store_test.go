@@ -0,0 +1,98 @@
+package configstore
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+
+	"github.com/open-policy-agent/opa/bundle"
+)
+
+// staticRoutes is a route store whose configuration never changes
+type staticRoutes struct{}
+
+func (staticRoutes) Name() string { return "static" }
+func (staticRoutes) LoadRoutes(ctx context.Context) (*types.RoutesConfig, error) {
+	return &types.RoutesConfig{}, nil
+}
+func (staticRoutes) LoadPolicies(ctx context.Context) (map[string]string, error) { return nil, nil }
+func (staticRoutes) Watch(ctx context.Context, onChange func()) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestBundleStorePollsBundleServer(t *testing.T) {
+	var mu sync.Mutex
+	revision := "1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == `"`+revision+`"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		var buf bytes.Buffer
+		bundle.Write(&buf, bundle.Bundle{
+			Manifest: bundle.Manifest{Revision: revision},
+			Data:     map[string]interface{}{"revision": revision},
+			Modules: []bundle.ModuleFile{{
+				URL: "/status.rego", Path: "/status.rego",
+				Raw: []byte("package status_policy\n\ndefault allow = true\n"),
+			}},
+		})
+		w.Header().Set("ETag", `"`+revision+`"`)
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	store := NewBundleStore(staticRoutes{}, server.URL, "token", nil, 10*time.Millisecond)
+	policies, err := store.LoadPolicies(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load policies: %v", err)
+	}
+	data, err := store.LoadData(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if _, exists := policies["status_policy"]; !exists || data["revision"] != "1" {
+		t.Errorf("Unexpected bundle content: %v %v", policies, data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go store.Watch(ctx, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	select {
+	case <-changed:
+		t.Fatal("Expected an unchanged bundle not to be reported")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mu.Lock()
+	revision = "2"
+	mu.Unlock()
+	select {
+	case <-changed:
+	case <-ctx.Done():
+		t.Fatal("Expected a new bundle revision to be reported")
+	}
+	if data, _ := store.LoadData(context.Background()); data["revision"] != "2" {
+		t.Errorf("Expected the polled bundle to be loaded, got data %v", data)
+	}
+}
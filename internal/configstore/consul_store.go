@@ -0,0 +1,179 @@
+package configstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Consul key layout and blocking query settings
+const (
+	DefaultConsulAddress = "http://127.0.0.1:8500"
+	DefaultConsulPrefix  = "dynamiccontrol/"
+	consulRoutesKey      = "routes"
+	consulPoliciesKey    = "policies/"
+	consulWaitTime       = "5m"
+	consulRetryDelay     = 2 * time.Second
+)
+
+// ConsulStore reads routes and policies from the Consul KV store. Routes are
+// stored as a RoutesConfig document under <prefix>routes and each policy as
+// Rego source under <prefix>policies/<name>.
+type ConsulStore struct {
+	address string
+	prefix  string
+	token   string
+	client  *http.Client
+}
+
+// consulKeyValue is a key/value pair returned by the Consul KV API
+type consulKeyValue struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+// NewConsulStore creates a Consul-backed config store
+func NewConsulStore(address, prefix, token string) *ConsulStore {
+	if address == "" {
+		address = DefaultConsulAddress
+	}
+	if prefix == "" {
+		prefix = DefaultConsulPrefix
+	}
+	return &ConsulStore{
+		address: strings.TrimSuffix(address, "/"),
+		prefix:  strings.TrimPrefix(prefix, "/"),
+		token:   token,
+		client:  &http.Client{},
+	}
+}
+
+// Name identifies the backend in logs
+func (cs *ConsulStore) Name() string {
+	return "consul"
+}
+
+// LoadRoutes reads the route configuration document
+func (cs *ConsulStore) LoadRoutes(ctx context.Context) (*types.RoutesConfig, error) {
+	kvs, _, err := cs.get(ctx, cs.prefix+consulRoutesKey, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(kvs) == 0 {
+		return nil, fmt.Errorf("no routes found at %s%s", cs.prefix, consulRoutesKey)
+	}
+
+	var config types.RoutesConfig
+	if err := json.Unmarshal([]byte(kvs[0].Value), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse routes from consul: %w", err)
+	}
+	return &config, nil
+}
+
+// LoadPolicies reads every policy stored under the policies prefix
+func (cs *ConsulStore) LoadPolicies(ctx context.Context) (map[string]string, error) {
+	policiesPrefix := cs.prefix + consulPoliciesKey
+	kvs, _, err := cs.get(ctx, policiesPrefix, true, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		name := strings.TrimPrefix(kv.Key, policiesPrefix)
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		policies[name] = kv.Value
+	}
+	return policies, nil
+}
+
+// Watch issues blocking queries on the store prefix and reports every index change
+func (cs *ConsulStore) Watch(ctx context.Context, onChange func()) error {
+	var index uint64
+	for {
+		_, next, err := cs.get(ctx, cs.prefix, true, index)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			slog.Warn("consul watch interrupted, retrying", "error", err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(consulRetryDelay):
+			}
+			continue
+		}
+
+		switch {
+		case index == 0:
+			// First query establishes the starting index
+		case next > index:
+			onChange()
+		case next < index:
+			// The index went backwards, e.g. after a snapshot restore; resync
+			onChange()
+		}
+		index = next
+	}
+}
+
+// get reads a key or, when recurse is set, every key under a prefix. A non-zero
+// index turns the request into a blocking query that returns once the data
+// changes past that index or the wait time elapses.
+func (cs *ConsulStore) get(ctx context.Context, key string, recurse bool, index uint64) ([]consulKeyValue, uint64, error) {
+	query := url.Values{}
+	if recurse {
+		query.Set("recurse", "true")
+	}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", consulWaitTime)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cs.address+"/v1/kv/"+key+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to build consul request: %w", err)
+	}
+	if cs.token != "" {
+		req.Header.Set("X-Consul-Token", cs.token)
+	}
+
+	resp, err := cs.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to reach consul: %w", err)
+	}
+	defer resp.Body.Close()
+
+	next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, next, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, next, fmt.Errorf("consul returned status %d", resp.StatusCode)
+	}
+
+	var kvs []consulKeyValue
+	if err := json.NewDecoder(resp.Body).Decode(&kvs); err != nil {
+		return nil, next, fmt.Errorf("failed to decode consul response: %w", err)
+	}
+	for i := range kvs {
+		valueBytes, err := base64.StdEncoding.DecodeString(kvs[i].Value)
+		if err != nil {
+			return nil, next, fmt.Errorf("failed to decode consul value: %w", err)
+		}
+		kvs[i].Value = string(valueBytes)
+	}
+	return kvs, next, nil
+}
@@ -0,0 +1,72 @@
+package configstore
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConsulStoreLoadAndWatch(t *testing.T) {
+	encode := func(value string) string {
+		return base64.StdEncoding.EncodeToString([]byte(value))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/kv/dynamiccontrol/routes":
+			w.Header().Set("X-Consul-Index", "7")
+			fmt.Fprintf(w, `[{"Key":"dynamiccontrol/routes","Value":%q}]`, encode(`{"routes":[{"routeName":"/v1/status","method":"GET"}]}`))
+		case r.URL.Path == "/v1/kv/dynamiccontrol/policies/":
+			w.Header().Set("X-Consul-Index", "7")
+			fmt.Fprintf(w, `[{"Key":"dynamiccontrol/policies/","Value":null},{"Key":"dynamiccontrol/policies/status_policy","Value":%q}]`, encode("package status_policy"))
+		case r.URL.Path == "/v1/kv/dynamiccontrol/" && r.URL.Query().Get("index") == "":
+			w.Header().Set("X-Consul-Index", "7")
+			fmt.Fprint(w, `[]`)
+		case r.URL.Path == "/v1/kv/dynamiccontrol/":
+			// Blocking query: report a change past the requested index
+			w.Header().Set("X-Consul-Index", "8")
+			fmt.Fprint(w, `[]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	store := NewConsulStore(server.URL, "", "")
+
+	config, err := store.LoadRoutes(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+	if len(config.Routes) != 1 || config.Routes[0].RouteName != "/v1/status" {
+		t.Errorf("Unexpected routes: %+v", config.Routes)
+	}
+
+	policies, err := store.LoadPolicies(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load policies: %v", err)
+	}
+	if len(policies) != 1 || policies["status_policy"] != "package status_policy" {
+		t.Errorf("Unexpected policies: %v", policies)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go store.Watch(ctx, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	select {
+	case <-changed:
+	case <-ctx.Done():
+		t.Error("Expected a change notification when the Consul index advanced")
+	}
+}
@@ -0,0 +1,227 @@
+package configstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Etcd key layout
+const (
+	DefaultEtcdPrefix = "/dynamiccontrol/"
+	etcdRoutesKey     = "routes"
+	etcdPoliciesKey   = "policies/"
+	etcdRetryDelay    = 2 * time.Second
+)
+
+// EtcdStore reads routes and policies from etcd through its v3 JSON gateway.
+// Routes are stored as a RoutesConfig document under <prefix>routes and each
+// policy as Rego source under <prefix>policies/<name>.
+type EtcdStore struct {
+	endpoints []string
+	prefix    string
+	client    *http.Client
+}
+
+// etcdKeyValue is a key/value pair returned by the etcd gateway
+type etcdKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// NewEtcdStore creates an etcd-backed config store
+func NewEtcdStore(endpoints []string, prefix string) *EtcdStore {
+	if prefix == "" {
+		prefix = DefaultEtcdPrefix
+	}
+	return &EtcdStore{
+		endpoints: endpoints,
+		prefix:    prefix,
+		client:    &http.Client{},
+	}
+}
+
+// Name identifies the backend in logs
+func (es *EtcdStore) Name() string {
+	return "etcd"
+}
+
+// LoadRoutes reads the route configuration document
+func (es *EtcdStore) LoadRoutes(ctx context.Context) (*types.RoutesConfig, error) {
+	kvs, err := es.rangeKeys(ctx, es.prefix+etcdRoutesKey, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(kvs) == 0 {
+		return nil, fmt.Errorf("no routes found at %s%s", es.prefix, etcdRoutesKey)
+	}
+
+	var config types.RoutesConfig
+	if err := json.Unmarshal([]byte(kvs[0].Value), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse routes from etcd: %w", err)
+	}
+	return &config, nil
+}
+
+// LoadPolicies reads every policy stored under the policies prefix
+func (es *EtcdStore) LoadPolicies(ctx context.Context) (map[string]string, error) {
+	policiesPrefix := es.prefix + etcdPoliciesKey
+	kvs, err := es.rangeKeys(ctx, policiesPrefix, prefixEnd(policiesPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		policies[strings.TrimPrefix(kv.Key, policiesPrefix)] = kv.Value
+	}
+	return policies, nil
+}
+
+// Watch streams changes under the store prefix, reconnecting on failure
+func (es *EtcdStore) Watch(ctx context.Context, onChange func()) error {
+	for {
+		err := es.watchOnce(ctx, onChange)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		slog.Warn("etcd watch interrupted, retrying", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(etcdRetryDelay):
+		}
+	}
+}
+
+// watchOnce opens a single watch stream and reads events until it ends
+func (es *EtcdStore) watchOnce(ctx context.Context, onChange func()) error {
+	request := map[string]interface{}{
+		"create_request": map[string]string{
+			"key":       encodeKey(es.prefix),
+			"range_end": encodeKey(prefixEnd(es.prefix)),
+		},
+	}
+
+	resp, err := es.post(ctx, "/v3/watch", request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message struct {
+			Result struct {
+				Events []json.RawMessage `json:"events"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := decoder.Decode(&message); err != nil {
+			return fmt.Errorf("failed to read watch stream: %w", err)
+		}
+		if message.Error != nil {
+			return fmt.Errorf("watch error: %s", message.Error.Message)
+		}
+		if len(message.Result.Events) > 0 {
+			onChange()
+		}
+	}
+}
+
+// rangeKeys reads a key or, when rangeEnd is set, a range of keys
+func (es *EtcdStore) rangeKeys(ctx context.Context, key, rangeEnd string) ([]etcdKeyValue, error) {
+	request := map[string]string{"key": encodeKey(key)}
+	if rangeEnd != "" {
+		request["range_end"] = encodeKey(rangeEnd)
+	}
+
+	resp, err := es.post(ctx, "/v3/kv/range", request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Kvs []etcdKeyValue `json:"kvs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode etcd response: %w", err)
+	}
+
+	for i := range response.Kvs {
+		keyBytes, err := base64.StdEncoding.DecodeString(response.Kvs[i].Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode etcd key: %w", err)
+		}
+		valueBytes, err := base64.StdEncoding.DecodeString(response.Kvs[i].Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode etcd value: %w", err)
+		}
+		response.Kvs[i].Key = string(keyBytes)
+		response.Kvs[i].Value = string(valueBytes)
+	}
+	return response.Kvs, nil
+}
+
+// post sends a request to the first etcd endpoint that answers
+func (es *EtcdStore) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode etcd request: %w", err)
+	}
+
+	var lastErr error
+	for _, endpoint := range es.endpoints {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+path, bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to build etcd request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := es.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("etcd endpoint %s returned status %d", endpoint, resp.StatusCode)
+			continue
+		}
+		return resp, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no etcd endpoints configured")
+	}
+	return nil, lastErr
+}
+
+// encodeKey base64-encodes a key for the etcd JSON gateway
+func encodeKey(key string) string {
+	return base64.StdEncoding.EncodeToString([]byte(key))
+}
+
+// prefixEnd returns the smallest key greater than every key with the given prefix
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return "\x00"
+}
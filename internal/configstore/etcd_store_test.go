@@ -0,0 +1,191 @@
+package configstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// fakeEtcd serves the range and watch calls of the etcd v3 JSON gateway from
+// an in-memory key space
+type fakeEtcd struct {
+	mu      sync.Mutex
+	kvs     map[string]string
+	watches chan string
+}
+
+func newFakeEtcd(kvs map[string]string) *fakeEtcd {
+	return &fakeEtcd{kvs: kvs, watches: make(chan string, 1)}
+}
+
+// put stores a key and notifies the open watch
+func (fe *fakeEtcd) put(key, value string) {
+	fe.mu.Lock()
+	fe.kvs[key] = value
+	fe.mu.Unlock()
+	fe.watches <- key
+}
+
+func (fe *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		var key, rangeEnd string
+		json.Unmarshal(request["key"], &key)
+		json.Unmarshal(request["range_end"], &rangeEnd)
+		fe.serveRange(w, decodeKey(key), decodeKey(rangeEnd))
+	case "/v3/watch":
+		fe.serveWatch(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// serveRange answers a key, or the keys in [key, rangeEnd) when rangeEnd is set
+func (fe *fakeEtcd) serveRange(w http.ResponseWriter, key, rangeEnd string) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	var keys []string
+	for candidate := range fe.kvs {
+		if candidate == key || (rangeEnd != "" && candidate >= key && candidate < rangeEnd) {
+			keys = append(keys, candidate)
+		}
+	}
+	sort.Strings(keys)
+	kvs := make([]map[string]string, 0, len(keys))
+	for _, candidate := range keys {
+		kvs = append(kvs, map[string]string{"key": encodeKey(candidate), "value": encodeKey(fe.kvs[candidate])})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"kvs": kvs})
+}
+
+// serveWatch streams one event message per put until the client disconnects
+func (fe *fakeEtcd) serveWatch(w http.ResponseWriter, r *http.Request) {
+	flusher := w.(http.Flusher)
+	fmt.Fprintln(w, `{"result":{"created":true}}`)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case key := <-fe.watches:
+			fmt.Fprintf(w, `{"result":{"events":[{"kv":{"key":%q}}]}}`+"\n", encodeKey(key))
+			flusher.Flush()
+		}
+	}
+}
+
+// decodeKey decodes a base64 key of the gateway
+func decodeKey(encoded string) string {
+	decoded, _ := base64.StdEncoding.DecodeString(encoded)
+	return string(decoded)
+}
+
+func TestEtcdStoreLoadRoutesAndPolicies(t *testing.T) {
+	etcd := newFakeEtcd(map[string]string{
+		"/dynamiccontrol/routes":                   `{"routes":[{"routeName":"/v1/status","method":"GET"}]}`,
+		"/dynamiccontrol/policies/status_policy":   "package status_policy",
+		"/dynamiccontrol/policies/traffic_policy":  "package traffic_policy",
+		"/dynamiccontrol/policies0":                "outside the policies prefix",
+		"/dynamiccontrol/routes/archived":          "not the routes key",
+		"/other/dynamiccontrol/policies/foreign_p": "package foreign_p",
+	})
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+
+	store := NewEtcdStore([]string{server.URL}, "")
+	config, err := store.LoadRoutes(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+	if len(config.Routes) != 1 || config.Routes[0].RouteName != "/v1/status" {
+		t.Errorf("Unexpected routes: %+v", config.Routes)
+	}
+
+	policies, err := store.LoadPolicies(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load policies: %v", err)
+	}
+	expected := map[string]string{"status_policy": "package status_policy", "traffic_policy": "package traffic_policy"}
+	if fmt.Sprint(policies) != fmt.Sprint(expected) {
+		t.Errorf("Expected policies %v, got %v", expected, policies)
+	}
+}
+
+func TestEtcdStoreLoadErrors(t *testing.T) {
+	etcd := newFakeEtcd(map[string]string{"/custom/routes": `{"routes": [`})
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+
+	if _, err := NewEtcdStore([]string{server.URL}, "/missing/").LoadRoutes(context.Background()); err == nil || !strings.Contains(err.Error(), "no routes found at /missing/routes") {
+		t.Errorf("Expected missing routes to fail, got %v", err)
+	}
+	if _, err := NewEtcdStore([]string{server.URL}, "/custom/").LoadRoutes(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to parse routes") {
+		t.Errorf("Expected an invalid routes document to fail, got %v", err)
+	}
+	if _, err := NewEtcdStore(nil, "").LoadPolicies(context.Background()); err == nil {
+		t.Error("Expected a store without endpoints to fail")
+	}
+}
+
+func TestEtcdStoreFailsOverToNextEndpoint(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	etcd := httptest.NewServer(newFakeEtcd(map[string]string{
+		"/dynamiccontrol/routes": `{"routes":[{"routeName":"/v1/status","method":"GET"}]}`,
+	}))
+	defer etcd.Close()
+
+	config, err := NewEtcdStore([]string{down.URL, etcd.URL}, "").LoadRoutes(context.Background())
+	if err != nil || len(config.Routes) != 1 {
+		t.Fatalf("Expected the second endpoint to answer, got %v %v", config, err)
+	}
+}
+
+func TestEtcdStoreWatchTriggersReload(t *testing.T) {
+	etcd := newFakeEtcd(map[string]string{
+		"/dynamiccontrol/routes": `{"routes":[{"routeName":"/v1/status","method":"GET"}]}`,
+	})
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+	store := NewEtcdStore([]string{server.URL}, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reloaded := make(chan *types.RoutesConfig, 1)
+	go store.Watch(ctx, func() {
+		config, err := store.LoadRoutes(ctx)
+		if err != nil {
+			t.Errorf("Failed to reload routes: %v", err)
+			return
+		}
+		reloaded <- config
+	})
+
+	etcd.put("/dynamiccontrol/routes", `{"routes":[{"routeName":"/v1/status","method":"GET"},{"routeName":"/v1/traffic","method":"POST"}]}`)
+	select {
+	case config := <-reloaded:
+		if len(config.Routes) != 2 || config.Routes[1].RouteName != "/v1/traffic" {
+			t.Errorf("Expected the reload to see the new route, got %+v", config.Routes)
+		}
+	case <-ctx.Done():
+		t.Fatal("Expected a change notification when a key under the prefix changed")
+	}
+}
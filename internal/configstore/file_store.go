@@ -0,0 +1,116 @@
+package configstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/types"
+
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultPollInterval is how often the file store checks for changes
+const DefaultPollInterval = 5 * time.Second
+
+// FileStore reads routes from a JSON or YAML file and policies from a directory of .rego files
+type FileStore struct {
+	routesPath   string
+	policiesDir  string
+	pollInterval time.Duration
+}
+
+// NewFileStore creates a file-backed config store
+func NewFileStore(routesPath, policiesDir string) *FileStore {
+	return &FileStore{
+		routesPath:   routesPath,
+		policiesDir:  policiesDir,
+		pollInterval: DefaultPollInterval,
+	}
+}
+
+// Name identifies the backend in logs
+func (fs *FileStore) Name() string {
+	return "file"
+}
+
+// LoadRoutes reads the route configuration file
+func (fs *FileStore) LoadRoutes(ctx context.Context) (*types.RoutesConfig, error) {
+	configBytes, err := ioutil.ReadFile(fs.routesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if ext := filepath.Ext(fs.routesPath); ext == ".yaml" || ext == ".yml" {
+		if configBytes, err = yaml.YAMLToJSON(configBytes); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+
+	var config types.RoutesConfig
+	if err := json.Unmarshal(configBytes, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return &config, nil
+}
+
+// LoadPolicies reads every .rego file in the policies directory, skipping
+// Rego test files
+func (fs *FileStore) LoadPolicies(ctx context.Context) (map[string]string, error) {
+	files, err := ioutil.ReadDir(fs.policiesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policies directory: %w", err)
+	}
+
+	policies := make(map[string]string)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".rego") || strings.HasSuffix(file.Name(), "_test.rego") {
+			continue
+		}
+		policyBytes, err := ioutil.ReadFile(filepath.Join(fs.policiesDir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy file %s: %w", file.Name(), err)
+		}
+		policies[strings.TrimSuffix(file.Name(), ".rego")] = string(policyBytes)
+	}
+	return policies, nil
+}
+
+// Watch polls file modification times and reports changes
+func (fs *FileStore) Watch(ctx context.Context, onChange func()) error {
+	last := fs.fingerprint()
+	ticker := time.NewTicker(fs.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			current := fs.fingerprint()
+			if current != last {
+				last = current
+				onChange()
+			}
+		}
+	}
+}
+
+// fingerprint summarizes the modification state of the watched files
+func (fs *FileStore) fingerprint() string {
+	var b strings.Builder
+	if info, err := os.Stat(fs.routesPath); err == nil {
+		fmt.Fprintf(&b, "%s:%d:%d;", fs.routesPath, info.ModTime().UnixNano(), info.Size())
+	}
+	if files, err := ioutil.ReadDir(fs.policiesDir); err == nil {
+		for _, file := range files {
+			fmt.Fprintf(&b, "%s:%d:%d;", file.Name(), file.ModTime().UnixNano(), file.Size())
+		}
+	}
+	return b.String()
+}
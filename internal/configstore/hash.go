@@ -0,0 +1,36 @@
+package configstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"dynamiccontrol/internal/types"
+)
+
+// ConfigHash returns a stable hash of the active configuration: routes,
+// policy sources and policy data documents. Object keys are encoded in
+// sorted order, so equal configurations hash equally regardless of how
+// they were loaded.
+func ConfigHash(routes *types.RoutesConfig, policies map[string]string, data map[string]interface{}) (string, error) {
+	hash, err := contentHash(struct {
+		Routes   *types.RoutesConfig    `json:"routes"`
+		Policies map[string]string      `json:"policies"`
+		Data     map[string]interface{} `json:"data"`
+	}{routes, policies, data})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	return hash, nil
+}
+
+// contentHash returns the truncated SHA-256 of a value's JSON encoding
+func contentHash(value interface{}) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])[:16], nil
+}
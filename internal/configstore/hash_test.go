@@ -0,0 +1,29 @@
+package configstore
+
+import (
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestConfigHashIsStable(t *testing.T) {
+	routes := &types.RoutesConfig{Routes: []types.RouteConfig{{RouteName: "/v1/status", Method: "GET"}}}
+	policies := map[string]string{"a": "package a", "b": "package b"}
+	data := map[string]interface{}{"authz/services": map[string]interface{}{"x": 1, "y": []interface{}{"z"}}}
+
+	first, err := ConfigHash(routes, policies, data)
+	if err != nil {
+		t.Fatalf("Failed to hash configuration: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		again, _ := ConfigHash(routes, map[string]string{"b": "package b", "a": "package a"}, data)
+		if again != first {
+			t.Fatalf("Expected equal configurations to hash equally, got %s and %s", first, again)
+		}
+	}
+
+	data["authz/services"] = map[string]interface{}{"x": 2}
+	if changed, _ := ConfigHash(routes, policies, data); changed == first {
+		t.Error("Expected a data change to change the hash")
+	}
+}
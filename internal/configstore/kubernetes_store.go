@@ -0,0 +1,361 @@
+package configstore
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/ingress"
+	"dynamiccontrol/internal/types"
+)
+
+// Kubernetes custom resource settings
+const (
+	CRDGroup            = "dynamiccontrol.io"
+	CRDVersion          = "v1alpha1"
+	routesResource      = "dynamicroutes"
+	policiesResource    = "dynamicpolicies"
+	serviceAccountDir   = "/var/run/secrets/kubernetes.io/serviceaccount"
+	kubernetesRetryWait = 2 * time.Second
+)
+
+// importedResources are the routing resources that can be imported as
+// routes, by the name SetImports accepts
+var importedResources = map[string]struct {
+	resource string
+	api      string
+	kind     string
+}{
+	"ingress":   {resource: "ingresses", api: "networking.k8s.io/v1", kind: ingress.KindIngress},
+	"httproute": {resource: "httproutes", api: "gateway.networking.k8s.io/v1", kind: ingress.KindHTTPRoute},
+}
+
+// KubernetesStore sources routes and policies from DynamicRoute and
+// DynamicPolicy custom resources in a namespace. Each DynamicRoute spec is a
+// single RouteConfig; each DynamicPolicy carries Rego source in spec.rego and
+// is named by spec.policyName, or by the resource name when unset.
+type KubernetesStore struct {
+	apiServer string
+	namespace string
+	token     string
+	client    *http.Client
+	imports   []string
+}
+
+// kubernetesObject is the subset of a custom resource the store reads
+type kubernetesObject struct {
+	Metadata struct {
+		Name            string `json:"name"`
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Spec json.RawMessage `json:"spec"`
+}
+
+// policySpec is the spec of a DynamicPolicy resource
+type policySpec struct {
+	PolicyName string `json:"policyName"`
+	Rego       string `json:"rego"`
+}
+
+// NewKubernetesStore creates a store for custom resources in namespace. When
+// apiServer is empty the in-cluster service account configuration is used;
+// otherwise requests go to apiServer unauthenticated, e.g. through kubectl proxy.
+func NewKubernetesStore(apiServer, namespace string) (*KubernetesStore, error) {
+	store := &KubernetesStore{
+		apiServer: strings.TrimSuffix(apiServer, "/"),
+		namespace: namespace,
+		client:    &http.Client{},
+	}
+
+	if store.apiServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("not running in a Kubernetes cluster and no API server configured")
+		}
+		store.apiServer = "https://" + net.JoinHostPort(host, port)
+
+		token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service account token: %w", err)
+		}
+		store.token = strings.TrimSpace(string(token))
+
+		caBytes, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service account CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("failed to parse service account CA")
+		}
+		store.client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	}
+
+	if store.namespace == "" {
+		if namespace, err := ioutil.ReadFile(serviceAccountDir + "/namespace"); err == nil {
+			store.namespace = strings.TrimSpace(string(namespace))
+		} else {
+			store.namespace = "default"
+		}
+	}
+
+	return store, nil
+}
+
+// Name identifies the backend in logs
+func (ks *KubernetesStore) Name() string {
+	return "kubernetes"
+}
+
+// SetImports also sources routes from Ingress or Gateway API HTTPRoute
+// resources of the namespace, named "ingress" and "httproute". DynamicRoutes
+// take precedence over imported routes they conflict with.
+func (ks *KubernetesStore) SetImports(kinds []string) error {
+	ks.imports = nil
+	for _, kind := range kinds {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if kind == "" {
+			continue
+		}
+		if _, ok := importedResources[kind]; !ok {
+			return fmt.Errorf("unsupported Kubernetes import %q, expected ingress or httproute", kind)
+		}
+		ks.imports = append(ks.imports, kind)
+	}
+	return nil
+}
+
+// LoadRoutes reads every DynamicRoute in the namespace, ordered by resource
+// name, followed by the routes imported from routing resources
+func (ks *KubernetesStore) LoadRoutes(ctx context.Context) (*types.RoutesConfig, error) {
+	objects, _, err := ks.list(ctx, routesResource)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &types.RoutesConfig{}
+	for _, object := range objects {
+		var route types.RouteConfig
+		if err := json.Unmarshal(object.Spec, &route); err != nil {
+			return nil, fmt.Errorf("failed to parse DynamicRoute %s: %w", object.Metadata.Name, err)
+		}
+		config.Routes = append(config.Routes, route)
+	}
+
+	var imported []types.RouteConfig
+	for _, kind := range ks.imports {
+		routes, err := ks.importRoutes(ctx, kind)
+		if err != nil {
+			return nil, err
+		}
+		imported = append(imported, routes...)
+	}
+	config.Routes = ingress.Merge(config.Routes, imported)
+	return config, nil
+}
+
+// importRoutes converts every resource of an imported kind into routes
+func (ks *KubernetesStore) importRoutes(ctx context.Context, kind string) ([]types.RouteConfig, error) {
+	imported := importedResources[kind]
+	objects, _, err := ks.list(ctx, imported.resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []types.RouteConfig
+	for _, object := range objects {
+		resource := ingress.Object{Kind: imported.kind, Spec: object.Spec}
+		resource.Metadata.Name = object.Metadata.Name
+		resource.Metadata.Namespace = ks.namespace
+		converted, err := ingress.Convert(resource, ingress.Options{})
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, converted...)
+	}
+	return routes, nil
+}
+
+// LoadPolicies reads every DynamicPolicy in the namespace
+func (ks *KubernetesStore) LoadPolicies(ctx context.Context) (map[string]string, error) {
+	objects, _, err := ks.list(ctx, policiesResource)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make(map[string]string, len(objects))
+	for _, object := range objects {
+		var spec policySpec
+		if err := json.Unmarshal(object.Spec, &spec); err != nil {
+			return nil, fmt.Errorf("failed to parse DynamicPolicy %s: %w", object.Metadata.Name, err)
+		}
+		name := spec.PolicyName
+		if name == "" {
+			name = object.Metadata.Name
+		}
+		policies[name] = spec.Rego
+	}
+	return policies, nil
+}
+
+// Watch watches both custom resource kinds and the imported routing
+// resources, reconnecting on failure, and reports every added, modified or
+// deleted resource
+func (ks *KubernetesStore) Watch(ctx context.Context, onChange func()) error {
+	var mu sync.Mutex
+	notify := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		onChange()
+	}
+
+	resources := []string{routesResource, policiesResource}
+	for _, kind := range ks.imports {
+		resources = append(resources, importedResources[kind].resource)
+	}
+
+	var wg sync.WaitGroup
+	for _, resource := range resources {
+		wg.Add(1)
+		go func(resource string) {
+			defer wg.Done()
+			ks.watchResource(ctx, resource, notify)
+		}(resource)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// watchResource keeps a watch open on one resource kind until ctx is done
+func (ks *KubernetesStore) watchResource(ctx context.Context, resource string, onChange func()) {
+	resourceVersion := ""
+	for {
+		if resourceVersion == "" {
+			_, version, err := ks.list(ctx, resource)
+			if err == nil {
+				resourceVersion = version
+			} else if ctx.Err() == nil {
+				slog.Error("Failed to list Kubernetes resources", "resource", resource, "error", err)
+			}
+		}
+
+		if resourceVersion != "" {
+			version, err := ks.watchOnce(ctx, resource, resourceVersion, onChange)
+			resourceVersion = version
+			if ctx.Err() == nil {
+				slog.Warn("Kubernetes watch interrupted, retrying", "resource", resource, "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(kubernetesRetryWait):
+		}
+	}
+}
+
+// watchOnce streams watch events from resourceVersion until the stream ends
+// and returns the version to resume from, or an empty version when the
+// watch has expired and the resource must be listed again
+func (ks *KubernetesStore) watchOnce(ctx context.Context, resource, resourceVersion string, onChange func()) (string, error) {
+	resp, err := ks.get(ctx, resource, "?watch=true&allowWatchBookmarks=true&resourceVersion="+resourceVersion)
+	if err != nil {
+		return resourceVersion, err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 8*1024*1024)
+	for scanner.Scan() {
+		var event struct {
+			Type   string           `json:"type"`
+			Object kubernetesObject `json:"object"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return resourceVersion, fmt.Errorf("failed to decode watch event: %w", err)
+		}
+
+		switch event.Type {
+		case "ERROR":
+			// Typically 410 Gone: the resource version is too old to resume from
+			return "", fmt.Errorf("watch expired")
+		case "BOOKMARK":
+			resourceVersion = event.Object.Metadata.ResourceVersion
+		default:
+			resourceVersion = event.Object.Metadata.ResourceVersion
+			onChange()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return resourceVersion, fmt.Errorf("failed to read watch stream: %w", err)
+	}
+	return resourceVersion, fmt.Errorf("watch stream closed")
+}
+
+// list returns the resources of one kind sorted by name, along with the
+// collection resource version
+func (ks *KubernetesStore) list(ctx context.Context, resource string) ([]kubernetesObject, string, error) {
+	resp, err := ks.get(ctx, resource, "")
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	var list struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+		Items []kubernetesObject `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, "", fmt.Errorf("failed to decode %s list: %w", resource, err)
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Metadata.Name < list.Items[j].Metadata.Name
+	})
+	return list.Items, list.Metadata.ResourceVersion, nil
+}
+
+// get requests a resource collection in the store namespace
+func (ks *KubernetesStore) get(ctx context.Context, resource, query string) (*http.Response, error) {
+	api := CRDGroup + "/" + CRDVersion
+	for _, imported := range importedResources {
+		if imported.resource == resource {
+			api = imported.api
+		}
+	}
+	url := fmt.Sprintf("%s/apis/%s/namespaces/%s/%s%s", ks.apiServer, api, ks.namespace, resource, query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Kubernetes request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if ks.token != "" {
+		req.Header.Set("Authorization", "Bearer "+ks.token)
+	}
+
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Kubernetes API: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Kubernetes API returned status %d for %s", resp.StatusCode, resource)
+	}
+	return resp, nil
+}
@@ -0,0 +1,133 @@
+package configstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestKubernetesServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apis/dynamiccontrol.io/v1alpha1/namespaces/test/dynamicroutes", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") == "true" {
+			fmt.Fprintln(w, `{"type":"MODIFIED","object":{"metadata":{"name":"status","resourceVersion":"11"},"spec":{}}}`)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, `{"metadata":{"resourceVersion":"10"},"items":[
+			{"metadata":{"name":"traffic"},"spec":{"routeName":"/v1/traffic","method":"POST","policies":["traffic_policy"]}},
+			{"metadata":{"name":"status"},"spec":{"routeName":"/v1/status","method":"GET"}}
+		]}`)
+	})
+	mux.HandleFunc("/apis/dynamiccontrol.io/v1alpha1/namespaces/test/dynamicpolicies", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") == "true" {
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, `{"metadata":{"resourceVersion":"10"},"items":[
+			{"metadata":{"name":"status-policy"},"spec":{"policyName":"status_policy","rego":"package status_policy"}},
+			{"metadata":{"name":"traffic_policy"},"spec":{"rego":"package traffic_policy"}}
+		]}`)
+	})
+	mux.HandleFunc("/apis/networking.k8s.io/v1/namespaces/test/ingresses", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"metadata":{"resourceVersion":"10"},"items":[
+			{"metadata":{"name":"web"},"spec":{"rules":[{"http":{"paths":[
+				{"path":"/v1/status","pathType":"Exact","backend":{"service":{"name":"status","port":{"number":8080}}}},
+				{"path":"/web","pathType":"Prefix","backend":{"service":{"name":"web","port":{"number":80}}}}
+			]}}]}}
+		]}`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestKubernetesStoreLoad(t *testing.T) {
+	server := newTestKubernetesServer()
+	defer server.Close()
+
+	store, err := NewKubernetesStore(server.URL, "test")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	config, err := store.LoadRoutes(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+	if len(config.Routes) != 2 || config.Routes[0].RouteName != "/v1/status" || config.Routes[1].Method != "POST" {
+		t.Errorf("Unexpected routes: %+v", config.Routes)
+	}
+
+	policies, err := store.LoadPolicies(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load policies: %v", err)
+	}
+	if policies["status_policy"] != "package status_policy" || policies["traffic_policy"] != "package traffic_policy" {
+		t.Errorf("Unexpected policies: %v", policies)
+	}
+}
+
+func TestKubernetesStoreImportsIngresses(t *testing.T) {
+	server := newTestKubernetesServer()
+	defer server.Close()
+
+	store, err := NewKubernetesStore(server.URL, "test")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if err := store.SetImports([]string{"gateway"}); err == nil {
+		t.Error("Expected an unsupported import to be rejected")
+	}
+	if err := store.SetImports([]string{"ingress"}); err != nil {
+		t.Fatalf("Failed to set imports: %v", err)
+	}
+
+	config, err := store.LoadRoutes(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+	routes := make(map[string]string)
+	for _, route := range config.Routes {
+		routes[route.Method+" "+route.RouteName] = route.Handler
+	}
+	// The DynamicRoute for GET /v1/status wins over the imported one
+	if routes["GET /v1/status"] != "" || routes["POST /v1/status"] != "proxy" || routes["GET /web/*path"] != "proxy" {
+		t.Errorf("Unexpected routes: %v", routes)
+	}
+}
+
+func TestKubernetesStoreWatch(t *testing.T) {
+	server := newTestKubernetesServer()
+	defer server.Close()
+
+	store, err := NewKubernetesStore(server.URL, "test")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		store.Watch(ctx, func() {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+		close(done)
+	}()
+
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Error("Expected a change notification for the modified route")
+	}
+
+	cancel()
+	server.CloseClientConnections()
+	<-done
+}
@@ -0,0 +1,206 @@
+package configstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Primary snapshot endpoint and polling settings
+const (
+	PrimarySnapshotPath    = "/admin/snapshot"
+	DefaultPrimaryInterval = 5 * time.Second
+)
+
+// Headers replicas send with snapshot requests to take part in rollouts
+const (
+	RolloutRingHeader     = "X-Rollout-Ring"
+	RolloutReplicaHeader  = "X-Replica-Id"
+	RolloutRevisionHeader = "X-Rollout-Revision"
+	RolloutRequestsHeader = "X-Rollout-Requests"
+	RolloutErrorsHeader   = "X-Rollout-Errors"
+)
+
+// Snapshot is the complete configuration served by a primary to its replicas
+type Snapshot struct {
+	Revision string              `json:"revision"`
+	Routes   *types.RoutesConfig `json:"routes"`
+	Policies map[string]string   `json:"policies"`
+}
+
+// NewSnapshot creates a snapshot whose revision is derived from its content
+func NewSnapshot(routes *types.RoutesConfig, policies map[string]string) (*Snapshot, error) {
+	revision, err := contentHash(struct {
+		Routes   *types.RoutesConfig `json:"routes"`
+		Policies map[string]string   `json:"policies"`
+	}{routes, policies})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return &Snapshot{
+		Revision: revision,
+		Routes:   routes,
+		Policies: policies,
+	}, nil
+}
+
+// PrimaryStore syncs routes and policies from the snapshot endpoint of a
+// primary instance, so read replicas serve exactly what the primary serves
+type PrimaryStore struct {
+	address  string
+	token    string
+	interval time.Duration
+	client   *http.Client
+
+	mu   sync.Mutex
+	last *Snapshot
+
+	// rollout identifies the replica to the primary's rollout controller
+	replica string
+	ring    int
+	traffic func() (int64, int64)
+}
+
+// NewPrimaryStore creates a store syncing from the primary at address. The
+// token is sent as a bearer token when the primary's admin API requires one.
+func NewPrimaryStore(address, token string, interval time.Duration) *PrimaryStore {
+	if interval <= 0 {
+		interval = DefaultPrimaryInterval
+	}
+	return &PrimaryStore{
+		address:  strings.TrimSuffix(address, "/"),
+		token:    token,
+		interval: interval,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetRollout makes the replica take part in the primary's rollout rings. Each
+// poll reports the ring, the revision the replica runs and the requests and
+// 5xx responses returned by traffic since that revision was applied.
+func (ps *PrimaryStore) SetRollout(replica string, ring int, traffic func() (int64, int64)) {
+	ps.replica = replica
+	ps.ring = ring
+	ps.traffic = traffic
+}
+
+// Name identifies the backend in logs
+func (ps *PrimaryStore) Name() string {
+	return "primary"
+}
+
+// LoadPolicies fetches a fresh snapshot from the primary and returns its
+// policies. The snapshot is kept, so the following LoadRoutes returns the
+// routes of the same revision.
+func (ps *PrimaryStore) LoadPolicies(ctx context.Context) (map[string]string, error) {
+	snapshot, _, err := ps.fetch(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	ps.mu.Lock()
+	ps.last = snapshot
+	ps.mu.Unlock()
+	return snapshot.Policies, nil
+}
+
+// LoadRoutes returns the routes of the snapshot fetched by LoadPolicies,
+// fetching one when none was loaded yet
+func (ps *PrimaryStore) LoadRoutes(ctx context.Context) (*types.RoutesConfig, error) {
+	ps.mu.Lock()
+	snapshot := ps.last
+	ps.mu.Unlock()
+	if snapshot == nil {
+		var err error
+		if snapshot, _, err = ps.fetch(ctx, ""); err != nil {
+			return nil, err
+		}
+	}
+	if snapshot.Routes == nil {
+		return nil, fmt.Errorf("primary snapshot %s holds no routes", snapshot.Revision)
+	}
+	return snapshot.Routes, nil
+}
+
+// Watch polls the primary and reports every new snapshot revision
+func (ps *PrimaryStore) Watch(ctx context.Context, onChange func()) error {
+	ticker := time.NewTicker(ps.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		ps.mu.Lock()
+		revision := ""
+		if ps.last != nil {
+			revision = ps.last.Revision
+		}
+		ps.mu.Unlock()
+
+		_, changed, err := ps.fetch(ctx, revision)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			slog.Warn("primary sync failed, retrying", "primary", ps.address, "error", err)
+			continue
+		}
+		if changed {
+			onChange()
+		}
+	}
+}
+
+// fetch requests the primary's snapshot. With a known revision the request
+// is conditional and reports changed=false when the primary still serves it.
+func (ps *PrimaryStore) fetch(ctx context.Context, revision string) (*Snapshot, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ps.address+PrimarySnapshotPath, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to build primary request: %w", err)
+	}
+	if ps.token != "" {
+		req.Header.Set("Authorization", "Bearer "+ps.token)
+	}
+	if revision != "" {
+		req.Header.Set("If-None-Match", `"`+revision+`"`)
+	}
+	if ps.traffic != nil {
+		requests, errors := ps.traffic()
+		req.Header.Set(RolloutRingHeader, strconv.Itoa(ps.ring))
+		req.Header.Set(RolloutReplicaHeader, ps.replica)
+		req.Header.Set(RolloutRevisionHeader, revision)
+		req.Header.Set(RolloutRequestsHeader, strconv.FormatInt(requests, 10))
+		req.Header.Set(RolloutErrorsHeader, strconv.FormatInt(errors, 10))
+	}
+
+	resp, err := ps.client.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reach primary: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, false, nil
+	case http.StatusOK:
+	default:
+		return nil, false, fmt.Errorf("primary returned status %d", resp.StatusCode)
+	}
+
+	var snapshot Snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		return nil, false, fmt.Errorf("failed to parse primary snapshot: %w", err)
+	}
+	return &snapshot, snapshot.Revision != revision, nil
+}
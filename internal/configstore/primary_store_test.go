@@ -0,0 +1,96 @@
+package configstore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestPrimaryStoreSyncsSnapshots(t *testing.T) {
+	var mu sync.Mutex
+	snapshot, err := NewSnapshot(&types.RoutesConfig{Routes: []types.RouteConfig{{RouteName: "/v1/status", Method: "GET"}}},
+		map[string]string{"status_policy": "package status_policy"})
+	if err != nil {
+		t.Fatalf("Failed to create snapshot: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != PrimarySnapshotPath || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == `"`+snapshot.Revision+`"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		json.NewEncoder(w).Encode(snapshot)
+	}))
+	defer server.Close()
+
+	store := NewPrimaryStore(server.URL, "token", 10*time.Millisecond)
+	policies, err := store.LoadPolicies(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load policies: %v", err)
+	}
+	config, err := store.LoadRoutes(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+	if policies["status_policy"] != "package status_policy" || len(config.Routes) != 1 {
+		t.Errorf("Unexpected snapshot content: %v %+v", policies, config)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go store.Watch(ctx, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	select {
+	case <-changed:
+		t.Fatal("Expected an unchanged revision not to be reported")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mu.Lock()
+	snapshot, _ = NewSnapshot(snapshot.Routes, map[string]string{"status_policy": "package status_policy\ndefault allow = false"})
+	mu.Unlock()
+	select {
+	case <-changed:
+	case <-ctx.Done():
+		t.Fatal("Expected a new revision to be reported")
+	}
+}
+
+func TestPrimaryStoreReportsRollout(t *testing.T) {
+	received := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		json.NewEncoder(w).Encode(Snapshot{Revision: "r1", Routes: &types.RoutesConfig{}})
+	}))
+	defer server.Close()
+
+	store := NewPrimaryStore(server.URL, "", time.Second)
+	store.SetRollout("replica-a", 1, func() (int64, int64) { return 42, 3 })
+	if _, err := store.LoadPolicies(context.Background()); err != nil {
+		t.Fatalf("Failed to load policies: %v", err)
+	}
+
+	header := <-received
+	if header.Get(RolloutRingHeader) != "1" || header.Get(RolloutReplicaHeader) != "replica-a" ||
+		header.Get(RolloutRequestsHeader) != "42" || header.Get(RolloutErrorsHeader) != "3" {
+		t.Errorf("Expected the rollout report in the request headers, got %v", header)
+	}
+}
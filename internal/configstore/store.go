@@ -0,0 +1,27 @@
+package configstore
+
+import (
+	"context"
+
+	"dynamiccontrol/internal/types"
+)
+
+// ConfigStore is a source of route configuration and Rego policies
+type ConfigStore interface {
+	// Name identifies the backend in logs
+	Name() string
+	// LoadRoutes returns the current route configuration
+	LoadRoutes(ctx context.Context) (*types.RoutesConfig, error)
+	// LoadPolicies returns the current policies keyed by policy name
+	LoadPolicies(ctx context.Context) (map[string]string, error)
+	// Watch blocks until ctx is done, calling onChange whenever routes or
+	// policies may have changed
+	Watch(ctx context.Context, onChange func()) error
+}
+
+// DataStore is implemented by stores that also provide the base data
+// document policies are evaluated against, such as OPA bundles
+type DataStore interface {
+	// LoadData returns the data document of the policies last loaded
+	LoadData(ctx context.Context) (map[string]interface{}, error)
+}
@@ -0,0 +1,358 @@
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+)
+
+// routeNotFound is the error of requests matching no route
+const routeNotFound = "Route not found"
+
+// healthCases check the health endpoints
+func (r *runner) healthCases(ctx context.Context) {
+	for _, path := range []string{"/health", "/health/live"} {
+		path := path
+		r.run(SuiteHealth, "GET "+path+" reports healthy", func() error {
+			resp, err := r.do(ctx, http.MethodGet, r.config.Target+path, nil, nil)
+			if err != nil {
+				return err
+			}
+			if resp.Status != http.StatusOK {
+				return fmt.Errorf("expected 200, got %d", resp.Status)
+			}
+			_, err = resp.object()
+			return err
+		})
+	}
+	r.run(SuiteHealth, "GET /health/ready reports readiness", func() error {
+		resp, err := r.do(ctx, http.MethodGet, r.config.Target+"/health/ready", nil, nil)
+		if err != nil {
+			return err
+		}
+		if resp.Status != http.StatusOK && resp.Status != http.StatusServiceUnavailable {
+			return fmt.Errorf("expected 200 or 503, got %d", resp.Status)
+		}
+		_, err = resp.object()
+		return err
+	})
+}
+
+// routingCases check that configured routes match and other requests do not
+func (r *runner) routingCases(ctx context.Context) {
+	r.run(SuiteRouting, "unknown paths return 404", func() error {
+		resp, err := r.do(ctx, http.MethodGet, r.config.Target+"/conformance/"+r.nextRequestID(), nil, nil)
+		if err != nil {
+			return err
+		}
+		if resp.Status != http.StatusNotFound {
+			return fmt.Errorf("expected 404, got %d", resp.Status)
+		}
+		_, err = errorMessage(resp)
+		return err
+	})
+
+	r.run(SuiteRouting, "unregistered methods return 404", func() error {
+		if err := r.requireRoutes(); err != nil {
+			return err
+		}
+		routes := r.routes()
+		if len(routes) == 0 {
+			return skip("no routes without a host or tenant")
+		}
+		// Only methods no route uses are sent, so no handler can run
+		used := make(map[string]bool)
+		for _, route := range r.snapshot.Routes.Routes {
+			used[route.Method] = true
+		}
+		for _, method := range []string{http.MethodPatch, http.MethodPut, http.MethodDelete} {
+			if used[method] {
+				continue
+			}
+			resp, err := r.do(ctx, method, r.config.Target+routePath(routes[0].RouteName), []byte(`{}`), nil)
+			if err != nil {
+				return err
+			}
+			if message, _ := errorMessage(resp); resp.Status != http.StatusNotFound || message != routeNotFound {
+				return fmt.Errorf("expected 404 %q for %s %s, got %d: %s", routeNotFound, method, routes[0].RouteName, resp.Status, truncate(string(resp.Body)))
+			}
+			return nil
+		}
+		return skip("every method is used by a route")
+	})
+
+	if r.snapshotErr != nil {
+		r.run(SuiteRouting, "configured routes match", r.requireRoutes)
+		return
+	}
+	for _, route := range r.routes() {
+		if route.Method != http.MethodGet {
+			continue
+		}
+		route := route
+		r.run(SuiteRouting, routeName(route)+" matches", func() error {
+			resp, err := r.do(ctx, http.MethodGet, r.config.Target+routePath(route.RouteName), nil, nil)
+			if err != nil {
+				return err
+			}
+			if message, _ := errorMessage(resp); resp.Status == http.StatusNotFound && message == routeNotFound {
+				return fmt.Errorf("request to %s matched no route", routePath(route.RouteName))
+			}
+			return nil
+		})
+	}
+}
+
+// validationCases check that request bodies are rejected before they reach
+// route handlers
+func (r *runner) validationCases(ctx context.Context) {
+	if r.snapshotErr != nil {
+		r.run(SuiteValidation, "request bodies are validated", r.requireRoutes)
+		return
+	}
+	for _, route := range r.routes() {
+		if route.Method != http.MethodPost && route.Method != http.MethodPut {
+			continue
+		}
+		route := route
+		path := r.config.Target + routePath(route.RouteName)
+
+		r.run(SuiteValidation, routeName(route)+" rejects malformed JSON", func() error {
+			resp, err := r.do(ctx, route.Method, path, []byte(`{"conformance":`), nil)
+			if err != nil {
+				return err
+			}
+			message, err := errorMessage(resp)
+			if err != nil {
+				return err
+			}
+			if resp.Status != http.StatusBadRequest || !strings.HasPrefix(message, "Invalid JSON") {
+				return fmt.Errorf("expected 400 Invalid JSON, got %d: %s", resp.Status, message)
+			}
+			return nil
+		})
+
+		r.run(SuiteValidation, routeName(route)+" rejects missing required fields", func() error {
+			if !requiresFields(route.RequestSchema) {
+				return skip("request schema has no required fields")
+			}
+			requestID := r.nextRequestID()
+			resp, err := r.do(ctx, route.Method, path, []byte(`{}`), map[string]string{requestIDHeader: requestID})
+			if err != nil {
+				return err
+			}
+			body, err := resp.object()
+			if err != nil {
+				return err
+			}
+			message, _ := body["error"].(string)
+			if resp.Status != http.StatusBadRequest || !strings.HasSuffix(message, "validation failed") {
+				return fmt.Errorf("expected 400 validation failed, got %d: %s", resp.Status, truncate(string(resp.Body)))
+			}
+			if body["details"] == nil {
+				return fmt.Errorf("expected validation errors in details")
+			}
+			if body["requestId"] != requestID {
+				return fmt.Errorf("expected requestId %q in the error, got %v", requestID, body["requestId"])
+			}
+			return nil
+		})
+	}
+}
+
+// policyCases check denials of routes whose policies reject requests
+// without credentials
+func (r *runner) policyCases(ctx context.Context) {
+	if r.snapshotErr != nil {
+		r.run(SuitePolicy, "denied requests return 403", r.requireRoutes)
+		return
+	}
+	for _, route := range r.routes() {
+		if route.Method != http.MethodGet || len(route.Policies) == 0 {
+			continue
+		}
+		route := route
+		r.run(SuitePolicy, routeName(route)+" denial", func() error {
+			requestID := r.nextRequestID()
+			resp, err := r.do(ctx, http.MethodGet, r.config.Target+routePath(route.RouteName), nil, map[string]string{requestIDHeader: requestID})
+			if err != nil {
+				return err
+			}
+			if resp.Status != http.StatusForbidden {
+				return skip("request without credentials was not denied (%d)", resp.Status)
+			}
+			body, err := resp.object()
+			if err != nil {
+				return err
+			}
+			if message, _ := body["error"].(string); !strings.HasPrefix(message, "Request denied by policy") {
+				return fmt.Errorf("expected a policy denial, got %q", message)
+			}
+			if body["requestId"] != requestID {
+				return fmt.Errorf("expected requestId %q in the denial, got %v", requestID, body["requestId"])
+			}
+			return r.checkDecision(ctx, requestID)
+		})
+	}
+}
+
+// checkDecision checks that a denied request is in the decision log
+func (r *runner) checkDecision(ctx context.Context, requestID string) error {
+	resp, err := r.admin(ctx, http.MethodGet, "/decisions?filter[requestId]="+url.QueryEscape(requestID), nil, nil)
+	if err != nil {
+		return err
+	}
+	var page struct {
+		Items []types.Decision `json:"items"`
+	}
+	if resp.Status != http.StatusOK || json.Unmarshal(resp.Body, &page) != nil {
+		return fmt.Errorf("failed to list decisions: %d %s", resp.Status, truncate(string(resp.Body)))
+	}
+	for _, decision := range page.Items {
+		if !decision.Shadow && !decision.Allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected a denied decision for request %s", requestID)
+}
+
+// headerCases check request ID propagation
+func (r *runner) headerCases(ctx context.Context) {
+	r.run(SuiteHeaders, "inbound request IDs are echoed", func() error {
+		requestID := r.nextRequestID()
+		resp, err := r.do(ctx, http.MethodGet, r.config.Target+"/health", nil, map[string]string{requestIDHeader: requestID})
+		if err != nil {
+			return err
+		}
+		if echoed := resp.Header.Get(requestIDHeader); echoed != requestID {
+			return fmt.Errorf("expected %s %q, got %q", requestIDHeader, requestID, echoed)
+		}
+		return nil
+	})
+	r.run(SuiteHeaders, "request IDs are assigned", func() error {
+		resp, err := r.do(ctx, http.MethodGet, r.config.Target+"/health", nil, nil)
+		if err != nil {
+			return err
+		}
+		if resp.Header.Get(requestIDHeader) == "" {
+			return fmt.Errorf("expected a generated %s", requestIDHeader)
+		}
+		return nil
+	})
+	r.run(SuiteHeaders, "unknown paths echo request IDs", func() error {
+		requestID := r.nextRequestID()
+		resp, err := r.do(ctx, http.MethodGet, r.config.Target+"/conformance/"+requestID, nil, map[string]string{requestIDHeader: requestID})
+		if err != nil {
+			return err
+		}
+		if echoed := resp.Header.Get(requestIDHeader); echoed != requestID {
+			return fmt.Errorf("expected %s %q, got %q", requestIDHeader, requestID, echoed)
+		}
+		return nil
+	})
+}
+
+// adminCases check the admin API contract
+func (r *runner) adminCases(ctx context.Context) {
+	r.run(SuiteAdmin, "snapshot serves the active configuration", func() error {
+		if r.snapshotErr != nil {
+			return r.snapshotErr
+		}
+		if r.snapshot.Revision == "" {
+			return fmt.Errorf("expected a snapshot revision")
+		}
+		resp, err := r.admin(ctx, http.MethodGet, "/snapshot", nil, map[string]string{"If-None-Match": `"` + r.snapshot.Revision + `"`})
+		if err != nil {
+			return err
+		}
+		if resp.Status != http.StatusNotModified {
+			return fmt.Errorf("expected 304 for the current revision, got %d", resp.Status)
+		}
+		return nil
+	})
+
+	r.run(SuiteAdmin, "requests without a token are rejected", func() error {
+		if r.config.Token == "" {
+			return skip("no admin token configured")
+		}
+		resp, err := r.do(ctx, http.MethodGet, r.config.Admin+"/admin/routes", nil, nil)
+		if err != nil {
+			return err
+		}
+		if resp.Status != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+			return fmt.Errorf("expected 401 with WWW-Authenticate, got %d", resp.Status)
+		}
+		return nil
+	})
+
+	r.run(SuiteAdmin, "routes list the route table", func() error {
+		if err := r.requireRoutes(); err != nil {
+			return err
+		}
+		resp, err := r.admin(ctx, http.MethodGet, "/routes?limit=1", nil, nil)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Items []map[string]interface{} `json:"items"`
+			Total int                      `json:"total"`
+		}
+		if resp.Status != http.StatusOK || json.Unmarshal(resp.Body, &page) != nil {
+			return fmt.Errorf("expected a route list, got %d: %s", resp.Status, truncate(string(resp.Body)))
+		}
+		if routes := len(r.snapshot.Routes.Routes); page.Total != routes {
+			return fmt.Errorf("expected %d routes, got %d", routes, page.Total)
+		}
+		if page.Total > 0 && (len(page.Items) != 1 || page.Items[0]["id"] == nil) {
+			return fmt.Errorf("expected a page of one route with an id, got %d", len(page.Items))
+		}
+		return nil
+	})
+
+	r.run(SuiteAdmin, "active configuration diffs empty", func() error {
+		if err := r.requireRoutes(); err != nil {
+			return err
+		}
+		request, err := json.Marshal(map[string]interface{}{
+			"routes":   r.snapshot.Routes,
+			"policies": r.snapshot.Policies,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode diff request: %w", err)
+		}
+		resp, err := r.admin(ctx, http.MethodPost, "/config/diff", request, nil)
+		if err != nil {
+			return err
+		}
+		var diff types.ConfigDiff
+		if resp.Status != http.StatusOK || json.Unmarshal(resp.Body, &diff) != nil {
+			return fmt.Errorf("expected a diff, got %d: %s", resp.Status, truncate(string(resp.Body)))
+		}
+		if len(diff.Added)+len(diff.Removed)+len(diff.Changed)+len(diff.Policies) > 0 {
+			return fmt.Errorf("expected no changes, got %s", truncate(string(resp.Body)))
+		}
+		return nil
+	})
+
+	r.run(SuiteAdmin, "unknown admin endpoints return 404", func() error {
+		resp, err := r.admin(ctx, http.MethodGet, "/conformance-unknown", nil, nil)
+		if err != nil {
+			return err
+		}
+		if resp.Status != http.StatusNotFound {
+			return fmt.Errorf("expected 404, got %d", resp.Status)
+		}
+		return nil
+	})
+}
+
+// requiresFields reports whether a request schema requires top-level fields
+func requiresFields(schema map[string]interface{}) bool {
+	required, _ := schema["required"].([]interface{})
+	return len(required) > 0
+}
@@ -0,0 +1,310 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// DefaultTimeout bounds every request sent to the deployment under test
+const DefaultTimeout = 10 * time.Second
+
+// Outcomes of a conformance case
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Suites group the conformance cases by the part of the contract they cover
+const (
+	SuiteHealth     = "health"
+	SuiteRouting    = "routing"
+	SuiteValidation = "validation"
+	SuitePolicy     = "policy"
+	SuiteHeaders    = "headers"
+	SuiteAdmin      = "admin"
+)
+
+// requestIDHeader carries the request correlation ID
+const requestIDHeader = "X-Request-ID"
+
+// Config describes the deployment under test
+type Config struct {
+	// Target is the base URL of the data plane
+	Target string
+	// Admin is the base URL serving /admin, the target when empty
+	Admin string
+	// Token authenticates admin requests
+	Token   string
+	Timeout time.Duration
+	Client  *http.Client
+}
+
+// Result is the outcome of a single conformance case
+type Result struct {
+	Suite      string  `json:"suite"`
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Message    string  `json:"message,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// Report summarizes a conformance run
+type Report struct {
+	Target     string    `json:"target"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs float64   `json:"durationMs"`
+	Passed     int       `json:"passed"`
+	Failed     int       `json:"failed"`
+	Skipped    int       `json:"skipped"`
+	Results    []Result  `json:"results"`
+}
+
+// skipError marks a case that does not apply to the deployment
+type skipError struct {
+	reason string
+}
+
+func (e skipError) Error() string {
+	return e.reason
+}
+
+// skip creates an error skipping the current case
+func skip(format string, args ...interface{}) error {
+	return skipError{reason: fmt.Sprintf(format, args...)}
+}
+
+// response is a response read in full
+type response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// object decodes the response body as a JSON object
+func (r *response) object() (map[string]interface{}, error) {
+	var object map[string]interface{}
+	if err := json.Unmarshal(r.Body, &object); err != nil {
+		return nil, fmt.Errorf("expected a JSON object, got %q", truncate(string(r.Body)))
+	}
+	return object, nil
+}
+
+// runner sends the requests of a conformance run
+type runner struct {
+	config   Config
+	client   *http.Client
+	sequence int
+	// snapshot is the active configuration read from the admin API
+	snapshot    *snapshot
+	snapshotErr error
+	results     []Result
+}
+
+// snapshot is the active configuration served by /admin/snapshot
+type snapshot struct {
+	Revision string              `json:"revision"`
+	Routes   *types.RoutesConfig `json:"routes"`
+	Policies map[string]string   `json:"policies"`
+}
+
+// Run runs the conformance suite against a deployment. Cases only send
+// requests that leave its state unchanged: route handlers are reached with
+// GET requests, and requests to other routes are rejected by validation.
+func Run(ctx context.Context, config Config) Report {
+	config.Target = strings.TrimSuffix(config.Target, "/")
+	config.Admin = strings.TrimSuffix(config.Admin, "/")
+	if config.Admin == "" {
+		config.Admin = config.Target
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	client := config.Client
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+	r := &runner{config: config, client: client}
+
+	report := Report{Target: config.Target, StartedAt: time.Now().UTC()}
+	r.loadSnapshot(ctx)
+	r.healthCases(ctx)
+	r.routingCases(ctx)
+	r.validationCases(ctx)
+	r.policyCases(ctx)
+	r.headerCases(ctx)
+	r.adminCases(ctx)
+
+	report.Results = r.results
+	for _, result := range report.Results {
+		switch result.Status {
+		case StatusPass:
+			report.Passed++
+		case StatusFail:
+			report.Failed++
+		default:
+			report.Skipped++
+		}
+	}
+	report.DurationMs = float64(time.Since(report.StartedAt).Microseconds()) / 1000
+	return report
+}
+
+// run runs a case and records its outcome
+func (r *runner) run(suite, name string, fn func() error) {
+	start := time.Now()
+	err := fn()
+	result := Result{
+		Suite:      suite,
+		Name:       name,
+		Status:     StatusPass,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	var skipped skipError
+	switch {
+	case errors.As(err, &skipped):
+		result.Status = StatusSkip
+		result.Message = skipped.reason
+	case err != nil:
+		result.Status = StatusFail
+		result.Message = err.Error()
+	}
+	r.results = append(r.results, result)
+}
+
+// nextRequestID returns a request ID identifying a conformance request
+func (r *runner) nextRequestID() string {
+	r.sequence++
+	return fmt.Sprintf("conformance-%d-%d", time.Now().UnixNano(), r.sequence)
+}
+
+// do sends a request and reads its response
+func (r *runner) do(ctx context.Context, method, url string, body []byte, headers map[string]string) (*response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", method, url, err)
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response of %s %s: %w", method, url, err)
+	}
+	return &response{Status: resp.StatusCode, Header: resp.Header, Body: content}, nil
+}
+
+// admin sends an authenticated request to the admin API
+func (r *runner) admin(ctx context.Context, method, path string, body []byte, headers map[string]string) (*response, error) {
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	if r.config.Token != "" {
+		headers["Authorization"] = "Bearer " + r.config.Token
+	}
+	return r.do(ctx, method, r.config.Admin+"/admin"+path, body, headers)
+}
+
+// loadSnapshot reads the active configuration the route cases are derived from
+func (r *runner) loadSnapshot(ctx context.Context) {
+	resp, err := r.admin(ctx, http.MethodGet, "/snapshot", nil, nil)
+	if err != nil {
+		r.snapshotErr = err
+		return
+	}
+	if resp.Status != http.StatusOK {
+		r.snapshotErr = fmt.Errorf("GET /admin/snapshot returned %d: %s", resp.Status, truncate(string(resp.Body)))
+		return
+	}
+	var current snapshot
+	if err := json.Unmarshal(resp.Body, &current); err != nil || current.Routes == nil {
+		r.snapshotErr = fmt.Errorf("GET /admin/snapshot returned an invalid snapshot: %q", truncate(string(resp.Body)))
+		return
+	}
+	r.snapshot = &current
+}
+
+// routes returns the routes reachable without a host or tenant, which the
+// route cases exercise
+func (r *runner) routes() []types.RouteConfig {
+	if r.snapshot == nil {
+		return nil
+	}
+	var routes []types.RouteConfig
+	for _, route := range r.snapshot.Routes.Routes {
+		if route.Host == "" && route.Tenant == "" && !strings.Contains(route.RouteName, "*") {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// requireRoutes skips route cases when the route table is unavailable
+func (r *runner) requireRoutes() error {
+	if r.snapshotErr != nil {
+		return skip("route table unavailable: %v", r.snapshotErr)
+	}
+	return nil
+}
+
+// routePath fills the path parameters of a route pattern
+func routePath(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "conformance"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// routeName names a route in case names
+func routeName(route types.RouteConfig) string {
+	return route.Method + " " + route.RouteName
+}
+
+// errorMessage returns the error field of a JSON error response
+func errorMessage(resp *response) (string, error) {
+	body, err := resp.object()
+	if err != nil {
+		return "", err
+	}
+	message, ok := body["error"].(string)
+	if !ok || message == "" {
+		return "", fmt.Errorf("expected an error field in %q", truncate(string(resp.Body)))
+	}
+	return message, nil
+}
+
+// truncate shortens response bodies quoted in failures
+func truncate(value string) string {
+	const limit = 200
+	if len(value) > limit {
+		return value[:limit] + "..."
+	}
+	return value
+}
@@ -0,0 +1,156 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dynamiccontrol/internal/admin"
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/reqctx"
+	"dynamiccontrol/internal/router"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newDeployment serves routes, health checks and the admin API the way the
+// server does
+func newDeployment(t *testing.T, token string) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	policyManager := opa.NewPolicyManager()
+	if err := policyManager.SetPolicy("deny_policy", "package deny_policy\n\ndefault allow = false\n"); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	rm := router.NewRouteManager(policyManager, validator.NewSchemaValidator())
+	t.Cleanup(rm.Stop)
+
+	engine := gin.New()
+	engine.Use(logging.Middleware())
+	engine.Use(reqctx.Middleware())
+	healthy := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "healthy"}) }
+	engine.GET("/health", healthy)
+	engine.GET("/health/live", healthy)
+	engine.GET("/health/ready", healthy)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{
+		{
+			RouteName:    "/v1/status",
+			Method:       "GET",
+			MockResponse: &types.MockResponseConfig{StatusCode: http.StatusOK, Template: `{"status": "ok"}`},
+		},
+		{
+			RouteName: "/v1/services/:serviceId/traffic",
+			Method:    "POST",
+			RequestSchema: map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"volume"},
+			},
+			MockResponse: &types.MockResponseConfig{StatusCode: http.StatusOK},
+		},
+		{
+			RouteName: "/v1/secrets",
+			Method:    "GET",
+			Policies:  []string{"deny_policy"},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	if err := rm.RegisterRoutes(engine); err != nil {
+		t.Fatalf("Failed to register routes: %v", err)
+	}
+
+	handler := admin.NewHandler()
+	handler.SetRouteManager(rm)
+	handler.SetPolicyManager(policyManager)
+	group := engine.Group("/admin")
+	group.Use(admin.Authenticate(token, nil))
+	handler.Register(group)
+
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunAgainstDeployment(t *testing.T) {
+	server := newDeployment(t, "secret")
+	report := Run(context.Background(), Config{Target: server.URL, Token: "secret"})
+
+	if report.Failed != 0 {
+		var text bytes.Buffer
+		WriteText(&text, report)
+		t.Fatalf("Expected the deployment to conform, got:\n%s", text.String())
+	}
+	outcomes := make(map[string]string)
+	for _, result := range report.Results {
+		outcomes[result.Name] = result.Status
+	}
+	for _, name := range []string{
+		"GET /v1/status matches",
+		"POST /v1/services/:serviceId/traffic rejects missing required fields",
+		"GET /v1/secrets denial",
+		"requests without a token are rejected",
+		"active configuration diffs empty",
+	} {
+		if outcomes[name] != StatusPass {
+			t.Errorf("Expected %q to pass, got %q", name, outcomes[name])
+		}
+	}
+}
+
+func TestRunReportsFailures(t *testing.T) {
+	server := newDeployment(t, "secret")
+	report := Run(context.Background(), Config{Target: server.URL, Token: "wrong"})
+
+	if report.Failed == 0 {
+		t.Fatal("Expected cases to fail without admin access")
+	}
+	for _, result := range report.Results {
+		if result.Name == "GET /health reports healthy" && result.Status != StatusPass {
+			t.Errorf("Expected data plane cases to run without admin access, got %+v", result)
+		}
+		if result.Suite == SuiteValidation && result.Status != StatusSkip {
+			t.Errorf("Expected route cases to be skipped without the route table, got %+v", result)
+		}
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	report := Report{
+		Passed: 1, Failed: 1, Skipped: 1,
+		Results: []Result{
+			{Suite: SuiteRouting, Name: "unknown paths return 404", Status: StatusPass},
+			{Suite: SuiteRouting, Name: "GET /v1/status matches", Status: StatusFail, Message: "matched no route"},
+			{Suite: SuiteAdmin, Name: "requests without a token are rejected", Status: StatusSkip, Message: "no admin token configured"},
+		},
+	}
+	var output bytes.Buffer
+	if err := Write(&output, report, FormatJUnit); err != nil {
+		t.Fatalf("Failed to write report: %v", err)
+	}
+
+	var document junitSuites
+	if err := xml.Unmarshal(output.Bytes(), &document); err != nil {
+		t.Fatalf("Failed to parse report: %v\n%s", err, output.String())
+	}
+	if document.Tests != 3 || document.Failures != 1 || document.Skipped != 1 || len(document.Suites) != 2 {
+		t.Fatalf("Unexpected totals %+v", document)
+	}
+	routing := document.Suites[0]
+	if routing.Name != SuiteRouting || routing.Failures != 1 || routing.Cases[1].Failure == nil || routing.Cases[1].Failure.Message != "matched no route" {
+		t.Errorf("Unexpected routing suite %+v", routing)
+	}
+	if !strings.Contains(output.String(), `<skipped message="no admin token configured">`) {
+		t.Errorf("Expected the skip reason in the report:\n%s", output.String())
+	}
+
+	if err := Write(&output, report, "yaml"); err == nil {
+		t.Error("Expected unsupported formats to be rejected")
+	}
+}
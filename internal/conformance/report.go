@@ -0,0 +1,140 @@
+package conformance
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Report formats
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+)
+
+// junitSuites is the JUnit XML document of a report
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+// junitSuite holds the cases of one suite
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+// junitCase is a single case; failures and skips carry their message
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+// junitMessage describes a failed or skipped case
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// Write writes a report in the given format
+func Write(w io.Writer, report Report, format string) error {
+	switch format {
+	case FormatJSON:
+		return WriteJSON(w, report)
+	case FormatJUnit:
+		return WriteJUnit(w, report)
+	case FormatText, "":
+		return WriteText(w, report)
+	default:
+		return fmt.Errorf("unsupported report format %q, expected text, json or junit", format)
+	}
+}
+
+// WriteJSON writes a report as indented JSON
+func WriteJSON(w io.Writer, report Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// WriteJUnit writes a report as JUnit XML with a test suite per conformance
+// suite, as read by CI systems
+func WriteJUnit(w io.Writer, report Report) error {
+	document := junitSuites{
+		Name:     "dynamiccontrol conformance",
+		Failures: report.Failed,
+		Skipped:  report.Skipped,
+		Time:     seconds(report.DurationMs),
+	}
+	index := make(map[string]int)
+	durations := make(map[string]float64)
+	for _, result := range report.Results {
+		i, ok := index[result.Suite]
+		if !ok {
+			i = len(document.Suites)
+			index[result.Suite] = i
+			document.Suites = append(document.Suites, junitSuite{Name: result.Suite})
+		}
+		suite := &document.Suites[i]
+		junit := junitCase{Name: result.Name, ClassName: "conformance." + result.Suite, Time: seconds(result.DurationMs)}
+		switch result.Status {
+		case StatusFail:
+			junit.Failure = &junitMessage{Message: result.Message}
+			suite.Failures++
+		case StatusSkip:
+			junit.Skipped = &junitMessage{Message: result.Message}
+			suite.Skipped++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, junit)
+		durations[result.Suite] += result.DurationMs
+		document.Tests++
+	}
+	for i := range document.Suites {
+		document.Suites[i].Time = seconds(durations[document.Suites[i].Name])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteText writes a report for terminals, one line per case
+func WriteText(w io.Writer, report Report) error {
+	var builder strings.Builder
+	for _, result := range report.Results {
+		fmt.Fprintf(&builder, "%-4s %s: %s\n", strings.ToUpper(result.Status), result.Suite, result.Name)
+		if result.Message != "" {
+			fmt.Fprintf(&builder, "     %s\n", result.Message)
+		}
+	}
+	fmt.Fprintf(&builder, "PASS: %d  FAIL: %d  SKIP: %d\n", report.Passed, report.Failed, report.Skipped)
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// seconds formats a duration in milliseconds as JUnit seconds
+func seconds(ms float64) string {
+	return fmt.Sprintf("%.3f", ms/1000)
+}
@@ -0,0 +1,193 @@
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+)
+
+// DefaultAllowMethods are allowed when a configuration declares no methods
+var DefaultAllowMethods = []string{"GET", "POST", "PUT", "DELETE", "HEAD"}
+
+// DefaultAllowHeaders are allowed when a configuration declares no headers
+var DefaultAllowHeaders = []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"}
+
+// Policy is a compiled CORS configuration
+type Policy struct {
+	anyOrigin        bool
+	origins          map[string]bool
+	wildcards        [][2]string
+	methods          map[string]bool
+	anyHeader        bool
+	headers          map[string]bool
+	allowMethods     string
+	allowHeaders     string
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+// Effective returns the configuration applying to a route: the route's own
+// configuration when set, the global one otherwise, and nil when disabled
+func Effective(global, route *types.CORSConfig) *types.CORSConfig {
+	config := global
+	if route != nil {
+		config = route
+	}
+	if config == nil || config.Disabled {
+		return nil
+	}
+	return config
+}
+
+// Compile validates a configuration and compiles it into a policy. A nil or
+// disabled configuration compiles to a nil policy.
+func Compile(config *types.CORSConfig) (*Policy, error) {
+	if config == nil || config.Disabled {
+		return nil, nil
+	}
+	if len(config.AllowOrigins) == 0 {
+		return nil, fmt.Errorf("cors requires at least one allowed origin")
+	}
+	if config.MaxAgeSeconds < 0 {
+		return nil, fmt.Errorf("cors maxAgeSeconds must not be negative")
+	}
+
+	policy := &Policy{
+		origins:          make(map[string]bool),
+		methods:          make(map[string]bool),
+		headers:          make(map[string]bool),
+		allowCredentials: config.AllowCredentials,
+	}
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		switch {
+		case origin == "*":
+			if config.AllowCredentials {
+				return nil, fmt.Errorf("cors origin \"*\" cannot be combined with allowCredentials")
+			}
+			policy.anyOrigin = true
+		case strings.Count(origin, "*") == 1 && strings.Contains(origin, "://*."):
+			parts := strings.SplitN(origin, "*", 2)
+			policy.wildcards = append(policy.wildcards, [2]string{parts[0], parts[1]})
+		case strings.Contains(origin, "*"):
+			return nil, fmt.Errorf("invalid cors origin %q", origin)
+		default:
+			policy.origins[origin] = true
+		}
+	}
+
+	methods := config.AllowMethods
+	if len(methods) == 0 {
+		methods = DefaultAllowMethods
+	}
+	upper := make([]string, len(methods))
+	for i, method := range methods {
+		upper[i] = strings.ToUpper(method)
+		policy.methods[upper[i]] = true
+	}
+	policy.allowMethods = strings.Join(upper, ", ")
+
+	headers := config.AllowHeaders
+	if len(headers) == 0 {
+		headers = DefaultAllowHeaders
+	}
+	for _, header := range headers {
+		if header == "*" {
+			policy.anyHeader = true
+			continue
+		}
+		policy.headers[textproto.CanonicalMIMEHeaderKey(header)] = true
+	}
+	policy.allowHeaders = strings.Join(headers, ", ")
+	policy.exposeHeaders = strings.Join(config.ExposeHeaders, ", ")
+	if config.MaxAgeSeconds > 0 {
+		policy.maxAge = strconv.Itoa(config.MaxAgeSeconds)
+	}
+	return policy, nil
+}
+
+// AllowsOrigin reports whether requests from origin are allowed
+func (p *Policy) AllowsOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	if p.anyOrigin || p.origins[origin] {
+		return true
+	}
+	for _, wildcard := range p.wildcards {
+		if strings.HasPrefix(origin, wildcard[0]) && strings.HasSuffix(origin, wildcard[1]) &&
+			len(origin) > len(wildcard[0])+len(wildcard[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPreflight reports whether r is a CORS preflight request
+func IsPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// Preflight writes the response to a preflight request. Preflights for a
+// disallowed origin, method or header are rejected with 403.
+func (p *Policy) Preflight(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if !p.AllowsOrigin(origin) || !p.methods[strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))] {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	requested := r.Header.Get("Access-Control-Request-Headers")
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header != "" && !p.anyHeader && !p.headers[textproto.CanonicalMIMEHeaderKey(header)] {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+
+	header := w.Header()
+	p.setOrigin(header, origin)
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	header.Set("Access-Control-Allow-Methods", p.allowMethods)
+	if p.anyHeader && requested != "" {
+		header.Set("Access-Control-Allow-Headers", requested)
+	} else if !p.anyHeader {
+		header.Set("Access-Control-Allow-Headers", p.allowHeaders)
+	}
+	if p.maxAge != "" {
+		header.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Apply sets the CORS response headers of an actual request from origin.
+// Disallowed origins get no CORS headers, so the browser blocks the response.
+func (p *Policy) Apply(header http.Header, origin string) {
+	if !p.AllowsOrigin(origin) {
+		return
+	}
+	p.setOrigin(header, origin)
+	if p.exposeHeaders != "" {
+		header.Set("Access-Control-Expose-Headers", p.exposeHeaders)
+	}
+}
+
+// setOrigin sets the allowed origin and credentials headers
+func (p *Policy) setOrigin(header http.Header, origin string) {
+	if p.anyOrigin {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Add("Vary", "Origin")
+	}
+	if p.allowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
@@ -0,0 +1,113 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestCompileRejectsInvalidConfig(t *testing.T) {
+	invalid := []*types.CORSConfig{
+		{},
+		{AllowOrigins: []string{"*"}, AllowCredentials: true},
+		{AllowOrigins: []string{"https://app*.example.com"}},
+		{AllowOrigins: []string{"https://app.example.com"}, MaxAgeSeconds: -1},
+	}
+	for _, config := range invalid {
+		if _, err := Compile(config); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+
+	policy, err := Compile(&types.CORSConfig{Disabled: true})
+	if err != nil || policy != nil {
+		t.Errorf("Expected disabled config to compile to nil, got %v, %v", policy, err)
+	}
+}
+
+func TestAllowsOrigin(t *testing.T) {
+	policy, err := Compile(&types.CORSConfig{AllowOrigins: []string{"https://app.example.com", "https://*.example.org"}})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	tests := map[string]bool{
+		"https://app.example.com":      true,
+		"https://APP.example.com":      true,
+		"https://a.b.example.org":      true,
+		"https://example.org":          false,
+		"http://app.example.com":       false,
+		"https://evil.com":             false,
+		"https://app.example.com.evil": false,
+	}
+	for origin, expected := range tests {
+		if policy.AllowsOrigin(origin) != expected {
+			t.Errorf("AllowsOrigin(%q) = %v, expected %v", origin, !expected, expected)
+		}
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	policy, err := Compile(&types.CORSConfig{
+		AllowOrigins:     []string{"https://app.example.com"},
+		AllowMethods:     []string{"get", "post"},
+		AllowCredentials: true,
+		MaxAgeSeconds:    600,
+	})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/status", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "content-type, x-request-id")
+	recorder := httptest.NewRecorder()
+	policy.Preflight(recorder, req)
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", recorder.Code)
+	}
+	header := recorder.Header()
+	if header.Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("Unexpected allowed origin %q", header.Get("Access-Control-Allow-Origin"))
+	}
+	if header.Get("Access-Control-Allow-Methods") != "GET, POST" {
+		t.Errorf("Unexpected allowed methods %q", header.Get("Access-Control-Allow-Methods"))
+	}
+	if header.Get("Access-Control-Allow-Credentials") != "true" || header.Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("Expected credentials and max age headers, got %v", header)
+	}
+
+	req.Header.Set("Access-Control-Request-Method", "DELETE")
+	recorder = httptest.NewRecorder()
+	policy.Preflight(recorder, req)
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected disallowed method to be rejected, got %d", recorder.Code)
+	}
+
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "X-Internal-Token")
+	recorder = httptest.NewRecorder()
+	policy.Preflight(recorder, req)
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected disallowed header to be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestEffective(t *testing.T) {
+	global := &types.CORSConfig{AllowOrigins: []string{"*"}}
+	route := &types.CORSConfig{AllowOrigins: []string{"https://app.example.com"}}
+
+	if Effective(global, nil) != global {
+		t.Error("Expected global config for routes without their own")
+	}
+	if Effective(global, route) != route {
+		t.Error("Expected route config to replace the global one")
+	}
+	if Effective(global, &types.CORSConfig{Disabled: true}) != nil {
+		t.Error("Expected disabled route config to turn CORS off")
+	}
+}
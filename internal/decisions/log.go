@@ -0,0 +1,137 @@
+package decisions
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// DefaultCapacity is the number of decisions kept in memory
+const DefaultCapacity = 1000
+
+// decisionOverhead approximates the memory of a decision besides its
+// encoded fields
+const decisionOverhead = 256
+
+// Sink receives every recorded decision, such as a shipper; Enqueue must
+// not block
+type Sink interface {
+	Enqueue(decision types.Decision)
+}
+
+// Log keeps the most recent policy decisions in a fixed-size ring buffer
+type Log struct {
+	mu       sync.RWMutex
+	entries  []types.Decision
+	start    int
+	count    int
+	sequence uint64
+	sinks    []Sink
+}
+
+// NewLog creates a decision log holding up to capacity decisions
+func NewLog(capacity int) *Log {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Log{entries: make([]types.Decision, capacity)}
+}
+
+// Record adds a decision, evicting the oldest one when the log is full
+func (l *Log) Record(decision types.Decision) types.Decision {
+	if decision.Timestamp.IsZero() {
+		decision.Timestamp = time.Now().UTC()
+	}
+	decision.ID = fmt.Sprintf("dec-%d-%d", decision.Timestamp.UnixNano(), atomic.AddUint64(&l.sequence, 1))
+
+	l.mu.Lock()
+	l.entries[(l.start+l.count)%len(l.entries)] = decision
+	if l.count == len(l.entries) {
+		l.start = (l.start + 1) % len(l.entries)
+	} else {
+		l.count++
+	}
+	sinks := l.sinks
+	l.mu.Unlock()
+
+	for _, sink := range sinks {
+		sink.Enqueue(decision)
+	}
+	return decision
+}
+
+// AddSink forwards every decision recorded from now on to sink
+func (l *Log) AddSink(sink Sink) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sinks = append(l.sinks, sink)
+}
+
+// List returns the recorded decisions, oldest first
+func (l *Log) List() []types.Decision {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	decisions := make([]types.Decision, 0, l.count)
+	for i := 0; i < l.count; i++ {
+		decisions = append(decisions, l.entries[(l.start+i)%len(l.entries)])
+	}
+	return decisions
+}
+
+// Name identifies the log to the memory manager
+func (l *Log) Name() string {
+	return "decision_log"
+}
+
+// SizeBytes returns the approximate memory held by recorded decisions
+func (l *Log) SizeBytes() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var size int64
+	for i := 0; i < l.count; i++ {
+		size += decisionSize(l.entries[(l.start+i)%len(l.entries)])
+	}
+	return size
+}
+
+// Shrink drops decisions, oldest first, until the log holds at most target
+// bytes
+func (l *Log) Shrink(target int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sizes := make([]int64, l.count)
+	var size int64
+	for i := range sizes {
+		sizes[i] = decisionSize(l.entries[(l.start+i)%len(l.entries)])
+		size += sizes[i]
+	}
+
+	var freed int64
+	for _, released := range sizes {
+		if size <= target {
+			break
+		}
+		l.entries[l.start] = types.Decision{}
+		l.start = (l.start + 1) % len(l.entries)
+		l.count--
+		size -= released
+		freed += released
+	}
+	return freed
+}
+
+// decisionSize approximates the memory held by a decision
+func decisionSize(decision types.Decision) int64 {
+	size := int64(decisionOverhead)
+	if encoded, err := json.Marshal(decision); err == nil {
+		size += int64(len(encoded))
+	}
+	return size
+}
@@ -0,0 +1,53 @@
+package decisions
+
+import (
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+// routes returns the routes of decisions in order
+func routes(decisions []types.Decision) []string {
+	names := make([]string, 0, len(decisions))
+	for _, decision := range decisions {
+		names = append(names, decision.Route)
+	}
+	return names
+}
+
+func TestLogEvictsOldestDecisions(t *testing.T) {
+	log := NewLog(3)
+	for _, route := range []string{"a", "b", "c", "d"} {
+		log.Record(types.Decision{Route: route})
+	}
+	if got := routes(log.List()); len(got) != 3 || got[0] != "b" || got[2] != "d" {
+		t.Errorf("Expected the three most recent decisions, got %v", got)
+	}
+}
+
+func TestLogShrinksOldestFirst(t *testing.T) {
+	log := NewLog(3)
+	for _, route := range []string{"a", "b", "c", "d"} {
+		log.Record(types.Decision{Route: route})
+	}
+
+	size := log.SizeBytes()
+	freed := log.Shrink(size - 1)
+	if freed == 0 || log.SizeBytes() != size-freed {
+		t.Errorf("Expected freed bytes to match the size change, freed %d of %d", freed, size)
+	}
+	if got := routes(log.List()); len(got) != 2 || got[0] != "c" || got[1] != "d" {
+		t.Errorf("Expected the oldest decision to be dropped, got %v", got)
+	}
+
+	log.Record(types.Decision{Route: "e"})
+	log.Record(types.Decision{Route: "f"})
+	if got := routes(log.List()); len(got) != 3 || got[0] != "d" || got[2] != "f" {
+		t.Errorf("Expected the log to fill up again after shrinking, got %v", got)
+	}
+
+	log.Shrink(0)
+	if len(log.List()) != 0 || log.SizeBytes() != 0 {
+		t.Errorf("Expected an empty log, got %v", routes(log.List()))
+	}
+}
@@ -0,0 +1,85 @@
+package decisions
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultSampleCapacity is the number of policy inputs kept per route
+const DefaultSampleCapacity = 100
+
+// Sample is a recorded policy input of one request
+type Sample struct {
+	RequestID string
+	Timestamp time.Time
+	Allowed   bool
+	Input     map[string]interface{}
+}
+
+// Samples keeps the most recent policy inputs of every route in fixed-size
+// ring buffers, so candidate policies can be replayed against real traffic
+type Samples struct {
+	mu       sync.RWMutex
+	capacity int
+	routes   map[string]*sampleRing
+}
+
+// sampleRing is the ring buffer of one route
+type sampleRing struct {
+	entries []Sample
+	next    int
+	full    bool
+}
+
+// NewSamples creates a sample store holding up to capacity inputs per route
+func NewSamples(capacity int) *Samples {
+	if capacity <= 0 {
+		capacity = DefaultSampleCapacity
+	}
+	return &Samples{
+		capacity: capacity,
+		routes:   make(map[string]*sampleRing),
+	}
+}
+
+// Record adds a sample for a route, evicting its oldest one when full
+func (s *Samples) Record(route string, sample Sample) {
+	if sample.Timestamp.IsZero() {
+		sample.Timestamp = time.Now().UTC()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ring, exists := s.routes[route]
+	if !exists {
+		ring = &sampleRing{entries: make([]Sample, s.capacity)}
+		s.routes[route] = ring
+	}
+	ring.entries[ring.next] = sample
+	ring.next = (ring.next + 1) % len(ring.entries)
+	if ring.next == 0 {
+		ring.full = true
+	}
+}
+
+// List returns up to limit of the most recent samples of a route, oldest
+// first; a limit of zero or less returns all of them
+func (s *Samples) List(route string, limit int) []Sample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ring, exists := s.routes[route]
+	if !exists {
+		return nil
+	}
+	var samples []Sample
+	if ring.full {
+		samples = append(append([]Sample{}, ring.entries[ring.next:]...), ring.entries[:ring.next]...)
+	} else {
+		samples = append([]Sample{}, ring.entries[:ring.next]...)
+	}
+	if limit > 0 && len(samples) > limit {
+		samples = samples[len(samples)-limit:]
+	}
+	return samples
+}
@@ -0,0 +1,516 @@
+package decisions
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/types"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Shipper defaults
+const (
+	DefaultBatchSize      = 100
+	DefaultSegmentBytes   = 4 * 1024 * 1024
+	DefaultQueueSize      = 10000
+	defaultRecentIDs      = 10000
+	defaultMinBackoff     = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+	defaultIdleInterval   = time.Second
+	segmentPrefix         = "segment-"
+	segmentSuffix         = ".ndjson"
+	cursorFile            = "cursor.json"
+	BatchIDHeader         = "X-Decision-Batch"
+	shipperRequestTimeout = 10 * time.Second
+)
+
+var (
+	shippedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "dynamiccontrol_decision_log_shipped_total",
+		Help: "Decisions delivered to the decision log sink",
+	})
+
+	shipFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "dynamiccontrol_decision_log_ship_failures_total",
+		Help: "Failed attempts to deliver a batch to the decision log sink",
+	})
+
+	droppedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "dynamiccontrol_decision_log_dropped_total",
+		Help: "Decisions dropped because the spool queue was full",
+	})
+
+	lagSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "dynamiccontrol_decision_log_lag_seconds",
+		Help: "Age of the oldest decision not yet delivered to the sink",
+	})
+
+	pendingBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "dynamiccontrol_decision_log_pending_bytes",
+		Help: "Bytes spooled to disk and not yet delivered to the sink",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(shippedTotal, shipFailures, droppedTotal, lagSeconds, pendingBytes)
+}
+
+// spoolCursor is the position of the next undelivered decision in the spool
+type spoolCursor struct {
+	Segment int   `json:"segment"`
+	Offset  int64 `json:"offset"`
+}
+
+// Shipper delivers decisions to an HTTP sink with at-least-once semantics.
+// Decisions are appended to an on-disk spool of newline-delimited JSON
+// segments and the delivered position is committed only after the sink
+// acknowledges a batch, so slow or unavailable sinks and restarts never lose
+// decisions. Sinks may receive a batch more than once and should dedupe by
+// decision ID; the shipper itself skips IDs it has recently delivered.
+type Shipper struct {
+	sinkURL      string
+	dir          string
+	client       *http.Client
+	batchSize    int
+	segmentBytes int64
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+
+	input  chan types.Decision
+	notify chan struct{}
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+
+	mu          sync.Mutex
+	writeSeq    int
+	writeFile   *os.File
+	writeSize   int64
+	recent      map[string]bool
+	recentOrder []string
+}
+
+// NewShipper creates a shipper spooling to dir and delivering to sinkURL
+func NewShipper(sinkURL, dir string) (*Shipper, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create decision spool directory: %w", err)
+	}
+
+	s := &Shipper{
+		sinkURL:      sinkURL,
+		dir:          dir,
+		client:       &http.Client{Timeout: shipperRequestTimeout},
+		batchSize:    DefaultBatchSize,
+		segmentBytes: DefaultSegmentBytes,
+		minBackoff:   defaultMinBackoff,
+		maxBackoff:   defaultMaxBackoff,
+		input:        make(chan types.Decision, DefaultQueueSize),
+		notify:       make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		recent:       make(map[string]bool),
+	}
+
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	// Always start a fresh segment so a partial line left by a crash is never appended to
+	if len(segments) > 0 {
+		s.writeSeq = segments[len(segments)-1]
+	}
+	if err := s.rotate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Start launches the spool writer and the delivery loop
+func (s *Shipper) Start() {
+	s.wg.Add(2)
+	go s.writeLoop()
+	go s.shipLoop()
+}
+
+// Stop flushes queued decisions to the spool and stops delivery. Undelivered
+// decisions remain on disk and are delivered after the next start.
+func (s *Shipper) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+		s.wg.Wait()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.writeFile != nil {
+			s.writeFile.Close()
+		}
+	})
+}
+
+// Enqueue queues a decision for delivery without blocking the request path.
+// Decisions are dropped and counted when the spool queue is full.
+func (s *Shipper) Enqueue(decision types.Decision) {
+	select {
+	case s.input <- decision:
+	default:
+		droppedTotal.Inc()
+	}
+}
+
+// Pending returns the number of decisions queued for the spool
+func (s *Shipper) Pending() int {
+	return len(s.input)
+}
+
+// writeLoop appends queued decisions to the current spool segment
+func (s *Shipper) writeLoop() {
+	defer s.wg.Done()
+	for {
+		select {
+		case decision := <-s.input:
+			s.append(decision)
+		case <-s.stop:
+			for {
+				select {
+				case decision := <-s.input:
+					s.append(decision)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// append writes one decision to the spool, rotating full segments
+func (s *Shipper) append(decision types.Decision) {
+	line, err := json.Marshal(decision)
+	if err != nil {
+		slog.Error("Failed to encode decision", "decision_id", decision.ID, "error", err)
+		return
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writeSize+int64(len(line)) > s.segmentBytes && s.writeSize > 0 {
+		if err := s.rotate(); err != nil {
+			slog.Error("Failed to rotate decision spool", "error", err)
+			return
+		}
+	}
+	n, err := s.writeFile.Write(line)
+	s.writeSize += int64(n)
+	if err != nil {
+		slog.Error("Failed to spool decision", "decision_id", decision.ID, "error", err)
+		return
+	}
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// rotate closes the current segment and opens the next one; callers hold mu
+func (s *Shipper) rotate() error {
+	if s.writeFile != nil {
+		s.writeFile.Close()
+	}
+	s.writeSeq++
+	file, err := os.OpenFile(s.segmentPath(s.writeSeq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open decision spool segment: %w", err)
+	}
+	s.writeFile = file
+	s.writeSize = 0
+	return nil
+}
+
+// shipLoop delivers spooled decisions in batches, backing off while the sink fails
+func (s *Shipper) shipLoop() {
+	defer s.wg.Done()
+	backoff := s.minBackoff
+
+	for {
+		delivered, err := s.shipBatch()
+		if err != nil {
+			shipFailures.Inc()
+			slog.Warn("Failed to ship decisions", "retry_in", backoff.String(), "error", err)
+			if !s.sleep(backoff) {
+				return
+			}
+			backoff *= 2
+			if backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+			continue
+		}
+		backoff = s.minBackoff
+
+		if delivered {
+			if !s.sleep(0) {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-s.notify:
+		case <-time.After(defaultIdleInterval):
+		}
+	}
+}
+
+// sleep waits for the given duration and returns false when the shipper is stopped
+func (s *Shipper) sleep(wait time.Duration) bool {
+	select {
+	case <-s.stop:
+		return false
+	case <-time.After(wait):
+		return true
+	}
+}
+
+// shipBatch delivers the next batch of spooled decisions and commits the
+// cursor past it. It reports whether any progress was made.
+func (s *Shipper) shipBatch() (bool, error) {
+	cursor := s.loadCursor()
+	segments, err := s.segments()
+	if err != nil {
+		return false, err
+	}
+	s.updatePending(segments, cursor)
+
+	s.mu.Lock()
+	writeSeq := s.writeSeq
+	s.mu.Unlock()
+
+	for _, segment := range segments {
+		if segment < cursor.Segment {
+			os.Remove(s.segmentPath(segment))
+			continue
+		}
+		if segment > cursor.Segment {
+			cursor = spoolCursor{Segment: segment}
+		}
+
+		batch, read, next, err := s.readBatch(cursor)
+		if err != nil {
+			return false, err
+		}
+
+		if read == 0 {
+			if segment == writeSeq {
+				lagSeconds.Set(0)
+				return false, nil
+			}
+			// Fully delivered segment; a trailing partial line is left by a crash
+			os.Remove(s.segmentPath(segment))
+			if err := s.saveCursor(spoolCursor{Segment: segment + 1}); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+
+		if len(batch) > 0 {
+			lagSeconds.Set(time.Since(batch[0].Timestamp).Seconds())
+			if err := s.send(batch); err != nil {
+				return false, err
+			}
+			s.remember(batch)
+			shippedTotal.Add(float64(len(batch)))
+		}
+
+		cursor.Offset = next
+		if err := s.saveCursor(cursor); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	lagSeconds.Set(0)
+	return false, nil
+}
+
+// readBatch reads up to batchSize complete lines from the cursor position and
+// returns the decisions not delivered recently, the number of lines read and
+// the next offset
+func (s *Shipper) readBatch(cursor spoolCursor) ([]types.Decision, int, int64, error) {
+	file, err := os.Open(s.segmentPath(cursor.Segment))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, cursor.Offset, nil
+		}
+		return nil, 0, 0, fmt.Errorf("failed to open decision spool segment: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(cursor.Offset, io.SeekStart); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to seek decision spool segment: %w", err)
+	}
+
+	reader := bufio.NewReader(file)
+	offset := cursor.Offset
+	var batch []types.Decision
+	read := 0
+	for read < s.batchSize {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			// Incomplete lines are still being written
+			break
+		}
+		offset += int64(len(line))
+		read++
+
+		var decision types.Decision
+		if err := json.Unmarshal(line, &decision); err != nil {
+			slog.Warn("Skipping corrupt decision spool entry", "error", err)
+			continue
+		}
+		if s.delivered(decision.ID) {
+			continue
+		}
+		batch = append(batch, decision)
+	}
+	return batch, read, offset, nil
+}
+
+// send posts a batch of decisions to the sink as newline-delimited JSON
+func (s *Shipper) send(batch []types.Decision) error {
+	var body bytes.Buffer
+	for _, decision := range batch {
+		line, _ := json.Marshal(decision)
+		body.Write(line)
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.sinkURL, &body)
+	if err != nil {
+		return fmt.Errorf("failed to build decision sink request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set(BatchIDHeader, batch[0].ID+".."+batch[len(batch)-1].ID)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("decision sink unavailable: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("decision sink returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// delivered reports whether a decision ID was recently delivered
+func (s *Shipper) delivered(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recent[id]
+}
+
+// remember records delivered IDs, forgetting the oldest beyond the limit
+func (s *Shipper) remember(batch []types.Decision) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, decision := range batch {
+		s.recent[decision.ID] = true
+		s.recentOrder = append(s.recentOrder, decision.ID)
+	}
+	for len(s.recentOrder) > defaultRecentIDs {
+		delete(s.recent, s.recentOrder[0])
+		s.recentOrder = s.recentOrder[1:]
+	}
+}
+
+// updatePending sets the pending bytes gauge from the spool and cursor
+func (s *Shipper) updatePending(segments []int, cursor spoolCursor) {
+	var pending int64
+	for _, segment := range segments {
+		if segment < cursor.Segment {
+			continue
+		}
+		info, err := os.Stat(s.segmentPath(segment))
+		if err != nil {
+			continue
+		}
+		pending += info.Size()
+		if segment == cursor.Segment {
+			pending -= cursor.Offset
+		}
+	}
+	pendingBytes.Set(float64(pending))
+}
+
+// segments returns the spool segment numbers in order
+func (s *Shipper) segments() ([]int, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read decision spool directory: %w", err)
+	}
+
+	var segments []int
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		seq, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix))
+		if err == nil {
+			segments = append(segments, seq)
+		}
+	}
+	sort.Ints(segments)
+	return segments, nil
+}
+
+// segmentPath returns the file path of a spool segment
+func (s *Shipper) segmentPath(seq int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%010d%s", segmentPrefix, seq, segmentSuffix))
+}
+
+// loadCursor reads the committed delivery position
+func (s *Shipper) loadCursor() spoolCursor {
+	var cursor spoolCursor
+	if data, err := ioutil.ReadFile(filepath.Join(s.dir, cursorFile)); err == nil {
+		json.Unmarshal(data, &cursor)
+	}
+	return cursor
+}
+
+// saveCursor atomically commits the delivery position
+func (s *Shipper) saveCursor(cursor spoolCursor) error {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return fmt.Errorf("failed to encode decision cursor: %w", err)
+	}
+
+	tmp := filepath.Join(s.dir, cursorFile+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write decision cursor: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, cursorFile)); err != nil {
+		return fmt.Errorf("failed to commit decision cursor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package decisions
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// testSink records delivered decision IDs and fails while unavailable is set
+type testSink struct {
+	mu          sync.Mutex
+	unavailable bool
+	received    map[string]int
+}
+
+func (ts *testSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.unavailable {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var decision types.Decision
+		if err := json.Unmarshal(scanner.Bytes(), &decision); err == nil {
+			ts.received[decision.ID]++
+		}
+	}
+}
+
+func (ts *testSink) setUnavailable(unavailable bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.unavailable = unavailable
+}
+
+func (ts *testSink) count() int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return len(ts.received)
+}
+
+func newTestShipper(t *testing.T, url, dir string) *Shipper {
+	shipper, err := NewShipper(url, dir)
+	if err != nil {
+		t.Fatalf("Failed to create shipper: %v", err)
+	}
+	shipper.batchSize = 3
+	shipper.segmentBytes = 512
+	shipper.minBackoff = 10 * time.Millisecond
+	shipper.maxBackoff = 20 * time.Millisecond
+	return shipper
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(3 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestShipperDeliversAfterSinkOutageAndRestart(t *testing.T) {
+	sink := &testSink{unavailable: true, received: make(map[string]int)}
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	decisionLog := NewLog(100)
+	shipper := newTestShipper(t, server.URL, dir)
+	decisionLog.AddSink(shipper)
+	shipper.Start()
+
+	for i := 0; i < 10; i++ {
+		decisionLog.Record(types.Decision{Route: fmt.Sprintf("GET /v1/%d", i), Allowed: true})
+	}
+	time.Sleep(50 * time.Millisecond)
+	shipper.Stop()
+
+	if sink.count() != 0 {
+		t.Fatalf("Expected nothing delivered while the sink is down, got %d", sink.count())
+	}
+
+	// A restarted shipper resumes from the spool once the sink recovers
+	sink.setUnavailable(false)
+	restarted := newTestShipper(t, server.URL, dir)
+	restarted.Start()
+	defer restarted.Stop()
+
+	waitFor(t, func() bool { return sink.count() == 10 })
+
+	restarted.Enqueue(types.Decision{ID: "dec-extra", Timestamp: time.Now()})
+	waitFor(t, func() bool { return sink.count() == 11 })
+}
+
+func TestShipperSkipsRecentlyDeliveredIDs(t *testing.T) {
+	sink := &testSink{received: make(map[string]int)}
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	shipper := newTestShipper(t, server.URL, dir)
+	shipper.Start()
+	defer shipper.Stop()
+
+	decision := types.Decision{ID: "dec-1", Timestamp: time.Now()}
+	shipper.Enqueue(decision)
+	waitFor(t, func() bool { return sink.count() == 1 })
+	shipper.Enqueue(decision)
+	shipper.Enqueue(types.Decision{ID: "dec-2", Timestamp: time.Now()})
+	waitFor(t, func() bool { return sink.count() == 2 })
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.received["dec-1"] != 1 {
+		t.Errorf("Expected dec-1 to be delivered once, got %d", sink.received["dec-1"])
+	}
+}
@@ -0,0 +1,67 @@
+package dependencies
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Outage records a dependency marked down by an operator
+type Outage struct {
+	Name     string    `json:"name"`
+	Reason   string    `json:"reason,omitempty"`
+	Actor    string    `json:"actor,omitempty"`
+	DownedAt time.Time `json:"downedAt"`
+}
+
+// Registry tracks the dependencies that are currently marked down. A
+// dependency is a free-form service name or the key of a route, such as
+// "GET /v1/services"; anything not marked down is considered up.
+type Registry struct {
+	mu      sync.RWMutex
+	outages map[string]Outage
+}
+
+// NewRegistry creates a registry in which every dependency is up
+func NewRegistry() *Registry {
+	return &Registry{outages: make(map[string]Outage)}
+}
+
+// MarkDown marks a dependency down, replacing any earlier outage
+func (r *Registry) MarkDown(name, reason, actor string) Outage {
+	outage := Outage{Name: name, Reason: reason, Actor: actor, DownedAt: time.Now().UTC()}
+	r.mu.Lock()
+	r.outages[name] = outage
+	r.mu.Unlock()
+	return outage
+}
+
+// MarkUp clears the outage of a dependency and reports whether it was down
+func (r *Registry) MarkUp(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, down := r.outages[name]
+	delete(r.outages, name)
+	return down
+}
+
+// Outage returns the outage of a dependency when it is marked down
+func (r *Registry) Outage(name string) (Outage, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	outage, down := r.outages[name]
+	return outage, down
+}
+
+// Outages lists the dependencies marked down, sorted by name
+func (r *Registry) Outages() []Outage {
+	r.mu.RLock()
+	outages := make([]Outage, 0, len(r.outages))
+	for _, outage := range r.outages {
+		outages = append(outages, outage)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(outages, func(i, j int) bool { return outages[i].Name < outages[j].Name })
+	return outages
+}
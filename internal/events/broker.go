@@ -0,0 +1,112 @@
+package events
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"dynamiccontrol/internal/types"
+)
+
+// DefaultSubscriberBuffer is the number of events buffered per subscriber
+// before events are dropped for that subscriber
+const DefaultSubscriberBuffer = 256
+
+// Broker fans events out to in-process subscribers such as the event stream
+// endpoint. Publishing never blocks: slow subscribers miss events instead.
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events matching its type filters
+type Subscription struct {
+	broker  *Broker
+	filters []string
+	events  chan types.Event
+	dropped uint64
+	once    sync.Once
+}
+
+// NewBroker creates an event broker without subscribers
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber for the given event types. A filter matches
+// an event type exactly or as a dotted prefix, so "policy" matches
+// "policy.denied"; no filters match every event.
+func (b *Broker) Subscribe(filters []string) *Subscription {
+	sub := &Subscription{
+		broker:  b,
+		filters: filters,
+		events:  make(chan types.Event, DefaultSubscriberBuffer),
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+// Publish delivers an event to every matching subscriber
+func (b *Broker) Publish(event types.Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subscribers {
+		if !sub.matches(event.Type) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+		}
+	}
+}
+
+// Subscribers returns the number of active subscriptions
+func (b *Broker) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers)
+}
+
+// Events returns the channel delivering matching events; it is closed by Close
+func (s *Subscription) Events() <-chan types.Event {
+	return s.events
+}
+
+// Dropped returns the number of events missed because the subscriber was too slow
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close unregisters the subscription
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.broker.mu.Lock()
+		delete(s.broker.subscribers, s)
+		s.broker.mu.Unlock()
+		close(s.events)
+	})
+}
+
+// matches reports whether an event type passes the subscription filters
+func (s *Subscription) matches(eventType string) bool {
+	if len(s.filters) == 0 {
+		return true
+	}
+	for _, filter := range s.filters {
+		if eventType == filter || strings.HasPrefix(eventType, filter+".") {
+			return true
+		}
+	}
+	return false
+}
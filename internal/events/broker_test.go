@@ -0,0 +1,126 @@
+package events
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func TestBrokerFiltersByTypePrefix(t *testing.T) {
+	broker := NewBroker()
+	policySub := broker.Subscribe([]string{"policy"})
+	allSub := broker.Subscribe(nil)
+	defer policySub.Close()
+	defer allSub.Close()
+
+	broker.Publish(NewEvent(types.EventRouteRegistered, "GET /v1/status", "", nil))
+	broker.Publish(NewEvent(types.EventPolicyDenied, "GET /v1/status", "", nil))
+
+	if event := <-policySub.Events(); event.Type != types.EventPolicyDenied {
+		t.Errorf("Expected policy.denied, got %s", event.Type)
+	}
+	if len(policySub.Events()) != 0 {
+		t.Error("Expected route events to be filtered out")
+	}
+	if len(allSub.Events()) != 2 {
+		t.Errorf("Expected 2 events for unfiltered subscriber, got %d", len(allSub.Events()))
+	}
+}
+
+func TestBrokerDropsForSlowSubscribers(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe(nil)
+
+	for i := 0; i < DefaultSubscriberBuffer+5; i++ {
+		broker.Publish(NewEvent(types.EventPolicyDenied, "", "", nil))
+	}
+	if sub.Dropped() != 5 {
+		t.Errorf("Expected 5 dropped events, got %d", sub.Dropped())
+	}
+
+	sub.Close()
+	if broker.Subscribers() != 0 {
+		t.Errorf("Expected no subscribers after close, got %d", broker.Subscribers())
+	}
+}
+
+func newStreamServer(broker *Broker) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/v1/events", StreamHandler(broker))
+	return httptest.NewServer(router)
+}
+
+func waitForSubscriber(t *testing.T, broker *Broker) {
+	deadline := time.Now().Add(2 * time.Second)
+	for broker.Subscribers() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Subscriber did not connect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStreamServerSentEvents(t *testing.T) {
+	broker := NewBroker()
+	server := newStreamServer(broker)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/v1/events?types=policy.denied")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Expected event stream, got %s", resp.Header.Get("Content-Type"))
+	}
+
+	waitForSubscriber(t, broker)
+	broker.Publish(NewEvent(types.EventPolicyDenied, "POST /v1/orders", "", nil))
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read stream: %v", err)
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	if !strings.HasPrefix(lines[0], "id:evt-") || lines[1] != "event:policy.denied" || !strings.Contains(lines[2], `"route":"POST /v1/orders"`) {
+		t.Errorf("Unexpected event: %v", lines)
+	}
+}
+
+func TestStreamWebSocket(t *testing.T) {
+	broker := NewBroker()
+	server := newStreamServer(broker)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/events?types=route", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	waitForSubscriber(t, broker)
+	broker.Publish(NewEvent(types.EventPolicyDenied, "", "", nil))
+	broker.Publish(NewEvent(types.EventRouteRemoved, "GET /v1/old", "", nil))
+
+	var event types.Event
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	if event.Type != types.EventRouteRemoved || event.Route != "GET /v1/old" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
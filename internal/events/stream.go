@@ -0,0 +1,120 @@
+package events
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// streamHeartbeat keeps idle event streams alive through proxies
+const streamHeartbeat = 15 * time.Second
+
+// upgrader accepts WebSocket connections from any origin; the stream is read-only
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// StreamHandler streams broker events over WebSocket, or as server-sent
+// events when the client does not request an upgrade. The optional "types"
+// query parameter takes a comma-separated list of event type filters.
+func StreamHandler(broker *Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filters []string
+		if value := c.Query("types"); value != "" {
+			for _, filter := range strings.Split(value, ",") {
+				if filter = strings.TrimSpace(filter); filter != "" {
+					filters = append(filters, filter)
+				}
+			}
+		}
+
+		if websocket.IsWebSocketUpgrade(c.Request) {
+			streamWebSocket(c, broker, filters)
+			return
+		}
+		streamSSE(c, broker, filters)
+	}
+}
+
+// streamSSE writes events as server-sent events until the client disconnects
+func streamSSE(c *gin.Context, broker *Broker, filters []string) {
+	sub := broker.Subscribe(filters)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			c.Render(-1, sse.Event{Id: event.ID, Event: event.Type, Data: event})
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			c.Writer.WriteString(": heartbeat\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// streamWebSocket writes events as JSON text messages until the client disconnects
+func streamWebSocket(c *gin.Context, broker *Broker, filters []string) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		slog.Warn("Failed to upgrade event stream", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	sub := broker.Subscribe(filters)
+	defer sub.Close()
+
+	// Reading is required to process close and pong frames
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamHeartbeat))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamHeartbeat)); err != nil {
+				return
+			}
+		}
+	}
+}
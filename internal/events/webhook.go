@@ -0,0 +1,144 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"dynamiccontrol/internal/sideeffects"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/pkg/webhook"
+)
+
+// Webhook delivery settings
+const (
+	SignatureHeader  = webhook.SignatureHeader
+	TimestampHeader  = webhook.TimestampHeader
+	EventTypeHeader  = webhook.EventTypeHeader
+	deliveryAttempts = 3
+	deliveryTimeout  = 5 * time.Second
+)
+
+// WebhookEmitter delivers events to webhook endpoints
+type WebhookEmitter struct {
+	client     *http.Client
+	defaultURL string
+	secret     []byte
+	inFlight   int64
+	effects    *sideeffects.Group
+}
+
+// NewWebhookEmitter creates a webhook emitter. Events without a route-specific
+// URL are sent to defaultURL; payloads are signed when secret is not empty.
+func NewWebhookEmitter(defaultURL, secret string) *WebhookEmitter {
+	return &WebhookEmitter{
+		client:     &http.Client{Timeout: deliveryTimeout},
+		defaultURL: defaultURL,
+		secret:     []byte(secret),
+		effects:    sideeffects.NewGroup(0),
+	}
+}
+
+// SetSideEffects sets the group deliveries run in, so they are bounded and
+// canceled on shutdown
+func (we *WebhookEmitter) SetSideEffects(group *sideeffects.Group) {
+	we.effects = group
+}
+
+// Emit delivers an event asynchronously to the given URL, or to the default URL when empty
+func (we *WebhookEmitter) Emit(url string, event types.Event) {
+	if url == "" {
+		url = we.defaultURL
+	}
+
+	slog.Info("Event", "type", event.Type, "route", event.Route, "revision", event.Revision)
+	if url == "" {
+		return
+	}
+
+	atomic.AddInt64(&we.inFlight, 1)
+	started := we.effects.Go(context.Background(), "webhook", func(ctx context.Context) {
+		defer atomic.AddInt64(&we.inFlight, -1)
+		if err := we.deliver(ctx, url, event); err != nil {
+			slog.Error("Failed to deliver event", "event_id", event.ID, "url", url, "error", err)
+		}
+	})
+	if !started {
+		atomic.AddInt64(&we.inFlight, -1)
+		slog.Warn("Event delivery dropped", "event_id", event.ID, "url", url)
+	}
+}
+
+// Pending returns the number of deliveries still in progress
+func (we *WebhookEmitter) Pending() int {
+	return int(atomic.LoadInt64(&we.inFlight))
+}
+
+// deliver posts an event to a webhook, retrying on failure
+func (we *WebhookEmitter) deliver(ctx context.Context, url string, event types.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= deliveryAttempts; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("failed to build webhook request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(EventTypeHeader, event.Type)
+
+		if len(we.secret) > 0 {
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			req.Header.Set(TimestampHeader, timestamp)
+			req.Header.Set(SignatureHeader, Sign(we.secret, timestamp, payload))
+		}
+
+		resp, err := we.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < http.StatusBadRequest {
+				return nil
+			}
+			err = fmt.Errorf("webhook returned status %d", resp.StatusCode)
+		}
+		lastErr = err
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("delivery canceled: %w", lastErr)
+		case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+		}
+	}
+
+	return lastErr
+}
+
+// Sign computes the webhook signature over the timestamp and payload using
+// the scheme verified by pkg/webhook
+func Sign(secret []byte, timestamp string, payload []byte) string {
+	return webhook.Sign(secret, timestamp, payload)
+}
+
+// eventSequence keeps event IDs unique within the same nanosecond
+var eventSequence uint64
+
+// NewEvent creates an event with a unique ID and the current timestamp
+func NewEvent(eventType, route, revision string, data map[string]interface{}) types.Event {
+	now := time.Now()
+	return types.Event{
+		ID:        fmt.Sprintf("evt-%d-%d", now.UnixNano(), atomic.AddUint64(&eventSequence, 1)),
+		Type:      eventType,
+		Route:     route,
+		Revision:  revision,
+		Timestamp: now,
+		Data:      data,
+	}
+}
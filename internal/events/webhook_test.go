@@ -0,0 +1,33 @@
+package events
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/pkg/webhook"
+)
+
+func TestWebhookDeliveriesVerify(t *testing.T) {
+	verified := make(chan error, 1)
+	verifier := webhook.NewVerifier("s3cret", 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := verifier.VerifyRequest(r)
+		verified <- err
+	}))
+	defer server.Close()
+
+	emitter := NewWebhookEmitter(server.URL, "s3cret")
+	emitter.Emit("", NewEvent(types.EventPolicyDenied, "GET /v1/status", "", nil))
+
+	select {
+	case err := <-verified:
+		if err != nil {
+			t.Errorf("Expected delivery to verify, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for delivery")
+	}
+}
@@ -0,0 +1,232 @@
+package graphqlapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"dynamiccontrol/internal/traffic"
+	"dynamiccontrol/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// PolicyLister lists the loaded policies
+type PolicyLister interface {
+	ListLoadedPolicies() []string
+}
+
+// Request is a GraphQL request
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// requestKey carries the incoming HTTP request to route resolvers
+type requestKey struct{}
+
+// Handler serves a GraphQL schema generated from the route configuration.
+// Its queries read the control plane state — routes, policies and traffic
+// rules — and call the GET routes, so consumers fetch them in one round
+// trip. Route fields are served in-process by the same handler as REST
+// traffic, with the headers of the GraphQL request, so route policies apply
+// unchanged. The Authorization header is not forwarded: it carries the
+// caller's admin credentials, which routes must never see.
+type Handler struct {
+	routes   http.Handler
+	policies PolicyLister
+	traffic  *traffic.Controller
+	schema   atomic.Pointer[graphql.Schema]
+}
+
+// NewHandler creates a GraphQL handler calling routes through the given
+// HTTP handler. Update must be called with the configuration before serving.
+func NewHandler(routes http.Handler, policies PolicyLister, controller *traffic.Controller) *Handler {
+	return &Handler{routes: routes, policies: policies, traffic: controller}
+}
+
+// Update regenerates the schema from a route configuration. The previous
+// schema is kept when generation fails.
+func (h *Handler) Update(config *types.RoutesConfig) error {
+	schema, err := h.buildSchema(config)
+	if err != nil {
+		return fmt.Errorf("failed to generate GraphQL schema: %w", err)
+	}
+	h.schema.Store(&schema)
+	return nil
+}
+
+// Register registers the GraphQL endpoint on a router group, accepting
+// queries in POST bodies and GET query strings
+func (h *Handler) Register(router gin.IRoutes) {
+	router.GET("", h.serve)
+	router.POST("", h.serve)
+}
+
+// Execute runs a GraphQL request against the current schema. The HTTP
+// request supplies the headers of the route calls.
+func (h *Handler) Execute(r *http.Request, request Request) *graphql.Result {
+	schema := h.schema.Load()
+	if schema == nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{
+			gqlerrors.NewFormattedError("GraphQL schema not generated yet"),
+		}}
+	}
+	return graphql.Do(graphql.Params{
+		Schema:         *schema,
+		RequestString:  request.Query,
+		OperationName:  request.OperationName,
+		VariableValues: request.Variables,
+		Context:        context.WithValue(r.Context(), requestKey{}, r),
+	})
+}
+
+// serve handles a GraphQL request. Query errors are reported in the result
+// with 200, as GraphQL clients expect.
+func (h *Handler) serve(c *gin.Context) {
+	var request Request
+	if c.Request.Method == http.MethodGet {
+		request.Query = c.Query("query")
+		request.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid variables",
+					"details": err.Error(),
+				})
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid JSON",
+			"details": err.Error(),
+		})
+		return
+	}
+	if strings.TrimSpace(request.Query) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "GraphQL request requires a query",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, h.Execute(c.Request, request))
+}
+
+// resolveRoute calls a GET route with the field arguments as path and query
+// parameters and returns its JSON response. Error responses become field
+// errors.
+func (h *Handler) resolveRoute(route types.RouteConfig) graphql.FieldResolveFn {
+	params := pathParams(route.RouteName)
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		segments := strings.Split(route.RouteName, "/")
+		for i, segment := range segments {
+			value, _ := p.Args[strings.TrimLeft(segment, ":*")].(string)
+			switch {
+			case strings.HasPrefix(segment, ":"):
+				segments[i] = url.PathEscape(value)
+			case strings.HasPrefix(segment, "*"):
+				// Wildcards match the rest of the path, slashes included
+				segments[i] = (&url.URL{Path: strings.TrimPrefix(value, "/")}).EscapedPath()
+			}
+		}
+		query := url.Values{}
+		for name, value := range p.Args {
+			if isPathParam(params, name) {
+				continue
+			}
+			if items, ok := value.([]interface{}); ok {
+				for _, item := range items {
+					query.Add(name, fmt.Sprint(item))
+				}
+				continue
+			}
+			query.Set(name, fmt.Sprint(value))
+		}
+
+		target := strings.Join(segments, "/")
+		if encoded := query.Encode(); encoded != "" {
+			target += "?" + encoded
+		}
+		req := httptest.NewRequest(http.MethodGet, target, nil).WithContext(p.Context)
+		if incoming, ok := p.Context.Value(requestKey{}).(*http.Request); ok {
+			req.Header = incoming.Header.Clone()
+			req.Host = incoming.Host
+			req.RemoteAddr = incoming.RemoteAddr
+		}
+		req.Header.Del("Authorization")
+		req.Header.Del("Content-Type")
+		req.Header.Del("Content-Length")
+		req.Header.Del("Accept-Encoding")
+		req.Header.Set("Accept", "application/json")
+
+		recorder := httptest.NewRecorder()
+		h.routes.ServeHTTP(recorder, req)
+		if recorder.Code >= http.StatusBadRequest {
+			return nil, routeError(route, recorder)
+		}
+		return decodeResponse(route, recorder.Body.Bytes())
+	}
+}
+
+// isPathParam reports whether an argument is a path parameter
+func isPathParam(params []string, name string) bool {
+	for _, param := range params {
+		if param == name {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeResponse decodes the JSON response of a route, or the lines of a
+// streamed one
+func decodeResponse(route types.RouteConfig, body []byte) (interface{}, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+	if !route.Streaming {
+		var document interface{}
+		if err := json.Unmarshal(body, &document); err != nil {
+			return nil, fmt.Errorf("GET %s returned invalid JSON: %w", route.RouteName, err)
+		}
+		return document, nil
+	}
+	items := []interface{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(nil, len(body)+1)
+	for scanner.Scan() {
+		var item interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			return nil, fmt.Errorf("GET %s returned invalid JSON: %w", route.RouteName, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// routeError describes the error response of a route
+func routeError(route types.RouteConfig, recorder *httptest.ResponseRecorder) error {
+	var response struct {
+		Error   string      `json:"error"`
+		Details interface{} `json:"details"`
+	}
+	message := http.StatusText(recorder.Code)
+	if json.Unmarshal(recorder.Body.Bytes(), &response) == nil && response.Error != "" {
+		message = response.Error
+		if response.Details != nil {
+			message = fmt.Sprintf("%s: %v", message, response.Details)
+		}
+	}
+	return fmt.Errorf("GET %s returned %d: %s", route.RouteName, recorder.Code, message)
+}
@@ -0,0 +1,134 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dynamiccontrol/internal/traffic"
+	"dynamiccontrol/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// staticPolicies lists a fixed set of policies
+type staticPolicies []string
+
+func (p staticPolicies) ListLoadedPolicies() []string {
+	return p
+}
+
+func TestQueriesStateAndRoutesInOneRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	routes := gin.New()
+	routes.GET("/v1/services/:serviceId/status", func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Admin credentials forwarded"})
+			return
+		}
+		if c.GetHeader("X-Api-Key") != "key" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Request denied by policy"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"service": c.Param("serviceId"), "healthy": true, "replicas": 3, "region": c.Query("region")})
+	})
+
+	controller := traffic.NewController()
+	if _, err := controller.Add("billing", types.TrafficRequest{TrafficType: "incoming", Volume: 5, Priority: "high"}); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	handler := NewHandler(routes, staticPolicies{"service_policy"}, controller)
+	err := handler.Update(&types.RoutesConfig{Routes: []types.RouteConfig{
+		{
+			RouteName: "/v1/services/:serviceId/status",
+			Method:    "GET",
+			Policies:  []string{"service_policy"},
+			QuerySchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"region": map[string]interface{}{"type": "string"}},
+			},
+			ResponseSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"service":  map[string]interface{}{"type": "string"},
+					"healthy":  map[string]interface{}{"type": "boolean"},
+					"replicas": map[string]interface{}{"type": "integer"},
+					"region":   map[string]interface{}{"type": "string"},
+				},
+			},
+		},
+		{RouteName: "/v1/services/:serviceId/traffic", Method: "POST", Handler: types.HandlerTraffic},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+	engine := gin.New()
+	handler.Register(engine.Group("/graphql"))
+
+	query := func(query, apiKey string) map[string]interface{} {
+		body, _ := json.Marshal(Request{Query: query})
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer admin-token")
+		req.Header.Set("X-Api-Key", apiKey)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		var result map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &result)
+		return result
+	}
+
+	result := query(`{
+		routes(method: "GET") { routeName policies }
+		policies { name routes { method routeName } }
+		trafficRules(service: "billing") { serviceId volume priority status }
+		getV1ServicesServiceIdStatus(serviceId: "billing", region: "eu") { service healthy replicas region }
+	}`, "key")
+	if result["errors"] != nil {
+		t.Fatalf("Unexpected errors %v", result["errors"])
+	}
+	data := result["data"].(map[string]interface{})
+	if routes := data["routes"].([]interface{}); len(routes) != 1 {
+		t.Errorf("Expected the GET route only, got %v", routes)
+	}
+	policy := data["policies"].([]interface{})[0].(map[string]interface{})
+	if policy["name"] != "service_policy" || len(policy["routes"].([]interface{})) != 1 {
+		t.Errorf("Unexpected policy %v", policy)
+	}
+	rules := data["trafficRules"].([]interface{})
+	if len(rules) != 1 || rules[0].(map[string]interface{})["volume"] != 5.0 {
+		t.Errorf("Unexpected traffic rules %v", rules)
+	}
+	status := data["getV1ServicesServiceIdStatus"].(map[string]interface{})
+	if status["service"] != "billing" || status["healthy"] != true || status["replicas"] != 3.0 || status["region"] != "eu" {
+		t.Errorf("Unexpected route response %v", status)
+	}
+
+	// Route errors, such as policy denials, become field errors
+	result = query(`{ getV1ServicesServiceIdStatus(serviceId: "billing") { service } }`, "")
+	errors, _ := result["errors"].([]interface{})
+	if len(errors) != 1 || !strings.Contains(errors[0].(map[string]interface{})["message"].(string), "403") {
+		t.Errorf("Expected a denied route to report a field error, got %v", result)
+	}
+}
+
+func TestServeRejectsMissingQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewHandler(http.NotFoundHandler(), staticPolicies{}, traffic.NewController())
+	if err := handler.Update(&types.RoutesConfig{}); err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+	engine := gin.New()
+	handler.Register(engine.Group("/graphql"))
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/graphql", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected a request without a query to be rejected, got %d", recorder.Code)
+	}
+}
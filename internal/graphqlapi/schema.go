@@ -0,0 +1,334 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dynamiccontrol/internal/openapi"
+	"dynamiccontrol/internal/types"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// fieldName matches names GraphQL accepts for fields, arguments and types
+var fieldName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// jsonScalar holds any JSON value, such as documents without a schema
+var jsonScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "Any JSON value",
+	Serialize:   func(value interface{}) interface{} { return value },
+	ParseValue:  func(value interface{}) interface{} { return value },
+	ParseLiteral: func(value ast.Value) interface{} {
+		return parseLiteral(value)
+	},
+})
+
+// parseLiteral converts a GraphQL literal to its JSON value
+func parseLiteral(value ast.Value) interface{} {
+	switch value := value.(type) {
+	case *ast.StringValue:
+		return value.Value
+	case *ast.BooleanValue:
+		return value.Value
+	case *ast.IntValue:
+		number, _ := strconv.ParseFloat(value.Value, 64)
+		return number
+	case *ast.FloatValue:
+		number, _ := strconv.ParseFloat(value.Value, 64)
+		return number
+	case *ast.ListValue:
+		items := make([]interface{}, len(value.Values))
+		for i, item := range value.Values {
+			items[i] = parseLiteral(item)
+		}
+		return items
+	case *ast.ObjectValue:
+		object := make(map[string]interface{}, len(value.Fields))
+		for _, field := range value.Fields {
+			object[field.Name.Value] = parseLiteral(field.Value)
+		}
+		return object
+	default:
+		return nil
+	}
+}
+
+// schemaBuilder generates the GraphQL schema of a route configuration
+type schemaBuilder struct {
+	handler *Handler
+	config  *types.RoutesConfig
+	// types holds the generated object types by name, so that names stay
+	// unique across routes
+	types map[string]bool
+}
+
+// buildSchema generates the schema of the control plane state and of the
+// GET routes of a configuration
+func (h *Handler) buildSchema(config *types.RoutesConfig) (graphql.Schema, error) {
+	b := &schemaBuilder{handler: h, config: config, types: make(map[string]bool)}
+	fields := b.stateFields()
+	for _, route := range config.Routes {
+		if route.Method != "GET" {
+			continue
+		}
+		name := openapi.OperationID(route)
+		if _, exists := fields[name]; exists || !fieldName.MatchString(name) {
+			continue
+		}
+		fields[name] = b.routeField(name, route)
+	}
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: fields}),
+	})
+}
+
+// routeField generates the query field serving a GET route. Path parameters
+// become required arguments and the properties of the query schema optional
+// ones; the field type follows the response schema.
+func (b *schemaBuilder) routeField(name string, route types.RouteConfig) *graphql.Field {
+	args := graphql.FieldConfigArgument{}
+	for _, param := range pathParams(route.RouteName) {
+		if fieldName.MatchString(param) {
+			args[param] = &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}
+		}
+	}
+	properties, _ := route.QuerySchema["properties"].(map[string]interface{})
+	for property, schema := range properties {
+		if _, exists := args[property]; exists || !fieldName.MatchString(property) {
+			continue
+		}
+		propertySchema, _ := schema.(map[string]interface{})
+		args[property] = &graphql.ArgumentConfig{Type: inputType(propertySchema)}
+	}
+
+	output := graphql.Output(jsonScalar)
+	if route.ResponseSchema != nil {
+		output = b.outputType(pascalCase(name)+"Response", route.ResponseSchema)
+	}
+	if route.Streaming {
+		if _, isList := output.(*graphql.List); !isList {
+			output = graphql.NewList(output)
+		}
+	}
+	return &graphql.Field{
+		Type:        output,
+		Description: fmt.Sprintf("GET %s", route.RouteName),
+		Args:        args,
+		Resolve:     b.handler.resolveRoute(route),
+	}
+}
+
+// outputType maps a JSON schema to a GraphQL output type. Objects with
+// properties become object types named after their position, other values
+// without a scalar type the JSON scalar.
+func (b *schemaBuilder) outputType(name string, schema map[string]interface{}) graphql.Output {
+	switch schemaType(schema) {
+	case "string":
+		return graphql.String
+	case "integer":
+		return graphql.Int
+	case "number":
+		return graphql.Float
+	case "boolean":
+		return graphql.Boolean
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		return graphql.NewList(b.outputType(name+"Item", items))
+	case "object":
+		properties, _ := schema["properties"].(map[string]interface{})
+		fields := graphql.Fields{}
+		for property, propertySchema := range properties {
+			nested, _ := propertySchema.(map[string]interface{})
+			if fieldName.MatchString(property) {
+				fields[property] = &graphql.Field{Type: b.outputType(name+pascalCase(property), nested)}
+			}
+		}
+		if len(fields) == 0 {
+			return jsonScalar
+		}
+		for b.types[name] {
+			name += "_"
+		}
+		b.types[name] = true
+		return graphql.NewObject(graphql.ObjectConfig{Name: name, Fields: fields})
+	default:
+		return jsonScalar
+	}
+}
+
+// inputType maps the JSON schema of a query parameter to a GraphQL input type
+func inputType(schema map[string]interface{}) graphql.Input {
+	switch schemaType(schema) {
+	case "integer":
+		return graphql.Int
+	case "number":
+		return graphql.Float
+	case "boolean":
+		return graphql.Boolean
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		return graphql.NewList(inputType(items))
+	default:
+		return graphql.String
+	}
+}
+
+// schemaType returns the first declared type of a JSON schema other than null
+func schemaType(schema map[string]interface{}) string {
+	switch declared := schema["type"].(type) {
+	case string:
+		return declared
+	case []interface{}:
+		for _, item := range declared {
+			if name, ok := item.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return ""
+}
+
+// pathParams returns the names of the parameters of a route path, in order
+func pathParams(routeName string) []string {
+	var params []string
+	for _, segment := range strings.Split(routeName, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+		}
+	}
+	return params
+}
+
+// pascalCase upper-cases the first letter of a name
+func pascalCase(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// routeType describes a configured route
+var routeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Route",
+	Fields: graphql.Fields{
+		"routeName":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"method":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"handler":        &graphql.Field{Type: graphql.String},
+		"host":           &graphql.Field{Type: graphql.String},
+		"policies":       &graphql.Field{Type: graphql.NewList(graphql.String)},
+		"contentTypes":   &graphql.Field{Type: graphql.NewList(graphql.String)},
+		"streaming":      &graphql.Field{Type: graphql.Boolean},
+		"requestSchema":  &graphql.Field{Type: jsonScalar},
+		"responseSchema": &graphql.Field{Type: jsonScalar},
+		"querySchema":    &graphql.Field{Type: jsonScalar},
+	},
+})
+
+// trafficRuleType describes the traffic rule of a service
+var trafficRuleType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TrafficRule",
+	Fields: graphql.Fields{
+		"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"serviceId":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"trafficType": &graphql.Field{Type: graphql.String},
+		"volume":      &graphql.Field{Type: graphql.Float},
+		"priority":    &graphql.Field{Type: graphql.String},
+		"status":      &graphql.Field{Type: graphql.String},
+		"metadata":    &graphql.Field{Type: jsonScalar},
+		"schedule":    &graphql.Field{Type: jsonScalar},
+		"opensAt":     &graphql.Field{Type: graphql.DateTime},
+		"createdAt":   &graphql.Field{Type: graphql.DateTime},
+	},
+})
+
+// policy is a loaded policy with the routes enforcing it
+type policy struct {
+	Name   string              `json:"name"`
+	Routes []types.RouteConfig `json:"routes"`
+}
+
+// policyType describes a loaded policy
+var policyType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Policy",
+	Fields: graphql.Fields{
+		"name":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"routes": &graphql.Field{Type: graphql.NewList(routeType)},
+	},
+})
+
+// stateFields returns the query fields of the control plane state
+func (b *schemaBuilder) stateFields() graphql.Fields {
+	config, h := b.config, b.handler
+	return graphql.Fields{
+		"routes": &graphql.Field{
+			Type:        graphql.NewList(routeType),
+			Description: "Configured routes, optionally of a method",
+			Args: graphql.FieldConfigArgument{
+				"method": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				method, _ := p.Args["method"].(string)
+				routes := make([]types.RouteConfig, 0, len(config.Routes))
+				for _, route := range config.Routes {
+					if method == "" || strings.EqualFold(route.Method, method) {
+						routes = append(routes, route)
+					}
+				}
+				return routes, nil
+			},
+		},
+		"policies": &graphql.Field{
+			Type:        graphql.NewList(policyType),
+			Description: "Loaded policies and the routes enforcing them",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				names := h.policies.ListLoadedPolicies()
+				sort.Strings(names)
+				policies := make([]policy, len(names))
+				for i, name := range names {
+					policies[i] = policy{Name: name, Routes: routesEnforcing(config, name)}
+				}
+				return policies, nil
+			},
+		},
+		"services": &graphql.Field{
+			Type:        graphql.NewList(graphql.String),
+			Description: "Services with traffic rules",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return h.traffic.Services(), nil
+			},
+		},
+		"trafficRules": &graphql.Field{
+			Type:        graphql.NewList(trafficRuleType),
+			Description: "Traffic rules of a service, highest priority first",
+			Args: graphql.FieldConfigArgument{
+				"service": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				service, _ := p.Args["service"].(string)
+				return h.traffic.Rules(service), nil
+			},
+		},
+	}
+}
+
+// routesEnforcing returns the routes of a configuration enforcing a policy
+func routesEnforcing(config *types.RoutesConfig, name string) []types.RouteConfig {
+	var routes []types.RouteConfig
+	for _, route := range config.Routes {
+		for _, policyName := range route.Policies {
+			if policyName == name {
+				routes = append(routes, route)
+				break
+			}
+		}
+	}
+	return routes
+}
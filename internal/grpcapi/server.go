@@ -0,0 +1,251 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	pb "dynamiccontrol/internal/grpcapi/controlplanev1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Server exposes the control plane over gRPC. Calls are translated into
+// HTTP requests and served in-process by the same handler as REST traffic,
+// so the policy and validation pipeline is shared rather than duplicated.
+type Server struct {
+	pb.UnimplementedControlPlaneServer
+	handler http.Handler
+	server  *grpc.Server
+}
+
+// NewServer creates a gRPC server backed by the given HTTP handler
+func NewServer(handler http.Handler) *Server {
+	s := &Server{
+		handler: handler,
+		server:  grpc.NewServer(),
+	}
+	pb.RegisterControlPlaneServer(s.server, s)
+	return s
+}
+
+// Serve accepts gRPC connections on the listener until Stop is called
+func (s *Server) Serve(listener net.Listener) error {
+	return s.server.Serve(listener)
+}
+
+// Stop gracefully stops the server
+func (s *Server) Stop() {
+	s.server.GracefulStop()
+}
+
+// GetStatus mirrors GET /v1/status
+func (s *Server) GetStatus(ctx context.Context, req *pb.GetStatusRequest) (*pb.RouteResponse, error) {
+	return s.call(ctx, http.MethodGet, "/v1/status", nil, req.GetHeaders(), nil)
+}
+
+// ManageTraffic mirrors POST /v1/services/:serviceId/traffic
+func (s *Server) ManageTraffic(ctx context.Context, req *pb.ManageTrafficRequest) (*pb.RouteResponse, error) {
+	if req.GetServiceId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "service_id is required")
+	}
+	path := "/v1/services/" + url.PathEscape(req.GetServiceId()) + "/traffic"
+	var body interface{}
+	if req.GetBody() != nil {
+		body = req.GetBody().AsMap()
+	}
+	return s.call(ctx, http.MethodPost, path, nil, req.GetHeaders(), body)
+}
+
+// Invoke calls any configured route by method and path
+func (s *Server) Invoke(ctx context.Context, req *pb.InvokeRequest) (*pb.RouteResponse, error) {
+	method := strings.ToUpper(req.GetMethod())
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !strings.HasPrefix(req.GetPath(), "/") {
+		return nil, status.Error(codes.InvalidArgument, "path must start with /")
+	}
+	var body interface{}
+	if req.GetBody() != nil {
+		body = req.GetBody().AsInterface()
+	}
+	return s.call(ctx, method, req.GetPath(), req.GetQuery(), req.GetHeaders(), body)
+}
+
+// GetOperation mirrors GET /v1/operations/:operationId
+func (s *Server) GetOperation(ctx context.Context, req *pb.GetOperationRequest) (*pb.RouteResponse, error) {
+	if req.GetOperationId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "operation_id is required")
+	}
+	return s.call(ctx, http.MethodGet, "/v1/operations/"+url.PathEscape(req.GetOperationId()), nil, nil, nil)
+}
+
+// ListRoutes mirrors GET /admin/routes
+func (s *Server) ListRoutes(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	return s.list(ctx, "/admin/routes", req)
+}
+
+// ListPolicies mirrors GET /admin/policies
+func (s *Server) ListPolicies(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	return s.list(ctx, "/admin/policies", req)
+}
+
+// ListDecisions mirrors GET /admin/decisions
+func (s *Server) ListDecisions(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	return s.list(ctx, "/admin/decisions", req)
+}
+
+// list serves an admin collection and converts the page into a list response
+func (s *Server) list(ctx context.Context, path string, req *pb.ListRequest) (*pb.ListResponse, error) {
+	resp, err := s.call(ctx, http.MethodGet, path, req.GetQuery(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	page := resp.GetBody().GetStructValue()
+	list := &pb.ListResponse{
+		Total:      int32(page.GetFields()["total"].GetNumberValue()),
+		NextCursor: page.GetFields()["nextCursor"].GetStringValue(),
+	}
+	for _, item := range page.GetFields()["items"].GetListValue().GetValues() {
+		list.Items = append(list.Items, item.GetStructValue())
+	}
+	return list, nil
+}
+
+// call serves a request through the HTTP handler. Successful responses are
+// returned as route responses; error statuses become gRPC status errors.
+func (s *Server) call(ctx context.Context, method, path string, query, headers map[string]string, body interface{}) (*pb.RouteResponse, error) {
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to encode body: %v", err)
+		}
+		reader = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, path, reader)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to build request: %v", err)
+	}
+	if len(query) > 0 {
+		values := url.Values{}
+		for key, value := range query {
+			values.Set(key, value)
+		}
+		req.URL.RawQuery = values.Encode()
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, values := range md {
+			if strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-") || key == "content-type" {
+				continue
+			}
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		req.RemoteAddr = p.Addr.String()
+	}
+
+	recorder := httptest.NewRecorder()
+	s.handler.ServeHTTP(recorder, req)
+
+	resp, err := toRouteResponse(recorder)
+	if err != nil {
+		return nil, err
+	}
+	if recorder.Code >= http.StatusBadRequest {
+		return nil, statusError(recorder.Code, resp.GetBody())
+	}
+	return resp, nil
+}
+
+// toRouteResponse converts a recorded HTTP response into a route response
+func toRouteResponse(recorder *httptest.ResponseRecorder) (*pb.RouteResponse, error) {
+	resp := &pb.RouteResponse{
+		StatusCode: int32(recorder.Code),
+		Headers:    make(map[string]string),
+	}
+	for key := range recorder.Header() {
+		resp.Headers[strings.ToLower(key)] = recorder.Header().Get(key)
+	}
+
+	if recorder.Body.Len() == 0 {
+		return resp, nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &decoded); err != nil {
+		decoded = recorder.Body.String()
+	}
+	value, err := structpb.NewValue(decoded)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode response body: %v", err)
+	}
+	resp.Body = value
+	return resp, nil
+}
+
+// statusError maps an HTTP error response onto a gRPC status, keeping the
+// error and details fields of the body in the message
+func statusError(code int, body *structpb.Value) error {
+	message := http.StatusText(code)
+	if fields := body.GetStructValue().GetFields(); fields != nil {
+		if msg := fields["error"].GetStringValue(); msg != "" {
+			message = msg
+		}
+		if details, ok := fields["details"]; ok {
+			message = fmt.Sprintf("%s: %v", message, details.AsInterface())
+		}
+	}
+	return status.Error(grpcCode(code), message)
+}
+
+// grpcCode returns the gRPC code corresponding to an HTTP status
+func grpcCode(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
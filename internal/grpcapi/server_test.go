@@ -0,0 +1,93 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	pb "dynamiccontrol/internal/grpcapi/controlplanev1"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func newTestClient(t *testing.T, handler http.Handler) pb.ControlPlaneClient {
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(handler)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewControlPlaneClient(conn)
+}
+
+func TestManageTrafficUsesHTTPHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/services/:serviceId/traffic", func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer token" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied", "details": "missing token"})
+			return
+		}
+		var body map[string]interface{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"serviceId": c.Param("serviceId"), "action": body["action"]})
+	})
+	client := newTestClient(t, router)
+
+	body, _ := structpb.NewStruct(map[string]interface{}{"action": "drain"})
+	_, err := client.ManageTraffic(context.Background(), &pb.ManageTrafficRequest{ServiceId: "svc", Body: body})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Expected PermissionDenied, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer token")
+	resp, err := client.ManageTraffic(ctx, &pb.ManageTrafficRequest{ServiceId: "svc", Body: body})
+	if err != nil {
+		t.Fatalf("ManageTraffic failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", resp.StatusCode)
+	}
+	fields := resp.Body.GetStructValue().GetFields()
+	if fields["serviceId"].GetStringValue() != "svc" || fields["action"].GetStringValue() != "drain" {
+		t.Errorf("Unexpected body: %v", resp.Body)
+	}
+}
+
+func TestListRoutesConvertsPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/routes", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"items":      []gin.H{{"id": "GET /v1/status"}},
+			"total":      3,
+			"nextCursor": c.Query("limit"),
+		})
+	})
+	client := newTestClient(t, router)
+
+	list, err := client.ListRoutes(context.Background(), &pb.ListRequest{Query: map[string]string{"limit": "1"}})
+	if err != nil {
+		t.Fatalf("ListRoutes failed: %v", err)
+	}
+	if list.Total != 3 || list.NextCursor != "1" || len(list.Items) != 1 {
+		t.Errorf("Unexpected list response: %v", list)
+	}
+}
@@ -0,0 +1,328 @@
+package guardrails
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// CriticalLabel marks a route as critical when set to "true"
+const CriticalLabel = "critical"
+
+// Guardrail rules reported in violations
+const (
+	RuleMaxRouteChanges  = "max-route-changes"
+	RuleMaxPolicyChanges = "max-policy-changes"
+	RuleWeightCooldown   = "weight-cooldown"
+	RuleCriticalRoute    = "critical-route"
+)
+
+// Config bounds the blast radius of configuration changes. Zero values
+// disable the corresponding limit.
+type Config struct {
+	MaxRouteChanges  int           `json:"maxRouteChanges"`
+	MaxPolicyChanges int           `json:"maxPolicyChanges"`
+	WeightCooldown   time.Duration `json:"weightCooldown"`
+}
+
+// Violation describes a change rejected by a guardrail
+type Violation struct {
+	Rule     string `json:"rule"`
+	Resource string `json:"resource,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Error is returned when a change violates one or more guardrails
+type Error struct {
+	Violations []Violation
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return "change rejected by guardrails: " + strings.Join(messages, "; ")
+}
+
+// BreakGlass is a time-limited window during which guardrails are bypassed
+type BreakGlass struct {
+	Actor     string    `json:"actor"`
+	Reason    string    `json:"reason"`
+	OpenedAt  time.Time `json:"openedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Guard checks route and policy changes against the configured guardrails
+type Guard struct {
+	mu            sync.Mutex
+	config        Config
+	weightChanges map[string]time.Time
+	breakGlass    *BreakGlass
+	now           func() time.Time
+}
+
+// New creates a guard enforcing the given configuration
+func New(config Config) *Guard {
+	return &Guard{
+		config:        config,
+		weightChanges: make(map[string]time.Time),
+		now:           time.Now,
+	}
+}
+
+// Config returns the enforced configuration
+func (g *Guard) Config() Config {
+	return g.config
+}
+
+// OpenBreakGlass bypasses all guardrails for the given duration
+func (g *Guard) OpenBreakGlass(actor, reason string, duration time.Duration) BreakGlass {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now().UTC()
+	g.breakGlass = &BreakGlass{
+		Actor:     actor,
+		Reason:    reason,
+		OpenedAt:  now,
+		ExpiresAt: now.Add(duration),
+	}
+	return *g.breakGlass
+}
+
+// CloseBreakGlass ends the break-glass window and reports whether one was open
+func (g *Guard) CloseBreakGlass() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	open := g.activeBreakGlass() != nil
+	g.breakGlass = nil
+	return open
+}
+
+// ActiveBreakGlass returns the open break-glass window, if any
+func (g *Guard) ActiveBreakGlass() (BreakGlass, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if breakGlass := g.activeBreakGlass(); breakGlass != nil {
+		return *breakGlass, true
+	}
+	return BreakGlass{}, false
+}
+
+// activeBreakGlass returns the unexpired break-glass window; callers must hold mu
+func (g *Guard) activeBreakGlass() *BreakGlass {
+	if g.breakGlass == nil || !g.now().Before(g.breakGlass.ExpiresAt) {
+		return nil
+	}
+	return g.breakGlass
+}
+
+// Cooldowns returns the time until each route's upstream weights may change again
+func (g *Guard) Cooldowns() map[string]time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	cooldowns := make(map[string]time.Duration)
+	for route, changedAt := range g.weightChanges {
+		if remaining := g.config.WeightCooldown - g.now().Sub(changedAt); remaining > 0 {
+			cooldowns[route] = remaining
+		}
+	}
+	return cooldowns
+}
+
+// Change is a configuration change submitted for checking. Routes or
+// policies are only checked when their previous state is known, so the
+// initial load is always allowed.
+type Change struct {
+	PreviousRoutes   *types.RoutesConfig
+	Routes           *types.RoutesConfig
+	PreviousPolicies map[string]string
+	Policies         map[string]string
+}
+
+// Check checks a change against the guardrails and records upstream weight
+// changes when it is allowed. An open break-glass window bypasses every rule.
+func (g *Guard) Check(change Change) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	var violations []Violation
+	var weightChanged []string
+
+	if change.PreviousRoutes != nil {
+		routes := changedRoutes(change.PreviousRoutes, change.Routes)
+		if g.config.MaxRouteChanges > 0 && len(routes) > g.config.MaxRouteChanges {
+			violations = append(violations, Violation{
+				Rule:    RuleMaxRouteChanges,
+				Message: fmt.Sprintf("%d routes changed, at most %d allowed per apply", len(routes), g.config.MaxRouteChanges),
+			})
+		}
+		for _, route := range routes {
+			if route.critical {
+				violations = append(violations, Violation{
+					Rule:     RuleCriticalRoute,
+					Resource: route.key,
+					Message:  fmt.Sprintf("route %s is critical and requires break-glass", route.key),
+				})
+			}
+			if !route.weightsChanged {
+				continue
+			}
+			weightChanged = append(weightChanged, route.key)
+			if changedAt, exists := g.weightChanges[route.key]; exists && g.config.WeightCooldown > 0 {
+				if remaining := g.config.WeightCooldown - now.Sub(changedAt); remaining > 0 {
+					violations = append(violations, Violation{
+						Rule:     RuleWeightCooldown,
+						Resource: route.key,
+						Message:  fmt.Sprintf("upstream weights of %s changed %s ago, wait %s", route.key, now.Sub(changedAt).Round(time.Second), remaining.Round(time.Second)),
+					})
+				}
+			}
+		}
+	}
+
+	if change.PreviousPolicies != nil {
+		policies := changedPolicies(change.PreviousPolicies, change.Policies)
+		if g.config.MaxPolicyChanges > 0 && len(policies) > g.config.MaxPolicyChanges {
+			violations = append(violations, Violation{
+				Rule:    RuleMaxPolicyChanges,
+				Message: fmt.Sprintf("%d policies changed, at most %d allowed per apply", len(policies), g.config.MaxPolicyChanges),
+			})
+		}
+		for _, policy := range policies {
+			if route, guarded := criticalRouteUsing(change.Routes, policy); guarded {
+				violations = append(violations, Violation{
+					Rule:     RuleCriticalRoute,
+					Resource: policy,
+					Message:  fmt.Sprintf("policy %s guards critical route %s %s and requires break-glass", policy, route.Method, route.RouteName),
+				})
+			}
+		}
+	}
+
+	if len(violations) > 0 && g.activeBreakGlass() == nil {
+		return &Error{Violations: violations}
+	}
+	for _, key := range weightChanged {
+		g.weightChanges[key] = now
+	}
+	return nil
+}
+
+// criticalRouteUsing returns a critical route guarded by the policy
+func criticalRouteUsing(config *types.RoutesConfig, policy string) (types.RouteConfig, bool) {
+	if config == nil {
+		return types.RouteConfig{}, false
+	}
+	for _, route := range config.Routes {
+		if isCritical(route) && containsString(route.Policies, policy) {
+			return route, true
+		}
+	}
+	return types.RouteConfig{}, false
+}
+
+// routeChange describes a route added, removed or modified by a change
+type routeChange struct {
+	key            string
+	critical       bool
+	weightsChanged bool
+}
+
+// changedRoutes lists the routes that differ between two configurations
+func changedRoutes(previous, next *types.RoutesConfig) []routeChange {
+	before := indexRoutes(previous)
+	after := indexRoutes(next)
+
+	var changes []routeChange
+	for key, route := range after {
+		old, exists := before[key]
+		if exists && sameRoute(old, route) {
+			continue
+		}
+		changes = append(changes, routeChange{
+			key:            key,
+			critical:       isCritical(route) || (exists && isCritical(old)),
+			weightsChanged: exists && !reflect.DeepEqual(weights(old), weights(route)),
+		})
+	}
+	for key, route := range before {
+		if _, exists := after[key]; !exists {
+			changes = append(changes, routeChange{key: key, critical: isCritical(route)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].key < changes[j].key })
+	return changes
+}
+
+// changedPolicies lists the policies added, removed or modified between two policy sets
+func changedPolicies(previous, next map[string]string) []string {
+	var changed []string
+	for name, source := range next {
+		if old, exists := previous[name]; !exists || old != source {
+			changed = append(changed, name)
+		}
+	}
+	for name := range previous {
+		if _, exists := next[name]; !exists {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// indexRoutes keys the routes of a configuration by method and path
+func indexRoutes(config *types.RoutesConfig) map[string]types.RouteConfig {
+	routes := make(map[string]types.RouteConfig)
+	if config == nil {
+		return routes
+	}
+	for _, route := range config.Routes {
+		routes[route.Method+" "+route.RouteName] = route
+	}
+	return routes
+}
+
+// sameRoute reports whether two route configurations are identical
+func sameRoute(a, b types.RouteConfig) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
+
+// weights returns the upstream weights of a route keyed by target URL
+func weights(route types.RouteConfig) map[string]int {
+	result := make(map[string]int, len(route.Upstreams))
+	for _, target := range route.Upstreams {
+		result[target.URL] = target.Weight
+	}
+	return result
+}
+
+// isCritical reports whether a route is labeled critical
+func isCritical(route types.RouteConfig) bool {
+	return route.Labels[CriticalLabel] == "true"
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
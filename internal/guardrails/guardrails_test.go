@@ -0,0 +1,110 @@
+package guardrails
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+func routesWithWeight(weight int) *types.RoutesConfig {
+	return &types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/status", Method: "GET"},
+		{RouteName: "/v1/orders", Method: "GET", Handler: types.HandlerProxy, Upstreams: []types.UpstreamTarget{
+			{URL: "http://orders-v1", Weight: 100 - weight},
+			{URL: "http://orders-v2", Weight: weight},
+		}},
+	}}
+}
+
+func violationRules(err error) []string {
+	var rejected *Error
+	if !errors.As(err, &rejected) {
+		return nil
+	}
+	rules := make([]string, len(rejected.Violations))
+	for i, violation := range rejected.Violations {
+		rules[i] = violation.Rule
+	}
+	return rules
+}
+
+func TestCheckAllowsInitialLoad(t *testing.T) {
+	guard := New(Config{MaxRouteChanges: 1, MaxPolicyChanges: 1})
+	err := guard.Check(Change{Routes: routesWithWeight(10), Policies: map[string]string{"a": "", "b": ""}})
+	if err != nil {
+		t.Errorf("Expected initial load to be allowed, got %v", err)
+	}
+}
+
+func TestCheckMaxChanges(t *testing.T) {
+	guard := New(Config{MaxRouteChanges: 1, MaxPolicyChanges: 1})
+	next := &types.RoutesConfig{Routes: []types.RouteConfig{{RouteName: "/v1/items", Method: "GET"}}}
+
+	err := guard.Check(Change{
+		PreviousRoutes:   routesWithWeight(10),
+		Routes:           next,
+		PreviousPolicies: map[string]string{"a": "1"},
+		Policies:         map[string]string{"a": "2", "b": "1"},
+	})
+	rules := violationRules(err)
+	if len(rules) != 2 || rules[0] != RuleMaxRouteChanges || rules[1] != RuleMaxPolicyChanges {
+		t.Errorf("Expected route and policy limits to be violated, got %v", err)
+	}
+}
+
+func TestCheckWeightCooldown(t *testing.T) {
+	now := time.Now()
+	guard := New(Config{WeightCooldown: 10 * time.Minute})
+	guard.now = func() time.Time { return now }
+
+	if err := guard.Check(Change{PreviousRoutes: routesWithWeight(10), Routes: routesWithWeight(20)}); err != nil {
+		t.Fatalf("Expected first weight change to be allowed, got %v", err)
+	}
+	err := guard.Check(Change{PreviousRoutes: routesWithWeight(20), Routes: routesWithWeight(50)})
+	if rules := violationRules(err); len(rules) != 1 || rules[0] != RuleWeightCooldown {
+		t.Errorf("Expected weight cooldown violation, got %v", err)
+	}
+	if _, cooling := guard.Cooldowns()["GET /v1/orders"]; !cooling {
+		t.Error("Expected route to be cooling down")
+	}
+
+	now = now.Add(11 * time.Minute)
+	if err := guard.Check(Change{PreviousRoutes: routesWithWeight(20), Routes: routesWithWeight(50)}); err != nil {
+		t.Errorf("Expected weight change after cooldown to be allowed, got %v", err)
+	}
+}
+
+func TestCheckCriticalRoutesRequireBreakGlass(t *testing.T) {
+	guard := New(Config{})
+	previous := routesWithWeight(10)
+	previous.Routes[1].Labels = map[string]string{CriticalLabel: "true"}
+	previous.Routes[1].Policies = []string{"orders_policy"}
+	next := routesWithWeight(20)
+	next.Routes[1].Labels = previous.Routes[1].Labels
+	next.Routes[1].Policies = previous.Routes[1].Policies
+
+	if rules := violationRules(guard.Check(Change{PreviousRoutes: previous, Routes: next})); len(rules) != 1 || rules[0] != RuleCriticalRoute {
+		t.Errorf("Expected critical route change to be rejected, got %v", rules)
+	}
+	policyChange := Change{
+		Routes:           previous,
+		PreviousPolicies: map[string]string{"orders_policy": "1"},
+		Policies:         map[string]string{"orders_policy": "2"},
+	}
+	if rules := violationRules(guard.Check(policyChange)); len(rules) != 1 || rules[0] != RuleCriticalRoute {
+		t.Errorf("Expected change of a critical route's policy to be rejected, got %v", rules)
+	}
+
+	guard.OpenBreakGlass("admin", "incident", time.Minute)
+	if err := guard.Check(Change{PreviousRoutes: previous, Routes: next}); err != nil {
+		t.Errorf("Expected break-glass to bypass guardrails, got %v", err)
+	}
+	if !guard.CloseBreakGlass() {
+		t.Error("Expected open break-glass window to be closed")
+	}
+	if err := guard.Check(policyChange); err == nil {
+		t.Error("Expected guardrails to apply again after break-glass closed")
+	}
+}
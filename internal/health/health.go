@@ -0,0 +1,140 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+)
+
+// Report statuses
+const (
+	StatusReady    = "ready"
+	StatusNotReady = "not ready"
+)
+
+// DefaultTimeout bounds a single check
+const DefaultTimeout = 2 * time.Second
+
+// Check probes a dependency, returning an error while it is unavailable
+type Check func(ctx context.Context) error
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+	// DurationMs is how long the check took
+	DurationMs int64 `json:"durationMs"`
+}
+
+// Report is the outcome of every check. The instance is ready when every
+// critical check passes; failing non-critical checks are reported only.
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Ready reports whether every critical check passed
+func (r Report) Ready() bool {
+	return r.Status == StatusReady
+}
+
+// registeredCheck is a named check
+type registeredCheck struct {
+	name     string
+	check    Check
+	critical bool
+}
+
+// Checker runs the readiness checks of the instance
+type Checker struct {
+	mu      sync.RWMutex
+	checks  []registeredCheck
+	timeout time.Duration
+}
+
+// NewChecker creates a checker bounding each check by timeout, or by
+// DefaultTimeout when it is zero
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Register adds a check. A failing critical check makes the instance not
+// ready; a failing non-critical check is only reported.
+func (hc *Checker) Register(name string, critical bool, check Check) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	hc.checks = append(hc.checks, registeredCheck{name: name, check: check, critical: critical})
+}
+
+// Run runs every check concurrently and reports their outcomes by name
+func (hc *Checker) Run(ctx context.Context) Report {
+	hc.mu.RLock()
+	checks := append([]registeredCheck(nil), hc.checks...)
+	hc.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check registeredCheck) {
+			defer wg.Done()
+			results[i] = hc.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	report := Report{Status: StatusReady, Checks: results}
+	for _, result := range results {
+		if result.Critical && result.Status != StatusOK {
+			report.Status = StatusNotReady
+		}
+	}
+	return report
+}
+
+// run runs a single check within the timeout
+func (hc *Checker) run(ctx context.Context, check registeredCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, hc.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+	}
+	if err == nil && ctx.Err() != nil {
+		err = fmt.Errorf("timed out after %s", hc.timeout)
+	}
+
+	result := CheckResult{
+		Name:       check.name,
+		Status:     StatusOK,
+		Critical:   check.critical,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,34 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckerReport(t *testing.T) {
+	checker := NewChecker(50 * time.Millisecond)
+	checker.Register("policies", true, func(ctx context.Context) error { return nil })
+	checker.Register("upstreams", false, func(ctx context.Context) error { return errors.New("GET /v1/items: no healthy upstream") })
+
+	report := checker.Run(context.Background())
+	if !report.Ready() {
+		t.Fatalf("Expected a failing non-critical check to keep the instance ready: %+v", report)
+	}
+	if len(report.Checks) != 2 || report.Checks[0].Name != "policies" || report.Checks[1].Status != StatusFailing {
+		t.Errorf("Unexpected checks %+v", report.Checks)
+	}
+
+	checker.Register("configStore", true, func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	report = checker.Run(context.Background())
+	if report.Ready() || report.Status != StatusNotReady {
+		t.Fatalf("Expected a hanging critical check to fail readiness: %+v", report)
+	}
+	if report.Checks[0].Name != "configStore" || report.Checks[0].Error == "" {
+		t.Errorf("Expected the timed out check to report an error, got %+v", report.Checks[0])
+	}
+}
@@ -0,0 +1,135 @@
+package identity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultHeader carries the identity assertion on upstream requests
+const DefaultHeader = "X-Identity-Assertion"
+
+// DefaultIssuer identifies the control plane in assertions
+const DefaultIssuer = "dynamiccontrol"
+
+// DefaultTTL is how long an assertion stays valid
+const DefaultTTL = 30 * time.Second
+
+// MinKeyLength is the minimum length of the signing key in bytes
+const MinKeyLength = 32
+
+// tokenHeader is the JOSE header of every assertion
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims is the payload of an identity assertion. It follows the registered
+// JWT claim names, so upstreams can verify it with any JWT library.
+type Claims struct {
+	Issuer     string                 `json:"iss"`
+	Subject    string                 `json:"sub,omitempty"`
+	Audience   string                 `json:"aud,omitempty"`
+	IssuedAt   int64                  `json:"iat"`
+	ExpiresAt  int64                  `json:"exp"`
+	ID         string                 `json:"jti,omitempty"`
+	ClientIP   string                 `json:"clientIp,omitempty"`
+	Decision   *DecisionClaim         `json:"decision,omitempty"`
+	Attributes map[string]interface{} `json:"claims,omitempty"`
+}
+
+// DecisionClaim summarizes the policy decision that authorized the request
+type DecisionClaim struct {
+	ID       string   `json:"id,omitempty"`
+	Allowed  bool     `json:"allowed"`
+	Policies []string `json:"policies,omitempty"`
+}
+
+// Options configures a signer
+type Options struct {
+	Issuer string
+	Header string
+	TTL    time.Duration
+}
+
+// Signer mints and verifies HS256-signed identity assertions
+type Signer struct {
+	key     []byte
+	options Options
+	now     func() time.Time
+}
+
+// NewSigner creates a signer with the given key
+func NewSigner(key []byte, options Options) (*Signer, error) {
+	if len(key) < MinKeyLength {
+		return nil, fmt.Errorf("identity assertion key must be at least %d bytes", MinKeyLength)
+	}
+	if options.Issuer == "" {
+		options.Issuer = DefaultIssuer
+	}
+	if options.Header == "" {
+		options.Header = DefaultHeader
+	}
+	if options.TTL <= 0 {
+		options.TTL = DefaultTTL
+	}
+	return &Signer{key: key, options: options, now: time.Now}, nil
+}
+
+// Header returns the name of the header carrying assertions
+func (s *Signer) Header() string {
+	return s.options.Header
+}
+
+// Sign mints an assertion for the claims, setting the issuer and validity window
+func (s *Signer) Sign(claims Claims) (string, error) {
+	now := s.now()
+	claims.Issuer = s.options.Issuer
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(s.options.TTL).Unix()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode assertion: %w", err)
+	}
+	unsigned := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + s.signature(unsigned), nil
+}
+
+// Verify checks the signature, issuer and expiry of an assertion and returns its claims
+func (s *Signer) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed assertion")
+	}
+	if parts[0] != tokenHeader {
+		return nil, fmt.Errorf("unsupported assertion header")
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(s.signature(parts[0]+"."+parts[1]))) {
+		return nil, fmt.Errorf("invalid assertion signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode assertion: %w", err)
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("failed to decode assertion: %w", err)
+	}
+	if claims.Issuer != s.options.Issuer {
+		return nil, fmt.Errorf("unexpected assertion issuer %q", claims.Issuer)
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return nil, fmt.Errorf("assertion expired")
+	}
+	return &claims, nil
+}
+
+// signature returns the encoded HMAC-SHA256 signature of the signing input
+func (s *Signer) signature(unsigned string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
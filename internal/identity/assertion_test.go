@@ -0,0 +1,69 @@
+package identity
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestSignAndVerify(t *testing.T) {
+	signer, err := NewSigner(testKey, Options{})
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+
+	token, err := signer.Sign(Claims{
+		Subject:  "user-1",
+		Audience: "GET /v1/status",
+		Decision: &DecisionClaim{ID: "d-1", Allowed: true, Policies: []string{"status_policy"}},
+	})
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	claims, err := signer.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if claims.Issuer != DefaultIssuer || claims.Subject != "user-1" || claims.Decision.ID != "d-1" {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+	if claims.ExpiresAt-claims.IssuedAt != int64(DefaultTTL/time.Second) {
+		t.Errorf("Expected validity of %v, got %ds", DefaultTTL, claims.ExpiresAt-claims.IssuedAt)
+	}
+}
+
+func TestVerifyRejectsTamperedAndExpired(t *testing.T) {
+	signer, err := NewSigner(testKey, Options{TTL: time.Minute})
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+	token, err := signer.Sign(Claims{Subject: "user-1"})
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	other, _ := NewSigner([]byte(strings.Repeat("k", MinKeyLength)), Options{})
+	if _, err := other.Verify(token); err == nil {
+		t.Error("Expected assertion signed with another key to be rejected")
+	}
+
+	parts := strings.Split(token, ".")
+	forged, _ := signer.Sign(Claims{Subject: "admin"})
+	if _, err := signer.Verify(parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2]); err == nil {
+		t.Error("Expected tampered payload to be rejected")
+	}
+
+	signer.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := signer.Verify(token); err == nil {
+		t.Error("Expected expired assertion to be rejected")
+	}
+}
+
+func TestNewSignerRequiresKeyLength(t *testing.T) {
+	if _, err := NewSigner([]byte("short"), Options{}); err == nil {
+		t.Error("Expected short key to be rejected")
+	}
+}
@@ -0,0 +1,428 @@
+package ingress
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Supported resource kinds
+const (
+	KindIngress   = "Ingress"
+	KindHTTPRoute = "HTTPRoute"
+)
+
+// Labels set on imported routes
+const (
+	SourceLabel = "source"
+	HostLabel   = "host"
+)
+
+// DefaultMethods are the methods routes are generated for when a resource
+// does not restrict them; Ingress rules never do
+var DefaultMethods = []string{"GET", "POST", "PUT", "DELETE"}
+
+// Options controls how Kubernetes routing resources are turned into routes
+type Options struct {
+	// Namespace is used for resources that do not declare one
+	Namespace string
+	// ClusterDomain completes backend service hostnames, default cluster.local
+	ClusterDomain string
+	// Methods overrides DefaultMethods
+	Methods []string
+	// DefaultPolicies are applied to every imported route
+	DefaultPolicies []string
+}
+
+// Object is a Kubernetes routing resource
+type Object struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec  json.RawMessage `json:"spec"`
+	Items []Object        `json:"items,omitempty"`
+}
+
+// ingressSpec is the subset of a networking.k8s.io/v1 Ingress spec the importer reads
+type ingressSpec struct {
+	DefaultBackend *ingressBackend `json:"defaultBackend"`
+	Rules          []struct {
+		Host string `json:"host"`
+		HTTP *struct {
+			Paths []struct {
+				Path     string         `json:"path"`
+				PathType string         `json:"pathType"`
+				Backend  ingressBackend `json:"backend"`
+			} `json:"paths"`
+		} `json:"http"`
+	} `json:"rules"`
+}
+
+// ingressBackend is the service an Ingress path routes to
+type ingressBackend struct {
+	Service *struct {
+		Name string `json:"name"`
+		Port struct {
+			Number int    `json:"number"`
+			Name   string `json:"name"`
+		} `json:"port"`
+	} `json:"service"`
+}
+
+// httpRouteSpec is the subset of a Gateway API HTTPRoute spec the importer reads
+type httpRouteSpec struct {
+	Hostnames []string `json:"hostnames"`
+	Rules     []struct {
+		Matches     []httpRouteMatch `json:"matches"`
+		BackendRefs []struct {
+			Kind      string `json:"kind"`
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+			Port      int    `json:"port"`
+			Weight    *int   `json:"weight"`
+		} `json:"backendRefs"`
+		Filters []json.RawMessage `json:"filters"`
+	} `json:"rules"`
+}
+
+// httpRouteMatch is a request match of an HTTPRoute rule
+type httpRouteMatch struct {
+	Path *struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"path"`
+	Method      string            `json:"method"`
+	Headers     []json.RawMessage `json:"headers"`
+	QueryParams []json.RawMessage `json:"queryParams"`
+}
+
+// documentSeparator splits multi-document YAML
+var documentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// Import generates route configurations from Ingress and HTTPRoute
+// resources in JSON or YAML, as written by kubectl get -o yaml. Documents
+// may be separated by "---" or wrapped in a List. Other kinds are skipped.
+func Import(document []byte, options Options) (*types.RoutesConfig, error) {
+	var imported []types.RouteConfig
+	for _, part := range documentSeparator.Split(string(document), -1) {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		jsonBytes, err := yaml.YAMLToJSON([]byte(part))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Kubernetes resource: %w", err)
+		}
+		if bytes.Equal(bytes.TrimSpace(jsonBytes), []byte("null")) {
+			continue
+		}
+		var object Object
+		if err := json.Unmarshal(jsonBytes, &object); err != nil {
+			return nil, fmt.Errorf("failed to parse Kubernetes resource: %w", err)
+		}
+
+		objects := []Object{object}
+		if strings.HasSuffix(object.Kind, "List") {
+			objects = object.Items
+		}
+		for _, item := range objects {
+			routes, err := Convert(item, options)
+			if err != nil {
+				return nil, err
+			}
+			imported = append(imported, routes...)
+		}
+	}
+	return &types.RoutesConfig{Routes: Merge(nil, imported)}, nil
+}
+
+// Convert generates the routes of one Ingress or HTTPRoute resource. Other
+// kinds yield no routes. Settings without an equivalent, such as header
+// matches or filters, are skipped with a warning.
+func Convert(object Object, options Options) ([]types.RouteConfig, error) {
+	if object.Metadata.Namespace == "" {
+		object.Metadata.Namespace = options.Namespace
+	}
+	if object.Metadata.Namespace == "" {
+		object.Metadata.Namespace = "default"
+	}
+
+	switch object.Kind {
+	case KindIngress:
+		var spec ingressSpec
+		if err := json.Unmarshal(object.Spec, &spec); err != nil {
+			return nil, fmt.Errorf("failed to parse Ingress %s: %w", object.Metadata.Name, err)
+		}
+		return convertIngress(object, spec, options), nil
+	case KindHTTPRoute:
+		var spec httpRouteSpec
+		if err := json.Unmarshal(object.Spec, &spec); err != nil {
+			return nil, fmt.Errorf("failed to parse HTTPRoute %s: %w", object.Metadata.Name, err)
+		}
+		return convertHTTPRoute(object, spec, options), nil
+	default:
+		return nil, nil
+	}
+}
+
+// convertIngress generates routes for every path of every Ingress rule
+func convertIngress(object Object, spec ingressSpec, options Options) []types.RouteConfig {
+	source := "ingress/" + object.Metadata.Namespace + "/" + object.Metadata.Name
+	var routes []types.RouteConfig
+	for _, rule := range spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			patterns, ok := convertPath(path.Path, path.PathType != "Exact")
+			if !ok {
+				warn(source, "path %q cannot be expressed as a route and was skipped", path.Path)
+				continue
+			}
+			target, ok := ingressUpstream(source, object.Metadata.Namespace, path.Backend, options)
+			if !ok {
+				continue
+			}
+			for _, pattern := range patterns {
+				routes = append(routes, newRoutes(source, rule.Host, pattern, options.Methods, []types.UpstreamTarget{target}, options)...)
+			}
+		}
+	}
+	if spec.DefaultBackend != nil {
+		if target, ok := ingressUpstream(source, object.Metadata.Namespace, *spec.DefaultBackend, options); ok {
+			routes = append(routes, newRoutes(source, "", "/*path", options.Methods, []types.UpstreamTarget{target}, options)...)
+		}
+	}
+	return routes
+}
+
+// ingressUpstream returns the upstream of an Ingress backend
+func ingressUpstream(source, namespace string, backend ingressBackend, options Options) (types.UpstreamTarget, bool) {
+	if backend.Service == nil {
+		warn(source, "resource backends are not supported and were skipped")
+		return types.UpstreamTarget{}, false
+	}
+	port := backend.Service.Port.Number
+	if port == 0 && backend.Service.Port.Name != "" {
+		warn(source, "named port %q of service %s cannot be resolved, using port 80", backend.Service.Port.Name, backend.Service.Name)
+	}
+	return types.UpstreamTarget{
+		Name:   backend.Service.Name,
+		URL:    serviceURL(backend.Service.Name, namespace, port, options),
+		Weight: 1,
+	}, true
+}
+
+// convertHTTPRoute generates routes for every match of every HTTPRoute rule,
+// splitting traffic across the weighted backend references
+func convertHTTPRoute(object Object, spec httpRouteSpec, options Options) []types.RouteConfig {
+	source := "httproute/" + object.Metadata.Namespace + "/" + object.Metadata.Name
+	hosts := spec.Hostnames
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+
+	var routes []types.RouteConfig
+	for i, rule := range spec.Rules {
+		if len(rule.Filters) > 0 {
+			warn(source, "filters of rule %d are not supported and were ignored", i)
+		}
+		var upstreams []types.UpstreamTarget
+		for _, ref := range rule.BackendRefs {
+			if ref.Kind != "" && ref.Kind != "Service" {
+				warn(source, "backend %s of kind %s is not supported and was skipped", ref.Name, ref.Kind)
+				continue
+			}
+			weight := 1
+			if ref.Weight != nil {
+				weight = *ref.Weight
+			}
+			if weight <= 0 {
+				continue
+			}
+			namespace := ref.Namespace
+			if namespace == "" {
+				namespace = object.Metadata.Namespace
+			}
+			upstreams = append(upstreams, types.UpstreamTarget{
+				Name:   ref.Name,
+				URL:    serviceURL(ref.Name, namespace, ref.Port, options),
+				Weight: weight,
+			})
+		}
+		if len(upstreams) == 0 {
+			warn(source, "rule %d has no service backends and was skipped", i)
+			continue
+		}
+
+		matches := rule.Matches
+		if len(matches) == 0 {
+			matches = []httpRouteMatch{{}}
+		}
+		for _, match := range matches {
+			path, pathType := "/", "PathPrefix"
+			if match.Path != nil {
+				if match.Path.Value != "" {
+					path = match.Path.Value
+				}
+				if match.Path.Type != "" {
+					pathType = match.Path.Type
+				}
+			}
+			if pathType == "RegularExpression" {
+				warn(source, "regular expression path %q is not supported and was skipped", path)
+				continue
+			}
+			patterns, ok := convertPath(path, pathType == "PathPrefix")
+			if !ok {
+				warn(source, "path %q cannot be expressed as a route and was skipped", path)
+				continue
+			}
+			if len(match.Headers) > 0 || len(match.QueryParams) > 0 {
+				warn(source, "header and query parameter matches of %s are not supported and were ignored", path)
+			}
+			methods := options.Methods
+			if match.Method != "" {
+				methods = []string{strings.ToUpper(match.Method)}
+			}
+			for _, host := range hosts {
+				for _, pattern := range patterns {
+					routes = append(routes, newRoutes(source, host, pattern, methods, upstreams, options)...)
+				}
+			}
+		}
+	}
+	return routes
+}
+
+// newRoutes creates a proxy route per method
+func newRoutes(source, host, pattern string, methods []string, upstreams []types.UpstreamTarget, options Options) []types.RouteConfig {
+	if len(methods) == 0 {
+		methods = DefaultMethods
+	}
+	labels := map[string]string{SourceLabel: source}
+	if host != "" {
+		labels[HostLabel] = host
+	}
+
+	routes := make([]types.RouteConfig, 0, len(methods))
+	for _, method := range methods {
+		routes = append(routes, types.RouteConfig{
+			RouteName: pattern,
+			Method:    method,
+			Handler:   types.HandlerProxy,
+			Upstreams: append([]types.UpstreamTarget(nil), upstreams...),
+			Policies:  append([]string{}, options.DefaultPolicies...),
+			Labels:    labels,
+		})
+	}
+	return routes
+}
+
+// convertPath turns a Kubernetes path into route patterns. A prefix path
+// matches itself and every path below it through a catch-all parameter.
+func convertPath(path string, prefix bool) ([]string, bool) {
+	if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, "*:{}") {
+		return nil, false
+	}
+	if !prefix {
+		return []string{path}, true
+	}
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		return []string{"/*path"}, true
+	}
+	return []string{path, path + "/*path"}, true
+}
+
+// serviceURL returns the cluster address of a service port
+func serviceURL(name, namespace string, port int, options Options) string {
+	domain := options.ClusterDomain
+	if domain == "" {
+		domain = "cluster.local"
+	}
+	host := name + "." + namespace + ".svc." + domain
+	if port > 0 && port != 80 {
+		host += ":" + strconv.Itoa(port)
+	}
+	return "http://" + host
+}
+
+// Merge appends the imported routes that neither repeat nor conflict with
+// a route already present, reporting the ones it skips. The router cannot
+// serve a catch-all path next to another path below its prefix, so more
+// specific imported routes are placed first and win. Routes differing only
+// by host collide too until hosts are matched.
+func Merge(routes, imported []types.RouteConfig) []types.RouteConfig {
+	ordered := append([]types.RouteConfig(nil), imported...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return specificity(ordered[i].RouteName) > specificity(ordered[j].RouteName)
+	})
+
+	merged := append([]types.RouteConfig(nil), routes...)
+	for _, route := range ordered {
+		owner := ""
+		for _, existing := range merged {
+			if existing.Method == route.Method && (existing.RouteName == route.RouteName || conflicts(existing.RouteName, route.RouteName)) {
+				owner = existing.Method + " " + existing.RouteName
+				if source := existing.Labels[SourceLabel]; source != "" {
+					owner += " from " + source
+				}
+				break
+			}
+		}
+		if owner != "" {
+			warn(route.Labels[SourceLabel], "route %s %s conflicts with %s and was skipped", route.Method, route.RouteName, owner)
+			continue
+		}
+		merged = append(merged, route)
+	}
+	return merged
+}
+
+// specificity ranks exact paths above catch-all paths, and longer catch-all
+// prefixes above shorter ones
+func specificity(pattern string) int {
+	prefix, catchAll := catchAllPrefix(pattern)
+	if !catchAll {
+		return math.MaxInt
+	}
+	return len(prefix)
+}
+
+// conflicts reports whether two patterns cannot be registered together
+// because one is a catch-all covering a path of the other
+func conflicts(a, b string) bool {
+	if prefix, ok := catchAllPrefix(a); ok && strings.HasPrefix(b, prefix) {
+		return true
+	}
+	prefix, ok := catchAllPrefix(b)
+	return ok && strings.HasPrefix(a, prefix)
+}
+
+// catchAllPrefix returns the static prefix of a pattern ending in a
+// catch-all parameter, such as "/api/" for "/api/*path"
+func catchAllPrefix(pattern string) (string, bool) {
+	i := strings.LastIndex(pattern, "/*")
+	if i < 0 {
+		return "", false
+	}
+	return pattern[:i+1], true
+}
+
+// warn reports a setting of a resource that could not be imported
+func warn(source, format string, args ...interface{}) {
+	slog.Warn("Kubernetes import", "source", source, "error", fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,172 @@
+package ingress
+
+import (
+	"strings"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+const manifests = `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: shop
+  namespace: store
+spec:
+  rules:
+    - host: shop.example.com
+      http:
+        paths:
+          - path: /
+            pathType: Prefix
+            backend:
+              service:
+                name: web
+                port:
+                  number: 80
+          - path: /api/
+            pathType: Prefix
+            backend:
+              service:
+                name: api
+                port:
+                  number: 8080
+          - path: /healthz
+            pathType: Exact
+            backend:
+              service:
+                name: api
+                port:
+                  number: 8080
+---
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: orders
+spec:
+  hostnames: [orders.example.com]
+  rules:
+    - matches:
+        - path:
+            type: Exact
+            value: /orders
+          method: POST
+      backendRefs:
+        - name: orders-v1
+          port: 9000
+          weight: 90
+        - name: orders-v2
+          port: 9000
+          weight: 10
+    - matches:
+        - path:
+            type: RegularExpression
+            value: /orders/[0-9]+
+      backendRefs:
+        - name: orders-v1
+          port: 9000
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: ignored
+`
+
+func findRoute(routes []types.RouteConfig, method, path string) *types.RouteConfig {
+	for i := range routes {
+		if routes[i].Method == method && routes[i].RouteName == path {
+			return &routes[i]
+		}
+	}
+	return nil
+}
+
+func TestImportIngress(t *testing.T) {
+	config, err := Import([]byte(manifests), Options{Methods: []string{"GET"}, DefaultPolicies: []string{"edge_policy"}})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	api := findRoute(config.Routes, "GET", "/api/*path")
+	if api == nil {
+		t.Fatalf("Missing prefix route: %+v", config.Routes)
+	}
+	if api.Handler != types.HandlerProxy || len(api.Upstreams) != 1 || api.Upstreams[0].URL != "http://api.store.svc.cluster.local:8080" {
+		t.Errorf("Unexpected upstreams: %+v", api.Upstreams)
+	}
+	if api.Labels[HostLabel] != "shop.example.com" || api.Labels[SourceLabel] != "ingress/store/shop" {
+		t.Errorf("Unexpected labels: %v", api.Labels)
+	}
+	if len(api.Policies) != 1 || api.Policies[0] != "edge_policy" {
+		t.Errorf("Unexpected policies: %v", api.Policies)
+	}
+	if route := findRoute(config.Routes, "GET", "/api"); route == nil {
+		t.Error("Missing route for the prefix itself")
+	}
+	if route := findRoute(config.Routes, "GET", "/healthz"); route == nil {
+		t.Error("Missing exact route")
+	}
+	// The root prefix would shadow the more specific paths in the router
+	if route := findRoute(config.Routes, "GET", "/*path"); route != nil {
+		t.Error("Expected conflicting catch-all route to be skipped")
+	}
+}
+
+func TestImportHTTPRoute(t *testing.T) {
+	config, err := Import([]byte(manifests), Options{Namespace: "sales"})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	orders := findRoute(config.Routes, "POST", "/orders")
+	if orders == nil {
+		t.Fatalf("Missing HTTPRoute route: %+v", config.Routes)
+	}
+	if len(orders.Upstreams) != 2 || orders.Upstreams[0].Weight != 90 || orders.Upstreams[1].URL != "http://orders-v2.sales.svc.cluster.local:9000" {
+		t.Errorf("Unexpected upstreams: %+v", orders.Upstreams)
+	}
+	if findRoute(config.Routes, "GET", "/orders") != nil {
+		t.Error("Expected the method match to restrict the route")
+	}
+	for _, route := range config.Routes {
+		if route.Labels[SourceLabel] == "httproute/sales/orders" && route.RouteName != "/orders" {
+			t.Errorf("Unexpected route from regular expression match: %s", route.RouteName)
+		}
+	}
+}
+
+func TestMergeKeepsExistingRoutes(t *testing.T) {
+	existing := []types.RouteConfig{{RouteName: "/api/users/:id", Method: "GET"}}
+	imported := []types.RouteConfig{
+		{RouteName: "/api/*path", Method: "GET"},
+		{RouteName: "/api/*path", Method: "POST"},
+	}
+
+	merged := Merge(existing, imported)
+	if len(merged) != 2 || merged[0].RouteName != "/api/users/:id" || merged[1].Method != "POST" {
+		t.Errorf("Unexpected routes: %+v", merged)
+	}
+}
+
+func TestConvertPath(t *testing.T) {
+	cases := []struct {
+		path     string
+		prefix   bool
+		patterns string
+		ok       bool
+	}{
+		{"/", true, "/*path", true},
+		{"/api", true, "/api /api/*path", true},
+		{"/api/", true, "/api /api/*path", true},
+		{"/api", false, "/api", true},
+		{"/files/:name", false, "", false},
+		{"relative", true, "", false},
+	}
+	for _, c := range cases {
+		patterns, ok := convertPath(c.path, c.prefix)
+		if strings.Join(patterns, " ") != c.patterns || ok != c.ok {
+			t.Errorf("convertPath(%q, %v) = %v, %v", c.path, c.prefix, patterns, ok)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package listquery
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Pagination limits
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// OrderField sorts items by a field, ascending unless Descending is set
+type OrderField struct {
+	Field      string
+	Descending bool
+}
+
+// Query describes how a collection is filtered, ordered, paginated and projected.
+// It is parsed from request parameters of the form
+//
+//	?filter[method]=GET,POST&order=-timestamp,name&fields=id,method&limit=20&cursor=...
+type Query struct {
+	Limit   int
+	Cursor  string
+	Filters map[string][]string
+	Order   []OrderField
+	Fields  []string
+}
+
+// Page is a single page of a listed collection
+type Page struct {
+	Items      []map[string]interface{} `json:"items"`
+	Total      int                      `json:"total"`
+	NextCursor string                   `json:"nextCursor,omitempty"`
+}
+
+// cursor identifies the last item of a page. The query fingerprint ties a
+// cursor to the filters and ordering it was issued for.
+type cursor struct {
+	Key         []interface{} `json:"k"`
+	Fingerprint string        `json:"f"`
+}
+
+// Parse reads a query from URL parameters
+func Parse(values url.Values) (Query, error) {
+	query := Query{
+		Limit:   DefaultLimit,
+		Cursor:  values.Get("cursor"),
+		Filters: make(map[string][]string),
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 {
+			return Query{}, fmt.Errorf("limit must be a positive integer")
+		}
+		if parsed > MaxLimit {
+			parsed = MaxLimit
+		}
+		query.Limit = parsed
+	}
+
+	for key, filterValues := range values {
+		if !strings.HasPrefix(key, "filter[") || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		field := key[len("filter[") : len(key)-1]
+		if field == "" {
+			return Query{}, fmt.Errorf("filter requires a field name")
+		}
+		for _, value := range filterValues {
+			query.Filters[field] = append(query.Filters[field], strings.Split(value, ",")...)
+		}
+	}
+
+	if order := values.Get("order"); order != "" {
+		for _, field := range strings.Split(order, ",") {
+			orderField := OrderField{Field: strings.TrimPrefix(field, "-"), Descending: strings.HasPrefix(field, "-")}
+			if orderField.Field == "" {
+				return Query{}, fmt.Errorf("order requires field names")
+			}
+			query.Order = append(query.Order, orderField)
+		}
+	}
+
+	if fields := values.Get("fields"); fields != "" {
+		query.Fields = strings.Split(fields, ",")
+	}
+
+	return query, nil
+}
+
+// Apply filters, orders and paginates items, which are uniquely identified by
+// idField. Items are always ordered by idField last so pages are stable.
+func Apply(items []map[string]interface{}, idField string, query Query) (Page, error) {
+	order := append(append([]OrderField{}, query.Order...), OrderField{Field: idField})
+	fingerprint := query.fingerprint()
+
+	matched := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if query.matches(item) {
+			matched = append(matched, item)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return compareKeys(sortKey(matched[i], order), sortKey(matched[j], order), order) < 0
+	})
+
+	start := 0
+	if query.Cursor != "" {
+		after, err := decodeCursor(query.Cursor, fingerprint)
+		if err != nil {
+			return Page{}, err
+		}
+		start = sort.Search(len(matched), func(i int) bool {
+			return compareKeys(sortKey(matched[i], order), after, order) > 0
+		})
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	end := start + limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	page := Page{
+		Items: make([]map[string]interface{}, 0, end-start),
+		Total: len(matched),
+	}
+	for _, item := range matched[start:end] {
+		page.Items = append(page.Items, project(item, query.Fields))
+	}
+	if end < len(matched) {
+		page.NextCursor = encodeCursor(cursor{Key: sortKey(matched[end-1], order), Fingerprint: fingerprint})
+	}
+	return page, nil
+}
+
+// ToItems converts values to generic items through their JSON representation
+func ToItems(values interface{}) ([]map[string]interface{}, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode items: %w", err)
+	}
+	var items []map[string]interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to decode items: %w", err)
+	}
+	return items, nil
+}
+
+// matches reports whether an item satisfies every filter. A filter matches
+// when the field equals any of its values; list fields match when any element does.
+func (q Query) matches(item map[string]interface{}) bool {
+	for field, accepted := range q.Filters {
+		value := Lookup(item, field)
+		candidates, isList := value.([]interface{})
+		if !isList {
+			candidates = []interface{}{value}
+		}
+
+		found := false
+		for _, candidate := range candidates {
+			text := formatValue(candidate)
+			for _, want := range accepted {
+				if text == want {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// fingerprint hashes the filters and ordering of a query
+func (q Query) fingerprint() string {
+	fields := make([]string, 0, len(q.Filters))
+	for field := range q.Filters {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	hash := sha256.New()
+	for _, field := range fields {
+		values := append([]string{}, q.Filters[field]...)
+		sort.Strings(values)
+		fmt.Fprintf(hash, "f:%s=%s;", field, strings.Join(values, ","))
+	}
+	for _, order := range q.Order {
+		fmt.Fprintf(hash, "o:%s:%t;", order.Field, order.Descending)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// Lookup returns the value of a dotted field path in an item
+func Lookup(item map[string]interface{}, path string) interface{} {
+	var current interface{} = item
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[part]
+	}
+	return current
+}
+
+// project keeps only the requested fields of an item
+func project(item map[string]interface{}, fields []string) map[string]interface{} {
+	if len(fields) == 0 {
+		return item
+	}
+	projected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, exists := item[field]; exists {
+			projected[field] = value
+		}
+	}
+	return projected
+}
+
+// sortKey extracts the ordering values of an item
+func sortKey(item map[string]interface{}, order []OrderField) []interface{} {
+	key := make([]interface{}, len(order))
+	for i, field := range order {
+		key[i] = Lookup(item, field.Field)
+	}
+	return key
+}
+
+// compareKeys compares two sort keys field by field
+func compareKeys(a, b []interface{}, order []OrderField) int {
+	for i, field := range order {
+		if i >= len(a) || i >= len(b) {
+			break
+		}
+		result := compareValues(a[i], b[i])
+		if field.Descending {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+// compareValues orders nulls first, then booleans, numbers and strings
+func compareValues(a, b interface{}) int {
+	rankA, rankB := typeRank(a), typeRank(b)
+	if rankA != rankB {
+		return rankA - rankB
+	}
+
+	switch x := a.(type) {
+	case bool:
+		y := b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		default:
+			return 1
+		}
+	case float64:
+		y := b.(float64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		default:
+			return 0
+		}
+	case string:
+		return strings.Compare(x, b.(string))
+	case nil:
+		return 0
+	default:
+		return strings.Compare(formatValue(a), formatValue(b))
+	}
+}
+
+// typeRank orders values of different JSON types
+func typeRank(value interface{}) int {
+	switch value.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// formatValue renders a JSON value for filter comparison
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// encodeCursor serializes a cursor into an opaque token
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor token issued for the same query
+func decodeCursor(token, fingerprint string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if c.Fingerprint != fingerprint {
+		return nil, fmt.Errorf("cursor does not match the filters and ordering of this query")
+	}
+	return c.Key, nil
+}
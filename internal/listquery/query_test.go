@@ -0,0 +1,107 @@
+package listquery
+
+import (
+	"net/url"
+	"testing"
+)
+
+func testItems() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"id": "a", "method": "GET", "latency": 3.0, "tags": []interface{}{"public"}},
+		{"id": "b", "method": "POST", "latency": 1.0, "tags": []interface{}{"internal"}},
+		{"id": "c", "method": "GET", "latency": 2.0, "tags": []interface{}{"public", "beta"}},
+		{"id": "d", "method": "DELETE", "latency": 2.0},
+		{"id": "e", "method": "GET", "latency": 5.0},
+	}
+}
+
+func ids(page Page) []string {
+	result := make([]string, len(page.Items))
+	for i, item := range page.Items {
+		result[i], _ = item["id"].(string)
+	}
+	return result
+}
+
+func parse(t *testing.T, raw string) Query {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", raw, err)
+	}
+	query, err := Parse(values)
+	if err != nil {
+		t.Fatalf("Failed to parse query %q: %v", raw, err)
+	}
+	return query
+}
+
+func TestApplyFilterAndOrder(t *testing.T) {
+	page, err := Apply(testItems(), "id", parse(t, "filter[method]=GET,DELETE&order=-latency"))
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got := ids(page); len(got) != 4 || got[0] != "e" || got[1] != "a" || got[2] != "c" || got[3] != "d" {
+		t.Errorf("Unexpected order %v", got)
+	}
+	if page.Total != 4 {
+		t.Errorf("Expected total 4, got %d", page.Total)
+	}
+
+	page, _ = Apply(testItems(), "id", parse(t, "filter[tags]=beta"))
+	if got := ids(page); len(got) != 1 || got[0] != "c" {
+		t.Errorf("Expected list filter to match c, got %v", got)
+	}
+}
+
+func TestApplyCursorPagination(t *testing.T) {
+	query := parse(t, "order=latency&limit=2")
+	var seen []string
+	for i := 0; i < 5; i++ {
+		page, err := Apply(testItems(), "id", query)
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		seen = append(seen, ids(page)...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	expected := []string{"b", "c", "d", "a", "e"}
+	if len(seen) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, seen)
+	}
+	for i := range expected {
+		if seen[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, seen)
+			break
+		}
+	}
+}
+
+func TestApplyRejectsCursorFromOtherQuery(t *testing.T) {
+	page, _ := Apply(testItems(), "id", parse(t, "limit=1"))
+	query := parse(t, "order=-latency&limit=1")
+	query.Cursor = page.NextCursor
+	if _, err := Apply(testItems(), "id", query); err == nil {
+		t.Error("Expected an error for a cursor issued for a different ordering")
+	}
+}
+
+func TestApplySparseFields(t *testing.T) {
+	page, _ := Apply(testItems(), "id", parse(t, "fields=id,method&limit=1"))
+	if len(page.Items[0]) != 2 || page.Items[0]["latency"] != nil {
+		t.Errorf("Expected only id and method, got %v", page.Items[0])
+	}
+}
+
+func TestParseRejectsInvalidLimit(t *testing.T) {
+	if _, err := Parse(url.Values{"limit": {"-1"}}); err == nil {
+		t.Error("Expected an error for a negative limit")
+	}
+	query, _ := Parse(url.Values{"limit": {"100000"}})
+	if query.Limit != MaxLimit {
+		t.Errorf("Expected limit to be capped at %d, got %d", MaxLimit, query.Limit)
+	}
+}
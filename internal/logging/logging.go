@@ -0,0 +1,198 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request correlation ID
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the context key holding the request ID
+type requestIDKey struct{}
+
+// Setup installs the default structured logger. format is "json" or "text";
+// level is one of debug, info, warn and error. Output of the standard log
+// package is routed through the same handler.
+func Setup(w io.Writer, format, level string) {
+	options := &slog.HandlerOptions{Level: parseLevel(level)}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "text") {
+		handler = slog.NewTextHandler(w, options)
+	} else {
+		handler = slog.NewJSONHandler(w, options)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// parseLevel converts a level name, defaulting to info
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// NewRequestID generates a random request ID
+func NewRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// WithRequestID returns a context carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// FromContext returns the default logger annotated with the request ID carried by ctx
+func FromContext(ctx context.Context) *slog.Logger {
+	if requestID := RequestID(ctx); requestID != "" {
+		return slog.Default().With("request_id", requestID)
+	}
+	return slog.Default()
+}
+
+// Middleware assigns every request an ID, reusing a valid inbound
+// X-Request-ID, echoes it in the response and logs the completed request
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		var requestID string
+		requestID, c.Request = assignRequestID(c.Writer, c.Request)
+		c.Next()
+		logRequest(c.Request, requestID, start, c.Writer.Status(), c.Writer.Size(), c.ClientIP())
+	}
+}
+
+// Handler is Middleware for net/http handlers. The logged client address
+// is the remote address of the connection.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID, r := assignRequestID(w, r)
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			clientIP = r.RemoteAddr
+		}
+		logRequest(r, requestID, start, recorder.Status(), recorder.size, clientIP)
+	})
+}
+
+// assignRequestID sets the request ID on the request, its context and the
+// response
+func assignRequestID(w http.ResponseWriter, r *http.Request) (string, *http.Request) {
+	requestID := r.Header.Get(RequestIDHeader)
+	if !validRequestID(requestID) {
+		requestID = NewRequestID()
+	}
+	// Keep the header on the request so proxied calls forward it
+	r.Header.Set(RequestIDHeader, requestID)
+	w.Header().Set(RequestIDHeader, requestID)
+	return requestID, r.WithContext(WithRequestID(r.Context(), requestID))
+}
+
+// logRequest logs a completed request at a level following its status
+func logRequest(r *http.Request, requestID string, start time.Time, status, size int, clientIP string) {
+	level := slog.LevelInfo
+	switch {
+	case status >= 500:
+		level = slog.LevelError
+	case status >= 400:
+		level = slog.LevelWarn
+	}
+	slog.LogAttrs(r.Context(), level, "request completed",
+		slog.String("request_id", requestID),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", status),
+		slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+		slog.String("client_ip", clientIP),
+		slog.Int("bytes", size),
+	)
+}
+
+// statusRecorder records the status and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+// WriteHeader records the status code
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the size of the body
+func (w *statusRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+// Flush flushes the response when the underlying writer supports it
+func (w *statusRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status returns the status code of the response, 200 when none was written
+func (w *statusRecorder) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// validRequestID accepts inbound IDs that are safe to log and forward
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > 128 {
+		return false
+	}
+	for _, r := range requestID {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestRouter(seen *string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/", func(c *gin.Context) {
+		*seen = RequestID(c.Request.Context())
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func TestMiddlewareReusesInboundRequestID(t *testing.T) {
+	var seen string
+	router := newTestRouter(&seen)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if seen != "abc-123" || w.Header().Get(RequestIDHeader) != "abc-123" {
+		t.Errorf("Expected inbound request ID to be reused, got context %q and header %q", seen, w.Header().Get(RequestIDHeader))
+	}
+}
+
+func TestMiddlewareGeneratesRequestID(t *testing.T) {
+	var seen string
+	router := newTestRouter(&seen)
+
+	for _, inbound := range []string{"", "has space", strings.Repeat("x", 200)} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if inbound != "" {
+			req.Header.Set(RequestIDHeader, inbound)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if len(seen) != 32 || w.Header().Get(RequestIDHeader) != seen {
+			t.Errorf("Expected generated request ID for inbound %q, got %q", inbound, seen)
+		}
+	}
+}
+
+func TestHandlerAssignsRequestID(t *testing.T) {
+	var seen string
+	handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if seen != "abc-123" || w.Header().Get(RequestIDHeader) != "abc-123" || w.Code != http.StatusNoContent {
+		t.Errorf("Expected inbound request ID to be reused, got context %q, header %q and status %d", seen, w.Header().Get(RequestIDHeader), w.Code)
+	}
+}
+
+func TestSetupWritesJSON(t *testing.T) {
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	var buf bytes.Buffer
+	Setup(&buf, "json", "info")
+	FromContext(WithRequestID(context.Background(), "req-1")).Info("hello", "route", "GET /v1/status")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected JSON log entry, got %q", buf.String())
+	}
+	if entry["msg"] != "hello" || entry["request_id"] != "req-1" || entry["route"] != "GET /v1/status" {
+		t.Errorf("Unexpected log entry: %v", entry)
+	}
+}
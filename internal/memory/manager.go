@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Memory manager defaults
+const (
+	DefaultCheckInterval = 5 * time.Second
+	DefaultHighWatermark = 0.9
+	DefaultTargetRatio   = 0.75
+)
+
+// Consumer is a cache or buffer whose memory usage can be reduced on demand
+type Consumer interface {
+	// Name identifies the consumer in logs and metrics
+	Name() string
+	// SizeBytes returns the approximate memory held by the consumer
+	SizeBytes() int64
+	// Shrink releases entries until the consumer holds at most target bytes
+	// and returns the number of bytes freed
+	Shrink(target int64) int64
+}
+
+// Config holds memory budget and GC tuning settings
+type Config struct {
+	BudgetBytes   int64
+	GCPercent     int
+	CheckInterval time.Duration
+	HighWatermark float64
+	TargetRatio   float64
+}
+
+var (
+	consumerBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dynamiccontrol_memory_consumer_bytes",
+			Help: "Approximate memory held by each registered cache",
+		},
+		[]string{"consumer"},
+	)
+
+	budgetBytes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "dynamiccontrol_memory_budget_bytes",
+			Help: "Configured memory budget",
+		},
+	)
+
+	heapBytes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "dynamiccontrol_memory_heap_bytes",
+			Help: "Heap memory in use at the last check",
+		},
+	)
+
+	shrinkTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dynamiccontrol_memory_shrink_total",
+			Help: "Number of times a consumer was asked to shrink",
+		},
+		[]string{"consumer"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(consumerBytes, budgetBytes, heapBytes, shrinkTotal)
+}
+
+// Manager enforces a memory budget by shrinking registered consumers under pressure
+type Manager struct {
+	config    Config
+	mu        sync.Mutex
+	consumers []Consumer
+	stop      chan struct{}
+	once      sync.Once
+	// heapAlloc returns the heap memory in use
+	heapAlloc func() uint64
+}
+
+// NewManager creates a memory manager and applies the GC tuning settings
+func NewManager(config Config) *Manager {
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = DefaultCheckInterval
+	}
+	if config.HighWatermark <= 0 {
+		config.HighWatermark = DefaultHighWatermark
+	}
+	if config.TargetRatio <= 0 {
+		config.TargetRatio = DefaultTargetRatio
+	}
+
+	if config.GCPercent != 0 {
+		debug.SetGCPercent(config.GCPercent)
+	}
+	if config.BudgetBytes > 0 {
+		debug.SetMemoryLimit(config.BudgetBytes)
+		budgetBytes.Set(float64(config.BudgetBytes))
+	}
+
+	return &Manager{
+		config:    config,
+		stop:      make(chan struct{}),
+		heapAlloc: readHeapAlloc,
+	}
+}
+
+// readHeapAlloc returns the heap memory in use from the runtime statistics
+func readHeapAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// Register adds a consumer to the manager
+func (m *Manager) Register(consumer Consumer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.consumers = append(m.consumers, consumer)
+}
+
+// Start periodically checks memory usage until Stop is called
+func (m *Manager) Start() {
+	go func() {
+		ticker := time.NewTicker(m.config.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				m.Check()
+			}
+		}
+	}()
+}
+
+// Stop terminates the periodic check
+func (m *Manager) Stop() {
+	m.once.Do(func() {
+		close(m.stop)
+	})
+}
+
+// Check updates consumer gauges and shrinks consumers when heap usage exceeds
+// the high watermark of the budget. Larger consumers are shrunk first, each
+// down to the target ratio of its current size, until enough memory is freed.
+func (m *Manager) Check() {
+	heap := m.heapAlloc()
+	heapBytes.Set(float64(heap))
+
+	m.mu.Lock()
+	consumers := append([]Consumer(nil), m.consumers...)
+	m.mu.Unlock()
+
+	sizes := make(map[string]int64, len(consumers))
+	for _, consumer := range consumers {
+		size := consumer.SizeBytes()
+		sizes[consumer.Name()] = size
+		consumerBytes.WithLabelValues(consumer.Name()).Set(float64(size))
+	}
+
+	if m.config.BudgetBytes <= 0 {
+		return
+	}
+
+	limit := int64(float64(m.config.BudgetBytes) * m.config.HighWatermark)
+	excess := int64(heap) - limit
+	if excess <= 0 {
+		return
+	}
+
+	sort.Slice(consumers, func(i, j int) bool {
+		return sizes[consumers[i].Name()] > sizes[consumers[j].Name()]
+	})
+
+	var freed int64
+	for _, consumer := range consumers {
+		if freed >= excess {
+			break
+		}
+		size := sizes[consumer.Name()]
+		if size == 0 {
+			continue
+		}
+
+		released := consumer.Shrink(int64(float64(size) * m.config.TargetRatio))
+		freed += released
+		shrinkTotal.WithLabelValues(consumer.Name()).Inc()
+		consumerBytes.WithLabelValues(consumer.Name()).Set(float64(consumer.SizeBytes()))
+	}
+
+	slog.Warn("Memory pressure", "heap_bytes", heap, "excess_bytes", excess, "released_bytes", freed)
+	runtime.GC()
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"reflect"
+	"runtime/debug"
+	"testing"
+)
+
+// testBudget is large enough that the memory limit it sets never triggers
+const testBudget = int64(1) << 40
+
+// shrinkCall records a Shrink call of a test consumer
+type shrinkCall struct {
+	name   string
+	target int64
+}
+
+// testConsumer holds size bytes and records its Shrink calls
+type testConsumer struct {
+	name  string
+	size  int64
+	calls *[]shrinkCall
+}
+
+func (tc *testConsumer) Name() string {
+	return tc.name
+}
+
+func (tc *testConsumer) SizeBytes() int64 {
+	return tc.size
+}
+
+func (tc *testConsumer) Shrink(target int64) int64 {
+	*tc.calls = append(*tc.calls, shrinkCall{name: tc.name, target: target})
+	if tc.size <= target {
+		return 0
+	}
+	freed := tc.size - target
+	tc.size = target
+	return freed
+}
+
+// newTestManager creates a manager whose heap usage exceeds the high
+// watermark of its budget by excess bytes, with consumers of the given sizes
+func newTestManager(t *testing.T, config Config, excess int64, sizes map[string]int64) (*Manager, *[]shrinkCall) {
+	t.Helper()
+	previous := debug.SetMemoryLimit(-1)
+	t.Cleanup(func() { debug.SetMemoryLimit(previous) })
+
+	manager := NewManager(config)
+	limit := int64(float64(config.BudgetBytes) * manager.config.HighWatermark)
+	manager.heapAlloc = func() uint64 { return uint64(limit + excess) }
+
+	calls := &[]shrinkCall{}
+	for _, name := range []string{"operations", "response_cache", "compiled_schemas", "decision_log"} {
+		if size, ok := sizes[name]; ok {
+			manager.Register(&testConsumer{name: name, size: size, calls: calls})
+		}
+	}
+	return manager, calls
+}
+
+func TestCheckShrinksLargestConsumersFirst(t *testing.T) {
+	manager, calls := newTestManager(t, Config{BudgetBytes: testBudget}, 1500, map[string]int64{
+		"operations":       1000,
+		"response_cache":   4000,
+		"compiled_schemas": 2000,
+		"decision_log":     0,
+	})
+	manager.Check()
+
+	// The response cache frees 1000 bytes and the compiled schemas the
+	// remaining 500, so the operations are left alone
+	expected := []shrinkCall{{"response_cache", 3000}, {"compiled_schemas", 1500}}
+	if !reflect.DeepEqual(*calls, expected) {
+		t.Errorf("Expected shrink calls %+v, got %+v", expected, *calls)
+	}
+}
+
+func TestCheckShrinksEveryConsumerUnderHighPressure(t *testing.T) {
+	manager, calls := newTestManager(t, Config{BudgetBytes: testBudget, TargetRatio: 0.5}, testBudget, map[string]int64{
+		"operations":       1000,
+		"response_cache":   4000,
+		"compiled_schemas": 2000,
+		"decision_log":     0,
+	})
+	manager.Check()
+
+	// Empty consumers are skipped
+	expected := []shrinkCall{{"response_cache", 2000}, {"compiled_schemas", 1000}, {"operations", 500}}
+	if !reflect.DeepEqual(*calls, expected) {
+		t.Errorf("Expected shrink calls %+v, got %+v", expected, *calls)
+	}
+}
+
+func TestCheckWithinBudgetDoesNotShrink(t *testing.T) {
+	manager, calls := newTestManager(t, Config{BudgetBytes: testBudget}, 0, map[string]int64{
+		"operations":     1000,
+		"response_cache": 4000,
+	})
+	manager.Check()
+	if len(*calls) != 0 {
+		t.Errorf("Expected no shrink at the high watermark, got %+v", *calls)
+	}
+
+	unbounded, calls := newTestManager(t, Config{}, 0, map[string]int64{"operations": 1000})
+	unbounded.heapAlloc = func() uint64 { return uint64(testBudget) }
+	unbounded.Check()
+	if len(*calls) != 0 {
+		t.Errorf("Expected no shrink without a budget, got %+v", *calls)
+	}
+}
@@ -0,0 +1,257 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// UpstreamRequests counts proxied requests per route, target and status code
+	UpstreamRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dynamiccontrol_upstream_requests_total",
+			Help: "Total number of requests sent to upstream targets",
+		},
+		[]string{"route", "target", "code"},
+	)
+
+	// UpstreamLatency observes proxied request latency per route and target
+	UpstreamLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dynamiccontrol_upstream_request_duration_seconds",
+			Help:    "Latency of requests sent to upstream targets",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"route", "target"},
+	)
+
+	// UpstreamHealthy reports 1 when an upstream target passes its health checks
+	UpstreamHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dynamiccontrol_upstream_healthy",
+			Help: "Health status of upstream targets (1 healthy, 0 unhealthy)",
+		},
+		[]string{"route", "target"},
+	)
+
+	// PipelineStageLatency observes the time spent in each request pipeline stage
+	PipelineStageLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dynamiccontrol_pipeline_stage_duration_seconds",
+			Help:    "Time spent in each request pipeline stage",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"route", "stage"},
+	)
+
+	// PipelineStageErrors counts requests stopped by a pipeline stage per status code
+	PipelineStageErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dynamiccontrol_pipeline_stage_errors_total",
+			Help: "Total number of requests stopped by a pipeline stage",
+		},
+		[]string{"route", "stage", "code"},
+	)
+
+	// RouteRequests counts requests served by route pipelines per status code
+	RouteRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dynamiccontrol_route_requests_total",
+			Help: "Total number of requests served by routes",
+		},
+		[]string{"route", "code"},
+	)
+
+	// RouteLatency observes the time route pipelines take to serve requests
+	RouteLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dynamiccontrol_route_request_duration_seconds",
+			Help:    "Time taken to serve requests of each route",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"route"},
+	)
+
+	// MirroredRequests counts requests copied to shadow upstreams per status
+	// code of the shadow response
+	MirroredRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dynamiccontrol_mirrored_requests_total",
+			Help: "Total number of requests mirrored to shadow upstreams",
+		},
+		[]string{"route", "code"},
+	)
+
+	// ChaosFaultsInjected counts control plane faults fired by the chaos injector
+	ChaosFaultsInjected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dynamiccontrol_chaos_faults_injected_total",
+			Help: "Total number of control plane faults injected for chaos testing",
+		},
+		[]string{"kind"},
+	)
+
+	// ChaosFaultActive reports 1 while a chaos fault is enabled
+	ChaosFaultActive = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dynamiccontrol_chaos_fault_active",
+			Help: "Whether a chaos fault is enabled (1 active, 0 inactive)",
+		},
+		[]string{"kind"},
+	)
+
+	// SchemaCanaryValidations counts requests validated against both the
+	// current and the candidate request schema of a route, by outcome
+	SchemaCanaryValidations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dynamiccontrol_schema_canary_validations_total",
+			Help: "Total number of requests validated against a candidate request schema",
+		},
+		[]string{"route", "current", "candidate"},
+	)
+
+	// ShadowPolicyDecisions counts decisions of policies running in shadow
+	// mode per route, policy and outcome
+	ShadowPolicyDecisions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dynamiccontrol_shadow_policy_decisions_total",
+			Help: "Total number of decisions of policies evaluated in shadow mode",
+		},
+		[]string{"route", "policy", "outcome"},
+	)
+
+	// DependencyFailures counts requests that found a route dependency
+	// unavailable, per route and failed dependency
+	DependencyFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dynamiccontrol_dependency_failures_total",
+			Help: "Total number of requests served while a route dependency was unavailable",
+		},
+		[]string{"route", "dependency"},
+	)
+
+	// SchemaKeywordLatency observes the validation time of the most expensive
+	// keywords of profiled request and response schemas
+	SchemaKeywordLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dynamiccontrol_schema_keyword_duration_seconds",
+			Help:    "Validation time of the most expensive keywords of profiled schemas",
+			Buckets: []float64{.00001, .000025, .00005, .0001, .00025, .0005, .001, .0025, .005, .01, .025},
+		},
+		[]string{"route", "schema", "keyword"},
+	)
+
+	// ResponseSampleValidations counts proxied responses sampled for
+	// background validation against the route's response schema by outcome:
+	// valid, invalid, skipped (not JSON or too large) or dropped (workers busy)
+	ResponseSampleValidations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dynamiccontrol_response_sample_validations_total",
+			Help: "Total number of sampled proxied responses validated against the response schema",
+		},
+		[]string{"route", "outcome"},
+	)
+
+	// ResponseCacheRequests counts response cache lookups and stores of
+	// cached routes by result: hit, miss, stored, skipped (not cacheable) or bypassed
+	ResponseCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dynamiccontrol_response_cache_requests_total",
+			Help: "Total number of response cache lookups and stores by result",
+		},
+		[]string{"route", "result"},
+	)
+
+	// RateLimitedRequests counts requests rejected with 429 per route and the
+	// limit that was exceeded
+	RateLimitedRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dynamiccontrol_rate_limited_requests_total",
+			Help: "Total number of requests rejected by rate limits",
+		},
+		[]string{"route", "reason"},
+	)
+
+	// SideEffects counts background side effects of requests by outcome
+	SideEffects = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dynamiccontrol_side_effects_total",
+			Help: "Total number of request side effects by kind and result (completed, canceled, panicked, dropped)",
+		},
+		[]string{"kind", "result"},
+	)
+
+	// SideEffectsInFlight tracks the side effects running in the background
+	SideEffectsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dynamiccontrol_side_effects_in_flight",
+			Help: "Number of request side effects running in the background",
+		},
+		[]string{"kind"},
+	)
+
+	// SideEffectDuration tracks how long side effects run
+	SideEffectDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dynamiccontrol_side_effect_duration_seconds",
+			Help:    "Time spent running request side effects",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"kind"},
+	)
+
+	// TrafficDivergence reports how far the observed traffic of a service
+	// diverges from its accepted traffic intents, relative to their volume
+	TrafficDivergence = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dynamiccontrol_traffic_divergence_ratio",
+			Help: "Divergence of observed service traffic from the accepted volume (0 in sync, positive over, negative under)",
+		},
+		[]string{"service"},
+	)
+
+	// RegistryInstances reports the healthy registered instances of services
+	RegistryInstances = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dynamiccontrol_registry_instances",
+			Help: "Healthy instances registered for each service",
+		},
+		[]string{"service"},
+	)
+
+	// TrafficRules reports the stored traffic rules of services
+	TrafficRules = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dynamiccontrol_traffic_rules",
+			Help: "Traffic rules stored for each service",
+		},
+		[]string{"service"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		UpstreamRequests,
+		UpstreamLatency,
+		UpstreamHealthy,
+		PipelineStageLatency,
+		PipelineStageErrors,
+		RouteRequests,
+		RouteLatency,
+		MirroredRequests,
+		ChaosFaultsInjected,
+		ChaosFaultActive,
+		SchemaCanaryValidations,
+		ShadowPolicyDecisions,
+		DependencyFailures,
+		SchemaKeywordLatency,
+		ResponseSampleValidations,
+		ResponseCacheRequests,
+		RateLimitedRequests,
+		SideEffects,
+		SideEffectsInFlight,
+		SideEffectDuration,
+		TrafficDivergence,
+		RegistryInstances,
+		TrafficRules,
+	)
+}
@@ -0,0 +1,97 @@
+package observability
+
+import (
+	"fmt"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Burn rate of the error budget alerting on availability SLOs: at this rate
+// a 30 day budget is spent in about two days
+const fastBurnRate = 14.4
+
+// RuleFile is a Prometheus rule file
+type RuleFile struct {
+	Groups []RuleGroup `json:"groups"`
+}
+
+// RuleGroup is a named group of alert rules
+type RuleGroup struct {
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules"`
+}
+
+// Rule is a Prometheus alert rule
+type Rule struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// GenerateAlertRules returns alert rules for the configured routes: deny
+// rates of routes with policies, latency and availability of routes with
+// SLOs, and proxied routes without a healthy upstream
+func GenerateAlertRules(config *types.RoutesConfig, options Options) RuleFile {
+	options = options.withDefaults()
+	rules := []Rule{}
+	for _, group := range groupRoutes(config) {
+		labels := func(severity string) map[string]string {
+			return map[string]string{"severity": severity, "route": group.path}
+		}
+
+		if len(group.policies) > 0 {
+			threshold := group.denyRate(options)
+			rules = append(rules, Rule{
+				Alert:  "DynamicControlRouteDenyRateHigh",
+				Expr:   fmt.Sprintf("%s > %s", denyRatio(group.path, "5m"), formatFloat(threshold)),
+				For:    "10m",
+				Labels: labels("warning"),
+				Annotations: map[string]string{
+					"summary":     fmt.Sprintf("Policies deny over %s%% of %s", formatFloat(threshold*100), group.title()),
+					"description": fmt.Sprintf("Policies %s deny {{ $value | humanizePercentage }} of requests.", strings.Join(group.policies, ", ")),
+				},
+			})
+		}
+		if group.slo != nil && group.slo.LatencyMs > 0 {
+			quantile := group.latencyQuantile()
+			rules = append(rules, Rule{
+				Alert:  "DynamicControlRouteLatencySLO",
+				Expr:   fmt.Sprintf("%s > %s", latencyQuantile(group.path, quantile, "5m"), formatFloat(float64(group.slo.LatencyMs)/1000)),
+				For:    "10m",
+				Labels: labels("warning"),
+				Annotations: map[string]string{
+					"summary":     fmt.Sprintf("p%s latency of %s is above %dms", formatFloat(quantile*100), group.title(), group.slo.LatencyMs),
+					"description": fmt.Sprintf("p%s latency is {{ $value | humanizeDuration }}.", formatFloat(quantile*100)),
+				},
+			})
+		}
+		if group.slo != nil && group.slo.Availability > 0 {
+			threshold := formatFloat(fastBurnRate * errorBudget(group.slo))
+			rules = append(rules, Rule{
+				Alert:  "DynamicControlRouteErrorBudgetBurn",
+				Expr:   fmt.Sprintf("(%s) > %s and (%s) > %s", errorRatio(group.path, "1h"), threshold, errorRatio(group.path, "5m"), threshold),
+				For:    "2m",
+				Labels: labels("critical"),
+				Annotations: map[string]string{
+					"summary":     fmt.Sprintf("%s is burning the error budget of its %s%% availability SLO", group.title(), formatFloat(group.slo.Availability)),
+					"description": "{{ $value | humanizePercentage }} of requests fail with a server error.",
+				},
+			})
+		}
+		if group.proxied {
+			rules = append(rules, Rule{
+				Alert:  "DynamicControlRouteUpstreamsDown",
+				Expr:   fmt.Sprintf("max(dynamiccontrol_upstream_healthy%s) == 0", routeSelector(group.path)),
+				For:    "1m",
+				Labels: labels("critical"),
+				Annotations: map[string]string{
+					"summary": fmt.Sprintf("No healthy upstream serves %s", group.title()),
+				},
+			})
+		}
+	}
+	return RuleFile{Groups: []RuleGroup{{Name: "dynamiccontrol-routes", Rules: rules}}}
+}
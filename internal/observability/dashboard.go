@@ -0,0 +1,173 @@
+package observability
+
+import (
+	"fmt"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Dashboard is a Grafana dashboard model, as imported through the Grafana
+// UI or provisioning
+type Dashboard = map[string]interface{}
+
+// Dashboard layout on Grafana's 24 column grid
+const (
+	panelWidth  = 8
+	panelHeight = 8
+	gridWidth   = 24
+)
+
+// datasourceRef refers panels to the dashboard's datasource variable
+var datasourceRef = map[string]interface{}{"type": "prometheus", "uid": "${datasource}"}
+
+// panelLayout places panels on the dashboard grid
+type panelLayout struct {
+	panels []interface{}
+	x, y   int
+}
+
+// row starts a collapsible row of panels
+func (l *panelLayout) row(title string) {
+	if l.x > 0 {
+		l.x, l.y = 0, l.y+panelHeight
+	}
+	l.panels = append(l.panels, map[string]interface{}{
+		"id":        len(l.panels) + 1,
+		"type":      "row",
+		"title":     title,
+		"collapsed": false,
+		"gridPos":   map[string]int{"x": 0, "y": l.y, "w": gridWidth, "h": 1},
+		"panels":    []interface{}{},
+	})
+	l.y++
+}
+
+// add places a panel after the previous one, wrapping at the grid width
+func (l *panelLayout) add(panel map[string]interface{}) {
+	if l.x+panelWidth > gridWidth {
+		l.x, l.y = 0, l.y+panelHeight
+	}
+	panel["id"] = len(l.panels) + 1
+	panel["datasource"] = datasourceRef
+	panel["gridPos"] = map[string]int{"x": l.x, "y": l.y, "w": panelWidth, "h": panelHeight}
+	l.panels = append(l.panels, panel)
+	l.x += panelWidth
+}
+
+// target is a query of a panel
+type target struct {
+	expr   string
+	legend string
+}
+
+// timeSeries returns a time series panel of the targets in unit, with a
+// threshold line unless threshold is zero
+func timeSeries(title, description, unit string, threshold float64, targets ...target) map[string]interface{} {
+	queries := make([]interface{}, 0, len(targets))
+	for i, target := range targets {
+		queries = append(queries, map[string]interface{}{
+			"refId":        string(rune('A' + i)),
+			"datasource":   datasourceRef,
+			"expr":         target.expr,
+			"legendFormat": target.legend,
+		})
+	}
+	defaults := map[string]interface{}{"unit": unit}
+	if threshold > 0 {
+		defaults["thresholds"] = map[string]interface{}{
+			"mode": "absolute",
+			"steps": []interface{}{
+				map[string]interface{}{"color": "green", "value": nil},
+				map[string]interface{}{"color": "red", "value": threshold},
+			},
+		}
+		defaults["custom"] = map[string]interface{}{"thresholdsStyle": map[string]string{"mode": "line"}}
+	}
+	return map[string]interface{}{
+		"type":        "timeseries",
+		"title":       title,
+		"description": description,
+		"targets":     queries,
+		"fieldConfig": map[string]interface{}{"defaults": defaults, "overrides": []interface{}{}},
+	}
+}
+
+// GenerateDashboard returns a dashboard with an overview row and a row per
+// route path: request rates by status, latency against the SLO, deny rates
+// of the route's policies, shadow policy outcomes and upstream latency
+func GenerateDashboard(config *types.RoutesConfig, options Options) Dashboard {
+	options = options.withDefaults()
+	layout := &panelLayout{}
+
+	layout.row("Overview")
+	layout.add(timeSeries("Requests by route", "Requests served by each route", "reqps", 0,
+		target{"sum by (route) (rate(dynamiccontrol_route_requests_total[5m]))", "{{route}}"}))
+	layout.add(timeSeries("Server errors by route", "Requests failing with a 5xx status", "reqps", 0,
+		target{`sum by (route) (rate(dynamiccontrol_route_requests_total{code=~"5.."}[5m]))`, "{{route}}"}))
+	layout.add(timeSeries("Rate limited requests", "Requests rejected by rate limits, throttles and quotas", "reqps", 0,
+		target{"sum by (route, reason) (rate(dynamiccontrol_rate_limited_requests_total[5m]))", "{{route}} {{reason}}"}))
+
+	for _, group := range groupRoutes(config) {
+		layout.row(group.title())
+		layout.add(timeSeries("Requests", "Requests by status code", "reqps", 0,
+			target{fmt.Sprintf("sum by (code) (rate(dynamiccontrol_route_requests_total%s[5m]))", routeSelector(group.path)), "{{code}}"}))
+
+		quantile := group.latencyQuantile()
+		var latencyObjective float64
+		description := "Latency of the route pipeline"
+		if group.slo != nil && group.slo.LatencyMs > 0 {
+			latencyObjective = float64(group.slo.LatencyMs) / 1000
+			description += fmt.Sprintf(", against the SLO of %dms at p%s", group.slo.LatencyMs, formatFloat(quantile*100))
+		}
+		layout.add(timeSeries("Latency", description, "s", latencyObjective,
+			target{latencyQuantile(group.path, 0.5, "5m"), "p50"},
+			target{latencyQuantile(group.path, quantile, "5m"), "p" + formatFloat(quantile*100)}))
+
+		if len(group.policies) > 0 {
+			layout.add(timeSeries("Deny rate", "Fraction of requests denied by "+strings.Join(group.policies, ", "), "percentunit", group.denyRate(options),
+				target{denyRatio(group.path, "5m"), "denied"}))
+		}
+		if len(group.shadow) > 0 {
+			layout.add(timeSeries("Shadow policies", "Decisions of shadow policies "+strings.Join(group.shadow, ", "), "reqps", 0,
+				target{fmt.Sprintf("sum by (policy, outcome) (rate(dynamiccontrol_shadow_policy_decisions_total%s[5m]))", routeSelector(group.path)), "{{policy}} {{outcome}}"}))
+		}
+		if group.slo != nil && group.slo.Availability > 0 {
+			layout.add(timeSeries("Error budget burn", fmt.Sprintf("Server error rate relative to the budget of a %s%% availability SLO", formatFloat(group.slo.Availability)), "none", 1,
+				target{fmt.Sprintf("(%s) / %s", errorRatio(group.path, "1h"), formatFloat(errorBudget(group.slo))), "1h burn rate"}))
+		}
+		if group.proxied {
+			layout.add(timeSeries("Upstream latency", "Latency of the upstream targets", "s", 0,
+				target{fmt.Sprintf("histogram_quantile(0.99, sum by (le, target) (rate(dynamiccontrol_upstream_request_duration_seconds_bucket%s[5m])))", routeSelector(group.path)), "p99 {{target}}"}))
+		}
+	}
+
+	return Dashboard{
+		"uid":           "dynamiccontrol-routes",
+		"title":         options.Title,
+		"tags":          []string{"dynamiccontrol", "generated"},
+		"timezone":      "browser",
+		"schemaVersion": 39,
+		"editable":      true,
+		"refresh":       "30s",
+		"time":          map[string]string{"from": "now-6h", "to": "now"},
+		"templating": map[string]interface{}{
+			"list": []interface{}{
+				map[string]interface{}{
+					"name":    "datasource",
+					"label":   "Datasource",
+					"type":    "datasource",
+					"query":   "prometheus",
+					"current": map[string]string{"text": options.Datasource, "value": options.Datasource},
+				},
+			},
+		},
+		"panels": layout.panels,
+	}
+}
+
+// errorBudget returns the fraction of requests an availability SLO allows
+// to fail
+func errorBudget(slo *types.SLOConfig) float64 {
+	return (100 - slo.Availability) / 100
+}
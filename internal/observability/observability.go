@@ -0,0 +1,180 @@
+// Package observability generates Grafana dashboards and Prometheus alert
+// rules for a route configuration, so monitoring follows the routes,
+// policies and SLOs that are actually configured.
+package observability
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Generator defaults
+const (
+	DefaultDatasource        = "Prometheus"
+	DefaultDenyRate          = 0.05
+	DefaultLatencyPercentile = 99
+	DefaultTitle             = "Dynamic Control Routes"
+)
+
+// Options tune the generated dashboard and alert rules
+type Options struct {
+	// Title of the dashboard, DefaultTitle by default
+	Title string
+	// Datasource is the default Prometheus datasource of the dashboard
+	Datasource string
+	// DenyRate is the fraction of denied requests that alerts on routes with
+	// policies, unless their SLO sets maxDenyRate
+	DenyRate float64
+}
+
+// withDefaults fills unset options with their defaults
+func (o Options) withDefaults() Options {
+	if o.Title == "" {
+		o.Title = DefaultTitle
+	}
+	if o.Datasource == "" {
+		o.Datasource = DefaultDatasource
+	}
+	if o.DenyRate <= 0 {
+		o.DenyRate = DefaultDenyRate
+	}
+	return o
+}
+
+// routeGroup gathers the routes sharing a path, which share metric labels
+type routeGroup struct {
+	path     string
+	methods  []string
+	policies []string
+	shadow   []string
+	proxied  bool
+	slo      *types.SLOConfig
+}
+
+// title names the group in panels and alerts, such as "GET, POST /v1/orders"
+func (g routeGroup) title() string {
+	return strings.Join(g.methods, ", ") + " " + g.path
+}
+
+// groupRoutes groups the routes of a configuration by path, in path order.
+// Metrics are labelled by path, so routes of several methods, hosts or
+// tenants with the same path share panels and alerts; the strictest SLO of
+// the group applies.
+func groupRoutes(config *types.RoutesConfig) []routeGroup {
+	groups := make(map[string]*routeGroup)
+	for _, route := range config.Routes {
+		group, exists := groups[route.RouteName]
+		if !exists {
+			group = &routeGroup{path: route.RouteName}
+			groups[route.RouteName] = group
+		}
+		group.methods = appendUnique(group.methods, route.Method)
+		for _, policy := range route.Policies {
+			if route.PolicySettings[policy].Mode == types.PolicyModeShadow {
+				group.shadow = appendUnique(group.shadow, policy)
+			} else {
+				group.policies = appendUnique(group.policies, policy)
+			}
+		}
+		group.proxied = group.proxied || route.Handler == types.HandlerProxy
+		group.slo = stricterSLO(group.slo, route.SLO)
+	}
+
+	result := make([]routeGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Strings(group.methods)
+		sort.Strings(group.policies)
+		sort.Strings(group.shadow)
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].path < result[j].path })
+	return result
+}
+
+// stricterSLO combines two SLOs, keeping the stricter value of each objective
+func stricterSLO(a, b *types.SLOConfig) *types.SLOConfig {
+	if a == nil || b == nil {
+		if a == nil {
+			return b
+		}
+		return a
+	}
+	combined := *a
+	if b.LatencyMs > 0 && (combined.LatencyMs == 0 || b.LatencyMs < combined.LatencyMs) {
+		combined.LatencyMs = b.LatencyMs
+		combined.LatencyPercentile = b.LatencyPercentile
+	}
+	combined.Availability = max(combined.Availability, b.Availability)
+	if b.MaxDenyRate > 0 && (combined.MaxDenyRate == 0 || b.MaxDenyRate < combined.MaxDenyRate) {
+		combined.MaxDenyRate = b.MaxDenyRate
+	}
+	return &combined
+}
+
+// latencyQuantile returns the SLO percentile of a group as a quantile
+func (g routeGroup) latencyQuantile() float64 {
+	if g.slo != nil && g.slo.LatencyPercentile > 0 {
+		return g.slo.LatencyPercentile / 100
+	}
+	return DefaultLatencyPercentile / 100.0
+}
+
+// denyRate returns the deny rate alerting on the group
+func (g routeGroup) denyRate(options Options) float64 {
+	if g.slo != nil && g.slo.MaxDenyRate > 0 {
+		return g.slo.MaxDenyRate
+	}
+	return options.DenyRate
+}
+
+// appendUnique appends value to values unless it is already present
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// routeSelector returns the label selector of a route's series
+func routeSelector(path string, extra ...string) string {
+	return "{" + strings.Join(append([]string{"route=" + strconv.Quote(path)}, extra...), ",") + "}"
+}
+
+// requestRate returns the request rate of a route over window
+func requestRate(path, window string, extra ...string) string {
+	return fmt.Sprintf("sum(rate(dynamiccontrol_route_requests_total%s[%s]))", routeSelector(path, extra...), window)
+}
+
+// errorRatio returns the fraction of a route's requests failing with 5xx
+func errorRatio(path, window string) string {
+	return fmt.Sprintf("%s / %s", requestRate(path, window, `code=~"5.."`), requestRate(path, window))
+}
+
+// denyRatio returns the fraction of a route's requests denied by policies
+func denyRatio(path, window string) string {
+	return fmt.Sprintf(
+		"sum(rate(dynamiccontrol_pipeline_stage_errors_total%s[%s])) / sum(rate(dynamiccontrol_pipeline_stage_duration_seconds_count%s[%s]))",
+		routeSelector(path, `stage="authorize"`, `code="403"`), window, routeSelector(path, `stage="authorize"`), window,
+	)
+}
+
+// latencyQuantile returns a latency quantile of a route in seconds
+func latencyQuantile(path string, quantile float64, window string) string {
+	return fmt.Sprintf(
+		"histogram_quantile(%s, sum by (le) (rate(dynamiccontrol_route_request_duration_seconds_bucket%s[%s])))",
+		formatFloat(quantile), routeSelector(path), window,
+	)
+}
+
+// formatFloat formats a number for PromQL and alert messages, rounded to
+// hide floating point noise such as 0.014400000000000001
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(math.Round(value*1e9)/1e9, 'f', -1, 64)
+}
@@ -0,0 +1,113 @@
+package observability
+
+import (
+	"strings"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+func testConfig() *types.RoutesConfig {
+	return &types.RoutesConfig{
+		Routes: []types.RouteConfig{
+			{
+				RouteName: "/v1/orders",
+				Method:    "POST",
+				Policies:  []string{"orders", "orders_v2"},
+				PolicySettings: map[string]types.PolicySettings{
+					"orders_v2": {Mode: types.PolicyModeShadow},
+				},
+				SLO: &types.SLOConfig{LatencyMs: 300, Availability: 99.9},
+			},
+			{
+				RouteName: "/v1/orders",
+				Method:    "GET",
+				Handler:   types.HandlerProxy,
+				SLO:       &types.SLOConfig{LatencyMs: 100, LatencyPercentile: 95, MaxDenyRate: 0.2},
+			},
+			{RouteName: "/v1/status", Method: "GET"},
+		},
+	}
+}
+
+func TestGroupRoutes(t *testing.T) {
+	groups := groupRoutes(testConfig())
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 route groups, got %d", len(groups))
+	}
+	orders := groups[0]
+	if orders.title() != "GET, POST /v1/orders" {
+		t.Errorf("Unexpected title %q", orders.title())
+	}
+	if strings.Join(orders.policies, ",") != "orders" || strings.Join(orders.shadow, ",") != "orders_v2" {
+		t.Errorf("Unexpected policies %v and shadow policies %v", orders.policies, orders.shadow)
+	}
+	if !orders.proxied {
+		t.Error("Expected the group to be proxied")
+	}
+	// The strictest objectives of the group's routes apply
+	if orders.slo.LatencyMs != 100 || orders.latencyQuantile() != 0.95 || orders.slo.Availability != 99.9 {
+		t.Errorf("Unexpected SLO %+v", orders.slo)
+	}
+	if rate := orders.denyRate(Options{DenyRate: 0.05}); rate != 0.2 {
+		t.Errorf("Expected the SLO deny rate 0.2, got %v", rate)
+	}
+	if rate := groups[1].denyRate(Options{DenyRate: 0.05}); rate != 0.05 {
+		t.Errorf("Expected the default deny rate 0.05, got %v", rate)
+	}
+}
+
+func TestGenerateAlertRules(t *testing.T) {
+	rules := GenerateAlertRules(testConfig(), Options{})
+	if len(rules.Groups) != 1 {
+		t.Fatalf("Expected one rule group, got %d", len(rules.Groups))
+	}
+
+	expressions := make(map[string]string)
+	for _, rule := range rules.Groups[0].Rules {
+		if rule.Labels["route"] != "/v1/orders" {
+			t.Errorf("Unexpected alert %s for route %s", rule.Alert, rule.Labels["route"])
+		}
+		expressions[rule.Alert] = rule.Expr
+	}
+	expected := map[string]string{
+		"DynamicControlRouteDenyRateHigh":    `dynamiccontrol_pipeline_stage_errors_total{route="/v1/orders",stage="authorize",code="403"}[5m])) / sum(rate(dynamiccontrol_pipeline_stage_duration_seconds_count{route="/v1/orders",stage="authorize"}[5m])) > 0.2`,
+		"DynamicControlRouteLatencySLO":      `histogram_quantile(0.95, sum by (le) (rate(dynamiccontrol_route_request_duration_seconds_bucket{route="/v1/orders"}[5m]))) > 0.1`,
+		"DynamicControlRouteErrorBudgetBurn": `{route="/v1/orders",code=~"5.."}[5m])) / sum(rate(dynamiccontrol_route_requests_total{route="/v1/orders"}[5m]))) > 0.0144`,
+		"DynamicControlRouteUpstreamsDown":   `max(dynamiccontrol_upstream_healthy{route="/v1/orders"}) == 0`,
+	}
+	if len(expressions) != len(expected) {
+		t.Errorf("Expected %d alerts, got %v", len(expected), expressions)
+	}
+	for alert, suffix := range expected {
+		if !strings.HasSuffix(expressions[alert], suffix) {
+			t.Errorf("Expected %s to end with %s, got %s", alert, suffix, expressions[alert])
+		}
+	}
+}
+
+func TestGenerateDashboard(t *testing.T) {
+	dashboard := GenerateDashboard(testConfig(), Options{Title: "Orders"})
+	if dashboard["title"] != "Orders" {
+		t.Errorf("Unexpected title %v", dashboard["title"])
+	}
+
+	var titles []string
+	ids := make(map[int]bool)
+	for _, panel := range dashboard["panels"].([]interface{}) {
+		panel := panel.(map[string]interface{})
+		titles = append(titles, panel["title"].(string))
+		ids[panel["id"].(int)] = true
+	}
+	expected := []string{
+		"Overview", "Requests by route", "Server errors by route", "Rate limited requests",
+		"GET, POST /v1/orders", "Requests", "Latency", "Deny rate", "Shadow policies", "Error budget burn", "Upstream latency",
+		"GET /v1/status", "Requests", "Latency",
+	}
+	if strings.Join(titles, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected panels %v, got %v", expected, titles)
+	}
+	if len(ids) != len(titles) {
+		t.Errorf("Expected unique panel ids, got %v", ids)
+	}
+}
@@ -0,0 +1,104 @@
+package opa
+
+import (
+	"fmt"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/tester"
+)
+
+// Builtin is an organization-specific function policies can call, such as a
+// GeoIP lookup or an entitlement check
+type Builtin struct {
+	// Decl names the function and declares its signature
+	Decl *rego.Function
+	// Impl computes the result from the evaluated arguments. A nil result
+	// leaves the call undefined.
+	Impl rego.BuiltinDyn
+}
+
+// RegisterBuiltin makes a custom function available to policies. Built-ins
+// are resolved when a policy is prepared for evaluation, so they must be
+// registered before any policy is loaded.
+func (pm *PolicyManager) RegisterBuiltin(builtin Builtin) error {
+	if builtin.Decl == nil || builtin.Decl.Name == "" || builtin.Decl.Decl == nil || builtin.Impl == nil {
+		return fmt.Errorf("built-in requires a name, a declaration and an implementation")
+	}
+	name := builtin.Decl.Name
+	if _, exists := ast.BuiltinMap[name]; exists {
+		return fmt.Errorf("built-in %s conflicts with an OPA built-in", name)
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if len(pm.policies.Load().queries) > 0 {
+		return fmt.Errorf("built-in %s must be registered before policies are loaded", name)
+	}
+	for _, existing := range pm.builtins {
+		if existing.Decl.Name == name {
+			return fmt.Errorf("built-in %s is already registered", name)
+		}
+	}
+	pm.builtins = append(pm.builtins, builtin)
+	return nil
+}
+
+// Builtins returns the names of the registered custom built-ins
+func (pm *PolicyManager) Builtins() []string {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	names := make([]string, len(pm.builtins))
+	for i, builtin := range pm.builtins {
+		names[i] = builtin.Decl.Name
+	}
+	return names
+}
+
+// RegisteredBuiltins returns the registered custom built-ins, for
+// evaluations outside the policy manager such as REPL queries and policy
+// tests
+func (pm *PolicyManager) RegisteredBuiltins() []Builtin {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return append([]Builtin(nil), pm.builtins...)
+}
+
+// builtinOptions returns the options declaring custom built-ins to rego
+func builtinOptions(builtins []Builtin) []func(*rego.Rego) {
+	options := make([]func(*rego.Rego), len(builtins))
+	for i, builtin := range builtins {
+		options[i] = rego.FunctionDyn(builtin.Decl, builtin.Impl)
+	}
+	return options
+}
+
+// testerBuiltins converts custom built-ins for the policy test runner
+func testerBuiltins(builtins []Builtin) []*tester.Builtin {
+	converted := make([]*tester.Builtin, len(builtins))
+	for i, builtin := range builtins {
+		converted[i] = &tester.Builtin{
+			Decl: &ast.Builtin{
+				Name: builtin.Decl.Name,
+				Decl: builtin.Decl.Decl,
+			},
+			Func: rego.FunctionDyn(builtin.Decl, builtin.Impl),
+		}
+	}
+	return converted
+}
+
+// regoOptions returns the options preparing a query of a policy module,
+// with the registered built-ins
+func (pm *PolicyManager) regoOptions(query string, module *ast.Module) []func(*rego.Rego) {
+	pm.mu.Lock()
+	builtins := pm.builtins
+	pm.mu.Unlock()
+
+	options := []func(*rego.Rego){
+		rego.Query(query),
+		rego.ParsedModule(module),
+		rego.Store(pm.store),
+	}
+	return append(options, builtinOptions(builtins)...)
+}
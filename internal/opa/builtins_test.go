@@ -0,0 +1,141 @@
+package opa
+
+import (
+	"context"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	regotypes "github.com/open-policy-agent/opa/types"
+)
+
+// entitledBuiltin grants the "reports" feature to the acme tenant
+var entitledBuiltin = Builtin{
+	Decl: &rego.Function{
+		Name: "org.entitled",
+		Decl: regotypes.NewFunction(regotypes.Args(regotypes.S, regotypes.S), regotypes.B),
+	},
+	Impl: func(_ rego.BuiltinContext, args []*ast.Term) (*ast.Term, error) {
+		tenant, _ := args[0].Value.(ast.String)
+		feature, _ := args[1].Value.(ast.String)
+		return ast.BooleanTerm(tenant == "acme" && feature == "reports"), nil
+	},
+}
+
+const entitlementPolicy = `package entitlement_policy
+
+import future.keywords.if
+
+default allow = false
+
+allow if org.entitled(input.tenant, "reports")
+
+plan := "enterprise" if org.entitled(input.tenant, "reports")
+`
+
+func TestPoliciesCallRegisteredBuiltins(t *testing.T) {
+	pm := NewPolicyManager()
+	if err := pm.RegisterBuiltin(entitledBuiltin); err != nil {
+		t.Fatalf("Failed to register built-in: %v", err)
+	}
+	if err := pm.SetPolicy("entitlement_policy", entitlementPolicy); err != nil {
+		t.Fatalf("Failed to load policy calling a built-in: %v", err)
+	}
+
+	result, _ := pm.EvaluatePolicy("entitlement_policy", map[string]interface{}{"tenant": "acme"})
+	if !result.Allowed {
+		t.Errorf("Expected an entitled tenant to be allowed: %s", result.Error)
+	}
+	result, _ = pm.EvaluatePolicy("entitlement_policy", map[string]interface{}{"tenant": "globex"})
+	if result.Allowed {
+		t.Error("Expected a tenant without the entitlement to be denied")
+	}
+	plan, defined, err := pm.EvaluateRule("entitlement_policy", "plan", map[string]interface{}{"tenant": "acme"})
+	if err != nil || !defined || plan != "enterprise" {
+		t.Errorf("Expected rules to call built-ins, got %v (defined %v): %v", plan, defined, err)
+	}
+
+	candidate, err := pm.WithPolicy("entitlement_policy", entitlementPolicy)
+	if err != nil {
+		t.Fatalf("Expected candidate policies to call built-ins: %v", err)
+	}
+	if result, _ := candidate.EvaluatePolicy("entitlement_policy", map[string]interface{}{"tenant": "acme"}); !result.Allowed {
+		t.Errorf("Expected the candidate policy to allow an entitled tenant: %s", result.Error)
+	}
+}
+
+func TestRegisterBuiltinRejectsInvalidRegistrations(t *testing.T) {
+	pm := NewPolicyManager()
+	if err := pm.SetPolicy("entitlement_policy", entitlementPolicy); err == nil {
+		t.Error("Expected a policy calling an unregistered built-in to fail to compile")
+	}
+
+	if err := pm.RegisterBuiltin(Builtin{Decl: &rego.Function{Name: "org.entitled"}}); err == nil {
+		t.Error("Expected a built-in without a declaration to be rejected")
+	}
+	conflicting := entitledBuiltin
+	conflicting.Decl = &rego.Function{Name: "count", Decl: entitledBuiltin.Decl.Decl}
+	if err := pm.RegisterBuiltin(conflicting); err == nil {
+		t.Error("Expected a built-in shadowing an OPA built-in to be rejected")
+	}
+	if err := pm.RegisterBuiltin(entitledBuiltin); err != nil {
+		t.Fatalf("Failed to register built-in: %v", err)
+	}
+	if err := pm.RegisterBuiltin(entitledBuiltin); err == nil {
+		t.Error("Expected a duplicate built-in to be rejected")
+	}
+
+	if err := pm.SetPolicy("entitlement_policy", entitlementPolicy); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	late := entitledBuiltin
+	late.Decl = &rego.Function{Name: "org.geoip", Decl: entitledBuiltin.Decl.Decl}
+	if err := pm.RegisterBuiltin(late); err == nil {
+		t.Error("Expected built-ins registered after policies are loaded to be rejected")
+	}
+	if names := pm.Builtins(); len(names) != 1 || names[0] != "org.entitled" {
+		t.Errorf("Unexpected built-ins %v", names)
+	}
+}
+
+func TestREPLAndTesterCallRegisteredBuiltins(t *testing.T) {
+	pm := NewPolicyManager()
+	if err := pm.RegisterBuiltin(entitledBuiltin); err != nil {
+		t.Fatalf("Failed to register built-in: %v", err)
+	}
+	if err := pm.SetPolicy("entitlement_policy", entitlementPolicy); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	result, err := EvaluateQuery(context.Background(), pm.PolicySources(), nil, types.PolicyREPLRequest{
+		Query: "data.entitlement_policy.allow",
+		Input: map[string]interface{}{"tenant": "acme"},
+	}, pm.RegisteredBuiltins()...)
+	if err != nil {
+		t.Fatalf("Expected the REPL to evaluate a policy calling a built-in: %v", err)
+	}
+	if !result.Defined || result.Results[0].Expressions[0] != true {
+		t.Errorf("Unexpected REPL result %+v", result)
+	}
+
+	report, err := RunTests(context.Background(), map[string]string{"entitlement_policy.rego": entitlementPolicy}, map[string]string{
+		"entitlement_policy_test.rego": `package entitlement_policy
+
+test_entitled_tenant_allowed {
+	allow with input as {"tenant": "acme"}
+}
+
+test_builtin_called_directly {
+	not org.entitled("globex", "reports")
+}
+`,
+	}, pm.RegisteredBuiltins()...)
+	if err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+	if report.Passed != 2 || report.Failed != 0 || report.Errors != 0 {
+		t.Errorf("Expected both tests to pass, got %+v", report)
+	}
+}
@@ -0,0 +1,87 @@
+package opa
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/open-policy-agent/opa/bundle"
+)
+
+// DefaultBundleKeyID names the verification key when none is configured
+const DefaultBundleKeyID = "default"
+
+// BundleVerification configures the key OPA bundle signatures are checked
+// against. Key is a PEM encoded public key, or the shared secret for HMAC
+// algorithms.
+type BundleVerification struct {
+	Key       string
+	KeyID     string
+	Algorithm string
+}
+
+// Bundle is the policy content of an OPA bundle
+type Bundle struct {
+	Revision string
+	// Policies holds the Rego source of each module keyed by package path,
+	// such as "status_policy" for package status_policy
+	Policies map[string]string
+	Data     map[string]interface{}
+}
+
+// ReadBundle reads a gzipped OPA bundle tarball. With a verification key the
+// bundle must carry a valid signature; without one signatures are ignored.
+func ReadBundle(r io.Reader, verification *BundleVerification) (*Bundle, error) {
+	return readBundle(bundle.NewReader(r), verification)
+}
+
+// ReadBundleDir reads an OPA bundle laid out as a directory
+func ReadBundleDir(dir string, verification *BundleVerification) (*Bundle, error) {
+	return readBundle(bundle.NewCustomReader(bundle.NewDirectoryLoader(dir)), verification)
+}
+
+// readBundle reads and verifies a bundle and extracts its policies and data
+func readBundle(reader *bundle.Reader, verification *BundleVerification) (*Bundle, error) {
+	if verification != nil {
+		keyID := verification.KeyID
+		if keyID == "" {
+			keyID = DefaultBundleKeyID
+		}
+		algorithm := verification.Algorithm
+		if algorithm == "" {
+			algorithm = "RS256"
+		}
+		keys := map[string]*bundle.KeyConfig{
+			keyID: {Key: verification.Key, Algorithm: algorithm},
+		}
+		reader = reader.WithBundleVerificationConfig(bundle.NewVerificationConfig(keys, keyID, "", nil))
+	} else {
+		reader = reader.WithSkipBundleVerification(true)
+	}
+
+	read, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	if verification != nil && len(read.Signatures.Signatures) == 0 {
+		return nil, fmt.Errorf("bundle is not signed")
+	}
+
+	result := &Bundle{
+		Revision: read.Manifest.Revision,
+		Policies: make(map[string]string, len(read.Modules)),
+		Data:     read.Data,
+	}
+	for _, module := range read.Modules {
+		if IsTestFile(path.Base(module.Path)) || module.Parsed == nil {
+			continue
+		}
+		name := strings.TrimPrefix(module.Parsed.Package.Path.String(), "data.")
+		if _, exists := result.Policies[name]; exists {
+			return nil, fmt.Errorf("bundle holds several modules of package %s", name)
+		}
+		result.Policies[name] = string(module.Raw)
+	}
+	return result, nil
+}
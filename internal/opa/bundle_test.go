@@ -0,0 +1,83 @@
+package opa
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/open-policy-agent/opa/bundle"
+)
+
+const bundlePolicy = `package orders_policy
+
+import future.keywords.if
+import future.keywords.in
+
+default allow = false
+
+allow if input.body.region in data.regions
+`
+
+// buildBundle writes a gzipped bundle, signed with secret when one is given
+func buildBundle(t *testing.T, secret string) []byte {
+	t.Helper()
+	b := bundle.Bundle{
+		Manifest: bundle.Manifest{Revision: "rev-1"},
+		Data:     map[string]interface{}{"regions": []interface{}{"eu", "us"}},
+		Modules: []bundle.ModuleFile{
+			{URL: "/orders/policy.rego", Path: "/orders/policy.rego", Raw: []byte(bundlePolicy)},
+			{URL: "/orders/policy_test.rego", Path: "/orders/policy_test.rego", Raw: []byte("package orders_policy\n\ntest_allow { allow with input as {\"body\": {\"region\": \"eu\"}} }\n")},
+		},
+	}
+	if secret != "" {
+		if err := b.GenerateSignature(bundle.NewSigningConfig(secret, "HS256", ""), DefaultBundleKeyID, false); err != nil {
+			t.Fatalf("Failed to sign bundle: %v", err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := bundle.NewWriter(&buf).DisableFormat(true).Write(b); err != nil {
+		t.Fatalf("Failed to write bundle: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadBundleVerifiesSignatures(t *testing.T) {
+	verification := &BundleVerification{Key: "secret", Algorithm: "HS256"}
+
+	read, err := ReadBundle(bytes.NewReader(buildBundle(t, "secret")), verification)
+	if err != nil {
+		t.Fatalf("Failed to read signed bundle: %v", err)
+	}
+	if read.Revision != "rev-1" || len(read.Policies) != 1 || read.Policies["orders_policy"] != bundlePolicy {
+		t.Errorf("Unexpected bundle content: %+v", read)
+	}
+
+	if _, err := ReadBundle(bytes.NewReader(buildBundle(t, "other")), verification); err == nil {
+		t.Error("Expected a bundle signed with another key to be rejected")
+	}
+	if _, err := ReadBundle(bytes.NewReader(buildBundle(t, "")), verification); err == nil {
+		t.Error("Expected an unsigned bundle to be rejected")
+	}
+	if _, err := ReadBundle(bytes.NewReader(buildBundle(t, "")), nil); err != nil {
+		t.Errorf("Expected unsigned bundles to load without a verification key: %v", err)
+	}
+}
+
+func TestBundleDataIsVisibleToPolicies(t *testing.T) {
+	read, err := ReadBundle(bytes.NewReader(buildBundle(t, "")), nil)
+	if err != nil {
+		t.Fatalf("Failed to read bundle: %v", err)
+	}
+
+	pm := NewPolicyManager()
+	pm.SetData(read.Data)
+	if err := pm.ReplacePolicies(read.Policies); err != nil {
+		t.Fatalf("Failed to load policies: %v", err)
+	}
+
+	for region, want := range map[string]bool{"eu": true, "apac": false} {
+		result, _ := pm.EvaluatePolicy("orders_policy", map[string]interface{}{"body": map[string]interface{}{"region": region}})
+		if result.Allowed != want {
+			t.Errorf("region %s: allowed = %v, want %v (%s)", region, result.Allowed, want, result.Error)
+		}
+	}
+}
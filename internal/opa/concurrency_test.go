@@ -0,0 +1,64 @@
+package opa
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentPolicyUpdates evaluates policies while they and their data
+// are replaced; run with -race
+func TestConcurrentPolicyUpdates(t *testing.T) {
+	pm := NewPolicyManager()
+	if err := pm.ReplacePolicies(map[string]string{"test_policy": testPolicy}); err != nil {
+		t.Fatalf("Failed to load policies: %v", err)
+	}
+	input := map[string]interface{}{"method": "POST", "body": map[string]interface{}{"priority": "low"}}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				result, err := pm.EvaluatePolicies([]string{"test_policy"}, input)
+				if err != nil || !result.Allowed {
+					t.Errorf("Expected a consistent policy set to allow, got %+v (err=%v)", result, err)
+					return
+				}
+				pm.ListLoadedPolicies()
+				pm.PolicySources()
+				pm.Data()
+			}
+		}()
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			name := fmt.Sprintf("extra_%d", i)
+			if err := pm.SetPolicy(name, "package "+name+"\n\ndefault allow = true\n"); err != nil {
+				t.Errorf("Failed to set policy: %v", err)
+			}
+			if _, err := pm.WithPolicy("candidate", "package candidate\n\ndefault allow = false\n"); err != nil {
+				t.Errorf("Failed to build candidate: %v", err)
+			}
+		}
+		if err := pm.ReplacePolicies(map[string]string{"test_policy": testPolicy}); err != nil {
+			t.Errorf("Failed to replace policies: %v", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			pm.SetData(map[string]interface{}{"revision": i})
+			pm.SetDocument("authz/revision", i)
+		}
+	}()
+	wg.Wait()
+
+	if policies := pm.ListLoadedPolicies(); len(policies) != 1 {
+		t.Errorf("Expected the replaced policy set to hold one policy, got %v", policies)
+	}
+}
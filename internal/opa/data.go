@@ -0,0 +1,193 @@
+package opa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/storage"
+	"sigs.k8s.io/yaml"
+)
+
+// documentSegment matches the segments of a data document path
+var documentSegment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// SetData replaces the base data document, such as the data.json files of a
+// bundle. Documents set with SetDocument are kept on top of it.
+func (pm *PolicyManager) SetData(data map[string]interface{}) {
+	pm.dataMu.Lock()
+	defer pm.dataMu.Unlock()
+
+	pm.baseData = data
+	if err := pm.writeData(); err != nil {
+		slog.Error("Failed to replace policy data", "error", err)
+	}
+}
+
+// SetDocument installs a data document at a slash separated path, so
+// "authz/roles" is available to policies as data.authz.roles. Compiled
+// policies see the document on their next evaluation.
+func (pm *PolicyManager) SetDocument(path string, value interface{}) error {
+	path, err := CleanDocumentPath(path)
+	if err != nil {
+		return err
+	}
+
+	pm.dataMu.Lock()
+	defer pm.dataMu.Unlock()
+
+	previous, existed := pm.documents[path]
+	pm.documents[path] = value
+	if err := pm.writeData(); err != nil {
+		if existed {
+			pm.documents[path] = previous
+		} else {
+			delete(pm.documents, path)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteDocument removes a document set with SetDocument and reports whether
+// it existed
+func (pm *PolicyManager) DeleteDocument(path string) bool {
+	path, err := CleanDocumentPath(path)
+	if err != nil {
+		return false
+	}
+
+	pm.dataMu.Lock()
+	defer pm.dataMu.Unlock()
+
+	if _, exists := pm.documents[path]; !exists {
+		return false
+	}
+	delete(pm.documents, path)
+	if err := pm.writeData(); err != nil {
+		slog.Error("Failed to replace policy data", "error", err)
+	}
+	return true
+}
+
+// Documents returns the documents set with SetDocument keyed by path
+func (pm *PolicyManager) Documents() map[string]interface{} {
+	pm.dataMu.Lock()
+	defer pm.dataMu.Unlock()
+
+	documents := make(map[string]interface{}, len(pm.documents))
+	for path, value := range pm.documents {
+		documents[path] = value
+	}
+	return documents
+}
+
+// Data returns the complete data document policies are evaluated against
+func (pm *PolicyManager) Data() map[string]interface{} {
+	pm.dataMu.Lock()
+	defer pm.dataMu.Unlock()
+	return pm.mergedData()
+}
+
+// LoadDataFiles sets a document for every JSON or YAML file below dir, at
+// the file's path without extension: dir/authz/roles.yaml becomes
+// data.authz.roles
+func (pm *PolicyManager) LoadDataFiles(dir string) error {
+	return filepath.WalkDir(dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := filepath.Ext(name)
+		if entry.IsDir() || (ext != ".json" && ext != ".yaml" && ext != ".yml") {
+			return nil
+		}
+
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("failed to read data file %s: %w", name, err)
+		}
+		if ext != ".json" {
+			if content, err = yaml.YAMLToJSON(content); err != nil {
+				return fmt.Errorf("failed to parse data file %s: %w", name, err)
+			}
+		}
+		var value interface{}
+		if err := json.Unmarshal(content, &value); err != nil {
+			return fmt.Errorf("failed to parse data file %s: %w", name, err)
+		}
+
+		relative, _ := filepath.Rel(dir, name)
+		path := filepath.ToSlash(strings.TrimSuffix(relative, ext))
+		if err := pm.SetDocument(path, value); err != nil {
+			return fmt.Errorf("failed to load data file %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// CleanDocumentPath validates a slash separated document path and strips
+// leading and trailing slashes
+func CleanDocumentPath(path string) (string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", fmt.Errorf("document path must not be empty")
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if !documentSegment.MatchString(segment) {
+			return "", fmt.Errorf("invalid document path segment %q", segment)
+		}
+	}
+	return path, nil
+}
+
+// writeData replaces the store's root document with the base data overlaid
+// with the documents; callers must hold dataMu
+func (pm *PolicyManager) writeData() error {
+	return storage.WriteOne(context.Background(), pm.store, storage.ReplaceOp, storage.Path{}, pm.mergedData())
+}
+
+// mergedData overlays the documents on a copy of the base data, shorter
+// paths first so nested documents extend their parents; callers must hold
+// dataMu
+func (pm *PolicyManager) mergedData() map[string]interface{} {
+	merged := copyObject(pm.baseData)
+
+	paths := make([]string, 0, len(pm.documents))
+	for path := range pm.documents {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		segments := strings.Split(path, "/")
+		parent := merged
+		for _, segment := range segments[:len(segments)-1] {
+			child, ok := parent[segment].(map[string]interface{})
+			if ok {
+				child = copyObject(child)
+			} else {
+				child = make(map[string]interface{})
+			}
+			parent[segment] = child
+			parent = child
+		}
+		parent[segments[len(segments)-1]] = pm.documents[path]
+	}
+	return merged
+}
+
+// copyObject returns a shallow copy of an object
+func copyObject(object map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		copied[key] = value
+	}
+	return copied
+}
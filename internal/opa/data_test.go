@@ -0,0 +1,62 @@
+package opa
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const allowlistPolicy = `package service_allowlist
+
+import future.keywords.if
+import future.keywords.in
+
+default allow = false
+
+allow if input.service in data.authz.services
+`
+
+func TestDocumentsApplyWithoutRecompiling(t *testing.T) {
+	pm := NewPolicyManager()
+	if err := pm.SetPolicy("service_allowlist", allowlistPolicy); err != nil {
+		t.Fatalf("Failed to compile policy: %v", err)
+	}
+	input := map[string]interface{}{"service": "billing"}
+
+	if result, _ := pm.EvaluatePolicy("service_allowlist", input); result.Allowed {
+		t.Fatal("Expected the request to be denied without an allowlist")
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "authz"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "authz", "services.yaml"), []byte("- billing\n- orders\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.LoadDataFiles(dir); err != nil {
+		t.Fatalf("Failed to load data files: %v", err)
+	}
+	if result, _ := pm.EvaluatePolicy("service_allowlist", input); !result.Allowed {
+		t.Errorf("Expected the loaded allowlist to allow billing: %s", result.Error)
+	}
+
+	if err := pm.SetDocument("authz/services", []interface{}{"orders"}); err != nil {
+		t.Fatalf("Failed to set document: %v", err)
+	}
+	if result, _ := pm.EvaluatePolicy("service_allowlist", input); result.Allowed {
+		t.Error("Expected the updated allowlist to deny billing")
+	}
+
+	pm.SetData(map[string]interface{}{"regions": []interface{}{"eu"}})
+	if data := pm.Data(); data["regions"] == nil || data["authz"] == nil {
+		t.Errorf("Expected documents to be kept on top of the base data, got %v", data)
+	}
+
+	if !pm.DeleteDocument("/authz/services/") || pm.DeleteDocument("authz/services") {
+		t.Error("Expected the document to be deleted exactly once")
+	}
+	if err := pm.SetDocument("authz/../secrets", true); err == nil {
+		t.Error("Expected an invalid document path to be rejected")
+	}
+}
@@ -0,0 +1,84 @@
+package opa
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// sunsetLayout is the date format of sunset annotations
+const sunsetLayout = "2006-01-02"
+
+// Deprecation describes a policy marked deprecated in the custom metadata of
+// its package annotation:
+//
+//	# METADATA
+//	# custom:
+//	#   deprecated: use traffic_policy_v2, which checks quotas
+//	#   replacement: traffic_policy_v2
+//	#   sunset: 2025-06-30
+//	package traffic_policy
+//
+// deprecated is true or a message; replacement and sunset are optional.
+type Deprecation struct {
+	Message     string     `json:"message,omitempty"`
+	Replacement string     `json:"replacement,omitempty"`
+	Sunset      *time.Time `json:"sunset,omitempty"`
+}
+
+// PastSunset reports whether the sunset date has passed at now
+func (d Deprecation) PastSunset(now time.Time) bool {
+	return d.Sunset != nil && !now.Before(*d.Sunset)
+}
+
+// ParseDeprecation reads the deprecation of a policy from its source, or
+// returns nil when the policy is not deprecated
+func ParseDeprecation(policyName, source string) (*Deprecation, error) {
+	module, err := ast.ParseModuleWithOpts(policyName+".rego", source, ast.ParserOptions{ProcessAnnotation: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", policyName, err)
+	}
+
+	for _, annotation := range module.Annotations {
+		if annotation.Scope != "package" {
+			continue
+		}
+		var deprecation Deprecation
+		switch marker := annotation.Custom["deprecated"].(type) {
+		case bool:
+			if !marker {
+				continue
+			}
+		case string:
+			deprecation.Message = marker
+		default:
+			continue
+		}
+
+		if replacement, ok := annotation.Custom["replacement"].(string); ok {
+			deprecation.Replacement = replacement
+		}
+		switch sunset := annotation.Custom["sunset"].(type) {
+		case nil:
+		case string:
+			date, err := parseSunset(sunset)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sunset of policy %s: %w", policyName, err)
+			}
+			deprecation.Sunset = &date
+		default:
+			return nil, fmt.Errorf("invalid sunset of policy %s: expected a date such as 2025-06-30", policyName)
+		}
+		return &deprecation, nil
+	}
+	return nil, nil
+}
+
+// parseSunset parses a sunset given as a date or an RFC 3339 timestamp
+func parseSunset(value string) (time.Time, error) {
+	if date, err := time.Parse(sunsetLayout, value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,46 @@
+package opa
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDeprecation(t *testing.T) {
+	source := `# METADATA
+# custom:
+#   deprecated: checks no quotas
+#   replacement: traffic_policy_v2
+#   sunset: 2025-06-30
+package traffic_policy
+
+allow := true
+`
+	deprecation, err := ParseDeprecation("traffic_policy", source)
+	if err != nil {
+		t.Fatalf("Failed to parse deprecation: %v", err)
+	}
+	if deprecation == nil || deprecation.Message != "checks no quotas" || deprecation.Replacement != "traffic_policy_v2" {
+		t.Fatalf("Unexpected deprecation %+v", deprecation)
+	}
+	sunset := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	if deprecation.Sunset == nil || !deprecation.Sunset.Equal(sunset) {
+		t.Fatalf("Expected sunset %s, got %v", sunset, deprecation.Sunset)
+	}
+	if deprecation.PastSunset(sunset.Add(-time.Hour)) || !deprecation.PastSunset(sunset) {
+		t.Error("Expected the sunset to pass on the sunset date")
+	}
+
+	for _, source := range []string{
+		"package plain\n\nallow := true\n",
+		"# METADATA\n# custom:\n#   deprecated: false\npackage plain\n\nallow := true\n",
+	} {
+		if deprecation, err := ParseDeprecation("plain", source); err != nil || deprecation != nil {
+			t.Errorf("Expected no deprecation, got %+v, %v", deprecation, err)
+		}
+	}
+
+	invalid := "# METADATA\n# custom:\n#   deprecated: true\n#   sunset: soon\npackage old\n\nallow := true\n"
+	if _, err := ParseDeprecation("old", invalid); err == nil {
+		t.Error("Expected an invalid sunset to be rejected")
+	}
+}
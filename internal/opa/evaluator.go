@@ -0,0 +1,214 @@
+package opa
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"dynamiccontrol/internal/types"
+)
+
+// PolicyEvaluator decides requests with named policies. The policy manager
+// evaluates embedded Rego; RemoteEvaluator delegates to an OPA server.
+type PolicyEvaluator interface {
+	// EvaluatePolicy evaluates a single policy's allow rule
+	EvaluatePolicy(policyName string, input map[string]interface{}) (*types.PolicyResult, error)
+	// EvaluatePolicies combines the results of several policies, by default
+	// allowing the input only when every policy allows it
+	EvaluatePolicies(policyNames []string, input map[string]interface{}) (*types.PolicyResult, error)
+	// EvaluateRule evaluates any rule of a policy and reports whether it is
+	// defined for the input
+	EvaluateRule(policyName, rule string, input map[string]interface{}) (interface{}, bool, error)
+}
+
+// Combination configures how EvaluatePolicies combines the results of a
+// policy set
+type Combination struct {
+	// Mode is types.PolicyCombineAll, where every policy must allow a
+	// request, or types.PolicyCombineAny, where one allowing policy is
+	// enough. Empty means all.
+	Mode string
+	// CollectReasons evaluates every policy concurrently and reports the
+	// reason of each one that did not allow the request, instead of stopping
+	// at the first decisive result
+	CollectReasons bool
+}
+
+// validate checks the combination mode
+func (c Combination) validate() error {
+	switch c.Mode {
+	case "", types.PolicyCombineAll, types.PolicyCombineAny:
+		return nil
+	default:
+		return fmt.Errorf("unknown policy combination mode %q", c.Mode)
+	}
+}
+
+// combinationSetting holds the combination of an evaluator. It is set at
+// startup and read by concurrent evaluations.
+type combinationSetting struct {
+	value atomic.Pointer[Combination]
+}
+
+// set validates and stores a combination
+func (s *combinationSetting) set(combination Combination) error {
+	if err := combination.validate(); err != nil {
+		return err
+	}
+	s.value.Store(&combination)
+	return nil
+}
+
+// get returns the stored combination, or the default one
+func (s *combinationSetting) get() Combination {
+	if combination := s.value.Load(); combination != nil {
+		return *combination
+	}
+	return Combination{}
+}
+
+// configuredCombination is implemented by evaluators with a configurable
+// combination
+type configuredCombination interface {
+	GetCombination() Combination
+}
+
+// Combine evaluates policies with the policy combination of a route instead
+// of the evaluator's. All and any combinations keep the evaluator's reason
+// collection setting.
+func Combine(evaluator PolicyEvaluator, policyNames []string, input map[string]interface{}, route types.PolicyCombination) *types.PolicyResult {
+	if route.Combine == types.PolicyCombinePriority {
+		return prioritizePolicies(evaluator.EvaluatePolicy, route.Priority, input)
+	}
+	var combination Combination
+	if configured, ok := evaluator.(configuredCombination); ok {
+		combination = configured.GetCombination()
+	}
+	combination.Mode = route.Combine
+	return combinePolicies(evaluator.EvaluatePolicy, policyNames, input, combination)
+}
+
+// prioritizePolicies applies the first priority rule whose policy decides
+// the input. Evaluation errors deny, and so does a list in which no rule
+// applies.
+func prioritizePolicies(evaluate func(string, map[string]interface{}) (*types.PolicyResult, error), rules []types.PolicyPriority, input map[string]interface{}) *types.PolicyResult {
+	for _, rule := range rules {
+		result, err := evaluate(rule.Policy, input)
+		if err != nil {
+			return &types.PolicyResult{
+				Allowed: false,
+				Error:   fmt.Sprintf("Policy evaluation error: %v", err),
+			}
+		}
+
+		switch {
+		case rule.Effect == types.PolicyEffectAllow && result.Allowed:
+			return &types.PolicyResult{Allowed: true}
+		case rule.Effect == types.PolicyEffectDeny && !result.Allowed:
+			return &types.PolicyResult{
+				Allowed: false,
+				Error:   fmt.Sprintf("Policy %s denied the request", rule.Policy),
+			}
+		}
+	}
+
+	return &types.PolicyResult{
+		Allowed: false,
+		Error:   "No priority rule decided the request",
+	}
+}
+
+// combinePolicies evaluates policies in order. In all mode it denies on the
+// first policy that does not allow the input, in any mode it allows on the
+// first policy that does. Collecting reasons evaluates every policy instead.
+func combinePolicies(evaluate func(string, map[string]interface{}) (*types.PolicyResult, error), policyNames []string, input map[string]interface{}, combination Combination) *types.PolicyResult {
+	if combination.CollectReasons {
+		return collectPolicies(evaluate, policyNames, input, combination.Mode)
+	}
+
+	anyMode := combination.Mode == types.PolicyCombineAny
+	for _, policyName := range policyNames {
+		result, err := evaluate(policyName, input)
+		if err != nil {
+			if anyMode {
+				continue
+			}
+			return &types.PolicyResult{
+				Allowed: false,
+				Error:   fmt.Sprintf("Policy evaluation error: %v", err),
+			}
+		}
+
+		if result.Allowed && anyMode {
+			return &types.PolicyResult{Allowed: true}
+		}
+		if !result.Allowed && !anyMode {
+			return &types.PolicyResult{
+				Allowed: false,
+				Error:   fmt.Sprintf("Policy %s denied the request", policyName),
+			}
+		}
+	}
+
+	if anyMode && len(policyNames) > 0 {
+		return &types.PolicyResult{
+			Allowed: false,
+			Error:   "No policy allowed the request",
+		}
+	}
+	return &types.PolicyResult{
+		Allowed: true,
+	}
+}
+
+// collectPolicies evaluates every policy concurrently and combines their
+// results, reporting the reason of each policy that did not allow the input
+// in policy order
+func collectPolicies(evaluate func(string, map[string]interface{}) (*types.PolicyResult, error), policyNames []string, input map[string]interface{}, mode string) *types.PolicyResult {
+	reasons := make([]string, len(policyNames))
+	var wg sync.WaitGroup
+	for i, policyName := range policyNames {
+		wg.Add(1)
+		go func(i int, policyName string) {
+			defer wg.Done()
+			reasons[i] = denialReason(policyName, evaluate, input)
+		}(i, policyName)
+	}
+	wg.Wait()
+
+	combined := &types.PolicyResult{}
+	allowing := 0
+	for _, reason := range reasons {
+		if reason == "" {
+			allowing++
+			continue
+		}
+		combined.Reasons = append(combined.Reasons, reason)
+	}
+	if mode == types.PolicyCombineAny {
+		combined.Allowed = allowing > 0 || len(policyNames) == 0
+	} else {
+		combined.Allowed = allowing == len(policyNames)
+	}
+	if !combined.Allowed {
+		combined.Error = strings.Join(combined.Reasons, "; ")
+	}
+	return combined
+}
+
+// denialReason evaluates a policy and describes why it did not allow the
+// input, or returns an empty reason when it did
+func denialReason(policyName string, evaluate func(string, map[string]interface{}) (*types.PolicyResult, error), input map[string]interface{}) string {
+	result, err := evaluate(policyName, input)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("Policy %s evaluation error: %v", policyName, err)
+	case result.Allowed:
+		return ""
+	case result.Error != "":
+		return fmt.Sprintf("Policy %s denied the request: %s", policyName, result.Error)
+	default:
+		return fmt.Sprintf("Policy %s denied the request", policyName)
+	}
+}
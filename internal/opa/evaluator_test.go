@@ -0,0 +1,156 @@
+package opa
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// fixedPolicies evaluates policies to fixed decisions, failing for unknown
+// names, and records the evaluated names
+type fixedPolicies struct {
+	mu        sync.Mutex
+	decisions map[string]bool
+	evaluated []string
+}
+
+func (f *fixedPolicies) evaluate(policyName string, _ map[string]interface{}) (*types.PolicyResult, error) {
+	f.mu.Lock()
+	f.evaluated = append(f.evaluated, policyName)
+	f.mu.Unlock()
+	allowed, ok := f.decisions[policyName]
+	if !ok {
+		return nil, fmt.Errorf("policy %s unavailable", policyName)
+	}
+	return &types.PolicyResult{Allowed: allowed}, nil
+}
+
+func TestCombinePoliciesModes(t *testing.T) {
+	decisions := map[string]bool{"allow_a": true, "allow_b": true, "deny_a": false, "deny_b": false}
+	cases := []struct {
+		name        string
+		combination Combination
+		policies    []string
+		allowed     bool
+		evaluated   int
+	}{
+		{"all stops at the first denial", Combination{}, []string{"allow_a", "deny_a", "deny_b"}, false, 2},
+		{"all allows when every policy allows", Combination{Mode: types.PolicyCombineAll}, []string{"allow_a", "allow_b"}, true, 2},
+		{"any stops at the first allow", Combination{Mode: types.PolicyCombineAny}, []string{"deny_a", "allow_a", "allow_b"}, true, 2},
+		{"any skips failing policies", Combination{Mode: types.PolicyCombineAny}, []string{"missing", "allow_a"}, true, 2},
+		{"any denies when no policy allows", Combination{Mode: types.PolicyCombineAny}, []string{"deny_a", "deny_b"}, false, 2},
+		{"collected all evaluates every policy", Combination{CollectReasons: true}, []string{"deny_a", "allow_a", "deny_b"}, false, 3},
+		{"collected any evaluates every policy", Combination{Mode: types.PolicyCombineAny, CollectReasons: true}, []string{"deny_a", "allow_a", "deny_b"}, true, 3},
+		{"no policies allow", Combination{Mode: types.PolicyCombineAny}, nil, true, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			policies := &fixedPolicies{decisions: decisions}
+			result := combinePolicies(policies.evaluate, tc.policies, nil, tc.combination)
+			if result.Allowed != tc.allowed {
+				t.Errorf("Expected allowed %v, got %v: %s", tc.allowed, result.Allowed, result.Error)
+			}
+			if len(policies.evaluated) != tc.evaluated {
+				t.Errorf("Expected %d evaluations, got %v", tc.evaluated, policies.evaluated)
+			}
+		})
+	}
+}
+
+func TestCollectedReasonsCoverEveryDenial(t *testing.T) {
+	policies := &fixedPolicies{decisions: map[string]bool{"allow_a": true, "deny_a": false}}
+	result := combinePolicies(policies.evaluate, []string{"deny_a", "allow_a", "missing"}, nil, Combination{CollectReasons: true})
+	expected := []string{
+		"Policy deny_a denied the request",
+		"Policy missing evaluation error: policy missing unavailable",
+	}
+	if len(result.Reasons) != len(expected) {
+		t.Fatalf("Expected reasons %v, got %v", expected, result.Reasons)
+	}
+	for i, reason := range expected {
+		if result.Reasons[i] != reason {
+			t.Errorf("Expected reason %q, got %q", reason, result.Reasons[i])
+		}
+	}
+	if result.Error != expected[0]+"; "+expected[1] {
+		t.Errorf("Expected the error to join the reasons, got %q", result.Error)
+	}
+}
+
+func TestCollectedPoliciesEvaluateConcurrently(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	slow := func(string, map[string]interface{}) (*types.PolicyResult, error) {
+		time.Sleep(delay)
+		return &types.PolicyResult{Allowed: true}, nil
+	}
+	start := time.Now()
+	result := combinePolicies(slow, []string{"a", "b", "c", "d"}, nil, Combination{CollectReasons: true})
+	if !result.Allowed {
+		t.Fatalf("Expected allowing policies to allow, got %s", result.Error)
+	}
+	if elapsed := time.Since(start); elapsed >= 3*delay {
+		t.Errorf("Expected policies to be evaluated concurrently, took %v", elapsed)
+	}
+}
+
+func TestSetCombinationRejectsUnknownModes(t *testing.T) {
+	pm := NewPolicyManager()
+	if err := pm.SetCombination(Combination{Mode: "majority"}); err == nil {
+		t.Error("Expected an unknown combination mode to be rejected")
+	}
+	pm.SetPolicy("deny_policy", "package deny_policy\n\ndefault allow = false\n")
+	pm.SetPolicy("allow_policy", "package allow_policy\n\ndefault allow = true\n")
+	if err := pm.SetCombination(Combination{Mode: types.PolicyCombineAny, CollectReasons: true}); err != nil {
+		t.Fatalf("Failed to set combination: %v", err)
+	}
+	result, _ := pm.EvaluatePolicies([]string{"deny_policy", "allow_policy"}, map[string]interface{}{})
+	if !result.Allowed || len(result.Reasons) != 1 {
+		t.Errorf("Expected any mode to allow with one reason, got %+v", result)
+	}
+}
+
+func TestCombineWithRoutePolicyModes(t *testing.T) {
+	pm := NewPolicyManager()
+	pm.SetPolicy("blocklist", "package blocklist\n\ndefault allow = true\n\nallow = false { input.client == \"blocked\" }\n")
+	pm.SetPolicy("admin", "package admin\n\ndefault allow = false\n\nallow { input.role == \"admin\" }\n")
+	pm.SetPolicy("business_hours", "package business_hours\n\ndefault allow = false\n\nallow { input.hour >= 9 }\n")
+
+	priority := types.PolicyCombination{Combine: types.PolicyCombinePriority, Priority: []types.PolicyPriority{
+		{Policy: "blocklist", Effect: types.PolicyEffectDeny},
+		{Policy: "admin", Effect: types.PolicyEffectAllow},
+		{Policy: "business_hours", Effect: types.PolicyEffectDeny},
+	}}
+	names := []string{"blocklist", "admin", "business_hours"}
+	cases := []struct {
+		name    string
+		input   map[string]interface{}
+		allowed bool
+	}{
+		{"deny precedes a later allow", map[string]interface{}{"client": "blocked", "role": "admin", "hour": 10}, false},
+		{"allow precedes a later deny", map[string]interface{}{"role": "admin", "hour": 3}, true},
+		{"later deny applies", map[string]interface{}{"role": "viewer", "hour": 3}, false},
+		{"no rule decides", map[string]interface{}{"role": "viewer", "hour": 10}, false},
+	}
+	for _, tc := range cases {
+		if result := Combine(pm, names, tc.input, priority); result.Allowed != tc.allowed {
+			t.Errorf("%s: expected allowed %v, got %v: %s", tc.name, tc.allowed, result.Allowed, result.Error)
+		}
+	}
+
+	input := map[string]interface{}{"role": "admin", "hour": 3}
+	if result, _ := pm.EvaluatePolicies(names, input); result.Allowed {
+		t.Error("Expected the default combination to require every policy")
+	}
+	if result := Combine(pm, names, input, types.PolicyCombination{Combine: types.PolicyCombineAny}); !result.Allowed {
+		t.Errorf("Expected any mode to allow, got %s", result.Error)
+	}
+
+	pm.SetCombination(Combination{CollectReasons: true})
+	result := Combine(pm, names, input, types.PolicyCombination{Combine: types.PolicyCombineAll})
+	if result.Allowed || len(result.Reasons) != 1 {
+		t.Errorf("Expected all mode to keep collecting reasons, got %+v", result)
+	}
+}
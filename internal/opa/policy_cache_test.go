@@ -0,0 +1,72 @@
+package opa
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dynamiccontrol/internal/cache"
+)
+
+const testPolicy = `package test_policy
+
+import future.keywords.if
+import future.keywords.in
+
+default allow = false
+
+allow if {
+    input.method == "POST"
+    input.body.priority in ["low", "medium"]
+}
+`
+
+func TestPolicyCacheRoundTrip(t *testing.T) {
+	policiesDir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(policiesDir, "test_policy.rego"), []byte(testPolicy), 0o644); err != nil {
+		t.Fatalf("failed to write policy: %v", err)
+	}
+
+	cacheDir := t.TempDir()
+	diskCache, err := cache.NewDiskCache(cacheDir)
+	if err != nil {
+		t.Fatalf("NewDiskCache() error = %v", err)
+	}
+
+	cold := NewPolicyManager()
+	cold.SetCache(diskCache)
+	if err := cold.LoadPolicies(policiesDir); err != nil {
+		t.Fatalf("LoadPolicies() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(cacheDir, policyCacheKind))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one cached policy, got %v (err=%v)", entries, err)
+	}
+
+	warm := NewPolicyManager()
+	warm.SetCache(diskCache)
+	if err := warm.LoadPolicies(policiesDir); err != nil {
+		t.Fatalf("LoadPolicies() error = %v", err)
+	}
+
+	inputs := []map[string]interface{}{
+		{"method": "POST", "body": map[string]interface{}{"priority": "low"}},
+		{"method": "POST", "body": map[string]interface{}{"priority": "critical"}},
+		{"method": "GET"},
+	}
+
+	for _, input := range inputs {
+		coldResult, _ := cold.EvaluatePolicy("test_policy", input)
+		warmResult, _ := warm.EvaluatePolicy("test_policy", input)
+		if coldResult.Allowed != warmResult.Allowed {
+			t.Errorf("cached policy decision differs for %v: %v != %v", input, coldResult.Allowed, warmResult.Allowed)
+		}
+	}
+
+	allowed, _ := warm.EvaluatePolicy("test_policy", inputs[0])
+	if !allowed.Allowed {
+		t.Error("cached policy should allow low priority requests")
+	}
+}
@@ -4,78 +4,220 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"path/filepath"
-	"strings"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 
+	"dynamiccontrol/internal/cache"
 	"dynamiccontrol/internal/types"
 
+	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/version"
 )
 
+// policyCacheKind is the disk cache namespace for parsed policy modules
+const policyCacheKind = "policies"
+
 // PolicyManager handles OPA policy loading and evaluation
 type PolicyManager struct {
-	policies map[string]*rego.PreparedEvalQuery
+	// policies is replaced as a whole on every change, so evaluations read a
+	// consistent set without locking; mu serializes the writers
+	mu       sync.Mutex
+	policies atomic.Pointer[policySet]
+	cache    *cache.DiskCache
+
+	// store holds the data document shared by every compiled policy, so data
+	// changes take effect without recompiling
+	store     storage.Store
+	dataMu    sync.Mutex
+	baseData  map[string]interface{}
+	documents map[string]interface{}
+
+	// builtins are the custom functions compiled into every policy
+	builtins []Builtin
+
+	combination combinationSetting
+}
+
+// policySet is an immutable snapshot of the loaded policies. Queries of
+// rules other than allow are prepared on first use and kept in rules.
+type policySet struct {
+	queries map[string]*rego.PreparedEvalQuery
+	sources map[string]string
+	rules   sync.Map
+}
+
+// with returns a copy of the set in which the given policy is added or replaced
+func (ps *policySet) with(policyName string, query *rego.PreparedEvalQuery, source string) *policySet {
+	next := &policySet{
+		queries: make(map[string]*rego.PreparedEvalQuery, len(ps.queries)+1),
+		sources: make(map[string]string, len(ps.sources)+1),
+	}
+	for name, existing := range ps.queries {
+		next.queries[name] = existing
+	}
+	for name, existing := range ps.sources {
+		next.sources[name] = existing
+	}
+	next.queries[policyName] = query
+	next.sources[policyName] = source
+	return next
 }
 
 // NewPolicyManager creates a new policy manager
 func NewPolicyManager() *PolicyManager {
-	return &PolicyManager{
-		policies: make(map[string]*rego.PreparedEvalQuery),
+	pm := &PolicyManager{
+		store:     inmem.New(),
+		documents: make(map[string]interface{}),
 	}
+	pm.policies.Store(&policySet{
+		queries: make(map[string]*rego.PreparedEvalQuery),
+		sources: make(map[string]string),
+	})
+	return pm
 }
 
-// LoadPolicies loads all Rego policies from the policies directory
+// SetCache enables persisting parsed policy modules to a disk cache so
+// restarts skip parsing policies whose content has not changed
+func (pm *PolicyManager) SetCache(diskCache *cache.DiskCache) {
+	pm.cache = diskCache
+}
+
+// LoadPolicies loads the policies directory as an OPA bundle: every Rego
+// module is loaded under its package path and data.json files become the
+// data document policies are evaluated against
 func (pm *PolicyManager) LoadPolicies(policiesDir string) error {
-	files, err := ioutil.ReadDir(policiesDir)
+	bundle, err := ReadBundleDir(policiesDir, nil)
 	if err != nil {
 		return fmt.Errorf("failed to read policies directory: %w", err)
 	}
+	pm.SetData(bundle.Data)
 
-	for _, file := range files {
-		if file.IsDir() || !strings.HasSuffix(file.Name(), ".rego") {
+	for policyName, source := range bundle.Policies {
+		if err := pm.SetPolicy(policyName, source); err != nil {
+			slog.Error("Failed to load policy", "policy", policyName, "error", err)
 			continue
 		}
 
-		policyName := strings.TrimSuffix(file.Name(), ".rego")
-		policyPath := filepath.Join(policiesDir, file.Name())
+		slog.Info("Loaded policy", "policy", policyName)
+	}
+
+	return nil
+}
+
+// compilePolicy parses and prepares a policy for evaluation
+func (pm *PolicyManager) compilePolicy(policyName string, policyBytes []byte) (*rego.PreparedEvalQuery, error) {
+	module, err := pm.parseModule(policyName, policyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	query := rego.New(pm.regoOptions("data."+policyName+".allow", module)...)
 
-		if err := pm.loadPolicy(policyName, policyPath); err != nil {
-			log.Printf("Failed to load policy %s: %v", policyName, err)
+	preparedQuery, err := query.PrepareForEval(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare policy %s: %w", policyName, err)
+	}
+	return &preparedQuery, nil
+}
+
+// ReplacePolicies compiles the given policy sources and atomically replaces
+// the loaded policy set. Policies that fail to compile are skipped.
+func (pm *PolicyManager) ReplacePolicies(sources map[string]string) error {
+	policies := make(map[string]*rego.PreparedEvalQuery, len(sources))
+	loaded := make(map[string]string, len(sources))
+	for policyName, source := range sources {
+		preparedQuery, err := pm.compilePolicy(policyName, []byte(source))
+		if err != nil {
+			slog.Error("Failed to load policy", "policy", policyName, "error", err)
 			continue
 		}
+		policies[policyName] = preparedQuery
+		loaded[policyName] = source
+	}
+
+	pm.mu.Lock()
+	pm.policies.Store(&policySet{queries: policies, sources: loaded})
+	pm.mu.Unlock()
+
+	slog.Info("Replaced policy set", "policies", len(policies))
+	return nil
+}
 
-		log.Printf("Loaded policy: %s", policyName)
+// SetPolicy compiles a policy and installs it, replacing any policy with the
+// same name. Unlike ReplacePolicies, a policy that fails to compile is an error.
+func (pm *PolicyManager) SetPolicy(policyName, source string) error {
+	preparedQuery, err := pm.compilePolicy(policyName, []byte(source))
+	if err != nil {
+		return err
 	}
 
+	pm.mu.Lock()
+	pm.policies.Store(pm.policies.Load().with(policyName, preparedQuery, source))
+	pm.mu.Unlock()
 	return nil
 }
 
-// loadPolicy loads a single Rego policy file
-func (pm *PolicyManager) loadPolicy(policyName, policyPath string) error {
-	policyBytes, err := ioutil.ReadFile(policyPath)
+// WithPolicy returns a copy of the manager in which the given policy replaces
+// or adds to the loaded set, leaving the manager itself unchanged. It is used
+// to evaluate candidate policies before they go live.
+func (pm *PolicyManager) WithPolicy(policyName, source string) (*PolicyManager, error) {
+	preparedQuery, err := pm.compilePolicy(policyName, []byte(source))
 	if err != nil {
-		return fmt.Errorf("failed to read policy file %s: %w", policyPath, err)
+		return nil, err
 	}
 
-	query := rego.New(
-		rego.Query("data."+policyName+".allow"),
-		rego.Module(policyName+".rego", string(policyBytes)),
-	)
+	pm.mu.Lock()
+	builtins := pm.builtins
+	pm.mu.Unlock()
 
-	preparedQuery, err := query.PrepareForEval(context.Background())
+	candidate := &PolicyManager{
+		cache:    pm.cache,
+		store:    pm.store,
+		builtins: builtins,
+	}
+	candidate.combination.set(pm.combination.get())
+	candidate.policies.Store(pm.policies.Load().with(policyName, preparedQuery, source))
+	return candidate, nil
+}
+
+// parseModule parses a policy module, reusing the disk cache when the policy content is unchanged
+func (pm *PolicyManager) parseModule(policyName string, policyBytes []byte) (*ast.Module, error) {
+	filename := policyName + ".rego"
+	hash := cache.ContentHash([]byte(version.Version), []byte(filename), policyBytes)
+
+	if pm.cache != nil {
+		if data, ok := pm.cache.Get(policyCacheKind, hash); ok {
+			var module ast.Module
+			if err := json.Unmarshal(data, &module); err == nil {
+				return &module, nil
+			}
+			slog.Warn("Ignoring unreadable cache entry", "policy", policyName)
+		}
+	}
+
+	module, err := ast.ParseModule(filename, string(policyBytes))
 	if err != nil {
-		return fmt.Errorf("failed to prepare policy %s: %w", policyName, err)
+		return nil, fmt.Errorf("failed to parse policy %s: %w", policyName, err)
 	}
 
-	pm.policies[policyName] = &preparedQuery
-	return nil
+	if pm.cache != nil {
+		if data, err := json.Marshal(module); err == nil {
+			if err := pm.cache.Put(policyCacheKind, hash, data); err != nil {
+				slog.Warn("Failed to cache policy", "policy", policyName, "error", err)
+			}
+		}
+	}
+
+	return module, nil
 }
 
 // EvaluatePolicy evaluates a policy with the given input
 func (pm *PolicyManager) EvaluatePolicy(policyName string, input map[string]interface{}) (*types.PolicyResult, error) {
-	preparedQuery, exists := pm.policies[policyName]
+	preparedQuery, exists := pm.policies.Load().queries[policyName]
 	if !exists {
 		return &types.PolicyResult{
 			Allowed: false,
@@ -112,34 +254,19 @@ func (pm *PolicyManager) EvaluatePolicy(policyName string, input map[string]inte
 	}, nil
 }
 
-// EvaluatePolicies evaluates multiple policies and returns combined result
-func (pm *PolicyManager) EvaluatePolicies(policyNames []string, input map[string]interface{}) (*types.PolicyResult, error) {
-	if len(policyNames) == 0 {
-		return &types.PolicyResult{
-			Allowed: true,
-		}, nil
-	}
-
-	for _, policyName := range policyNames {
-		result, err := pm.EvaluatePolicy(policyName, input)
-		if err != nil {
-			return &types.PolicyResult{
-				Allowed: false,
-				Error:   fmt.Sprintf("Policy evaluation error: %v", err),
-			}, nil
-		}
+// SetCombination configures how EvaluatePolicies combines policy results
+func (pm *PolicyManager) SetCombination(combination Combination) error {
+	return pm.combination.set(combination)
+}
 
-		if !result.Allowed {
-			return &types.PolicyResult{
-				Allowed: false,
-				Error:   fmt.Sprintf("Policy %s denied the request", policyName),
-			}, nil
-		}
-	}
+// GetCombination returns how EvaluatePolicies combines policy results
+func (pm *PolicyManager) GetCombination() Combination {
+	return pm.combination.get()
+}
 
-	return &types.PolicyResult{
-		Allowed: true,
-	}, nil
+// EvaluatePolicies evaluates multiple policies and returns combined result
+func (pm *PolicyManager) EvaluatePolicies(policyNames []string, input map[string]interface{}) (*types.PolicyResult, error) {
+	return combinePolicies(pm.EvaluatePolicy, policyNames, input, pm.combination.get()), nil
 }
 
 // CreatePolicyInput creates the input for policy evaluation
@@ -163,11 +290,55 @@ func CreatePolicyInput(method, path string, headers map[string]string, body inte
 	return input
 }
 
+// EvaluateRule evaluates any rule of a loaded policy, such as a computed
+// cache TTL, and reports whether the rule is defined for the input
+func (pm *PolicyManager) EvaluateRule(policyName, rule string, input map[string]interface{}) (interface{}, bool, error) {
+	set := pm.policies.Load()
+	source, exists := set.sources[policyName]
+	if !exists {
+		return nil, false, fmt.Errorf("policy %s not found", policyName)
+	}
+
+	ref := "data." + policyName + "." + rule
+	cached, ok := set.rules.Load(ref)
+	if !ok {
+		module, err := pm.parseModule(policyName, []byte(source))
+		if err != nil {
+			return nil, false, err
+		}
+		query, err := rego.New(pm.regoOptions(ref, module)...).PrepareForEval(context.Background())
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to prepare %s: %w", ref, err)
+		}
+		cached, _ = set.rules.LoadOrStore(ref, &query)
+	}
+
+	results, err := cached.(*rego.PreparedEvalQuery).Eval(context.Background(), rego.EvalInput(input))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to evaluate %s: %w", ref, err)
+	}
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		return nil, false, nil
+	}
+	return results[0].Expressions[0].Value, true, nil
+}
+
 // ListLoadedPolicies returns a list of loaded policy names
 func (pm *PolicyManager) ListLoadedPolicies() []string {
-	policies := make([]string, 0, len(pm.policies))
-	for policyName := range pm.policies {
+	set := pm.policies.Load()
+	policies := make([]string, 0, len(set.queries))
+	for policyName := range set.queries {
 		policies = append(policies, policyName)
 	}
 	return policies
 }
+
+// PolicySources returns the Rego source of every loaded policy keyed by name
+func (pm *PolicyManager) PolicySources() map[string]string {
+	set := pm.policies.Load()
+	sources := make(map[string]string, len(set.sources))
+	for policyName, source := range set.sources {
+		sources[policyName] = source
+	}
+	return sources
+}
@@ -0,0 +1,168 @@
+package opa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Remote evaluation defaults
+const (
+	DefaultRemoteTimeout = 500 * time.Millisecond
+	// remoteIdleConns bounds the pooled connections kept open to the OPA server
+	remoteIdleConns = 64
+)
+
+// RemoteEvaluator evaluates policies with the Data API of an external OPA
+// server, typically a sidecar, instead of embedded Rego. A policy named
+// traffic_policy is decided by POST /v1/data/traffic_policy/allow.
+type RemoteEvaluator struct {
+	address     string
+	token       string
+	client      *http.Client
+	combination combinationSetting
+}
+
+// NewRemoteEvaluator creates an evaluator calling the OPA server at address.
+// Each evaluation is bounded by timeout, and the token is sent as a bearer
+// token when the server requires authentication.
+func NewRemoteEvaluator(address, token string, timeout time.Duration) *RemoteEvaluator {
+	if timeout <= 0 {
+		timeout = DefaultRemoteTimeout
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = remoteIdleConns
+	transport.MaxIdleConnsPerHost = remoteIdleConns
+	return &RemoteEvaluator{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: timeout, Transport: transport},
+	}
+}
+
+// Address returns the address of the OPA server
+func (re *RemoteEvaluator) Address() string {
+	return re.address
+}
+
+// Health checks that the OPA server is up and has activated its bundles
+func (re *RemoteEvaluator) Health(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, re.address+"/health?bundles", nil)
+	if err != nil {
+		return fmt.Errorf("failed to build OPA request: %w", err)
+	}
+	resp, err := re.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OPA health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// EvaluatePolicy queries the allow rule of a policy package on the OPA server
+func (re *RemoteEvaluator) EvaluatePolicy(policyName string, input map[string]interface{}) (*types.PolicyResult, error) {
+	result, err := re.query(policyName, "allow", input)
+	if err != nil {
+		return &types.PolicyResult{
+			Allowed: false,
+			Error:   fmt.Sprintf("Policy evaluation error: %v", err),
+		}, nil
+	}
+
+	// An undefined result means the server holds no such policy or rule
+	if result == nil {
+		return &types.PolicyResult{
+			Allowed: false,
+			Error:   "No policy result found",
+		}, nil
+	}
+	var allowed bool
+	if err := json.Unmarshal(*result, &allowed); err != nil {
+		return &types.PolicyResult{
+			Allowed: false,
+			Error:   "Policy result is not a boolean",
+		}, nil
+	}
+
+	return &types.PolicyResult{
+		Allowed: allowed,
+	}, nil
+}
+
+// EvaluateRule queries any rule of a policy package on the OPA server
+func (re *RemoteEvaluator) EvaluateRule(policyName, rule string, input map[string]interface{}) (interface{}, bool, error) {
+	result, err := re.query(policyName, rule, input)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to evaluate %s.%s: %w", policyName, rule, err)
+	}
+	if result == nil {
+		return nil, false, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(*result, &value); err != nil {
+		return nil, false, fmt.Errorf("failed to decode %s.%s: %w", policyName, rule, err)
+	}
+	return value, true, nil
+}
+
+// query posts the input to the Data API document of a rule and returns its
+// result, which is nil when the rule is undefined
+func (re *RemoteEvaluator) query(policyName, rule string, input map[string]interface{}) (*json.RawMessage, error) {
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode policy input: %w", err)
+	}
+	path := "/v1/data/" + strings.ReplaceAll(policyName, ".", "/") + "/" + rule
+	req, err := http.NewRequest(http.MethodPost, re.address+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OPA request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if re.token != "" {
+		req.Header.Set("Authorization", "Bearer "+re.token)
+	}
+
+	resp, err := re.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Result  *json.RawMessage `json:"result"`
+		Message string           `json:"message"`
+	}
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&response)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OPA returned status %d %s", resp.StatusCode, response.Message)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("invalid OPA response: %v", decodeErr)
+	}
+	return response.Result, nil
+}
+
+// SetCombination configures how EvaluatePolicies combines policy results
+func (re *RemoteEvaluator) SetCombination(combination Combination) error {
+	return re.combination.set(combination)
+}
+
+// GetCombination returns how EvaluatePolicies combines policy results
+func (re *RemoteEvaluator) GetCombination() Combination {
+	return re.combination.get()
+}
+
+// EvaluatePolicies evaluates multiple policies and returns combined result
+func (re *RemoteEvaluator) EvaluatePolicies(policyNames []string, input map[string]interface{}) (*types.PolicyResult, error) {
+	return combinePolicies(re.EvaluatePolicy, policyNames, input, re.combination.get()), nil
+}
@@ -0,0 +1,53 @@
+package opa
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRemoteEvaluatorQueriesDataAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Input map[string]interface{} `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		switch r.URL.Path {
+		case "/v1/data/status_policy/allow":
+			json.NewEncoder(w).Encode(map[string]interface{}{"result": request.Input["method"] == "GET"})
+		case "/v1/data/cache/ttl_policy/ttl":
+			json.NewEncoder(w).Encode(map[string]interface{}{"result": 60})
+		case "/v1/data/slow_policy/allow":
+			time.Sleep(200 * time.Millisecond)
+			json.NewEncoder(w).Encode(map[string]interface{}{"result": true})
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	evaluator := NewRemoteEvaluator(server.URL, "", 50*time.Millisecond)
+
+	result, err := evaluator.EvaluatePolicies([]string{"status_policy"}, map[string]interface{}{"method": "GET"})
+	if err != nil || !result.Allowed {
+		t.Errorf("Expected GET to be allowed, got %+v (err=%v)", result, err)
+	}
+	result, _ = evaluator.EvaluatePolicies([]string{"status_policy"}, map[string]interface{}{"method": "DELETE"})
+	if result.Allowed || result.Error != "Policy status_policy denied the request" {
+		t.Errorf("Expected DELETE to be denied, got %+v", result)
+	}
+	if result, _ := evaluator.EvaluatePolicy("missing_policy", nil); result.Allowed || result.Error != "No policy result found" {
+		t.Errorf("Expected an undefined result to deny, got %+v", result)
+	}
+	if result, _ := evaluator.EvaluatePolicy("slow_policy", nil); result.Allowed || result.Error == "" {
+		t.Errorf("Expected a timed out evaluation to deny with an error, got %+v", result)
+	}
+	if value, defined, err := evaluator.EvaluateRule("cache.ttl_policy", "ttl", nil); err != nil || !defined || value != float64(60) {
+		t.Errorf("Expected ttl 60, got %v (defined=%v, err=%v)", value, defined, err)
+	}
+	if _, defined, err := evaluator.EvaluateRule("missing_policy", "ttl", nil); err != nil || defined {
+		t.Errorf("Expected an undefined rule, got defined=%v (err=%v)", defined, err)
+	}
+}
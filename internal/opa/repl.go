@@ -0,0 +1,127 @@
+package opa
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/types"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/topdown/print"
+)
+
+// DefaultREPLTimeout bounds the evaluation of a policy REPL query
+const DefaultREPLTimeout = 5 * time.Second
+
+// replUnsafeBuiltins are the builtins REPL queries may not call, since they
+// reach the network or expose the server's environment
+var replUnsafeBuiltins = map[string]struct{}{
+	ast.HTTPSend.Name:        {},
+	ast.NetLookupIPAddr.Name: {},
+	ast.OPARuntime.Name:      {},
+}
+
+// EvaluateQuery evaluates a policy REPL query against the given policies,
+// keyed by policy name, and data document. The query runs in a sandbox of its
+// own: the request's modules and data only apply to it, builtins reaching
+// the network are refused and evaluation is bounded by DefaultREPLTimeout.
+// Without a query, the packages of the request's modules are evaluated.
+// Policies calling custom built-ins need the registered built-ins passed.
+func EvaluateQuery(ctx context.Context, policies map[string]string, data map[string]interface{}, request types.PolicyREPLRequest, builtins ...Builtin) (*types.PolicyREPLResult, error) {
+	sources := make(map[string]string, len(policies)+len(request.Modules))
+	for name, source := range policies {
+		sources[name+".rego"] = source
+	}
+	var packages []string
+	for name, source := range request.Modules {
+		file := strings.TrimSuffix(name, ".rego") + ".rego"
+		module, err := ast.ParseModule(file, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		sources[file] = source
+		packages = append(packages, module.Package.Path.String())
+	}
+
+	query := strings.TrimSpace(request.Query)
+	if query == "" {
+		if len(packages) == 0 {
+			return nil, fmt.Errorf("a query or modules to evaluate are required")
+		}
+		sort.Strings(packages)
+		query = strings.Join(packages, "; ")
+	}
+
+	documents := make(map[string]interface{}, len(data)+len(request.Data))
+	for key, value := range data {
+		documents[key] = value
+	}
+	for key, value := range request.Data {
+		documents[key] = value
+	}
+
+	output := &printBuffer{}
+	options := []func(*rego.Rego){
+		rego.Query(query),
+		rego.Store(inmem.NewFromObject(documents)),
+		rego.UnsafeBuiltins(replUnsafeBuiltins),
+		rego.EnablePrintStatements(true),
+		rego.PrintHook(output),
+	}
+	options = append(options, builtinOptions(builtins)...)
+	files := make([]string, 0, len(sources))
+	for file := range sources {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		options = append(options, rego.Module(file, sources[file]))
+	}
+	if request.Input != nil {
+		options = append(options, rego.Input(request.Input))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultREPLTimeout)
+	defer cancel()
+	started := time.Now()
+	results, err := rego.New(options...).Eval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate query: %w", err)
+	}
+
+	result := &types.PolicyREPLResult{
+		Query:      query,
+		Defined:    len(results) > 0,
+		Results:    make([]types.PolicyREPLRow, 0, len(results)),
+		Output:     output.String(),
+		DurationMs: float64(time.Since(started).Microseconds()) / 1000,
+	}
+	for _, row := range results {
+		entry := types.PolicyREPLRow{Expressions: make([]interface{}, 0, len(row.Expressions))}
+		for _, expression := range row.Expressions {
+			entry.Expressions = append(entry.Expressions, expression.Value)
+		}
+		if len(row.Bindings) > 0 {
+			entry.Bindings = row.Bindings
+		}
+		result.Results = append(result.Results, entry)
+	}
+	return result, nil
+}
+
+// printBuffer collects the output of print calls
+type printBuffer struct {
+	strings.Builder
+}
+
+// Print records the output of a single print call
+func (pb *printBuffer) Print(_ print.Context, message string) error {
+	pb.WriteString(message)
+	pb.WriteByte('\n')
+	return nil
+}
@@ -0,0 +1,73 @@
+package opa
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestEvaluateQuery(t *testing.T) {
+	policies := map[string]string{
+		"orders": "package orders\n\ndefault allow = false\n\nallow { data.roles[input.user] == \"admin\" }\n",
+	}
+	data := map[string]interface{}{"roles": map[string]interface{}{"alice": "admin"}}
+
+	result, err := EvaluateQuery(context.Background(), policies, data, types.PolicyREPLRequest{
+		Query: "data.orders.allow",
+		Input: map[string]interface{}{"user": "alice"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to evaluate query: %v", err)
+	}
+	if !result.Defined || result.Results[0].Expressions[0] != true {
+		t.Errorf("Expected the loaded policy to allow alice, got %+v", result)
+	}
+
+	// Request data and modules apply to the query only
+	result, err = EvaluateQuery(context.Background(), policies, data, types.PolicyREPLRequest{
+		Query: "x := data.orders.allow; y := data.orders.reason",
+		Modules: map[string]string{
+			"orders.rego": "package orders\n\nallow { print(\"role\", data.roles[input.user]); data.roles[input.user] == \"editor\" }\n\nreason := \"editors only\"\n",
+		},
+		Input: map[string]interface{}{"user": "alice"},
+		Data:  map[string]interface{}{"roles": map[string]interface{}{"alice": "editor"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to evaluate query: %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].Bindings["x"] != true || result.Results[0].Bindings["y"] != "editors only" {
+		t.Errorf("Expected the snippet to replace the loaded policy, got %+v", result.Results)
+	}
+	if result.Output != "role editor\n" {
+		t.Errorf("Expected print output, got %q", result.Output)
+	}
+	if data["roles"].(map[string]interface{})["alice"] != "admin" {
+		t.Error("Expected the loaded data to be unchanged")
+	}
+
+	// Without a query the packages of the modules are evaluated
+	result, err = EvaluateQuery(context.Background(), nil, nil, types.PolicyREPLRequest{
+		Modules: map[string]string{"limits": "package limits\n\nmax_items := 10\n"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to evaluate modules: %v", err)
+	}
+	if result.Query != "data.limits" || result.Results[0].Expressions[0].(map[string]interface{})["max_items"] == nil {
+		t.Errorf("Expected the module's package to be evaluated, got %+v", result)
+	}
+
+	result, err = EvaluateQuery(context.Background(), nil, nil, types.PolicyREPLRequest{Query: "input.missing"})
+	if err != nil || result.Defined {
+		t.Errorf("Expected an undefined result, got %+v, %v", result, err)
+	}
+
+	_, err = EvaluateQuery(context.Background(), nil, nil, types.PolicyREPLRequest{Query: `http.send({"method": "GET", "url": "http://localhost"})`})
+	if err == nil || !strings.Contains(err.Error(), "http.send") {
+		t.Errorf("Expected http.send to be refused, got %v", err)
+	}
+	if _, err := EvaluateQuery(context.Background(), nil, nil, types.PolicyREPLRequest{}); err == nil {
+		t.Error("Expected an error without query or modules")
+	}
+}
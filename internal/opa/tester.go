@@ -0,0 +1,157 @@
+package opa
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/types"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/tester"
+)
+
+// DefaultTestTimeout bounds the evaluation of a single test rule
+const DefaultTestTimeout = 5 * time.Second
+
+// IsTestFile reports whether a file holds Rego tests: either the OPA
+// convention "*_test.rego" or the "*.rego.test" naming used in this repository
+func IsTestFile(name string) bool {
+	return strings.HasSuffix(name, "_test.rego") || strings.HasSuffix(name, ".rego.test")
+}
+
+// LoadTestDir reads the policies and tests of a directory, keyed by file name
+func LoadTestDir(dir string) (policies, tests map[string]string, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read policies directory: %w", err)
+	}
+
+	policies = make(map[string]string)
+	tests = make(map[string]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".rego") || IsTestFile(name)) {
+			continue
+		}
+		source, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if IsTestFile(name) {
+			tests[name] = string(source)
+		} else {
+			policies[name] = string(source)
+		}
+	}
+	return policies, tests, nil
+}
+
+// RunTests runs the test rules of the test modules against the policies with
+// the OPA version embedded in the server. Both maps are keyed by file name.
+// When the modules do not compile together, each test file is run on its own
+// and files that fail to compile are reported as errors. Policies calling
+// custom built-ins need the registered built-ins passed.
+func RunTests(ctx context.Context, policies, tests map[string]string, builtins ...Builtin) (*types.PolicyTestReport, error) {
+	policyModules := make(map[string]*ast.Module, len(policies))
+	for file, source := range policies {
+		module, err := ast.ParseModule(file, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		policyModules[file] = module
+	}
+
+	report := &types.PolicyTestReport{Results: []types.PolicyTestResult{}}
+	testModules := make(map[string]*ast.Module, len(tests))
+	for file, source := range tests {
+		module, err := ast.ParseModule(file, source)
+		if err != nil {
+			addCompileError(report, file, err)
+			continue
+		}
+		testModules[file] = module
+	}
+
+	if err := runModules(ctx, report, policyModules, testModules, builtins); err != nil {
+		for file, module := range testModules {
+			if err := runModules(ctx, report, policyModules, map[string]*ast.Module{file: module}, builtins); err != nil {
+				addCompileError(report, file, err)
+			}
+		}
+	}
+
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Row < b.Row
+	})
+	return report, nil
+}
+
+// runModules runs the tests of the given modules and adds the results to the
+// report; nothing is added when the modules fail to compile
+func runModules(ctx context.Context, report *types.PolicyTestReport, policies, tests map[string]*ast.Module, builtins []Builtin) error {
+	modules := make(map[string]*ast.Module, len(policies)+len(tests))
+	for file, module := range policies {
+		modules[file] = module
+	}
+	for file, module := range tests {
+		modules[file] = module
+	}
+
+	runner := tester.NewRunner().
+		CapturePrintOutput(true).
+		SetTimeout(DefaultTestTimeout).
+		AddCustomBuiltins(testerBuiltins(builtins))
+	results, err := runner.Run(ctx, modules)
+	if err != nil {
+		return err
+	}
+
+	for result := range results {
+		entry := types.PolicyTestResult{
+			Package:    strings.TrimPrefix(result.Package, "data."),
+			Name:       result.Name,
+			Output:     string(result.Output),
+			DurationMs: float64(result.Duration.Microseconds()) / 1000,
+		}
+		if result.Location != nil {
+			entry.File = result.Location.File
+			entry.Row = result.Location.Row
+		}
+		switch {
+		case result.Error != nil:
+			entry.Status = types.PolicyTestError
+			entry.Error = result.Error.Error()
+			report.Errors++
+		case result.Skip:
+			entry.Status = types.PolicyTestSkip
+			report.Skipped++
+		case result.Fail:
+			entry.Status = types.PolicyTestFail
+			report.Failed++
+		default:
+			entry.Status = types.PolicyTestPass
+			report.Passed++
+		}
+		report.Results = append(report.Results, entry)
+	}
+	return nil
+}
+
+// addCompileError reports a test file that failed to parse or compile
+func addCompileError(report *types.PolicyTestReport, file string, err error) {
+	report.Errors++
+	report.Results = append(report.Results, types.PolicyTestResult{
+		File:   file,
+		Status: types.PolicyTestError,
+		Error:  err.Error(),
+	})
+}
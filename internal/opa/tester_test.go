@@ -0,0 +1,72 @@
+package opa
+
+import (
+	"context"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+const testedPolicy = `package tested
+
+default allow = false
+
+allow {
+	input.method == "GET"
+}
+`
+
+func TestRunTests(t *testing.T) {
+	tests := map[string]string{
+		"tested_test.rego": `package tested
+
+test_allow_get {
+	allow with input as {"method": "GET"}
+}
+
+test_allow_post {
+	allow with input as {"method": "POST"}
+}
+`,
+		"broken_test.rego": `package tested
+
+test_undefined {
+	undefined_helper("x")
+}
+`,
+	}
+
+	report, err := RunTests(context.Background(), map[string]string{"tested.rego": testedPolicy}, tests)
+	if err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+	if report.Passed != 1 || report.Failed != 1 || report.Errors != 1 {
+		t.Fatalf("Expected 1 pass, 1 failure and 1 compile error, got %+v", report)
+	}
+
+	statuses := make(map[string]string)
+	for _, result := range report.Results {
+		statuses[result.File+":"+result.Name] = result.Status
+	}
+	if statuses["tested_test.rego:test_allow_get"] != types.PolicyTestPass {
+		t.Errorf("Expected test_allow_get to pass, got %v", statuses)
+	}
+	if statuses["tested_test.rego:test_allow_post"] != types.PolicyTestFail {
+		t.Errorf("Expected test_allow_post to fail, got %v", statuses)
+	}
+	if statuses["broken_test.rego:"] != types.PolicyTestError {
+		t.Errorf("Expected broken test file to be reported as an error, got %v", statuses)
+	}
+}
+
+func TestIsTestFile(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"status_policy_test.rego": true,
+		"status_policy.rego.test": true,
+		"status_policy.rego":      false,
+	} {
+		if IsTestFile(name) != expected {
+			t.Errorf("IsTestFile(%q) = %v, expected %v", name, !expected, expected)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package openapi
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+)
+
+// ExportVersion is the OpenAPI version of exported documents. OpenAPI 3.1
+// schemas are plain JSON Schema, so route schemas are embedded unchanged.
+const ExportVersion = "3.1.0"
+
+// Info describes the API in an exported document
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// errorSchema describes the JSON error responses written by the pipeline
+var errorSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []interface{}{"error"},
+	"properties": map[string]interface{}{
+		"error":   map[string]interface{}{"type": "string"},
+		"details": map[string]interface{}{},
+	},
+}
+
+// Export renders a route configuration as an OpenAPI document. Route patterns
+// become path templates, schemas become the JSON request body and success
+// response, and policies are listed in the x-policies extension so the
+// document can be imported again.
+func Export(config *types.RoutesConfig, info Info) map[string]interface{} {
+	paths := make(map[string]interface{})
+	for _, route := range config.Routes {
+		path, parameters := exportPath(route.RouteName)
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+
+		operation := map[string]interface{}{
+			"operationId":     OperationID(route),
+			"responses":       exportResponses(route),
+			PoliciesExtension: append([]string{}, route.Policies...),
+		}
+		parameters = append(parameters, schemaParameters(route.QuerySchema, "query")...)
+		parameters = append(parameters, schemaParameters(route.HeaderSchema, "header")...)
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if route.RequestSchema != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  routeContent(route, route.RequestSchema),
+			}
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": ExportVersion,
+		"info":    info,
+		"paths":   paths,
+	}
+}
+
+// exportPath converts a gin route pattern into an OpenAPI path template and
+// its path parameters
+func exportPath(pattern string) (string, []interface{}) {
+	var parameters []interface{}
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		name := segment[1:]
+		segments[i] = "{" + name + "}"
+		parameters = append(parameters, map[string]interface{}{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	return strings.Join(segments, "/"), parameters
+}
+
+// schemaParameters describes the properties of a query or header schema as
+// OpenAPI parameters
+func schemaParameters(schema map[string]interface{}, in string) []interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	if names, ok := schema["required"].([]interface{}); ok {
+		for _, name := range names {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parameters := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		parameters = append(parameters, map[string]interface{}{
+			"name":     name,
+			"in":       in,
+			"required": required[name],
+			"schema":   properties[name],
+		})
+	}
+	return parameters
+}
+
+// exportResponses describes the success response and the error responses a route can return
+func exportResponses(route types.RouteConfig) map[string]interface{} {
+	status := http.StatusOK
+	if route.MockResponse != nil && route.MockResponse.StatusCode != 0 {
+		status = route.MockResponse.StatusCode
+	}
+
+	success := map[string]interface{}{"description": "Successful response"}
+	if route.ResponseSchema != nil {
+		success["content"] = routeContent(route, route.ResponseSchema)
+		if route.Streaming {
+			success["content"] = map[string]interface{}{
+				"application/x-ndjson": map[string]interface{}{"schema": route.ResponseSchema},
+			}
+		}
+	}
+
+	responses := map[string]interface{}{
+		strconv.Itoa(status): success,
+	}
+	if route.RequestSchema != nil || route.QuerySchema != nil || route.HeaderSchema != nil {
+		responses["400"] = errorResponse("Invalid request")
+	}
+	if len(route.Policies) > 0 {
+		responses["403"] = errorResponse("Request denied by policy")
+	}
+	if len(route.ContentTypes) > 0 {
+		responses["406"] = errorResponse("Response content type not acceptable")
+		if route.RequestSchema != nil {
+			responses["415"] = errorResponse("Unsupported request content type")
+		}
+	}
+	// Traffic requests may also exceed the quota of their service
+	if route.RateLimit != nil || len(route.Throttles) > 0 || (route.Handler == types.HandlerTraffic && route.Method == "POST") {
+		responses["429"] = errorResponse("Rate limit exceeded")
+	}
+	if route.Handler == types.HandlerAggregate || route.Handler == types.HandlerProxy {
+		responses["502"] = errorResponse("Upstream failure")
+	}
+	return responses
+}
+
+// errorResponse describes a JSON error response
+func errorResponse(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content":     jsonContent(errorSchema),
+	}
+}
+
+// jsonContent wraps a schema as application/json content
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+// routeContent wraps a schema as content of each media type of a route,
+// or as application/json content for routes without contentTypes
+func routeContent(route types.RouteConfig, schema map[string]interface{}) map[string]interface{} {
+	if len(route.ContentTypes) == 0 {
+		return jsonContent(schema)
+	}
+	content := make(map[string]interface{}, len(route.ContentTypes))
+	for _, contentType := range route.ContentTypes {
+		content[contentType] = map[string]interface{}{"schema": schema}
+	}
+	return content
+}
+
+// OperationID derives a stable operation ID from the route method and path
+func OperationID(route types.RouteConfig) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.Method))
+	for _, segment := range strings.Split(route.RouteName, "/") {
+		segment = strings.TrimLeft(segment, ":*")
+		if segment == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestExportRoundTrip(t *testing.T) {
+	config := &types.RoutesConfig{Routes: []types.RouteConfig{
+		{
+			RouteName:      "/v1/services/:serviceId/traffic",
+			Method:         "POST",
+			RequestSchema:  map[string]interface{}{"type": "object", "required": []interface{}{"trafficType"}},
+			ResponseSchema: map[string]interface{}{"type": "object"},
+			Policies:       []string{"traffic_policy", "service_policy"},
+		},
+		{
+			RouteName: "/v1/status",
+			Method:    "GET",
+			Policies:  []string{},
+			QuerySchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"limit": map[string]interface{}{"type": "integer"}},
+			},
+			HeaderSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"x-tenant-id": map[string]interface{}{"type": "string"}},
+				"required":   []interface{}{"x-tenant-id"},
+			},
+		},
+	}}
+
+	document := Export(config, Info{Title: "Test", Version: "1.0.0"})
+	paths := document["paths"].(map[string]interface{})
+	operation := paths["/v1/services/{serviceId}/traffic"].(map[string]interface{})["post"].(map[string]interface{})
+	if operation["operationId"] != "postV1ServicesServiceIdTraffic" {
+		t.Errorf("Unexpected operation ID %v", operation["operationId"])
+	}
+	if len(operation["parameters"].([]interface{})) != 1 {
+		t.Errorf("Expected one path parameter, got %v", operation["parameters"])
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		t.Fatalf("Failed to encode document: %v", err)
+	}
+	imported, err := Import(data, Options{})
+	if err != nil {
+		t.Fatalf("Failed to import exported document: %v", err)
+	}
+
+	byKey := make(map[string]types.RouteConfig)
+	for _, route := range imported.Routes {
+		byKey[route.Method+" "+route.RouteName] = route
+	}
+	for _, route := range config.Routes {
+		got, exists := byKey[route.Method+" "+route.RouteName]
+		if !exists {
+			t.Errorf("Route %s %s missing after round trip", route.Method, route.RouteName)
+			continue
+		}
+		if !reflect.DeepEqual(got.RequestSchema, route.RequestSchema) || !reflect.DeepEqual(got.ResponseSchema, route.ResponseSchema) {
+			t.Errorf("Schemas of %s %s changed after round trip: %v %v", route.Method, route.RouteName, got.RequestSchema, got.ResponseSchema)
+		}
+		if !reflect.DeepEqual(got.QuerySchema, route.QuerySchema) || !reflect.DeepEqual(got.HeaderSchema, route.HeaderSchema) {
+			t.Errorf("Parameters of %s %s changed after round trip: %v %v", route.Method, route.RouteName, got.QuerySchema, got.HeaderSchema)
+		}
+		if !reflect.DeepEqual(got.Policies, route.Policies) {
+			t.Errorf("Policies of %s %s changed after round trip: %v", route.Method, route.RouteName, got.Policies)
+		}
+	}
+}
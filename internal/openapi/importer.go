@@ -0,0 +1,276 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+
+	"sigs.k8s.io/yaml"
+)
+
+// PoliciesExtension lists the policies of an operation in an OpenAPI document
+const PoliciesExtension = "x-policies"
+
+// maxRefDepth bounds $ref expansion so recursive schemas terminate
+const maxRefDepth = 16
+
+// Options controls how an OpenAPI document is turned into routes
+type Options struct {
+	// DefaultPolicies are applied to operations without an x-policies extension
+	DefaultPolicies []string
+}
+
+// supportedMethods are the OpenAPI operations that map to dynamic routes
+var supportedMethods = []string{"get", "post", "put", "delete"}
+
+// Import generates route configurations from an OpenAPI 3 document in JSON
+// or YAML. Path templates become gin path parameters, the application/json
+// request body and first successful response become the route schemas, and
+// component schema references are inlined.
+func Import(document []byte, options Options) (*types.RoutesConfig, error) {
+	jsonBytes, err := yaml.YAMLToJSON(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+
+	var spec map[string]interface{}
+	if err := json.Unmarshal(jsonBytes, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+
+	version, _ := spec["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q, expected 3.x", version)
+	}
+
+	paths, _ := spec["paths"].(map[string]interface{})
+	pathNames := make([]string, 0, len(paths))
+	for path := range paths {
+		pathNames = append(pathNames, path)
+	}
+	sort.Strings(pathNames)
+
+	resolver := &refResolver{spec: spec}
+	config := &types.RoutesConfig{}
+	for _, path := range pathNames {
+		item, _ := paths[path].(map[string]interface{})
+		for _, method := range supportedMethods {
+			operation, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			route := types.RouteConfig{
+				RouteName:      ConvertPath(path),
+				Method:         strings.ToUpper(method),
+				RequestSchema:  resolver.requestSchema(operation),
+				ResponseSchema: resolver.responseSchema(operation),
+				QuerySchema:    resolver.parameterSchema(operation, "query"),
+				HeaderSchema:   resolver.parameterSchema(operation, "header"),
+				Policies:       operationPolicies(operation, options.DefaultPolicies),
+			}
+			config.Routes = append(config.Routes, route)
+		}
+	}
+
+	for _, err := range resolver.errors {
+		slog.Warn("OpenAPI import", "error", err)
+	}
+	return config, nil
+}
+
+// ConvertPath turns an OpenAPI path template such as /pets/{petId} into a
+// gin route pattern such as /pets/:petId
+func ConvertPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + segment[1:len(segment)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// operationPolicies returns the x-policies of an operation or the defaults
+func operationPolicies(operation map[string]interface{}, defaults []string) []string {
+	raw, ok := operation[PoliciesExtension].([]interface{})
+	if !ok {
+		return append([]string{}, defaults...)
+	}
+
+	policies := []string{}
+	for _, policy := range raw {
+		if name, ok := policy.(string); ok {
+			policies = append(policies, name)
+		}
+	}
+	return policies
+}
+
+// refResolver inlines local $ref pointers of an OpenAPI document
+type refResolver struct {
+	spec   map[string]interface{}
+	errors []error
+}
+
+// requestSchema returns the application/json request body schema of an operation
+func (r *refResolver) requestSchema(operation map[string]interface{}) map[string]interface{} {
+	body, ok := r.resolve(operation["requestBody"], 0).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return r.jsonSchema(body)
+}
+
+// parameterSchema collects the query or header parameters of an operation
+// into an object schema. Header names are lowercased to match how requests
+// are validated.
+func (r *refResolver) parameterSchema(operation map[string]interface{}, in string) map[string]interface{} {
+	parameters, _ := operation["parameters"].([]interface{})
+	properties := make(map[string]interface{})
+	required := []interface{}{}
+	for _, raw := range parameters {
+		parameter, ok := r.resolve(raw, 0).(map[string]interface{})
+		if !ok || parameter["in"] != in {
+			continue
+		}
+		name, _ := parameter["name"].(string)
+		if in == "header" {
+			name = strings.ToLower(name)
+		}
+		if name == "" {
+			continue
+		}
+		schema, ok := parameter["schema"].(map[string]interface{})
+		if !ok {
+			schema = map[string]interface{}{"type": "string"}
+		}
+		properties[name] = schema
+		if isRequired, _ := parameter["required"].(bool); isRequired {
+			required = append(required, name)
+		}
+	}
+	if len(properties) == 0 {
+		return nil
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// responseSchema returns the application/json schema of the first 2xx response
+func (r *refResolver) responseSchema(operation map[string]interface{}) map[string]interface{} {
+	responses, _ := operation["responses"].(map[string]interface{})
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		response, ok := r.resolve(responses[code], 0).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if schema := r.jsonSchema(response); schema != nil {
+			return schema
+		}
+	}
+	return nil
+}
+
+// jsonSchema extracts and converts the application/json schema of a request body or response
+func (r *refResolver) jsonSchema(object map[string]interface{}) map[string]interface{} {
+	content, _ := object["content"].(map[string]interface{})
+	media, ok := content["application/json"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	schema, ok := r.resolve(media["schema"], 0).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return schema
+}
+
+// resolve deep-copies a document node, inlining $ref pointers and converting
+// OpenAPI-specific schema keywords to JSON Schema
+func (r *refResolver) resolve(node interface{}, depth int) interface{} {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := value["$ref"].(string); ok {
+			if depth >= maxRefDepth {
+				r.errors = append(r.errors, fmt.Errorf("$ref %s nested too deeply, leaving it unconstrained", ref))
+				return map[string]interface{}{}
+			}
+			target, err := r.lookup(ref)
+			if err != nil {
+				r.errors = append(r.errors, err)
+				return map[string]interface{}{}
+			}
+			return r.resolve(target, depth+1)
+		}
+
+		resolved := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			resolved[key] = r.resolve(child, depth)
+		}
+		convertNullable(resolved)
+		return resolved
+	case []interface{}:
+		resolved := make([]interface{}, len(value))
+		for i, child := range value {
+			resolved[i] = r.resolve(child, depth)
+		}
+		return resolved
+	default:
+		return value
+	}
+}
+
+// lookup follows a local JSON pointer such as #/components/schemas/Pet
+func (r *refResolver) lookup(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("external $ref %s is not supported", ref)
+	}
+
+	var current interface{} = r.spec
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("$ref %s not found", ref)
+		}
+		if current, ok = object[part]; !ok {
+			return nil, fmt.Errorf("$ref %s not found", ref)
+		}
+	}
+	return current, nil
+}
+
+// convertNullable rewrites the OpenAPI 3.0 nullable keyword as a JSON Schema type union
+func convertNullable(schema map[string]interface{}) {
+	nullable, ok := schema["nullable"].(bool)
+	if !ok {
+		return
+	}
+	delete(schema, "nullable")
+	if !nullable {
+		return
+	}
+	if schemaType, ok := schema["type"].(string); ok {
+		schema["type"] = []interface{}{schemaType, "null"}
+	}
+}
@@ -0,0 +1,108 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+const petstore = `
+openapi: 3.0.3
+info:
+  title: Petstore
+  version: 1.0.0
+paths:
+  /pets/{petId}:
+    get:
+      x-policies: [pet_policy]
+      responses:
+        "404":
+          description: Not found
+        "200":
+          description: A pet
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Pet"
+  /pets:
+    post:
+      requestBody:
+        $ref: "#/components/requestBodies/NewPet"
+      responses:
+        "201":
+          description: Created
+components:
+  requestBodies:
+    NewPet:
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Pet"
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+        tag:
+          type: string
+          nullable: true
+`
+
+func TestImportGeneratesRoutes(t *testing.T) {
+	config, err := Import([]byte(petstore), Options{DefaultPolicies: []string{"default_policy"}})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if len(config.Routes) != 2 {
+		t.Fatalf("Expected 2 routes, got %d", len(config.Routes))
+	}
+
+	create, get := config.Routes[0], config.Routes[1]
+	if create.RouteName != "/pets" || create.Method != "POST" {
+		t.Errorf("Unexpected first route %s %s", create.Method, create.RouteName)
+	}
+	if !reflect.DeepEqual(create.Policies, []string{"default_policy"}) {
+		t.Errorf("Expected default policies, got %v", create.Policies)
+	}
+	if create.ResponseSchema != nil {
+		t.Errorf("Expected no response schema for a response without content, got %v", create.ResponseSchema)
+	}
+
+	properties := create.RequestSchema["properties"].(map[string]interface{})
+	tag := properties["tag"].(map[string]interface{})
+	if !reflect.DeepEqual(tag["type"], []interface{}{"string", "null"}) {
+		t.Errorf("Expected nullable to become a type union, got %v", tag)
+	}
+
+	if get.RouteName != "/pets/:petId" || get.Method != "GET" {
+		t.Errorf("Unexpected second route %s %s", get.Method, get.RouteName)
+	}
+	if !reflect.DeepEqual(get.Policies, []string{"pet_policy"}) {
+		t.Errorf("Expected x-policies to be used, got %v", get.Policies)
+	}
+	if get.ResponseSchema["type"] != "object" {
+		t.Errorf("Expected the 200 response schema, got %v", get.ResponseSchema)
+	}
+}
+
+func TestImportRejectsSwagger2(t *testing.T) {
+	if _, err := Import([]byte(`{"swagger": "2.0", "paths": {}}`), Options{}); err == nil {
+		t.Error("Expected an error for a Swagger 2.0 document")
+	}
+}
+
+func TestImportRecursiveSchema(t *testing.T) {
+	spec := `{"openapi": "3.0.0", "paths": {"/nodes": {"post": {
+		"requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Node"}}}},
+		"responses": {}}}},
+		"components": {"schemas": {"Node": {"type": "object", "properties": {"child": {"$ref": "#/components/schemas/Node"}}}}}}`
+
+	config, err := Import([]byte(spec), Options{})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if config.Routes[0].RequestSchema["type"] != "object" {
+		t.Errorf("Expected the recursive schema to be inlined, got %v", config.Routes[0].RequestSchema)
+	}
+}
@@ -0,0 +1,179 @@
+package operations
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Store keeps track of long-running operations in memory
+type Store struct {
+	mu         sync.RWMutex
+	operations map[string]*types.Operation
+	sizes      map[string]int64
+	totalSize  int64
+	sequence   uint64
+}
+
+// NewStore creates a new operation store
+func NewStore() *Store {
+	return &Store{
+		operations: make(map[string]*types.Operation),
+		sizes:      make(map[string]int64),
+	}
+}
+
+// Create registers a new pending operation with the given step names
+func (s *Store) Create(route string, stepNames []string) types.Operation {
+	now := time.Now()
+	operation := &types.Operation{
+		ID:        fmt.Sprintf("op-%s-%d", now.Format("20060102150405"), atomic.AddUint64(&s.sequence, 1)),
+		Route:     route,
+		Status:    types.OperationPending,
+		Steps:     make([]types.OperationStep, len(stepNames)),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for i, name := range stepNames {
+		operation.Steps[i] = types.OperationStep{Name: name, Status: types.OperationPending}
+	}
+
+	s.mu.Lock()
+	s.operations[operation.ID] = operation
+	s.trackSize(operation)
+	s.mu.Unlock()
+
+	return copyOperation(operation)
+}
+
+// Get returns a snapshot of an operation
+func (s *Store) Get(id string) (types.Operation, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	operation, exists := s.operations[id]
+	if !exists {
+		return types.Operation{}, false
+	}
+	return copyOperation(operation), true
+}
+
+// SetStatus updates the overall status of an operation
+func (s *Store) SetStatus(id, status, errorMessage string) {
+	s.update(id, func(operation *types.Operation) {
+		operation.Status = status
+		operation.Error = errorMessage
+	})
+}
+
+// SetStepStatus updates the status of a single step of an operation
+func (s *Store) SetStepStatus(id, step, status, errorMessage string) {
+	s.update(id, func(operation *types.Operation) {
+		for i := range operation.Steps {
+			if operation.Steps[i].Name == step {
+				operation.Steps[i].Status = status
+				operation.Steps[i].Error = errorMessage
+				return
+			}
+		}
+	})
+}
+
+// SetResult stores the final result of an operation
+func (s *Store) SetResult(id string, result interface{}) {
+	s.update(id, func(operation *types.Operation) {
+		operation.Result = result
+	})
+}
+
+// update applies a mutation to an operation under lock
+func (s *Store) update(id string, mutate func(*types.Operation)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operation, exists := s.operations[id]
+	if !exists {
+		return
+	}
+	mutate(operation)
+	operation.UpdatedAt = time.Now()
+	s.trackSize(operation)
+}
+
+// trackSize records the approximate memory held by an operation; callers must hold the lock
+func (s *Store) trackSize(operation *types.Operation) {
+	size := int64(256)
+	if encoded, err := json.Marshal(operation); err == nil {
+		size += int64(len(encoded))
+	}
+	s.totalSize += size - s.sizes[operation.ID]
+	s.sizes[operation.ID] = size
+}
+
+// Pending returns the number of operations that have not finished yet
+func (s *Store) Pending() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pending := 0
+	for _, operation := range s.operations {
+		if operation.Status == types.OperationPending || operation.Status == types.OperationRunning {
+			pending++
+		}
+	}
+	return pending
+}
+
+// Name identifies the store to the memory manager
+func (s *Store) Name() string {
+	return "operations"
+}
+
+// SizeBytes returns the approximate memory held by stored operations
+func (s *Store) SizeBytes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.totalSize
+}
+
+// Shrink evicts finished operations, oldest first, until the store holds at
+// most target bytes. Pending and running operations are never evicted.
+func (s *Store) Shrink(target int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	finished := make([]*types.Operation, 0, len(s.operations))
+	for _, operation := range s.operations {
+		if operation.Status != types.OperationPending && operation.Status != types.OperationRunning {
+			finished = append(finished, operation)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].UpdatedAt.Before(finished[j].UpdatedAt)
+	})
+
+	var freed int64
+	for _, operation := range finished {
+		if s.totalSize <= target {
+			break
+		}
+		size := s.sizes[operation.ID]
+		delete(s.operations, operation.ID)
+		delete(s.sizes, operation.ID)
+		s.totalSize -= size
+		freed += size
+	}
+	return freed
+}
+
+// copyOperation returns a copy that is safe to hand out to readers
+func copyOperation(operation *types.Operation) types.Operation {
+	result := *operation
+	result.Steps = append([]types.OperationStep(nil), operation.Steps...)
+	return result
+}
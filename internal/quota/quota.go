@@ -0,0 +1,228 @@
+package quota
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultWindow is the quota window of quotas without windowSeconds
+const DefaultWindow = time.Hour
+
+// Reasons a request exceeds its quota
+const (
+	ReasonRequests = "quota-requests"
+	ReasonVolume   = "quota-volume"
+)
+
+// Quota bounds the traffic requests a service may make within a window: at
+// most Requests requests, with at most Volume summed volume. A zero bound is
+// unlimited.
+type Quota struct {
+	Service       string  `json:"service"`
+	Requests      int     `json:"requests,omitempty"`
+	Volume        float64 `json:"volume,omitempty"`
+	WindowSeconds int     `json:"windowSeconds,omitempty"`
+}
+
+// Window returns the length of the quota window
+func (q Quota) Window() time.Duration {
+	if q.WindowSeconds <= 0 {
+		return DefaultWindow
+	}
+	return time.Duration(q.WindowSeconds) * time.Second
+}
+
+// Validate checks the bounds of a quota
+func (q Quota) Validate() error {
+	if q.Service == "" {
+		return fmt.Errorf("quota requires a service")
+	}
+	if q.Requests < 0 || q.Volume < 0 || q.WindowSeconds < 0 {
+		return fmt.Errorf("quota bounds must not be negative")
+	}
+	if q.Requests == 0 && q.Volume == 0 {
+		return fmt.Errorf("quota requires a requests or volume bound")
+	}
+	return nil
+}
+
+// Usage is the consumption of a quota in its current window
+type Usage struct {
+	Quota
+	UsedRequests int     `json:"usedRequests"`
+	UsedVolume   float64 `json:"usedVolume"`
+	// ResetSeconds is the time until the current window ends
+	ResetSeconds int `json:"resetSeconds"`
+}
+
+// Result is the outcome of consuming a quota. Reason names the exceeded
+// bound of rejected requests.
+type Result struct {
+	Allowed bool
+	Reason  string
+	Usage
+}
+
+// RemainingRequests returns the requests left in the window, or -1 without
+// a requests bound
+func (r Result) RemainingRequests() int {
+	if r.Requests == 0 {
+		return -1
+	}
+	return max(r.Requests-r.UsedRequests, 0)
+}
+
+// RemainingVolume returns the volume left in the window, or -1 without a
+// volume bound
+func (r Result) RemainingVolume() float64 {
+	if r.Volume == 0 {
+		return -1
+	}
+	return math.Max(r.Volume-r.UsedVolume, 0)
+}
+
+// SetHeaders describes the quota of a service in the X-Quota headers of a
+// response
+func SetHeaders(header http.Header, result Result) {
+	if remaining := result.RemainingRequests(); remaining >= 0 {
+		header.Set("X-Quota-Limit", strconv.Itoa(result.Requests))
+		header.Set("X-Quota-Remaining", strconv.Itoa(remaining))
+	}
+	if remaining := result.RemainingVolume(); remaining >= 0 {
+		header.Set("X-Quota-Volume-Limit", strconv.FormatFloat(result.Volume, 'f', -1, 64))
+		header.Set("X-Quota-Volume-Remaining", strconv.FormatFloat(remaining, 'f', -1, 64))
+	}
+	header.Set("X-Quota-Reset", strconv.Itoa(result.ResetSeconds))
+}
+
+// counter is the consumption of a quota in the window started at start
+type counter struct {
+	start    time.Time
+	requests int
+	volume   float64
+}
+
+// Manager holds the quotas of services and their consumption in fixed
+// windows, restarting when a quota changes
+type Manager struct {
+	mu       sync.Mutex
+	quotas   map[string]Quota
+	counters map[string]*counter
+}
+
+// NewManager creates a manager without quotas
+func NewManager() *Manager {
+	return &Manager{quotas: make(map[string]Quota), counters: make(map[string]*counter)}
+}
+
+// Set sets the quota of a service
+func (m *Manager) Set(quota Quota) error {
+	if err := quota.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotas[quota.Service] = quota
+	delete(m.counters, quota.Service)
+	return nil
+}
+
+// Delete removes the quota of a service and reports whether it had one
+func (m *Manager) Delete(service string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, exists := m.quotas[service]
+	delete(m.quotas, service)
+	delete(m.counters, service)
+	return exists
+}
+
+// Get returns the quota of a service and its usage
+func (m *Manager) Get(service string) (Usage, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	quota, exists := m.quotas[service]
+	if !exists {
+		return Usage{}, false
+	}
+	return m.usage(quota, time.Now()), true
+}
+
+// List returns the quotas of every service and their usage, by service
+func (m *Manager) List() []Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	usages := make([]Usage, 0, len(m.quotas))
+	for _, quota := range m.quotas {
+		usages = append(usages, m.usage(quota, now))
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Service < usages[j].Service })
+	return usages
+}
+
+// Consume counts a request of the given volume against the quota of a
+// service, unless it would exceed the quota. Services without a quota are
+// always allowed, and reported as found false.
+func (m *Manager) Consume(service string, volume float64) (Result, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	quota, exists := m.quotas[service]
+	if !exists {
+		return Result{Allowed: true}, false
+	}
+	now := time.Now()
+	current := m.counter(quota, now)
+	result := Result{Allowed: true}
+	switch {
+	case quota.Requests > 0 && current.requests+1 > quota.Requests:
+		result = Result{Reason: ReasonRequests}
+	case quota.Volume > 0 && current.volume+volume > quota.Volume:
+		result = Result{Reason: ReasonVolume}
+	default:
+		current.requests++
+		current.volume += volume
+	}
+	result.Usage = m.usage(quota, now)
+	return result, true
+}
+
+// Refund returns a consumed request, such as one that failed afterwards,
+// to the quota of a service
+func (m *Manager) Refund(service string, volume float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if current, exists := m.counters[service]; exists && current.requests > 0 {
+		current.requests--
+		current.volume = math.Max(current.volume-volume, 0)
+	}
+}
+
+// counter returns the counter of the current window of a quota; callers
+// must hold the lock
+func (m *Manager) counter(quota Quota, now time.Time) *counter {
+	current, exists := m.counters[quota.Service]
+	if !exists || now.Sub(current.start) >= quota.Window() {
+		current = &counter{start: now}
+		m.counters[quota.Service] = current
+	}
+	return current
+}
+
+// usage describes the consumption of a quota at now; callers must hold the
+// lock
+func (m *Manager) usage(quota Quota, now time.Time) Usage {
+	current := m.counter(quota, now)
+	reset := current.start.Add(quota.Window()).Sub(now)
+	return Usage{
+		Quota:        quota,
+		UsedRequests: current.requests,
+		UsedVolume:   current.volume,
+		ResetSeconds: int(math.Ceil(reset.Seconds())),
+	}
+}
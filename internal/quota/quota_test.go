@@ -0,0 +1,60 @@
+package quota
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestConsumeRequestAndVolumeBounds(t *testing.T) {
+	manager := NewManager()
+	if result, limited := manager.Consume("billing", 1000); !result.Allowed || limited {
+		t.Errorf("Expected services without a quota to be allowed, got %+v", result)
+	}
+	if err := manager.Set(Quota{Service: "billing", Requests: 3, Volume: 100}); err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+
+	if result, _ := manager.Consume("billing", 60); !result.Allowed || result.RemainingRequests() != 2 || result.RemainingVolume() != 40 {
+		t.Errorf("Expected the request to be allowed, got %+v", result)
+	}
+	if result, _ := manager.Consume("billing", 50); result.Allowed || result.Reason != ReasonVolume || result.UsedVolume != 60 {
+		t.Errorf("Expected the volume bound to be exceeded, got %+v", result)
+	}
+	manager.Consume("billing", 10)
+	manager.Consume("billing", 10)
+	result, _ := manager.Consume("billing", 0)
+	if result.Allowed || result.Reason != ReasonRequests || result.RemainingRequests() != 0 {
+		t.Errorf("Expected the requests bound to be exceeded, got %+v", result)
+	}
+
+	header := http.Header{}
+	SetHeaders(header, result)
+	if header.Get("X-Quota-Remaining") != "0" || header.Get("X-Quota-Volume-Remaining") != "20" || header.Get("X-Quota-Reset") != "3600" {
+		t.Errorf("Unexpected headers %v", header)
+	}
+
+	manager.Refund("billing", 10)
+	if usage, _ := manager.Get("billing"); usage.UsedRequests != 2 || usage.UsedVolume != 70 {
+		t.Errorf("Expected the refund to return the request, got %+v", usage)
+	}
+	manager.Set(Quota{Service: "billing", Requests: 3})
+	if usage, _ := manager.Get("billing"); usage.UsedRequests != 0 {
+		t.Errorf("Expected a changed quota to restart its window, got %+v", usage)
+	}
+	if !manager.Delete("billing") || manager.Delete("billing") {
+		t.Error("Expected the quota to be deleted once")
+	}
+}
+
+func TestValidateQuota(t *testing.T) {
+	for _, quota := range []Quota{
+		{Requests: 1},
+		{Service: "billing"},
+		{Service: "billing", Requests: -1},
+		{Service: "billing", Volume: 1, WindowSeconds: -60},
+	} {
+		if err := quota.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", quota)
+		}
+	}
+}
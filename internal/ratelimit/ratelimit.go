@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Rate limit response headers, as defined by the IETF RateLimit header
+// fields draft (draft-ietf-httpapi-ratelimit-headers)
+const (
+	LimitHeader     = "RateLimit-Limit"
+	RemainingHeader = "RateLimit-Remaining"
+	ResetHeader     = "RateLimit-Reset"
+	PolicyHeader    = "RateLimit-Policy"
+)
+
+// Headers lists the rate limit response headers
+var Headers = []string{LimitHeader, RemainingHeader, ResetHeader, PolicyHeader}
+
+// sweepInterval is how often expired windows are dropped from memory
+const sweepInterval = time.Minute
+
+// Result is the outcome of counting a request against a limit
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the current window ends
+	Reset  time.Duration
+	Window time.Duration
+}
+
+// Limiter counts requests per key in fixed windows
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+// MemoryLimiter counts requests in memory, so limits apply per instance
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*window
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// window is the request count of a key in its current window
+type window struct {
+	count int
+	ends  time.Time
+}
+
+// NewMemoryLimiter creates an in-memory limiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		windows: make(map[string]*window),
+		now:     time.Now,
+	}
+}
+
+// Allow counts a request against the limit of key. Requests beyond the
+// limit are rejected until the window ends and are not counted.
+func (ml *MemoryLimiter) Allow(ctx context.Context, key string, limit int, length time.Duration) (Result, error) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	now := ml.now()
+	if now.Sub(ml.lastSweep) > sweepInterval {
+		for k, w := range ml.windows {
+			if !now.Before(w.ends) {
+				delete(ml.windows, k)
+			}
+		}
+		ml.lastSweep = now
+	}
+
+	w, exists := ml.windows[key]
+	if !exists || !now.Before(w.ends) {
+		w = &window{ends: now.Add(length)}
+		ml.windows[key] = w
+	}
+	if w.count < limit {
+		w.count++
+		return newResult(true, limit, limit-w.count, w.ends.Sub(now), length), nil
+	}
+	return newResult(false, limit, 0, w.ends.Sub(now), length), nil
+}
+
+// newResult creates a result
+func newResult(allowed bool, limit, remaining int, reset, length time.Duration) Result {
+	return Result{Allowed: allowed, Limit: limit, Remaining: remaining, Reset: reset, Window: length}
+}
+
+// SetHeaders sets the rate limit headers describing a result
+func SetHeaders(header http.Header, result Result) {
+	header.Set(LimitHeader, strconv.Itoa(result.Limit))
+	header.Set(RemainingHeader, strconv.Itoa(result.Remaining))
+	header.Set(ResetHeader, strconv.Itoa(seconds(result.Reset)))
+	header.Set(PolicyHeader, Policy(result.Limit, result.Window))
+}
+
+// Policy describes a limit as a quota policy such as "100;w=60"
+func Policy(limit int, window time.Duration) string {
+	return strconv.Itoa(limit) + ";w=" + strconv.Itoa(seconds(window))
+}
+
+// ResetSeconds returns the whole seconds until the window of a result resets
+func ResetSeconds(result Result) int {
+	return seconds(result.Reset)
+}
+
+// RetryAfter returns the Retry-After value of a rejected request
+func RetryAfter(result Result) string {
+	return strconv.Itoa(ResetSeconds(result))
+}
+
+// seconds rounds a duration up to whole seconds, so clients never retry early
+func seconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
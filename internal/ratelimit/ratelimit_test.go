@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/redis"
+	"dynamiccontrol/internal/redis/redistest"
+)
+
+func TestMemoryLimiterWindows(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		result, _ := limiter.Allow(ctx, "client", 3, time.Minute)
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("Expected an allowed request with %d remaining, got %+v", i, result)
+		}
+	}
+
+	now = now.Add(20 * time.Second)
+	result, _ := limiter.Allow(ctx, "client", 3, time.Minute)
+	if result.Allowed || result.Remaining != 0 || result.Reset != 40*time.Second {
+		t.Errorf("Expected a rejection resetting in 40s, got %+v", result)
+	}
+	if other, _ := limiter.Allow(ctx, "other", 3, time.Minute); !other.Allowed {
+		t.Error("Expected keys to be limited independently")
+	}
+
+	now = now.Add(40 * time.Second)
+	if result, _ := limiter.Allow(ctx, "client", 3, time.Minute); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("Expected a new window, got %+v", result)
+	}
+}
+
+func TestSetHeaders(t *testing.T) {
+	header := http.Header{}
+	result := Result{Limit: 100, Remaining: 7, Reset: 1500 * time.Millisecond, Window: time.Minute}
+	SetHeaders(header, result)
+
+	expected := map[string]string{
+		LimitHeader:     "100",
+		RemainingHeader: "7",
+		ResetHeader:     "2",
+		PolicyHeader:    "100;w=60",
+	}
+	for name, value := range expected {
+		if header.Get(name) != value {
+			t.Errorf("Expected %s %q, got %q", name, value, header.Get(name))
+		}
+	}
+	if RetryAfter(result) != "2" {
+		t.Errorf("Expected Retry-After 2, got %s", RetryAfter(result))
+	}
+}
+
+func TestRedisLimiterSharesCounters(t *testing.T) {
+	server := redistest.NewServer()
+	defer server.Close()
+	newLimiter := func() *RedisLimiter {
+		client, err := redis.NewClient(server.URL)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		return NewRedisLimiter(client, "")
+	}
+	// Two instances share the limit
+	first, second := newLimiter(), newLimiter()
+	ctx := context.Background()
+
+	if result, err := first.Allow(ctx, "client", 2, time.Minute); err != nil || !result.Allowed || result.Remaining != 1 {
+		t.Fatalf("Expected an allowed request, got %+v: %v", result, err)
+	}
+	if result, _ := second.Allow(ctx, "client", 2, time.Minute); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Expected the last allowed request, got %+v", result)
+	}
+	result, _ := first.Allow(ctx, "client", 2, time.Minute)
+	if result.Allowed || result.Reset <= 0 || result.Reset > time.Minute {
+		t.Errorf("Expected a rejection within the window, got %+v", result)
+	}
+
+	server.Advance(time.Minute)
+	if result, _ := second.Allow(ctx, "client", 2, time.Minute); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("Expected a new window, got %+v", result)
+	}
+}
+
+func TestRedisLimiterFailsWhenUnavailable(t *testing.T) {
+	client, _ := redis.NewClient("redis://127.0.0.1:1")
+	if _, err := NewRedisLimiter(client, "").Allow(context.Background(), "client", 1, time.Second); err == nil {
+		t.Error("Expected an error when Redis is unreachable")
+	}
+}
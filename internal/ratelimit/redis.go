@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"dynamiccontrol/internal/redis"
+)
+
+// DefaultRedisPrefix namespaces the counters of the Redis limiter
+const DefaultRedisPrefix = "dynamiccontrol:ratelimit:"
+
+// redisTimeout bounds counting a request, so a slow Redis fails open quickly
+const redisTimeout = 250 * time.Millisecond
+
+// RedisLimiter counts requests in Redis, so limits apply across every
+// instance sharing the server. Each key is a counter that expires with its
+// window.
+type RedisLimiter struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisLimiter creates a limiter keeping counters under prefix
+func NewRedisLimiter(client *redis.Client, prefix string) *RedisLimiter {
+	if prefix == "" {
+		prefix = DefaultRedisPrefix
+	}
+	return &RedisLimiter{client: client, prefix: prefix}
+}
+
+// Allow counts a request against the limit of key
+func (rl *RedisLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	key = rl.prefix + key
+
+	reply, err := rl.client.Do(ctx, "INCR", key)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to count request: %w", err)
+	}
+	count, _ := reply.(int64)
+	if count == 1 {
+		if _, err := rl.client.Do(ctx, "PEXPIRE", key, strconv.FormatInt(window.Milliseconds(), 10)); err != nil {
+			return Result{}, fmt.Errorf("failed to start rate limit window: %w", err)
+		}
+	}
+
+	reply, err = rl.client.Do(ctx, "PTTL", key)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to read rate limit window: %w", err)
+	}
+	ttl, _ := reply.(int64)
+	reset := time.Duration(ttl) * time.Millisecond
+	if ttl < 0 {
+		// The window was never started, such as when the instance counting
+		// the first request failed before setting it
+		rl.client.Do(ctx, "PEXPIRE", key, strconv.FormatInt(window.Milliseconds(), 10))
+		reset = window
+	}
+
+	remaining := limit - int(count)
+	if remaining < 0 {
+		return newResult(false, limit, 0, reset, window), nil
+	}
+	return newResult(true, limit, remaining, reset, window), nil
+}
@@ -0,0 +1,267 @@
+package reconcile
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/metrics"
+)
+
+// Reconciler defaults
+const (
+	DefaultInterval  = 30 * time.Second
+	DefaultTolerance = 0.25
+)
+
+// Reconciliation modes: flag only reports divergence, correct also enforces
+// the accepted volume of services exceeding it
+const (
+	ModeFlag    = "flag"
+	ModeCorrect = "correct"
+)
+
+// States of a service's observed traffic against its accepted volume
+const (
+	StateInSync = "in-sync"
+	StateOver   = "over"
+	StateUnder  = "under"
+)
+
+// Config holds the reconcile interval, the tolerated divergence and the mode
+type Config struct {
+	Interval  time.Duration `json:"-"`
+	Tolerance float64       `json:"tolerance"`
+	Mode      string        `json:"mode"`
+}
+
+// IntentSource returns the accepted traffic volume of every service with
+// traffic intents, in requests per second
+type IntentSource func() map[string]float64
+
+// ServiceStatus compares the observed traffic of a service with its accepted
+// volume over the last reconcile interval. Divergence is relative to the
+// accepted volume, so 0.5 means half again as many requests as accepted.
+type ServiceStatus struct {
+	Service     string    `json:"service"`
+	DesiredRPS  float64   `json:"desiredRps"`
+	ObservedRPS float64   `json:"observedRps"`
+	Divergence  float64   `json:"divergence"`
+	State       string    `json:"state"`
+	Enforced    bool      `json:"enforced"`
+	CheckedAt   time.Time `json:"checkedAt"`
+}
+
+// Reconciler periodically compares the accepted traffic intents of services
+// with their observed traffic. Services diverging beyond the tolerance are
+// flagged; in correct mode services exceeding their accepted volume have it
+// enforced until their intents are removed.
+type Reconciler struct {
+	config  Config
+	intents IntentSource
+
+	mu           sync.Mutex
+	counts       map[string]int64
+	since        time.Time
+	statuses     map[string]ServiceStatus
+	enforced     map[string]bool
+	onTransition []func(previous string, status ServiceStatus)
+	stop         chan struct{}
+	once         sync.Once
+}
+
+// ValidateConfig checks a reconciler configuration
+func ValidateConfig(config Config) error {
+	if config.Mode != "" && config.Mode != ModeFlag && config.Mode != ModeCorrect {
+		return fmt.Errorf("unsupported reconcile mode %q, expected flag or correct", config.Mode)
+	}
+	if config.Tolerance < 0 {
+		return fmt.Errorf("reconcile tolerance must not be negative")
+	}
+	return nil
+}
+
+// New creates a reconciler, filling unset settings with defaults
+func New(config Config, intents IntentSource) *Reconciler {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.Tolerance <= 0 {
+		config.Tolerance = DefaultTolerance
+	}
+	if config.Mode == "" {
+		config.Mode = ModeFlag
+	}
+
+	return &Reconciler{
+		config:   config,
+		intents:  intents,
+		counts:   make(map[string]int64),
+		since:    time.Now(),
+		statuses: make(map[string]ServiceStatus),
+		enforced: make(map[string]bool),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Config returns the reconciler configuration
+func (r *Reconciler) Config() Config {
+	return r.config
+}
+
+// OnTransition registers a function called when the state of a service
+// changes, with its previous state or "" for newly reconciled services
+func (r *Reconciler) OnTransition(fn func(previous string, status ServiceStatus)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onTransition = append(r.onTransition, fn)
+}
+
+// Observe counts a request served for a service
+func (r *Reconciler) Observe(service string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[service]++
+}
+
+// Limit returns the accepted volume enforced for a service in requests per
+// second, if any
+func (r *Reconciler) Limit(service string) (float64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.enforced[service] {
+		return 0, false
+	}
+	return r.statuses[service].DesiredRPS, true
+}
+
+// Start periodically reconciles services until Stop is called
+func (r *Reconciler) Start() {
+	go func() {
+		ticker := time.NewTicker(r.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.Reconcile()
+			}
+		}
+	}()
+}
+
+// Stop terminates the periodic reconcile
+func (r *Reconciler) Stop() {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+}
+
+// Reconcile compares the traffic observed since the last reconcile with the
+// accepted volumes and returns the status of every service with intents
+func (r *Reconciler) Reconcile() []ServiceStatus {
+	return r.reconcile(time.Now())
+}
+
+// reconcile runs a reconcile as of the given time
+func (r *Reconciler) reconcile(now time.Time) []ServiceStatus {
+	desired := r.intents()
+
+	type transition struct {
+		previous string
+		status   ServiceStatus
+	}
+	var transitions []transition
+
+	r.mu.Lock()
+	elapsed := now.Sub(r.since).Seconds()
+	counts := r.counts
+	r.counts = make(map[string]int64)
+	r.since = now
+
+	statuses := make(map[string]ServiceStatus, len(desired))
+	for service, volume := range desired {
+		observed := 0.0
+		if elapsed > 0 {
+			observed = float64(counts[service]) / elapsed
+		}
+		status := ServiceStatus{
+			Service:     service,
+			DesiredRPS:  volume,
+			ObservedRPS: observed,
+			Divergence:  divergence(volume, observed),
+			CheckedAt:   now.UTC(),
+		}
+		switch {
+		case status.Divergence > r.config.Tolerance:
+			status.State = StateOver
+		case status.Divergence < -r.config.Tolerance:
+			status.State = StateUnder
+		default:
+			status.State = StateInSync
+		}
+		if r.config.Mode == ModeCorrect && (status.State == StateOver || r.enforced[service]) {
+			r.enforced[service] = true
+			status.Enforced = true
+		}
+
+		if previous := r.statuses[service].State; previous != status.State {
+			transitions = append(transitions, transition{previous: previous, status: status})
+		}
+		statuses[service] = status
+		metrics.TrafficDivergence.WithLabelValues(service).Set(status.Divergence)
+	}
+	for service := range r.statuses {
+		if _, ok := statuses[service]; !ok {
+			metrics.TrafficDivergence.DeleteLabelValues(service)
+		}
+	}
+	for service := range r.enforced {
+		if _, ok := statuses[service]; !ok {
+			delete(r.enforced, service)
+		}
+	}
+	r.statuses = statuses
+	listeners := r.onTransition
+	r.mu.Unlock()
+
+	for _, t := range transitions {
+		for _, fn := range listeners {
+			fn(t.previous, t.status)
+		}
+	}
+	return sortStatuses(statuses)
+}
+
+// Status returns the status of every service as of the last reconcile,
+// sorted by service
+func (r *Reconciler) Status() []ServiceStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return sortStatuses(r.statuses)
+}
+
+// divergence returns the observed rate relative to the desired one. Any
+// traffic to a service accepting none counts as fully diverged.
+func divergence(desired, observed float64) float64 {
+	if desired == 0 {
+		if observed > 0 {
+			return 1
+		}
+		return 0
+	}
+	return (observed - desired) / desired
+}
+
+// sortStatuses lists statuses sorted by service
+func sortStatuses(statuses map[string]ServiceStatus) []ServiceStatus {
+	list := make([]ServiceStatus, 0, len(statuses))
+	for _, status := range statuses {
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Service < list[j].Service })
+	return list
+}
@@ -0,0 +1,96 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconcileFlagsDivergence(t *testing.T) {
+	desired := map[string]float64{"orders": 1, "billing": 2, "search": 1}
+	r := New(Config{Tolerance: 0.5}, func() map[string]float64 { return desired })
+	var transitions []string
+	r.OnTransition(func(previous string, status ServiceStatus) {
+		transitions = append(transitions, status.Service+":"+previous+"->"+status.State)
+	})
+
+	start := r.since
+	for i := 0; i < 30; i++ {
+		r.Observe("orders")
+	}
+	for i := 0; i < 20; i++ {
+		r.Observe("billing")
+	}
+	r.Observe("unmanaged")
+	statuses := r.reconcile(start.Add(10 * time.Second))
+
+	if len(statuses) != 3 {
+		t.Fatalf("Expected a status per service with intents, got %+v", statuses)
+	}
+	states := map[string]string{}
+	for _, status := range statuses {
+		states[status.Service] = status.State
+	}
+	if states["orders"] != StateOver || states["billing"] != StateInSync || states["search"] != StateUnder {
+		t.Errorf("Unexpected states %v", states)
+	}
+	if statuses[1].ObservedRPS != 3 || statuses[1].Divergence != 2 {
+		t.Errorf("Expected orders at 3 rps diverging by 2, got %+v", statuses[1])
+	}
+	if _, enforced := r.Limit("orders"); enforced {
+		t.Error("Expected flag mode not to enforce accepted volumes")
+	}
+	if len(transitions) != 3 {
+		t.Errorf("Expected a transition per newly reconciled service, got %v", transitions)
+	}
+
+	// Counts restart with every reconcile
+	transitions = nil
+	statuses = r.reconcile(start.Add(20 * time.Second))
+	if statuses[1].State != StateUnder || len(transitions) != 2 {
+		t.Errorf("Expected orders and billing to fall under, got %+v %v", statuses, transitions)
+	}
+}
+
+func TestReconcileCorrectsExcess(t *testing.T) {
+	desired := map[string]float64{"orders": 2}
+	r := New(Config{Mode: ModeCorrect}, func() map[string]float64 { return desired })
+	start := r.since
+
+	r.reconcile(start.Add(time.Second))
+	if _, enforced := r.Limit("orders"); enforced {
+		t.Error("Expected services within their volume not to be enforced")
+	}
+
+	for i := 0; i < 10; i++ {
+		r.Observe("orders")
+	}
+	r.reconcile(start.Add(2 * time.Second))
+	if limit, enforced := r.Limit("orders"); !enforced || limit != 2 {
+		t.Errorf("Expected the accepted volume to be enforced, got %v %v", limit, enforced)
+	}
+
+	// Enforcement holds once traffic is back in line, and follows the intents
+	desired["orders"] = 4
+	statuses := r.reconcile(start.Add(3 * time.Second))
+	if limit, enforced := r.Limit("orders"); !enforced || limit != 4 || !statuses[0].Enforced {
+		t.Errorf("Expected the updated volume to be enforced, got %v %v", limit, enforced)
+	}
+
+	delete(desired, "orders")
+	r.reconcile(start.Add(4 * time.Second))
+	if _, enforced := r.Limit("orders"); enforced {
+		t.Error("Expected enforcement to end with the intents")
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	if err := ValidateConfig(Config{Mode: ModeCorrect, Tolerance: 0.1}); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+	if err := ValidateConfig(Config{Mode: "repair"}); err == nil {
+		t.Error("Expected an unknown mode to be rejected")
+	}
+	if err := ValidateConfig(Config{Tolerance: -1}); err == nil {
+		t.Error("Expected a negative tolerance to be rejected")
+	}
+}
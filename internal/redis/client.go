@@ -0,0 +1,226 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client settings
+const (
+	// DefaultAddr is the address used when a URL names no host
+	DefaultAddr = "localhost:6379"
+	// maxIdle bounds the connections kept open between commands
+	maxIdle = 8
+	// dialTimeout bounds connecting when the context has no deadline
+	dialTimeout = 5 * time.Second
+)
+
+// Nil is returned for missing keys
+var Nil = errors.New("redis: nil")
+
+// Error is an error reply of the server
+type Error string
+
+// Error returns the server's message
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Client runs commands on a Redis server over a small pool of connections.
+// It speaks just enough RESP for the commands the control plane issues.
+type Client struct {
+	addr     string
+	username string
+	password string
+	db       int
+	idle     chan *conn
+}
+
+// conn is a pooled connection
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// NewClient creates a client for a redis:// URL such as
+// redis://:secret@cache:6379/2
+func NewClient(rawURL string) (*Client, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+	if parsed.Scheme != "redis" {
+		return nil, fmt.Errorf("unsupported Redis URL scheme %q", parsed.Scheme)
+	}
+
+	client := &Client{
+		addr: parsed.Host,
+		idle: make(chan *conn, maxIdle),
+	}
+	if client.addr == "" {
+		client.addr = DefaultAddr
+	}
+	if parsed.User != nil {
+		client.username = parsed.User.Username()
+		client.password, _ = parsed.User.Password()
+	}
+	if db := strings.Trim(parsed.Path, "/"); db != "" {
+		if client.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", db)
+		}
+	}
+	return client, nil
+}
+
+// Do runs a command and returns its reply: a string, an int64, a slice of
+// replies, or Nil for a missing value. Server errors are returned as Error.
+func (c *Client) Do(ctx context.Context, args ...string) (interface{}, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := cn.do(ctx, args)
+	var serverError Error
+	if err != nil && err != Nil && !errors.As(err, &serverError) {
+		cn.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// Close closes the idle connections
+func (c *Client) Close() {
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// get returns an idle connection or dials a new one
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: dialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	cn := &conn{Conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if c.password != "" {
+		auth := []string{"AUTH", c.password}
+		if c.username != "" {
+			auth = []string{"AUTH", c.username, c.password}
+		}
+		if _, err := cn.do(ctx, auth); err != nil {
+			cn.Close()
+			return nil, fmt.Errorf("failed to authenticate to Redis: %w", err)
+		}
+	}
+	if c.db != 0 {
+		if _, err := cn.do(ctx, []string{"SELECT", strconv.Itoa(c.db)}); err != nil {
+			cn.Close()
+			return nil, fmt.Errorf("failed to select Redis database: %w", err)
+		}
+	}
+	return cn, nil
+}
+
+// put returns a connection to the pool, closing it when the pool is full
+func (c *Client) put(cn *conn) {
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+// do writes a command and reads its reply
+func (cn *conn) do(ctx context.Context, args []string) (interface{}, error) {
+	deadline, _ := ctx.Deadline()
+	cn.SetDeadline(deadline)
+
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(cn, command.String()); err != nil {
+		return nil, fmt.Errorf("failed to send Redis command: %w", err)
+	}
+	return readReply(cn.reader)
+}
+
+// readReply reads one RESP reply
+func readReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Redis reply: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty Redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid Redis bulk length %q", line[1:])
+		}
+		if size < 0 {
+			return nil, Nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, fmt.Errorf("failed to read Redis reply: %w", err)
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid Redis array length %q", line[1:])
+		}
+		if count < 0 {
+			return nil, Nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			item, err := readReply(reader)
+			if err != nil && err != Nil {
+				var serverError Error
+				if !errors.As(err, &serverError) {
+					return nil, err
+				}
+				item = serverError
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected Redis reply %q", line)
+	}
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"dynamiccontrol/internal/redis/redistest"
+)
+
+func TestClientCommands(t *testing.T) {
+	server := redistest.NewServer()
+	defer server.Close()
+
+	client, err := NewClient(server.URL + "/1")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	if reply, err := client.Do(ctx, "SET", "greeting", "hello\r\nworld"); err != nil || reply != "OK" {
+		t.Fatalf("Unexpected SET reply %v: %v", reply, err)
+	}
+	if reply, err := client.Do(ctx, "GET", "greeting"); err != nil || reply != "hello\r\nworld" {
+		t.Errorf("Unexpected GET reply %q: %v", reply, err)
+	}
+	if _, err := client.Do(ctx, "GET", "missing"); err != Nil {
+		t.Errorf("Expected Nil for a missing key, got %v", err)
+	}
+	if reply, err := client.Do(ctx, "INCR", "counter"); err != nil || reply != int64(1) {
+		t.Errorf("Unexpected INCR reply %v: %v", reply, err)
+	}
+
+	reply, err := client.Do(ctx, "SCAN", "0", "MATCH", "greet*")
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	if page, ok := reply.([]interface{}); !ok || len(page) != 2 || len(page[1].([]interface{})) != 1 {
+		t.Errorf("Unexpected SCAN reply %v", reply)
+	}
+
+	var serverError Error
+	if _, err := client.Do(ctx, "BOGUS"); !errors.As(err, &serverError) {
+		t.Errorf("Expected a server error, got %v", err)
+	}
+	// The connection survives server errors
+	if _, err := client.Do(ctx, "GET", "greeting"); err != nil {
+		t.Errorf("Unexpected error after a server error: %v", err)
+	}
+}
+
+func TestNewClientRejectsInvalidURLs(t *testing.T) {
+	for _, rawURL := range []string{"http://cache:6379", "redis://cache:6379/db"} {
+		if _, err := NewClient(rawURL); err == nil {
+			t.Errorf("Expected %s to be rejected", rawURL)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Package redistest provides an in-memory Redis server for tests, covering
+// the commands the control plane issues
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is an in-memory Redis server listening on a local port
+type Server struct {
+	URL      string
+	listener net.Listener
+	mu       sync.Mutex
+	values   map[string]string
+	expires  map[string]time.Time
+	now      func() time.Time
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a server; callers must Close it
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("redistest: failed to listen: %v", err))
+	}
+	s := &Server{
+		URL:      "redis://" + listener.Addr().String(),
+		listener: listener,
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Time),
+		now:      time.Now,
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// Advance moves the server clock forward, expiring keys
+func (s *Server) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().Add(d)
+	s.now = func() time.Time { return now }
+}
+
+// Keys returns the live keys, sorted
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for key := range s.values {
+		if s.live(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// serve accepts connections until the listener closes
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle runs the commands of one connection
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, s.run(args)); err != nil {
+			return
+		}
+	}
+}
+
+// run executes a command and returns its encoded reply
+func (s *Server) run(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING", "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		if !s.live(args[1]) {
+			return "$-1\r\n"
+		}
+		return bulk(s.values[args[1]])
+	case "SET":
+		var expires time.Time
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				if s.live(args[1]) {
+					return "$-1\r\n"
+				}
+			case "PX":
+				if i+1 < len(args) {
+					ms, _ := strconv.Atoi(args[i+1])
+					expires = s.now().Add(time.Duration(ms) * time.Millisecond)
+					i++
+				}
+			}
+		}
+		s.values[args[1]] = args[2]
+		delete(s.expires, args[1])
+		if !expires.IsZero() {
+			s.expires[args[1]] = expires
+		}
+		return "+OK\r\n"
+	case "DEL":
+		removed := 0
+		for _, key := range args[1:] {
+			if s.live(key) {
+				removed++
+			}
+			delete(s.values, key)
+			delete(s.expires, key)
+		}
+		return fmt.Sprintf(":%d\r\n", removed)
+	case "INCR":
+		value := 0
+		if s.live(args[1]) {
+			value, _ = strconv.Atoi(s.values[args[1]])
+		}
+		value++
+		s.values[args[1]] = strconv.Itoa(value)
+		return fmt.Sprintf(":%d\r\n", value)
+	case "PEXPIRE":
+		if !s.live(args[1]) {
+			return ":0\r\n"
+		}
+		ms, _ := strconv.Atoi(args[2])
+		s.expires[args[1]] = s.now().Add(time.Duration(ms) * time.Millisecond)
+		return ":1\r\n"
+	case "PTTL":
+		if !s.live(args[1]) {
+			return ":-2\r\n"
+		}
+		expires, ok := s.expires[args[1]]
+		if !ok {
+			return ":-1\r\n"
+		}
+		return fmt.Sprintf(":%d\r\n", expires.Sub(s.now()).Milliseconds())
+	case "SCAN":
+		pattern := "*"
+		for i := 2; i+1 < len(args); i += 2 {
+			if strings.EqualFold(args[i], "MATCH") {
+				pattern = args[i+1]
+			}
+		}
+		var keys []string
+		for key := range s.values {
+			if glob(pattern).MatchString(key) && s.live(key) {
+				keys = append(keys, key)
+			}
+		}
+		reply := "*2\r\n" + bulk("0") + fmt.Sprintf("*%d\r\n", len(keys))
+		for _, key := range keys {
+			reply += bulk(key)
+		}
+		return reply
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+// live reports whether a key exists and has not expired; callers must hold mu
+func (s *Server) live(key string) bool {
+	if _, exists := s.values[key]; !exists {
+		return false
+	}
+	if expires, ok := s.expires[key]; ok && !s.now().Before(expires) {
+		delete(s.values, key)
+		delete(s.expires, key)
+		return false
+	}
+	return true
+}
+
+// glob compiles a Redis glob pattern, whose wildcards also match "/"
+func glob(pattern string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			expr.WriteString("(?s:.*)")
+		case '?':
+			expr.WriteString("(?s:.)")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+// bulk encodes a bulk string reply
+func bulk(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("invalid command %q", line)
+	}
+	args := make([]string, count)
+	for i := range args {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument %q", header)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
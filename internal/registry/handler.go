@@ -0,0 +1,108 @@
+package registry
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"dynamiccontrol/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes serves the registration API under /v1/services. With a
+// token, registrations and deregistrations require it as a bearer token.
+func RegisterRoutes(router gin.IRouter, registry *Registry, token string) {
+	write := requireToken(token)
+	router.POST("/v1/services", write, registerHandler(registry))
+	router.GET("/v1/services", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"services": registry.Services()})
+	})
+	router.GET("/v1/services/:serviceId", func(c *gin.Context) {
+		instances := registry.Instances(c.Param("serviceId"))
+		if len(instances) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Service not registered",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"service": c.Param("serviceId"), "instances": instances})
+	})
+	router.PUT("/v1/services/:serviceId/instances/:instanceId", write, instanceHandler(registry, registry.Renew))
+	router.DELETE("/v1/services/:serviceId/instances/:instanceId", write, instanceHandler(registry, registry.Deregister))
+}
+
+// registerHandler registers an instance, or renews it when it is already
+// registered with the same service and URL
+func registerHandler(registry *Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var registration Registration
+		if err := c.ShouldBindJSON(&registration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid registration",
+				"details": err.Error(),
+			})
+			return
+		}
+		instance, created, err := registry.Register(registration)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid registration",
+				"details": err.Error(),
+			})
+			return
+		}
+		if !created {
+			c.JSON(http.StatusOK, instance)
+			return
+		}
+		logging.FromContext(c.Request.Context()).Info("Service instance registered", "service", instance.Service, "instance", instance.ID, "url", instance.URL)
+		c.JSON(http.StatusCreated, instance)
+	}
+}
+
+// instanceHandler applies an operation to the instance named by the path,
+// which must belong to the service of the path
+func instanceHandler(registry *Registry, operation func(id string) (Instance, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("instanceId")
+		owned := false
+		for _, instance := range registry.Instances(c.Param("serviceId")) {
+			owned = owned || instance.ID == id
+		}
+		if !owned {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Instance not found",
+			})
+			return
+		}
+		instance, err := operation(id)
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Instance not found",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, instance)
+	}
+}
+
+// requireToken rejects requests without the bearer token, when one is set
+func requireToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="registry"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Unauthorized",
+			})
+			return
+		}
+		c.Next()
+	}
+}
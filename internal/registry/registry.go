@@ -0,0 +1,445 @@
+package registry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/metrics"
+)
+
+// Registry defaults
+const (
+	DefaultTTL                = 30 * time.Second
+	MaxTTL                    = 24 * time.Hour
+	DefaultSweepInterval      = 5 * time.Second
+	DefaultHealthInterval     = 10 * time.Second
+	DefaultHealthTimeout      = 2 * time.Second
+	DefaultUnhealthyThreshold = 3
+)
+
+// Changes reported to listeners
+const (
+	ChangeRegistered   = "registered"
+	ChangeDeregistered = "deregistered"
+	ChangeExpired      = "expired"
+	ChangeUnhealthy    = "unhealthy"
+	ChangeRecovered    = "recovered"
+)
+
+// ErrNotFound is returned for unknown instance IDs
+var ErrNotFound = errors.New("instance not found")
+
+// Config holds the default TTL of registrations and the expiry and health
+// check intervals
+type Config struct {
+	DefaultTTL         time.Duration
+	SweepInterval      time.Duration
+	HealthInterval     time.Duration
+	HealthTimeout      time.Duration
+	UnhealthyThreshold int
+	Client             *http.Client
+}
+
+// Registration is the request of a service instance to be discovered.
+// Registering the same service and URL again renews the registration.
+type Registration struct {
+	Service string `json:"service"`
+	URL     string `json:"url"`
+	// HealthEndpoint is a path below URL, or an absolute URL, probed with GET;
+	// without one the instance is healthy while its registration is renewed
+	HealthEndpoint string            `json:"healthEndpoint,omitempty"`
+	TTLSeconds     int               `json:"ttlSeconds,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+// Instance is a registered instance of a service
+type Instance struct {
+	ID             string            `json:"id"`
+	Service        string            `json:"service"`
+	URL            string            `json:"url"`
+	HealthEndpoint string            `json:"healthEndpoint,omitempty"`
+	TTLSeconds     int               `json:"ttlSeconds"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	Healthy        bool              `json:"healthy"`
+	RegisteredAt   time.Time         `json:"registeredAt"`
+	RenewedAt      time.Time         `json:"renewedAt"`
+	ExpiresAt      time.Time         `json:"expiresAt"`
+	failures       int
+}
+
+// Service summarizes the instances of a service
+type Service struct {
+	Name      string     `json:"name"`
+	Healthy   int        `json:"healthy"`
+	Instances []Instance `json:"instances"`
+}
+
+// Registry tracks the instances of backend services. Registrations expire
+// unless renewed within their TTL, and instances with a health endpoint
+// are probed and skipped by Resolve while unhealthy.
+type Registry struct {
+	config Config
+
+	mu        sync.Mutex
+	instances map[string]*Instance
+	next      map[string]int
+	onChange  []func(change string, instance Instance)
+	stop      chan struct{}
+	once      sync.Once
+}
+
+// New creates a registry, filling in defaults for unset settings
+func New(config Config) *Registry {
+	if config.DefaultTTL <= 0 {
+		config.DefaultTTL = DefaultTTL
+	}
+	if config.SweepInterval <= 0 {
+		config.SweepInterval = DefaultSweepInterval
+	}
+	if config.HealthInterval <= 0 {
+		config.HealthInterval = DefaultHealthInterval
+	}
+	if config.HealthTimeout <= 0 {
+		config.HealthTimeout = DefaultHealthTimeout
+	}
+	if config.UnhealthyThreshold <= 0 {
+		config.UnhealthyThreshold = DefaultUnhealthyThreshold
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{}
+	}
+	return &Registry{
+		config:    config,
+		instances: make(map[string]*Instance),
+		next:      make(map[string]int),
+		stop:      make(chan struct{}),
+	}
+}
+
+// OnChange registers a listener called when an instance is registered,
+// deregistered, expires or changes health. Listeners run with the registry
+// unlocked.
+func (r *Registry) OnChange(fn func(change string, instance Instance)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = append(r.onChange, fn)
+}
+
+// Register adds an instance, or renews the registration of the instance
+// with the same service and URL. It reports whether the instance is new.
+func (r *Registry) Register(registration Registration) (Instance, bool, error) {
+	if err := validate(registration); err != nil {
+		return Instance{}, false, err
+	}
+	ttl := r.config.DefaultTTL
+	if registration.TTLSeconds > 0 {
+		ttl = time.Duration(registration.TTLSeconds) * time.Second
+	}
+	now := time.Now().UTC()
+
+	r.mu.Lock()
+	instance := r.find(registration.Service, registration.URL)
+	created := instance == nil
+	if created {
+		instance = &Instance{
+			ID:           newID(),
+			Service:      registration.Service,
+			URL:          registration.URL,
+			Healthy:      true,
+			RegisteredAt: now,
+		}
+		r.instances[instance.ID] = instance
+	}
+	instance.HealthEndpoint = registration.HealthEndpoint
+	instance.Metadata = registration.Metadata
+	instance.TTLSeconds = int(ttl / time.Second)
+	instance.RenewedAt = now
+	instance.ExpiresAt = now.Add(ttl)
+	registered := *instance
+	r.updateMetrics(registered.Service)
+	r.mu.Unlock()
+
+	if created {
+		r.notify(ChangeRegistered, registered)
+	}
+	return registered, created, nil
+}
+
+// Renew extends the registration of an instance by its TTL
+func (r *Registry) Renew(id string) (Instance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	instance, exists := r.instances[id]
+	if !exists {
+		return Instance{}, ErrNotFound
+	}
+	instance.RenewedAt = time.Now().UTC()
+	instance.ExpiresAt = instance.RenewedAt.Add(time.Duration(instance.TTLSeconds) * time.Second)
+	return *instance, nil
+}
+
+// Deregister removes an instance
+func (r *Registry) Deregister(id string) (Instance, error) {
+	r.mu.Lock()
+	instance, exists := r.instances[id]
+	if !exists {
+		r.mu.Unlock()
+		return Instance{}, ErrNotFound
+	}
+	delete(r.instances, id)
+	removed := *instance
+	r.updateMetrics(removed.Service)
+	r.mu.Unlock()
+
+	r.notify(ChangeDeregistered, removed)
+	return removed, nil
+}
+
+// Services lists the registered services and their instances, by name
+func (r *Registry) Services() []Service {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byName := make(map[string]*Service)
+	for _, instance := range r.instances {
+		service, exists := byName[instance.Service]
+		if !exists {
+			service = &Service{Name: instance.Service}
+			byName[instance.Service] = service
+		}
+		service.Instances = append(service.Instances, *instance)
+		if instance.Healthy {
+			service.Healthy++
+		}
+	}
+	services := make([]Service, 0, len(byName))
+	for _, service := range byName {
+		sortInstances(service.Instances)
+		services = append(services, *service)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services
+}
+
+// Instances returns the instances of a service, healthy or not
+func (r *Registry) Instances(service string) []Instance {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var instances []Instance
+	for _, instance := range r.instances {
+		if instance.Service == service {
+			instances = append(instances, *instance)
+		}
+	}
+	sortInstances(instances)
+	return instances
+}
+
+// Resolve selects a healthy instance of a service, rotating between them
+func (r *Registry) Resolve(service string) (Instance, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var healthy []*Instance
+	for _, instance := range r.instances {
+		if instance.Service == service && instance.Healthy {
+			healthy = append(healthy, instance)
+		}
+	}
+	if len(healthy) == 0 {
+		return Instance{}, false
+	}
+	sort.Slice(healthy, func(i, j int) bool { return healthy[i].ID < healthy[j].ID })
+	selected := healthy[r.next[service]%len(healthy)]
+	r.next[service]++
+	return *selected, true
+}
+
+// Start expires registrations and probes health endpoints in the background
+// until Stop is called
+func (r *Registry) Start() {
+	go r.loop(r.config.SweepInterval, func() { r.Sweep(time.Now()) })
+	go r.loop(r.config.HealthInterval, r.CheckHealth)
+}
+
+// Stop stops the background expiry and health checks
+func (r *Registry) Stop() {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+}
+
+// loop runs fn every interval until the registry is stopped
+func (r *Registry) loop(interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			fn()
+		}
+	}
+}
+
+// Sweep removes the registrations that expired at now
+func (r *Registry) Sweep(now time.Time) {
+	r.mu.Lock()
+	var expired []Instance
+	for id, instance := range r.instances {
+		if now.After(instance.ExpiresAt) {
+			delete(r.instances, id)
+			expired = append(expired, *instance)
+			r.updateMetrics(instance.Service)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, instance := range expired {
+		r.notify(ChangeExpired, instance)
+	}
+}
+
+// CheckHealth probes the health endpoint of every instance having one. An
+// instance is unhealthy after UnhealthyThreshold failed probes in a row and
+// healthy again after a successful one.
+func (r *Registry) CheckHealth() {
+	r.mu.Lock()
+	var probes []Instance
+	for _, instance := range r.instances {
+		if instance.HealthEndpoint != "" {
+			probes = append(probes, *instance)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, probe := range probes {
+		ok := r.probe(probe)
+
+		r.mu.Lock()
+		instance, exists := r.instances[probe.ID]
+		if !exists {
+			r.mu.Unlock()
+			continue
+		}
+		change := ""
+		if ok {
+			instance.failures = 0
+			if !instance.Healthy {
+				instance.Healthy = true
+				change = ChangeRecovered
+			}
+		} else {
+			instance.failures++
+			if instance.Healthy && instance.failures >= r.config.UnhealthyThreshold {
+				instance.Healthy = false
+				change = ChangeUnhealthy
+			}
+		}
+		updated := *instance
+		r.updateMetrics(updated.Service)
+		r.mu.Unlock()
+
+		if change != "" {
+			r.notify(change, updated)
+		}
+	}
+}
+
+// probe requests the health endpoint of an instance; responses below 500
+// are healthy
+func (r *Registry) probe(instance Instance) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.HealthTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, HealthURL(instance), nil)
+	if err != nil {
+		return false
+	}
+	response, err := r.config.Client.Do(request)
+	if err != nil {
+		return false
+	}
+	response.Body.Close()
+	return response.StatusCode < http.StatusInternalServerError
+}
+
+// HealthURL returns the URL probed for the health of an instance
+func HealthURL(instance Instance) string {
+	if strings.HasPrefix(instance.HealthEndpoint, "http://") || strings.HasPrefix(instance.HealthEndpoint, "https://") {
+		return instance.HealthEndpoint
+	}
+	return strings.TrimSuffix(instance.URL, "/") + "/" + strings.TrimPrefix(instance.HealthEndpoint, "/")
+}
+
+// find returns the instance of a service at a URL; callers must hold mu
+func (r *Registry) find(service, url string) *Instance {
+	for _, instance := range r.instances {
+		if instance.Service == service && instance.URL == url {
+			return instance
+		}
+	}
+	return nil
+}
+
+// updateMetrics exports the healthy instances of a service; callers must
+// hold mu
+func (r *Registry) updateMetrics(service string) {
+	healthy := 0
+	for _, instance := range r.instances {
+		if instance.Service == service && instance.Healthy {
+			healthy++
+		}
+	}
+	metrics.RegistryInstances.WithLabelValues(service).Set(float64(healthy))
+}
+
+// notify calls the change listeners
+func (r *Registry) notify(change string, instance Instance) {
+	r.mu.Lock()
+	listeners := r.onChange
+	r.mu.Unlock()
+	for _, fn := range listeners {
+		fn(change, instance)
+	}
+}
+
+// validate checks a registration
+func validate(registration Registration) error {
+	if registration.Service == "" {
+		return fmt.Errorf("service is required")
+	}
+	if strings.ContainsAny(registration.Service, "/ ") {
+		return fmt.Errorf("service name %q must not contain slashes or spaces", registration.Service)
+	}
+	parsed, err := url.Parse(registration.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if registration.TTLSeconds < 0 || time.Duration(registration.TTLSeconds)*time.Second > MaxTTL {
+		return fmt.Errorf("ttlSeconds must be between 0 and %d", int(MaxTTL/time.Second))
+	}
+	return nil
+}
+
+// sortInstances orders instances by URL
+func sortInstances(instances []Instance) {
+	sort.Slice(instances, func(i, j int) bool { return instances[i].URL < instances[j].URL })
+}
+
+// newID returns a random instance ID
+func newID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return "inst-" + hex.EncodeToString(buf)
+}
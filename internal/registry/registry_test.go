@@ -0,0 +1,154 @@
+package registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterRenewsExistingInstances(t *testing.T) {
+	registry := New(Config{})
+	first, created, err := registry.Register(Registration{Service: "payments", URL: "http://payments-1:8080", TTLSeconds: 10})
+	if err != nil || !created {
+		t.Fatalf("Expected the instance to be created, got %v, %v", created, err)
+	}
+	again, created, err := registry.Register(Registration{Service: "payments", URL: "http://payments-1:8080", TTLSeconds: 60})
+	if err != nil || created || again.ID != first.ID || again.TTLSeconds != 60 {
+		t.Errorf("Expected the registration to be renewed, got %+v, %v, %v", again, created, err)
+	}
+	registry.Register(Registration{Service: "payments", URL: "http://payments-2:8080"})
+
+	services := registry.Services()
+	if len(services) != 1 || services[0].Healthy != 2 || services[0].Instances[1].TTLSeconds != int(DefaultTTL/time.Second) {
+		t.Errorf("Unexpected services %+v", services)
+	}
+
+	for _, registration := range []Registration{
+		{URL: "http://payments:8080"},
+		{Service: "payments", URL: "payments:8080"},
+		{Service: "pay ments", URL: "http://payments:8080"},
+		{Service: "payments", URL: "http://payments:8080", TTLSeconds: 100000},
+	} {
+		if _, _, err := registry.Register(registration); err == nil {
+			t.Errorf("Expected %+v to be rejected", registration)
+		}
+	}
+}
+
+func TestSweepExpiresRegistrations(t *testing.T) {
+	registry := New(Config{})
+	var changes []string
+	registry.OnChange(func(change string, instance Instance) { changes = append(changes, change+" "+instance.URL) })
+	instance, _, _ := registry.Register(Registration{Service: "payments", URL: "http://payments-1:8080", TTLSeconds: 5})
+
+	registry.Sweep(time.Now().Add(4 * time.Second))
+	if len(registry.Instances("payments")) != 1 {
+		t.Fatal("Expected the registration to be live within its TTL")
+	}
+	registry.Sweep(time.Now().Add(6 * time.Second))
+	if len(registry.Instances("payments")) != 0 {
+		t.Error("Expected the registration to expire after its TTL")
+	}
+	if _, err := registry.Renew(instance.ID); err != ErrNotFound {
+		t.Errorf("Expected expired instances to be gone, got %v", err)
+	}
+	if strings.Join(changes, ",") != "registered http://payments-1:8080,expired http://payments-1:8080" {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+}
+
+func TestResolveSkipsUnhealthyInstances(t *testing.T) {
+	healthy := true
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" || !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer backend.Close()
+
+	registry := New(Config{UnhealthyThreshold: 2})
+	registry.Register(Registration{Service: "payments", URL: backend.URL, HealthEndpoint: "/healthz"})
+	registry.Register(Registration{Service: "payments", URL: "http://payments-static:8080"})
+
+	seen := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		instance, _ := registry.Resolve("payments")
+		seen[instance.URL] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("Expected requests to rotate between instances, got %v", seen)
+	}
+
+	healthy = false
+	registry.CheckHealth()
+	if instances := registry.Instances("payments"); !instances[0].Healthy && !instances[1].Healthy {
+		t.Fatal("Expected a single failed probe to be tolerated")
+	}
+	registry.CheckHealth()
+	for i := 0; i < 3; i++ {
+		if instance, ok := registry.Resolve("payments"); !ok || instance.URL != "http://payments-static:8080" {
+			t.Errorf("Expected the unhealthy instance to be skipped, got %+v", instance)
+		}
+	}
+
+	healthy = true
+	registry.CheckHealth()
+	if services := registry.Services(); services[0].Healthy != 2 {
+		t.Errorf("Expected the instance to recover, got %+v", services)
+	}
+	if _, ok := registry.Resolve("orders"); ok {
+		t.Error("Expected unknown services not to resolve")
+	}
+}
+
+func TestRegistrationAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := New(Config{})
+	engine := gin.New()
+	RegisterRoutes(engine, registry, "secret")
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	registration := `{"service": "payments", "url": "http://payments-1:8080", "healthEndpoint": "/healthz"}`
+	if recorder := do(http.MethodPost, "/v1/services", registration, ""); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without the token, got %d", recorder.Code)
+	}
+	if recorder := do(http.MethodPost, "/v1/services", registration, "secret"); recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := do(http.MethodPost, "/v1/services", registration, "secret"); recorder.Code != http.StatusOK {
+		t.Errorf("Expected a repeated registration to renew it, got %d", recorder.Code)
+	}
+	if recorder := do(http.MethodPost, "/v1/services", `{"service": "payments"}`, "secret"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a url, got %d", recorder.Code)
+	}
+	if recorder := do(http.MethodGet, "/v1/services/orders", "", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown service, got %d", recorder.Code)
+	}
+
+	id := registry.Instances("payments")[0].ID
+	if recorder := do(http.MethodPut, "/v1/services/orders/instances/"+id, "", "secret"); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected instances of other services not to be renewed, got %d", recorder.Code)
+	}
+	if recorder := do(http.MethodPut, "/v1/services/payments/instances/"+id, "", "secret"); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the heartbeat to renew the instance, got %d", recorder.Code)
+	}
+	if recorder := do(http.MethodDelete, "/v1/services/payments/instances/"+id, "", "secret"); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the instance to be deregistered, got %d", recorder.Code)
+	}
+	if recorder := do(http.MethodGet, "/v1/services", "", ""); recorder.Code != http.StatusOK || recorder.Body.String() != `{"services":[]}` {
+		t.Errorf("Expected no services left, got %s", recorder.Body.String())
+	}
+}
@@ -0,0 +1,215 @@
+package reqctx
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contextKey is the request context key holding the request context
+type contextKey struct{}
+
+// ClientInfo describes the caller of a request
+type ClientInfo struct {
+	IP        string `json:"ip"`
+	UserAgent string `json:"userAgent,omitempty"`
+}
+
+// Timing records the time spent in one processing step
+type Timing struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+}
+
+// RequestContext is the canonical set of request attributes shared by
+// middlewares, pipeline stages, policy input construction, transforms and
+// logging. It is created once per request and carried in the context of the
+// *http.Request, so later readers reuse what earlier steps parsed instead of
+// parsing it again, whichever framework serves the request.
+type RequestContext struct {
+	RequestID string
+	StartedAt time.Time
+	Method    string
+	Path      string
+	Client    ClientInfo
+	Headers   map[string]string
+	Params    map[string]string
+	Query     map[string]string
+	// Body is the decoded request body; HasBody is false when the request carries none
+	Body    interface{}
+	HasBody bool
+	// Claims holds identity attributes established by authentication middleware
+	Claims map[string]interface{}
+	// Tenant is the tenant the request was resolved to, if any
+	Tenant string
+
+	mu        sync.Mutex
+	decisions []types.Decision
+	timings   []Timing
+}
+
+// New creates the request context of a request
+func New(r *http.Request) *RequestContext {
+	return &RequestContext{
+		RequestID: logging.RequestID(r.Context()),
+		StartedAt: time.Now(),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Client: ClientInfo{
+			IP:        ClientIP(r),
+			UserAgent: r.UserAgent(),
+		},
+	}
+}
+
+// ClientIP returns the address of the client of a request: the first
+// address of X-Forwarded-For or X-Real-IP, as set by proxies, or the remote
+// address otherwise, like gin's default
+func ClientIP(r *http.Request) string {
+	forwarded, _, _ := strings.Cut(r.Header.Get("X-Forwarded-For"), ",")
+	for _, candidate := range []string{forwarded, r.Header.Get("X-Real-IP")} {
+		if ip := net.ParseIP(strings.TrimSpace(candidate)); ip != nil {
+			return ip.String()
+		}
+	}
+	if host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr)); err == nil {
+		return host
+	}
+	return ""
+}
+
+// Attach returns the request context of a request, creating it on first use
+// together with the request carrying it
+func Attach(r *http.Request) (*RequestContext, *http.Request) {
+	if rc, ok := Lookup(r.Context()); ok {
+		return rc, r
+	}
+	rc := New(r)
+	return rc, r.WithContext(context.WithValue(r.Context(), contextKey{}, rc))
+}
+
+// Lookup returns the request context carried by a context, if any
+func Lookup(ctx context.Context) (*RequestContext, bool) {
+	rc, ok := ctx.Value(contextKey{}).(*RequestContext)
+	return rc, ok
+}
+
+// Middleware attaches a request context to every request of a gin engine,
+// so its start time covers all later handlers
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		From(c)
+		c.Next()
+	}
+}
+
+// From returns the request context of a gin request, creating it on first
+// use. The client address follows the trusted proxies of the gin engine.
+func From(c *gin.Context) *RequestContext {
+	if rc, ok := Lookup(c.Request.Context()); ok {
+		return rc
+	}
+	rc := New(c.Request)
+	rc.Client.IP = c.ClientIP()
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), contextKey{}, rc))
+	return rc
+}
+
+// SetClaim records an identity attribute of the caller
+func (rc *RequestContext) SetClaim(name string, value interface{}) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.Claims == nil {
+		rc.Claims = make(map[string]interface{})
+	}
+	rc.Claims[name] = value
+}
+
+// AddDecision records a policy decision taken for the request
+func (rc *RequestContext) AddDecision(decision types.Decision) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.decisions = append(rc.decisions, decision)
+}
+
+// Decisions returns the policy decisions taken for the request
+func (rc *RequestContext) Decisions() []types.Decision {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]types.Decision(nil), rc.decisions...)
+}
+
+// AddTiming records the time spent in a processing step
+func (rc *RequestContext) AddTiming(name string, duration time.Duration) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.timings = append(rc.timings, Timing{Name: name, Duration: duration})
+}
+
+// Timings returns the recorded processing steps in order
+func (rc *RequestContext) Timings() []Timing {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]Timing(nil), rc.timings...)
+}
+
+// Elapsed returns the time since the request started
+func (rc *RequestContext) Elapsed() time.Duration {
+	return time.Since(rc.StartedAt)
+}
+
+// PolicyInput builds the OPA input document for the request. path is the
+// route pattern policies match on; the concrete request path is available as
+// requestPath.
+func (rc *RequestContext) PolicyInput(method, path string) map[string]interface{} {
+	input := map[string]interface{}{
+		"requestId":   rc.RequestID,
+		"method":      method,
+		"path":        path,
+		"requestPath": rc.Path,
+		"headers":     rc.Headers,
+		"params":      rc.Params,
+		"query":       rc.Query,
+		"client":      map[string]interface{}{"ip": rc.Client.IP, "userAgent": rc.Client.UserAgent},
+	}
+	if rc.Claims != nil {
+		input["claims"] = rc.Claims
+	}
+	if rc.Tenant != "" {
+		input["tenant"] = rc.Tenant
+	}
+	if body := policyBody(rc.Body); body != nil {
+		input["body"] = body
+	}
+	return input
+}
+
+// policyBody returns the body as an object for Rego evaluation. Decoded JSON
+// objects are used as is; other values are converted through JSON.
+func policyBody(body interface{}) map[string]interface{} {
+	switch typed := body.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		return typed
+	}
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil
+	}
+	var object map[string]interface{}
+	if json.Unmarshal(encoded, &object) != nil {
+		return nil
+	}
+	return object
+}
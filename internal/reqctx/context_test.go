@@ -0,0 +1,82 @@
+package reqctx
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFromReusesContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/orders/42", nil)
+	c.Request.Header.Set("User-Agent", "dashboard/1.0")
+
+	rc := From(c)
+	if From(c) != rc {
+		t.Error("Expected the same request context on every call")
+	}
+	if rc.Path != "/v1/orders/42" || rc.Client.UserAgent != "dashboard/1.0" {
+		t.Errorf("Unexpected request attributes: %+v", rc)
+	}
+
+	rc.AddTiming("decode", time.Millisecond)
+	rc.AddDecision(types.Decision{Route: "POST /v1/orders/:id", Allowed: true})
+	if len(rc.Timings()) != 1 || len(rc.Decisions()) != 1 {
+		t.Errorf("Expected one timing and one decision, got %v and %v", rc.Timings(), rc.Decisions())
+	}
+}
+
+func TestAttachCarriesContextInRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/v1/orders", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+
+	rc, attached := Attach(req)
+	if found, ok := Lookup(attached.Context()); !ok || found != rc {
+		t.Fatal("Expected the request context in the attached request")
+	}
+	if again, same := Attach(attached); again != rc || same != attached {
+		t.Error("Expected the attached request context to be reused")
+	}
+	if _, ok := Lookup(req.Context()); ok {
+		t.Error("Expected the original request to be left unchanged")
+	}
+	if rc.Client.IP != "203.0.113.7" {
+		t.Errorf("Expected the forwarded client address, got %q", rc.Client.IP)
+	}
+}
+
+func TestPolicyInput(t *testing.T) {
+	body := map[string]interface{}{"amount": 10.0}
+	rc := &RequestContext{
+		Path:    "/v1/orders/42",
+		Headers: map[string]string{"Authorization": "Bearer token"},
+		Params:  map[string]string{"id": "42"},
+		Body:    body,
+		Client:  ClientInfo{IP: "10.0.0.1"},
+	}
+	rc.SetClaim("sub", "svc-billing")
+
+	input := rc.PolicyInput("POST", "/v1/orders/:id")
+	if input["path"] != "/v1/orders/:id" || input["requestPath"] != "/v1/orders/42" {
+		t.Errorf("Unexpected paths: %v, %v", input["path"], input["requestPath"])
+	}
+	if input["params"].(map[string]string)["id"] != "42" {
+		t.Errorf("Expected params in input, got %v", input["params"])
+	}
+	if input["claims"].(map[string]interface{})["sub"] != "svc-billing" {
+		t.Errorf("Expected claims in input, got %v", input["claims"])
+	}
+	if input["body"].(map[string]interface{})["amount"] != 10.0 {
+		t.Errorf("Expected body in input, got %v", input["body"])
+	}
+
+	rc.Body = []interface{}{1, 2}
+	if _, exists := rc.PolicyInput("POST", "/v1/orders/:id")["body"]; exists {
+		t.Error("Expected non-object bodies to be omitted from the input")
+	}
+}
@@ -0,0 +1,219 @@
+package responsecache
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity is the number of responses kept in memory
+const DefaultCapacity = 10000
+
+// entryOverhead approximates the memory of an entry besides its body and
+// headers
+const entryOverhead = 256
+
+// Entry is a cached response
+type Entry struct {
+	Status  int
+	Header  http.Header
+	Body    []byte
+	Expires time.Time
+}
+
+// Store holds cached responses. Cache keeps them in memory; RedisStore
+// shares them between replicas.
+type Store interface {
+	Get(key string) (*Entry, bool)
+	Set(key string, entry Entry, ttl time.Duration)
+	// Add stores a response unless one is stored under key, reporting
+	// whether it was stored
+	Add(key string, entry Entry, ttl time.Duration) bool
+	Delete(key string)
+	Invalidate(prefix string) int
+}
+
+// Cache keeps responses in memory until their TTL expires. When the cache
+// is full, the entry closest to expiry is evicted.
+type Cache struct {
+	mu       sync.Mutex
+	entries  map[string]*Entry
+	capacity int
+	now      func() time.Time
+}
+
+// New creates a cache holding up to capacity responses
+func New(capacity int) *Cache {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Cache{
+		entries:  make(map[string]*Entry),
+		capacity: capacity,
+		now:      time.Now,
+	}
+}
+
+// Get returns the unexpired response stored under key
+func (c *Cache) Get(key string) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[key]
+	if !exists {
+		return nil, false
+	}
+	if !c.now().Before(entry.Expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry, true
+}
+
+// Set stores a response for ttl, replacing any response stored under key
+func (c *Cache) Set(key string, entry Entry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	entry.Expires = c.now().Add(ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.capacity {
+		c.evict()
+	}
+	c.entries[key] = &entry
+}
+
+// Add stores a response for ttl unless an unexpired response is stored
+// under key, reporting whether it was stored
+func (c *Cache) Add(key string, entry Entry, ttl time.Duration) bool {
+	if ttl <= 0 {
+		return false
+	}
+	now := c.now()
+	entry.Expires = now.Add(ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	existing, exists := c.entries[key]
+	if exists && now.Before(existing.Expires) {
+		return false
+	}
+	if !exists && len(c.entries) >= c.capacity {
+		c.evict()
+	}
+	c.entries[key] = &entry
+	return true
+}
+
+// Delete removes the response stored under key
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// Invalidate removes every response whose key starts with prefix and
+// returns how many were removed
+func (c *Cache) Invalidate(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Len returns the number of stored responses, including expired ones not yet evicted
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// evict drops expired entries, or the entry closest to expiry when none has
+// expired; callers must hold mu
+func (c *Cache) evict() {
+	now := c.now()
+	var soonest string
+	for key, entry := range c.entries {
+		if !now.Before(entry.Expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if soonest == "" || entry.Expires.Before(c.entries[soonest].Expires) {
+			soonest = key
+		}
+	}
+	if len(c.entries) >= c.capacity && soonest != "" {
+		delete(c.entries, soonest)
+	}
+}
+
+// Name identifies the cache to the memory manager
+func (c *Cache) Name() string {
+	return "response_cache"
+}
+
+// SizeBytes returns the approximate memory held by cached responses
+func (c *Cache) SizeBytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var size int64
+	for key, entry := range c.entries {
+		size += entrySize(key, entry)
+	}
+	return size
+}
+
+// Shrink evicts responses, closest to expiry first, until the cache holds at
+// most target bytes
+func (c *Cache) Shrink(target int64) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]string, 0, len(c.entries))
+	var size int64
+	for key, entry := range c.entries {
+		keys = append(keys, key)
+		size += entrySize(key, entry)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].Expires.Before(c.entries[keys[j]].Expires)
+	})
+
+	var freed int64
+	for _, key := range keys {
+		if size <= target {
+			break
+		}
+		released := entrySize(key, c.entries[key])
+		delete(c.entries, key)
+		size -= released
+		freed += released
+	}
+	return freed
+}
+
+// entrySize approximates the memory held by a cached response
+func entrySize(key string, entry *Entry) int64 {
+	size := int64(entryOverhead + len(key) + len(entry.Body))
+	for name, values := range entry.Header {
+		size += int64(len(name))
+		for _, value := range values {
+			size += int64(len(value))
+		}
+	}
+	return size
+}
@@ -0,0 +1,118 @@
+package responsecache
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/redis"
+	"dynamiccontrol/internal/redis/redistest"
+)
+
+func TestCacheExpiresEntries(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := New(2)
+	cache.now = func() time.Time { return now }
+
+	cache.Set("GET /v1/items /v1/items/1", Entry{Status: 200, Body: []byte("one")}, time.Minute)
+	if entry, ok := cache.Get("GET /v1/items /v1/items/1"); !ok || string(entry.Body) != "one" {
+		t.Fatalf("Expected a cached entry, got %v", entry)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := cache.Get("GET /v1/items /v1/items/1"); ok {
+		t.Errorf("Expected the entry to expire after its TTL")
+	}
+}
+
+func TestCacheEvictsSoonestExpiry(t *testing.T) {
+	cache := New(2)
+	cache.Set("a", Entry{}, time.Minute)
+	cache.Set("b", Entry{}, time.Hour)
+	cache.Set("c", Entry{}, time.Hour)
+
+	if _, ok := cache.Get("a"); ok {
+		t.Errorf("Expected the entry closest to expiry to be evicted")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", cache.Len())
+	}
+	if removed := cache.Invalidate("b"); removed != 1 {
+		t.Errorf("Expected one invalidated entry, got %d", removed)
+	}
+}
+
+func TestCacheAddKeepsExistingEntries(t *testing.T) {
+	cache := New(2)
+	if !cache.Add("key", Entry{}, time.Minute) || cache.Add("key", Entry{Status: 200}, time.Minute) {
+		t.Fatal("Expected a key to be added once")
+	}
+	cache.Delete("key")
+	if !cache.Add("key", Entry{Status: 200}, time.Minute) {
+		t.Error("Expected a deleted key to be added again")
+	}
+}
+
+func TestCacheShrinksSoonestExpiryFirst(t *testing.T) {
+	cache := New(10)
+	body := make([]byte, 1000)
+	cache.Set("a", Entry{Body: body}, time.Hour)
+	cache.Set("b", Entry{Body: body}, time.Minute)
+	cache.Set("c", Entry{Body: body}, 2*time.Hour)
+
+	size := cache.SizeBytes()
+	if size < 3000 {
+		t.Fatalf("Expected the size to cover the bodies, got %d", size)
+	}
+	freed := cache.Shrink(size - 1)
+	if freed == 0 || cache.SizeBytes() != size-freed {
+		t.Errorf("Expected freed bytes to match the size change, freed %d of %d", freed, size)
+	}
+	if _, ok := cache.Get("b"); ok || cache.Len() != 2 {
+		t.Errorf("Expected only the entry closest to expiry to be evicted, %d left", cache.Len())
+	}
+
+	cache.Shrink(0)
+	if cache.Len() != 0 || cache.SizeBytes() != 0 {
+		t.Errorf("Expected an empty cache, got %d entries", cache.Len())
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	server := redistest.NewServer()
+	defer server.Close()
+	client, err := redis.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	store := NewRedisStore(client, "")
+
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	store.Set("GET /v1/items /v1/items?page=[1]", Entry{Status: 200, Header: header, Body: []byte(`{"id":1}`)}, time.Minute)
+	entry, ok := store.Get("GET /v1/items /v1/items?page=[1]")
+	if !ok || entry.Status != 200 || string(entry.Body) != `{"id":1}` || entry.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Unexpected cached entry %+v", entry)
+	}
+
+	server.Advance(2 * time.Minute)
+	if _, ok := store.Get("GET /v1/items /v1/items?page=[1]"); ok {
+		t.Error("Expected the entry to expire after its TTL")
+	}
+
+	store.Set("GET /v1/items /v1/items?page=[1]", Entry{Status: 200}, time.Minute)
+	store.Set("GET /v1/other /v1/other", Entry{Status: 200}, time.Minute)
+	if removed := store.Invalidate("GET /v1/items "); removed != 1 {
+		t.Errorf("Expected one invalidated entry, got %d", removed)
+	}
+	if keys := server.Keys(); len(keys) != 1 || keys[0] != DefaultRedisPrefix+"GET /v1/other /v1/other" {
+		t.Errorf("Unexpected remaining keys %v", keys)
+	}
+
+	if !store.Add("idempotency", Entry{}, time.Minute) || store.Add("idempotency", Entry{Status: 201}, time.Minute) {
+		t.Error("Expected SET NX to add a key once")
+	}
+	store.Delete("idempotency")
+	if _, ok := store.Get("idempotency"); ok {
+		t.Error("Expected the key to be deleted")
+	}
+}
@@ -0,0 +1,169 @@
+package responsecache
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/redis"
+)
+
+// Redis store settings
+const (
+	// DefaultRedisPrefix namespaces the keys of cached responses
+	DefaultRedisPrefix = "dynamiccontrol:cache:"
+	// redisTimeout bounds a cache operation, so a slow Redis degrades to
+	// cache misses instead of slow responses
+	redisTimeout = 500 * time.Millisecond
+	// scanCount is the number of keys requested per SCAN during invalidation
+	scanCount = "500"
+)
+
+// RedisStore keeps responses in Redis, so replicas share their cache.
+// Failed operations are logged and treated as misses.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// redisEntry is a response as stored in Redis
+type redisEntry struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// NewRedisStore creates a store keeping responses under prefix
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = DefaultRedisPrefix
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Get returns the response stored under key
+func (rs *RedisStore) Get(key string) (*Entry, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	reply, err := rs.client.Do(ctx, "GET", rs.prefix+key)
+	if err == redis.Nil {
+		return nil, false
+	}
+	if err != nil {
+		slog.Warn("Failed to read cached response", "key", key, "error", err)
+		return nil, false
+	}
+	var stored redisEntry
+	if err := json.Unmarshal([]byte(reply.(string)), &stored); err != nil {
+		slog.Warn("Failed to decode cached response", "key", key, "error", err)
+		return nil, false
+	}
+	return &Entry{Status: stored.Status, Header: stored.Header, Body: stored.Body}, true
+}
+
+// Set stores a response for ttl
+func (rs *RedisStore) Set(key string, entry Entry, ttl time.Duration) {
+	if ttl < time.Millisecond {
+		return
+	}
+	data, err := json.Marshal(redisEntry{Status: entry.Status, Header: entry.Header, Body: entry.Body})
+	if err != nil {
+		slog.Warn("Failed to encode cached response", "key", key, "error", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if _, err := rs.client.Do(ctx, "SET", rs.prefix+key, string(data), "PX", strconv.FormatInt(ttl.Milliseconds(), 10)); err != nil {
+		slog.Warn("Failed to store cached response", "key", key, "error", err)
+	}
+}
+
+// Add stores a response for ttl with SET NX, so that a single replica
+// stores a key. A failed operation reports the response as stored, like a
+// miss.
+func (rs *RedisStore) Add(key string, entry Entry, ttl time.Duration) bool {
+	if ttl < time.Millisecond {
+		return false
+	}
+	data, err := json.Marshal(redisEntry{Status: entry.Status, Header: entry.Header, Body: entry.Body})
+	if err != nil {
+		slog.Warn("Failed to encode cached response", "key", key, "error", err)
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	_, err = rs.client.Do(ctx, "SET", rs.prefix+key, string(data), "PX", strconv.FormatInt(ttl.Milliseconds(), 10), "NX")
+	if err == redis.Nil {
+		return false
+	}
+	if err != nil {
+		slog.Warn("Failed to store cached response", "key", key, "error", err)
+	}
+	return true
+}
+
+// Delete removes the response stored under key
+func (rs *RedisStore) Delete(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if _, err := rs.client.Do(ctx, "DEL", rs.prefix+key); err != nil {
+		slog.Warn("Failed to delete cached response", "key", key, "error", err)
+	}
+}
+
+// Invalidate removes every response whose key starts with prefix and
+// returns how many were removed
+func (rs *RedisStore) Invalidate(prefix string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*redisTimeout)
+	defer cancel()
+
+	removed := 0
+	cursor := "0"
+	for {
+		reply, err := rs.client.Do(ctx, "SCAN", cursor, "MATCH", escapeGlob(rs.prefix+prefix)+"*", "COUNT", scanCount)
+		if err != nil {
+			slog.Warn("Failed to invalidate cached responses", "prefix", prefix, "error", err)
+			return removed
+		}
+		page, _ := reply.([]interface{})
+		if len(page) != 2 {
+			return removed
+		}
+		cursor, _ = page[0].(string)
+		keys, _ := page[1].([]interface{})
+		if len(keys) > 0 {
+			args := []string{"DEL"}
+			for _, key := range keys {
+				if name, ok := key.(string); ok {
+					args = append(args, name)
+				}
+			}
+			if reply, err := rs.client.Do(ctx, args...); err == nil {
+				count, _ := reply.(int64)
+				removed += int(count)
+			}
+		}
+		if cursor == "0" || cursor == "" {
+			return removed
+		}
+	}
+}
+
+// escapeGlob escapes the glob characters of a SCAN pattern
+func escapeGlob(value string) string {
+	var escaped strings.Builder
+	for _, r := range value {
+		if strings.ContainsRune(`*?[]\`, r) {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
@@ -0,0 +1,308 @@
+package rollout
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/configstore"
+)
+
+// Rollout defaults
+const (
+	DefaultBakeTime     = 5 * time.Minute
+	DefaultMaxErrorRate = 0.05
+	DefaultMinRequests  = 100
+	// replicaTimeout is how long a replica that stopped polling still counts
+	replicaTimeout = time.Minute
+)
+
+// Rollout states
+const (
+	StateIdle             = "idle"
+	StateProgressing      = "progressing"
+	StateAwaitingApproval = "awaiting-approval"
+	StateAborted          = "aborted"
+)
+
+// Config describes how new revisions progress through the replica rings.
+// Ring 0 receives a new revision first; once it baked for BakeTime with at
+// least MinRequests and an error rate of at most MaxErrorRate, the next ring
+// receives it, automatically or on operator approval.
+type Config struct {
+	Rings        int           `json:"rings"`
+	BakeTime     time.Duration `json:"bakeTime"`
+	MaxErrorRate float64       `json:"maxErrorRate"`
+	MinRequests  int64         `json:"minRequests"`
+	AutoPromote  bool          `json:"autoPromote"`
+}
+
+// Report is the traffic a replica served on the revision it runs
+type Report struct {
+	Replica  string
+	Ring     int
+	Revision string
+	Requests int64
+	Errors   int64
+}
+
+// ReplicaStatus is the last report of a replica
+type ReplicaStatus struct {
+	Replica  string    `json:"replica"`
+	Ring     int       `json:"ring"`
+	Revision string    `json:"revision"`
+	Requests int64     `json:"requests"`
+	Errors   int64     `json:"errors"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// Status describes the current rollout
+type Status struct {
+	State             string          `json:"state"`
+	Rings             int             `json:"rings"`
+	StableRevision    string          `json:"stableRevision,omitempty"`
+	CandidateRevision string          `json:"candidateRevision,omitempty"`
+	PromotedRing      int             `json:"promotedRing"`
+	RingStartedAt     *time.Time      `json:"ringStartedAt,omitempty"`
+	Requests          int64           `json:"requests"`
+	Errors            int64           `json:"errors"`
+	ErrorRate         float64         `json:"errorRate"`
+	Reason            string          `json:"reason,omitempty"`
+	Replicas          []ReplicaStatus `json:"replicas"`
+}
+
+// Controller stages configuration revisions across rings of replicas. The
+// primary serves the candidate revision to promoted rings and the last fully
+// rolled out revision to the others.
+type Controller struct {
+	mu        sync.Mutex
+	config    Config
+	stable    *configstore.Snapshot
+	candidate *configstore.Snapshot
+	promoted  int
+	started   time.Time
+	state     string
+	reason    string
+	aborted   string
+	replicas  map[string]ReplicaStatus
+	now       func() time.Time
+}
+
+// New creates a controller rolling revisions out over config.Rings rings
+func New(config Config) *Controller {
+	if config.Rings < 2 {
+		config.Rings = 2
+	}
+	if config.BakeTime <= 0 {
+		config.BakeTime = DefaultBakeTime
+	}
+	if config.MaxErrorRate <= 0 {
+		config.MaxErrorRate = DefaultMaxErrorRate
+	}
+	if config.MinRequests <= 0 {
+		config.MinRequests = DefaultMinRequests
+	}
+	return &Controller{
+		config:   config,
+		state:    StateIdle,
+		replicas: make(map[string]ReplicaStatus),
+		now:      time.Now,
+	}
+}
+
+// Observe records the primary's active snapshot. The first snapshot is
+// stable; a later one with a new revision starts a rollout at ring 0.
+func (rc *Controller) Observe(snapshot *configstore.Snapshot) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	switch {
+	case rc.stable == nil:
+		rc.stable = snapshot
+	case snapshot.Revision == rc.stable.Revision:
+		// The primary went back to the stable revision
+		if rc.candidate != nil {
+			rc.finish(StateIdle, "candidate withdrawn on the primary")
+		}
+	case rc.candidate != nil && snapshot.Revision == rc.candidate.Revision, snapshot.Revision == rc.aborted:
+	default:
+		rc.candidate = snapshot
+		rc.promoted = 0
+		rc.started = rc.now()
+		rc.state = StateProgressing
+		rc.reason = ""
+		rc.aborted = ""
+	}
+}
+
+// Record stores a replica's report and advances the rollout
+func (rc *Controller) Record(report Report) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if report.Replica != "" {
+		rc.replicas[report.Replica] = ReplicaStatus{
+			Replica:  report.Replica,
+			Ring:     report.Ring,
+			Revision: report.Revision,
+			Requests: report.Requests,
+			Errors:   report.Errors,
+			LastSeen: rc.now(),
+		}
+	}
+	rc.advance()
+}
+
+// SnapshotFor returns the snapshot a replica of the given ring should run
+func (rc *Controller) SnapshotFor(ring int) *configstore.Snapshot {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.candidate != nil && ring >= 0 && ring <= rc.promoted {
+		return rc.candidate
+	}
+	return rc.stable
+}
+
+// Promote moves the candidate to the next ring regardless of its health
+func (rc *Controller) Promote() error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.candidate == nil {
+		return fmt.Errorf("no rollout in progress")
+	}
+	rc.promote()
+	return nil
+}
+
+// Abort stops the rollout and returns every ring to the stable revision
+func (rc *Controller) Abort(reason string) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.candidate == nil {
+		return fmt.Errorf("no rollout in progress")
+	}
+	rc.aborted = rc.candidate.Revision
+	rc.finish(StateAborted, reason)
+	return nil
+}
+
+// Status returns the state of the current rollout, advancing it first so
+// that bake times elapse even while no replica reports
+func (rc *Controller) Status() Status {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.advance()
+	requests, errors := rc.health()
+	status := Status{
+		State:        rc.state,
+		Rings:        rc.config.Rings,
+		PromotedRing: rc.promoted,
+		Requests:     requests,
+		Errors:       errors,
+		ErrorRate:    errorRate(requests, errors),
+		Reason:       rc.reason,
+		Replicas:     make([]ReplicaStatus, 0, len(rc.replicas)),
+	}
+	if rc.stable != nil {
+		status.StableRevision = rc.stable.Revision
+	}
+	if rc.candidate != nil {
+		status.CandidateRevision = rc.candidate.Revision
+		started := rc.started
+		status.RingStartedAt = &started
+	}
+	for _, replica := range rc.replicas {
+		status.Replicas = append(status.Replicas, replica)
+	}
+	sort.Slice(status.Replicas, func(i, j int) bool {
+		return status.Replicas[i].Replica < status.Replicas[j].Replica
+	})
+	return status
+}
+
+// Config returns the rollout configuration
+func (rc *Controller) Config() Config {
+	return rc.config
+}
+
+// advance aborts an unhealthy candidate and promotes a healthy one that
+// baked long enough; callers must hold mu
+func (rc *Controller) advance() {
+	for id, replica := range rc.replicas {
+		if rc.now().Sub(replica.LastSeen) > replicaTimeout {
+			delete(rc.replicas, id)
+		}
+	}
+	if rc.candidate == nil || rc.state != StateProgressing {
+		return
+	}
+
+	requests, errors := rc.health()
+	if requests < rc.config.MinRequests {
+		return
+	}
+	if rate := errorRate(requests, errors); rate > rc.config.MaxErrorRate {
+		rc.aborted = rc.candidate.Revision
+		rc.finish(StateAborted, fmt.Sprintf("error rate %.3f exceeded %.3f in ring %d", rate, rc.config.MaxErrorRate, rc.promoted))
+		return
+	}
+	if rc.now().Sub(rc.started) < rc.config.BakeTime {
+		return
+	}
+	if rc.config.AutoPromote {
+		rc.promote()
+		return
+	}
+	rc.state = StateAwaitingApproval
+}
+
+// promote moves the candidate to the next ring, completing the rollout after
+// the last ring; callers must hold mu
+func (rc *Controller) promote() {
+	if rc.promoted+1 >= rc.config.Rings {
+		rc.stable = rc.candidate
+		rc.finish(StateIdle, "")
+		return
+	}
+	rc.promoted++
+	rc.started = rc.now()
+	rc.state = StateProgressing
+}
+
+// finish ends the rollout, leaving every ring on the stable revision;
+// callers must hold mu
+func (rc *Controller) finish(state, reason string) {
+	rc.candidate = nil
+	rc.promoted = 0
+	rc.state = state
+	rc.reason = reason
+}
+
+// health sums the traffic served on the candidate by replicas of promoted
+// rings; callers must hold mu
+func (rc *Controller) health() (int64, int64) {
+	if rc.candidate == nil {
+		return 0, 0
+	}
+	var requests, errors int64
+	for _, replica := range rc.replicas {
+		if replica.Revision == rc.candidate.Revision && replica.Ring <= rc.promoted {
+			requests += replica.Requests
+			errors += replica.Errors
+		}
+	}
+	return requests, errors
+}
+
+// errorRate returns the share of failed requests
+func errorRate(requests, errors int64) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(errors) / float64(requests)
+}
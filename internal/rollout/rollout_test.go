@@ -0,0 +1,89 @@
+package rollout
+
+import (
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/configstore"
+)
+
+func newTestController(autoPromote bool) (*Controller, *time.Time) {
+	now := time.Unix(0, 0)
+	controller := New(Config{Rings: 2, BakeTime: time.Minute, MaxErrorRate: 0.1, MinRequests: 10, AutoPromote: autoPromote})
+	controller.now = func() time.Time { return now }
+	controller.Observe(&configstore.Snapshot{Revision: "r1"})
+	controller.Observe(&configstore.Snapshot{Revision: "r2"})
+	return controller, &now
+}
+
+func TestRolloutPromotesHealthyCandidate(t *testing.T) {
+	controller, now := newTestController(true)
+
+	if controller.SnapshotFor(0).Revision != "r2" || controller.SnapshotFor(1).Revision != "r1" {
+		t.Fatalf("Expected ring 0 on the candidate and ring 1 on the stable revision")
+	}
+
+	controller.Record(Report{Replica: "canary", Ring: 0, Revision: "r2", Requests: 50, Errors: 1})
+	if status := controller.Status(); status.State != StateProgressing || status.PromotedRing != 0 {
+		t.Errorf("Expected the candidate to bake in ring 0, got %+v", status)
+	}
+
+	*now = now.Add(time.Minute)
+	controller.Record(Report{Replica: "canary", Ring: 0, Revision: "r2", Requests: 100, Errors: 2})
+	if controller.SnapshotFor(1).Revision != "r2" {
+		t.Errorf("Expected ring 1 to receive the candidate after baking")
+	}
+
+	*now = now.Add(time.Minute)
+	status := controller.Status()
+	if status.State != StateIdle || status.StableRevision != "r2" || status.CandidateRevision != "" {
+		t.Errorf("Expected the rollout to complete, got %+v", status)
+	}
+}
+
+func TestRolloutAwaitsApproval(t *testing.T) {
+	controller, now := newTestController(false)
+	*now = now.Add(time.Minute)
+	controller.Record(Report{Replica: "canary", Ring: 0, Revision: "r2", Requests: 100})
+
+	if status := controller.Status(); status.State != StateAwaitingApproval {
+		t.Fatalf("Expected the rollout to await approval, got %+v", status)
+	}
+	if controller.SnapshotFor(1).Revision != "r1" {
+		t.Errorf("Expected ring 1 to stay on the stable revision until approved")
+	}
+	if err := controller.Promote(); err != nil {
+		t.Fatalf("Failed to promote: %v", err)
+	}
+	if controller.SnapshotFor(1).Revision != "r2" {
+		t.Errorf("Expected ring 1 to receive the candidate once approved")
+	}
+}
+
+func TestRolloutAbortsOnErrors(t *testing.T) {
+	controller, _ := newTestController(true)
+	// Reports for other revisions do not count towards the candidate
+	controller.Record(Report{Replica: "stable", Ring: 1, Revision: "r1", Requests: 100, Errors: 90})
+	if status := controller.Status(); status.State != StateProgressing {
+		t.Fatalf("Expected stable traffic to be ignored, got %+v", status)
+	}
+
+	controller.Record(Report{Replica: "canary", Ring: 0, Revision: "r2", Requests: 20, Errors: 5})
+	status := controller.Status()
+	if status.State != StateAborted || status.Reason == "" {
+		t.Fatalf("Expected the rollout to abort, got %+v", status)
+	}
+	if controller.SnapshotFor(0).Revision != "r1" {
+		t.Errorf("Expected ring 0 to return to the stable revision")
+	}
+
+	// The aborted revision is not rolled out again until the primary changes
+	controller.Observe(&configstore.Snapshot{Revision: "r2"})
+	if controller.Status().State != StateAborted {
+		t.Errorf("Expected the aborted revision to stay aborted")
+	}
+	controller.Observe(&configstore.Snapshot{Revision: "r3"})
+	if controller.SnapshotFor(0).Revision != "r3" {
+		t.Errorf("Expected a new revision to start a new rollout")
+	}
+}
@@ -0,0 +1,207 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/transform"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/upstream"
+	"dynamiccontrol/internal/validator"
+)
+
+// aggregateOutcome holds the assembled response of an aggregate route
+type aggregateOutcome struct {
+	status   int
+	response interface{}
+	opStatus string
+	err      string
+}
+
+// executeAggregate calls the configured upstreams and assembles their
+// responses into a single document using the route mapping template
+func (rm *RouteManager) executeAggregate(ex *Exchange) error {
+	route := ex.Route
+	request := map[string]interface{}{
+		"params":  ex.Params,
+		"headers": ex.Headers,
+		"query":   ex.Query,
+		"body":    ex.Body,
+	}
+
+	aggregate := route.Aggregate
+	if aggregate.Mode != types.AggregateModeSaga && !aggregate.Async {
+		return applyAggregateOutcome(ex, rm.runAggregate(rm.upstreamContext(ex.Request.Context(), ex), route, ex.schemas.response, ex.Params, request, ""))
+	}
+
+	stepNames := make([]string, len(aggregate.Calls))
+	for i, call := range aggregate.Calls {
+		stepNames[i] = call.Name
+	}
+	operation := rm.operations.Create(route.RouteName, stepNames)
+	ex.Writer.Header().Set("X-Operation-ID", operation.ID)
+
+	if !aggregate.Async {
+		return applyAggregateOutcome(ex, rm.runAggregate(rm.upstreamContext(ex.Request.Context(), ex), route, ex.schemas.response, ex.Params, request, operation.ID))
+	}
+
+	started := rm.sideEffects.Go(rm.upstreamContext(ex.Request.Context(), ex), "aggregate", func(ctx context.Context) {
+		rm.runAggregate(ctx, route, ex.schemas.response, ex.Params, request, operation.ID)
+	})
+	if !started {
+		rm.operations.SetStatus(operation.ID, types.OperationFailed, "server is shutting down or busy")
+		return &StageError{Status: http.StatusServiceUnavailable, Message: "Server is busy", Details: "async operation could not be started"}
+	}
+
+	ex.ResponseHeaders["Location"] = "/v1/operations/" + operation.ID
+	ex.StatusCode = http.StatusAccepted
+	ex.Response = map[string]interface{}{
+		"operationId": operation.ID,
+		"status":      operation.Status,
+	}
+	ex.ResponseValidated = true
+	return nil
+}
+
+// runAggregate executes the upstream calls of an aggregate route and
+// assembles the response, validated against the route's prepared response
+// schema, recording progress when an operation ID is given
+func (rm *RouteManager) runAggregate(ctx context.Context, route types.RouteConfig, responseSchema *validator.PreparedSchema, params map[string]string, request map[string]interface{}, operationID string) aggregateOutcome {
+	aggregate := route.Aggregate
+	if operationID != "" {
+		rm.operations.SetStatus(operationID, types.OperationRunning, "")
+	}
+
+	var results map[string]types.UpstreamResult
+	opStatus := types.OperationSucceeded
+	if aggregate.Mode == types.AggregateModeSaga {
+		results, opStatus = rm.upstreamClient.RunSaga(ctx, aggregate.Calls, params, request["body"], func(step, status, errorMessage string) {
+			if operationID != "" {
+				rm.operations.SetStepStatus(operationID, step, status, errorMessage)
+			}
+		})
+	} else {
+		results = rm.upstreamClient.CallAll(ctx, aggregate.Calls, params, request["body"])
+	}
+
+	var failures []string
+	upstreams := make(map[string]interface{}, len(results))
+	for _, call := range aggregate.Calls {
+		result, called := results[call.Name]
+		if !called {
+			continue
+		}
+		if aggregate.Mode != types.AggregateModeSaga && operationID != "" {
+			stepStatus := types.OperationSucceeded
+			if result.Error != "" {
+				stepStatus = types.OperationFailed
+			}
+			rm.operations.SetStepStatus(operationID, call.Name, stepStatus, result.Error)
+		}
+		if result.Error != "" && !call.Optional {
+			failures = append(failures, fmt.Sprintf("%s: %s", call.Name, result.Error))
+		}
+		upstreams[call.Name] = map[string]interface{}{
+			"statusCode": result.StatusCode,
+			"body":       result.Body,
+			"error":      result.Error,
+		}
+	}
+
+	outcome := aggregateOutcome{opStatus: opStatus}
+	if len(failures) > 0 {
+		if opStatus == types.OperationSucceeded {
+			outcome.opStatus = types.OperationFailed
+		}
+		outcome.status = http.StatusBadGateway
+		outcome.err = "Upstream call failed"
+		outcome.response = strings.Join(failures, "; ")
+	} else {
+		document := map[string]interface{}{
+			"request":   request,
+			"upstreams": upstreams,
+		}
+		vars, err := rm.templateVars(route)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to resolve route variables", "route", routeKey(route), "error", err)
+		}
+		if vars != nil {
+			document["vars"] = vars
+		}
+
+		response, mappingErrors := transform.Apply(aggregate.Mapping, document)
+		for _, mappingErr := range mappingErrors {
+			logging.FromContext(ctx).Warn("Aggregate mapping failed", "route", routeKey(route), "error", mappingErr)
+		}
+
+		// Validate the assembled response against schema
+		validationResult := rm.schemaValidator.ValidatePrepared(responseSchema, response)
+		if validationResult.Valid {
+			outcome.status = http.StatusOK
+			outcome.response = response
+		} else {
+			outcome.opStatus = types.OperationFailed
+			outcome.status = http.StatusBadGateway
+			outcome.err = "Aggregated response validation failed"
+			outcome.response = validator.FormatValidationErrors(validationResult.Errors)
+		}
+	}
+
+	if operationID != "" {
+		if outcome.err == "" {
+			rm.operations.SetResult(operationID, outcome.response)
+			rm.operations.SetStatus(operationID, outcome.opStatus, "")
+		} else {
+			rm.operations.SetStatus(operationID, outcome.opStatus, fmt.Sprintf("%s: %v", outcome.err, outcome.response))
+		}
+	}
+
+	return outcome
+}
+
+// upstreamContext returns a context carrying the headers the route's header
+// policy propagates to upstream calls, plus the identity assertion when
+// assertions are enabled
+func (rm *RouteManager) upstreamContext(ctx context.Context, ex *Exchange) context.Context {
+	return upstream.WithPropagatedHeaders(ctx, rm.upstreamHeaders(ctx, ex))
+}
+
+// upstreamHeaders returns the headers sent with upstream calls of a request
+func (rm *RouteManager) upstreamHeaders(ctx context.Context, ex *Exchange) http.Header {
+	header := upstream.PropagateHeaders(ex.Route.Headers, ex.Request.Header, exchangeVariables(ex))
+	injectTrace(ex, header)
+	rm.assertIdentity(ctx, ex, header)
+	return header
+}
+
+// exchangeVariables returns the values of the ${name} placeholders available
+// to added headers and telemetry
+func exchangeVariables(ex *Exchange) map[string]string {
+	vars := map[string]string{
+		"requestId": ex.RequestID,
+		"clientIp":  ex.Client.IP,
+		"route":     routeKey(ex.Route),
+	}
+	for name, value := range ex.Params {
+		vars["param."+name] = value
+	}
+	for name, value := range ex.Claims {
+		vars["claim."+name] = fmt.Sprint(value)
+	}
+	return vars
+}
+
+// applyAggregateOutcome stores the result of an aggregate route on the
+// exchange, mapping failed outcomes to a stage error
+func applyAggregateOutcome(ex *Exchange, outcome aggregateOutcome) error {
+	if outcome.err != "" {
+		return stageError(outcome.status, outcome.err, outcome.response)
+	}
+	ex.StatusCode = outcome.status
+	ex.Response = outcome.response
+	ex.ResponseValidated = true
+	return nil
+}
@@ -0,0 +1,255 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+)
+
+// newAggregateManager serves an aggregate route from a route manager
+func newAggregateManager(t *testing.T, aggregate *types.AggregateConfig) *RouteManager {
+	t.Helper()
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	t.Cleanup(rm.Stop)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName: "/v1/profiles/:id",
+		Method:    "GET",
+		Handler:   types.HandlerAggregate,
+		Aggregate: aggregate,
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	return rm
+}
+
+// getJSON serves a GET request and decodes the JSON response
+func getJSON(t *testing.T, handler http.Handler, path string) (int, map[string]interface{}) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	var body map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, body
+}
+
+func TestAggregateCallsUpstreamsInParallel(t *testing.T) {
+	// Each upstream answers only once both were called, so sequential calls
+	// would time out
+	arrived := make(chan struct{}, 2)
+	both := make(chan struct{})
+	go func() {
+		<-arrived
+		<-arrived
+		close(both)
+	}()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		select {
+		case <-both:
+		case <-r.Context().Done():
+			return
+		}
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/users/"):
+			w.Write([]byte(`{"id": "` + strings.TrimPrefix(r.URL.Path, "/users/") + `", "name": "Ada"}`))
+		case r.URL.Path == "/orders":
+			w.Write([]byte(`{"count": 3}`))
+		}
+	}))
+	defer backend.Close()
+
+	rm := newAggregateManager(t, &types.AggregateConfig{
+		Calls: []types.UpstreamCall{
+			{Name: "user", URL: backend.URL + "/users/{id}", TimeoutMs: 1000},
+			{Name: "orders", URL: backend.URL + "/orders", TimeoutMs: 1000},
+		},
+		Mapping: map[string]interface{}{
+			"id":     "$.request.params.id",
+			"name":   "$.upstreams.user.body.name",
+			"orders": "$.upstreams.orders.body.count",
+			"status": map[string]interface{}{"user": "$.upstreams.user.statusCode"},
+		},
+	})
+
+	status, body := getJSON(t, rm, "/v1/profiles/42")
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %v", status, body)
+	}
+	expected := map[string]interface{}{
+		"id":     "42",
+		"name":   "Ada",
+		"orders": float64(3),
+		"status": map[string]interface{}{"user": float64(200)},
+	}
+	if !reflect.DeepEqual(body, expected) {
+		t.Errorf("Expected combined body %v, got %v", expected, body)
+	}
+}
+
+func TestAggregateCallTimeout(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Write([]byte(`{"name": "Ada"}`))
+	}))
+	defer backend.Close()
+
+	calls := []types.UpstreamCall{
+		{Name: "user", URL: backend.URL + "/users", TimeoutMs: 1000},
+		{Name: "recommendations", URL: backend.URL + "/slow", TimeoutMs: 50},
+	}
+	mapping := map[string]interface{}{"name": "$.upstreams.user.body.name"}
+
+	start := time.Now()
+	status, body := getJSON(t, newAggregateManager(t, &types.AggregateConfig{Calls: calls, Mapping: mapping}), "/v1/profiles/42")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the call to time out after 50ms, took %v", elapsed)
+	}
+	if status != http.StatusBadGateway || body["error"] != "Upstream call failed" {
+		t.Fatalf("Expected a timed out required call to fail the route, got %d %v", status, body)
+	}
+	if details, _ := body["details"].(string); !strings.HasPrefix(details, "recommendations: request failed") {
+		t.Errorf("Expected the details to name the timed out call, got %q", details)
+	}
+
+	// A timed out optional call leaves the other responses
+	calls[1].Optional = true
+	mapping["recommendations"] = "$.upstreams.recommendations.error"
+	status, body = getJSON(t, newAggregateManager(t, &types.AggregateConfig{Calls: calls, Mapping: mapping}), "/v1/profiles/42")
+	if status != http.StatusOK || body["name"] != "Ada" {
+		t.Fatalf("Expected the optional call to be skipped, got %d %v", status, body)
+	}
+	if message, _ := body["recommendations"].(string); !strings.Contains(message, "deadline exceeded") {
+		t.Errorf("Expected the mapping to see the timeout, got %q", message)
+	}
+}
+
+func TestAggregateRequiredUpstreamFailure(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/billing" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "ledger unavailable"}`))
+			return
+		}
+		w.Write([]byte(`{"name": "Ada"}`))
+	}))
+	defer backend.Close()
+
+	calls := []types.UpstreamCall{
+		{Name: "user", URL: backend.URL + "/users"},
+		{Name: "billing", URL: backend.URL + "/billing"},
+	}
+	mapping := map[string]interface{}{
+		"name":    "$.upstreams.user.body.name",
+		"billing": "$.upstreams.billing.statusCode",
+	}
+
+	status, body := getJSON(t, newAggregateManager(t, &types.AggregateConfig{Calls: calls, Mapping: mapping}), "/v1/profiles/42")
+	if status != http.StatusBadGateway || body["error"] != "Upstream call failed" {
+		t.Fatalf("Expected a failed required call to fail the route, got %d %v", status, body)
+	}
+	if body["details"] != "billing: upstream returned status 500" {
+		t.Errorf("Expected the details to name the failed call, got %v", body["details"])
+	}
+
+	calls[1].Optional = true
+	status, body = getJSON(t, newAggregateManager(t, &types.AggregateConfig{Calls: calls, Mapping: mapping}), "/v1/profiles/42")
+	expected := map[string]interface{}{"name": "Ada", "billing": float64(500)}
+	if status != http.StatusOK || !reflect.DeepEqual(body, expected) {
+		t.Errorf("Expected a failed optional call to be mapped, got %d %v", status, body)
+	}
+}
+
+func TestSagaCompensatesInReverseOrder(t *testing.T) {
+	var mu sync.Mutex
+	var called []string
+	released := make(chan string, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		called = append(called, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/reservations":
+			w.Write([]byte(`{"reservationId": "r-1"}`))
+		case "/release":
+			released <- string(body)
+		case "/shipments":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer backend.Close()
+
+	compensation := func(name, path string) *types.UpstreamCall {
+		return &types.UpstreamCall{Name: name, Method: http.MethodPost, URL: backend.URL + path}
+	}
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName: "/v1/items",
+		Method:    "POST",
+		Handler:   types.HandlerAggregate,
+		Aggregate: &types.AggregateConfig{
+			Mode: types.AggregateModeSaga,
+			Calls: []types.UpstreamCall{
+				{Name: "reserve", Method: http.MethodPost, URL: backend.URL + "/reservations", Compensation: compensation("release", "/release")},
+				{Name: "charge", Method: http.MethodPost, URL: backend.URL + "/payments", Compensation: compensation("refund", "/refunds")},
+				{Name: "ship", Method: http.MethodPost, URL: backend.URL + "/shipments", Compensation: compensation("recall", "/recalls")},
+			},
+			Mapping: map[string]interface{}{"reservation": "$.upstreams.reserve.body.reservationId"},
+		},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	recorder := serve(rm, `{"serviceId": "svc-1"}`)
+	if recorder.Code != http.StatusBadGateway {
+		t.Fatalf("Expected the failed saga to answer 502, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	expected := []string{"POST /reservations", "POST /payments", "POST /shipments", "POST /refunds", "POST /release"}
+	mu.Lock()
+	if !reflect.DeepEqual(called, expected) {
+		t.Errorf("Expected compensations in reverse order %v, got %v", expected, called)
+	}
+	mu.Unlock()
+	if body := <-released; !strings.Contains(body, `"reservationId":"r-1"`) {
+		t.Errorf("Expected the compensation to receive the step response, got %s", body)
+	}
+
+	// The operation served by /v1/operations/:operationId records the outcome
+	operation, found := rm.GetOperations().Get(recorder.Header().Get("X-Operation-ID"))
+	if !found {
+		t.Fatalf("Expected an operation, got header %q", recorder.Header().Get("X-Operation-ID"))
+	}
+	if operation.Status != types.OperationCompensated || !strings.Contains(operation.Error, "ship: upstream returned status 503") {
+		t.Errorf("Expected a compensated operation naming the failed step, got %s %q", operation.Status, operation.Error)
+	}
+	steps := []types.OperationStep{
+		{Name: "reserve", Status: types.OperationCompensated},
+		{Name: "charge", Status: types.OperationCompensated},
+		{Name: "ship", Status: types.OperationFailed, Error: "upstream returned status 503"},
+	}
+	if !reflect.DeepEqual(operation.Steps, steps) {
+		t.Errorf("Expected steps %+v, got %+v", steps, operation.Steps)
+	}
+}
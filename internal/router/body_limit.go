@@ -0,0 +1,72 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"dynamiccontrol/internal/types"
+)
+
+// DefaultMaxBodyBytes bounds request bodies of routes without maxBodyBytes
+const DefaultMaxBodyBytes = 1 << 20
+
+// SetMaxBodyBytes sets the default bound of request bodies; routes with
+// maxBodyBytes override it. It applies to routes registered afterwards.
+func (rm *RouteManager) SetMaxBodyBytes(limit int64) {
+	if limit > 0 {
+		rm.maxBodyBytes = limit
+	}
+}
+
+// validateBodyLimit checks the body limit of a route at registration time
+func validateBodyLimit(route types.RouteConfig) error {
+	if route.MaxBodyBytes < 0 {
+		return fmt.Errorf("maxBodyBytes must not be negative")
+	}
+	return nil
+}
+
+// limitBody rejects requests whose body exceeds the route's limit with 413
+// Request Entity Too Large. Requests declaring a larger Content-Length are
+// rejected before their body is read; other bodies fail once the limit is
+// read past, before JSON binding.
+func (rm *RouteManager) limitBody(route types.RouteConfig) func(next http.Handler) http.Handler {
+	limit := route.MaxBodyBytes
+	if limit == 0 {
+		limit = rm.maxBodyBytes
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				writeBodyTooLarge(w, limit)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bodyTooLarge reports whether reading a request body failed on its limit
+func bodyTooLarge(err error) (int64, bool) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return tooLarge.Limit, true
+	}
+	return 0, false
+}
+
+// bodyTooLargeError is the stage error of a request body exceeding limit
+func bodyTooLargeError(limit int64) *StageError {
+	return stageError(http.StatusRequestEntityTooLarge, "Request body too large", fmt.Sprintf("request bodies are limited to %d bytes", limit))
+}
+
+// writeBodyTooLarge writes the 413 response of a request body exceeding limit
+func writeBodyTooLarge(w http.ResponseWriter, limit int64) {
+	failure := bodyTooLargeError(limit)
+	writeJSON(w, failure.Status, map[string]interface{}{
+		"error":   failure.Message,
+		"details": failure.Details,
+	})
+}
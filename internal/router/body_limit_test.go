@@ -0,0 +1,63 @@
+package router
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyLimitRejectsOversizedPayloads(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	rm.SetMaxBodyBytes(64)
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/small", Method: "POST"},
+		{RouteName: "/v1/large", Method: "POST", MaxBodyBytes: 1024},
+	}})
+
+	body := `{"payload": "` + strings.Repeat("x", 100) + `"}`
+	post := func(path string, reader io.Reader, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, reader)
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	if recorder := post("/v1/small", strings.NewReader(body), ""); recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected a declared oversized body to be rejected, got %d", recorder.Code)
+	}
+	// Without a Content-Length the body is rejected once the limit is read past
+	if recorder := post("/v1/small", io.MultiReader(strings.NewReader(body)), ""); recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected a streamed oversized body to be rejected, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := post("/v1/small", io.MultiReader(strings.NewReader(body)), "retry-1"); recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected an oversized idempotent body to be rejected, got %d", recorder.Code)
+	}
+	if recorder := post("/v1/large", strings.NewReader(body), ""); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the route limit to override the default, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := post("/v1/small", strings.NewReader(`{"payload": "x"}`), ""); recorder.Code != http.StatusOK {
+		t.Errorf("Expected a small body to be served, got %d", recorder.Code)
+	}
+}
+
+func TestBodyLimitRejectsNegativeLimit(t *testing.T) {
+	if err := validateBodyLimit(types.RouteConfig{MaxBodyBytes: -1}); err == nil {
+		t.Error("Expected a negative maxBodyBytes to be rejected")
+	}
+}
@@ -0,0 +1,341 @@
+package router
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/responsecache"
+	"dynamiccontrol/internal/rollout"
+	"dynamiccontrol/internal/types"
+)
+
+// DefaultCanaryHeader lets clients pick the canary ("true") or stable
+// ("false") configuration
+const DefaultCanaryHeader = "X-Canary"
+
+// canaryCheckInterval is how often a running canary is judged
+const canaryCheckInterval = time.Second
+
+// CanaryConfig configures staged rollouts of route configuration changes.
+// A new configuration first serves Percent of the requests, chosen by the
+// hash of HashHeader or of the client IP; requests can pick a side with
+// Header. Once the canary served MinRequests with an error rate above
+// MaxErrorRate it is rolled back; once it baked for BakeTime it replaces the
+// active configuration, automatically or on operator approval.
+type CanaryConfig struct {
+	Percent      float64       `json:"percent"`
+	Header       string        `json:"header"`
+	HashHeader   string        `json:"hashHeader,omitempty"`
+	BakeTime     time.Duration `json:"bakeTime"`
+	MaxErrorRate float64       `json:"maxErrorRate"`
+	MinRequests  int64         `json:"minRequests"`
+	AutoPromote  bool          `json:"autoPromote"`
+}
+
+// CanaryStatus describes the current or last canary rollout. States are
+// those of rollout rings.
+type CanaryStatus struct {
+	State     string     `json:"state"`
+	Percent   float64    `json:"percent"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	Routes    int        `json:"routes"`
+	Requests  int64      `json:"requests"`
+	Errors    int64      `json:"errors"`
+	ErrorRate float64    `json:"errorRate"`
+	Reason    string     `json:"reason,omitempty"`
+}
+
+// canaryRollout is a candidate configuration compiled by a route manager of
+// its own, which serves the requests selected for the canary
+type canaryRollout struct {
+	config   *types.RoutesConfig
+	manager  *RouteManager
+	settings CanaryConfig
+	started  time.Time
+	stop     chan struct{}
+}
+
+// SetCanary enables canary rollouts: configuration changes from stores are
+// applied to a share of the traffic first. Unset settings take the defaults
+// of rollout rings.
+func (rm *RouteManager) SetCanary(settings CanaryConfig) {
+	if settings.Percent <= 0 || settings.Percent > 100 {
+		settings.Percent = 10
+	}
+	if settings.Header == "" {
+		settings.Header = DefaultCanaryHeader
+	}
+	if settings.BakeTime <= 0 {
+		settings.BakeTime = rollout.DefaultBakeTime
+	}
+	if settings.MaxErrorRate <= 0 {
+		settings.MaxErrorRate = rollout.DefaultMaxErrorRate
+	}
+	if settings.MinRequests <= 0 {
+		settings.MinRequests = rollout.DefaultMinRequests
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.canarySettings = &settings
+	if rm.canaryState == "" {
+		rm.canaryState = rollout.StateIdle
+	}
+}
+
+// CanarySettings returns the canary rollout settings, or false when canary
+// rollouts are disabled
+func (rm *RouteManager) CanarySettings() (CanaryConfig, bool) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	if rm.canarySettings == nil {
+		return CanaryConfig{}, false
+	}
+	return *rm.canarySettings, true
+}
+
+// StartCanary compiles a candidate configuration and serves the canary
+// share of requests from it, replacing any running canary. Policies are
+// shared with the active configuration.
+func (rm *RouteManager) StartCanary(config *types.RoutesConfig) error {
+	settings, enabled := rm.CanarySettings()
+	if !enabled {
+		return fmt.Errorf("canary rollouts are not enabled")
+	}
+
+	candidate := rm.fork()
+	if err := candidate.ApplyConfig(config); err != nil {
+		candidate.Stop()
+		return err
+	}
+	canary := &canaryRollout{
+		config:   config,
+		manager:  candidate,
+		settings: settings,
+		started:  time.Now(),
+		stop:     make(chan struct{}),
+	}
+
+	rm.mu.Lock()
+	previous := rm.canary
+	rm.canary = canary
+	rm.canaryState = rollout.StateProgressing
+	rm.canaryReason = ""
+	rm.mu.Unlock()
+	if previous != nil {
+		previous.end()
+	}
+
+	slog.Info("Canary rollout started", "routes", len(config.Routes), "percent", settings.Percent)
+	go rm.watchCanary(canary)
+	return nil
+}
+
+// PromoteCanary applies the canary configuration to all traffic
+func (rm *RouteManager) PromoteCanary() error {
+	rm.mu.RLock()
+	canary := rm.canary
+	rm.mu.RUnlock()
+	if canary == nil {
+		return fmt.Errorf("no canary rollout in progress")
+	}
+	return rm.promoteCanary(canary)
+}
+
+// promoteCanary applies a canary's configuration to all traffic
+func (rm *RouteManager) promoteCanary(canary *canaryRollout) error {
+	if err := rm.ApplyConfig(canary.config); err != nil {
+		return err
+	}
+	rm.finishCanary(canary, rollout.StateIdle, "")
+	slog.Info("Canary rollout promoted", "routes", len(canary.config.Routes))
+	return nil
+}
+
+// AbortCanary returns all traffic to the active configuration
+func (rm *RouteManager) AbortCanary(reason string) error {
+	rm.mu.RLock()
+	canary := rm.canary
+	rm.mu.RUnlock()
+	if canary == nil {
+		return fmt.Errorf("no canary rollout in progress")
+	}
+
+	rm.finishCanary(canary, rollout.StateAborted, reason)
+	slog.Warn("Canary rollout aborted", "reason", reason)
+	return nil
+}
+
+// CanaryStatus reports the progress of the current or last canary rollout
+func (rm *RouteManager) CanaryStatus() CanaryStatus {
+	rm.mu.RLock()
+	canary := rm.canary
+	status := CanaryStatus{State: rm.canaryState, Reason: rm.canaryReason}
+	if rm.canarySettings != nil {
+		status.Percent = rm.canarySettings.Percent
+	}
+	rm.mu.RUnlock()
+
+	if canary != nil {
+		started := canary.started
+		status.StartedAt = &started
+		status.Routes = len(canary.config.Routes)
+		status.Requests, status.Errors = canary.manager.TrafficSinceApply()
+		if status.Requests > 0 {
+			status.ErrorRate = float64(status.Errors) / float64(status.Requests)
+		}
+	}
+	return status
+}
+
+// finishCanary ends a canary rollout unless another one replaced it
+func (rm *RouteManager) finishCanary(canary *canaryRollout, state, reason string) {
+	rm.mu.Lock()
+	if rm.canary != canary {
+		rm.mu.Unlock()
+		return
+	}
+	rm.canary = nil
+	rm.canaryState = state
+	rm.canaryReason = reason
+	rm.mu.Unlock()
+	canary.end()
+}
+
+// end stops judging the canary and releases its compiled routes
+func (cr *canaryRollout) end() {
+	close(cr.stop)
+	cr.manager.Stop()
+}
+
+// watchCanary judges a canary until it ends
+func (rm *RouteManager) watchCanary(canary *canaryRollout) {
+	ticker := time.NewTicker(canaryCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-canary.stop:
+			return
+		case now := <-ticker.C:
+			rm.checkCanary(canary, now)
+		}
+	}
+}
+
+// checkCanary rolls back a canary whose error rate is too high and promotes
+// one that baked long enough, or waits for approval to promote it
+func (rm *RouteManager) checkCanary(canary *canaryRollout, now time.Time) {
+	requests, errors := canary.manager.TrafficSinceApply()
+	if requests < canary.settings.MinRequests {
+		return
+	}
+
+	details := map[string]interface{}{"requests": requests, "errors": errors}
+	if rate := float64(errors) / float64(requests); rate > canary.settings.MaxErrorRate {
+		reason := fmt.Sprintf("error rate %.3f exceeded %.3f", rate, canary.settings.MaxErrorRate)
+		rm.finishCanary(canary, rollout.StateAborted, reason)
+		slog.Warn("Canary rollout rolled back", "reason", reason)
+		details["reason"] = reason
+		rm.audit.Record(types.AuditEntry{Actor: "canary", Action: "canary.rollback", Resource: "routes", Details: details})
+		return
+	}
+	if now.Sub(canary.started) < canary.settings.BakeTime {
+		return
+	}
+	if !canary.settings.AutoPromote {
+		rm.mu.Lock()
+		if rm.canary == canary {
+			rm.canaryState = rollout.StateAwaitingApproval
+		}
+		rm.mu.Unlock()
+		return
+	}
+	if err := rm.promoteCanary(canary); err != nil {
+		rm.finishCanary(canary, rollout.StateAborted, err.Error())
+		slog.Error("Failed to promote canary rollout", "error", err)
+		return
+	}
+	rm.audit.Record(types.AuditEntry{Actor: "canary", Action: "canary.promote", Resource: "routes", Details: details})
+}
+
+// selects reports whether a request is served by the canary. The canary
+// header decides when set; otherwise the hash of the hash header or client
+// IP keeps each client on one side.
+func (cr *canaryRollout) selects(r *http.Request, clientIP string) bool {
+	switch strings.ToLower(r.Header.Get(cr.settings.Header)) {
+	case "true", "canary":
+		return true
+	case "false", "stable":
+		return false
+	}
+
+	key := clientIP
+	if cr.settings.HashHeader != "" {
+		if value := r.Header.Get(cr.settings.HashHeader); value != "" {
+			key = value
+		}
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return float64(hash.Sum32()%10000) < cr.settings.Percent*100
+}
+
+// fork creates a route manager for a candidate configuration. It shares the
+// policies, upstream client, limiter, side effects, event broker and logs of
+// rm and compiles lazily when rm does, but compiles routes into balancers,
+// templates and caches of its own, so the candidate cannot disturb the
+// active routes.
+func (rm *RouteManager) fork() *RouteManager {
+	fork := NewRouteManager(rm.policyManager, rm.schemaValidator)
+	fork.mockData = rm.mockData
+	fork.upstreamClient = rm.upstreamClient
+	fork.operations = rm.operations
+	fork.emitter = rm.emitter
+	fork.broker = rm.broker
+	fork.lazy = rm.lazy
+	fork.extraStages = rm.extraStages
+	fork.decisions = rm.decisions
+	fork.samples = rm.samples
+	fork.audit = rm.audit
+	fork.chaos = rm.chaos
+	fork.assertions = rm.assertions
+	fork.dependencies = rm.dependencies
+	fork.schemaProfilePercent = rm.schemaProfilePercent
+	fork.responseSamplePercent = rm.responseSamplePercent
+	fork.sampleWorkers = rm.sampleWorkers
+	fork.evaluator = rm.evaluator
+	fork.responseCache = responsecache.New(responsecache.DefaultCapacity)
+	fork.idempotency = rm.idempotency
+	fork.maxBodyBytes = rm.maxBodyBytes
+	fork.codecs = rm.codecs
+	fork.limiter = rm.limiter
+	fork.sideEffects = rm.sideEffects
+	fork.enforceSunset = rm.enforceSunset
+	fork.tenantHeader = rm.tenantHeader
+	fork.variables = rm.variables
+	fork.reconciler = rm.reconciler
+	fork.registry = rm.registry
+	fork.traffic = rm.traffic
+	fork.quotas = rm.quotas
+	// Candidates become versions once promoted
+	fork.history = nil
+	return fork
+}
+
+// serveCanary serves a request from the canary when one is running and
+// selects it, marking the response with the canary header
+func (rm *RouteManager) serveCanary(w ResponseWriter, r *http.Request, clientIP string) bool {
+	rm.mu.RLock()
+	canary := rm.canary
+	rm.mu.RUnlock()
+	if canary == nil || !canary.selects(r, clientIP) {
+		return false
+	}
+	w.Header().Set(canary.settings.Header, "true")
+	canary.manager.serve(w, r)
+	return true
+}
@@ -0,0 +1,190 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/chaos"
+	"dynamiccontrol/internal/identity"
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/reconcile"
+	"dynamiccontrol/internal/registry"
+	"dynamiccontrol/internal/rollout"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCanaryRollout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := func(status int, body string) *types.RoutesConfig {
+		return &types.RoutesConfig{Routes: []types.RouteConfig{{
+			RouteName:    "/v1/status",
+			Method:       "GET",
+			MockResponse: &types.MockResponseConfig{StatusCode: status, Template: body},
+		}}}
+	}
+
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	if err := rm.ApplyConfig(config(http.StatusOK, `{"version": 1}`)); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	if err := rm.StartCanary(config(http.StatusOK, `{"version": 2}`)); err == nil {
+		t.Fatal("Expected canaries to require canary settings")
+	}
+	rm.SetCanary(CanaryConfig{Percent: 50, HashHeader: "X-User-ID", MinRequests: 2, MaxErrorRate: 0.5, BakeTime: time.Minute})
+
+	get := func(header, value string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder.Code, recorder.Body.String()
+	}
+
+	// A healthy canary is promoted once it baked
+	if err := rm.StartCanary(config(http.StatusOK, `{"version": 2}`)); err != nil {
+		t.Fatalf("Failed to start canary: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, body := get("X-Canary", "true"); body != `{"version":2}` {
+			t.Errorf("Expected the canary to serve forced requests, got %s", body)
+		}
+	}
+	if _, body := get("X-Canary", "false"); body != `{"version":1}` {
+		t.Errorf("Expected the stable configuration to serve opted out requests, got %s", body)
+	}
+	// Requests of one user stay on one side
+	_, first := get("X-User-ID", "user-42")
+	for i := 0; i < 5; i++ {
+		if _, body := get("X-User-ID", "user-42"); body != first {
+			t.Fatalf("Expected sticky canary selection, got %s then %s", first, body)
+		}
+	}
+
+	rm.mu.RLock()
+	canary := rm.canary
+	rm.mu.RUnlock()
+	rm.checkCanary(canary, canary.started.Add(time.Second))
+	if state := rm.CanaryStatus().State; state != rollout.StateProgressing {
+		t.Errorf("Expected the canary to bake, got state %s", state)
+	}
+	rm.checkCanary(canary, canary.started.Add(2*time.Minute))
+	if state := rm.CanaryStatus().State; state != rollout.StateAwaitingApproval {
+		t.Errorf("Expected the canary to await approval, got state %s", state)
+	}
+	if err := rm.PromoteCanary(); err != nil {
+		t.Fatalf("Failed to promote canary: %v", err)
+	}
+	if _, body := get("", ""); body != `{"version":2}` {
+		t.Errorf("Expected the promoted configuration to serve all requests, got %s", body)
+	}
+
+	// A failing canary is rolled back
+	if err := rm.StartCanary(config(http.StatusInternalServerError, `{"version": 3}`)); err != nil {
+		t.Fatalf("Failed to start canary: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if code, _ := get("X-Canary", "true"); code != http.StatusInternalServerError {
+			t.Fatalf("Expected the canary to fail, got %d", code)
+		}
+	}
+	rm.mu.RLock()
+	canary = rm.canary
+	rm.mu.RUnlock()
+	rm.checkCanary(canary, canary.started)
+	status := rm.CanaryStatus()
+	if status.State != rollout.StateAborted || status.Reason == "" {
+		t.Errorf("Expected the canary to be rolled back, got %+v", status)
+	}
+	if _, body := get("X-Canary", "true"); body != `{"version":2}` {
+		t.Errorf("Expected the active configuration to serve after rollback, got %s", body)
+	}
+	if rm.GetConfig().Routes[0].MockResponse.Template != `{"version": 2}` {
+		t.Error("Expected the rolled back canary to leave the active configuration")
+	}
+}
+
+// forkOwnFields are the route manager fields a canary fork does not share,
+// because they hold compiled routes or the state of one configuration
+var forkOwnFields = map[string]bool{
+	"mu": true, "applyMu": true, "reloadMu": true, "config": true, "table": true,
+	"balancers": true, "mockTemplates": true, "firstTraffic": true, "lazyRouter": true,
+	"applyListeners": true, "applied": true, "cors": true, "guard": true, "routeIndex": true,
+	"responseCache": true, "learners": true, "tenants": true, "canarySettings": true,
+	"canary": true, "canaryState": true, "canaryReason": true, "history": true,
+	"runtime": true, "served": true, "failed": true,
+}
+
+// sameField reports whether two values of a field hold the same value, or
+// point to the same instance
+func sameField(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Chan, reflect.Map, reflect.Slice, reflect.Func:
+		return a.Pointer() == b.Pointer()
+	case reflect.Interface:
+		return !a.IsNil() && !b.IsNil() && a.Elem().Type() == b.Elem().Type() && sameField(a.Elem(), b.Elem())
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.String:
+		return a.String() == b.String()
+	}
+	return false
+}
+
+func TestForkSharesManagerState(t *testing.T) {
+	signer, err := identity.NewSigner([]byte("0123456789abcdef0123456789abcdef"), identity.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	// Every shared field gets a value a new manager would not have
+	rm.SetLazy(true)
+	rm.SetChaos(chaos.NewInjector())
+	rm.SetIdentitySigner(signer)
+	rm.SetPolicyEvaluator(opa.NewPolicyManager())
+	rm.SetReconciler(reconcile.New(reconcile.Config{Interval: time.Minute}, rm.TrafficIntents))
+	rm.SetRegistry(registry.New(registry.Config{}))
+	rm.SetSchemaProfiling(5)
+	rm.SetResponseSampling(10)
+	rm.SetMaxBodyBytes(1024)
+	rm.SetEnforcePolicySunset(true)
+	rm.SetTenantHeader("X-Team")
+
+	fork := rm.fork()
+	defer fork.Stop()
+
+	original, forked := reflect.ValueOf(rm).Elem(), reflect.ValueOf(fork).Elem()
+	for i := 0; i < original.NumField(); i++ {
+		name := original.Type().Field(i).Name
+		if forkOwnFields[name] {
+			continue
+		}
+		if original.Field(i).IsZero() {
+			t.Errorf("Expected %s to be set on the original manager", name)
+			continue
+		}
+		if !sameField(original.Field(i), forked.Field(i)) {
+			t.Errorf("Expected the fork to share %s", name)
+		}
+	}
+
+	// The fork keeps its own response cache and version history
+	if fork.responseCache == rm.responseCache || fork.history != nil {
+		t.Error("Expected the fork to keep its own response cache and no history")
+	}
+}
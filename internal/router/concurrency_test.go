@@ -0,0 +1,112 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reloadConfig returns a configuration whose mock response and policy
+// change with the revision, so every apply recompiles the routes
+func reloadConfig(revision int) *types.RoutesConfig {
+	return &types.RoutesConfig{Routes: []types.RouteConfig{
+		{
+			RouteName: "/v1/items",
+			Method:    "POST",
+			Policies:  []string{"allow_post"},
+			MockResponse: &types.MockResponseConfig{
+				Template: fmt.Sprintf(`{"revision": %d}`, revision),
+			},
+		},
+		{
+			RouteName:      "/v1/items/:itemId",
+			Method:         "GET",
+			DependsOn:      []string{"POST /v1/items"},
+			RequestSchema:  map[string]interface{}{"type": "object"},
+			ResponseSchema: map[string]interface{}{"type": "object"},
+		},
+	}}
+}
+
+// TestConcurrentReloadWhileServing applies configurations, policies and
+// pipeline stages while requests are served; run with -race
+func TestConcurrentReloadWhileServing(t *testing.T) {
+	for _, lazy := range []bool{false, true} {
+		t.Run(fmt.Sprintf("lazy=%v", lazy), func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			policyManager := opa.NewPolicyManager()
+			if err := policyManager.ReplacePolicies(map[string]string{"allow_post": allowPostPolicy}); err != nil {
+				t.Fatalf("Failed to load policy: %v", err)
+			}
+			rm := NewRouteManager(policyManager, validator.NewSchemaValidator())
+			rm.SetLazy(lazy)
+			defer rm.Stop()
+			engine := gin.New()
+			engine.NoRoute(rm.Dispatch)
+			if err := rm.ApplyConfig(reloadConfig(0)); err != nil {
+				t.Fatalf("Failed to apply config: %v", err)
+			}
+
+			var wg sync.WaitGroup
+			for worker := 0; worker < 4; worker++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 50; i++ {
+						for _, req := range []*http.Request{
+							httptest.NewRequest(http.MethodPost, "/v1/items", strings.NewReader(`{"serviceId": "a"}`)),
+							httptest.NewRequest(http.MethodGet, "/v1/items/1", nil),
+						} {
+							recorder := httptest.NewRecorder()
+							engine.ServeHTTP(recorder, req)
+							if recorder.Code >= http.StatusInternalServerError && recorder.Code != http.StatusServiceUnavailable {
+								t.Errorf("Unexpected status %d: %s", recorder.Code, recorder.Body.String())
+							}
+						}
+					}
+				}()
+			}
+
+			wg.Add(3)
+			go func() {
+				defer wg.Done()
+				for revision := 1; revision <= 20; revision++ {
+					if err := rm.ApplyConfig(reloadConfig(revision)); err != nil {
+						t.Errorf("Failed to apply config: %v", err)
+					}
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 20; i++ {
+					if err := policyManager.SetPolicy("allow_post", allowPostPolicy); err != nil {
+						t.Errorf("Failed to set policy: %v", err)
+					}
+					policyManager.SetDocument("revision", i)
+					rm.AddStage(StageEnrich, NewStage(fmt.Sprintf("noop-%d", i), func(ex *Exchange) error { return nil }))
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 20; i++ {
+					rm.GetConfig()
+					rm.GetUpstreamStatus()
+					rm.DependencyStates()
+					rm.PendingCompilations()
+					policyManager.ListLoadedPolicies()
+					policyManager.PolicySources()
+				}
+			}()
+			wg.Wait()
+		})
+	}
+}
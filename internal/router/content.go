@@ -0,0 +1,83 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dynamiccontrol/internal/codec"
+	"dynamiccontrol/internal/types"
+)
+
+// RegisterCodec adds a codec that routes can name in contentTypes, for its
+// content type and any alias media types. Codecs must be registered before
+// the routes naming them are applied.
+func (rm *RouteManager) RegisterCodec(c codec.Codec, aliases ...string) {
+	rm.codecs.Register(c, aliases...)
+}
+
+// GetCodecs returns the registry of the codecs routes can name
+func (rm *RouteManager) GetCodecs() *codec.Registry {
+	return rm.codecs
+}
+
+// validateContentTypes checks that a route names registered codecs. Proxy
+// routes forward the content types of their upstreams unchanged.
+func (rm *RouteManager) validateContentTypes(route types.RouteConfig) error {
+	if len(route.ContentTypes) == 0 {
+		return nil
+	}
+	if route.Handler == types.HandlerProxy {
+		return fmt.Errorf("contentTypes are not supported by the proxy handler")
+	}
+	for _, contentType := range route.ContentTypes {
+		if _, ok := rm.codecs.Lookup(contentType); !ok {
+			return fmt.Errorf("no codec is registered for content type %q", contentType)
+		}
+	}
+	return nil
+}
+
+// negotiateResponse picks the codec encoding the response of a route with
+// contentTypes from the Accept header. Requests accepting none of them are
+// rejected with 406 before they are executed.
+func (rm *RouteManager) negotiateResponse(ex *Exchange) error {
+	offered := ex.Route.ContentTypes
+	encoder, ok := rm.codecs.Negotiate(ex.Request.Header.Get("Accept"), offered)
+	if !ok {
+		return stageError(http.StatusNotAcceptable, "Not acceptable", fmt.Sprintf("responses are available as %s", strings.Join(offered, ", ")))
+	}
+	ex.encoder = encoder
+	return nil
+}
+
+// requestCodec returns the codec decoding the request body of a route by its
+// Content-Type header, or JSON on routes without contentTypes. Request bodies
+// without a Content-Type are decoded with the route's default codec, and
+// bodies of other media types are rejected with 415.
+func (rm *RouteManager) requestCodec(ex *Exchange) (codec.Codec, error) {
+	offered := ex.Route.ContentTypes
+	if len(offered) == 0 {
+		decoder, _ := rm.codecs.Lookup(codec.JSON)
+		return decoder, nil
+	}
+	contentType := ex.Request.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = offered[0]
+	}
+	decoder, ok := rm.codecs.Lookup(contentType)
+	if !ok || !offersCodec(rm.codecs, offered, decoder) {
+		return nil, stageError(http.StatusUnsupportedMediaType, "Unsupported media type", fmt.Sprintf("request bodies are accepted as %s", strings.Join(offered, ", ")))
+	}
+	return decoder, nil
+}
+
+// offersCodec reports whether one of the offered media types uses a codec
+func offersCodec(codecs *codec.Registry, offered []string, c codec.Codec) bool {
+	for _, mediaType := range offered {
+		if candidate, ok := codecs.Lookup(mediaType); ok && candidate.ContentType() == c.ContentType() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dynamiccontrol/internal/codec"
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestContentNegotiationDecodesAndRendersCodecs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{
+		{
+			RouteName:    "/v1/services/:serviceId/traffic",
+			Method:       "POST",
+			Handler:      types.HandlerTraffic,
+			ContentTypes: []string{codec.JSON, codec.XML, codec.Protobuf},
+			RequestSchema: map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"volume"},
+				"properties": map[string]interface{}{
+					"volume": map[string]interface{}{"type": "number", "minimum": 0},
+				},
+			},
+		},
+	}})
+
+	post := func(contentType, accept, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/services/billing/traffic", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("Accept", accept)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// XML values are converted to the types of the request schema
+	recorder := post("application/xml", "application/xml", `<traffic><trafficType>incoming</trafficType><volume>5</volume><priority>low</priority></traffic>`)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected an XML request to be served, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder.Header().Get("Content-Type") != codec.XML || !strings.Contains(recorder.Body.String(), "<serviceId>billing</serviceId>") {
+		t.Errorf("Expected an XML response, got %s: %s", recorder.Header().Get("Content-Type"), recorder.Body.String())
+	}
+	if rules := rm.GetTrafficController().Rules("billing"); len(rules) != 1 || rules[0].Volume != 5 {
+		t.Errorf("Expected the XML request to store a rule, got %v", rules)
+	}
+
+	if recorder := post("application/xml", "", `<traffic><volume>-1</volume></traffic>`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid XML request to fail validation, got %d", recorder.Code)
+	}
+	if recorder := post("text/plain", "", "volume=5"); recorder.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected an unsupported media type to be rejected, got %d", recorder.Code)
+	}
+	if recorder := post(codec.JSON, "text/html", `{"trafficType": "incoming", "volume": 1, "priority": "low"}`); recorder.Code != http.StatusNotAcceptable {
+		t.Errorf("Expected an unacceptable response type to be rejected, got %d", recorder.Code)
+	}
+	if rules := rm.GetTrafficController().Rules("billing"); len(rules) != 1 {
+		t.Errorf("Expected rejected requests not to store rules, got %d", len(rules))
+	}
+
+	protobuf, _ := rm.GetCodecs().Lookup(codec.Protobuf)
+	body, _ := protobuf.Encode(map[string]interface{}{"trafficType": "outgoing", "volume": 2, "priority": "high"})
+	recorder = post(codec.Protobuf, codec.Protobuf, string(body))
+	if recorder.Code != http.StatusCreated || recorder.Header().Get("Content-Type") != codec.Protobuf {
+		t.Fatalf("Expected a protobuf request to be served, got %d", recorder.Code)
+	}
+	response, err := protobuf.Decode(recorder.Body.Bytes())
+	if document, ok := response.(map[string]interface{}); err != nil || !ok || document["serviceId"] != "billing" {
+		t.Errorf("Unexpected protobuf response %v: %v", response, err)
+	}
+}
+
+func TestContentTypesRequireRegisteredCodecs(t *testing.T) {
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	route := types.RouteConfig{RouteName: "/v1/items", Method: "GET", ContentTypes: []string{"application/yaml"}}
+	if err := rm.validateContentTypes(route); err == nil {
+		t.Error("Expected an unregistered content type to be rejected")
+	}
+	route.ContentTypes = []string{codec.JSON}
+	route.Handler = types.HandlerProxy
+	if err := rm.validateContentTypes(route); err == nil {
+		t.Error("Expected content types on a proxy route to be rejected")
+	}
+}
@@ -0,0 +1,64 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+
+	"dynamiccontrol/internal/cors"
+	"dynamiccontrol/internal/types"
+)
+
+// corsRoute is the compiled CORS policy of a single route
+type corsRoute struct {
+	method  string
+	pattern string
+	policy  *cors.Policy
+}
+
+// compileCORS compiles the CORS policy of every route, applying the global
+// configuration to routes without their own
+func compileCORS(config *types.RoutesConfig) ([]corsRoute, error) {
+	if _, err := cors.Compile(config.CORS); err != nil {
+		return nil, fmt.Errorf("invalid global CORS configuration: %w", err)
+	}
+
+	var routes []corsRoute
+	for _, route := range config.Routes {
+		policy, err := cors.Compile(cors.Effective(config.CORS, route.CORS))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CORS configuration for route %s: %w", routeKey(route), err)
+		}
+		if policy != nil {
+			routes = append(routes, corsRoute{method: route.Method, pattern: route.RouteName, policy: policy})
+		}
+	}
+	return routes, nil
+}
+
+// serveCORS answers preflight requests and sets the CORS headers of actual
+// requests matching a route with a CORS policy. It reports whether the
+// request has been fully handled.
+func serveCORS(w http.ResponseWriter, r *http.Request, routes []corsRoute) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || len(routes) == 0 {
+		return false
+	}
+
+	preflight := cors.IsPreflight(r)
+	method := r.Method
+	if preflight {
+		method = r.Header.Get("Access-Control-Request-Method")
+	}
+	for _, route := range routes {
+		if route.method != method || !matchPattern(route.pattern, r.URL.Path) {
+			continue
+		}
+		if preflight {
+			route.policy.Preflight(w, r)
+			return true
+		}
+		route.policy.Apply(w.Header(), origin)
+		return false
+	}
+	return false
+}
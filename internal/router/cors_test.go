@@ -0,0 +1,67 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDispatchAppliesCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{
+		CORS: &types.CORSConfig{AllowOrigins: []string{"https://app.example.com"}},
+		Routes: []types.RouteConfig{
+			{RouteName: "/v1/items/:id", Method: "GET", MockResponse: &types.MockResponseConfig{Template: `{"id": "{{.Params.id}}"}`}},
+			{RouteName: "/v1/internal", Method: "GET", CORS: &types.CORSConfig{Disabled: true}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/v1/items/1", nil)
+	preflight.Header.Set("Origin", "https://app.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "GET")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, preflight)
+	if recorder.Code != http.StatusNoContent || recorder.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("Expected preflight to be allowed, got %d %v", recorder.Code, recorder.Header())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/items/1", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("Expected CORS headers on the response, got %d %v", recorder.Code, recorder.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/internal", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	if recorder.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected no CORS headers on a route with CORS disabled, got %v", recorder.Header())
+	}
+}
+
+func TestApplyConfigRejectsInvalidCORS(t *testing.T) {
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	err := rm.ApplyConfig(&types.RoutesConfig{
+		Routes: []types.RouteConfig{{RouteName: "/v1/items", Method: "GET", CORS: &types.CORSConfig{}}},
+	})
+	if err == nil {
+		t.Error("Expected route CORS without origins to be rejected")
+	}
+}
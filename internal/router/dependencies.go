@@ -0,0 +1,139 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/transform"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/upstream"
+)
+
+// fallbackSuffix keys the dependency fallback template of a route
+const fallbackSuffix = " fallback"
+
+// DependencyState describes whether a route's dependencies are available
+type DependencyState struct {
+	Route     string   `json:"route"`
+	DependsOn []string `json:"dependsOn"`
+	Available bool     `json:"available"`
+	// Failed is the unavailable dependency, which may be an indirect one
+	Failed   string `json:"failed,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Fallback bool   `json:"fallback"`
+}
+
+// compileFallback parses the template of a route's dependency fallback
+func (rm *RouteManager) compileFallback(route types.RouteConfig) error {
+	if route.DependencyFallback == nil || route.DependencyFallback.Template == "" {
+		return nil
+	}
+	if route.DependencyFallback.Store != nil {
+		return fmt.Errorf("dependency fallback cannot use the mock store")
+	}
+	key := routeKey(route) + fallbackSuffix
+	tmpl, err := transform.ParseTemplate(key, route.DependencyFallback.Template)
+	if err != nil {
+		return fmt.Errorf("invalid dependency fallback: %w", err)
+	}
+	rm.mu.Lock()
+	rm.mockTemplates[key] = tmpl
+	rm.mu.Unlock()
+	return nil
+}
+
+// guardDependencies wraps an executor so that it only runs while every
+// dependency of the route is available. Otherwise the route's fallback is
+// served, or a 503 naming the failed dependency.
+func (rm *RouteManager) guardDependencies(execute func(ex *Exchange) error) func(ex *Exchange) error {
+	return func(ex *Exchange) error {
+		failed, reason, down := rm.dependencyFailure(ex.Route)
+		if !down {
+			return execute(ex)
+		}
+
+		metrics.DependencyFailures.WithLabelValues(ex.Route.RouteName, failed).Inc()
+		logging.FromContext(ex.Request.Context()).Warn("Route dependency unavailable",
+			"route", routeKey(ex.Route), "dependency", failed, "reason", reason)
+		if ex.Route.DependencyFallback != nil {
+			ex.ResponseHeaders["X-Dependency-Fallback"] = failed
+			return rm.renderMock(ex, ex.Route.DependencyFallback, routeKey(ex.Route)+fallbackSuffix)
+		}
+		return stageError(http.StatusServiceUnavailable, fmt.Sprintf("Dependency %s is unavailable", failed), reason)
+	}
+}
+
+// dependencyFailure returns the first unavailable dependency of a route.
+// Dependencies on other routes cascade: such a dependency fails when it is
+// marked down, when all of its upstream targets are unhealthy, or when one
+// of its own dependencies fails.
+func (rm *RouteManager) dependencyFailure(route types.RouteConfig) (string, string, bool) {
+	visited := map[string]bool{routeKey(route): true}
+	return rm.checkDependencies(route.DependsOn, visited)
+}
+
+// checkDependencies checks dependencies depth-first, skipping visited ones so
+// that cyclic declarations terminate
+func (rm *RouteManager) checkDependencies(names []string, visited map[string]bool) (string, string, bool) {
+	for _, name := range names {
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+
+		if outage, down := rm.dependencies.Outage(name); down {
+			return name, outage.Reason, true
+		}
+		rm.mu.RLock()
+		route, isRoute := rm.routeIndex[name]
+		balancer := rm.balancers[name]
+		rm.mu.RUnlock()
+		if !isRoute {
+			continue
+		}
+		if balancer != nil && !anyHealthy(balancer.Status()) {
+			return name, "all upstream targets are unhealthy", true
+		}
+		if failed, reason, down := rm.checkDependencies(route.DependsOn, visited); down {
+			return failed, reason, true
+		}
+	}
+	return "", "", false
+}
+
+// DependencyStates reports the dependency availability of every route that
+// declares dependencies
+func (rm *RouteManager) DependencyStates() []DependencyState {
+	config := rm.GetConfig()
+	if config == nil {
+		return nil
+	}
+	states := []DependencyState{}
+	for _, route := range config.Routes {
+		if len(route.DependsOn) == 0 {
+			continue
+		}
+		failed, reason, down := rm.dependencyFailure(route)
+		states = append(states, DependencyState{
+			Route:     routeKey(route),
+			DependsOn: route.DependsOn,
+			Available: !down,
+			Failed:    failed,
+			Reason:    reason,
+			Fallback:  route.DependencyFallback != nil,
+		})
+	}
+	return states
+}
+
+// anyHealthy reports whether at least one upstream target is healthy
+func anyHealthy(targets []upstream.TargetStatus) bool {
+	for _, target := range targets {
+		if target.Healthy {
+			return true
+		}
+	}
+	return false
+}
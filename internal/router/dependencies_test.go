@@ -0,0 +1,81 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDependencyOutagesCascade(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{
+		Routes: []types.RouteConfig{
+			{RouteName: "/v1/registry", Method: "GET"},
+			{
+				RouteName:          "/v1/services/:id",
+				Method:             "GET",
+				DependsOn:          []string{"GET /v1/registry"},
+				DependencyFallback: &types.MockResponseConfig{StatusCode: 200, Template: `{"id": "{{.Params.id}}", "stale": true}`},
+			},
+			{RouteName: "/v1/traffic", Method: "GET", DependsOn: []string{"GET /v1/services/:id", "billing"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+	if recorder := get("/v1/traffic"); recorder.Code != http.StatusOK {
+		t.Fatalf("Expected route to be served while dependencies are up, got %d", recorder.Code)
+	}
+
+	rm.GetDependencies().MarkDown("GET /v1/registry", "maintenance", "test")
+	recorder := get("/v1/services/a")
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"stale":true`) ||
+		recorder.Header().Get("X-Dependency-Fallback") != "GET /v1/registry" {
+		t.Errorf("Expected the fallback response, got %d %v %s", recorder.Code, recorder.Header(), recorder.Body.String())
+	}
+	recorder = get("/v1/traffic")
+	if recorder.Code != http.StatusServiceUnavailable || !strings.Contains(recorder.Body.String(), "GET /v1/registry") {
+		t.Errorf("Expected the outage to cascade to indirect dependents, got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	rm.GetDependencies().MarkUp("GET /v1/registry")
+	rm.GetDependencies().MarkDown("billing", "", "test")
+	states := rm.DependencyStates()
+	if len(states) != 2 || !states[0].Available || states[1].Available || states[1].Failed != "billing" {
+		t.Errorf("Unexpected dependency states: %+v", states)
+	}
+}
+
+func TestDependencyCyclesTerminate(t *testing.T) {
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	err := rm.ApplyConfig(&types.RoutesConfig{
+		Routes: []types.RouteConfig{
+			{RouteName: "/v1/a", Method: "GET", DependsOn: []string{"GET /v1/b"}},
+			{RouteName: "/v1/b", Method: "GET", DependsOn: []string{"GET /v1/a"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	if _, _, down := rm.dependencyFailure(rm.GetConfig().Routes[0]); down {
+		t.Error("Expected cyclic dependencies without outages to be available")
+	}
+}
@@ -0,0 +1,105 @@
+package router
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+)
+
+// DeprecatedPolicy is a policy marked deprecated with the routes still using it
+type DeprecatedPolicy struct {
+	Policy string `json:"policy"`
+	opa.Deprecation
+	PastSunset bool     `json:"pastSunset"`
+	Routes     []string `json:"routes"`
+}
+
+// SetEnforcePolicySunset rejects configurations and policy changes that leave
+// routes using a deprecated policy past its sunset date
+func (rm *RouteManager) SetEnforcePolicySunset(enforce bool) {
+	rm.enforceSunset = enforce
+}
+
+// DeprecatedPolicies lists the loaded policies marked deprecated, with the
+// routes of the active configuration that still use them
+func (rm *RouteManager) DeprecatedPolicies() []DeprecatedPolicy {
+	config := rm.GetConfig()
+	if config == nil {
+		config = &types.RoutesConfig{}
+	}
+	return deprecatedPolicies(config, rm.policyManager.PolicySources(), time.Now())
+}
+
+// deprecatedPolicies finds the deprecated policies among sources and the
+// routes using them, ordered by policy name. Policies that fail to parse are
+// left to policy loading to report.
+func deprecatedPolicies(config *types.RoutesConfig, sources map[string]string, now time.Time) []DeprecatedPolicy {
+	users := make(map[string][]string)
+	for _, route := range config.Routes {
+		names := route.Policies
+		if route.Cache != nil && route.Cache.TTLPolicy != "" {
+			names = append(append([]string(nil), names...), route.Cache.TTLPolicy)
+		}
+		for _, name := range names {
+			users[name] = append(users[name], routeKey(route))
+		}
+	}
+
+	var deprecated []DeprecatedPolicy
+	for name, source := range sources {
+		deprecation, err := opa.ParseDeprecation(name, source)
+		if err != nil || deprecation == nil {
+			continue
+		}
+		deprecated = append(deprecated, DeprecatedPolicy{
+			Policy:      name,
+			Deprecation: *deprecation,
+			PastSunset:  deprecation.PastSunset(now),
+			Routes:      append([]string{}, users[name]...),
+		})
+	}
+	sort.Slice(deprecated, func(i, j int) bool { return deprecated[i].Policy < deprecated[j].Policy })
+	return deprecated
+}
+
+// checkPolicySunsets returns an error naming the first route that uses a
+// deprecated policy past its sunset date, when sunsets are enforced
+func (rm *RouteManager) checkPolicySunsets(deprecated []DeprecatedPolicy) error {
+	if !rm.enforceSunset {
+		return nil
+	}
+	for _, policy := range deprecated {
+		if !policy.PastSunset || len(policy.Routes) == 0 {
+			continue
+		}
+		message := fmt.Sprintf("route %s uses policy %s, deprecated since its sunset on %s", policy.Routes[0], policy.Policy, policy.Sunset.Format(time.DateOnly))
+		if policy.Replacement != "" {
+			message += ", use " + policy.Replacement + " instead"
+		}
+		return fmt.Errorf("%s", message)
+	}
+	return nil
+}
+
+// warnDeprecatedPolicies logs every route still using a deprecated policy
+func warnDeprecatedPolicies(deprecated []DeprecatedPolicy) {
+	for _, policy := range deprecated {
+		for _, route := range policy.Routes {
+			attrs := []any{"route", route, "policy", policy.Policy}
+			if policy.Replacement != "" {
+				attrs = append(attrs, "replacement", policy.Replacement)
+			}
+			if policy.Sunset != nil {
+				attrs = append(attrs, "sunset", policy.Sunset.Format(time.DateOnly), "pastSunset", policy.PastSunset)
+			}
+			if policy.Message != "" {
+				attrs = append(attrs, "message", policy.Message)
+			}
+			slog.Warn("Route uses deprecated policy", attrs...)
+		}
+	}
+}
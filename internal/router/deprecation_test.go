@@ -0,0 +1,51 @@
+package router
+
+import (
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+)
+
+func TestPolicySunsetEnforcement(t *testing.T) {
+	pm := opa.NewPolicyManager()
+	deprecated := func(sunset string) string {
+		return "# METADATA\n# custom:\n#   deprecated: true\n#   replacement: orders_v2\n#   sunset: " + sunset + "\npackage orders\n\nallow := true\n"
+	}
+	if err := pm.SetPolicy("orders", deprecated("2999-01-01")); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	rm := NewRouteManager(pm, validator.NewSchemaValidator())
+	defer rm.Stop()
+	rm.SetEnforcePolicySunset(true)
+
+	config := &types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/orders", Method: "POST", Policies: []string{"orders"}},
+	}}
+	if err := rm.ApplyConfig(config); err != nil {
+		t.Fatalf("Expected a policy before its sunset to be allowed, got %v", err)
+	}
+	listed := rm.DeprecatedPolicies()
+	if len(listed) != 1 || listed[0].Replacement != "orders_v2" || listed[0].PastSunset || len(listed[0].Routes) != 1 || listed[0].Routes[0] != "POST /v1/orders" {
+		t.Fatalf("Unexpected deprecated policies %+v", listed)
+	}
+
+	if err := rm.CheckPolicyChange("orders", deprecated("2020-01-01")); err == nil {
+		t.Error("Expected moving the sunset into the past to be rejected while routes use the policy")
+	}
+	if err := pm.SetPolicy("orders", deprecated("2020-01-01")); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if err := rm.ApplyConfig(config); err == nil {
+		t.Error("Expected a route using a policy past its sunset to be rejected")
+	}
+	if err := rm.ApplyConfig(&types.RoutesConfig{}); err != nil {
+		t.Errorf("Expected a configuration without users of the policy to apply, got %v", err)
+	}
+
+	rm.SetEnforcePolicySunset(false)
+	if err := rm.ApplyConfig(config); err != nil {
+		t.Errorf("Expected sunsets to only warn when not enforced, got %v", err)
+	}
+}
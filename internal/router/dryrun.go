@@ -0,0 +1,103 @@
+package router
+
+import (
+	"strings"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+)
+
+// maxDryRunChanges caps the changed decisions listed per route
+const maxDryRunChanges = 10
+
+// DryRunPolicy evaluates a candidate policy against the recorded inputs of
+// the routes using it, up to limit inputs per route, and compares the
+// outcome with the live policy set. Nothing is installed.
+func (rm *RouteManager) DryRunPolicy(policyName, source string, limit int) (*types.PolicyDryRun, error) {
+	candidate, err := rm.policyManager.WithPolicy(policyName, source)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.PolicyDryRun{Policy: policyName, Routes: []types.RouteDryRun{}}
+	config := rm.GetConfig()
+	if config == nil {
+		return result, nil
+	}
+
+	for _, route := range config.Routes {
+		if !containsString(route.Policies, policyName) {
+			continue
+		}
+
+		routeResult := types.RouteDryRun{Route: routeKey(route)}
+		for _, sample := range rm.samples.List(routeKey(route), limit) {
+			current := policyOutcome(rm.GetPolicyEvaluator(), route, sample.Input)
+			proposed := policyOutcome(candidate, route, sample.Input)
+			countOutcome(&routeResult.Current, current)
+			countOutcome(&routeResult.Candidate, proposed)
+			routeResult.Samples++
+			if current == proposed {
+				continue
+			}
+			routeResult.Changed++
+			if len(routeResult.Changes) < maxDryRunChanges {
+				routeResult.Changes = append(routeResult.Changes, types.DecisionChange{
+					RequestID: sample.RequestID,
+					Timestamp: sample.Timestamp,
+					Current:   current,
+					Candidate: proposed,
+				})
+			}
+		}
+
+		result.Samples += routeResult.Samples
+		result.Changed += routeResult.Changed
+		addOutcomes(&result.Current, routeResult.Current)
+		addOutcomes(&result.Candidate, routeResult.Candidate)
+		result.Routes = append(result.Routes, routeResult)
+	}
+	return result, nil
+}
+
+// policyOutcome evaluates a route's policies and classifies the result
+func policyOutcome(evaluator opa.PolicyEvaluator, route types.RouteConfig, input map[string]interface{}) string {
+	result, err := evaluateRoutePolicies(evaluator, route, route.Policies, input)
+	switch {
+	case err != nil || strings.HasPrefix(result.Error, "Policy evaluation error"):
+		return types.OutcomeError
+	case result.Allowed:
+		return types.OutcomeAllow
+	default:
+		return types.OutcomeDeny
+	}
+}
+
+// countOutcome adds one outcome to the counts
+func countOutcome(outcomes *types.PolicyOutcomes, outcome string) {
+	switch outcome {
+	case types.OutcomeAllow:
+		outcomes.Allowed++
+	case types.OutcomeDeny:
+		outcomes.Denied++
+	default:
+		outcomes.Errors++
+	}
+}
+
+// addOutcomes adds the counts of other to outcomes
+func addOutcomes(outcomes *types.PolicyOutcomes, other types.PolicyOutcomes) {
+	outcomes.Allowed += other.Allowed
+	outcomes.Denied += other.Denied
+	outcomes.Errors += other.Errors
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
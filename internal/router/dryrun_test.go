@@ -0,0 +1,72 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+const volumePolicy = `package volume_policy
+
+default allow = false
+
+allow {
+	input.body.volume <= %s
+}
+`
+
+func TestDryRunPolicyComparesRecordedTraffic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policyManager := opa.NewPolicyManager()
+	if err := policyManager.SetPolicy("volume_policy", fmt.Sprintf(volumePolicy, "100")); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	rm := NewRouteManager(policyManager, validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName:    "/v1/traffic",
+		Method:       "POST",
+		Policies:     []string{"volume_policy"},
+		MockResponse: &types.MockResponseConfig{Template: `{}`},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	for _, body := range []string{`{"volume": 10}`, `{"volume": 50}`, `{"volume": 500}`} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/traffic", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	result, err := rm.DryRunPolicy("volume_policy", fmt.Sprintf(volumePolicy, "20"), 0)
+	if err != nil {
+		t.Fatalf("DryRunPolicy failed: %v", err)
+	}
+	if result.Samples != 3 || result.Changed != 1 {
+		t.Fatalf("Expected 3 samples with 1 change, got %+v", result)
+	}
+	if result.Current.Allowed != 2 || result.Candidate.Allowed != 1 || result.Candidate.Denied != 2 {
+		t.Errorf("Unexpected outcome distribution %+v / %+v", result.Current, result.Candidate)
+	}
+	if change := result.Routes[0].Changes[0]; change.Current != types.OutcomeAllow || change.Candidate != types.OutcomeDeny {
+		t.Errorf("Unexpected change %+v", change)
+	}
+
+	if _, err := rm.DryRunPolicy("volume_policy", "package volume_policy\nallow {", 0); err == nil {
+		t.Error("Expected invalid candidate to be rejected")
+	}
+	if allowed, _ := policyManager.EvaluatePolicy("volume_policy", map[string]interface{}{"body": map[string]interface{}{"volume": 50}}); !allowed.Allowed {
+		t.Error("Expected dry run to leave the live policy unchanged")
+	}
+}
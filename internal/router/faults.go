@@ -0,0 +1,78 @@
+package router
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Random sources of fault injection, replaced by tests
+var (
+	faultFloat64 = rand.Float64
+	faultIntn    = rand.Intn
+)
+
+// injectFaults delays or aborts a configurable percentage of requests to a route
+func (rm *RouteManager) injectFaults(faults *types.FaultConfig) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if delay := faults.Delay; delay != nil && chance(delay.Percentage) {
+				duration := time.Duration(delay.FixedMs) * time.Millisecond
+				if delay.JitterMs > 0 {
+					duration += time.Duration(faultIntn(delay.JitterMs+1)) * time.Millisecond
+				}
+				w.Header().Set("X-Fault-Delay", duration.String())
+
+				timer := time.NewTimer(duration)
+				select {
+				case <-r.Context().Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+
+			if abort := faults.Abort; abort != nil && chance(abort.Percentage) {
+				message := abort.Message
+				if message == "" {
+					message = fmt.Sprintf("Injected fault: %s", http.StatusText(abort.StatusCode))
+				}
+				w.Header().Set("X-Fault-Abort", "true")
+				writeJSON(w, abort.StatusCode, map[string]interface{}{
+					"error": message,
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// chance returns true with the given percentage (0-100) probability
+func chance(percentage float64) bool {
+	if percentage <= 0 {
+		return false
+	}
+	if percentage >= 100 {
+		return true
+	}
+	return faultFloat64()*100 < percentage
+}
+
+// validateFaults checks a fault configuration at registration time
+func validateFaults(faults *types.FaultConfig) error {
+	if faults == nil {
+		return nil
+	}
+	if faults.Delay != nil && (faults.Delay.FixedMs < 0 || faults.Delay.JitterMs < 0) {
+		return fmt.Errorf("fault delay must not be negative")
+	}
+	if faults.Abort != nil && (faults.Abort.StatusCode < 400 || faults.Abort.StatusCode > 599) {
+		return fmt.Errorf("fault abort status code must be between 400 and 599")
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+)
+
+// stubFaultRandom replaces the random sources of fault injection with fixed
+// draws, returned in turn, and the highest jitter
+func stubFaultRandom(t *testing.T, draws ...float64) {
+	t.Helper()
+	float64s, intns := faultFloat64, faultIntn
+	t.Cleanup(func() { faultFloat64, faultIntn = float64s, intns })
+	faultFloat64 = func() float64 {
+		draw := draws[0]
+		draws = append(draws[1:], draw)
+		return draw
+	}
+	faultIntn = func(n int) int { return n - 1 }
+}
+
+// serveFaults serves a request through the fault injection of a route,
+// reporting whether the request reached the route handler
+func serveFaults(t *testing.T, faults *types.FaultConfig) (*httptest.ResponseRecorder, bool) {
+	t.Helper()
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	reached := false
+	handler := rm.injectFaults(faults)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/items", nil))
+	return recorder, reached
+}
+
+func TestChance(t *testing.T) {
+	for _, tc := range []struct {
+		percentage float64
+		draw       float64
+		expected   bool
+	}{
+		{0, 0, false},
+		{-5, 0, false},
+		{100, 0.999, true},
+		{150, 0.999, true},
+		{25, 0.2, true},
+		{25, 0.25, false},
+		{25, 0.9, false},
+	} {
+		stubFaultRandom(t, tc.draw)
+		if got := chance(tc.percentage); got != tc.expected {
+			t.Errorf("chance(%v) with draw %v: expected %v, got %v", tc.percentage, tc.draw, tc.expected, got)
+		}
+	}
+}
+
+func TestInjectFaultsDelay(t *testing.T) {
+	stubFaultRandom(t, 0.5)
+	start := time.Now()
+	recorder, reached := serveFaults(t, &types.FaultConfig{
+		Delay: &types.DelayFault{FixedMs: 20, JitterMs: 10, Percentage: 100},
+	})
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected the fixed delay plus the highest jitter, took %v", elapsed)
+	}
+	if !reached || recorder.Code != http.StatusOK {
+		t.Errorf("Expected a delayed request to be served, got %d", recorder.Code)
+	}
+	if delay := recorder.Header().Get("X-Fault-Delay"); delay != "30ms" {
+		t.Errorf("Expected X-Fault-Delay 30ms, got %q", delay)
+	}
+}
+
+func TestInjectFaultsAbort(t *testing.T) {
+	stubFaultRandom(t, 0.999)
+	recorder, reached := serveFaults(t, &types.FaultConfig{
+		Abort: &types.AbortFault{StatusCode: http.StatusServiceUnavailable, Percentage: 100},
+	})
+	if reached {
+		t.Error("Expected an aborted request not to reach the route")
+	}
+	if recorder.Code != http.StatusServiceUnavailable || recorder.Header().Get("X-Fault-Abort") != "true" {
+		t.Errorf("Expected an injected 503, got %d %v", recorder.Code, recorder.Header())
+	}
+	if body := recorder.Body.String(); body != `{"error":"Injected fault: Service Unavailable"}` {
+		t.Errorf("Unexpected abort body %s", body)
+	}
+}
+
+func TestInjectFaultsAtZeroPercent(t *testing.T) {
+	stubFaultRandom(t, 0)
+	start := time.Now()
+	recorder, reached := serveFaults(t, &types.FaultConfig{
+		Delay: &types.DelayFault{FixedMs: 1000, Percentage: 0},
+		Abort: &types.AbortFault{StatusCode: http.StatusInternalServerError, Percentage: 0},
+	})
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected no delay, took %v", elapsed)
+	}
+	if !reached || recorder.Code != http.StatusOK {
+		t.Errorf("Expected the request to be served, got %d", recorder.Code)
+	}
+	if recorder.Header().Get("X-Fault-Delay") != "" || recorder.Header().Get("X-Fault-Abort") != "" {
+		t.Errorf("Expected no fault headers, got %v", recorder.Header())
+	}
+}
+
+func TestInjectFaultsAbortsShareOfRequests(t *testing.T) {
+	stubFaultRandom(t, 0.1, 0.6)
+	faults := &types.FaultConfig{Abort: &types.AbortFault{StatusCode: http.StatusBadGateway, Message: "upstream flaky", Percentage: 50}}
+
+	recorder, reached := serveFaults(t, faults)
+	if reached || recorder.Code != http.StatusBadGateway || recorder.Body.String() != `{"error":"upstream flaky"}` {
+		t.Errorf("Expected the first request to be aborted, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	recorder, reached = serveFaults(t, faults)
+	if !reached || recorder.Code != http.StatusOK {
+		t.Errorf("Expected the second request to be served, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,85 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+
+	"dynamiccontrol/internal/events"
+	"dynamiccontrol/internal/types"
+)
+
+// firstTraffic records whether a route revision has seen its first success and denial
+type firstTraffic struct {
+	revision string
+	success  int32
+	denial   int32
+}
+
+// routeRevision returns a short content hash identifying a route configuration
+func routeRevision(route types.RouteConfig) string {
+	encoded, err := json.Marshal(route)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// trackRevision resets first-traffic tracking when a route is new or its configuration changed
+func (rm *RouteManager) trackRevision(route types.RouteConfig) *firstTraffic {
+	key := routeKey(route)
+	revision := routeRevision(route)
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	tracker, exists := rm.firstTraffic[key]
+	if !exists || tracker.revision != revision {
+		tracker = &firstTraffic{revision: revision}
+		rm.firstTraffic[key] = tracker
+	}
+	return tracker
+}
+
+// emit delivers an event to webhooks and to event stream subscribers
+func (rm *RouteManager) emit(webhookURL string, event types.Event) {
+	rm.emitter.Emit(webhookURL, event)
+	rm.broker.Publish(event)
+}
+
+// trackFirstTraffic emits an event on the first successful request and the
+// first policy denial seen by a route revision
+func (rm *RouteManager) trackFirstTraffic(route types.RouteConfig, tracker *firstTraffic) func(next http.Handler) http.Handler {
+	webhookURL := ""
+	if route.Notifications != nil {
+		webhookURL = route.Notifications.WebhookURL
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writer := newResponseWriter(w)
+			next.ServeHTTP(writer, r)
+
+			status := writer.Status()
+			switch {
+			case status >= http.StatusOK && status < http.StatusMultipleChoices:
+				if atomic.CompareAndSwapInt32(&tracker.success, 0, 1) {
+					rm.emit(webhookURL, events.NewEvent(types.EventRouteFirstSuccess, routeKey(route), tracker.revision, map[string]interface{}{
+						"status": status,
+						"path":   r.URL.Path,
+					}))
+				}
+			case status == http.StatusForbidden:
+				if atomic.CompareAndSwapInt32(&tracker.denial, 0, 1) {
+					rm.emit(webhookURL, events.NewEvent(types.EventRouteFirstDenial, routeKey(route), tracker.revision, map[string]interface{}{
+						"status": status,
+						"path":   r.URL.Path,
+					}))
+				}
+			}
+		})
+	}
+}
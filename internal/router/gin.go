@@ -0,0 +1,30 @@
+package router
+
+import (
+	"fmt"
+
+	"dynamiccontrol/internal/reqctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes installs the dynamic route dispatcher on the router and
+// applies the loaded configuration
+func (rm *RouteManager) RegisterRoutes(router *gin.Engine) error {
+	config := rm.GetConfig()
+	if config == nil {
+		return fmt.Errorf("no configuration loaded")
+	}
+
+	router.NoRoute(rm.Dispatch)
+	return rm.ApplyConfig(config)
+}
+
+// Dispatch serves a gin request from the active route table. It is the
+// handler RegisterRoutes installs for unmatched requests, for routers that
+// mount the route table themselves. Client addresses follow the trusted
+// proxies of the gin engine.
+func (rm *RouteManager) Dispatch(c *gin.Context) {
+	reqctx.From(c)
+	rm.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CheckPolicies reports whether policy decisions can be made: every policy
+// referenced by a route is loaded, or the remote OPA server is healthy when
+// policies are evaluated remotely
+func (rm *RouteManager) CheckPolicies(ctx context.Context) error {
+	config := rm.GetConfig()
+	if config == nil {
+		return fmt.Errorf("no configuration loaded")
+	}
+	if remote, ok := rm.GetPolicyEvaluator().(interface{ Health(context.Context) error }); ok {
+		return remote.Health(ctx)
+	}
+
+	loaded := make(map[string]bool)
+	for _, name := range rm.policyManager.ListLoadedPolicies() {
+		loaded[name] = true
+	}
+	missing := make(map[string]bool)
+	for _, route := range config.Routes {
+		for _, name := range route.Policies {
+			if !loaded[name] {
+				missing[name] = true
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("policies not loaded: %s", sortedKeys(missing))
+	}
+	return nil
+}
+
+// CheckUpstreams reports proxied routes without a healthy upstream target
+func (rm *RouteManager) CheckUpstreams(ctx context.Context) error {
+	unavailable := make(map[string]bool)
+	for route, targets := range rm.GetUpstreamStatus() {
+		healthy := false
+		for _, target := range targets {
+			healthy = healthy || target.Healthy
+		}
+		if !healthy {
+			unavailable[route] = true
+		}
+	}
+	if len(unavailable) > 0 {
+		return fmt.Errorf("no healthy upstream for %s", sortedKeys(unavailable))
+	}
+	return nil
+}
+
+// sortedKeys joins the keys of a set in order
+func sortedKeys(set map[string]bool) string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
@@ -0,0 +1,50 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+)
+
+func TestReadinessChecks(t *testing.T) {
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	ctx := context.Background()
+
+	err := rm.ApplyConfig(&types.RoutesConfig{
+		Routes: []types.RouteConfig{
+			{RouteName: "/v1/items", Method: "GET", Policies: []string{"items"}},
+			{
+				RouteName: "/v1/orders",
+				Method:    "GET",
+				Handler:   types.HandlerProxy,
+				Upstreams: []types.UpstreamTarget{{
+					URL:         "http://127.0.0.1:1",
+					HealthCheck: &types.HealthCheckConfig{Path: "/health", IntervalMs: 10, UnhealthyThreshold: 1},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	if err := rm.CheckPolicies(ctx); err == nil || !strings.Contains(err.Error(), "items") {
+		t.Errorf("Expected the unloaded policy to be reported, got %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	err = rm.CheckUpstreams(ctx)
+	for err == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		err = rm.CheckUpstreams(ctx)
+	}
+	if err == nil || !strings.Contains(err.Error(), "/v1/orders") {
+		t.Errorf("Expected the route without a healthy upstream to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package router
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	"dynamiccontrol/internal/reqctx"
+	"dynamiccontrol/internal/types"
+)
+
+// routeScope identifies the routes of a tenant for a host, where "" stands
+// for shared routes and routes serving every host
+type routeScope struct {
+	tenant string
+	host   string
+}
+
+// scopeOf returns the scope of a route
+func scopeOf(route types.RouteConfig) routeScope {
+	return routeScope{tenant: route.Tenant, host: route.Host}
+}
+
+// validateHost checks the host match of a route: a lowercase host name
+// without port, optionally starting with a *. wildcard label
+func validateHost(route types.RouteConfig) error {
+	if route.Host == "" {
+		return nil
+	}
+	name := strings.TrimPrefix(route.Host, "*.")
+	if name == "" || name != strings.ToLower(name) || strings.ContainsAny(name, "*/: ") {
+		return fmt.Errorf("invalid host %q, expected a lowercase host name such as api.example.com or *.example.com", route.Host)
+	}
+	return nil
+}
+
+// requestHost returns the lowercase host of a request without its port
+func requestHost(req *http.Request) string {
+	host := strings.ToLower(req.Host)
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		return name
+	}
+	return host
+}
+
+// requestScopes returns the scopes that may serve a request, most specific
+// first. Routes of the request's tenant come before shared routes, and
+// within each, an exact host before wildcards from the longest suffix down,
+// and those before routes serving every host.
+func requestScopes(tenant, host string) []routeScope {
+	var hosts []string
+	if host != "" {
+		hosts = append(hosts, host)
+		for suffix := host; ; {
+			_, parent, found := strings.Cut(suffix, ".")
+			if !found {
+				break
+			}
+			hosts = append(hosts, "*."+parent)
+			suffix = parent
+		}
+	}
+	hosts = append(hosts, "")
+
+	tenants := []string{""}
+	if tenant != "" {
+		tenants = []string{tenant, ""}
+	}
+	scopes := make([]routeScope, 0, len(tenants)*len(hosts))
+	for _, tenant := range tenants {
+		for _, host := range hosts {
+			scopes = append(scopes, routeScope{tenant: tenant, host: host})
+		}
+	}
+	return scopes
+}
+
+// matchesRoute reports whether a route serves a request method and path
+func matchesRoute(route types.RouteConfig, method, path string) bool {
+	return route.Method == method && matchPattern(route.RouteName, path)
+}
+
+// compileTables registers the routes of each tenant and host on a table of
+// their own and returns the dispatcher choosing between them. A request is
+// served by the most specific scope with a matching route, falling back to
+// the shared routes serving every host.
+func (rm *RouteManager) compileTables(routes []types.RouteConfig) http.Handler {
+	shared := newRouteTable()
+	tables := map[routeScope]*routeTable{{}: shared}
+	for _, route := range routes {
+		table, exists := tables[scopeOf(route)]
+		if !exists {
+			table = newRouteTable()
+			tables[scopeOf(route)] = table
+		}
+		if err := rm.registerRoute(table, route); err != nil {
+			slog.Error("Failed to register route", "route", routeKey(route), "error", err)
+			continue
+		}
+		slog.Info("Registered route", "route", routeKey(route))
+	}
+	if len(tables) == 1 {
+		return shared
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc, r := reqctx.Attach(r)
+		for _, scope := range requestScopes(rc.Tenant, requestHost(r)) {
+			if table, exists := tables[scope]; exists && table != shared {
+				if _, _, ok := table.match(r.Method, r.URL.Path); ok {
+					table.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+		shared.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,100 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHostRouting(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mock := func(tenant, host, route, body string) types.RouteConfig {
+		return types.RouteConfig{
+			RouteName:    route,
+			Method:       "GET",
+			Tenant:       tenant,
+			Host:         host,
+			MockResponse: &types.MockResponseConfig{Template: body},
+		}
+	}
+	config := &types.RoutesConfig{
+		Tenants: []types.TenantConfig{{Name: "acme"}},
+		Routes: []types.RouteConfig{
+			mock("", "", "/v1/status", `{"served":"any"}`),
+			mock("", "api.example.com", "/v1/status", `{"served":"api"}`),
+			mock("", "*.example.com", "/v1/status", `{"served":"wildcard"}`),
+			mock("", "*.eu.example.com", "/v1/status", `{"served":"eu"}`),
+			mock("", "api.example.com", "/v1/users/:id", `{"served":"api users"}`),
+			mock("acme", "api.example.com", "/v1/status", `{"served":"acme api"}`),
+		},
+	}
+
+	for _, lazy := range []bool{false, true} {
+		rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+		rm.SetLazy(lazy)
+		engine := gin.New()
+		engine.NoRoute(rm.Dispatch)
+		if err := rm.ApplyConfig(config); err != nil {
+			t.Fatalf("Failed to apply config: %v", err)
+		}
+
+		tests := []struct {
+			host, tenant, path string
+			status             int
+			expected           string
+		}{
+			{"api.example.com", "", "/v1/status", http.StatusOK, `{"served":"api"}`},
+			{"API.example.com:8443", "", "/v1/status", http.StatusOK, `{"served":"api"}`},
+			{"www.example.com", "", "/v1/status", http.StatusOK, `{"served":"wildcard"}`},
+			// The longest wildcard wins
+			{"fr.eu.example.com", "", "/v1/status", http.StatusOK, `{"served":"eu"}`},
+			{"example.com", "", "/v1/status", http.StatusOK, `{"served":"any"}`},
+			{"api.example.com", "", "/v1/users/7", http.StatusOK, `{"served":"api users"}`},
+			{"www.example.com", "", "/v1/users/7", http.StatusNotFound, ""},
+			{"api.example.com", "acme", "/v1/status", http.StatusOK, `{"served":"acme api"}`},
+			// Tenants fall back to shared routes of their host
+			{"www.example.com", "acme", "/v1/status", http.StatusOK, `{"served":"wildcard"}`},
+		}
+		for _, test := range tests {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req.Host = test.host
+			if test.tenant != "" {
+				req.Header.Set("X-Tenant-ID", test.tenant)
+			}
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, req)
+			if recorder.Code != test.status || (test.expected != "" && recorder.Body.String() != test.expected) {
+				t.Errorf("lazy=%v %s%s (tenant %q): expected %d %s, got %d %s", lazy, test.host, test.path, test.tenant, test.status, test.expected, recorder.Code, recorder.Body.String())
+			}
+		}
+		rm.Stop()
+	}
+}
+
+func TestValidateHost(t *testing.T) {
+	tests := []struct {
+		host  string
+		valid bool
+	}{
+		{"", true},
+		{"api.example.com", true},
+		{"*.example.com", true},
+		{"API.example.com", false},
+		{"api.example.com:8080", false},
+		{"api.*.com", false},
+		{"*.", false},
+		{"example.com/v1", false},
+	}
+	for _, test := range tests {
+		err := validateHost(types.RouteConfig{Host: test.host})
+		if (err == nil) != test.valid {
+			t.Errorf("validateHost(%q): expected valid=%v, got error %v", test.host, test.valid, err)
+		}
+	}
+}
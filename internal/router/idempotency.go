@@ -0,0 +1,149 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/ratelimit"
+	"dynamiccontrol/internal/responsecache"
+	"dynamiccontrol/internal/validator"
+)
+
+// Idempotency settings
+const (
+	// DefaultIdempotencyTTL is how long the first response to an
+	// idempotency key is replayed
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// idempotencyKeyHeader carries the client's key for a POST request
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotencyReplayedHeader marks replayed responses
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	// idempotencyFingerprintHeader keeps the request body hash with a stored
+	// response; it is never sent to clients
+	idempotencyFingerprintHeader = "X-Idempotency-Fingerprint"
+	// maxIdempotencyKey bounds the length of idempotency keys
+	maxIdempotencyKey = 255
+	// idempotencyInFlightTTL bounds how long a key stays in progress when
+	// the replica serving its first request stops before storing a response
+	idempotencyInFlightTTL = 5 * time.Minute
+)
+
+// idempotency holds the first responses to idempotency keys. A key whose
+// first request is still being served holds a pending entry, added with
+// SET NX on shared stores so that a single replica serves it.
+type idempotency struct {
+	store responsecache.Store
+	ttl   time.Duration
+}
+
+// newIdempotency creates an in-memory idempotency store
+func newIdempotency() *idempotency {
+	return &idempotency{
+		store: responsecache.New(responsecache.DefaultCapacity),
+		ttl:   DefaultIdempotencyTTL,
+	}
+}
+
+// SetIdempotencyStore replaces the store holding the responses replayed for
+// idempotency keys, such as with a Redis store shared by replicas
+func (rm *RouteManager) SetIdempotencyStore(store responsecache.Store) {
+	rm.idempotency.store = store
+}
+
+// SetIdempotencyTTL sets how long the first response to an idempotency key
+// is replayed
+func (rm *RouteManager) SetIdempotencyTTL(ttl time.Duration) {
+	if ttl > 0 {
+		rm.idempotency.ttl = ttl
+	}
+}
+
+// idempotentRequests wraps the executor of a POST route so that the first
+// response to a request carrying an Idempotency-Key header is replayed for
+// later requests of the same caller with the same key, so that clients can
+// retry safely. Replays are served after the authorize stage, so every
+// retry is evaluated against the route policies. Reusing a key with a
+// different body is rejected with 422, and a retry while the first request
+// is still served with 409. Server errors and rejections by quotas are not
+// stored, so those requests can be retried.
+func (rm *RouteManager) idempotentRequests(execute func(ex *Exchange) error) func(ex *Exchange) error {
+	return func(ex *Exchange) error {
+		clientKey := ex.Request.Header.Get(idempotencyKeyHeader)
+		if clientKey == "" {
+			return execute(ex)
+		}
+		if len(clientKey) > maxIdempotencyKey {
+			return stageError(http.StatusBadRequest, "Invalid Idempotency-Key header", "keys must not be longer than 255 characters")
+		}
+
+		body, err := validator.CanonicalJSON(ex.Body)
+		if err != nil {
+			return stageError(http.StatusBadRequest, "Failed to read request body", err.Error())
+		}
+		sum := sha256.Sum256(body)
+		fingerprint := hex.EncodeToString(sum[:])
+
+		state := rm.idempotency
+		key := idempotencyKey(ex, clientKey)
+		if !state.store.Add(key, responsecache.Entry{}, idempotencyInFlightTTL) {
+			entry, ok := state.store.Get(key)
+			if !ok || entry.Status == 0 {
+				return stageError(http.StatusConflict, "Request in progress", "a request with this Idempotency-Key is still being processed")
+			}
+			return replayIdempotent(ex, entry, fingerprint)
+		}
+
+		recorder := &cacheRecorder{ResponseWriter: ex.Writer}
+		ex.Writer = recorder
+		ex.onFinished = func() {
+			status := recorder.Status()
+			if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests || recorder.truncated {
+				state.store.Delete(key)
+				return
+			}
+			header := recorder.Header().Clone()
+			for _, name := range ratelimit.Headers {
+				header.Del(name)
+			}
+			header.Set(idempotencyFingerprintHeader, fingerprint)
+			state.store.Set(key, responsecache.Entry{
+				Status: status,
+				Header: header,
+				Body:   append([]byte(nil), recorder.body.Bytes()...),
+			}, state.ttl)
+			logging.FromContext(ex.Request.Context()).Debug("Stored idempotent response", "route", routeKey(ex.Route), "status", status)
+		}
+		return execute(ex)
+	}
+}
+
+// idempotencyKey returns the store key of a request's idempotency key. It
+// includes the caller, identified by a hash of its subject claim and
+// Authorization header, so that callers never replay each other's responses.
+func idempotencyKey(ex *Exchange, clientKey string) string {
+	subject, _ := ex.Claims["sub"].(string)
+	caller := sha256.Sum256([]byte(subject + "\n" + ex.Request.Header.Get("Authorization")))
+	return "idempotency:" + routeKey(ex.Route) + " " + ex.Request.URL.Path + "\n" +
+		hex.EncodeToString(caller[:]) + "\n" + clientKey
+}
+
+// replayIdempotent writes a stored response, or fails with 422 when the
+// request body differs from the body of the first request
+func replayIdempotent(ex *Exchange, entry *responsecache.Entry, fingerprint string) error {
+	if entry.Header.Get(idempotencyFingerprintHeader) != fingerprint {
+		return stageError(http.StatusUnprocessableEntity, "Idempotency-Key reused", "the key was used for a request with a different body")
+	}
+	header := ex.Writer.Header()
+	for name, values := range entry.Header {
+		if name != idempotencyFingerprintHeader {
+			header[name] = values
+		}
+	}
+	header.Set(idempotencyReplayedHeader, "true")
+	writeData(ex.Writer, entry.Status, entry.Header.Get("Content-Type"), entry.Body)
+	ex.Written = true
+	return nil
+}
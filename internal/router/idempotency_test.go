@@ -0,0 +1,156 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/quota"
+	"dynamiccontrol/internal/responsecache"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIdempotencyKeyReplaysFirstResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/services/:serviceId/traffic", Method: "POST", Handler: types.HandlerTraffic},
+	}})
+	rm.GetQuotas().Set(quota.Quota{Service: "billing", Requests: 1})
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/services/billing/traffic", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder
+	}
+	body := `{"trafficType": "incoming", "volume": 5, "priority": "low"}`
+	first := post("retry-1", body)
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("Expected the first request to be served, got %d", first.Code)
+	}
+
+	// A retry within the quota of one request is replayed instead of rejected
+	retry := post("retry-1", body)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected the first response to be replayed, got %d: %s", retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get("X-Idempotency-Fingerprint") != "" || retry.Header().Get("X-Quota-Remaining") != "0" {
+		t.Errorf("Unexpected replayed headers %v", retry.Header())
+	}
+	if rules := rm.GetTrafficController().Rules("billing"); len(rules) != 1 {
+		t.Errorf("Expected a single rule, got %d", len(rules))
+	}
+
+	if recorder := post("retry-1", `{"trafficType": "incoming", "volume": 6, "priority": "low"}`); recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected a reused key with another body to be rejected, got %d", recorder.Code)
+	}
+	if recorder := post("retry-2", body); recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected a new key to be served again, got %d", recorder.Code)
+	}
+	// Rejections by quotas are not stored, so the key stays usable
+	rm.GetQuotas().Delete("billing")
+	if recorder := post("retry-2", body); recorder.Code != http.StatusCreated || recorder.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Expected the retried rejection to be served, got %d", recorder.Code)
+	}
+	if recorder := post("", body); recorder.Code != http.StatusCreated {
+		t.Errorf("Expected requests without a key to be served, got %d", recorder.Code)
+	}
+}
+
+func TestIdempotencyKeyIsScopedToCallerAndPolicies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policyManager := opa.NewPolicyManager()
+	if err := policyManager.SetPolicy("writers", "package writers\n\ndefault allow = false\n\nallow { input.headers[\"X-Role\"] == \"writer\" }\n"); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	rm := NewRouteManager(policyManager, validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/services/:serviceId/traffic", Method: "POST", Handler: types.HandlerTraffic, Policies: []string{"writers"}},
+	}})
+
+	post := func(authorization, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/services/billing/traffic", strings.NewReader(`{"trafficType": "incoming", "volume": 5, "priority": "low"}`))
+		req.Header.Set("Idempotency-Key", "retry-1")
+		req.Header.Set("Authorization", authorization)
+		req.Header.Set("X-Role", role)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder
+	}
+	if recorder := post("Bearer first", "writer"); recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected the first request to be served, got %d", recorder.Code)
+	}
+	// Another caller reusing the key is served on its own
+	if recorder := post("Bearer second", "writer"); recorder.Code != http.StatusCreated || recorder.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Expected another caller not to receive the stored response, got %d", recorder.Code)
+	}
+	if rules := rm.GetTrafficController().Rules("billing"); len(rules) != 2 {
+		t.Errorf("Expected a rule per caller, got %d", len(rules))
+	}
+	// Retries are evaluated against the route policies before a replay
+	if recorder := post("Bearer first", "reader"); recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected a retry denied by policy not to be replayed, got %d", recorder.Code)
+	}
+	if recorder := post("Bearer first", "writer"); recorder.Code != http.StatusCreated || recorder.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected the stored response to be replayed, got %d", recorder.Code)
+	}
+}
+
+func TestIdempotencyKeyInFlightAcrossReplicas(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := responsecache.New(responsecache.DefaultCapacity)
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	replica := func() *gin.Engine {
+		rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+		t.Cleanup(rm.Stop)
+		rm.SetIdempotencyStore(store)
+		rm.AddStage(StageExecute, NewStage("wait", func(ex *Exchange) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		}))
+		rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{RouteName: "/v1/orders", Method: "POST"}}})
+		engine := gin.New()
+		engine.NoRoute(rm.Dispatch)
+		return engine
+	}
+	post := func(engine *gin.Engine) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(`{"id": 1}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "order-1")
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	first, second := replica(), replica()
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(first) }()
+	<-started
+	if recorder := post(second); recorder.Code != http.StatusConflict {
+		t.Errorf("Expected a retry on another replica to be rejected while in flight, got %d", recorder.Code)
+	}
+	close(release)
+	if recorder := <-done; recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the first request to be served, got %d", recorder.Code)
+	}
+	if recorder := post(second); recorder.Code != http.StatusOK || recorder.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected the other replica to replay the stored response, got %d", recorder.Code)
+	}
+}
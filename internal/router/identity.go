@@ -0,0 +1,50 @@
+package router
+
+import (
+	"context"
+	"net/http"
+
+	"dynamiccontrol/internal/identity"
+	"dynamiccontrol/internal/logging"
+)
+
+// SetIdentitySigner enables signed identity assertions on upstream calls
+func (rm *RouteManager) SetIdentitySigner(signer *identity.Signer) {
+	rm.assertions = signer
+}
+
+// assertIdentity sets a signed assertion of the caller's identity and the
+// policy decision on the upstream headers. Inbound assertions are always
+// dropped so callers cannot forge one by forwarding their own.
+func (rm *RouteManager) assertIdentity(ctx context.Context, ex *Exchange, header http.Header) {
+	header.Del(identity.DefaultHeader)
+	if rm.assertions == nil {
+		return
+	}
+	header.Del(rm.assertions.Header())
+
+	claims := identity.Claims{
+		Audience:   routeKey(ex.Route),
+		ID:         ex.RequestID,
+		ClientIP:   ex.Client.IP,
+		Attributes: ex.Claims,
+	}
+	if subject, ok := ex.Claims["sub"].(string); ok {
+		claims.Subject = subject
+	}
+	if decisions := ex.Decisions(); len(decisions) > 0 {
+		decision := decisions[len(decisions)-1]
+		claims.Decision = &identity.DecisionClaim{
+			ID:       decision.ID,
+			Allowed:  decision.Allowed,
+			Policies: decision.Policies,
+		}
+	}
+
+	token, err := rm.assertions.Sign(claims)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to sign identity assertion", "route", claims.Audience, "error", err)
+		return
+	}
+	header.Set(rm.assertions.Header(), token)
+}
@@ -0,0 +1,135 @@
+package router
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/reqctx"
+	"dynamiccontrol/internal/types"
+)
+
+// lazyRouter compiles routes on first hit instead of at startup. Each
+// compiled route is registered on its own small table, so serving a
+// request never holds the lock that compilation needs.
+type lazyRouter struct {
+	mu       sync.RWMutex
+	routes   []types.RouteConfig
+	compiled map[string]*routeTable
+	failed   map[string]bool
+	stop     chan struct{}
+}
+
+// newLazyRouter creates a lazy router for the given routes
+func newLazyRouter(routes []types.RouteConfig) *lazyRouter {
+	return &lazyRouter{
+		routes:   routes,
+		compiled: make(map[string]*routeTable),
+		failed:   make(map[string]bool),
+		stop:     make(chan struct{}),
+	}
+}
+
+// SetLazy enables lazy route compilation, trading first-request latency for fast startup
+func (rm *RouteManager) SetLazy(lazy bool) {
+	rm.lazy = lazy
+}
+
+// lazyDispatch returns a handler that compiles the matching route on first
+// hit and serves the request from it
+func (rm *RouteManager) lazyDispatch(lr *lazyRouter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc, r := reqctx.Attach(r)
+		route, ok := lr.match(rc.Tenant, requestHost(r), r.Method, r.URL.Path)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{
+				"error": "Route not found",
+			})
+			return
+		}
+
+		table := rm.compileLazyRoute(lr, route)
+		if table == nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error": "Route failed to compile",
+			})
+			return
+		}
+
+		table.ServeHTTP(w, r)
+	})
+}
+
+// compileLazyRoute compiles a route once and returns the table serving it,
+// or nil when the route failed to compile
+func (rm *RouteManager) compileLazyRoute(lr *lazyRouter, route types.RouteConfig) *routeTable {
+	key := routeKey(route)
+
+	lr.mu.RLock()
+	table, failed := lr.compiled[key], lr.failed[key]
+	lr.mu.RUnlock()
+	if table != nil || failed {
+		return table
+	}
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	if table := lr.compiled[key]; table != nil || lr.failed[key] {
+		return table
+	}
+
+	start := time.Now()
+	table = newRouteTable()
+	if err := rm.registerRoute(table, route); err != nil {
+		slog.Error("Failed to compile route", "route", key, "error", err)
+		lr.failed[key] = true
+		return nil
+	}
+
+	lr.compiled[key] = table
+	slog.Info("Compiled route", "route", key, "duration_ms", float64(time.Since(start).Microseconds())/1000)
+	return table
+}
+
+// precompileRoutes compiles every route in the background so that only
+// requests arriving before the queue reaches their route pay the compile cost
+func (rm *RouteManager) precompileRoutes(lr *lazyRouter) {
+	for _, route := range lr.routes {
+		select {
+		case <-lr.stop:
+			return
+		default:
+		}
+		rm.compileLazyRoute(lr, route)
+	}
+	slog.Info("Background precompilation finished")
+}
+
+// PendingCompilations returns the number of routes still waiting in the
+// lazy precompile queue
+func (rm *RouteManager) PendingCompilations() int {
+	rm.mu.RLock()
+	lr := rm.lazyRouter
+	rm.mu.RUnlock()
+	if lr == nil {
+		return 0
+	}
+
+	lr.mu.RLock()
+	defer lr.mu.RUnlock()
+	return len(lr.routes) - len(lr.compiled) - len(lr.failed)
+}
+
+// match finds the configured route for a request, preferring the routes of
+// the request's tenant and host in the order of requestScopes
+func (lr *lazyRouter) match(tenant, host, method, path string) (types.RouteConfig, bool) {
+	for _, scope := range requestScopes(tenant, host) {
+		for _, route := range lr.routes {
+			if scopeOf(route) == scope && matchesRoute(route, method, path) {
+				return route, true
+			}
+		}
+	}
+	return types.RouteConfig{}, false
+}
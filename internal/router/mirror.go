@@ -0,0 +1,100 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"time"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/upstream"
+	"dynamiccontrol/internal/validator"
+)
+
+// MirrorHeader marks requests mirrored to a shadow upstream
+const MirrorHeader = "X-Mirrored-Request"
+
+// defaultMirrorTimeout bounds mirrored requests of routes without a timeout
+const defaultMirrorTimeout = 5 * time.Second
+
+// validateMirror checks the mirroring configuration of a route at
+// registration time
+func validateMirror(route types.RouteConfig) error {
+	mirror := route.Mirror
+	if mirror == nil {
+		return nil
+	}
+	target, err := url.Parse(mirror.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("mirror url must be an absolute http or https URL")
+	}
+	if mirror.Percent <= 0 || mirror.Percent > 100 {
+		return fmt.Errorf("mirror percent must be above 0 and at most 100")
+	}
+	if mirror.TimeoutMs < 0 {
+		return fmt.Errorf("mirror timeoutMs must not be negative")
+	}
+	return nil
+}
+
+// mirrorRequests wraps an execute stage function so that the route's share
+// of requests is copied to its shadow upstream before being served
+func (rm *RouteManager) mirrorRequests(execute func(ex *Exchange) error) func(ex *Exchange) error {
+	return func(ex *Exchange) error {
+		if rand.Float64()*100 < ex.Route.Mirror.Percent {
+			rm.mirrorRequest(ex)
+		}
+		return execute(ex)
+	}
+}
+
+// mirrorRequest sends a copy of the request to the route's shadow upstream
+// in the background and discards the response. The copy carries the
+// canonical body and the headers upstreams receive, plus MirrorHeader.
+func (rm *RouteManager) mirrorRequest(ex *Exchange) {
+	route := ex.Route
+	logger := logging.FromContext(ex.Request.Context())
+
+	var body []byte
+	if ex.HasBody {
+		encoded, err := validator.CanonicalJSON(ex.Body)
+		if err != nil {
+			logger.Warn("Failed to encode mirrored request body", "route", routeKey(route), "error", err)
+			return
+		}
+		body = encoded
+	}
+
+	ctx := ex.Request.Context()
+	header := rm.upstreamHeaders(ctx, ex)
+	header.Set(MirrorHeader, "true")
+	ctx = upstream.WithPropagatedHeaders(ctx, header)
+
+	// The request outlives the exchange, so the copy must not share its state
+	request := ex.Request.Clone(context.Background())
+	timeout := defaultMirrorTimeout
+	if route.Mirror.TimeoutMs > 0 {
+		timeout = time.Duration(route.Mirror.TimeoutMs) * time.Millisecond
+	}
+
+	rm.sideEffects.Go(ctx, "mirror", func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		code := "error"
+		resp, err := rm.upstreamClient.Forward(ctx, route.Mirror.URL, request, body)
+		if err != nil {
+			logging.FromContext(ctx).Debug("Mirrored request failed", "route", routeKey(route), "error", err)
+		} else {
+			code = strconv.Itoa(resp.StatusCode)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		metrics.MirroredRequests.WithLabelValues(route.RouteName, code).Inc()
+	})
+}
@@ -0,0 +1,85 @@
+package router
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMirrorRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type mirrored struct {
+		path, query, body, header string
+	}
+	received := make(chan mirrored, 1)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- mirrored{r.URL.Path, r.URL.RawQuery, string(body), r.Header.Get(MirrorHeader)}
+		// The shadow response never reaches the client
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer shadow.Close()
+
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName:    "/v1/orders",
+		Method:       "POST",
+		MockResponse: &types.MockResponseConfig{StatusCode: http.StatusCreated, Template: `{"status": "created"}`},
+		Mirror:       &types.MirrorConfig{URL: shadow.URL + "/shadow", Percent: 100},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/orders?dryRun=false", strings.NewReader(`{ "item": "book" }`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected the primary response 201, got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	select {
+	case request := <-received:
+		expected := mirrored{"/shadow/v1/orders", "dryRun=false", `{"item":"book"}`, "true"}
+		if request != expected {
+			t.Errorf("Expected mirrored request %+v, got %+v", expected, request)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Request was not mirrored")
+	}
+}
+
+func TestValidateMirror(t *testing.T) {
+	tests := []struct {
+		mirror types.MirrorConfig
+		valid  bool
+	}{
+		{types.MirrorConfig{URL: "http://shadow:8080", Percent: 10}, true},
+		{types.MirrorConfig{URL: "https://shadow.example.com/v2", Percent: 100, TimeoutMs: 500}, true},
+		{types.MirrorConfig{URL: "shadow:8080", Percent: 10}, false},
+		{types.MirrorConfig{URL: "http://shadow:8080"}, false},
+		{types.MirrorConfig{URL: "http://shadow:8080", Percent: 101}, false},
+		{types.MirrorConfig{URL: "http://shadow:8080", Percent: 10, TimeoutMs: -1}, false},
+	}
+	for _, test := range tests {
+		mirror := test.mirror
+		err := validateMirror(types.RouteConfig{Mirror: &mirror})
+		if (err == nil) != test.valid {
+			t.Errorf("validateMirror(%+v): expected valid=%v, got error %v", test.mirror, test.valid, err)
+		}
+	}
+}
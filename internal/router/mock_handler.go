@@ -0,0 +1,124 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+
+	"dynamiccontrol/internal/transform"
+	"dynamiccontrol/internal/types"
+)
+
+// executeMock produces the mock response configured for a route, falling back
+// to a generic acknowledgement when the route declares no mockResponse
+func (rm *RouteManager) executeMock(ex *Exchange) error {
+	return rm.renderMock(ex, ex.Route.MockResponse, routeKey(ex.Route))
+}
+
+// renderMock produces a mock response, rendering the template registered
+// under templateKey
+func (rm *RouteManager) renderMock(ex *Exchange, mock *types.MockResponseConfig, templateKey string) error {
+	route := ex.Route
+	if mock == nil {
+		response := map[string]interface{}{
+			"message": fmt.Sprintf("%s request processed successfully", route.Method),
+			"route":   route.RouteName,
+			"method":  route.Method,
+		}
+		if ex.Body != nil {
+			response["data"] = ex.Body
+		}
+		ex.Response = response
+		return nil
+	}
+
+	data := transform.TemplateData{
+		Route:   route.RouteName,
+		Method:  route.Method,
+		Params:  ex.Params,
+		Headers: ex.Headers,
+		Query:   ex.Query,
+		Body:    ex.Body,
+	}
+
+	statusCode := mock.StatusCode
+	var response interface{}
+
+	if mock.Store != nil {
+		stored, storeStatus, found := rm.applyMockStore(mock.Store, ex.Params, ex.Body)
+		if !found {
+			return stageError(http.StatusNotFound, "Record not found", nil)
+		}
+		if mock.Store.Operation == types.MockStoreList {
+			data.Records = stored
+		} else {
+			data.Record = stored
+		}
+		response = stored
+		if statusCode == 0 {
+			statusCode = storeStatus
+		}
+	}
+
+	if tmpl, exists := rm.mockTemplate(templateKey); exists {
+		vars, err := rm.templateVars(route)
+		if err != nil {
+			return stageError(http.StatusInternalServerError, fmt.Sprintf("Mock response error: %v", err), nil)
+		}
+		data.Vars = vars
+		rendered, err := transform.RenderTemplate(tmpl, data)
+		if err != nil {
+			return stageError(http.StatusInternalServerError, fmt.Sprintf("Mock response error: %v", err), nil)
+		}
+		response = rendered
+	}
+
+	for key, value := range mock.Headers {
+		ex.ResponseHeaders[key] = value
+	}
+	ex.StatusCode = statusCode
+	ex.Response = response
+	return nil
+}
+
+// applyMockStore performs the configured store operation and returns the
+// affected record(s), the default status code and whether the record exists
+func (rm *RouteManager) applyMockStore(store *types.MockStoreConfig, params map[string]string, requestBody interface{}) (interface{}, int, bool) {
+	key := params[store.KeyParam]
+	id := params[store.IDParam]
+
+	switch store.Operation {
+	case types.MockStoreCreate:
+		return rm.mockData.CreateRecord(store.Collection, key, requestBody), http.StatusCreated, true
+	case types.MockStoreList:
+		return rm.mockData.ListRecords(store.Collection, key), http.StatusOK, true
+	case types.MockStoreGet:
+		record, found := rm.mockData.GetRecord(store.Collection, key, id)
+		return record, http.StatusOK, found
+	case types.MockStoreDelete:
+		removed := rm.mockData.DeleteRecords(store.Collection, key, id)
+		return nil, http.StatusNoContent, removed > 0 || id == ""
+	default:
+		return nil, http.StatusInternalServerError, true
+	}
+}
+
+// validateMockStore checks a mock store binding at registration time
+func validateMockStore(store *types.MockStoreConfig) error {
+	if store == nil {
+		return nil
+	}
+	if store.Collection == "" {
+		return fmt.Errorf("mock store requires a collection")
+	}
+	switch store.Operation {
+	case types.MockStoreCreate, types.MockStoreList:
+	case types.MockStoreGet:
+		if store.IDParam == "" {
+			return fmt.Errorf("mock store get operation requires idParam")
+		}
+	case types.MockStoreDelete:
+	default:
+		return fmt.Errorf("unsupported mock store operation: %s", store.Operation)
+	}
+	return nil
+}
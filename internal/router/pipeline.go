@@ -0,0 +1,240 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dynamiccontrol/internal/codec"
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/reqctx"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+)
+
+// Built-in pipeline stage names, in execution order. The throttle stage only
+// runs on routes with throttles.
+const (
+	StageDecode           = "decode"
+	StageValidate         = "validate"
+	StageThrottle         = "throttle"
+	StageEnrich           = "enrich"
+	StageAuthorize        = "authorize"
+	StageExecute          = "execute"
+	StageValidateResponse = "validate-response"
+	StageEncode           = "encode"
+)
+
+// Exchange carries the state of a single request through the pipeline. The
+// request attributes live in the embedded request context, which is shared
+// with middlewares and handlers outside the pipeline.
+type Exchange struct {
+	Writer  ResponseWriter
+	Request *http.Request
+	Route   types.RouteConfig
+	*reqctx.RequestContext
+	// Response is the document produced by the execute stage, written by encode
+	Response        interface{}
+	StatusCode      int
+	ResponseHeaders map[string]string
+	// ResponseValidated is set by executors that validate their own response
+	ResponseValidated bool
+	// Written is set when a stage has already written the response itself
+	Written bool
+	// onWritten runs after every stage succeeded, such as to cache the response
+	onWritten func()
+	// onFinished runs once the response is written, whether or not every
+	// stage succeeded
+	onFinished func()
+	// encoder renders the response of routes with contentTypes
+	encoder codec.Codec
+	// schemas are the route schemas prepared when the pipeline was built
+	schemas *routeSchemas
+}
+
+// Stage is a single step of the request pipeline. A stage returning an error
+// stops the pipeline; a *StageError controls the status code of the response.
+type Stage interface {
+	Name() string
+	Process(ex *Exchange) error
+}
+
+// StageError is a pipeline failure mapped to an HTTP error response
+type StageError struct {
+	Status  int
+	Message string
+	Details interface{}
+}
+
+// Error implements the error interface
+func (e *StageError) Error() string {
+	if e.Details != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Details)
+	}
+	return e.Message
+}
+
+// stageError creates a StageError
+func stageError(status int, message string, details interface{}) *StageError {
+	return &StageError{Status: status, Message: message, Details: details}
+}
+
+// stageFunc adapts a function to the Stage interface
+type stageFunc struct {
+	name string
+	fn   func(ex *Exchange) error
+}
+
+// NewStage creates a stage from a function
+func NewStage(name string, fn func(ex *Exchange) error) Stage {
+	return stageFunc{name: name, fn: fn}
+}
+
+// Name returns the stage name
+func (s stageFunc) Name() string {
+	return s.name
+}
+
+// Process runs the stage function
+func (s stageFunc) Process(ex *Exchange) error {
+	return s.fn(ex)
+}
+
+// Pipeline runs a route's stages in order and maps stage errors to responses
+type Pipeline struct {
+	route   types.RouteConfig
+	stages  []Stage
+	schemas *routeSchemas
+}
+
+// routeSchemas holds the schemas of a route prepared for validation, so
+// requests look up their compiled form without encoding them again
+type routeSchemas struct {
+	request   *validator.PreparedSchema
+	candidate *validator.PreparedSchema
+	response  *validator.PreparedSchema
+}
+
+// prepareRouteSchemas prepares the schemas a route validates against
+func prepareRouteSchemas(route types.RouteConfig) *routeSchemas {
+	return &routeSchemas{
+		request:   validator.PrepareSchema(route.RequestSchema),
+		candidate: validator.PrepareSchema(route.CandidateRequestSchema),
+		response:  validator.PrepareSchema(route.ResponseSchema),
+	}
+}
+
+// Stages returns the names of the pipeline stages in execution order
+func (p *Pipeline) Stages() []string {
+	names := make([]string, len(p.stages))
+	for i, stage := range p.stages {
+		names[i] = stage.Name()
+	}
+	return names
+}
+
+// ServeHTTP serves a request through the pipeline
+func (p *Pipeline) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc, r := reqctx.Attach(r)
+	ex := &Exchange{
+		Writer:          newResponseWriter(w),
+		Request:         r,
+		Route:           p.route,
+		RequestContext:  rc,
+		ResponseHeaders: make(map[string]string),
+		schemas:         p.schemas,
+	}
+	defer func(start time.Time) {
+		ex.Writer.WriteHeaderNow()
+		metrics.RouteLatency.WithLabelValues(p.route.RouteName).Observe(time.Since(start).Seconds())
+		metrics.RouteRequests.WithLabelValues(p.route.RouteName, strconv.Itoa(ex.Writer.Status())).Inc()
+	}(time.Now())
+
+	for _, stage := range p.stages {
+		start := time.Now()
+		err := stage.Process(ex)
+		elapsed := time.Since(start)
+		ex.AddTiming(stage.Name(), elapsed)
+		metrics.PipelineStageLatency.WithLabelValues(p.route.RouteName, stage.Name()).Observe(elapsed.Seconds())
+		if err == nil {
+			continue
+		}
+
+		var failure *StageError
+		if !errors.As(err, &failure) {
+			failure = stageError(http.StatusInternalServerError, fmt.Sprintf("%s stage failed: %v", stage.Name(), err), nil)
+		}
+		metrics.PipelineStageErrors.WithLabelValues(p.route.RouteName, stage.Name(), strconv.Itoa(failure.Status)).Inc()
+
+		if !ex.Written {
+			writeStageError(ex.Writer, failure, ex.RequestID)
+		}
+		ex.finish()
+		return
+	}
+
+	if ex.onWritten != nil {
+		ex.onWritten()
+	}
+	ex.finish()
+}
+
+// finish runs the onFinished hook of an exchange
+func (ex *Exchange) finish() {
+	if ex.onFinished != nil {
+		ex.onFinished()
+	}
+}
+
+// writeStageError writes a stage failure as a JSON error response
+func writeStageError(w http.ResponseWriter, failure *StageError, requestID string) {
+	response := map[string]interface{}{
+		"error": failure.Message,
+	}
+	if failure.Details != nil {
+		response["details"] = failure.Details
+	}
+	if requestID != "" {
+		response["requestId"] = requestID
+	}
+	writeJSON(w, failure.Status, response)
+}
+
+// AddStage inserts a custom stage into every route pipeline right after the
+// named built-in stage. Routes compiled before the call are not affected
+// until the configuration is applied again.
+func (rm *RouteManager) AddStage(after string, stage Stage) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.extraStages[after] = append(rm.extraStages[after], stage)
+}
+
+// buildPipeline assembles the stages serving a route
+func (rm *RouteManager) buildPipeline(route types.RouteConfig) *Pipeline {
+	builtins := []Stage{
+		NewStage(StageDecode, rm.decodeStage),
+		NewStage(StageValidate, rm.validateStage),
+	}
+	if len(route.Throttles) > 0 {
+		builtins = append(builtins, NewStage(StageThrottle, rm.throttleStage))
+	}
+	builtins = append(builtins,
+		NewStage(StageEnrich, enrichStage),
+		NewStage(StageAuthorize, rm.authorizeStage),
+		NewStage(StageExecute, rm.executorFor(route)),
+		NewStage(StageValidateResponse, rm.validateResponseStage),
+		NewStage(StageEncode, encodeStage),
+	)
+
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	pipeline := &Pipeline{route: route, schemas: prepareRouteSchemas(route)}
+	for _, stage := range builtins {
+		pipeline.stages = append(pipeline.stages, stage)
+		pipeline.stages = append(pipeline.stages, rm.extraStages[stage.Name()]...)
+	}
+	return pipeline
+}
@@ -0,0 +1,244 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const allowPostPolicy = `package allow_post
+
+import future.keywords.if
+
+default allow = false
+
+allow if {
+	input.method == "POST"
+	input.body.serviceId != "blocked"
+}
+`
+
+func newTestPipeline(t *testing.T, route types.RouteConfig, configure func(rm *RouteManager)) http.Handler {
+	policyManager := opa.NewPolicyManager()
+	if err := policyManager.ReplacePolicies(map[string]string{"allow_post": allowPostPolicy}); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	rm := NewRouteManager(policyManager, validator.NewSchemaValidator())
+	if configure != nil {
+		configure(rm)
+	}
+
+	return rm.buildPipeline(route)
+}
+
+func serve(handler http.Handler, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/items", strings.NewReader(body))
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestPipelineStageOrder(t *testing.T) {
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	rm.AddStage(StageAuthorize, NewStage("quota", func(ex *Exchange) error { return nil }))
+
+	stages := rm.buildPipeline(types.RouteConfig{RouteName: "/v1/items", Method: "POST"}).Stages()
+	expected := []string{StageDecode, StageValidate, StageEnrich, StageAuthorize, "quota", StageExecute, StageValidateResponse, StageEncode}
+	if strings.Join(stages, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected stages %v, got %v", expected, stages)
+	}
+}
+
+func TestPipelineErrorMapping(t *testing.T) {
+	route := types.RouteConfig{
+		RouteName: "/v1/items",
+		Method:    "POST",
+		Policies:  []string{"allow_post"},
+		RequestSchema: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"serviceId"},
+		},
+	}
+	engine := newTestPipeline(t, route, nil)
+
+	cases := []struct {
+		name   string
+		body   string
+		status int
+		error  string
+	}{
+		{"decode", `{not json`, http.StatusBadRequest, "Invalid JSON"},
+		{"validate", `{}`, http.StatusBadRequest, "Request validation failed"},
+		{"authorize", `{"serviceId":"blocked"}`, http.StatusForbidden, "Request denied by policy"},
+		{"execute", `{"serviceId":"svc"}`, http.StatusOK, ""},
+	}
+
+	for _, tc := range cases {
+		recorder := serve(engine, tc.body)
+		if recorder.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", tc.name, tc.status, recorder.Code, recorder.Body.String())
+		}
+		if tc.error != "" && !strings.Contains(recorder.Body.String(), tc.error) {
+			t.Errorf("%s: expected error %q, got %s", tc.name, tc.error, recorder.Body.String())
+		}
+	}
+}
+
+func TestPipelineCustomStageStopsRequest(t *testing.T) {
+	route := types.RouteConfig{RouteName: "/v1/items", Method: "POST", Policies: []string{"allow_post"}}
+	executed := false
+	engine := newTestPipeline(t, route, func(rm *RouteManager) {
+		rm.AddStage(StageAuthorize, NewStage("quota", func(ex *Exchange) error {
+			return &StageError{Status: http.StatusTooManyRequests, Message: "Quota exceeded"}
+		}))
+		rm.AddStage(StageExecute, NewStage("observe", func(ex *Exchange) error {
+			executed = true
+			return nil
+		}))
+	})
+
+	recorder := serve(engine, `{"serviceId":"svc"}`)
+	if recorder.Code != http.StatusTooManyRequests || !strings.Contains(recorder.Body.String(), "Quota exceeded") {
+		t.Errorf("Expected the custom stage error, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if executed {
+		t.Error("Expected stages after the failing stage to be skipped")
+	}
+}
+
+func TestPipelineCandidateSchemaIsNotEnforced(t *testing.T) {
+	route := types.RouteConfig{
+		RouteName:     "/v1/items",
+		Method:        "POST",
+		RequestSchema: map[string]interface{}{"type": "object"},
+		CandidateRequestSchema: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"serviceId"},
+		},
+		MockResponse: &types.MockResponseConfig{Template: `{}`},
+	}
+	engine := newTestPipeline(t, route, nil)
+	counter := metrics.SchemaCanaryValidations.WithLabelValues("/v1/items", "valid", "invalid")
+	before := testutil.ToFloat64(counter)
+
+	if recorder := serve(engine, `{"name": "a"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("Expected candidate schema not to be enforced, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("Expected one would-be rejection to be counted, got %v", got)
+	}
+}
+
+func TestPipelineShadowPolicyDoesNotDeny(t *testing.T) {
+	route := types.RouteConfig{
+		RouteName:      "/v1/items",
+		Method:         "POST",
+		Policies:       []string{"allow_post"},
+		PolicySettings: map[string]types.PolicySettings{"allow_post": {Mode: types.PolicyModeShadow}},
+		MockResponse:   &types.MockResponseConfig{Template: `{}`},
+	}
+	var rm *RouteManager
+	engine := newTestPipeline(t, route, func(configured *RouteManager) { rm = configured })
+	counter := metrics.ShadowPolicyDecisions.WithLabelValues("/v1/items", "allow_post", types.OutcomeDeny)
+	before := testutil.ToFloat64(counter)
+
+	if recorder := serve(engine, `{"serviceId": "blocked"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("Expected shadow policy not to deny, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("Expected one shadow denial to be counted, got %v", got)
+	}
+
+	var shadow []types.Decision
+	for _, decision := range rm.GetDecisions().List() {
+		if decision.Shadow {
+			shadow = append(shadow, decision)
+		}
+	}
+	if len(shadow) != 1 || shadow[0].Allowed || shadow[0].Policies[0] != "allow_post" {
+		t.Errorf("Expected a recorded shadow denial, got %+v", shadow)
+	}
+}
+
+func TestValidatePolicySettings(t *testing.T) {
+	route := types.RouteConfig{
+		Policies:       []string{"allow_post"},
+		PolicySettings: map[string]types.PolicySettings{"allow_post": {Mode: "audit"}},
+	}
+	if err := validatePolicySettings(route); err == nil {
+		t.Error("Expected an unknown mode to be rejected")
+	}
+	route.PolicySettings = map[string]types.PolicySettings{"other": {Mode: types.PolicyModeShadow}}
+	if err := validatePolicySettings(route); err == nil {
+		t.Error("Expected settings of an unused policy to be rejected")
+	}
+}
+
+func TestPipelineAppliesResponsePatch(t *testing.T) {
+	route := types.RouteConfig{
+		RouteName: "/v1/items",
+		Method:    "POST",
+		ResponsePatch: []types.PatchOperation{
+			{Op: "remove", Path: "/route"},
+			{Op: "add", Path: "/features", Value: map[string]interface{}{"beta": true}},
+		},
+	}
+	engine := newTestPipeline(t, route, nil)
+
+	recorder := serve(engine, `{"serviceId":"svc-1"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	body := recorder.Body.String()
+	if strings.Contains(body, `"route"`) || !strings.Contains(body, `"features":{"beta":true}`) {
+		t.Errorf("Expected the patched response, got %s", body)
+	}
+}
+
+func TestPipelineValidatesQueryAndHeaders(t *testing.T) {
+	route := types.RouteConfig{
+		RouteName: "/v1/status",
+		Method:    "GET",
+		QuerySchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"limit": map[string]interface{}{"type": "integer"}},
+		},
+		HeaderSchema: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"x-tenant-id"},
+		},
+	}
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	pipeline := rm.buildPipeline(route)
+
+	cases := []struct {
+		name   string
+		target string
+		tenant string
+		status int
+		error  string
+	}{
+		{"valid", "/v1/status?limit=5", "acme", http.StatusOK, ""},
+		{"missing header", "/v1/status?limit=5", "", http.StatusBadRequest, "Header validation failed"},
+		{"invalid query", "/v1/status?limit=five", "acme", http.StatusBadRequest, "Query validation failed"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if tc.tenant != "" {
+			req.Header.Set("X-Tenant-Id", tc.tenant)
+		}
+		recorder := httptest.NewRecorder()
+		pipeline.ServeHTTP(recorder, req)
+		if recorder.Code != tc.status || !strings.Contains(recorder.Body.String(), tc.error) {
+			t.Errorf("%s: expected %d %q, got %d %s", tc.name, tc.status, tc.error, recorder.Code, recorder.Body.String())
+		}
+	}
+}
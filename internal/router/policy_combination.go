@@ -0,0 +1,63 @@
+package router
+
+import (
+	"fmt"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+)
+
+// validatePolicyCombination checks the policy combination of a route.
+// Priority lists must rank every enforced policy of the route exactly once;
+// shadow policies never decide requests, so they cannot be ranked.
+func validatePolicyCombination(route types.RouteConfig) error {
+	combination := route.PolicyCombination
+	if combination == nil {
+		return nil
+	}
+	switch combination.Combine {
+	case types.PolicyCombineAll, types.PolicyCombineAny:
+		if len(combination.Priority) > 0 {
+			return fmt.Errorf("policy combination %s cannot have priority rules", combination.Combine)
+		}
+		return nil
+	case types.PolicyCombinePriority:
+	default:
+		return fmt.Errorf("unsupported policy combination %q", combination.Combine)
+	}
+
+	enforced, _ := splitPolicies(route)
+	if len(combination.Priority) == 0 {
+		return fmt.Errorf("priority policy combination requires priority rules")
+	}
+	ranked := make(map[string]bool, len(combination.Priority))
+	for _, rule := range combination.Priority {
+		if !containsString(enforced, rule.Policy) {
+			return fmt.Errorf("priority rule refers to policy %s, which the route does not enforce", rule.Policy)
+		}
+		if ranked[rule.Policy] {
+			return fmt.Errorf("policy %s is ranked more than once", rule.Policy)
+		}
+		ranked[rule.Policy] = true
+		switch rule.Effect {
+		case types.PolicyEffectAllow, types.PolicyEffectDeny:
+		default:
+			return fmt.Errorf("unsupported effect %q for policy %s", rule.Effect, rule.Policy)
+		}
+	}
+	for _, policy := range enforced {
+		if !ranked[policy] {
+			return fmt.Errorf("policy %s is missing from the priority rules", policy)
+		}
+	}
+	return nil
+}
+
+// evaluateRoutePolicies evaluates the enforced policies of a route with its
+// policy combination, or with the evaluator's when it has none
+func evaluateRoutePolicies(evaluator opa.PolicyEvaluator, route types.RouteConfig, enforced []string, input map[string]interface{}) (*types.PolicyResult, error) {
+	if route.PolicyCombination == nil {
+		return evaluator.EvaluatePolicies(enforced, input)
+	}
+	return opa.Combine(evaluator, enforced, input, *route.PolicyCombination), nil
+}
@@ -0,0 +1,113 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoutePolicyCombinationOverridesGlobal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policyManager := opa.NewPolicyManager()
+	policyManager.SetPolicy("blocklist", "package blocklist\n\ndefault allow = true\n\nallow = false { input.headers[\"X-Client\"] == \"blocked\" }\n")
+	policyManager.SetPolicy("admin", "package admin\n\ndefault allow = false\n\nallow { input.headers[\"X-Role\"] == \"admin\" }\n")
+	rm := NewRouteManager(policyManager, validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+
+	mock := &types.MockResponseConfig{Template: `{}`}
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/all", Method: "GET", Policies: []string{"blocklist", "admin"}, MockResponse: mock},
+		{
+			RouteName: "/v1/any", Method: "GET", Policies: []string{"blocklist", "admin"}, MockResponse: mock,
+			PolicyCombination: &types.PolicyCombination{Combine: types.PolicyCombineAny},
+		},
+		{
+			RouteName: "/v1/priority", Method: "GET", Policies: []string{"admin", "blocklist"}, MockResponse: mock,
+			PolicyCombination: &types.PolicyCombination{Combine: types.PolicyCombinePriority, Priority: []types.PolicyPriority{
+				{Policy: "blocklist", Effect: types.PolicyEffectDeny},
+				{Policy: "admin", Effect: types.PolicyEffectAllow},
+			}},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	get := func(path string, headers map[string]string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+	for _, tc := range []struct {
+		path     string
+		headers  map[string]string
+		expected int
+	}{
+		{"/v1/all", nil, http.StatusForbidden},
+		{"/v1/any", nil, http.StatusOK},
+		{"/v1/priority", map[string]string{"X-Role": "admin"}, http.StatusOK},
+		{"/v1/priority", map[string]string{"X-Role": "admin", "X-Client": "blocked"}, http.StatusForbidden},
+		{"/v1/priority", nil, http.StatusForbidden},
+	} {
+		if code := get(tc.path, tc.headers); code != tc.expected {
+			t.Errorf("Expected %d for %s with %v, got %d", tc.expected, tc.path, tc.headers, code)
+		}
+	}
+}
+
+func TestValidatePolicyCombination(t *testing.T) {
+	route := types.RouteConfig{
+		Policies:       []string{"blocklist", "admin", "audit"},
+		PolicySettings: map[string]types.PolicySettings{"audit": {Mode: types.PolicyModeShadow}},
+	}
+	priority := func(rules ...types.PolicyPriority) *types.PolicyCombination {
+		return &types.PolicyCombination{Combine: types.PolicyCombinePriority, Priority: rules}
+	}
+	deny := func(policy string) types.PolicyPriority {
+		return types.PolicyPriority{Policy: policy, Effect: types.PolicyEffectDeny}
+	}
+	allow := func(policy string) types.PolicyPriority {
+		return types.PolicyPriority{Policy: policy, Effect: types.PolicyEffectAllow}
+	}
+
+	valid := []*types.PolicyCombination{
+		nil,
+		{Combine: types.PolicyCombineAll},
+		{Combine: types.PolicyCombineAny},
+		priority(deny("blocklist"), allow("admin")),
+	}
+	for _, combination := range valid {
+		route.PolicyCombination = combination
+		if err := validatePolicyCombination(route); err != nil {
+			t.Errorf("Expected %+v to be valid: %v", combination, err)
+		}
+	}
+
+	invalid := map[string]*types.PolicyCombination{
+		"unknown combination": {Combine: "majority"},
+		"empty priority":      priority(),
+		"unknown policy":      priority(deny("blocklist"), allow("admin"), allow("other")),
+		"shadow policy":       priority(deny("blocklist"), allow("admin"), allow("audit")),
+		"duplicate policy":    priority(deny("blocklist"), allow("admin"), allow("blocklist")),
+		"missing policy":      priority(deny("blocklist")),
+		"unknown effect":      priority(deny("blocklist"), types.PolicyPriority{Policy: "admin", Effect: "abstain"}),
+	}
+	for name, combination := range invalid {
+		route.PolicyCombination = combination
+		if err := validatePolicyCombination(route); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/upstream"
+	"dynamiccontrol/internal/validator"
+)
+
+// executeProxy forwards the request to one of the route's weighted upstream
+// targets and streams the upstream response back to the client
+func (rm *RouteManager) executeProxy(ex *Exchange) error {
+	route := ex.Route
+
+	// Forward the canonical body so upstreams see the same input as policies
+	var rawBody []byte
+	if ex.HasBody {
+		encoded, err := validator.CanonicalJSON(ex.Body)
+		if err != nil {
+			return stageError(http.StatusInternalServerError, fmt.Sprintf("Failed to encode request body: %v", err), nil)
+		}
+		rawBody = encoded
+	}
+
+	ctx := rm.upstreamContext(ex.Request.Context(), ex)
+	if route.TimeoutMs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(route.TimeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+
+	balancer := rm.balancer(routeKey(route))
+	maxAttempts := upstream.MaxAttempts(route.Retry)
+	if !upstream.Retryable(ex.Request.Method) {
+		maxAttempts = 1
+	}
+
+	var resp *http.Response
+	var err error
+	for attempt := 1; ; attempt++ {
+		target, ok := balancer.Next()
+		if !ok {
+			return stageError(http.StatusServiceUnavailable, "No healthy upstream available", nil)
+		}
+
+		targetURL, resolveErr := rm.resolveUpstream(target)
+		if resolveErr != nil {
+			return resolveErr
+		}
+
+		start := time.Now()
+		resp, err = rm.upstreamClient.Forward(ctx, targetURL, ex.Request, rawBody)
+		metrics.UpstreamLatency.WithLabelValues(route.RouteName, target.Name).Observe(time.Since(start).Seconds())
+
+		code := "error"
+		if err == nil {
+			code = strconv.Itoa(resp.StatusCode)
+		}
+		metrics.UpstreamRequests.WithLabelValues(route.RouteName, target.Name, code).Inc()
+
+		retryable := err != nil || upstream.ShouldRetry(route.Retry, resp.StatusCode)
+		if !retryable || attempt >= maxAttempts || ctx.Err() != nil {
+			break
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+			resp = nil
+		}
+		logging.FromContext(ctx).Info("Retrying upstream request", "route", routeKey(route), "attempt", attempt+1, "max_attempts", maxAttempts)
+		if err := upstream.Sleep(ctx, upstream.Backoff(route.Retry, attempt)); err != nil {
+			break
+		}
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return stageError(http.StatusGatewayTimeout, "Upstream request timed out", nil)
+	}
+
+	if resp == nil {
+		return stageError(http.StatusBadGateway, fmt.Sprintf("Upstream request failed: %v", err), nil)
+	}
+	defer resp.Body.Close()
+
+	if len(route.ResponsePatch) > 0 && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return rm.bufferProxyResponse(ex, resp)
+	}
+
+	upstream.CopyHeaders(ex.Writer.Header(), resp.Header)
+	ex.Writer.WriteHeader(resp.StatusCode)
+	ex.Written = true
+
+	// Keep a copy of sampled responses for validation after streaming
+	var body io.Writer = ex.Writer
+	var sample *responseSample
+	if rm.sampleResponse(route, resp.StatusCode) {
+		sample = &responseSample{}
+		body = io.MultiWriter(ex.Writer, sample)
+	}
+	if _, err := io.Copy(body, resp.Body); err != nil {
+		logging.FromContext(ctx).Error("Failed to copy upstream response", "route", routeKey(route), "error", err)
+	} else if sample != nil {
+		rm.validateSample(ex, sample, resp.Header)
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/identity"
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/tracing"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestProxyAppliesHeaderPolicy(t *testing.T) {
+	received := make(chan http.Header, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName: "/v1/orders/:orderId",
+		Method:    "GET",
+		Handler:   types.HandlerProxy,
+		Upstreams: []types.UpstreamTarget{{URL: backend.URL}},
+		Headers: &types.HeaderPolicy{
+			Forward: []string{"Accept", "X-Tenant-*"},
+			Add:     map[string]string{"X-Order-Id": "${param.orderId}"},
+		},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/orders/42", nil)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Tenant-Id", "acme")
+	req.Header.Set("User-Agent", "test")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	header := <-received
+	if header.Get("Accept") != "application/json" || header.Get("X-Tenant-Id") != "acme" {
+		t.Errorf("Expected allowlisted headers upstream, got %v", header)
+	}
+	if header.Get("Authorization") != "" || strings.Contains(header.Get("User-Agent"), "test") {
+		t.Errorf("Expected unlisted headers to be dropped, got %v", header)
+	}
+	if header.Get("X-Order-Id") != "42" {
+		t.Errorf("Expected added header X-Order-Id=42, got %q", header.Get("X-Order-Id"))
+	}
+}
+
+func TestProxyAddsIdentityAssertion(t *testing.T) {
+	received := make(chan http.Header, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	signer, err := identity.NewSigner([]byte("0123456789abcdef0123456789abcdef"), identity.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	rm.SetIdentitySigner(signer)
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err = rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName: "/v1/orders",
+		Method:    "GET",
+		Handler:   types.HandlerProxy,
+		Upstreams: []types.UpstreamTarget{{URL: backend.URL}},
+		Headers:   &types.HeaderPolicy{Forward: []string{"*"}},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/orders", nil)
+	req.Header.Set(identity.DefaultHeader, "forged")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	claims, err := signer.Verify((<-received).Get(identity.DefaultHeader))
+	if err != nil {
+		t.Fatalf("Expected a valid assertion upstream: %v", err)
+	}
+	if claims.Audience != "GET /v1/orders" || claims.Decision == nil || !claims.Decision.Allowed {
+		t.Errorf("Unexpected assertion claims %+v", claims)
+	}
+}
+
+func TestProxyValidatesSampledResponses(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status": 42}`))
+	}))
+	defer backend.Close()
+
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName:             "/v1/sampled",
+		Method:                "GET",
+		Handler:               types.HandlerProxy,
+		Upstreams:             []types.UpstreamTarget{{URL: backend.URL}},
+		ResponseSamplePercent: 100,
+		ResponseSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"status": map[string]interface{}{"type": "string"}},
+		},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	invalid := metrics.ResponseSampleValidations.WithLabelValues("/v1/sampled", sampleInvalid)
+	before := testutil.ToFloat64(invalid)
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/sampled", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != `{"status": 42}` {
+		t.Fatalf("Expected the response to be streamed unchanged, got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(invalid) == before {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the schema violation to be recorded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestProxyPropagatesRouteTelemetry(t *testing.T) {
+	received := make(chan http.Header, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.Use(tracing.Middleware())
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName: "/v1/orders/:orderId",
+		Method:    "GET",
+		Handler:   types.HandlerProxy,
+		Upstreams: []types.UpstreamTarget{{URL: backend.URL}},
+		Headers:   &types.HeaderPolicy{Forward: []string{"Accept"}},
+		Telemetry: &types.TelemetryConfig{
+			Attributes: map[string]string{"team": "payments", "order.id": "${param.orderId}"},
+			Baggage:    map[string]string{"tier": "gold"},
+		},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/orders/42", nil)
+	req.Header.Set("Baggage", "experiment=checkout-b")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	header := <-received
+	if header.Get("Traceparent") == "" {
+		t.Error("Expected the trace context to be propagated upstream")
+	}
+	bag := header.Get("Baggage")
+	if !strings.Contains(bag, "tier=gold") || !strings.Contains(bag, "experiment=checkout-b") {
+		t.Errorf("Expected route and caller baggage upstream, got %q", bag)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "GET /v1/orders/:orderId" {
+		t.Fatalf("Expected one span named after the route, got %v", spans)
+	}
+	attributes := make(map[string]string)
+	for _, attribute := range spans[0].Attributes() {
+		attributes[string(attribute.Key)] = attribute.Value.Emit()
+	}
+	if attributes["team"] != "payments" || attributes["order.id"] != "42" {
+		t.Errorf("Expected route attributes on the span, got %v", attributes)
+	}
+}
+
+func TestProxyAppliesResponsePatch(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Upstream", "orders")
+		if r.URL.Path == "/v1/orders/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found","legacyId":"x"}`))
+			return
+		}
+		w.Write([]byte(`{"id":"42","legacyId":"x"}`))
+	}))
+	defer backend.Close()
+
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName: "/v1/orders/:orderId",
+		Method:    "GET",
+		Handler:   types.HandlerProxy,
+		Upstreams: []types.UpstreamTarget{{URL: backend.URL}},
+		ResponsePatch: []types.PatchOperation{
+			{Op: "remove", Path: "/legacyId"},
+			{Op: "add", Path: "/flags", Value: []interface{}{"fast-checkout"}},
+		},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/orders/42", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("X-Upstream") != "orders" {
+		t.Fatalf("Expected the upstream response, got %d %v", recorder.Code, recorder.Header())
+	}
+	if body := recorder.Body.String(); body != `{"flags":["fast-checkout"],"id":"42"}` {
+		t.Errorf("Expected the patched response, got %s", body)
+	}
+
+	// Error responses are passed through unchanged
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/orders/missing", nil))
+	if recorder.Code != http.StatusNotFound || !strings.Contains(recorder.Body.String(), "legacyId") {
+		t.Errorf("Expected the unpatched error response, got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestProxyRetriesIdempotentRequestsOnly(t *testing.T) {
+	attempts := make(map[string]int)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts[r.Method]++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	retry := &types.RetryConfig{MaxAttempts: 3, BackoffMs: 1}
+	var routes []types.RouteConfig
+	for _, method := range []string{"GET", "PUT", "DELETE", "POST"} {
+		routes = append(routes, types.RouteConfig{
+			RouteName: "/v1/orders",
+			Method:    method,
+			Handler:   types.HandlerProxy,
+			Upstreams: []types.UpstreamTarget{{URL: backend.URL}},
+			Retry:     retry,
+		})
+	}
+	if err := rm.ApplyConfig(&types.RoutesConfig{Routes: routes}); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	expected := map[string]int{"GET": 3, "PUT": 3, "DELETE": 3, "POST": 1}
+	for method, count := range expected {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/v1/orders", nil)
+		if method != "GET" {
+			req = httptest.NewRequest(method, "/v1/orders", strings.NewReader(`{"serviceId": "svc-1"}`))
+		}
+		rm.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected the upstream status, got %d: %s", method, recorder.Code, recorder.Body.String())
+		}
+		if attempts[method] != count {
+			t.Errorf("%s: expected %d attempts, got %d", method, count, attempts[method])
+		}
+	}
+}
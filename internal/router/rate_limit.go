@@ -0,0 +1,136 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/ratelimit"
+	"dynamiccontrol/internal/reqctx"
+	"dynamiccontrol/internal/types"
+)
+
+// Rate limit keys partitioning a route's limit
+const (
+	rateLimitKeyRoute  = "route"
+	rateLimitKeyIP     = "ip"
+	rateLimitKeyHeader = "header:"
+	rateLimitKeyParam  = "param:"
+)
+
+// SetRateLimiter replaces the limiter counting requests of rate limited
+// routes, such as with one shared by replicas
+func (rm *RouteManager) SetRateLimiter(limiter ratelimit.Limiter) {
+	rm.limiter = limiter
+}
+
+// validateRateLimit checks the rate limit configuration of a route at registration time
+func validateRateLimit(route types.RouteConfig) error {
+	config := route.RateLimit
+	if config == nil {
+		return nil
+	}
+	if config.Requests <= 0 {
+		return fmt.Errorf("rateLimit requests must be positive")
+	}
+	if config.WindowSeconds <= 0 {
+		return fmt.Errorf("rateLimit windowSeconds must be positive")
+	}
+	if !validRateLimitKey(route, config.Key) {
+		return fmt.Errorf("unsupported rateLimit key %q, expected route, ip, header:<name> or param:<name>", config.Key)
+	}
+	return nil
+}
+
+// validRateLimitKey reports whether a key partitioning a limit of the route
+// is supported
+func validRateLimitKey(route types.RouteConfig, key string) bool {
+	switch {
+	case key == "", key == rateLimitKeyRoute, key == rateLimitKeyIP:
+	case strings.HasPrefix(key, rateLimitKeyHeader) && len(key) > len(rateLimitKeyHeader):
+	case strings.HasPrefix(key, rateLimitKeyParam) && hasPathParam(route.RouteName, strings.TrimPrefix(key, rateLimitKeyParam)):
+	default:
+		return false
+	}
+	return true
+}
+
+// hasPathParam reports whether a route pattern declares a path parameter
+func hasPathParam(pattern, name string) bool {
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == ":"+name || segment == "*"+name {
+			return true
+		}
+	}
+	return false
+}
+
+// limitRequests rejects requests beyond the route's rate limit with 429 Too
+// Many Requests and describes the limit in the RateLimit headers of every
+// response. Requests are let through when the limiter fails.
+func (rm *RouteManager) limitRequests(route types.RouteConfig) func(next http.Handler) http.Handler {
+	config := route.RateLimit
+	window := time.Duration(config.WindowSeconds) * time.Second
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rc, r := reqctx.Attach(r)
+			key := "route:" + routeKey(route) + ":" + rateLimitPartition(rc, r, config.Key)
+			result, err := rm.limiter.Allow(r.Context(), key, config.Requests, window)
+			if err != nil {
+				logging.FromContext(r.Context()).Warn("Rate limiter unavailable, allowing request", "route", routeKey(route), "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !config.DisableHeaders {
+				ratelimit.SetHeaders(w.Header(), result)
+			}
+			if result.Allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			metrics.RateLimitedRequests.WithLabelValues(route.RouteName, "rate-limit").Inc()
+			writeRateLimited(w, rc.RequestID, result, "rate-limit", fmt.Sprintf("%d requests per %s exceeded", config.Requests, window))
+		})
+	}
+}
+
+// rateLimitPartition returns the part of the limiter key that separates
+// clients sharing a route's limit
+func rateLimitPartition(rc *reqctx.RequestContext, r *http.Request, key string) string {
+	switch {
+	case key == rateLimitKeyIP:
+		return rc.Client.IP
+	case strings.HasPrefix(key, rateLimitKeyHeader):
+		return r.Header.Get(strings.TrimPrefix(key, rateLimitKeyHeader))
+	case strings.HasPrefix(key, rateLimitKeyParam):
+		return rc.Params[strings.TrimPrefix(key, rateLimitKeyParam)]
+	default:
+		return ""
+	}
+}
+
+// writeRateLimited writes the standard 429 response of a rejected request.
+// The reason names the limit that was hit and Retry-After tells the client
+// when the window resets.
+func writeRateLimited(w http.ResponseWriter, requestID string, result ratelimit.Result, reason, message string) {
+	w.Header().Set("Retry-After", ratelimit.RetryAfter(result))
+	response := map[string]interface{}{
+		"error": "Too many requests",
+		"details": map[string]interface{}{
+			"reason":       reason,
+			"message":      message,
+			"limit":        result.Limit,
+			"remaining":    result.Remaining,
+			"resetSeconds": ratelimit.ResetSeconds(result),
+			"policy":       ratelimit.Policy(result.Limit, result.Window),
+		},
+	}
+	if requestID != "" {
+		response["requestId"] = requestID
+	}
+	writeJSON(w, http.StatusTooManyRequests, response)
+}
@@ -0,0 +1,102 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitHeadersAndRejection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{
+		{
+			RouteName: "/v1/services/:id/status",
+			Method:    "GET",
+			RateLimit: &types.RateLimitConfig{Requests: 2, WindowSeconds: 60, Key: "param:id"},
+		},
+		{
+			RouteName: "/v1/quiet",
+			Method:    "GET",
+			RateLimit: &types.RateLimitConfig{Requests: 1, WindowSeconds: 60, DisableHeaders: true},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		recorder := get("/v1/services/a/status")
+		if recorder.Code != http.StatusOK || recorder.Header().Get("RateLimit-Remaining") != remaining {
+			t.Fatalf("Request %d: expected 200 with %s remaining, got %d with %q", i, remaining, recorder.Code, recorder.Header().Get("RateLimit-Remaining"))
+		}
+		if recorder.Header().Get("RateLimit-Limit") != "2" || recorder.Header().Get("RateLimit-Policy") != "2;w=60" {
+			t.Errorf("Unexpected rate limit headers %v", recorder.Header())
+		}
+	}
+
+	rejected := get("/v1/services/a/status")
+	if rejected.Code != http.StatusTooManyRequests || rejected.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected 429 with Retry-After, got %d: %v", rejected.Code, rejected.Header())
+	}
+	var body struct {
+		Error   string                 `json:"error"`
+		Details map[string]interface{} `json:"details"`
+	}
+	if err := json.Unmarshal(rejected.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Details["reason"] != "rate-limit" || body.Details["limit"] != float64(2) {
+		t.Errorf("Unexpected 429 body %s", rejected.Body.String())
+	}
+
+	// Each path parameter value has its own limit
+	if recorder := get("/v1/services/b/status"); recorder.Code != http.StatusOK {
+		t.Errorf("Expected another service to be allowed, got %d", recorder.Code)
+	}
+
+	if recorder := get("/v1/quiet"); recorder.Code != http.StatusOK || recorder.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("Expected no rate limit headers, got %v", recorder.Header())
+	}
+	if recorder := get("/v1/quiet"); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 with headers disabled, got %d", recorder.Code)
+	}
+}
+
+func TestValidateRateLimit(t *testing.T) {
+	cases := []struct {
+		name   string
+		config types.RateLimitConfig
+		valid  bool
+	}{
+		{"route key", types.RateLimitConfig{Requests: 5, WindowSeconds: 1}, true},
+		{"header key", types.RateLimitConfig{Requests: 5, WindowSeconds: 1, Key: "header:X-Service-ID"}, true},
+		{"param key", types.RateLimitConfig{Requests: 5, WindowSeconds: 1, Key: "param:id"}, true},
+		{"unknown param", types.RateLimitConfig{Requests: 5, WindowSeconds: 1, Key: "param:name"}, false},
+		{"unknown key", types.RateLimitConfig{Requests: 5, WindowSeconds: 1, Key: "user"}, false},
+		{"no window", types.RateLimitConfig{Requests: 5}, false},
+	}
+	for _, tc := range cases {
+		config := tc.config
+		route := types.RouteConfig{RouteName: "/v1/services/:id", Method: "GET", RateLimit: &config}
+		if err := validateRateLimit(route); (err == nil) != tc.valid {
+			t.Errorf("%s: expected valid=%v, got %v", tc.name, tc.valid, err)
+		}
+	}
+}
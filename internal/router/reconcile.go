@@ -0,0 +1,134 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+
+	"dynamiccontrol/internal/events"
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/reconcile"
+	"dynamiccontrol/internal/types"
+)
+
+// Traffic intents are the rules of the traffic controller and the records of
+// the traffic collection, keyed by the service path parameter of the routes
+// serving the service
+const (
+	trafficCollection   = "traffic"
+	trafficServiceParam = "serviceId"
+)
+
+// SetReconciler enables reconciling the accepted traffic intents of services
+// against their observed traffic. Divergence is published as events, and in
+// correct mode the reconciler's enforced volumes limit service routes.
+func (rm *RouteManager) SetReconciler(reconciler *reconcile.Reconciler) {
+	reconciler.OnTransition(func(previous string, status reconcile.ServiceStatus) {
+		eventType := types.EventTrafficDiverged
+		if status.State == reconcile.StateInSync {
+			if previous == "" {
+				return
+			}
+			eventType = types.EventTrafficConverged
+		} else {
+			slog.Warn("Service traffic diverges from accepted intents", "service", status.Service,
+				"state", status.State, "desired_rps", status.DesiredRPS, "observed_rps", status.ObservedRPS)
+		}
+		rm.broker.Publish(events.NewEvent(eventType, "", "", map[string]interface{}{
+			"service":     status.Service,
+			"state":       status.State,
+			"previous":    previous,
+			"desiredRps":  status.DesiredRPS,
+			"observedRps": status.ObservedRPS,
+			"divergence":  status.Divergence,
+			"enforced":    status.Enforced,
+		}))
+	})
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.reconciler = reconciler
+}
+
+// GetReconciler returns the traffic intent reconciler, if enabled
+func (rm *RouteManager) GetReconciler() *reconcile.Reconciler {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.reconciler
+}
+
+// TrafficIntents sums the volume of the accepted traffic intents of every
+// service, in requests per second
+func (rm *RouteManager) TrafficIntents() map[string]float64 {
+	intents := rm.traffic.Volumes()
+	for _, service := range rm.mockData.RecordKeys(trafficCollection) {
+		for _, record := range rm.mockData.ListRecords(trafficCollection, service) {
+			intents[service] += intentVolume(record["volume"])
+		}
+	}
+	return intents
+}
+
+// intentVolume reads the volume of a traffic intent record
+func intentVolume(value interface{}) float64 {
+	switch volume := value.(type) {
+	case float64:
+		return volume
+	case int:
+		return float64(volume)
+	case int64:
+		return float64(volume)
+	case json.Number:
+		parsed, _ := volume.Float64()
+		return parsed
+	default:
+		return 0
+	}
+}
+
+// servesTraffic reports whether a route serves requests of a service whose
+// traffic is reconciled; routes managing the traffic intents themselves
+// are not counted
+func servesTraffic(route types.RouteConfig) bool {
+	if !hasPathParam(route.RouteName, trafficServiceParam) || route.Handler == types.HandlerTraffic {
+		return false
+	}
+	if mock := route.MockResponse; mock != nil && mock.Store != nil && mock.Store.Collection == trafficCollection {
+		return false
+	}
+	return true
+}
+
+// reconcileTraffic counts the requests of a service route for the
+// reconciler and rejects requests beyond the accepted volume it enforces
+// with 429 Too Many Requests. Requests are let through when the limiter
+// fails.
+func (rm *RouteManager) reconcileTraffic(next func(ex *Exchange) error) func(ex *Exchange) error {
+	return func(ex *Exchange) error {
+		reconciler := rm.GetReconciler()
+		if reconciler == nil {
+			return next(ex)
+		}
+		service := ex.Params[trafficServiceParam]
+
+		if limit, enforced := reconciler.Limit(service); enforced {
+			window := reconciler.Config().Interval
+			requests := int(math.Max(1, math.Round(limit*window.Seconds())))
+			result, err := rm.limiter.Allow(ex.Request.Context(), "reconcile:"+service, requests, window)
+			if err != nil {
+				logging.FromContext(ex.Request.Context()).Warn("Rate limiter unavailable, allowing request",
+					"route", routeKey(ex.Route), "service", service, "error", err)
+			} else if !result.Allowed {
+				metrics.RateLimitedRequests.WithLabelValues(ex.Route.RouteName, "traffic-intent").Inc()
+				writeRateLimited(ex.Writer, ex.RequestID, result, "traffic-intent", fmt.Sprintf("service %s accepted %g requests per second", service, limit))
+				ex.Written = true
+				return stageError(http.StatusTooManyRequests, "Too many requests", nil)
+			}
+		}
+		reconciler.Observe(service)
+		return next(ex)
+	}
+}
@@ -0,0 +1,95 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/reconcile"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReconcileTrafficIntents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{
+		{
+			RouteName: "/v1/services/:serviceId/traffic",
+			Method:    "POST",
+			MockResponse: &types.MockResponseConfig{
+				Store: &types.MockStoreConfig{Collection: "traffic", Operation: "create", KeyParam: "serviceId"},
+			},
+		},
+		{
+			RouteName:    "/v1/services/:serviceId/orders",
+			Method:       "GET",
+			MockResponse: &types.MockResponseConfig{StatusCode: http.StatusOK, Template: `{"orders": []}`},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	reconciler := reconcile.New(reconcile.Config{Interval: time.Minute, Mode: reconcile.ModeCorrect}, rm.TrafficIntents)
+	rm.SetReconciler(reconciler)
+	subscription := rm.broker.Subscribe([]string{"traffic"})
+	defer subscription.Close()
+
+	serve := func(method, path, body string) int {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return recorder.Code
+	}
+	if code := serve(http.MethodPost, "/v1/services/billing/traffic", `{"trafficType": "incoming", "volume": 0.05, "priority": "high"}`); code != http.StatusCreated {
+		t.Fatalf("Expected the intent to be accepted, got %d", code)
+	}
+	if intents := rm.TrafficIntents(); intents["billing"] != 0.05 {
+		t.Fatalf("Unexpected intents %v", intents)
+	}
+
+	for i := 0; i < 5; i++ {
+		serve(http.MethodGet, "/v1/services/billing/orders", "")
+	}
+	statuses := reconciler.Reconcile()
+	if len(statuses) != 1 || statuses[0].State != reconcile.StateOver || !statuses[0].Enforced {
+		t.Fatalf("Expected billing to be over its intents and enforced, got %+v", statuses)
+	}
+	select {
+	case event := <-subscription.Events():
+		if event.Type != types.EventTrafficDiverged || event.Data["service"] != "billing" {
+			t.Errorf("Unexpected event %+v", event)
+		}
+	default:
+		t.Error("Expected a divergence event")
+	}
+
+	// 0.05 requests per second over a minute allows 3 requests
+	for i := 0; i < 3; i++ {
+		if code := serve(http.MethodGet, "/v1/services/billing/orders", ""); code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200 within the accepted volume, got %d", i, code)
+		}
+	}
+	if code := serve(http.MethodGet, "/v1/services/billing/orders", ""); code != http.StatusTooManyRequests {
+		t.Errorf("Expected requests beyond the accepted volume to be rejected, got %d", code)
+	}
+	if code := serve(http.MethodGet, "/v1/services/search/orders", ""); code != http.StatusOK {
+		t.Errorf("Expected services without intents to be unaffected, got %d", code)
+	}
+
+	if code := serve(http.MethodPost, "/v1/services/billing/traffic", `{"trafficType": "incoming", "volume": 1000, "priority": "high"}`); code != http.StatusCreated {
+		t.Fatalf("Expected the intent to be accepted, got %d", code)
+	}
+	reconciler.Reconcile()
+	if limit, enforced := reconciler.Limit("billing"); !enforced || limit != 1000.05 {
+		t.Errorf("Expected the enforced volume to follow the intents, got %v %v", limit, enforced)
+	}
+}
@@ -0,0 +1,64 @@
+package router
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"dynamiccontrol/internal/events"
+	"dynamiccontrol/internal/registry"
+	"dynamiccontrol/internal/types"
+)
+
+// registryEvents maps registry changes to the event types they publish
+var registryEvents = map[string]string{
+	registry.ChangeRegistered:   types.EventServiceRegistered,
+	registry.ChangeDeregistered: types.EventServiceDeregistered,
+	registry.ChangeExpired:      types.EventServiceExpired,
+	registry.ChangeUnhealthy:    types.EventServiceUnhealthy,
+	registry.ChangeRecovered:    types.EventServiceRecovered,
+}
+
+// SetRegistry resolves upstream targets naming a service to the registered
+// instances of the service, and publishes instance changes as events
+func (rm *RouteManager) SetRegistry(services *registry.Registry) {
+	services.OnChange(func(change string, instance registry.Instance) {
+		if change == registry.ChangeExpired || change == registry.ChangeUnhealthy {
+			slog.Warn("Service instance unavailable", "service", instance.Service, "instance", instance.ID, "change", change)
+		}
+		rm.broker.Publish(events.NewEvent(registryEvents[change], "", "", map[string]interface{}{
+			"service":  instance.Service,
+			"instance": instance.ID,
+			"url":      instance.URL,
+			"healthy":  instance.Healthy,
+		}))
+	})
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.registry = services
+}
+
+// GetRegistry returns the service registry, if enabled
+func (rm *RouteManager) GetRegistry() *registry.Registry {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.registry
+}
+
+// resolveUpstream returns the URL of an upstream target, selecting a healthy
+// registered instance for targets naming a service
+func (rm *RouteManager) resolveUpstream(target types.UpstreamTarget) (string, error) {
+	if target.Service == "" {
+		return target.URL, nil
+	}
+	services := rm.GetRegistry()
+	if services == nil {
+		return "", stageError(http.StatusServiceUnavailable, "Service registry is not enabled", nil)
+	}
+	instance, ok := services.Resolve(target.Service)
+	if !ok {
+		return "", stageError(http.StatusServiceUnavailable, fmt.Sprintf("No healthy instance of service %s", target.Service), nil)
+	}
+	return instance.URL, nil
+}
@@ -0,0 +1,72 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/registry"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProxyResolvesRegisteredServices(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"served": true}`))
+	}))
+	defer backend.Close()
+
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName: "/v1/payments",
+		Method:    "GET",
+		Handler:   types.HandlerProxy,
+		Upstreams: []types.UpstreamTarget{{Service: "payments"}},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	serve := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/payments", nil))
+		return recorder
+	}
+
+	if recorder := serve(); recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a registry, got %d", recorder.Code)
+	}
+	services := registry.New(registry.Config{})
+	rm.SetRegistry(services)
+	if recorder := serve(); recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a registered instance, got %d", recorder.Code)
+	}
+
+	subscription := rm.GetEventBroker().Subscribe(nil)
+	defer subscription.Close()
+	if _, _, err := services.Register(registry.Registration{Service: "payments", URL: backend.URL}); err != nil {
+		t.Fatalf("Failed to register instance: %v", err)
+	}
+	if recorder := serve(); recorder.Code != http.StatusOK || recorder.Body.String() != `{"served": true}` {
+		t.Errorf("Expected the registered instance to serve the request, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if event := <-subscription.Events(); event.Type != types.EventServiceRegistered || event.Data["service"] != "payments" {
+		t.Errorf("Expected a registration event, got %+v", event)
+	}
+
+	rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName: "/v1/payments",
+		Method:    "GET",
+		Handler:   types.HandlerProxy,
+		Upstreams: []types.UpstreamTarget{{Service: "payments", URL: backend.URL}},
+	}}})
+	if recorder := serve(); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected a target with both a url and a service to be rejected, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,109 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ResponseWriter is the response writer of the pipeline. Like the writer of
+// gin, which implements it, it holds back the status line until the body is
+// written, so headers can still be set after the status is chosen.
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	// Status returns the status code of the response, 200 when none was set
+	Status() int
+	// Size returns the number of body bytes written, -1 before the header
+	Size() int
+	// Written reports whether the status line was sent
+	Written() bool
+	// WriteHeaderNow sends the status line if it was not sent yet
+	WriteHeaderNow()
+}
+
+// responseWriter adapts an http.ResponseWriter to ResponseWriter
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+// newResponseWriter returns w as a ResponseWriter, wrapping it unless it
+// already is one
+func newResponseWriter(w http.ResponseWriter) ResponseWriter {
+	if rw, ok := w.(ResponseWriter); ok {
+		return rw
+	}
+	return &responseWriter{ResponseWriter: w, status: http.StatusOK, size: -1}
+}
+
+// WriteHeader sets the status code, sent with the first body write
+func (w *responseWriter) WriteHeader(status int) {
+	if status > 0 && !w.Written() {
+		w.status = status
+	}
+}
+
+// WriteHeaderNow sends the status line if it was not sent yet
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// Write sends the status line if needed and writes the body
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+// Flush sends buffered data to the client
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Status returns the status code of the response
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+// Size returns the number of body bytes written
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+// Written reports whether the status line was sent
+func (w *responseWriter) Written() bool {
+	return w.size != -1
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeData(w, status, "application/json; charset=utf-8", body)
+}
+
+// writeData writes a response body, with a content type unless the
+// response already has one
+func writeData(w http.ResponseWriter, status int, contentType string, body []byte) {
+	if contentType != "" && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
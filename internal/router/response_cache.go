@@ -0,0 +1,249 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/ratelimit"
+	"dynamiccontrol/internal/responsecache"
+	"dynamiccontrol/internal/types"
+)
+
+// Response caching settings
+const (
+	// maxCachedResponse bounds the size of a response kept in the cache
+	maxCachedResponse = 1 << 20
+	// cacheTTLRule is the rule of a TTL policy computing the cache TTL
+	cacheTTLRule = "ttl"
+	// cacheHeader reports whether a response was served from the cache
+	cacheHeader = "X-Cache"
+)
+
+// Outcomes of response cache lookups
+const (
+	cacheHit    = "hit"
+	cacheMiss   = "miss"
+	cacheStored = "stored"
+	cacheSkip   = "skipped"
+	cacheBypass = "bypassed"
+)
+
+// SetResponseCache replaces the store holding cached responses, such as
+// with a Redis store shared by replicas
+func (rm *RouteManager) SetResponseCache(store responsecache.Store) {
+	rm.responseCache = store
+}
+
+// GetResponseCache returns the store holding cached responses
+func (rm *RouteManager) GetResponseCache() responsecache.Store {
+	return rm.responseCache
+}
+
+// validateCache checks the cache configuration of a route at registration time
+func validateCache(route types.RouteConfig) error {
+	if route.Cache == nil {
+		return nil
+	}
+	if route.Method != "GET" {
+		return fmt.Errorf("response caching is only supported for GET routes")
+	}
+	if route.Cache.TTLSeconds < 0 {
+		return fmt.Errorf("cache ttlSeconds must not be negative")
+	}
+	if route.Cache.TTLSeconds == 0 && route.Cache.TTLPolicy == "" {
+		return fmt.Errorf("cache requires ttlSeconds or ttlPolicy")
+	}
+	for _, name := range append(append([]string{}, route.Cache.Vary...), route.Cache.BypassHeaders...) {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("cache header names must not be empty")
+		}
+	}
+	return nil
+}
+
+// cacheResponses wraps an executor so that responses are served from the
+// cache while fresh. On a miss the response is recorded as it is written and
+// stored once the pipeline completes. Requests carrying a bypass header skip
+// the cache; requests sending Cache-Control: no-cache refresh it.
+func (rm *RouteManager) cacheResponses(execute func(ex *Exchange) error) func(ex *Exchange) error {
+	return func(ex *Exchange) error {
+		config := ex.Route.Cache
+		for _, name := range config.BypassHeaders {
+			if ex.Request.Header.Get(name) != "" {
+				metrics.ResponseCacheRequests.WithLabelValues(ex.Route.RouteName, cacheBypass).Inc()
+				ex.Writer.Header().Set(cacheHeader, "BYPASS")
+				return execute(ex)
+			}
+		}
+
+		key := cacheKey(ex.Route, ex.Request)
+		refresh := strings.Contains(strings.ToLower(ex.Request.Header.Get("Cache-Control")), "no-cache")
+		if entry, ok := rm.responseCache.Get(key); ok && !refresh {
+			metrics.ResponseCacheRequests.WithLabelValues(ex.Route.RouteName, cacheHit).Inc()
+			header := ex.Writer.Header()
+			for name, values := range entry.Header {
+				header[name] = values
+			}
+			header.Set(cacheHeader, "HIT")
+			writeData(ex.Writer, entry.Status, entry.Header.Get("Content-Type"), entry.Body)
+			ex.Written = true
+			return nil
+		}
+
+		metrics.ResponseCacheRequests.WithLabelValues(ex.Route.RouteName, cacheMiss).Inc()
+		recorder := &cacheRecorder{ResponseWriter: ex.Writer}
+		ex.Writer = recorder
+		if refresh {
+			recorder.Header().Set(cacheHeader, "REFRESH")
+		} else {
+			recorder.Header().Set(cacheHeader, "MISS")
+		}
+		ex.onWritten = func() {
+			rm.storeResponse(ex, key, recorder)
+		}
+		return execute(ex)
+	}
+}
+
+// cacheKey identifies the cached response of a request: the route, the
+// request URI, the values of the route's vary headers and the Accept header
+// on routes with contentTypes. Keys start with
+// the route key so that a route's responses can be invalidated together.
+func cacheKey(route types.RouteConfig, r *http.Request) string {
+	key := routeKey(route) + " " + r.URL.RequestURI()
+	for _, name := range route.Cache.Vary {
+		key += "\n" + http.CanonicalHeaderKey(name) + ": " + strings.Join(r.Header.Values(name), ", ")
+	}
+	if len(route.ContentTypes) > 0 {
+		key += "\nAccept: " + strings.Join(r.Header.Values("Accept"), ", ")
+	}
+	return key
+}
+
+// cacheRecorder keeps a copy of a response body as it is written, giving up
+// once the body exceeds maxCachedResponse
+type cacheRecorder struct {
+	ResponseWriter
+	body      bytes.Buffer
+	truncated bool
+}
+
+// Write records and writes the response body
+func (r *cacheRecorder) Write(p []byte) (int, error) {
+	r.record(p)
+	return r.ResponseWriter.Write(p)
+}
+
+// record appends to the recorded body unless it grew too large
+func (r *cacheRecorder) record(p []byte) {
+	if r.truncated {
+		return
+	}
+	if r.body.Len()+len(p) > maxCachedResponse {
+		r.truncated = true
+		r.body = bytes.Buffer{}
+		return
+	}
+	r.body.Write(p)
+}
+
+// storeResponse caches a recorded successful response for its TTL. Responses
+// that set cookies, forbid storing or vary on any header are never cached.
+func (rm *RouteManager) storeResponse(ex *Exchange, key string, recorder *cacheRecorder) {
+	status := recorder.Status()
+	header := recorder.Header().Clone()
+	header.Del(cacheHeader)
+	for _, name := range ratelimit.Headers {
+		header.Del(name)
+	}
+	if status < 200 || status >= 300 || recorder.truncated || header.Get("Set-Cookie") != "" ||
+		strings.Contains(header.Get("Cache-Control"), "no-store") || header.Get("Vary") == "*" {
+		metrics.ResponseCacheRequests.WithLabelValues(ex.Route.RouteName, cacheSkip).Inc()
+		return
+	}
+
+	ttl := rm.cacheTTL(ex, status, header, recorder.body.Bytes())
+	if ttl <= 0 {
+		metrics.ResponseCacheRequests.WithLabelValues(ex.Route.RouteName, cacheSkip).Inc()
+		return
+	}
+	rm.responseCache.Set(key, responsecache.Entry{
+		Status: status,
+		Header: header,
+		Body:   append([]byte(nil), recorder.body.Bytes()...),
+	}, ttl)
+	metrics.ResponseCacheRequests.WithLabelValues(ex.Route.RouteName, cacheStored).Inc()
+}
+
+// cacheTTL returns how long a response is cached. The ttl rule of the
+// route's TTL policy overrides the static TTL when it is defined: it yields
+// seconds or a duration string such as "5m", and zero disables caching.
+func (rm *RouteManager) cacheTTL(ex *Exchange, status int, header http.Header, body []byte) time.Duration {
+	config := ex.Route.Cache
+	static := time.Duration(config.TTLSeconds) * time.Second
+	if config.TTLPolicy == "" {
+		return static
+	}
+
+	response := map[string]interface{}{
+		"status":  status,
+		"headers": firstValues(header),
+	}
+	var document interface{}
+	if json.Unmarshal(body, &document) == nil {
+		response["body"] = document
+	}
+	input := ex.PolicyInput(ex.Route.Method, ex.Route.RouteName)
+	input["response"] = response
+
+	logger := logging.FromContext(ex.Request.Context())
+	value, defined, err := rm.GetPolicyEvaluator().EvaluateRule(config.TTLPolicy, cacheTTLRule, input)
+	if err != nil {
+		logger.Warn("Failed to evaluate cache TTL policy", "route", routeKey(ex.Route), "policy", config.TTLPolicy, "error", err)
+		return static
+	}
+	if !defined {
+		return static
+	}
+	ttl, err := parseTTL(value)
+	if err != nil {
+		logger.Warn("Invalid cache TTL", "route", routeKey(ex.Route), "policy", config.TTLPolicy, "error", err)
+		return static
+	}
+	return ttl
+}
+
+// parseTTL converts a TTL computed by a policy to a duration
+func parseTTL(value interface{}) (time.Duration, error) {
+	switch typed := value.(type) {
+	case json.Number:
+		seconds, err := typed.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("ttl %s is not a number", typed)
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	case float64:
+		return time.Duration(typed * float64(time.Second)), nil
+	case string:
+		return time.ParseDuration(typed)
+	default:
+		return 0, fmt.Errorf("ttl must be a number of seconds or a duration, got %T", value)
+	}
+}
+
+// firstValues returns the first value of every header
+func firstValues(header http.Header) map[string]string {
+	values := make(map[string]string, len(header))
+	for name, value := range header {
+		if len(value) > 0 {
+			values[name] = value[0]
+		}
+	}
+	return values
+}
@@ -0,0 +1,186 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+const cacheTTLPolicy = `package traffic_cache
+
+import future.keywords.if
+
+ttl := 0 if input.response.body.state == "pending"
+
+ttl := "10m" if input.response.body.state == "settled"
+`
+
+func TestResponseCacheUsesPolicyTTL(t *testing.T) {
+	var calls atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/traffic/pending":
+			w.Write([]byte(`{"state":"pending"}`))
+		case "/v1/traffic/settled":
+			w.Write([]byte(`{"state":"settled"}`))
+		default:
+			w.Write([]byte(`{"state":"unknown"}`))
+		}
+	}))
+	defer backend.Close()
+
+	gin.SetMode(gin.TestMode)
+	policyManager := opa.NewPolicyManager()
+	if err := policyManager.SetPolicy("traffic_cache", cacheTTLPolicy); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	rm := NewRouteManager(policyManager, validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName: "/v1/traffic/:id",
+		Method:    "GET",
+		Handler:   types.HandlerProxy,
+		Upstreams: []types.UpstreamTarget{{URL: backend.URL}},
+		Cache:     &types.CacheConfig{TTLSeconds: 30, TTLPolicy: "traffic_cache"},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d: %s", path, recorder.Code, recorder.Body.String())
+		}
+		return recorder
+	}
+
+	cases := []struct {
+		path  string
+		calls int32
+	}{
+		// The policy disables caching of pending states
+		{"/v1/traffic/pending", 2},
+		// Settled states are cached for the policy's TTL
+		{"/v1/traffic/settled", 1},
+		// The static TTL applies where the policy's ttl rule is undefined
+		{"/v1/traffic/other", 1},
+	}
+	for _, tc := range cases {
+		calls.Store(0)
+		first := get(tc.path)
+		second := get(tc.path)
+		if calls.Load() != tc.calls {
+			t.Errorf("Expected %d upstream calls for %s, got %d", tc.calls, tc.path, calls.Load())
+		}
+		if first.Body.String() != second.Body.String() {
+			t.Errorf("Expected identical bodies for %s, got %q and %q", tc.path, first.Body.String(), second.Body.String())
+		}
+		expected := "HIT"
+		if tc.calls == 2 {
+			expected = "MISS"
+		}
+		if second.Header().Get("X-Cache") != expected {
+			t.Errorf("Expected X-Cache %s for %s, got %q", expected, tc.path, second.Header().Get("X-Cache"))
+		}
+	}
+
+	// Changing the route drops its cached responses
+	config := rm.GetConfig()
+	changed := *config
+	changed.Routes = append([]types.RouteConfig(nil), config.Routes...)
+	changed.Routes[0].TimeoutMs = 1000
+	if err := rm.ApplyConfig(&changed); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	if get("/v1/traffic/settled").Header().Get("X-Cache") != "MISS" {
+		t.Errorf("Expected the cache to be invalidated by a route change")
+	}
+}
+
+func TestValidateCache(t *testing.T) {
+	cases := []struct {
+		name  string
+		route types.RouteConfig
+		valid bool
+	}{
+		{"static ttl", types.RouteConfig{Method: "GET", Cache: &types.CacheConfig{TTLSeconds: 5}}, true},
+		{"policy ttl", types.RouteConfig{Method: "GET", Cache: &types.CacheConfig{TTLPolicy: "traffic_cache"}}, true},
+		{"no ttl", types.RouteConfig{Method: "GET", Cache: &types.CacheConfig{}}, false},
+		{"post route", types.RouteConfig{Method: "POST", Cache: &types.CacheConfig{TTLSeconds: 5}}, false},
+	}
+	for _, tc := range cases {
+		if err := validateCache(tc.route); (err == nil) != tc.valid {
+			t.Errorf("%s: expected valid=%v, got %v", tc.name, tc.valid, err)
+		}
+	}
+}
+
+func TestResponseCacheVaryAndBypass(t *testing.T) {
+	var calls atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+	defer backend.Close()
+
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName: "/v1/greeting",
+		Method:    "GET",
+		Handler:   types.HandlerProxy,
+		Upstreams: []types.UpstreamTarget{{URL: backend.URL}},
+		Cache:     &types.CacheConfig{TTLSeconds: 30, Vary: []string{"accept-language"}, BypassHeaders: []string{"Authorization"}},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	get := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/greeting", nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		cache   string
+		body    string
+		calls   int32
+	}{
+		{"first language", map[string]string{"Accept-Language": "en"}, "MISS", "en", 1},
+		{"cached language", map[string]string{"Accept-Language": "en"}, "HIT", "en", 1},
+		{"other language", map[string]string{"Accept-Language": "de"}, "MISS", "de", 2},
+		{"bypass header", map[string]string{"Accept-Language": "en", "Authorization": "Bearer x"}, "BYPASS", "en", 3},
+		{"refresh", map[string]string{"Accept-Language": "en", "Cache-Control": "no-cache"}, "REFRESH", "en", 4},
+		{"cached after refresh", map[string]string{"Accept-Language": "en"}, "HIT", "en", 4},
+	}
+	for _, tc := range cases {
+		recorder := get(tc.headers)
+		if recorder.Header().Get("X-Cache") != tc.cache || recorder.Body.String() != tc.body || calls.Load() != tc.calls {
+			t.Errorf("%s: got X-Cache %q, body %q after %d calls", tc.name, recorder.Header().Get("X-Cache"), recorder.Body.String(), calls.Load())
+		}
+	}
+}
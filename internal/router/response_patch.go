@@ -0,0 +1,85 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/transform"
+	"dynamiccontrol/internal/upstream"
+)
+
+// maxPatchedResponse bounds the size of an upstream response buffered for patching
+const maxPatchedResponse = 10 << 20
+
+// patchResponses wraps an executor so that the route's JSON Patch is
+// applied to successful responses. A patch that fails, such as on a failed
+// test operation, leaves the response unchanged.
+func patchResponses(execute func(ex *Exchange) error) func(ex *Exchange) error {
+	return func(ex *Exchange) error {
+		if err := execute(ex); err != nil || ex.Written || ex.Response == nil {
+			return err
+		}
+		if ex.StatusCode != 0 && (ex.StatusCode < 200 || ex.StatusCode >= 300) {
+			return nil
+		}
+
+		logger := logging.FromContext(ex.Request.Context())
+		document, err := decodeDocument(ex.Response)
+		if err == nil {
+			document, err = transform.ApplyPatch(document, ex.Route.ResponsePatch)
+		}
+		if err != nil {
+			logger.Warn("Response patch not applied", "route", routeKey(ex.Route), "error", err)
+			return nil
+		}
+		ex.Response = document
+		return nil
+	}
+}
+
+// decodeDocument converts a response to its decoded JSON form
+func decodeDocument(response interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	var document interface{}
+	err = json.Unmarshal(encoded, &document)
+	return document, err
+}
+
+// bufferProxyResponse reads a successful JSON upstream response into the
+// exchange so that it can be patched before it is encoded. Responses that
+// are compressed, not JSON or too large are written through unchanged.
+func (rm *RouteManager) bufferProxyResponse(ex *Exchange, resp *http.Response) error {
+	w := ex.Writer
+	upstream.CopyHeaders(w.Header(), resp.Header)
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPatchedResponse+1))
+	if err != nil {
+		return stageError(http.StatusBadGateway, fmt.Sprintf("Failed to read upstream response: %v", err), nil)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	var document interface{}
+	if mediaType == "application/json" && resp.Header.Get("Content-Encoding") == "" &&
+		len(body) <= maxPatchedResponse && json.Unmarshal(body, &document) == nil {
+		w.Header().Del("Content-Length")
+		ex.Response = document
+		ex.StatusCode = resp.StatusCode
+		// Proxied responses are validated by sampling, not on every request
+		ex.ResponseValidated = true
+		return nil
+	}
+
+	w.WriteHeader(resp.StatusCode)
+	ex.Written = true
+	w.Write(body)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		logging.FromContext(ex.Request.Context()).Error("Failed to copy upstream response", "route", routeKey(ex.Route), "error", err)
+	}
+	return nil
+}
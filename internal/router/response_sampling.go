@@ -0,0 +1,112 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"mime"
+	"net/http"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/types"
+)
+
+// Background response validation settings
+const (
+	// maxSampledResponse bounds the size of a response buffered for validation
+	maxSampledResponse = 1 << 20
+	// responseValidationWorkers bounds the validations running at once; samples
+	// taken while all workers are busy are dropped
+	responseValidationWorkers = 4
+)
+
+// Outcomes of sampled response validations
+const (
+	sampleValid   = "valid"
+	sampleInvalid = "invalid"
+	sampleSkipped = "skipped"
+	sampleDropped = "dropped"
+)
+
+// SetResponseSampling validates the given percentage of proxied responses
+// against their route's response schema in the background. Routes override
+// the rate with responseSamplePercent.
+func (rm *RouteManager) SetResponseSampling(percent float64) {
+	rm.responseSamplePercent = percent
+}
+
+// sampleResponse reports whether a proxied response is validated
+func (rm *RouteManager) sampleResponse(route types.RouteConfig, statusCode int) bool {
+	percent := rm.responseSamplePercent
+	if route.ResponseSamplePercent > 0 {
+		percent = route.ResponseSamplePercent
+	}
+	if percent <= 0 || len(route.ResponseSchema) == 0 || statusCode < 200 || statusCode >= 300 {
+		return false
+	}
+	return rand.Float64()*100 < percent
+}
+
+// responseSample keeps a copy of a proxied response body as it is streamed
+// to the client, giving up once the body exceeds maxSampledResponse
+type responseSample struct {
+	body      bytes.Buffer
+	truncated bool
+}
+
+// Write implements io.Writer and never fails, so streaming is unaffected
+func (s *responseSample) Write(p []byte) (int, error) {
+	if !s.truncated {
+		if s.body.Len()+len(p) > maxSampledResponse {
+			s.truncated = true
+			s.body = bytes.Buffer{}
+		} else {
+			s.body.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// validateSample validates a sampled response off the request path and
+// records the outcome per route. Samples that are not plain JSON, or that
+// arrive while every worker is busy, are counted but not validated.
+func (rm *RouteManager) validateSample(ex *Exchange, sample *responseSample, header http.Header) {
+	route := ex.Route
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if sample.truncated || header.Get("Content-Encoding") != "" || mediaType != "application/json" {
+		metrics.ResponseSampleValidations.WithLabelValues(route.RouteName, sampleSkipped).Inc()
+		return
+	}
+
+	select {
+	case rm.sampleWorkers <- struct{}{}:
+	default:
+		metrics.ResponseSampleValidations.WithLabelValues(route.RouteName, sampleDropped).Inc()
+		return
+	}
+
+	started := rm.sideEffects.Go(ex.Request.Context(), "response-sample", func(ctx context.Context) {
+		defer func() { <-rm.sampleWorkers }()
+		logger := logging.FromContext(ctx)
+
+		var response interface{}
+		if err := json.Unmarshal(sample.body.Bytes(), &response); err != nil {
+			metrics.ResponseSampleValidations.WithLabelValues(route.RouteName, sampleInvalid).Inc()
+			logger.Warn("Sampled response is not valid JSON", "route", routeKey(route), "error", err)
+			return
+		}
+		result := rm.schemaValidator.ValidatePrepared(ex.schemas.response, response)
+		if !result.Valid {
+			metrics.ResponseSampleValidations.WithLabelValues(route.RouteName, sampleInvalid).Inc()
+			logger.Warn("Sampled response violates response schema", "route", routeKey(route), "errors", result.Errors)
+			return
+		}
+		metrics.ResponseSampleValidations.WithLabelValues(route.RouteName, sampleValid).Inc()
+	})
+	if !started {
+		<-rm.sampleWorkers
+		metrics.ResponseSampleValidations.WithLabelValues(route.RouteName, sampleDropped).Inc()
+	}
+}
@@ -1,35 +1,254 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
-
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"dynamiccontrol/internal/audit"
+	"dynamiccontrol/internal/chaos"
+	"dynamiccontrol/internal/codec"
+	"dynamiccontrol/internal/configstore"
+	"dynamiccontrol/internal/decisions"
+	"dynamiccontrol/internal/dependencies"
+	"dynamiccontrol/internal/events"
+	"dynamiccontrol/internal/guardrails"
+	"dynamiccontrol/internal/identity"
 	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/operations"
+	"dynamiccontrol/internal/quota"
+	"dynamiccontrol/internal/ratelimit"
+	"dynamiccontrol/internal/reconcile"
+	"dynamiccontrol/internal/registry"
+	"dynamiccontrol/internal/reqctx"
+	"dynamiccontrol/internal/responsecache"
+	"dynamiccontrol/internal/rollout"
+	"dynamiccontrol/internal/sideeffects"
+	"dynamiccontrol/internal/tenancy"
+	"dynamiccontrol/internal/traffic"
+	"dynamiccontrol/internal/transform"
 	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/upstream"
 	"dynamiccontrol/internal/validator"
-
-	"github.com/gin-gonic/gin"
+	"dynamiccontrol/internal/versions"
 )
 
 // RouteManager handles dynamic route registration and management
 type RouteManager struct {
+	mu              sync.RWMutex
+	applyMu         sync.Mutex
+	reloadMu        sync.Mutex
 	config          *types.RoutesConfig
+	table           http.Handler
 	policyManager   *opa.PolicyManager
 	schemaValidator *validator.SchemaValidator
 	mockData        *types.MockData
+	upstreamClient  *upstream.Client
+	balancers       map[string]*upstream.Balancer
+	operations      *operations.Store
+	mockTemplates   map[string]*template.Template
+	emitter         *events.WebhookEmitter
+	firstTraffic    map[string]*firstTraffic
+	lazy            bool
+	lazyRouter      *lazyRouter
+	extraStages     map[string][]Stage
+	decisions       *decisions.Log
+	audit           *audit.Log
+	chaos           *chaos.Injector
+	applyListeners  []func(*types.RoutesConfig)
+	broker          *events.Broker
+	applied         map[string]string
+	cors            []corsRoute
+	assertions      *identity.Signer
+	samples         *decisions.Samples
+	guard           *guardrails.Guard
+	dependencies    *dependencies.Registry
+	routeIndex      map[string]types.RouteConfig
+	// schemaProfilePercent is the percentage of validations that are profiled
+	schemaProfilePercent float64
+	// responseSamplePercent is the percentage of proxied responses validated
+	// in the background, bounded by sampleWorkers
+	responseSamplePercent float64
+	sampleWorkers         chan struct{}
+	// evaluator decides requests when policies are evaluated outside the
+	// policy manager, such as by an OPA sidecar
+	evaluator opa.PolicyEvaluator
+	// responseCache holds the responses of routes with a cache configuration
+	responseCache responsecache.Store
+	// idempotency replays the first responses to POST requests with an
+	// Idempotency-Key header
+	idempotency *idempotency
+	// maxBodyBytes bounds the request bodies of routes without maxBodyBytes
+	maxBodyBytes int64
+	// codecs decode and encode the bodies of routes with contentTypes
+	codecs *codec.Registry
+	// limiter counts the requests of routes with a rate limit
+	limiter ratelimit.Limiter
+	// sideEffects runs the background work of requests, such as async
+	// aggregates and response sampling
+	sideEffects *sideeffects.Group
+	// learners infer the request schemas of routes in learning mode, by
+	// route key
+	learners *SchemaLearners
+	// enforceSunset rejects routes using deprecated policies past their sunset
+	enforceSunset bool
+	// tenants resolves the tenant of requests; tenantHeader names the
+	// request header selecting a tenant
+	tenants      *tenancy.Resolver
+	tenantHeader string
+	// canary serves a share of requests from a candidate configuration;
+	// canaryState and canaryReason describe the last outcome
+	canarySettings *CanaryConfig
+	canary         *canaryRollout
+	canaryState    string
+	canaryReason   string
+	// history keeps the applied configuration versions for rollbacks
+	history *versions.History
+	// variables are the admin-managed values of route templates
+	variables *variableStore
+	// runtime holds the routes created through the admin API
+	runtime *runtimeRoutes
+	// reconciler compares the accepted traffic intents of services with
+	// their observed traffic
+	reconciler *reconcile.Reconciler
+	// registry resolves upstream targets naming a service
+	registry *registry.Registry
+	// traffic stores the traffic rules served by traffic routes, within
+	// the quotas of their services
+	traffic *traffic.Controller
+	quotas  *quota.Manager
+	// served and failed count the requests and 5xx responses since the
+	// configuration was last applied
+	served atomic.Int64
+	failed atomic.Int64
 }
 
 // NewRouteManager creates a new route manager
 func NewRouteManager(policyManager *opa.PolicyManager, schemaValidator *validator.SchemaValidator) *RouteManager {
-	return &RouteManager{
+	rm := &RouteManager{
 		policyManager:   policyManager,
 		schemaValidator: schemaValidator,
 		mockData:        types.NewMockData(),
+		upstreamClient:  upstream.NewClient(),
+		balancers:       make(map[string]*upstream.Balancer),
+		operations:      operations.NewStore(),
+		mockTemplates:   make(map[string]*template.Template),
+		emitter:         events.NewWebhookEmitter("", ""),
+		firstTraffic:    make(map[string]*firstTraffic),
+		extraStages:     make(map[string][]Stage),
+		decisions:       decisions.NewLog(decisions.DefaultCapacity),
+		samples:         decisions.NewSamples(decisions.DefaultSampleCapacity),
+		audit:           audit.NewLog(audit.DefaultCapacity),
+		broker:          events.NewBroker(),
+		applied:         make(map[string]string),
+		dependencies:    dependencies.NewRegistry(),
+		routeIndex:      make(map[string]types.RouteConfig),
+		sampleWorkers:   make(chan struct{}, responseValidationWorkers),
+		responseCache:   responsecache.New(responsecache.DefaultCapacity),
+		idempotency:     newIdempotency(),
+		maxBodyBytes:    DefaultMaxBodyBytes,
+		codecs:          codec.NewRegistry(),
+		limiter:         ratelimit.NewMemoryLimiter(),
+		sideEffects:     sideeffects.NewGroup(0),
+		learners:        newSchemaLearners(),
+		history:         versions.NewHistory(versions.DefaultCapacity),
+		variables:       newVariableStore(),
+		runtime:         newRuntimeRoutes(),
+		traffic:         traffic.NewController(),
+		quotas:          quota.NewManager(),
+	}
+	rm.traffic.OnChange(rm.publishTrafficChange)
+	return rm
+}
+
+// SetSideEffects sets the group running the background work of requests
+func (rm *RouteManager) SetSideEffects(group *sideeffects.Group) {
+	rm.sideEffects = group
+}
+
+// SetWebhookEmitter sets the emitter used to deliver route lifecycle events
+func (rm *RouteManager) SetWebhookEmitter(emitter *events.WebhookEmitter) {
+	rm.emitter = emitter
+}
+
+// SetChaos wires the chaos injector into schema validation and upstream calls
+func (rm *RouteManager) SetChaos(injector *chaos.Injector) {
+	rm.chaos = injector
+	rm.upstreamClient.SetChaos(injector)
+}
+
+// SetPolicyEvaluator evaluates route policies with the given evaluator
+// instead of the embedded policy manager
+func (rm *RouteManager) SetPolicyEvaluator(evaluator opa.PolicyEvaluator) {
+	rm.evaluator = evaluator
+}
+
+// GetPolicyEvaluator returns the evaluator deciding route policies
+func (rm *RouteManager) GetPolicyEvaluator() opa.PolicyEvaluator {
+	if rm.evaluator != nil {
+		return rm.evaluator
 	}
+	return rm.policyManager
+}
+
+// SetSchemaProfiling profiles the given percentage of schema validations,
+// exporting the time spent per keyword and logging hotspots at debug level
+func (rm *RouteManager) SetSchemaProfiling(percent float64) {
+	rm.schemaProfilePercent = percent
+}
+
+// SetGuardrails limits the blast radius of configuration changes loaded from the store
+func (rm *RouteManager) SetGuardrails(guard *guardrails.Guard) {
+	rm.guard = guard
+}
+
+// GetGuardrails returns the guardrails checking configuration changes, or nil
+func (rm *RouteManager) GetGuardrails() *guardrails.Guard {
+	return rm.guard
+}
+
+// CheckPolicyChange checks replacing a single policy against the guardrails
+// and, when enforced, the sunset dates of deprecated policies
+func (rm *RouteManager) CheckPolicyChange(policyName, source string) error {
+	if rm.guard == nil && !rm.enforceSunset {
+		return nil
+	}
+	previous := rm.policyManager.PolicySources()
+	next := make(map[string]string, len(previous)+1)
+	for name, policySource := range previous {
+		next[name] = policySource
+	}
+	next[policyName] = source
+	if config := rm.GetConfig(); config != nil {
+		if err := rm.checkPolicySunsets(deprecatedPolicies(config, map[string]string{policyName: source}, time.Now())); err != nil {
+			return err
+		}
+	}
+	if rm.guard == nil {
+		return nil
+	}
+	return rm.guard.Check(guardrails.Change{
+		Routes:           rm.GetConfig(),
+		PreviousPolicies: previous,
+		Policies:         next,
+	})
+}
+
+// OnApply registers a callback invoked after every applied configuration, in
+// the order configurations are applied
+func (rm *RouteManager) OnApply(listener func(*types.RoutesConfig)) {
+	rm.applyMu.Lock()
+	defer rm.applyMu.Unlock()
+	rm.applyListeners = append(rm.applyListeners, listener)
 }
 
 // LoadConfig loads the route configuration from JSON file
@@ -44,198 +263,536 @@ func (rm *RouteManager) LoadConfig(configPath string) error {
 		return fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	rm.mu.Lock()
 	rm.config = &config
-	log.Printf("Loaded %d routes from configuration", len(config.Routes))
+	rm.mu.Unlock()
+
+	slog.Info("Loaded route configuration", "routes", len(config.Routes))
 	return nil
 }
 
-// RegisterRoutes registers all routes from the configuration
-func (rm *RouteManager) RegisterRoutes(router *gin.Engine) error {
-	if rm.config == nil {
-		return fmt.Errorf("no configuration loaded")
-	}
-
-	for _, route := range rm.config.Routes {
-		if err := rm.registerRoute(router, route); err != nil {
-			log.Printf("Failed to register route %s: %v", route.RouteName, err)
-			continue
-		}
-		log.Printf("Registered route: %s %s", route.Method, route.RouteName)
+// LoadFromStore loads policies and routes from a config store
+func (rm *RouteManager) LoadFromStore(ctx context.Context, store configstore.ConfigStore) error {
+	config, err := rm.loadFromStore(ctx, store)
+	if err != nil {
+		return err
 	}
 
+	rm.mu.Lock()
+	rm.config = config
+	rm.mu.Unlock()
 	return nil
 }
 
-// registerRoute registers a single route
-func (rm *RouteManager) registerRoute(router *gin.Engine, route types.RouteConfig) error {
-	switch route.Method {
-	case "GET":
-		router.GET(route.RouteName, rm.createHandler(route))
-	case "POST":
-		router.POST(route.RouteName, rm.createHandler(route))
-	case "PUT":
-		router.PUT(route.RouteName, rm.createHandler(route))
-	case "DELETE":
-		router.DELETE(route.RouteName, rm.createHandler(route))
-	default:
-		return fmt.Errorf("unsupported HTTP method: %s", route.Method)
+// loadFromStore loads policies from a config store and returns its route
+// configuration without applying it
+func (rm *RouteManager) loadFromStore(ctx context.Context, store configstore.ConfigStore) (*types.RoutesConfig, error) {
+	policies, err := store.LoadPolicies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policies from %s store: %w", store.Name(), err)
+	}
+	config, err := store.LoadRoutes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load routes from %s store: %w", store.Name(), err)
 	}
+	config = rm.withRuntimeRoutes(ctx, config)
 
-	return nil
+	if rm.guard != nil {
+		change := guardrails.Change{Routes: config, Policies: policies}
+		if previous := rm.GetConfig(); previous != nil {
+			change.PreviousRoutes = previous
+			change.PreviousPolicies = rm.policyManager.PolicySources()
+		}
+		if err := rm.guard.Check(change); err != nil {
+			return nil, err
+		}
+	}
+
+	var data map[string]interface{}
+	if dataStore, ok := store.(configstore.DataStore); ok {
+		if data, err = dataStore.LoadData(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load policy data from %s store: %w", store.Name(), err)
+		}
+	}
+	rm.policyManager.SetData(data)
+	if err := rm.policyManager.ReplacePolicies(policies); err != nil {
+		return nil, err
+	}
+	rm.broker.Publish(events.NewEvent(types.EventPolicyReloaded, "", "", map[string]interface{}{
+		"store":    store.Name(),
+		"policies": rm.policyManager.ListLoadedPolicies(),
+	}))
+
+	slog.Info("Loaded configuration from store", "store", store.Name(), "routes", len(config.Routes), "policies", len(policies))
+	return config, nil
 }
 
-// createHandler creates a Gin handler for a route
-func (rm *RouteManager) createHandler(route types.RouteConfig) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Extract headers
-		headers := make(map[string]string)
-		for key, values := range c.Request.Header {
-			if len(values) > 0 {
-				headers[key] = values[0]
+// WatchStore subscribes to config store changes and applies them live until
+// ctx is done. Reloads are serialized, so each configuration is loaded and
+// applied before the next one is loaded.
+func (rm *RouteManager) WatchStore(ctx context.Context, store configstore.ConfigStore) {
+	err := store.Watch(ctx, func() {
+		rm.reloadMu.Lock()
+		defer rm.reloadMu.Unlock()
+
+		slog.Info("Configuration change detected", "store", store.Name())
+		actor := "configstore/" + store.Name()
+		previous, previousPolicies := rm.GetConfig(), rm.policyManager.PolicySources()
+		config, err := rm.loadFromStore(ctx, store)
+		if err != nil {
+			slog.Error("Failed to reload configuration", "store", store.Name(), "error", err)
+			entry := types.AuditEntry{Actor: actor, Action: "config.reload", Resource: "routes", Outcome: audit.OutcomeFailure, Error: err.Error()}
+			var rejected *guardrails.Error
+			if errors.As(err, &rejected) {
+				entry.Action = "config.rejected"
+				entry.Details = map[string]interface{}{"violations": rejected.Violations}
 			}
+			rm.audit.Record(entry)
+			return
 		}
-
-		// Handle different HTTP methods
-		switch route.Method {
-		case "GET":
-			rm.handleGET(c, route, headers)
-		case "POST":
-			rm.handlePOST(c, route, headers)
-		default:
-			c.JSON(http.StatusMethodNotAllowed, gin.H{
-				"error": "Method not allowed",
+		if _, canary := rm.CanarySettings(); canary {
+			if err := rm.StartCanary(config); err != nil {
+				slog.Error("Failed to start canary rollout", "store", store.Name(), "error", err)
+				rm.audit.Record(types.AuditEntry{Actor: actor, Action: "canary.start", Resource: "routes", Outcome: audit.OutcomeFailure, Error: err.Error()})
+				return
+			}
+			rm.audit.Record(types.AuditEntry{
+				Actor:    actor,
+				Action:   "canary.start",
+				Resource: "routes",
+				Details:  map[string]interface{}{"routes": len(config.Routes)},
+				Diff:     audit.ConfigDiff(previous, config, previousPolicies, rm.policyManager.PolicySources()),
 			})
+			// The reloaded policies are live while the routes are canaried
+			rm.RecordVersion()
+			return
+		}
+		if err := rm.ApplyConfig(config); err != nil {
+			slog.Error("Failed to apply configuration", "store", store.Name(), "error", err)
+			rm.audit.Record(types.AuditEntry{Actor: actor, Action: "config.apply", Resource: "routes", Outcome: audit.OutcomeFailure, Error: err.Error()})
+			return
 		}
+		rm.audit.Record(types.AuditEntry{
+			Actor:    actor,
+			Action:   "config.apply",
+			Resource: "routes",
+			Details: map[string]interface{}{
+				"routes":   len(config.Routes),
+				"policies": rm.policyManager.ListLoadedPolicies(),
+			},
+			Diff: audit.ConfigDiff(previous, config, previousPolicies, rm.policyManager.PolicySources()),
+		})
+	})
+	if err != nil && ctx.Err() == nil {
+		slog.Error("Stopped watching store", "store", store.Name(), "error", err)
 	}
 }
 
-// handleGET handles GET requests
-func (rm *RouteManager) handleGET(c *gin.Context, route types.RouteConfig, headers map[string]string) {
-	// Create policy input
-	input := opa.CreatePolicyInput("GET", route.RouteName, headers, nil)
+// ApplyConfig compiles a route configuration and atomically replaces the
+// active route table, so configuration changes take effect without a restart
+func (rm *RouteManager) ApplyConfig(config *types.RoutesConfig) error {
+	if config == nil {
+		return fmt.Errorf("no configuration loaded")
+	}
 
-	// Evaluate policies
-	policyResult, err := rm.policyManager.EvaluatePolicies(route.Policies, input)
+	tenants, err := tenancy.NewResolver(config.Tenants, rm.tenantHeader)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Policy evaluation error: %v", err),
-		})
-		return
+		return fmt.Errorf("invalid tenants: %w", err)
+	}
+	if err := tenants.Validate(config); err != nil {
+		return err
+	}
+	deprecated := deprecatedPolicies(config, rm.policyManager.PolicySources(), time.Now())
+	if err := rm.checkPolicySunsets(deprecated); err != nil {
+		return err
+	}
+	config, err = rm.resolveSchemaRefs(config)
+	if err != nil {
+		return err
+	}
+	corsRoutes, err := compileCORS(config)
+	if err != nil {
+		return err
 	}
 
-	if !policyResult.Allowed {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error": fmt.Sprintf("Request denied by policy: %s", policyResult.Error),
-		})
-		return
+	rm.applyMu.Lock()
+	defer rm.applyMu.Unlock()
+
+	var table http.Handler
+	var lr *lazyRouter
+	if rm.lazy {
+		lr = newLazyRouter(config.Routes)
+		table = rm.lazyDispatch(lr)
+		slog.Info("Lazy registration enabled", "routes", len(config.Routes))
+	} else {
+		table = rm.compileTables(config.Routes)
 	}
 
-	// Generate mock response based on route
-	var response interface{}
-	switch route.RouteName {
-	case "/v1/status":
-		statusResponse := rm.mockData.GenerateStatusResponse()
-		response = statusResponse
+	rm.mu.Lock()
+	previousLazy := rm.lazyRouter
+	rm.config = config
+	rm.table = table
+	rm.cors = corsRoutes
+	rm.tenants = tenants
+	rm.lazyRouter = lr
+	rm.pruneRoutes(config)
+	rm.mu.Unlock()
+
+	if previousLazy != nil {
+		close(previousLazy.stop)
+	}
+	if lr != nil {
+		go rm.precompileRoutes(lr)
+	}
+	rm.publishRouteChanges(config)
+	warnDeprecatedPolicies(deprecated)
+	rm.served.Store(0)
+	rm.failed.Store(0)
+	for _, listener := range rm.applyListeners {
+		listener(config)
+	}
+	rm.RecordVersion()
 
-		// Validate response against schema
-		validationResult := rm.schemaValidator.ValidateStatusResponse(statusResponse)
-		if !validationResult.Valid {
-			log.Printf("Response validation failed: %v", validationResult.Errors)
+	return nil
+}
+
+// publishRouteChanges publishes an event for every route added, changed or
+// removed since the previously applied configuration and drops their cached
+// responses; callers must hold applyMu
+func (rm *RouteManager) publishRouteChanges(config *types.RoutesConfig) {
+	current := make(map[string]string, len(config.Routes))
+	for _, route := range config.Routes {
+		key := routeKey(route)
+		revision := routeRevision(route)
+		current[key] = revision
+		if rm.applied[key] != revision {
+			rm.responseCache.Invalidate(key + " ")
+			rm.broker.Publish(events.NewEvent(types.EventRouteRegistered, key, revision, map[string]interface{}{
+				"handler":  route.Handler,
+				"policies": route.Policies,
+			}))
 		}
-	default:
-		// Generic response for other GET routes
-		response = gin.H{
-			"message": "GET request processed successfully",
-			"route":   route.RouteName,
-			"method":  route.Method,
+	}
+	for key, revision := range rm.applied {
+		if _, exists := current[key]; !exists {
+			rm.responseCache.Invalidate(key + " ")
+			rm.broker.Publish(events.NewEvent(types.EventRouteRemoved, key, revision, nil))
 		}
 	}
+	rm.applied = current
+}
 
-	c.JSON(http.StatusOK, response)
+// ServeHTTP serves a request from the active route table. It depends on no
+// web framework; Dispatch and RegisterRoutes adapt it to gin.
+func (rm *RouteManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writer := newResponseWriter(w)
+	rm.serve(writer, r)
+	writer.WriteHeaderNow()
 }
 
-// handlePOST handles POST requests
-func (rm *RouteManager) handlePOST(c *gin.Context, route types.RouteConfig, headers map[string]string) {
-	// Parse request body
-	var requestBody interface{}
-	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("Invalid JSON: %v", err),
-		})
+// serve serves a request from the active route table
+func (rm *RouteManager) serve(w ResponseWriter, r *http.Request) {
+	rm.mu.RLock()
+	table := rm.table
+	corsRoutes := rm.cors
+	tenants := rm.tenants
+	rm.mu.RUnlock()
+
+	rc, r := reqctx.Attach(r)
+	if tenants != nil {
+		rc.Tenant = tenants.Resolve(r)
+	}
+	if serveCORS(w, r, corsRoutes) {
 		return
 	}
-
-	// Validate request against schema
-	validationResult := rm.schemaValidator.ValidateRequest(route.RequestSchema, requestBody)
-	if !validationResult.Valid {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Request validation failed",
-			"details": validator.FormatValidationErrors(validationResult.Errors),
-		})
+	if rm.serveCanary(w, r, rc.Client.IP) {
 		return
 	}
-
-	// Create policy input
-	input := opa.CreatePolicyInput("POST", route.RouteName, headers, requestBody)
-
-	// Evaluate policies
-	policyResult, err := rm.policyManager.EvaluatePolicies(route.Policies, input)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Policy evaluation error: %v", err),
+	if table == nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Route not found",
 		})
 		return
 	}
+	table.ServeHTTP(w, r)
 
-	if !policyResult.Allowed {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error": fmt.Sprintf("Request denied by policy: %s", policyResult.Error),
-		})
-		return
+	rm.served.Add(1)
+	if w.Status() >= http.StatusInternalServerError {
+		rm.failed.Add(1)
 	}
+}
 
-	// Generate mock response based on route
-	var response interface{}
-	switch {
-	case strings.Contains(route.RouteName, "/services/") && strings.HasSuffix(route.RouteName, "/traffic"):
-		// Extract service ID from actual path
-		pathParts := strings.Split(c.Request.URL.Path, "/")
-		if len(pathParts) >= 4 {
-			serviceID := pathParts[3] // /v1/services/service123/traffic
+// TrafficSinceApply returns the number of requests served and of 5xx
+// responses since the configuration was last applied
+func (rm *RouteManager) TrafficSinceApply() (int64, int64) {
+	return rm.served.Load(), rm.failed.Load()
+}
 
-			// Parse traffic request
-			var trafficRequest types.TrafficRequest
-			if requestBytes, err := json.Marshal(requestBody); err == nil {
-				json.Unmarshal(requestBytes, &trafficRequest)
-			}
+// pruneRoutes releases compiled state of routes that are no longer configured;
+// callers must hold the write lock
+func (rm *RouteManager) pruneRoutes(config *types.RoutesConfig) {
+	active := make(map[string]bool, len(config.Routes))
+	rm.routeIndex = make(map[string]types.RouteConfig, len(config.Routes))
+	for _, route := range config.Routes {
+		active[routeKey(route)] = true
+		rm.routeIndex[routeKey(route)] = route
+	}
 
-			trafficResponse := rm.mockData.GenerateTrafficResponse(serviceID, trafficRequest)
-			response = trafficResponse
+	for key, balancer := range rm.balancers {
+		if !active[key] {
+			balancer.Stop()
+			delete(rm.balancers, key)
+		}
+	}
+	for key := range rm.mockTemplates {
+		if !active[strings.TrimSuffix(key, fallbackSuffix)] {
+			delete(rm.mockTemplates, key)
+		}
+	}
+}
 
-			// Validate response against schema
-			responseValidation := rm.schemaValidator.ValidateTrafficResponse(trafficResponse)
-			if !responseValidation.Valid {
-				log.Printf("Response validation failed: %v", responseValidation.Errors)
+// registerRoute registers a single route
+func (rm *RouteManager) registerRoute(table *routeTable, route types.RouteConfig) error {
+	switch route.Handler {
+	case "", types.HandlerMock:
+		if route.MockResponse != nil {
+			if err := validateMockStore(route.MockResponse.Store); err != nil {
+				return err
+			}
+			if route.MockResponse.Template != "" {
+				tmpl, err := transform.ParseTemplate(routeKey(route), route.MockResponse.Template)
+				if err != nil {
+					return err
+				}
+				rm.mu.Lock()
+				rm.mockTemplates[routeKey(route)] = tmpl
+				rm.mu.Unlock()
 			}
 		}
+	case types.HandlerAggregate:
+		if route.Aggregate == nil || len(route.Aggregate.Calls) == 0 {
+			return fmt.Errorf("aggregate handler requires at least one upstream call")
+		}
+		switch route.Aggregate.Mode {
+		case "", types.AggregateModeParallel, types.AggregateModeSaga:
+		default:
+			return fmt.Errorf("unsupported aggregate mode: %s", route.Aggregate.Mode)
+		}
+	case types.HandlerProxy:
+		if len(route.Upstreams) == 0 {
+			return fmt.Errorf("proxy handler requires at least one upstream target")
+		}
+		for _, target := range route.Upstreams {
+			if (target.URL == "") == (target.Service == "") {
+				return fmt.Errorf("upstream target %s must have either a url or a service", target.Name)
+			}
+		}
+		if route.ResponseSamplePercent < 0 || route.ResponseSamplePercent > 100 {
+			return fmt.Errorf("responseSamplePercent must be between 0 and 100")
+		}
+	case types.HandlerTraffic:
+		if err := validateTrafficRoute(route); err != nil {
+			return err
+		}
 	default:
-		// Generic response for other POST routes
-		response = gin.H{
-			"message": "POST request processed successfully",
-			"route":   route.RouteName,
-			"method":  route.Method,
-			"data":    requestBody,
+		return fmt.Errorf("unsupported handler type: %s", route.Handler)
+	}
+
+	if err := validateFaults(route.Faults); err != nil {
+		return err
+	}
+	if err := validatePolicySettings(route); err != nil {
+		return err
+	}
+	if err := validatePolicyCombination(route); err != nil {
+		return err
+	}
+	if err := validateTelemetry(route); err != nil {
+		return err
+	}
+	if err := validateSLO(route); err != nil {
+		return err
+	}
+	if err := validateCache(route); err != nil {
+		return err
+	}
+	if err := validateRateLimit(route); err != nil {
+		return err
+	}
+	if err := validateBodyLimit(route); err != nil {
+		return err
+	}
+	if err := rm.validateContentTypes(route); err != nil {
+		return err
+	}
+	if err := validateStreaming(route); err != nil {
+		return err
+	}
+	if err := validateMirror(route); err != nil {
+		return err
+	}
+	if err := validateHost(route); err != nil {
+		return err
+	}
+	if err := validateThrottles(route); err != nil {
+		return err
+	}
+	if err := validateSchemaLearning(route); err != nil {
+		return err
+	}
+	if err := transform.ValidatePatch(route.ResponsePatch); err != nil {
+		return fmt.Errorf("invalid responsePatch: %w", err)
+	}
+	if err := rm.compileFallback(route); err != nil {
+		return err
+	}
+	if err := upstream.ValidateHeaderPolicy(route.Headers); err != nil {
+		return err
+	}
+
+	middlewares := []func(next http.Handler) http.Handler{rm.trackFirstTraffic(route, rm.trackRevision(route)), rm.limitBody(route)}
+	if route.RateLimit != nil {
+		middlewares = append(middlewares, rm.limitRequests(route))
+	}
+	if route.Faults != nil {
+		middlewares = append(middlewares, rm.injectFaults(route.Faults))
+	}
+	var handler http.Handler = rm.buildPipeline(route)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	switch route.Method {
+	case "GET", "POST", "PUT", "DELETE":
+		if err := table.handle(route.Method, route.RouteName, handler); err != nil {
+			return err
 		}
+	default:
+		return fmt.Errorf("unsupported HTTP method: %s", route.Method)
 	}
 
-	c.JSON(http.StatusOK, response)
+	if route.Handler == types.HandlerProxy {
+		balancer := upstream.NewBalancer(route.RouteName, route.Upstreams)
+		balancer.StartHealthChecks(rm.upstreamClient.HTTPClient())
+
+		rm.mu.Lock()
+		if previous, exists := rm.balancers[routeKey(route)]; exists {
+			previous.Stop()
+		}
+		rm.balancers[routeKey(route)] = balancer
+		rm.mu.Unlock()
+	}
+
+	return nil
+}
+
+// routeKey returns the unique key of a route, such as "POST /v1/orders",
+// "POST api.example.com/v1/orders" for a route of one host, or
+// "acme:POST /v1/orders" for a route of tenant acme
+func routeKey(route types.RouteConfig) string {
+	key := route.Method + " " + route.Host + route.RouteName
+	if route.Tenant != "" {
+		return route.Tenant + ":" + key
+	}
+	return key
+}
+
+// RouteKey returns the unique key of a route, as used in logs and admin
+// listings
+func RouteKey(route types.RouteConfig) string {
+	return routeKey(route)
 }
 
 // GetConfig returns the current route configuration
 func (rm *RouteManager) GetConfig() *types.RoutesConfig {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
 	return rm.config
 }
 
+// mockTemplate returns the compiled mock response template of a route
+func (rm *RouteManager) mockTemplate(key string) (*template.Template, bool) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	tmpl, exists := rm.mockTemplates[key]
+	return tmpl, exists
+}
+
+// balancer returns the upstream balancer of a proxied route
+func (rm *RouteManager) balancer(key string) *upstream.Balancer {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.balancers[key]
+}
+
+// GetUpstreamStatus returns the state of upstream targets for every proxied route
+func (rm *RouteManager) GetUpstreamStatus() map[string][]upstream.TargetStatus {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	status := make(map[string][]upstream.TargetStatus, len(rm.balancers))
+	for key, balancer := range rm.balancers {
+		status[key] = balancer.Status()
+	}
+	return status
+}
+
+// Stop releases background resources such as upstream health checks
+func (rm *RouteManager) Stop() {
+	rm.mu.RLock()
+	canary := rm.canary
+	rm.mu.RUnlock()
+	if canary != nil {
+		rm.finishCanary(canary, rollout.StateAborted, "route manager stopped")
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if rm.lazyRouter != nil {
+		close(rm.lazyRouter.stop)
+		rm.lazyRouter = nil
+	}
+	for _, balancer := range rm.balancers {
+		balancer.Stop()
+	}
+}
+
+// GetOperations returns the operation store
+func (rm *RouteManager) GetOperations() *operations.Store {
+	return rm.operations
+}
+
+// GetDecisions returns the policy decision log
+func (rm *RouteManager) GetDecisions() *decisions.Log {
+	return rm.decisions
+}
+
+// GetPolicySamples returns the recorded policy inputs of recent requests
+func (rm *RouteManager) GetPolicySamples() *decisions.Samples {
+	return rm.samples
+}
+
+// GetAuditLog returns the configuration audit log
+func (rm *RouteManager) GetAuditLog() *audit.Log {
+	return rm.audit
+}
+
+// GetEventBroker returns the broker streaming route and policy events
+func (rm *RouteManager) GetEventBroker() *events.Broker {
+	return rm.broker
+}
+
+// GetSchemaValidator returns the validator of request and response schemas
+func (rm *RouteManager) GetSchemaValidator() *validator.SchemaValidator {
+	return rm.schemaValidator
+}
+
+// GetDependencies returns the registry of dependencies marked down
+func (rm *RouteManager) GetDependencies() *dependencies.Registry {
+	return rm.dependencies
+}
+
 // GetMockData returns the mock data instance
 func (rm *RouteManager) GetMockData() *types.MockData {
 	return rm.mockData
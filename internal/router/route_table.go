@@ -0,0 +1,173 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/reqctx"
+)
+
+// Kinds of route pattern segments, in matching priority
+const (
+	segmentStatic = iota
+	segmentParam
+	segmentWildcard
+)
+
+// routeTable serves the compiled routes of one configuration. Patterns use
+// gin-style :param and *wildcard segments; when several routes match a
+// request, static segments win over parameters and parameters over
+// wildcards, segment by segment.
+type routeTable struct {
+	routes map[string][]tableRoute
+}
+
+// tableRoute is a route registered on a route table
+type tableRoute struct {
+	pattern  string
+	segments []string
+	handler  http.Handler
+}
+
+// newRouteTable creates an empty route table
+func newRouteTable() *routeTable {
+	return &routeTable{routes: make(map[string][]tableRoute)}
+}
+
+// handle registers the handler of a route pattern
+func (t *routeTable) handle(method, pattern string, handler http.Handler) error {
+	segments := splitPattern(pattern)
+	for i, segment := range segments {
+		if segmentKind(segment) == segmentWildcard && i != len(segments)-1 {
+			return fmt.Errorf("wildcard segment %s must be the last segment of %s", segment, pattern)
+		}
+	}
+	for _, existing := range t.routes[method] {
+		if sameShape(existing.segments, segments) {
+			return fmt.Errorf("route %s %s conflicts with %s %s", method, pattern, method, existing.pattern)
+		}
+	}
+	t.routes[method] = append(t.routes[method], tableRoute{pattern: pattern, segments: segments, handler: handler})
+	return nil
+}
+
+// match finds the route serving a request method and path, with its path
+// parameters
+func (t *routeTable) match(method, path string) (tableRoute, map[string]string, bool) {
+	var best tableRoute
+	var bestParams map[string]string
+	found := false
+	for _, route := range t.routes[method] {
+		params, ok := matchSegments(route.segments, path)
+		if ok && (!found || precedes(route.segments, best.segments)) {
+			best, bestParams, found = route, params, true
+		}
+	}
+	return best, bestParams, found
+}
+
+// ServeHTTP serves a request from the matching route, recording its path
+// parameters in the request context. Panics of route handlers are logged
+// and answered with 500 Internal Server Error.
+func (t *routeTable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, params, ok := t.match(r.Method, r.URL.Path)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Route not found",
+		})
+		return
+	}
+	rc, r := reqctx.Attach(r)
+	rc.Params = params
+
+	defer func() {
+		if err := recover(); err != nil {
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			logging.FromContext(r.Context()).Error("Route handler panicked",
+				"route", r.Method+" "+route.pattern, "error", err, "stack", string(debug.Stack()))
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}()
+	route.handler.ServeHTTP(w, r)
+}
+
+// splitPattern splits a route pattern into its segments
+func splitPattern(pattern string) []string {
+	return strings.Split(strings.Trim(pattern, "/"), "/")
+}
+
+// segmentKind returns the kind of a pattern segment
+func segmentKind(segment string) int {
+	switch {
+	case strings.HasPrefix(segment, "*"):
+		return segmentWildcard
+	case strings.HasPrefix(segment, ":"):
+		return segmentParam
+	default:
+		return segmentStatic
+	}
+}
+
+// sameShape reports whether two patterns match the same paths
+func sameShape(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if segmentKind(a[i]) != segmentKind(b[i]) || (segmentKind(a[i]) == segmentStatic && a[i] != b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// precedes reports whether a pattern takes priority over another pattern
+// matching the same path
+func precedes(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if kindA, kindB := segmentKind(a[i]), segmentKind(b[i]); kindA != kindB {
+			return kindA < kindB
+		}
+	}
+	return len(a) > len(b)
+}
+
+// matchSegments matches a request path against pattern segments and returns
+// the path parameters. Wildcard values keep their leading slash, as in gin.
+func matchSegments(segments []string, path string) (map[string]string, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	params := make(map[string]string)
+	for i, segment := range segments {
+		switch segmentKind(segment) {
+		case segmentWildcard:
+			rest := ""
+			if remaining := strings.SplitN(strings.TrimPrefix(path, "/"), "/", i+1); len(remaining) > i {
+				rest = remaining[i]
+			}
+			params[segment[1:]] = "/" + rest
+			return params, true
+		case segmentParam:
+			if i >= len(parts) || parts[i] == "" {
+				return nil, false
+			}
+			params[segment[1:]] = parts[i]
+		default:
+			if i >= len(parts) || segment != parts[i] {
+				return nil, false
+			}
+		}
+	}
+	return params, len(segments) == len(parts)
+}
+
+// matchPattern reports whether a request path matches a gin-style route
+// pattern with :param and *wildcard segments
+func matchPattern(pattern, path string) bool {
+	_, ok := matchSegments(splitPattern(pattern), path)
+	return ok
+}
@@ -0,0 +1,61 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"/v1/status", "/v1/status", true},
+		{"/v1/status", "/v1/status/", true},
+		{"/v1/status", "/v1/other", false},
+		{"/v1/services/:serviceId/traffic", "/v1/services/service123/traffic", true},
+		{"/v1/services/:serviceId/traffic", "/v1/services//traffic", false},
+		{"/v1/services/:serviceId/traffic", "/v1/services/service123", false},
+		{"/v1/services/:serviceId/traffic", "/v1/services/service123/traffic/extra", false},
+		{"/static/*filepath", "/static/css/site.css", true},
+	}
+
+	for _, tc := range cases {
+		if got := matchPattern(tc.pattern, tc.path); got != tc.match {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", tc.pattern, tc.path, got, tc.match)
+		}
+	}
+}
+
+func TestRouteTablePrefersSpecificRoutes(t *testing.T) {
+	table := newRouteTable()
+	for _, pattern := range []string{"/v1/items/:id", "/v1/items/latest", "/v1/*path"} {
+		if err := table.handle("GET", pattern, http.NotFoundHandler()); err != nil {
+			t.Fatalf("Failed to register %s: %v", pattern, err)
+		}
+	}
+	if err := table.handle("GET", "/v1/items/:name", http.NotFoundHandler()); err == nil {
+		t.Error("Expected a conflicting pattern to be rejected")
+	}
+
+	cases := []struct {
+		path    string
+		pattern string
+		param   string
+		value   string
+	}{
+		{"/v1/items/latest", "/v1/items/latest", "", ""},
+		{"/v1/items/42", "/v1/items/:id", "id", "42"},
+		{"/v1/orders/7/lines", "/v1/*path", "path", "/orders/7/lines"},
+	}
+	for _, tc := range cases {
+		route, params, ok := table.match("GET", tc.path)
+		if !ok || route.pattern != tc.pattern || params[tc.param] != tc.value {
+			t.Errorf("%s: expected %s with %s=%q, got %s with %v", tc.path, tc.pattern, tc.param, tc.value, route.pattern, params)
+		}
+	}
+	if _, _, ok := table.match("POST", "/v1/items/42"); ok {
+		t.Error("Expected no match for another method")
+	}
+}
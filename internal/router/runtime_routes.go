@@ -0,0 +1,260 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"dynamiccontrol/internal/guardrails"
+	"dynamiccontrol/internal/routestore"
+	"dynamiccontrol/internal/types"
+)
+
+// ErrNotRuntimeRoute is returned when deleting a route that was not created
+// through the admin API
+var ErrNotRuntimeRoute = errors.New("route was not created at runtime")
+
+// runtimeRoutes holds the routes created through the admin API by route key,
+// overlaid on the routes of the config store. With a route store they are
+// persisted, so they survive restarts.
+type runtimeRoutes struct {
+	mu     sync.RWMutex
+	store  routestore.Store
+	routes map[string]types.RouteConfig
+	// base holds the routes of the config store by route key, so deleting a
+	// runtime route overriding one restores it
+	base map[string]types.RouteConfig
+}
+
+// newRuntimeRoutes creates an empty overlay kept in memory only
+func newRuntimeRoutes() *runtimeRoutes {
+	return &runtimeRoutes{
+		routes: make(map[string]types.RouteConfig),
+		base:   make(map[string]types.RouteConfig),
+	}
+}
+
+// SetRouteStore persists runtime routes in the given store and loads the
+// routes it holds. They are merged with the config store routes on the next
+// load, so it must be called before LoadFromStore.
+func (rm *RouteManager) SetRouteStore(ctx context.Context, store routestore.Store) error {
+	routes, err := store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load runtime routes from %s store: %w", store.Name(), err)
+	}
+
+	rm.runtime.mu.Lock()
+	defer rm.runtime.mu.Unlock()
+	rm.runtime.store = store
+	rm.runtime.routes = routes
+	slog.Info("Loaded runtime routes", "store", store.Name(), "routes", len(routes))
+	return nil
+}
+
+// withRuntimeRoutes overlays the runtime routes on a configuration loaded
+// from the config store. A runtime route replaces a configured route with
+// the same key, unless both are identical: the route is then part of the
+// configuration and no longer kept as a runtime route.
+func (rm *RouteManager) withRuntimeRoutes(ctx context.Context, config *types.RoutesConfig) *types.RoutesConfig {
+	rm.runtime.mu.Lock()
+	defer rm.runtime.mu.Unlock()
+
+	rm.runtime.base = make(map[string]types.RouteConfig, len(config.Routes))
+	for _, route := range config.Routes {
+		rm.runtime.base[routeKey(route)] = route
+	}
+	if len(rm.runtime.routes) == 0 {
+		return config
+	}
+
+	for key, route := range rm.runtime.routes {
+		configured, exists := rm.runtime.base[key]
+		if !exists || !sameRoute(configured, route) {
+			continue
+		}
+		slog.Info("Runtime route is now part of the configuration", "route", key)
+		delete(rm.runtime.routes, key)
+		if rm.runtime.store != nil {
+			if err := rm.runtime.store.Delete(ctx, key); err != nil {
+				slog.Warn("Failed to remove runtime route from store", "route", key, "error", err)
+			}
+		}
+	}
+
+	merged := *config
+	merged.Routes = make([]types.RouteConfig, 0, len(config.Routes)+len(rm.runtime.routes))
+	for _, route := range config.Routes {
+		key := routeKey(route)
+		if runtime, exists := rm.runtime.routes[key]; exists {
+			slog.Info("Runtime route overrides the configured route", "route", key)
+			route = runtime
+		}
+		merged.Routes = append(merged.Routes, route)
+	}
+	for _, key := range runtimeKeys(rm.runtime.routes) {
+		if _, exists := rm.runtime.base[key]; !exists {
+			merged.Routes = append(merged.Routes, rm.runtime.routes[key])
+		}
+	}
+	return &merged
+}
+
+// PutRuntimeRoute creates or replaces a route at runtime and persists it.
+// It reports whether no route with the same key was served before.
+func (rm *RouteManager) PutRuntimeRoute(ctx context.Context, route types.RouteConfig) (bool, error) {
+	route.Method = strings.ToUpper(route.Method)
+	if route.Method == "" {
+		return false, fmt.Errorf("route method is required")
+	}
+	if !strings.HasPrefix(route.RouteName, "/") {
+		return false, fmt.Errorf("route path %q must start with /", route.RouteName)
+	}
+	key := routeKey(route)
+
+	rm.reloadMu.Lock()
+	defer rm.reloadMu.Unlock()
+
+	previous := rm.GetConfig()
+	config := types.RoutesConfig{}
+	if previous != nil {
+		config = *previous
+		config.Routes = append([]types.RouteConfig(nil), previous.Routes...)
+	}
+	created := true
+	for i, existing := range config.Routes {
+		if routeKey(existing) == key {
+			config.Routes[i] = route
+			created = false
+			break
+		}
+	}
+	if created {
+		config.Routes = append(config.Routes, route)
+	}
+
+	if err := rm.applyRuntimeChange(previous, &config); err != nil {
+		return false, err
+	}
+	rm.runtime.mu.Lock()
+	defer rm.runtime.mu.Unlock()
+	if rm.runtime.store != nil {
+		if err := rm.runtime.store.Save(ctx, key, route); err != nil {
+			rm.restoreConfig(previous)
+			return false, fmt.Errorf("failed to persist route %s: %w", key, err)
+		}
+	}
+	rm.runtime.routes[key] = route
+	return created, nil
+}
+
+// DeleteRuntimeRoute removes a route created at runtime and returns it. A
+// configured route it replaced is served again.
+func (rm *RouteManager) DeleteRuntimeRoute(ctx context.Context, key string) (types.RouteConfig, error) {
+	rm.reloadMu.Lock()
+	defer rm.reloadMu.Unlock()
+
+	rm.runtime.mu.RLock()
+	route, exists := rm.runtime.routes[key]
+	configured, restore := rm.runtime.base[key]
+	rm.runtime.mu.RUnlock()
+	if !exists {
+		return types.RouteConfig{}, ErrNotRuntimeRoute
+	}
+
+	previous := rm.GetConfig()
+	if previous == nil {
+		return types.RouteConfig{}, fmt.Errorf("no configuration loaded")
+	}
+	config := *previous
+	config.Routes = make([]types.RouteConfig, 0, len(previous.Routes))
+	for _, existing := range previous.Routes {
+		if routeKey(existing) == key {
+			if !restore {
+				continue
+			}
+			existing = configured
+		}
+		config.Routes = append(config.Routes, existing)
+	}
+
+	if err := rm.applyRuntimeChange(previous, &config); err != nil {
+		return types.RouteConfig{}, err
+	}
+	rm.runtime.mu.Lock()
+	defer rm.runtime.mu.Unlock()
+	if rm.runtime.store != nil {
+		if err := rm.runtime.store.Delete(ctx, key); err != nil {
+			rm.restoreConfig(previous)
+			return types.RouteConfig{}, fmt.Errorf("failed to delete persisted route %s: %w", key, err)
+		}
+	}
+	delete(rm.runtime.routes, key)
+	return route, nil
+}
+
+// ListRuntimeRoutes returns the routes created at runtime, sorted by key
+func (rm *RouteManager) ListRuntimeRoutes() []types.RouteConfig {
+	rm.runtime.mu.RLock()
+	defer rm.runtime.mu.RUnlock()
+
+	routes := make([]types.RouteConfig, 0, len(rm.runtime.routes))
+	for _, key := range runtimeKeys(rm.runtime.routes) {
+		routes = append(routes, rm.runtime.routes[key])
+	}
+	return routes
+}
+
+// IsRuntimeRoute reports whether the route with the given key was created
+// at runtime
+func (rm *RouteManager) IsRuntimeRoute(key string) bool {
+	rm.runtime.mu.RLock()
+	defer rm.runtime.mu.RUnlock()
+	_, exists := rm.runtime.routes[key]
+	return exists
+}
+
+// applyRuntimeChange checks a runtime route change against the guardrails
+// and applies it
+func (rm *RouteManager) applyRuntimeChange(previous, config *types.RoutesConfig) error {
+	if rm.guard != nil && previous != nil {
+		if err := rm.guard.Check(guardrails.Change{PreviousRoutes: previous, Routes: config}); err != nil {
+			return err
+		}
+	}
+	return rm.ApplyConfig(config)
+}
+
+// restoreConfig re-applies the configuration served before a runtime change
+// that could not be persisted
+func (rm *RouteManager) restoreConfig(previous *types.RoutesConfig) {
+	if previous == nil {
+		return
+	}
+	if err := rm.ApplyConfig(previous); err != nil {
+		slog.Error("Failed to restore configuration", "error", err)
+	}
+}
+
+// sameRoute reports whether two routes have the same configuration, ignoring
+// the difference between empty and missing fields
+func sameRoute(a, b types.RouteConfig) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+// runtimeKeys returns the keys of a route map in order
+func runtimeKeys(routes map[string]types.RouteConfig) []string {
+	keys := make([]string, 0, len(routes))
+	for key := range routes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dynamiccontrol/internal/configstore"
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/routestore"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+)
+
+// bootWithRouteStore starts a route manager the way the server does, loading
+// the routes file over the persisted runtime routes
+func bootWithRouteStore(t *testing.T, routesFile, snapshot string) *RouteManager {
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	t.Cleanup(rm.Stop)
+	ctx := context.Background()
+	if err := rm.SetRouteStore(ctx, routestore.NewFileStore(snapshot)); err != nil {
+		t.Fatalf("Failed to set route store: %v", err)
+	}
+	if err := rm.LoadFromStore(ctx, configstore.NewFileStore(routesFile, t.TempDir())); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := rm.ApplyConfig(rm.GetConfig()); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	return rm
+}
+
+func TestRuntimeRoutesSurviveRestarts(t *testing.T) {
+	dir := t.TempDir()
+	routesFile := filepath.Join(dir, "routes.json")
+	snapshot := filepath.Join(dir, "runtime.json")
+	configured := `{"routes": [{"routeName": "/v1/status", "method": "GET"}]}`
+	if err := os.WriteFile(routesFile, []byte(configured), 0o644); err != nil {
+		t.Fatalf("Failed to write routes: %v", err)
+	}
+	ctx := context.Background()
+
+	rm := bootWithRouteStore(t, routesFile, snapshot)
+	created, err := rm.PutRuntimeRoute(ctx, types.RouteConfig{RouteName: "/v1/orders", Method: "post"})
+	if err != nil || !created {
+		t.Fatalf("Expected the route to be created, got %v, %v", created, err)
+	}
+	override := types.RouteConfig{RouteName: "/v1/status", Method: "GET", Policies: []string{"status_policy"}}
+	if created, err := rm.PutRuntimeRoute(ctx, override); err != nil || created {
+		t.Fatalf("Expected the configured route to be replaced, got %v, %v", created, err)
+	}
+	if _, err := rm.PutRuntimeRoute(ctx, types.RouteConfig{RouteName: "v1/invalid", Method: "GET"}); err == nil {
+		t.Error("Expected a path without a leading slash to be rejected")
+	}
+
+	rm = bootWithRouteStore(t, routesFile, snapshot)
+	routes := rm.GetConfig().Routes
+	if len(routes) != 2 || len(routes[0].Policies) != 1 || routeKey(routes[1]) != "POST /v1/orders" {
+		t.Fatalf("Expected the runtime routes to be restored over the configuration, got %+v", routes)
+	}
+	if !rm.IsRuntimeRoute("POST /v1/orders") || len(rm.ListRuntimeRoutes()) != 2 {
+		t.Errorf("Expected two runtime routes, got %+v", rm.ListRuntimeRoutes())
+	}
+
+	if _, err := rm.DeleteRuntimeRoute(ctx, "GET /v1/status"); err != nil {
+		t.Fatalf("Failed to delete route: %v", err)
+	}
+	if routes := rm.GetConfig().Routes; len(routes) != 2 || len(routes[0].Policies) != 0 {
+		t.Errorf("Expected the configured route to be served again, got %+v", routes)
+	}
+	if _, err := rm.DeleteRuntimeRoute(ctx, "GET /v1/status"); !errors.Is(err, ErrNotRuntimeRoute) {
+		t.Errorf("Expected configured routes not to be deletable, got %v", err)
+	}
+}
+
+func TestRuntimeRouteAdoptedByConfiguration(t *testing.T) {
+	dir := t.TempDir()
+	routesFile := filepath.Join(dir, "routes.json")
+	snapshot := filepath.Join(dir, "runtime.json")
+	if err := os.WriteFile(routesFile, []byte(`{"routes": []}`), 0o644); err != nil {
+		t.Fatalf("Failed to write routes: %v", err)
+	}
+
+	rm := bootWithRouteStore(t, routesFile, snapshot)
+	if _, err := rm.PutRuntimeRoute(context.Background(), types.RouteConfig{RouteName: "/v1/orders", Method: "GET"}); err != nil {
+		t.Fatalf("Failed to create route: %v", err)
+	}
+
+	adopted := `{"routes": [{"routeName": "/v1/orders", "method": "GET"}]}`
+	if err := os.WriteFile(routesFile, []byte(adopted), 0o644); err != nil {
+		t.Fatalf("Failed to write routes: %v", err)
+	}
+	rm = bootWithRouteStore(t, routesFile, snapshot)
+	if len(rm.GetConfig().Routes) != 1 || rm.IsRuntimeRoute("GET /v1/orders") {
+		t.Errorf("Expected the route to be owned by the configuration, got %+v", rm.ListRuntimeRoutes())
+	}
+	persisted, err := routestore.NewFileStore(snapshot).Load(context.Background())
+	if err != nil || len(persisted) != 0 {
+		t.Errorf("Expected the adopted route to be removed from the store, got %v, %v", persisted, err)
+	}
+}
@@ -0,0 +1,193 @@
+package router
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"dynamiccontrol/internal/schemainfer"
+	"dynamiccontrol/internal/types"
+)
+
+// LearnedSchema is the request schema inferred for a route in learning mode
+type LearnedSchema struct {
+	Route   string                 `json:"route"`
+	Samples int                    `json:"samples"`
+	Schema  map[string]interface{} `json:"schema"`
+}
+
+// SchemaLearners holds the schema inferrers of the routes in learning mode,
+// by route key
+type SchemaLearners struct {
+	mu        sync.Mutex
+	inferrers map[string]*schemainfer.Inferrer
+}
+
+// newSchemaLearners creates an empty set of schema learners
+func newSchemaLearners() *SchemaLearners {
+	return &SchemaLearners{inferrers: make(map[string]*schemainfer.Inferrer)}
+}
+
+// learner returns the inferrer of a route, creating it when missing
+func (sl *SchemaLearners) learner(route string, maxSamples int) *schemainfer.Inferrer {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	inferrer, exists := sl.inferrers[route]
+	if !exists {
+		inferrer = schemainfer.New(maxSamples)
+		sl.inferrers[route] = inferrer
+	}
+	return inferrer
+}
+
+// get returns the inferrer of a route
+func (sl *SchemaLearners) get(route string) (*schemainfer.Inferrer, bool) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	inferrer, exists := sl.inferrers[route]
+	return inferrer, exists
+}
+
+// reset discards the inferrer of a route
+func (sl *SchemaLearners) reset(route string) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	delete(sl.inferrers, route)
+}
+
+// Name identifies the schema learners to the memory manager
+func (sl *SchemaLearners) Name() string {
+	return "schema_learning"
+}
+
+// SizeBytes returns the approximate memory held by the learned samples
+func (sl *SchemaLearners) SizeBytes() int64 {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	var size int64
+	for _, inferrer := range sl.inferrers {
+		size += inferrer.SizeBytes()
+	}
+	return size
+}
+
+// Shrink discards the samples of routes, largest first, until the learners
+// hold at most target bytes. Learning restarts for the discarded routes.
+func (sl *SchemaLearners) Shrink(target int64) int64 {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	routes := make([]string, 0, len(sl.inferrers))
+	sizes := make(map[string]int64, len(sl.inferrers))
+	var size int64
+	for route, inferrer := range sl.inferrers {
+		routes = append(routes, route)
+		sizes[route] = inferrer.SizeBytes()
+		size += sizes[route]
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return sizes[routes[i]] > sizes[routes[j]]
+	})
+
+	var freed int64
+	for _, route := range routes {
+		if size <= target {
+			break
+		}
+		delete(sl.inferrers, route)
+		size -= sizes[route]
+		freed += sizes[route]
+	}
+	return freed
+}
+
+// GetSchemaLearners returns the schema inferrers of the routes in learning
+// mode
+func (rm *RouteManager) GetSchemaLearners() *SchemaLearners {
+	return rm.learners
+}
+
+// validateSchemaLearning checks the schema learning configuration of a route
+// at registration time
+func validateSchemaLearning(route types.RouteConfig) error {
+	config := route.SchemaLearning
+	if config == nil {
+		return nil
+	}
+	if len(route.RequestSchema) > 0 {
+		return fmt.Errorf("schemaLearning requires a route without a request schema")
+	}
+	if config.SamplePercent < 0 || config.SamplePercent > 100 {
+		return fmt.Errorf("schemaLearning samplePercent must be between 0 and 100")
+	}
+	if config.MaxSamples < 0 {
+		return fmt.Errorf("schemaLearning maxSamples must not be negative")
+	}
+	return nil
+}
+
+// learnSchema records a sampled request body of a route in learning mode
+func (rm *RouteManager) learnSchema(ex *Exchange) {
+	config := ex.Route.SchemaLearning
+	if config.SamplePercent > 0 && rand.Float64()*100 >= config.SamplePercent {
+		return
+	}
+
+	rm.learners.learner(routeKey(ex.Route), config.MaxSamples).Observe(ex.Body)
+}
+
+// LearnedSchemas returns the schemas inferred for the routes in learning mode
+func (rm *RouteManager) LearnedSchemas() []LearnedSchema {
+	rm.mu.RLock()
+	var routes []string
+	for key, route := range rm.routeIndex {
+		if route.SchemaLearning != nil {
+			routes = append(routes, key)
+		}
+	}
+	rm.mu.RUnlock()
+	sort.Strings(routes)
+
+	schemas := make([]LearnedSchema, 0, len(routes))
+	for _, route := range routes {
+		schemas = append(schemas, rm.learnedSchema(route))
+	}
+	return schemas
+}
+
+// LearnedSchema returns the schema inferred for a route, such as
+// "POST /v1/orders", in learning mode
+func (rm *RouteManager) LearnedSchema(route string) (LearnedSchema, bool) {
+	if !rm.isLearning(route) {
+		return LearnedSchema{}, false
+	}
+	return rm.learnedSchema(route), true
+}
+
+// learnedSchema returns the schema inferred from the samples of a route
+func (rm *RouteManager) learnedSchema(route string) LearnedSchema {
+	learner, exists := rm.learners.get(route)
+	if !exists {
+		return LearnedSchema{Route: route, Schema: map[string]interface{}{}}
+	}
+	return LearnedSchema{Route: route, Samples: learner.Samples(), Schema: learner.Schema()}
+}
+
+// ResetLearnedSchema discards the samples recorded for a route
+func (rm *RouteManager) ResetLearnedSchema(route string) bool {
+	if !rm.isLearning(route) {
+		return false
+	}
+	rm.learners.reset(route)
+	return true
+}
+
+// isLearning reports whether a route is configured in learning mode
+func (rm *RouteManager) isLearning(route string) bool {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	config, exists := rm.routeIndex[route]
+	return exists && config.SchemaLearning != nil
+}
@@ -0,0 +1,88 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSchemaLearning(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{
+		Routes: []types.RouteConfig{{
+			RouteName:      "/v1/items",
+			Method:         "POST",
+			SchemaLearning: &types.SchemaLearningConfig{MaxSamples: 2},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	for _, body := range []string{`{"serviceId": "a", "replicas": 2}`, `{"serviceId": "b"}`, `{"serviceId": "c", "extra": true}`} {
+		if recorder := serve(engine, body); recorder.Code != http.StatusOK {
+			t.Fatalf("Expected learning mode not to reject requests, got %d %s", recorder.Code, recorder.Body.String())
+		}
+	}
+
+	learned, found := rm.LearnedSchema("POST /v1/items")
+	if !found || learned.Samples != 2 {
+		t.Fatalf("Expected two samples, got %+v", learned)
+	}
+	properties, _ := learned.Schema["properties"].(map[string]interface{})
+	required, _ := learned.Schema["required"].([]string)
+	if len(properties) != 2 || len(required) != 1 || required[0] != "serviceId" {
+		t.Errorf("Unexpected inferred schema %v", learned.Schema)
+	}
+	if list := rm.LearnedSchemas(); len(list) != 1 || list[0].Route != "POST /v1/items" {
+		t.Errorf("Expected the route to be listed, got %+v", list)
+	}
+
+	if !rm.ResetLearnedSchema("POST /v1/items") {
+		t.Fatal("Expected the samples to be reset")
+	}
+	if learned, _ := rm.LearnedSchema("POST /v1/items"); learned.Samples != 0 {
+		t.Errorf("Expected no samples after reset, got %d", learned.Samples)
+	}
+	if _, found := rm.LearnedSchema("GET /v1/items"); found {
+		t.Error("Expected routes outside learning mode not to be found")
+	}
+}
+
+func TestSchemaLearningRequiresRouteWithoutSchema(t *testing.T) {
+	err := validateSchemaLearning(types.RouteConfig{
+		RequestSchema:  map[string]interface{}{"type": "object"},
+		SchemaLearning: &types.SchemaLearningConfig{},
+	})
+	if err == nil {
+		t.Error("Expected schema learning to be rejected on a route with a request schema")
+	}
+}
+
+func TestSchemaLearnersShrinkLargestFirst(t *testing.T) {
+	learners := newSchemaLearners()
+	learners.learner("POST /v1/small", 0).Observe(map[string]interface{}{"id": "a"})
+	large := learners.learner("POST /v1/large", 0)
+	large.Observe(map[string]interface{}{"id": "a", "name": "b", "tags": []interface{}{"c"}})
+
+	size := learners.SizeBytes()
+	freed := learners.Shrink(size - 1)
+	if freed != large.SizeBytes() || learners.SizeBytes() != size-freed {
+		t.Errorf("Expected the largest learner to be dropped, freed %d of %d", freed, size)
+	}
+	if _, exists := learners.get("POST /v1/large"); exists {
+		t.Error("Expected the large learner to be dropped")
+	}
+	if _, exists := learners.get("POST /v1/small"); !exists {
+		t.Error("Expected the small learner to be kept")
+	}
+}
@@ -0,0 +1,56 @@
+package router
+
+import (
+	"fmt"
+	"log/slog"
+
+	"dynamiccontrol/internal/types"
+)
+
+// resolveSchemaRefs returns the configuration with the registered schemas
+// named by requestSchemaRef and responseSchemaRef inlined. A route that
+// already carries an inline schema keeps it when its reference cannot be
+// resolved, as on read replicas applying a primary's resolved snapshot.
+func (rm *RouteManager) resolveSchemaRefs(config *types.RoutesConfig) (*types.RoutesConfig, error) {
+	var resolved *types.RoutesConfig
+	for i, route := range config.Routes {
+		if route.RequestSchemaRef == "" && route.ResponseSchemaRef == "" {
+			continue
+		}
+		if resolved == nil {
+			copied := *config
+			copied.Routes = append([]types.RouteConfig(nil), config.Routes...)
+			resolved = &copied
+		}
+
+		var err error
+		if route.RequestSchema, err = rm.resolveSchemaRef(route.RequestSchemaRef, route.RequestSchema); err != nil {
+			return nil, fmt.Errorf("route %s: requestSchemaRef: %w", routeKey(route), err)
+		}
+		if route.ResponseSchema, err = rm.resolveSchemaRef(route.ResponseSchemaRef, route.ResponseSchema); err != nil {
+			return nil, fmt.Errorf("route %s: responseSchemaRef: %w", routeKey(route), err)
+		}
+		resolved.Routes[i] = route
+	}
+	if resolved == nil {
+		return config, nil
+	}
+	return resolved, nil
+}
+
+// resolveSchemaRef returns the schema a reference names, or the inline schema
+// when there is no reference or it cannot be resolved
+func (rm *RouteManager) resolveSchemaRef(ref string, inline map[string]interface{}) (map[string]interface{}, error) {
+	if ref == "" {
+		return inline, nil
+	}
+	schema, err := rm.schemaValidator.ResolveSchemaRef(ref)
+	if err != nil {
+		if inline != nil {
+			slog.Warn("Using inline schema in place of unresolved schema reference", "ref", ref, "error", err)
+			return inline, nil
+		}
+		return nil, err
+	}
+	return schema, nil
+}
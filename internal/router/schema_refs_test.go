@@ -0,0 +1,49 @@
+package router
+
+import (
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+)
+
+func TestApplyConfigResolvesSchemaRefs(t *testing.T) {
+	schemaValidator := validator.NewSchemaValidator()
+	if err := schemaValidator.SetSchemaDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to set schema directory: %v", err)
+	}
+	rm := NewRouteManager(opa.NewPolicyManager(), schemaValidator)
+	defer rm.Stop()
+
+	v1 := map[string]interface{}{"type": "object", "required": []interface{}{"serviceId"}}
+	if err := schemaValidator.RegisterSchema("traffic-request", "v1", v1); err != nil {
+		t.Fatalf("Failed to register schema: %v", err)
+	}
+
+	route := types.RouteConfig{RouteName: "/v1/items", Method: "POST", RequestSchemaRef: "traffic-request@v1"}
+	config := &types.RoutesConfig{Routes: []types.RouteConfig{route}}
+	if err := rm.ApplyConfig(config); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	applied := rm.GetConfig().Routes[0]
+	if applied.RequestSchema == nil || applied.RequestSchemaRef != "traffic-request@v1" {
+		t.Errorf("Expected the referenced schema to be inlined, got %+v", applied)
+	}
+	if config.Routes[0].RequestSchema != nil {
+		t.Error("Expected the applied configuration not to be modified")
+	}
+
+	// A resolved configuration applies where the reference is unknown,
+	// as on replicas without the registry
+	replica := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer replica.Stop()
+	if err := replica.ApplyConfig(rm.GetConfig()); err != nil {
+		t.Errorf("Expected a resolved configuration to apply, got %v", err)
+	}
+
+	route.RequestSchemaRef = "traffic-request@v2"
+	if err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{route}}); err == nil {
+		t.Error("Expected an unknown schema reference to fail the apply")
+	}
+}
@@ -0,0 +1,79 @@
+package router
+
+import (
+	"fmt"
+	"time"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/types"
+)
+
+// splitPolicies separates the enforced policies of a route from those
+// running in shadow mode, keeping their configured order
+func splitPolicies(route types.RouteConfig) (enforced, shadow []string) {
+	for _, policy := range route.Policies {
+		if route.PolicySettings[policy].Mode == types.PolicyModeShadow {
+			shadow = append(shadow, policy)
+		} else {
+			enforced = append(enforced, policy)
+		}
+	}
+	return enforced, shadow
+}
+
+// validatePolicySettings checks that settings refer to the route's policies
+// and use a known mode
+func validatePolicySettings(route types.RouteConfig) error {
+	for policy, settings := range route.PolicySettings {
+		if !containsString(route.Policies, policy) {
+			return fmt.Errorf("policy settings refer to policy %s, which the route does not use", policy)
+		}
+		switch settings.Mode {
+		case "", types.PolicyModeEnforce, types.PolicyModeShadow:
+		default:
+			return fmt.Errorf("unsupported mode %q for policy %s", settings.Mode, policy)
+		}
+	}
+	return nil
+}
+
+// evaluateShadowPolicies evaluates the shadow policies of a route and records
+// their decisions without affecting the request. Denials are logged so a
+// policy's impact is known before it is enforced.
+func (rm *RouteManager) evaluateShadowPolicies(ex *Exchange, policies []string, input map[string]interface{}) {
+	for _, policy := range policies {
+		start := time.Now()
+		result, err := rm.GetPolicyEvaluator().EvaluatePolicy(policy, input)
+		decision := types.Decision{
+			RequestID:  ex.RequestID,
+			Route:      routeKey(ex.Route),
+			Method:     ex.Route.Method,
+			Path:       ex.Path,
+			Policies:   []string{policy},
+			DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+			Shadow:     true,
+		}
+		outcome := types.OutcomeAllow
+		switch {
+		case err != nil:
+			decision.Reason = err.Error()
+			outcome = types.OutcomeError
+		case result.Error != "":
+			decision.Reason = result.Error
+			outcome = types.OutcomeError
+		case result.Allowed:
+			decision.Allowed = true
+		default:
+			decision.Reason = fmt.Sprintf("Policy %s denied the request", policy)
+			outcome = types.OutcomeDeny
+		}
+		decision = rm.decisions.Record(decision)
+		metrics.ShadowPolicyDecisions.WithLabelValues(ex.Route.RouteName, policy, outcome).Inc()
+
+		if !decision.Allowed {
+			logging.FromContext(ex.Request.Context()).Info("Shadow policy would deny request",
+				"route", decision.Route, "policy", policy, "decisionId", decision.ID, "reason", decision.Reason)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/chaos"
+	"dynamiccontrol/internal/codec"
+	"dynamiccontrol/internal/decisions"
+	"dynamiccontrol/internal/events"
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+)
+
+// decodeStage extracts request headers, negotiates the content types of
+// routes with contentTypes, and parses and canonicalizes the body of requests
+// that carry one
+func (rm *RouteManager) decodeStage(ex *Exchange) error {
+	ex.Headers = make(map[string]string, len(ex.Request.Header))
+	for key, values := range ex.Request.Header {
+		if len(values) > 0 {
+			ex.Headers[key] = values[0]
+		}
+	}
+	if len(ex.Route.ContentTypes) > 0 {
+		if err := rm.negotiateResponse(ex); err != nil {
+			return err
+		}
+	}
+
+	if !expectsBody(ex.Route, ex.Request) {
+		return nil
+	}
+
+	decoder, err := rm.requestCodec(ex)
+	if err != nil {
+		return err
+	}
+	rawBody, err := io.ReadAll(ex.Request.Body)
+	if limit, tooLarge := bodyTooLarge(err); tooLarge {
+		return bodyTooLargeError(limit)
+	}
+	if err == nil {
+		ex.Body, err = decoder.Decode(rawBody)
+	}
+	if err != nil {
+		if decoder.ContentType() != codec.JSON {
+			return stageError(http.StatusBadRequest, fmt.Sprintf("Invalid %s body: %v", decoder.ContentType(), err), nil)
+		}
+		return stageError(http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err), nil)
+	}
+	// Other media types may carry values as text, such as XML elements, so
+	// they are converted to the types of the request schema
+	if decoder.ContentType() != codec.JSON {
+		ex.Body = rm.schemaValidator.Canonicalize(ex.Route.RequestSchema, ex.Body, types.CanonicalizeConfig{CoerceTypes: true})
+	}
+	ex.HasBody = true
+
+	// Normalize request body so policies see canonical input
+	if ex.Route.Canonicalize != nil {
+		ex.Body = rm.schemaValidator.Canonicalize(ex.Route.RequestSchema, ex.Body, *ex.Route.Canonicalize)
+	}
+	return nil
+}
+
+// expectsBody reports whether a request should carry a JSON body. Mock routes
+// always read the body of POST and PUT requests; upstream-backed routes read
+// it for any method but GET when one is sent.
+func expectsBody(route types.RouteConfig, r *http.Request) bool {
+	switch route.Handler {
+	case types.HandlerAggregate, types.HandlerProxy:
+		return route.Method != "GET" && r.ContentLength != 0
+	default:
+		return route.Method == "POST" || route.Method == "PUT"
+	}
+}
+
+// validateStage validates the request headers, query parameters and body
+// against the route schemas
+func (rm *RouteManager) validateStage(ex *Exchange) error {
+	request := ex.Request
+	if ex.Route.HeaderSchema != nil {
+		headers := make(map[string][]string, len(request.Header))
+		for name, values := range request.Header {
+			headers[strings.ToLower(name)] = values
+		}
+		if result := rm.schemaValidator.ValidateParameters(ex.Route.HeaderSchema, headers); !result.Valid {
+			return stageError(http.StatusBadRequest, "Header validation failed", validator.FormatValidationErrors(result.Errors))
+		}
+	}
+	if ex.Route.QuerySchema != nil {
+		if result := rm.schemaValidator.ValidateParameters(ex.Route.QuerySchema, request.URL.Query()); !result.Valid {
+			return stageError(http.StatusBadRequest, "Query validation failed", validator.FormatValidationErrors(result.Errors))
+		}
+	}
+	if !ex.HasBody {
+		return nil
+	}
+	if ex.Route.SchemaLearning != nil {
+		rm.learnSchema(ex)
+	}
+
+	rm.chaos.Delay(ex.Request.Context(), chaos.SlowSchemaValidation)
+	validationResult := rm.schemaValidator.ValidatePrepared(ex.schemas.request, ex.Body)
+	rm.profileSchema(ex, "request", ex.Route.RequestSchema, ex.Body)
+	if ex.Route.CandidateRequestSchema != nil {
+		rm.validateCandidate(ex, validationResult.Valid)
+	}
+	if !validationResult.Valid {
+		return stageError(http.StatusBadRequest, "Request validation failed", validator.FormatValidationErrors(validationResult.Errors))
+	}
+	return nil
+}
+
+// validateCandidate validates the request against the route's candidate
+// schema and records the outcome next to the enforced one. Requests the
+// candidate would newly reject are logged with the candidate's errors.
+func (rm *RouteManager) validateCandidate(ex *Exchange, currentValid bool) {
+	candidate := rm.schemaValidator.ValidatePrepared(ex.schemas.candidate, ex.Body)
+	metrics.SchemaCanaryValidations.WithLabelValues(ex.Route.RouteName, validityLabel(currentValid), validityLabel(candidate.Valid)).Inc()
+	if currentValid && !candidate.Valid {
+		logging.FromContext(ex.Request.Context()).Info("Candidate request schema would reject request",
+			"route", routeKey(ex.Route), "errors", candidate.Errors)
+	}
+}
+
+// profileSchema profiles a sampled share of schema validations, exporting
+// the time of the most expensive keywords and logging them with restructuring
+// suggestions at debug level
+func (rm *RouteManager) profileSchema(ex *Exchange, kind string, schema map[string]interface{}, data interface{}) {
+	if rm.schemaProfilePercent <= 0 || len(schema) == 0 || rand.Float64()*100 >= rm.schemaProfilePercent {
+		return
+	}
+	profile, err := rm.schemaValidator.Profile(schema, data, validator.DefaultHotspots)
+	if err != nil {
+		return
+	}
+	for _, hotspot := range profile.Hotspots {
+		metrics.SchemaKeywordLatency.WithLabelValues(ex.Route.RouteName, kind, hotspot.Keyword).Observe(hotspot.Duration.Seconds())
+	}
+	logging.FromContext(ex.Request.Context()).Debug("Schema validation profile",
+		"route", routeKey(ex.Route), "schema", kind, "total", profile.Total,
+		"hotspots", profile.Hotspots, "suggestions", profile.Suggestions)
+}
+
+// validityLabel returns the metric label of a validation outcome
+func validityLabel(valid bool) string {
+	if valid {
+		return "valid"
+	}
+	return "invalid"
+}
+
+// enrichStage collects query values for later stages; path parameters are
+// set when the route is matched
+func enrichStage(ex *Exchange) error {
+	if ex.Params == nil {
+		ex.Params = make(map[string]string)
+	}
+
+	ex.Query = make(map[string]string)
+	for key, values := range ex.Request.URL.Query() {
+		if len(values) > 0 {
+			ex.Query[key] = values[0]
+		}
+	}
+
+	annotateTrace(ex)
+	return nil
+}
+
+// authorizeStage evaluates the route policies against the request. Policies
+// in shadow mode are evaluated as well but never block the request.
+func (rm *RouteManager) authorizeStage(ex *Exchange) error {
+	input := ex.PolicyInput(ex.Route.Method, ex.Route.RouteName)
+	enforced, shadow := splitPolicies(ex.Route)
+
+	start := time.Now()
+	var policyResult *types.PolicyResult
+	var err error
+	// Label evaluation so CPU profiles can be broken down by route and policy
+	labels := pprof.Labels("route", routeKey(ex.Route), "policies", strings.Join(enforced, ","))
+	pprof.Do(ex.Request.Context(), labels, func(context.Context) {
+		policyResult, err = evaluateRoutePolicies(rm.GetPolicyEvaluator(), ex.Route, enforced, input)
+	})
+	decision := types.Decision{
+		RequestID:  ex.RequestID,
+		Route:      routeKey(ex.Route),
+		Method:     ex.Route.Method,
+		Path:       ex.Path,
+		Policies:   enforced,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		decision.Reason = err.Error()
+	} else {
+		decision.Allowed = policyResult.Allowed
+		decision.Reason = policyResult.Error
+	}
+	decision = rm.decisions.Record(decision)
+	ex.AddDecision(decision)
+	if len(shadow) > 0 {
+		rm.evaluateShadowPolicies(ex, shadow, input)
+	}
+	if len(ex.Route.Policies) > 0 {
+		rm.samples.Record(decision.Route, decisions.Sample{
+			RequestID: ex.RequestID,
+			Timestamp: decision.Timestamp,
+			Allowed:   decision.Allowed,
+			Input:     input,
+		})
+	}
+	if !decision.Allowed {
+		rm.broker.Publish(events.NewEvent(types.EventPolicyDenied, decision.Route, "", map[string]interface{}{
+			"decisionId": decision.ID,
+			"path":       decision.Path,
+			"policies":   decision.Policies,
+			"reason":     decision.Reason,
+		}))
+	}
+
+	if err != nil {
+		return stageError(http.StatusInternalServerError, fmt.Sprintf("Policy evaluation error: %v", err), nil)
+	}
+	if !policyResult.Allowed {
+		return stageError(http.StatusForbidden, fmt.Sprintf("Request denied by policy: %s", policyResult.Error), nil)
+	}
+	return nil
+}
+
+// executorFor returns the execute stage function for the route handler type,
+// guarded by the route's dependencies when it declares any
+func (rm *RouteManager) executorFor(route types.RouteConfig) func(ex *Exchange) error {
+	var execute func(ex *Exchange) error
+	switch route.Handler {
+	case types.HandlerAggregate:
+		execute = rm.executeAggregate
+	case types.HandlerProxy:
+		execute = rm.executeProxy
+	case types.HandlerTraffic:
+		execute = rm.executeTraffic
+	default:
+		execute = rm.executeMock
+	}
+	if len(route.ResponsePatch) > 0 {
+		execute = patchResponses(execute)
+	}
+	if route.Cache != nil {
+		execute = rm.cacheResponses(execute)
+	}
+	if route.Mirror != nil {
+		execute = rm.mirrorRequests(execute)
+	}
+	if servesTraffic(route) {
+		execute = rm.reconcileTraffic(execute)
+	}
+	if route.Method == "POST" {
+		execute = rm.idempotentRequests(execute)
+	}
+	if len(route.DependsOn) > 0 {
+		return rm.guardDependencies(execute)
+	}
+	return execute
+}
+
+// validateResponseStage validates the produced response against the route
+// response schema. Mock responses are served even when invalid, so failures
+// are only logged.
+func (rm *RouteManager) validateResponseStage(ex *Exchange) error {
+	if ex.Written || ex.ResponseValidated || ex.Response == nil {
+		return nil
+	}
+
+	validationResult := rm.schemaValidator.ValidatePrepared(ex.schemas.response, ex.Response)
+	rm.profileSchema(ex, "response", ex.Route.ResponseSchema, ex.Response)
+	if !validationResult.Valid {
+		logging.FromContext(ex.Request.Context()).Warn("Response validation failed", "route", routeKey(ex.Route), "errors", validationResult.Errors)
+	}
+	return nil
+}
+
+// encodeStage writes the produced response to the client, rendered by the
+// negotiated codec on routes with contentTypes and as newline-delimited JSON
+// on streaming routes
+func encodeStage(ex *Exchange) error {
+	if ex.Written {
+		return nil
+	}
+
+	w := ex.Writer
+	for key, value := range ex.ResponseHeaders {
+		w.Header().Set(key, value)
+	}
+
+	statusCode := ex.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	switch response := ex.Response.(type) {
+	case nil:
+		w.WriteHeader(statusCode)
+	case string:
+		writeData(w, statusCode, "text/plain; charset=utf-8", []byte(response))
+	default:
+		if ex.Route.Streaming {
+			streamResponse(ex, statusCode)
+			break
+		}
+		if ex.encoder == nil {
+			writeJSON(w, statusCode, response)
+			break
+		}
+		w.Header().Add("Vary", "Accept")
+		encoded, err := ex.encoder.Encode(response)
+		if err != nil {
+			return stageError(http.StatusInternalServerError, fmt.Sprintf("Failed to encode response as %s: %v", ex.encoder.ContentType(), err), nil)
+		}
+		writeData(w, statusCode, ex.encoder.ContentType(), encoded)
+	}
+	ex.Written = true
+	return nil
+}
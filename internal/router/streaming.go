@@ -0,0 +1,61 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/types"
+)
+
+// Streaming settings
+const (
+	// ndjsonContentType is the media type of streamed responses
+	ndjsonContentType = "application/x-ndjson"
+	// streamFlushItems is the number of items written between flushes
+	streamFlushItems = 64
+)
+
+// validateStreaming checks the streaming setting of a route at registration
+// time. Proxy routes stream their upstream responses as they are, and
+// streamed responses are always newline-delimited JSON.
+func validateStreaming(route types.RouteConfig) error {
+	if !route.Streaming {
+		return nil
+	}
+	if route.Handler == types.HandlerProxy {
+		return fmt.Errorf("streaming is not supported by the proxy handler")
+	}
+	if len(route.ContentTypes) > 0 {
+		return fmt.Errorf("streaming routes cannot declare contentTypes")
+	}
+	return nil
+}
+
+// streamResponse writes the response of a streaming route as
+// newline-delimited JSON, one line per item of a list response, flushing the
+// lines to the client as they are written. Other responses are written as a
+// single line. Once the first line is written the status can no longer
+// change, so encoding failures end the stream early.
+func streamResponse(ex *Exchange, statusCode int) {
+	w := ex.Writer
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(statusCode)
+
+	items := reflect.ValueOf(ex.Response)
+	if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+		items = reflect.ValueOf([]interface{}{ex.Response})
+	}
+	encoder := json.NewEncoder(w)
+	for i := 0; i < items.Len(); i++ {
+		if err := encoder.Encode(items.Index(i).Interface()); err != nil {
+			logging.FromContext(ex.Request.Context()).Warn("Failed to stream response item", "route", routeKey(ex.Route), "item", i, "error", err)
+			break
+		}
+		if (i+1)%streamFlushItems == 0 {
+			w.Flush()
+		}
+	}
+	w.Flush()
+}
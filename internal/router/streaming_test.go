@@ -0,0 +1,63 @@
+package router
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamingRouteWritesNDJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/services/:serviceId/traffic", Method: "GET", Handler: types.HandlerTraffic, Streaming: true},
+	}})
+	for i := 0; i < streamFlushItems+6; i++ {
+		if _, err := rm.GetTrafficController().Add("billing", types.TrafficRequest{TrafficType: "incoming", Volume: float64(i), Priority: "low"}); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/services/billing/traffic", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != ndjsonContentType {
+		t.Fatalf("Expected a streamed response, got %d %s", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if !recorder.Flushed {
+		t.Error("Expected the stream to be flushed")
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(strings.NewReader(recorder.Body.String()))
+	for scanner.Scan() {
+		var rule map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &rule); err != nil || rule["serviceId"] != "billing" {
+			t.Fatalf("Unexpected line %q: %v", scanner.Text(), err)
+		}
+		lines++
+	}
+	if lines != streamFlushItems+6 {
+		t.Errorf("Expected a line per rule, got %d", lines)
+	}
+}
+
+func TestStreamingRejectsProxyAndContentTypes(t *testing.T) {
+	if err := validateStreaming(types.RouteConfig{Streaming: true, Handler: types.HandlerProxy}); err == nil {
+		t.Error("Expected a streaming proxy route to be rejected")
+	}
+	if err := validateStreaming(types.RouteConfig{Streaming: true, ContentTypes: []string{"application/xml"}}); err == nil {
+		t.Error("Expected a streaming route with content types to be rejected")
+	}
+}
@@ -0,0 +1,92 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/tracing"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/upstream"
+)
+
+// validateSLO checks the service level objectives of a route
+func validateSLO(route types.RouteConfig) error {
+	slo := route.SLO
+	if slo == nil {
+		return nil
+	}
+	if slo.LatencyMs < 0 {
+		return fmt.Errorf("slo latencyMs must not be negative")
+	}
+	if slo.LatencyPercentile < 0 || slo.LatencyPercentile >= 100 {
+		return fmt.Errorf("slo latencyPercentile must be between 0 and 100")
+	}
+	if slo.Availability < 0 || slo.Availability >= 100 {
+		return fmt.Errorf("slo availability must be a percentage below 100")
+	}
+	if slo.MaxDenyRate < 0 || slo.MaxDenyRate > 1 {
+		return fmt.Errorf("slo maxDenyRate must be a fraction between 0 and 1")
+	}
+	return nil
+}
+
+// validateTelemetry checks the telemetry configuration of a route at registration time
+func validateTelemetry(route types.RouteConfig) error {
+	if route.Telemetry == nil {
+		return nil
+	}
+	for key := range route.Telemetry.Attributes {
+		if key == "" {
+			return fmt.Errorf("telemetry attribute names must not be empty")
+		}
+	}
+	for key := range route.Telemetry.Baggage {
+		if err := tracing.ValidateBaggageKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// annotateTrace names the request span after the route and attaches the
+// route's telemetry attributes and baggage to the request
+func annotateTrace(ex *Exchange) {
+	telemetry := ex.Route.Telemetry
+	request := ex.Request
+	if telemetry == nil {
+		tracing.Annotate(request.Context(), routeKey(ex.Route), nil)
+		return
+	}
+
+	vars := exchangeVariables(ex)
+	attributes := make(map[string]string, len(telemetry.Attributes))
+	for key, value := range telemetry.Attributes {
+		attributes[key] = upstream.ExpandVariables(value, vars)
+	}
+	tracing.Annotate(request.Context(), routeKey(ex.Route), attributes)
+
+	if len(telemetry.Baggage) == 0 {
+		return
+	}
+	members := make(map[string]string, len(telemetry.Baggage))
+	for key, value := range telemetry.Baggage {
+		members[key] = upstream.ExpandVariables(value, vars)
+	}
+	ctx, err := tracing.WithBaggage(request.Context(), members)
+	if err != nil {
+		logging.FromContext(request.Context()).Warn("Failed to add route baggage", "route", routeKey(ex.Route), "error", err)
+	}
+	ex.Request = request.WithContext(ctx)
+}
+
+// injectTrace propagates the request's trace context and baggage to an
+// upstream call, unless the route's header policy strips them
+func injectTrace(ex *Exchange, header http.Header) {
+	tracing.Inject(ex.Request.Context(), header)
+	if ex.Route.Headers != nil {
+		for _, name := range ex.Route.Headers.Strip {
+			header.Del(name)
+		}
+	}
+}
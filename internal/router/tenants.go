@@ -0,0 +1,24 @@
+package router
+
+import (
+	"dynamiccontrol/internal/tenancy"
+)
+
+// SetTenantHeader sets the request header naming the tenant of requests
+// whose host belongs to no tenant, tenancy.DefaultHeader by default. It takes
+// effect when the configuration is next applied.
+func (rm *RouteManager) SetTenantHeader(header string) {
+	rm.tenantHeader = header
+}
+
+// Tenants returns the resolver of the tenants declared by the active
+// configuration
+func (rm *RouteManager) Tenants() *tenancy.Resolver {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	if rm.tenants == nil {
+		resolver, _ := tenancy.NewResolver(nil, rm.tenantHeader)
+		return resolver
+	}
+	return rm.tenants
+}
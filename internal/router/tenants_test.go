@@ -0,0 +1,97 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTenantRouting(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mock := func(tenant, route, body string) types.RouteConfig {
+		return types.RouteConfig{
+			RouteName:    route,
+			Method:       "GET",
+			Tenant:       tenant,
+			MockResponse: &types.MockResponseConfig{Template: body},
+		}
+	}
+	config := &types.RoutesConfig{
+		Tenants: []types.TenantConfig{
+			{Name: "acme", Hosts: []string{"acme.example.com"}},
+			{Name: "globex", Hosts: []string{"*.globex.example.com"}},
+		},
+		Routes: []types.RouteConfig{
+			mock("", "/v1/orders", `{"owner": "shared"}`),
+			mock("", "/v1/catalog", `{"owner": "shared"}`),
+			mock("acme", "/v1/orders", `{"owner": "acme"}`),
+			mock("globex", "/v1/orders", `{"owner": "globex"}`),
+		},
+	}
+
+	for _, lazy := range []bool{false, true} {
+		rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+		rm.SetLazy(lazy)
+		engine := gin.New()
+		engine.NoRoute(rm.Dispatch)
+		if err := rm.ApplyConfig(config); err != nil {
+			t.Fatalf("Failed to apply config: %v", err)
+		}
+
+		tests := []struct {
+			host, header, path, expected string
+		}{
+			{"acme.example.com", "", "/v1/orders", `{"owner":"acme"}`},
+			{"eu.globex.example.com:8080", "", "/v1/orders", `{"owner":"globex"}`},
+			{"api.example.com", "acme", "/v1/orders", `{"owner":"acme"}`},
+			// The host decides over the header
+			{"acme.example.com", "globex", "/v1/orders", `{"owner":"acme"}`},
+			{"api.example.com", "unknown", "/v1/orders", `{"owner":"shared"}`},
+			{"api.example.com", "", "/v1/orders", `{"owner":"shared"}`},
+			// Tenants fall back to shared routes
+			{"acme.example.com", "", "/v1/catalog", `{"owner":"shared"}`},
+		}
+		for _, test := range tests {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req.Host = test.host
+			if test.header != "" {
+				req.Header.Set("X-Tenant-ID", test.header)
+			}
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, req)
+			if recorder.Code != http.StatusOK || recorder.Body.String() != test.expected {
+				t.Errorf("lazy=%v %s%s (tenant header %q): expected %s, got %d %s", lazy, test.host, test.path, test.header, test.expected, recorder.Code, recorder.Body.String())
+			}
+		}
+		rm.Stop()
+	}
+}
+
+func TestTenantConfigValidation(t *testing.T) {
+	tenants := []types.TenantConfig{{Name: "acme"}, {Name: "globex"}}
+	tests := []struct {
+		name  string
+		route types.RouteConfig
+		valid bool
+	}{
+		{"own policy", types.RouteConfig{RouteName: "/a", Method: "GET", Tenant: "acme", Policies: []string{"acme_orders", "status_policy"}}, true},
+		{"undeclared tenant", types.RouteConfig{RouteName: "/a", Method: "GET", Tenant: "initech"}, false},
+		{"foreign policy", types.RouteConfig{RouteName: "/a", Method: "GET", Tenant: "acme", Policies: []string{"globex_orders"}}, false},
+		{"tenant policy on shared route", types.RouteConfig{RouteName: "/a", Method: "GET", Policies: []string{"acme_orders"}}, false},
+		{"foreign schema", types.RouteConfig{RouteName: "/a", Method: "POST", Tenant: "acme", RequestSchemaRef: "globex_order@v1"}, false},
+	}
+	for _, test := range tests {
+		rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+		err := rm.ApplyConfig(&types.RoutesConfig{Tenants: tenants, Routes: []types.RouteConfig{test.route}})
+		if (err == nil) != test.valid {
+			t.Errorf("%s: expected valid=%v, got %v", test.name, test.valid, err)
+		}
+		rm.Stop()
+	}
+}
@@ -0,0 +1,126 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/transform"
+	"dynamiccontrol/internal/types"
+)
+
+// throttleKeyBody partitions a throttle by the value of a body field
+const throttleKeyBody = "body:"
+
+// validateThrottles checks the throttles of a route at registration time
+func validateThrottles(route types.RouteConfig) error {
+	names := make(map[string]bool, len(route.Throttles))
+	for _, throttle := range route.Throttles {
+		if throttle.Name == "" {
+			return fmt.Errorf("throttle name is required")
+		}
+		if names[throttle.Name] {
+			return fmt.Errorf("duplicate throttle %q", throttle.Name)
+		}
+		names[throttle.Name] = true
+		if len(throttle.Match) == 0 {
+			return fmt.Errorf("throttle %q must match at least one body field", throttle.Name)
+		}
+		if throttle.Requests <= 0 {
+			return fmt.Errorf("throttle %q requests must be positive", throttle.Name)
+		}
+		if throttle.WindowSeconds <= 0 {
+			return fmt.Errorf("throttle %q windowSeconds must be positive", throttle.Name)
+		}
+		bodyKey := strings.HasPrefix(throttle.Key, throttleKeyBody) && len(throttle.Key) > len(throttleKeyBody)
+		if !bodyKey && !validRateLimitKey(route, throttle.Key) {
+			return fmt.Errorf("unsupported key %q of throttle %q, expected route, ip, header:<name>, param:<name> or body:<path>", throttle.Key, throttle.Name)
+		}
+	}
+	return nil
+}
+
+// throttleStage counts validated requests against every throttle whose match
+// they satisfy and rejects them with 429 Too Many Requests once one is
+// exhausted. Requests are let through when the limiter fails.
+func (rm *RouteManager) throttleStage(ex *Exchange) error {
+	for _, throttle := range ex.Route.Throttles {
+		if !matchesThrottle(ex.Body, throttle.Match) {
+			continue
+		}
+
+		partition := throttlePartition(ex, throttle.Key)
+		key := "throttle:" + routeKey(ex.Route) + ":" + throttle.Name + ":" + partition
+		window := time.Duration(throttle.WindowSeconds) * time.Second
+		result, err := rm.limiter.Allow(ex.Request.Context(), key, throttle.Requests, window)
+		if err != nil {
+			logging.FromContext(ex.Request.Context()).Warn("Rate limiter unavailable, allowing request",
+				"route", routeKey(ex.Route), "throttle", throttle.Name, "error", err)
+			continue
+		}
+		if result.Allowed {
+			continue
+		}
+
+		metrics.RateLimitedRequests.WithLabelValues(ex.Route.RouteName, "throttle").Inc()
+		message := fmt.Sprintf("throttle %s allows %d requests with %s per %s", throttle.Name, throttle.Requests, describeMatch(throttle.Match), window)
+		if throttle.Key != "" && throttle.Key != rateLimitKeyRoute {
+			message += fmt.Sprintf(" for each %s, exceeded for %q", throttle.Key, partition)
+		}
+		writeRateLimited(ex.Writer, ex.RequestID, result, "throttle", message)
+		ex.Written = true
+		return stageError(http.StatusTooManyRequests, "Too many requests", nil)
+	}
+	return nil
+}
+
+// matchesThrottle reports whether every matched body field holds its value.
+// Values are compared by their JSON encoding, so numbers of different Go
+// types compare equal.
+func matchesThrottle(body interface{}, match map[string]interface{}) bool {
+	for path, expected := range match {
+		value, err := transform.Lookup(body, path)
+		if err != nil {
+			return false
+		}
+		encodedValue, errValue := json.Marshal(value)
+		encodedExpected, errExpected := json.Marshal(expected)
+		if errValue != nil || errExpected != nil || string(encodedValue) != string(encodedExpected) {
+			return false
+		}
+	}
+	return true
+}
+
+// throttlePartition returns the part of the limiter key that separates
+// clients sharing a throttle
+func throttlePartition(ex *Exchange, key string) string {
+	if !strings.HasPrefix(key, throttleKeyBody) {
+		return rateLimitPartition(ex.RequestContext, ex.Request, key)
+	}
+	value, err := transform.Lookup(ex.Body, strings.TrimPrefix(key, throttleKeyBody))
+	if err != nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// describeMatch renders a throttle match as sorted path=value pairs
+func describeMatch(match map[string]interface{}) string {
+	pairs := make([]string, 0, len(match))
+	for path, value := range match {
+		encoded, _ := json.Marshal(value)
+		pairs = append(pairs, path+"="+string(encoded))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,97 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestThrottleByPayload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName:     "/v1/services/:serviceId/traffic",
+		Method:        "POST",
+		RequestSchema: map[string]interface{}{"type": "object", "required": []interface{}{"priority"}},
+		Throttles: []types.ThrottleConfig{{
+			Name:          "critical",
+			Match:         map[string]interface{}{"priority": "critical"},
+			Requests:      2,
+			WindowSeconds: 60,
+			Key:           "param:serviceId",
+		}},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	post := func(service, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/services/"+service+"/traffic", strings.NewReader(body)))
+		return recorder
+	}
+
+	for i := 0; i < 2; i++ {
+		if recorder := post("billing", `{"priority": "critical"}`); recorder.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d: %s", i, recorder.Code, recorder.Body.String())
+		}
+	}
+	rejected := post("billing", `{"priority": "critical"}`)
+	if rejected.Code != http.StatusTooManyRequests || rejected.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected 429 with Retry-After, got %d: %s", rejected.Code, rejected.Body.String())
+	}
+	var body struct {
+		Details map[string]interface{} `json:"details"`
+	}
+	if err := json.Unmarshal(rejected.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	message, _ := body.Details["message"].(string)
+	if body.Details["reason"] != "throttle" || !strings.Contains(message, `priority="critical"`) || !strings.Contains(message, `"billing"`) {
+		t.Errorf("Unexpected 429 body %s", rejected.Body.String())
+	}
+
+	// Other payloads and other services are not throttled
+	if recorder := post("billing", `{"priority": "low"}`); recorder.Code != http.StatusOK {
+		t.Errorf("Expected a low priority request to be allowed, got %d", recorder.Code)
+	}
+	if recorder := post("search", `{"priority": "critical"}`); recorder.Code != http.StatusOK {
+		t.Errorf("Expected another service to be allowed, got %d", recorder.Code)
+	}
+	// Invalid requests are rejected before they count
+	if recorder := post("search", `{}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid request to be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestValidateThrottles(t *testing.T) {
+	route := types.RouteConfig{RouteName: "/v1/services/:serviceId", Method: "POST"}
+	tests := []struct {
+		throttle types.ThrottleConfig
+		valid    bool
+	}{
+		{types.ThrottleConfig{Name: "a", Match: map[string]interface{}{"p": 1}, Requests: 1, WindowSeconds: 1, Key: "body:tenant.id"}, true},
+		{types.ThrottleConfig{Name: "a", Match: map[string]interface{}{"p": 1}, Requests: 1, WindowSeconds: 1, Key: "param:serviceId"}, true},
+		{types.ThrottleConfig{Match: map[string]interface{}{"p": 1}, Requests: 1, WindowSeconds: 1}, false},
+		{types.ThrottleConfig{Name: "a", Requests: 1, WindowSeconds: 1}, false},
+		{types.ThrottleConfig{Name: "a", Match: map[string]interface{}{"p": 1}, WindowSeconds: 1}, false},
+		{types.ThrottleConfig{Name: "a", Match: map[string]interface{}{"p": 1}, Requests: 1, WindowSeconds: 1, Key: "param:missing"}, false},
+	}
+	for _, test := range tests {
+		route.Throttles = []types.ThrottleConfig{test.throttle}
+		if err := validateThrottles(route); (err == nil) != test.valid {
+			t.Errorf("Expected valid=%v for %+v, got %v", test.valid, test.throttle, err)
+		}
+	}
+}
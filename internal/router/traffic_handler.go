@@ -0,0 +1,171 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dynamiccontrol/internal/events"
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/quota"
+	"dynamiccontrol/internal/traffic"
+	"dynamiccontrol/internal/types"
+)
+
+// trafficRuleParam is the path parameter naming a single traffic rule
+const trafficRuleParam = "ruleId"
+
+// trafficEvents maps traffic rule changes to the event types they publish
+var trafficEvents = map[string]string{
+	traffic.ChangeCreated:     types.EventTrafficRuleCreated,
+	traffic.ChangeDeleted:     types.EventTrafficRuleDeleted,
+	traffic.ChangeActivated:   types.EventTrafficRuleActivated,
+	traffic.ChangeDeactivated: types.EventTrafficRuleDeactivated,
+}
+
+// GetQuotas returns the quotas of the traffic requests of services
+func (rm *RouteManager) GetQuotas() *quota.Manager {
+	return rm.quotas
+}
+
+// GetTrafficController returns the controller storing the traffic rules of
+// services
+func (rm *RouteManager) GetTrafficController() *traffic.Controller {
+	return rm.traffic
+}
+
+// publishTrafficChange publishes a traffic rule being created, deleted, or
+// taking or leaving effect with its schedule
+func (rm *RouteManager) publishTrafficChange(change string, rule traffic.Rule) {
+	rm.broker.Publish(events.NewEvent(trafficEvents[change], "", "", map[string]interface{}{
+		"service":     rule.ServiceID,
+		"rule":        rule.ID,
+		"trafficType": rule.TrafficType,
+		"volume":      rule.Volume,
+		"priority":    rule.Priority,
+		"status":      rule.Status,
+	}))
+}
+
+// validateTrafficRoute checks that a traffic route names its service, and
+// names a rule only for reading and deleting single rules
+func validateTrafficRoute(route types.RouteConfig) error {
+	if !hasPathParam(route.RouteName, trafficServiceParam) {
+		return fmt.Errorf("traffic handler requires a :%s path parameter", trafficServiceParam)
+	}
+	switch route.Method {
+	case http.MethodPost:
+		if hasPathParam(route.RouteName, trafficRuleParam) {
+			return fmt.Errorf("traffic handler creates rules without a :%s path parameter", trafficRuleParam)
+		}
+	case http.MethodGet, http.MethodDelete:
+	default:
+		return fmt.Errorf("traffic handler does not support method %s", route.Method)
+	}
+	return nil
+}
+
+// consumeQuota counts a traffic request against the quota of its service and
+// describes the quota in the X-Quota headers. Requests exceeding the quota
+// are rejected with 429 Too Many Requests.
+func (rm *RouteManager) consumeQuota(ex *Exchange, service string, volume float64) error {
+	result, limited := rm.quotas.Consume(service, volume)
+	if !limited {
+		return nil
+	}
+	quota.SetHeaders(ex.Writer.Header(), result)
+	if result.Allowed {
+		return nil
+	}
+
+	message := fmt.Sprintf("service %s exceeded its quota of %d requests per %s", service, result.Requests, result.Window())
+	if result.Reason == quota.ReasonVolume {
+		message = fmt.Sprintf("service %s exceeded its quota of %g volume per %s", service, result.Volume, result.Window())
+	}
+	metrics.RateLimitedRequests.WithLabelValues(ex.Route.RouteName, result.Reason).Inc()
+	ex.Writer.Header().Set("Retry-After", strconv.Itoa(result.ResetSeconds))
+	details := map[string]interface{}{
+		"reason":       result.Reason,
+		"message":      message,
+		"usedRequests": result.UsedRequests,
+		"usedVolume":   result.UsedVolume,
+		"resetSeconds": result.ResetSeconds,
+	}
+	if remaining := result.RemainingRequests(); remaining >= 0 {
+		details["requestLimit"] = result.Requests
+		details["remainingRequests"] = remaining
+	}
+	if remaining := result.RemainingVolume(); remaining >= 0 {
+		details["volumeLimit"] = result.Volume
+		details["remainingVolume"] = remaining
+	}
+	response := map[string]interface{}{
+		"error":   "Quota exceeded",
+		"details": details,
+	}
+	if ex.RequestID != "" {
+		response["requestId"] = ex.RequestID
+	}
+	writeJSON(ex.Writer, http.StatusTooManyRequests, response)
+	ex.Written = true
+	return stageError(http.StatusTooManyRequests, "Quota exceeded", nil)
+}
+
+// executeTraffic creates, lists, reads and deletes the traffic rules of the
+// service named by the path. POST stores the request body as a rule; GET
+// and DELETE act on the rule named by :ruleId, or on every rule of the
+// service without one.
+func (rm *RouteManager) executeTraffic(ex *Exchange) error {
+	service := ex.Params[trafficServiceParam]
+	id := ex.Params[trafficRuleParam]
+
+	switch ex.Route.Method {
+	case http.MethodPost:
+		var request types.TrafficRequest
+		encoded, _ := json.Marshal(ex.Body)
+		if err := json.Unmarshal(encoded, &request); err != nil {
+			return stageError(http.StatusBadRequest, "Invalid traffic request", err.Error())
+		}
+		if err := rm.consumeQuota(ex, service, request.Volume); err != nil {
+			return err
+		}
+		rule, err := rm.traffic.Add(service, request)
+		if err != nil {
+			rm.quotas.Refund(service, request.Volume)
+			return stageError(http.StatusBadRequest, "Invalid traffic request", err.Error())
+		}
+		message := "Traffic rule accepted"
+		if rule.Status == traffic.StatusPending {
+			message = fmt.Sprintf("Traffic rule pending until its schedule window opens at %s", rule.OpensAt.Format(time.RFC3339))
+		}
+		ex.StatusCode = http.StatusCreated
+		ex.Response = types.TrafficResponse{
+			ID:        rule.ID,
+			ServiceID: rule.ServiceID,
+			Status:    rule.Status,
+			Message:   message,
+			Timestamp: rule.CreatedAt,
+		}
+	case http.MethodGet:
+		if id == "" {
+			ex.StatusCode = http.StatusOK
+			ex.Response = rm.traffic.Rules(service)
+			return nil
+		}
+		rule, err := rm.traffic.Rule(service, id)
+		if err != nil {
+			return stageError(http.StatusNotFound, "Traffic rule not found", nil)
+		}
+		ex.StatusCode = http.StatusOK
+		ex.Response = rule
+	case http.MethodDelete:
+		if _, err := rm.traffic.Delete(service, id); errors.Is(err, traffic.ErrNotFound) {
+			return stageError(http.StatusNotFound, "Traffic rule not found", nil)
+		}
+		ex.StatusCode = http.StatusNoContent
+	}
+	return nil
+}
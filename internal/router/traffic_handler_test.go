@@ -0,0 +1,176 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/quota"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTrafficRoutesManageRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	var routes []types.RouteConfig
+	for _, method := range []string{"POST", "GET", "DELETE"} {
+		routes = append(routes, types.RouteConfig{RouteName: "/v1/services/:serviceId/traffic", Method: method, Handler: types.HandlerTraffic})
+	}
+	for _, method := range []string{"GET", "DELETE"} {
+		routes = append(routes, types.RouteConfig{RouteName: "/v1/services/:serviceId/traffic/:ruleId", Method: method, Handler: types.HandlerTraffic})
+	}
+	if err := rm.ApplyConfig(&types.RoutesConfig{Routes: routes}); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	subscription := rm.GetEventBroker().Subscribe([]string{"traffic"})
+	defer subscription.Close()
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return recorder
+	}
+
+	recorder := serve(http.MethodPost, "/v1/services/billing/traffic", `{"trafficType": "incoming", "volume": 12.5, "priority": "high"}`)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected the rule to be created, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var created types.TrafficResponse
+	json.Unmarshal(recorder.Body.Bytes(), &created)
+	if created.ID == "" || created.ServiceID != "billing" || created.Status != "accepted" {
+		t.Errorf("Unexpected response %+v", created)
+	}
+	if event := <-subscription.Events(); event.Type != types.EventTrafficRuleCreated || event.Data["rule"] != created.ID {
+		t.Errorf("Expected a rule created event, got %+v", event)
+	}
+	if recorder := serve(http.MethodPost, "/v1/services/billing/traffic", `{"trafficType": "incoming", "volume": 1, "priority": "urgent"}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid priority to be rejected, got %d", recorder.Code)
+	}
+	serve(http.MethodPost, "/v1/services/billing/traffic", `{"trafficType": "outgoing", "volume": 2, "priority": "low"}`)
+	<-subscription.Events()
+
+	if intents := rm.TrafficIntents(); intents["billing"] != 14.5 {
+		t.Errorf("Expected the rules to be traffic intents, got %v", intents)
+	}
+	recorder = serve(http.MethodGet, "/v1/services/billing/traffic", "")
+	var rules []map[string]interface{}
+	json.Unmarshal(recorder.Body.Bytes(), &rules)
+	if recorder.Code != http.StatusOK || len(rules) != 2 || rules[0]["id"] != created.ID {
+		t.Errorf("Expected both rules highest priority first, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := serve(http.MethodGet, "/v1/services/billing/traffic/"+created.ID, ""); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"volume":12.5`) {
+		t.Errorf("Expected the rule, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := serve(http.MethodGet, "/v1/services/search/traffic/"+created.ID, ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected rules of other services not to be found, got %d", recorder.Code)
+	}
+
+	if recorder := serve(http.MethodDelete, "/v1/services/billing/traffic/"+created.ID, ""); recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected the rule to be deleted, got %d", recorder.Code)
+	}
+	if event := <-subscription.Events(); event.Type != types.EventTrafficRuleDeleted || event.Data["rule"] != created.ID {
+		t.Errorf("Expected a rule deleted event, got %+v", event)
+	}
+	if recorder := serve(http.MethodDelete, "/v1/services/billing/traffic/"+created.ID, ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected deleting twice to return 404, got %d", recorder.Code)
+	}
+	if recorder := serve(http.MethodDelete, "/v1/services/billing/traffic", ""); recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected the rules to be cleared, got %d", recorder.Code)
+	}
+	if rules := rm.GetTrafficController().Rules("billing"); len(rules) != 0 {
+		t.Errorf("Expected no rules left, got %+v", rules)
+	}
+}
+
+func TestTrafficRoutesRequireService(t *testing.T) {
+	for _, route := range []types.RouteConfig{
+		{RouteName: "/v1/traffic", Method: "GET", Handler: types.HandlerTraffic},
+		{RouteName: "/v1/services/:serviceId/traffic/:ruleId", Method: "POST", Handler: types.HandlerTraffic},
+		{RouteName: "/v1/services/:serviceId/traffic", Method: "PUT", Handler: types.HandlerTraffic},
+	} {
+		if err := validateTrafficRoute(route); err == nil {
+			t.Errorf("Expected %s %s to be rejected", route.Method, route.RouteName)
+		}
+	}
+}
+
+func TestScheduledTrafficRulesArePending(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/services/:serviceId/traffic", Method: "POST", Handler: types.HandlerTraffic},
+	}})
+
+	start := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/services/billing/traffic",
+		strings.NewReader(`{"trafficType": "incoming", "volume": 5, "priority": "low", "schedule": {"start": "`+start+`"}}`)))
+	var response types.TrafficResponse
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	if recorder.Code != http.StatusCreated || response.Status != "pending" || !strings.Contains(response.Message, start) {
+		t.Errorf("Expected the rule to be pending until %s, got %d: %s", start, recorder.Code, recorder.Body.String())
+	}
+	if intents := rm.TrafficIntents(); len(intents) != 0 {
+		t.Errorf("Expected pending rules not to be traffic intents, got %v", intents)
+	}
+
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/services/billing/traffic",
+		strings.NewReader(`{"trafficType": "incoming", "volume": 5, "priority": "low", "schedule": {"cron": "0 9 * * *"}}`)))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected a cron schedule without a duration to be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestTrafficRequestsBeyondQuotaAreRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/services/:serviceId/traffic", Method: "POST", Handler: types.HandlerTraffic},
+	}})
+	rm.GetQuotas().Set(quota.Quota{Service: "billing", Requests: 1, WindowSeconds: 60})
+
+	post := func(service, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/services/"+service+"/traffic", strings.NewReader(body)))
+		return recorder
+	}
+	if recorder := post("billing", `{"trafficType": "incoming", "volume": 5, "priority": "low", "schedule": {"cron": "bad"}}`); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the invalid request to be rejected, got %d", recorder.Code)
+	}
+	recorder := post("billing", `{"trafficType": "incoming", "volume": 5, "priority": "low"}`)
+	if recorder.Code != http.StatusCreated || recorder.Header().Get("X-Quota-Remaining") != "0" {
+		t.Fatalf("Expected the request within quota to be accepted, got %d with headers %v", recorder.Code, recorder.Header())
+	}
+	recorder = post("billing", `{"trafficType": "incoming", "volume": 5, "priority": "low"}`)
+	var response struct {
+		Error   string                 `json:"error"`
+		Details map[string]interface{} `json:"details"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	if recorder.Code != http.StatusTooManyRequests || response.Details["reason"] != quota.ReasonRequests || recorder.Header().Get("X-Quota-Remaining") != "0" || recorder.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected a structured 429 response, got %d with headers %v: %s", recorder.Code, recorder.Header(), recorder.Body.String())
+	}
+	if rules := rm.GetTrafficController().Rules("billing"); len(rules) != 1 {
+		t.Errorf("Expected the rejected request not to be stored, got %d rules", len(rules))
+	}
+	if recorder := post("search", `{"trafficType": "incoming", "volume": 5, "priority": "low"}`); recorder.Code != http.StatusCreated || recorder.Header().Get("X-Quota-Remaining") != "" {
+		t.Errorf("Expected services without a quota to be unaffected, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,155 @@
+package router
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Schemes of route variable secret references
+const (
+	secretRefEnv  = "env:"
+	secretRefFile = "file:"
+)
+
+// variableName matches names usable as .Vars.name in templates
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// RouteVariables lists the variables of a route; secret values are redacted
+type RouteVariables struct {
+	Route     string                         `json:"route"`
+	Variables map[string]types.RouteVariable `json:"variables"`
+}
+
+// variableStore holds the admin-managed variables of routes by route key.
+// Variables outlive configuration changes, so a route keeps its variables
+// while its templates are edited.
+type variableStore struct {
+	mu     sync.RWMutex
+	routes map[string]map[string]types.RouteVariable
+}
+
+// newVariableStore creates an empty variable store
+func newVariableStore() *variableStore {
+	return &variableStore{routes: make(map[string]map[string]types.RouteVariable)}
+}
+
+// validateVariable checks a route variable before it is stored
+func validateVariable(name string, variable types.RouteVariable) error {
+	if !variableName.MatchString(name) {
+		return fmt.Errorf("variable name %q must start with a letter or underscore and contain only letters, digits and underscores", name)
+	}
+	if variable.SecretRef == "" {
+		return nil
+	}
+	if variable.Value != "" {
+		return fmt.Errorf("variable %s cannot have both a value and a secretRef", name)
+	}
+	scheme, target, _ := strings.Cut(variable.SecretRef, ":")
+	if (scheme+":" != secretRefEnv && scheme+":" != secretRefFile) || target == "" {
+		return fmt.Errorf("variable %s: secretRef must be env:NAME or file:PATH", name)
+	}
+	return nil
+}
+
+// SetRouteVariables replaces the variables of the route with the given key.
+// An empty set removes them.
+func (rm *RouteManager) SetRouteVariables(key string, variables map[string]types.RouteVariable) error {
+	stored := make(map[string]types.RouteVariable, len(variables))
+	for name, variable := range variables {
+		if err := validateVariable(name, variable); err != nil {
+			return err
+		}
+		if variable.SecretRef != "" {
+			variable.Secret = true
+		}
+		stored[name] = variable
+	}
+
+	rm.variables.mu.Lock()
+	defer rm.variables.mu.Unlock()
+	if len(stored) == 0 {
+		delete(rm.variables.routes, key)
+		return nil
+	}
+	rm.variables.routes[key] = stored
+	return nil
+}
+
+// ListRouteVariables lists the variables of every route, sorted by route
+// key, with the values of secrets redacted
+func (rm *RouteManager) ListRouteVariables() []RouteVariables {
+	rm.variables.mu.RLock()
+	defer rm.variables.mu.RUnlock()
+
+	list := make([]RouteVariables, 0, len(rm.variables.routes))
+	for key, variables := range rm.variables.routes {
+		list = append(list, RouteVariables{Route: key, Variables: RedactVariables(variables)})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Route < list[j].Route })
+	return list
+}
+
+// RedactVariables returns a copy of variables without the values of secrets
+func RedactVariables(variables map[string]types.RouteVariable) map[string]types.RouteVariable {
+	redacted := make(map[string]types.RouteVariable, len(variables))
+	for name, variable := range variables {
+		if variable.SecretRef != "" {
+			variable.Secret = true
+		}
+		if variable.Secret {
+			variable.Value = ""
+		}
+		redacted[name] = variable
+	}
+	return redacted
+}
+
+// templateVars resolves the variables of a route for rendering its
+// templates, reading secret references now so rotated secrets apply to the
+// next request
+func (rm *RouteManager) templateVars(route types.RouteConfig) (map[string]string, error) {
+	rm.variables.mu.RLock()
+	variables := rm.variables.routes[routeKey(route)]
+	rm.variables.mu.RUnlock()
+	if len(variables) == 0 {
+		return nil, nil
+	}
+
+	resolved := make(map[string]string, len(variables))
+	for name, variable := range variables {
+		value, err := resolveVariable(variable)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", name, err)
+		}
+		resolved[name] = value
+	}
+	return resolved, nil
+}
+
+// resolveVariable returns the value of a variable, reading its secret
+// reference if it has one
+func resolveVariable(variable types.RouteVariable) (string, error) {
+	switch {
+	case strings.HasPrefix(variable.SecretRef, secretRefEnv):
+		name := strings.TrimPrefix(variable.SecretRef, secretRefEnv)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(variable.SecretRef, secretRefFile):
+		content, err := os.ReadFile(strings.TrimPrefix(variable.SecretRef, secretRefFile))
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	default:
+		return variable.Value, nil
+	}
+}
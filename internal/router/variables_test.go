@@ -0,0 +1,106 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRouteVariables(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TEST_API_KEY", "key-123")
+	secretFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secretFile, []byte("token-456\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+	route := types.RouteConfig{
+		RouteName: "/v1/config",
+		Method:    "GET",
+		MockResponse: &types.MockResponseConfig{
+			StatusCode: http.StatusOK,
+			Template:   `{"region": "{{.Vars.region}}", "key": "{{.Vars.apiKey}}", "token": "{{.Vars.token}}"}`,
+		},
+	}
+	if err := rm.ApplyConfig(&types.RoutesConfig{Routes: []types.RouteConfig{route}}); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	get := func() (int, string) {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/config", nil))
+		return recorder.Code, recorder.Body.String()
+	}
+
+	err := rm.SetRouteVariables(RouteKey(route), map[string]types.RouteVariable{
+		"region": {Value: "eu-west-1"},
+		"apiKey": {SecretRef: "env:TEST_API_KEY"},
+		"token":  {SecretRef: "file:" + secretFile},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set variables: %v", err)
+	}
+	if code, body := get(); code != http.StatusOK || body != `{"key":"key-123","region":"eu-west-1","token":"token-456"}` {
+		t.Errorf("Unexpected response %d %s", code, body)
+	}
+
+	// Secrets are read at render time
+	t.Setenv("TEST_API_KEY", "key-789")
+	if _, body := get(); body != `{"key":"key-789","region":"eu-west-1","token":"token-456"}` {
+		t.Errorf("Expected the rotated secret, got %s", body)
+	}
+
+	listed := rm.ListRouteVariables()
+	if len(listed) != 1 || listed[0].Route != RouteKey(route) {
+		t.Fatalf("Unexpected variables %+v", listed)
+	}
+	if apiKey := listed[0].Variables["apiKey"]; !apiKey.Secret || apiKey.Value != "" || apiKey.SecretRef != "env:TEST_API_KEY" {
+		t.Errorf("Expected the secret reference to be listed as secret, got %+v", apiKey)
+	}
+
+	os.Unsetenv("TEST_API_KEY")
+	if code, _ := get(); code != http.StatusInternalServerError {
+		t.Errorf("Expected unresolvable secrets to fail rendering, got %d", code)
+	}
+
+	if err := rm.SetRouteVariables(RouteKey(route), nil); err != nil {
+		t.Fatalf("Failed to remove variables: %v", err)
+	}
+	if _, body := get(); body != `{"key":"","region":"","token":""}` {
+		t.Errorf("Expected removed variables to render empty, got %s", body)
+	}
+}
+
+func TestValidateVariable(t *testing.T) {
+	tests := []struct {
+		name     string
+		variable types.RouteVariable
+		valid    bool
+	}{
+		{"region", types.RouteVariable{Value: "eu-west-1"}, true},
+		{"api_key", types.RouteVariable{SecretRef: "env:API_KEY"}, true},
+		{"token", types.RouteVariable{SecretRef: "file:/run/secrets/token"}, true},
+		{"password", types.RouteVariable{Value: "hunter2", Secret: true}, true},
+		{"api-key", types.RouteVariable{Value: "x"}, false},
+		{"token", types.RouteVariable{Value: "x", SecretRef: "env:TOKEN"}, false},
+		{"token", types.RouteVariable{SecretRef: "vault:token"}, false},
+		{"token", types.RouteVariable{SecretRef: "env:"}, false},
+	}
+	for _, test := range tests {
+		err := validateVariable(test.name, test.variable)
+		if (err == nil) != test.valid {
+			t.Errorf("validateVariable(%s, %+v): expected valid=%v, got error %v", test.name, test.variable, test.valid, err)
+		}
+	}
+}
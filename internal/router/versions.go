@@ -0,0 +1,91 @@
+package router
+
+import (
+	"fmt"
+	"log/slog"
+
+	"dynamiccontrol/internal/audit"
+	"dynamiccontrol/internal/events"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/versions"
+)
+
+// SetHistory replaces the history of applied configuration versions
+func (rm *RouteManager) SetHistory(history *versions.History) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.history = history
+}
+
+// GetHistory returns the history of applied configuration versions
+func (rm *RouteManager) GetHistory() *versions.History {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.history
+}
+
+// RecordVersion records the active routes and policies as a configuration
+// version. Applied route configurations are recorded automatically; policy
+// changes made without applying routes must be recorded by the caller.
+func (rm *RouteManager) RecordVersion() {
+	history := rm.GetHistory()
+	config := rm.GetConfig()
+	if history == nil || config == nil {
+		return
+	}
+	if _, err := history.Record(config, rm.policyManager.PolicySources()); err != nil {
+		slog.Error("Failed to record configuration version", "error", err)
+	}
+}
+
+// Rollback restores the routes and policies of a recorded version. Any
+// running canary is aborted, and the restored configuration is recorded as
+// a new version.
+func (rm *RouteManager) Rollback(id int) (versions.Version, error) {
+	history := rm.GetHistory()
+	if history == nil {
+		return versions.Version{}, fmt.Errorf("configuration history is disabled")
+	}
+	version, ok := history.Get(id)
+	if !ok {
+		return versions.Version{}, fmt.Errorf("configuration version %d not found", id)
+	}
+
+	rm.reloadMu.Lock()
+	defer rm.reloadMu.Unlock()
+
+	if rm.CanaryStatus().StartedAt != nil {
+		rm.AbortCanary(fmt.Sprintf("rolled back to version %d", id))
+	}
+	previousPolicies := rm.policyManager.PolicySources()
+	if err := rm.policyManager.ReplacePolicies(version.Policies); err != nil {
+		return versions.Version{}, err
+	}
+	if err := rm.ApplyConfig(version.Routes); err != nil {
+		rm.policyManager.ReplacePolicies(previousPolicies)
+		return versions.Version{}, err
+	}
+	rm.broker.Publish(events.NewEvent(types.EventPolicyReloaded, "", "", map[string]interface{}{
+		"store":    "rollback",
+		"policies": rm.policyManager.ListLoadedPolicies(),
+	}))
+
+	slog.Info("Rolled back configuration", "version", id, "revision", version.Revision)
+	return version, nil
+}
+
+// DiffConfig reviews a candidate configuration against the active one
+// without applying it. Schema references of the candidate are resolved as
+// they would be when applied. Without candidate policies the loaded
+// policies are kept.
+func (rm *RouteManager) DiffConfig(candidate *types.RoutesConfig, policies map[string]string) (types.ConfigDiff, error) {
+	resolved, err := rm.resolveSchemaRefs(candidate)
+	if err != nil {
+		return types.ConfigDiff{}, err
+	}
+	current := rm.policyManager.PolicySources()
+	if policies == nil {
+		policies = current
+	}
+	return audit.CompareConfigs(rm.GetConfig(), resolved, current, policies), nil
+}
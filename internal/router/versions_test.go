@@ -0,0 +1,79 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRollback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policyManager := opa.NewPolicyManager()
+	rm := NewRouteManager(policyManager, validator.NewSchemaValidator())
+	defer rm.Stop()
+	engine := gin.New()
+	engine.NoRoute(rm.Dispatch)
+
+	route := func(body string) *types.RoutesConfig {
+		return &types.RoutesConfig{Routes: []types.RouteConfig{{
+			RouteName:    "/v1/status",
+			Method:       "GET",
+			Policies:     []string{"status"},
+			MockResponse: &types.MockResponseConfig{StatusCode: http.StatusOK, Template: body},
+		}}}
+	}
+	get := func() int {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/status", nil))
+		return recorder.Code
+	}
+
+	if err := policyManager.SetPolicy("status", "package status\n\nallow := true\n"); err != nil {
+		t.Fatalf("Failed to set policy: %v", err)
+	}
+	if err := rm.ApplyConfig(route(`{"version": 1}`)); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	// A policy change alone is a version as well
+	if err := policyManager.SetPolicy("status", "package status\n\nallow := false\n"); err != nil {
+		t.Fatalf("Failed to set policy: %v", err)
+	}
+	rm.RecordVersion()
+	if err := rm.ApplyConfig(route(`{"version": 2}`)); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	if code := get(); code != http.StatusForbidden {
+		t.Fatalf("Expected the changed policy to deny, got %d", code)
+	}
+
+	summaries := rm.GetHistory().List()
+	if len(summaries) != 3 || summaries[0].ID != 3 || !summaries[0].Current {
+		t.Fatalf("Expected three versions, got %+v", summaries)
+	}
+
+	version, err := rm.Rollback(1)
+	if err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	if code := get(); code != http.StatusOK {
+		t.Errorf("Expected the restored policy to allow, got %d", code)
+	}
+	if rm.GetConfig().Routes[0].MockResponse.Template != `{"version": 1}` {
+		t.Error("Expected the routes of version 1 to be restored")
+	}
+	// The rollback is recorded as a new version of the same content
+	current := rm.GetHistory().List()[0]
+	if current.ID != 4 || current.Revision != version.Revision {
+		t.Errorf("Expected version 4 with revision %s, got %+v", version.Revision, current)
+	}
+
+	if _, err := rm.Rollback(42); err == nil {
+		t.Error("Expected unknown versions to be rejected")
+	}
+}
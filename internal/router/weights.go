@@ -0,0 +1,70 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+
+	"dynamiccontrol/internal/guardrails"
+	"dynamiccontrol/internal/types"
+)
+
+// SetUpstreamWeights changes the weights of a route's upstreams, keyed by
+// upstream name or URL, and applies the result. The change is checked
+// against the guardrails and lasts until the configuration store changes.
+func (rm *RouteManager) SetUpstreamWeights(method, routeName string, weights map[string]int) (types.RouteConfig, error) {
+	return rm.SetRouteUpstreamWeights(routeKey(types.RouteConfig{Method: strings.ToUpper(method), RouteName: routeName}), weights)
+}
+
+// SetRouteUpstreamWeights changes the upstream weights of the route with
+// the given key, as returned by RouteKey
+func (rm *RouteManager) SetRouteUpstreamWeights(key string, weights map[string]int) (types.RouteConfig, error) {
+	rm.reloadMu.Lock()
+	defer rm.reloadMu.Unlock()
+
+	previous := rm.GetConfig()
+	if previous == nil {
+		return types.RouteConfig{}, fmt.Errorf("no configuration loaded")
+	}
+	config := *previous
+	config.Routes = append([]types.RouteConfig(nil), previous.Routes...)
+
+	index := -1
+	for i, route := range config.Routes {
+		if routeKey(route) == key {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return types.RouteConfig{}, fmt.Errorf("route %s not found", key)
+	}
+
+	route := config.Routes[index]
+	route.Upstreams = append([]types.UpstreamTarget(nil), route.Upstreams...)
+	for key, weight := range weights {
+		if weight <= 0 {
+			return types.RouteConfig{}, fmt.Errorf("weight of upstream %s must be positive", key)
+		}
+		found := false
+		for i, target := range route.Upstreams {
+			if target.Name == key || target.URL == key {
+				route.Upstreams[i].Weight = weight
+				found = true
+			}
+		}
+		if !found {
+			return types.RouteConfig{}, fmt.Errorf("route %s has no upstream %s", routeKey(route), key)
+		}
+	}
+	config.Routes[index] = route
+
+	if rm.guard != nil {
+		if err := rm.guard.Check(guardrails.Change{PreviousRoutes: previous, Routes: &config}); err != nil {
+			return types.RouteConfig{}, err
+		}
+	}
+	if err := rm.ApplyConfig(&config); err != nil {
+		return types.RouteConfig{}, err
+	}
+	return route, nil
+}
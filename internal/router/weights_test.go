@@ -0,0 +1,47 @@
+package router
+
+import (
+	"testing"
+
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+)
+
+func TestSetUpstreamWeights(t *testing.T) {
+	rm := NewRouteManager(opa.NewPolicyManager(), validator.NewSchemaValidator())
+	defer rm.Stop()
+	config := &types.RoutesConfig{Routes: []types.RouteConfig{{
+		RouteName: "/v1/payments",
+		Method:    "POST",
+		Handler:   types.HandlerProxy,
+		Upstreams: []types.UpstreamTarget{
+			{Name: "stable", URL: "http://payments-v1:8080", Weight: 100},
+			{Name: "canary", URL: "http://payments-v2:8080", Weight: 1},
+		},
+	}}}
+	if err := rm.ApplyConfig(config); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	route, err := rm.SetUpstreamWeights("POST", "/v1/payments", map[string]int{"stable": 90, "http://payments-v2:8080": 10})
+	if err != nil {
+		t.Fatalf("Failed to set weights: %v", err)
+	}
+	if route.Upstreams[0].Weight != 90 || route.Upstreams[1].Weight != 10 {
+		t.Errorf("Expected weights 90/10, got %+v", route.Upstreams)
+	}
+	if applied := rm.GetConfig().Routes[0].Upstreams; applied[1].Weight != 10 {
+		t.Errorf("Expected the weights to be applied, got %+v", applied)
+	}
+	if config.Routes[0].Upstreams[1].Weight != 1 {
+		t.Error("Expected the previous configuration not to be modified")
+	}
+
+	if _, err := rm.SetUpstreamWeights("POST", "/v1/payments", map[string]int{"unknown": 10}); err == nil {
+		t.Error("Expected an unknown upstream to be rejected")
+	}
+	if _, err := rm.SetUpstreamWeights("POST", "/v1/payments", map[string]int{"stable": 0}); err == nil {
+		t.Error("Expected a non-positive weight to be rejected")
+	}
+}
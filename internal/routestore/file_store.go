@@ -0,0 +1,115 @@
+package routestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"dynamiccontrol/internal/types"
+)
+
+// fileSnapshot is the content of a file store, with routes keyed by route key
+type fileSnapshot struct {
+	Routes map[string]types.RouteConfig `json:"routes"`
+}
+
+// FileStore keeps runtime routes in a JSON snapshot file that is rewritten
+// on every change
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStore creates a store writing its snapshot to path
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Name identifies the backend in logs
+func (fs *FileStore) Name() string {
+	return "file"
+}
+
+// Load reads the snapshot; a missing file holds no routes
+func (fs *FileStore) Load(ctx context.Context) (map[string]types.RouteConfig, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.read()
+}
+
+// Save creates or replaces a route in the snapshot
+func (fs *FileStore) Save(ctx context.Context, key string, route types.RouteConfig) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	routes, err := fs.read()
+	if err != nil {
+		return err
+	}
+	routes[key] = route
+	return fs.write(routes)
+}
+
+// Delete removes a route from the snapshot
+func (fs *FileStore) Delete(ctx context.Context, key string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	routes, err := fs.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := routes[key]; !ok {
+		return nil
+	}
+	delete(routes, key)
+	return fs.write(routes)
+}
+
+// Close releases nothing; the snapshot is written on every change
+func (fs *FileStore) Close() error {
+	return nil
+}
+
+// read reads the snapshot file; callers must hold mu
+func (fs *FileStore) read() (map[string]types.RouteConfig, error) {
+	data, err := os.ReadFile(fs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]types.RouteConfig), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read route snapshot: %w", err)
+	}
+	var snapshot fileSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse route snapshot: %w", err)
+	}
+	if snapshot.Routes == nil {
+		snapshot.Routes = make(map[string]types.RouteConfig)
+	}
+	return snapshot.Routes, nil
+}
+
+// write replaces the snapshot file, so a crash never leaves it half
+// written; callers must hold mu
+func (fs *FileStore) write(routes map[string]types.RouteConfig) error {
+	data, err := json.MarshalIndent(fileSnapshot{Routes: routes}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode route snapshot: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(fs.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create route snapshot directory: %w", err)
+	}
+	temp := fs.path + ".tmp"
+	if err := os.WriteFile(temp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write route snapshot: %w", err)
+	}
+	if err := os.Rename(temp, fs.path); err != nil {
+		return fmt.Errorf("failed to write route snapshot: %w", err)
+	}
+	return nil
+}
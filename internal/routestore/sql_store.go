@@ -0,0 +1,128 @@
+package routestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"dynamiccontrol/internal/types"
+
+	// Register the database/sql drivers of the supported dialects
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// SQL dialects of the SQL store
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+// createTable creates the runtime routes table; the statement is valid in
+// both dialects
+const createTable = `CREATE TABLE IF NOT EXISTS runtime_routes (
+	route_key  TEXT PRIMARY KEY,
+	config     TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+)`
+
+// SQLStore keeps runtime routes in a SQLite or Postgres table, with each
+// route stored as its JSON configuration
+type SQLStore struct {
+	db      *sql.DB
+	dialect string
+}
+
+// NewSQLStore opens a database with the dialect's driver and creates the
+// runtime routes table if needed
+func NewSQLStore(dialect, dsn string) (*SQLStore, error) {
+	if dialect != DialectSQLite && dialect != DialectPostgres {
+		return nil, fmt.Errorf("unsupported SQL dialect %q", dialect)
+	}
+	db, err := sql.Open(dialect, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s route store: %w", dialect, err)
+	}
+	if dialect == DialectSQLite {
+		// SQLite allows a single writer
+		db.SetMaxOpenConns(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := db.ExecContext(ctx, createTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create %s route table: %w", dialect, err)
+	}
+	return &SQLStore{db: db, dialect: dialect}, nil
+}
+
+// Name identifies the backend in logs
+func (ss *SQLStore) Name() string {
+	return ss.dialect
+}
+
+// Load reads every persisted route
+func (ss *SQLStore) Load(ctx context.Context) (map[string]types.RouteConfig, error) {
+	rows, err := ss.db.QueryContext(ctx, `SELECT route_key, config FROM runtime_routes`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load runtime routes: %w", err)
+	}
+	defer rows.Close()
+
+	routes := make(map[string]types.RouteConfig)
+	for rows.Next() {
+		var key, config string
+		if err := rows.Scan(&key, &config); err != nil {
+			return nil, fmt.Errorf("failed to load runtime routes: %w", err)
+		}
+		var route types.RouteConfig
+		if err := json.Unmarshal([]byte(config), &route); err != nil {
+			return nil, fmt.Errorf("failed to parse runtime route %s: %w", key, err)
+		}
+		routes[key] = route
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load runtime routes: %w", err)
+	}
+	return routes, nil
+}
+
+// Save creates or replaces a route
+func (ss *SQLStore) Save(ctx context.Context, key string, route types.RouteConfig) error {
+	config, err := json.Marshal(route)
+	if err != nil {
+		return fmt.Errorf("failed to encode runtime route: %w", err)
+	}
+	query := fmt.Sprintf(`INSERT INTO runtime_routes (route_key, config, updated_at) VALUES (%s, %s, %s)
+		ON CONFLICT (route_key) DO UPDATE SET config = excluded.config, updated_at = excluded.updated_at`,
+		ss.placeholder(1), ss.placeholder(2), ss.placeholder(3))
+	if _, err := ss.db.ExecContext(ctx, query, key, string(config), time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to save runtime route: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a route
+func (ss *SQLStore) Delete(ctx context.Context, key string) error {
+	query := `DELETE FROM runtime_routes WHERE route_key = ` + ss.placeholder(1)
+	if _, err := ss.db.ExecContext(ctx, query, key); err != nil {
+		return fmt.Errorf("failed to delete runtime route: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database
+func (ss *SQLStore) Close() error {
+	return ss.db.Close()
+}
+
+// placeholder returns the query parameter placeholder at a position
+func (ss *SQLStore) placeholder(position int) string {
+	if ss.dialect == DialectPostgres {
+		return fmt.Sprintf("$%d", position)
+	}
+	return "?"
+}
@@ -0,0 +1,39 @@
+package routestore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Store persists the routes created at runtime through the admin API, so
+// they survive restarts. Routes are keyed by their route key.
+type Store interface {
+	// Name identifies the backend in logs
+	Name() string
+	// Load returns the persisted routes keyed by route key
+	Load(ctx context.Context) (map[string]types.RouteConfig, error)
+	// Save creates or replaces a route
+	Save(ctx context.Context, key string, route types.RouteConfig) error
+	// Delete removes a route; removing a missing route is not an error
+	Delete(ctx context.Context, key string) error
+	// Close releases the resources of the store
+	Close() error
+}
+
+// Open opens the store named by a location: file:PATH for a JSON snapshot,
+// sqlite:PATH for a SQLite database, or a postgres:// URL
+func Open(location string) (Store, error) {
+	switch {
+	case strings.HasPrefix(location, "file:"):
+		return NewFileStore(strings.TrimPrefix(location, "file:")), nil
+	case strings.HasPrefix(location, "sqlite:"):
+		return NewSQLStore(DialectSQLite, strings.TrimPrefix(location, "sqlite:"))
+	case strings.HasPrefix(location, "postgres://"), strings.HasPrefix(location, "postgresql://"):
+		return NewSQLStore(DialectPostgres, location)
+	default:
+		return nil, fmt.Errorf("unsupported route store %q, expected file:PATH, sqlite:PATH or a postgres:// URL", location)
+	}
+}
@@ -0,0 +1,77 @@
+package routestore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+// exerciseStore saves, replaces, reopens and deletes routes in a store
+func exerciseStore(t *testing.T, open func() Store) {
+	ctx := context.Background()
+	store := open()
+
+	routes, err := store.Load(ctx)
+	if err != nil || len(routes) != 0 {
+		t.Fatalf("Expected an empty store, got %v, %v", routes, err)
+	}
+	orders := types.RouteConfig{RouteName: "/v1/orders", Method: "GET"}
+	if err := store.Save(ctx, "GET /v1/orders", orders); err != nil {
+		t.Fatalf("Failed to save route: %v", err)
+	}
+	orders.Policies = []string{"orders_policy"}
+	if err := store.Save(ctx, "GET /v1/orders", orders); err != nil {
+		t.Fatalf("Failed to replace route: %v", err)
+	}
+	if err := store.Save(ctx, "POST /v1/orders", types.RouteConfig{RouteName: "/v1/orders", Method: "POST"}); err != nil {
+		t.Fatalf("Failed to save route: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Failed to close store: %v", err)
+	}
+
+	store = open()
+	defer store.Close()
+	routes, err = store.Load(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+	if len(routes) != 2 || len(routes["GET /v1/orders"].Policies) != 1 {
+		t.Fatalf("Expected the replaced route to survive a reopen, got %+v", routes)
+	}
+
+	if err := store.Delete(ctx, "POST /v1/orders"); err != nil {
+		t.Fatalf("Failed to delete route: %v", err)
+	}
+	if err := store.Delete(ctx, "DELETE /v1/missing"); err != nil {
+		t.Errorf("Expected deleting a missing route to succeed, got %v", err)
+	}
+	routes, _ = store.Load(ctx)
+	if _, ok := routes["POST /v1/orders"]; ok || len(routes) != 1 {
+		t.Errorf("Expected the deleted route to be gone, got %+v", routes)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "routes.json")
+	exerciseStore(t, func() Store { return NewFileStore(path) })
+}
+
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.db")
+	exerciseStore(t, func() Store {
+		store, err := Open("sqlite:" + path)
+		if err != nil {
+			t.Fatalf("Failed to open store: %v", err)
+		}
+		return store
+	})
+}
+
+func TestOpenRejectsUnknownLocations(t *testing.T) {
+	if _, err := Open("redis://localhost:6379"); err == nil {
+		t.Error("Expected an unsupported location to be rejected")
+	}
+}
@@ -0,0 +1,56 @@
+package scaffold
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// template holds the files of a new project, laid out as written to disk
+//
+//go:embed template
+var template embed.FS
+
+// Files lists the paths written by Write, relative to the project directory
+func Files() []string {
+	var files []string
+	fs.WalkDir(template, "template", func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			relative, _ := filepath.Rel("template", path)
+			files = append(files, relative)
+		}
+		return err
+	})
+	return files
+}
+
+// Write scaffolds a runnable project into dir: routes, Rego policies with
+// their tests, server settings and a docker-compose stack with Prometheus and
+// Grafana. Nothing is written when a file already exists, unless force is set.
+func Write(dir string, force bool) ([]string, error) {
+	files := Files()
+	if !force {
+		for _, file := range files {
+			if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+				return nil, fmt.Errorf("%s already exists, use -force to overwrite", filepath.Join(dir, file))
+			}
+		}
+	}
+
+	for _, file := range files {
+		content, err := template.ReadFile(filepath.ToSlash(filepath.Join("template", file)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %s: %w", file, err)
+		}
+		target := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", file, err)
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file, err)
+		}
+	}
+	return files, nil
+}
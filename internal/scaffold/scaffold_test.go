@@ -0,0 +1,65 @@
+package scaffold
+
+import (
+	"context"
+	"testing"
+
+	"dynamiccontrol/internal/configstore"
+	"dynamiccontrol/internal/opa"
+)
+
+func TestWriteCreatesRunnableProject(t *testing.T) {
+	dir := t.TempDir()
+	files, err := Write(dir, false)
+	if err != nil {
+		t.Fatalf("Failed to scaffold project: %v", err)
+	}
+	if len(files) != len(Files()) {
+		t.Errorf("Expected %d files, got %d", len(Files()), len(files))
+	}
+
+	store := configstore.NewFileStore(dir+"/config/routes.yaml", dir+"/policies")
+	config, err := store.LoadRoutes(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load scaffolded routes: %v", err)
+	}
+	if len(config.Routes) == 0 {
+		t.Fatal("Expected scaffolded routes")
+	}
+	policies, err := store.LoadPolicies(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load scaffolded policies: %v", err)
+	}
+	for _, route := range config.Routes {
+		for _, policy := range route.Policies {
+			if _, exists := policies[policy]; !exists {
+				t.Errorf("Route %s references missing policy %s", route.RouteName, policy)
+			}
+		}
+	}
+
+	sources, tests, err := opa.LoadTestDir(dir + "/policies")
+	if err != nil {
+		t.Fatalf("Failed to load policy tests: %v", err)
+	}
+	report, err := opa.RunTests(context.Background(), sources, tests)
+	if err != nil {
+		t.Fatalf("Failed to run policy tests: %v", err)
+	}
+	if report.Passed == 0 || report.Failed > 0 || report.Errors > 0 {
+		t.Errorf("Expected scaffolded policy tests to pass, got %+v", report)
+	}
+}
+
+func TestWriteRefusesToOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Write(dir, false); err != nil {
+		t.Fatalf("Failed to scaffold project: %v", err)
+	}
+	if _, err := Write(dir, false); err == nil {
+		t.Error("Expected an error when files already exist")
+	}
+	if _, err := Write(dir, true); err != nil {
+		t.Errorf("Expected -force to overwrite files: %v", err)
+	}
+}
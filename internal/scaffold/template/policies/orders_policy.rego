@@ -0,0 +1,13 @@
+package orders_policy
+
+import future.keywords.if
+
+default allow := false
+
+# Orders require the demo API key and are limited to 100 items
+allow if {
+	input.method == "POST"
+	input.path == "/v1/orders"
+	input.headers["X-Api-Key"] == "demo-key"
+	input.body.quantity <= 100
+}
@@ -0,0 +1,22 @@
+package orders_policy
+
+import future.keywords.if
+
+order := {
+	"method": "POST",
+	"path": "/v1/orders",
+	"headers": {"X-Api-Key": "demo-key"},
+	"body": {"item": "widget", "quantity": 2},
+}
+
+test_allow_order_with_api_key if {
+	allow with input as order
+}
+
+test_deny_order_without_api_key if {
+	not allow with input as object.remove(order, ["headers"])
+}
+
+test_deny_large_order if {
+	not allow with input as object.union(order, {"body": {"item": "widget", "quantity": 500}})
+}
@@ -0,0 +1,11 @@
+package status_policy
+
+import future.keywords.if
+
+default allow := false
+
+# Anyone may read the service status
+allow if {
+	input.method == "GET"
+	input.path == "/v1/status"
+}
@@ -0,0 +1,11 @@
+package status_policy
+
+import future.keywords.if
+
+test_allow_get_status if {
+	allow with input as {"method": "GET", "path": "/v1/status"}
+}
+
+test_deny_other_methods if {
+	not allow with input as {"method": "DELETE", "path": "/v1/status"}
+}
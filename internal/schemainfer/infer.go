@@ -0,0 +1,267 @@
+package schemainfer
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Inference heuristics
+const (
+	// MaxEnumValues is the most distinct values a string field may take to
+	// be inferred as an enum
+	MaxEnumValues = 10
+	// MinEnumObservations is how often a string field must be observed
+	// before its values are considered an enum
+	MinEnumObservations = 20
+	// DefaultMaxSamples bounds the samples an inferrer observes
+	DefaultMaxSamples = 1000
+	// nodeOverhead approximates the memory of a node besides its names and
+	// values
+	nodeOverhead = 256
+)
+
+// String formats recognized when every observed value matches
+var formats = []struct {
+	name    string
+	matches func(string) bool
+}{
+	{"date-time", func(s string) bool { _, err := time.Parse(time.RFC3339, s); return err == nil }},
+	{"uuid", regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString},
+	{"email", regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`).MatchString},
+}
+
+// Inferrer infers a JSON Schema from observed JSON documents. A field is
+// required when it was present in every observed object, and a string field
+// is an enum when it repeatedly took a few distinct values.
+type Inferrer struct {
+	mu         sync.Mutex
+	root       *node
+	samples    int
+	maxSamples int
+}
+
+// node accumulates the observations of one location in the documents
+type node struct {
+	count int
+	types map[string]bool
+	// properties and their presence count, for objects
+	properties map[string]*node
+	objects    int
+	// items merges every array element
+	items *node
+	// values holds the distinct strings seen while they may form an enum
+	values  map[string]bool
+	strings int
+	// formats holds the string formats every value matched so far
+	formats map[string]bool
+}
+
+// New creates an inferrer observing at most maxSamples documents, or
+// DefaultMaxSamples when it is zero
+func New(maxSamples int) *Inferrer {
+	if maxSamples <= 0 {
+		maxSamples = DefaultMaxSamples
+	}
+	return &Inferrer{root: newNode(), maxSamples: maxSamples}
+}
+
+// newNode creates an empty node
+func newNode() *node {
+	return &node{types: make(map[string]bool)}
+}
+
+// Observe records a decoded JSON document, returning false once the sample
+// limit is reached
+func (i *Inferrer) Observe(document interface{}) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.samples >= i.maxSamples {
+		return false
+	}
+	i.samples++
+	i.root.observe(document)
+	return true
+}
+
+// Samples returns the number of documents observed
+func (i *Inferrer) Samples() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.samples
+}
+
+// Schema returns the schema inferred from the documents observed so far
+func (i *Inferrer) Schema() map[string]interface{} {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.samples == 0 {
+		return map[string]interface{}{}
+	}
+	return i.root.schema()
+}
+
+// SizeBytes returns the approximate memory held by the observations
+func (i *Inferrer) SizeBytes() int64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.root.size()
+}
+
+// size approximates the memory held by the node and its children
+func (n *node) size() int64 {
+	size := int64(nodeOverhead)
+	for name, property := range n.properties {
+		size += int64(len(name)) + property.size()
+	}
+	if n.items != nil {
+		size += n.items.size()
+	}
+	for value := range n.values {
+		size += int64(len(value))
+	}
+	return size
+}
+
+// observe records a value at the node
+func (n *node) observe(value interface{}) {
+	n.count++
+	switch v := value.(type) {
+	case nil:
+		n.types["null"] = true
+	case bool:
+		n.types["boolean"] = true
+	case float64:
+		n.observeNumber(v == float64(int64(v)))
+	case json.Number:
+		_, err := v.Int64()
+		n.observeNumber(err == nil)
+	case string:
+		n.types["string"] = true
+		n.observeString(v)
+	case []interface{}:
+		n.types["array"] = true
+		if n.items == nil {
+			n.items = newNode()
+		}
+		for _, item := range v {
+			n.items.observe(item)
+		}
+	case map[string]interface{}:
+		n.types["object"] = true
+		n.objects++
+		if n.properties == nil {
+			n.properties = make(map[string]*node)
+		}
+		for name, property := range v {
+			child, exists := n.properties[name]
+			if !exists {
+				child = newNode()
+				n.properties[name] = child
+			}
+			child.observe(property)
+		}
+	}
+}
+
+// observeNumber records a number, keeping integer only while every number
+// was integral
+func (n *node) observeNumber(integral bool) {
+	if integral && !n.types["number"] {
+		n.types["integer"] = true
+		return
+	}
+	delete(n.types, "integer")
+	n.types["number"] = true
+}
+
+// observeString records the value and format of a string
+func (n *node) observeString(value string) {
+	if n.strings == 0 {
+		n.values = make(map[string]bool)
+		n.formats = make(map[string]bool)
+		for _, format := range formats {
+			n.formats[format.name] = true
+		}
+	}
+	n.strings++
+
+	if n.values != nil {
+		n.values[value] = true
+		if len(n.values) > MaxEnumValues {
+			n.values = nil
+		}
+	}
+	for _, format := range formats {
+		if n.formats[format.name] && !format.matches(value) {
+			delete(n.formats, format.name)
+		}
+	}
+}
+
+// schema renders the observations of the node as a schema
+func (n *node) schema() map[string]interface{} {
+	schema := make(map[string]interface{})
+	types := make([]string, 0, len(n.types))
+	for name := range n.types {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	switch len(types) {
+	case 0:
+	case 1:
+		schema["type"] = types[0]
+	default:
+		schema["type"] = types
+	}
+
+	if n.types["string"] {
+		if len(n.formats) == 1 {
+			for name := range n.formats {
+				schema["format"] = name
+			}
+		} else if enum := n.enum(); enum != nil {
+			schema["enum"] = enum
+		}
+	}
+	if n.items != nil && n.items.count > 0 {
+		schema["items"] = n.items.schema()
+	}
+	if n.properties != nil {
+		properties := make(map[string]interface{}, len(n.properties))
+		var required []string
+		for name, child := range n.properties {
+			properties[name] = child.schema()
+			if child.count == n.objects {
+				required = append(required, name)
+			}
+		}
+		schema["properties"] = properties
+		if len(required) > 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+	}
+	return schema
+}
+
+// enum returns the values of a string field that looks like an enum: it was
+// observed often enough, only ever held strings and repeated a few distinct
+// values
+func (n *node) enum() []interface{} {
+	if len(n.values) < 2 || len(n.types) != 1 || n.strings < MinEnumObservations || len(n.values)*2 > n.strings {
+		return nil
+	}
+	values := make([]string, 0, len(n.values))
+	for value := range n.values {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	enum := make([]interface{}, len(values))
+	for i, value := range values {
+		enum[i] = value
+	}
+	return enum
+}
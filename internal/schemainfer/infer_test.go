@@ -0,0 +1,62 @@
+package schemainfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestInferSchema(t *testing.T) {
+	inferrer := New(0)
+	for i := 0; i < MinEnumObservations; i++ {
+		document := map[string]interface{}{
+			"id":        fmt.Sprintf("1b4e28ba-2fa1-11d2-883f-0016d3cca4%02d", i),
+			"status":    []string{"active", "paused"}[i%2],
+			"name":      fmt.Sprintf("service-%d", i),
+			"replicas":  float64(i),
+			"createdAt": "2024-05-01T10:00:00Z",
+			"tags":      []interface{}{fmt.Sprintf("tag-%d", i)},
+		}
+		if i%2 == 0 {
+			document["weight"] = 0.5
+			document["owner"] = "team@example.com"
+		} else {
+			document["weight"] = float64(1)
+		}
+		inferrer.Observe(document)
+	}
+
+	var expected map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["createdAt", "id", "name", "replicas", "status", "tags", "weight"],
+		"properties": {
+			"id": {"type": "string", "format": "uuid"},
+			"status": {"type": "string", "enum": ["active", "paused"]},
+			"name": {"type": "string"},
+			"replicas": {"type": "integer"},
+			"createdAt": {"type": "string", "format": "date-time"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"weight": {"type": "number"},
+			"owner": {"type": "string", "format": "email"}
+		}
+	}`), &expected)
+	actual, _ := json.Marshal(inferrer.Schema())
+	var schema map[string]interface{}
+	json.Unmarshal(actual, &schema)
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("Unexpected schema %s", actual)
+	}
+}
+
+func TestInferSchemaStopsAtMaxSamples(t *testing.T) {
+	inferrer := New(1)
+	if !inferrer.Observe(map[string]interface{}{"id": "a"}) || inferrer.Observe(nil) {
+		t.Error("Expected only the first sample to be observed")
+	}
+	schema := inferrer.Schema()
+	if inferrer.Samples() != 1 || schema["type"] != "object" {
+		t.Errorf("Expected a schema of the first sample, got %v", schema)
+	}
+}
@@ -0,0 +1,128 @@
+package sideeffects
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/metrics"
+)
+
+// DefaultLimit bounds the side effects running at once
+const DefaultLimit = 1024
+
+// Side effect results
+const (
+	ResultCompleted = "completed"
+	ResultCanceled  = "canceled"
+	ResultPanicked  = "panicked"
+	ResultDropped   = "dropped"
+)
+
+// ErrShutdown is returned when side effects are still running after the
+// shutdown deadline
+var ErrShutdown = errors.New("side effects canceled before completing")
+
+// Group runs the side effects of requests, such as webhook deliveries and
+// background validation, in bounded goroutines. Side effects outlive the
+// request that started them but are canceled when the group shuts down.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	slots  chan struct{}
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewGroup creates a group running at most limit side effects at once, or
+// DefaultLimit when it is zero
+func NewGroup(limit int) *Group {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{ctx: ctx, cancel: cancel, slots: make(chan struct{}, limit)}
+}
+
+// Go runs fn in the background. Its context keeps the values of ctx, such
+// as the request ID and trace, but is only canceled when the group shuts
+// down. It returns false without running fn when the limit is reached or the
+// group is shutting down.
+func (g *Group) Go(ctx context.Context, kind string, fn func(ctx context.Context)) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.closed {
+		metrics.SideEffects.WithLabelValues(kind, ResultDropped).Inc()
+		return false
+	}
+	select {
+	case g.slots <- struct{}{}:
+	default:
+		metrics.SideEffects.WithLabelValues(kind, ResultDropped).Inc()
+		logging.FromContext(ctx).Warn("Side effect dropped", "kind", kind, "limit", cap(g.slots))
+		return false
+	}
+
+	g.wg.Add(1)
+	metrics.SideEffectsInFlight.WithLabelValues(kind).Inc()
+	go g.run(ctx, kind, fn)
+	return true
+}
+
+// run runs a side effect and records its outcome
+func (g *Group) run(ctx context.Context, kind string, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(g.ctx, cancel)
+	start := time.Now()
+	result := ResultCompleted
+	defer func() {
+		if r := recover(); r != nil {
+			result = ResultPanicked
+			logging.FromContext(ctx).Error("Side effect panicked", "kind", kind, "panic", r)
+		} else if ctx.Err() != nil {
+			result = ResultCanceled
+		}
+		stop()
+		cancel()
+		metrics.SideEffectDuration.WithLabelValues(kind).Observe(time.Since(start).Seconds())
+		metrics.SideEffects.WithLabelValues(kind, result).Inc()
+		metrics.SideEffectsInFlight.WithLabelValues(kind).Dec()
+		<-g.slots
+		g.wg.Done()
+	}()
+
+	fn(ctx)
+}
+
+// InFlight returns the number of side effects running
+func (g *Group) InFlight() int {
+	return len(g.slots)
+}
+
+// Shutdown stops accepting side effects and waits for the running ones. When
+// ctx is done first, the remaining side effects are canceled and ErrShutdown
+// is returned once they return.
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		g.cancel()
+		return nil
+	case <-ctx.Done():
+		g.cancel()
+		<-done
+		return ErrShutdown
+	}
+}
@@ -0,0 +1,68 @@
+package sideeffects
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/logging"
+)
+
+func TestGroupBoundsSideEffects(t *testing.T) {
+	group := NewGroup(1)
+	release := make(chan struct{})
+	requestCtx, cancelRequest := context.WithCancel(logging.WithRequestID(context.Background(), "req-1"))
+
+	requestIDs := make(chan string, 1)
+	started := group.Go(requestCtx, "webhook", func(ctx context.Context) {
+		<-release
+		if ctx.Err() != nil {
+			t.Error("Expected the side effect to outlive its request")
+		}
+		requestIDs <- logging.RequestID(ctx)
+	})
+	if !started {
+		t.Fatal("Expected the side effect to start")
+	}
+	if group.Go(context.Background(), "webhook", func(ctx context.Context) {}) {
+		t.Error("Expected side effects beyond the limit to be dropped")
+	}
+
+	cancelRequest()
+	close(release)
+	if id := <-requestIDs; id != "req-1" {
+		t.Errorf("Expected the request ID to be kept, got %q", id)
+	}
+	if err := group.Shutdown(context.Background()); err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+	if group.Go(context.Background(), "webhook", func(ctx context.Context) {}) {
+		t.Error("Expected side effects to be rejected after shutdown")
+	}
+}
+
+func TestGroupShutdownCancelsSideEffects(t *testing.T) {
+	group := NewGroup(0)
+	canceled := make(chan struct{})
+	group.Go(context.Background(), "aggregate", func(ctx context.Context) {
+		<-ctx.Done()
+		close(canceled)
+	})
+	group.Go(context.Background(), "aggregate", func(ctx context.Context) {
+		panic("boom")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := group.Shutdown(ctx); err != ErrShutdown {
+		t.Errorf("Expected ErrShutdown, got %v", err)
+	}
+	select {
+	case <-canceled:
+	default:
+		t.Error("Expected the running side effect to be canceled")
+	}
+	if group.InFlight() != 0 {
+		t.Errorf("Expected no side effects in flight, got %d", group.InFlight())
+	}
+}
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"dynamiccontrol/internal/types"
+
+	"github.com/lib/pq"
+)
+
+// Configuration change notifications
+const (
+	notifyChannel = "dynamiccontrol_config"
+	// notifyDebounce coalesces the notifications of a single import
+	notifyDebounce = 200 * time.Millisecond
+	// listenerPing checks the listening connection while no change arrives
+	listenerPing = 90 * time.Second
+)
+
+// Names of the settings rows
+const (
+	settingCORS    = "cors"
+	settingTenants = "tenants"
+)
+
+// Name identifies the backend in logs
+func (p *Postgres) Name() string {
+	return "postgres"
+}
+
+// LoadRoutes reads the routes, in the order they were imported, with the
+// cors and tenants settings
+func (p *Postgres) LoadRoutes(ctx context.Context) (*types.RoutesConfig, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT route_key, config FROM routes ORDER BY position, route_key`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load routes from postgres: %w", err)
+	}
+	defer rows.Close()
+
+	config := &types.RoutesConfig{Routes: []types.RouteConfig{}}
+	for rows.Next() {
+		var key string
+		var document []byte
+		if err := rows.Scan(&key, &document); err != nil {
+			return nil, fmt.Errorf("failed to load routes from postgres: %w", err)
+		}
+		var route types.RouteConfig
+		if err := json.Unmarshal(document, &route); err != nil {
+			return nil, fmt.Errorf("failed to parse route %s: %w", key, err)
+		}
+		config.Routes = append(config.Routes, route)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load routes from postgres: %w", err)
+	}
+
+	if err := p.loadSetting(ctx, settingCORS, &config.CORS); err != nil {
+		return nil, err
+	}
+	if err := p.loadSetting(ctx, settingTenants, &config.Tenants); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// LoadPolicies reads the policies keyed by name
+func (p *Postgres) LoadPolicies(ctx context.Context) (map[string]string, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT name, source FROM policies`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policies from postgres: %w", err)
+	}
+	defer rows.Close()
+
+	policies := make(map[string]string)
+	for rows.Next() {
+		var name, source string
+		if err := rows.Scan(&name, &source); err != nil {
+			return nil, fmt.Errorf("failed to load policies from postgres: %w", err)
+		}
+		policies[name] = source
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load policies from postgres: %w", err)
+	}
+	return policies, nil
+}
+
+// Watch listens for the change notifications of the configuration tables
+// until ctx is done. Changes made while the listener reconnects are covered
+// by a reload once it is back.
+func (p *Postgres) Watch(ctx context.Context, onChange func()) error {
+	listener := pq.NewListener(p.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("postgres listener interrupted, reconnecting", "error", err)
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(notifyChannel); err != nil {
+		return fmt.Errorf("failed to listen for configuration changes: %w", err)
+	}
+
+	ping := time.NewTicker(listenerPing)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-listener.Notify:
+			p.drain(ctx, listener)
+			onChange()
+		case <-ping.C:
+			go listener.Ping()
+		}
+	}
+}
+
+// drain discards the notifications following a change, so one import
+// touching several tables triggers a single reload
+func (p *Postgres) drain(ctx context.Context, listener *pq.Listener) {
+	timer := time.NewTimer(notifyDebounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			return
+		case <-listener.Notify:
+		}
+	}
+}
+
+// ImportConfig replaces the routes and settings with a configuration in a
+// single transaction. Policies are replaced as well unless nil.
+func (p *Postgres) ImportConfig(ctx context.Context, config *types.RoutesConfig, policies map[string]string) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin import: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM routes`); err != nil {
+		return fmt.Errorf("failed to clear routes: %w", err)
+	}
+	for position, route := range config.Routes {
+		document, err := json.Marshal(route)
+		if err != nil {
+			return fmt.Errorf("failed to encode route %s: %w", routeKey(route), err)
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO routes (route_key, position, config) VALUES ($1, $2, $3)`,
+			routeKey(route), position, string(document))
+		if err != nil {
+			return fmt.Errorf("failed to import route %s: %w", routeKey(route), err)
+		}
+	}
+	if err := saveSetting(ctx, tx, settingCORS, config.CORS, config.CORS == nil); err != nil {
+		return err
+	}
+	if err := saveSetting(ctx, tx, settingTenants, config.Tenants, len(config.Tenants) == 0); err != nil {
+		return err
+	}
+
+	if policies != nil {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM policies`); err != nil {
+			return fmt.Errorf("failed to clear policies: %w", err)
+		}
+		for name, source := range policies {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO policies (name, source) VALUES ($1, $2)`, name, source); err != nil {
+				return fmt.Errorf("failed to import policy %s: %w", name, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+	return nil
+}
+
+// loadSetting decodes a settings row into value, leaving it unset when the
+// row is missing
+func (p *Postgres) loadSetting(ctx context.Context, name string, value interface{}) error {
+	var document []byte
+	err := p.db.QueryRowContext(ctx, `SELECT value FROM settings WHERE name = $1`, name).Scan(&document)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load %s from postgres: %w", name, err)
+	}
+	if err := json.Unmarshal(document, value); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// saveSetting writes a settings row, or removes it when empty
+func saveSetting(ctx context.Context, tx *sql.Tx, name string, value interface{}, empty bool) error {
+	if empty {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM settings WHERE name = $1`, name); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", name, err)
+		}
+		return nil
+	}
+	document, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO settings (name, value) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET value = excluded.value, updated_at = now()`, name, string(document))
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %w", name, err)
+	}
+	return nil
+}
+
+// routeKey keys a route by method, host and path, prefixed with the tenant
+// of tenant routes
+func routeKey(route types.RouteConfig) string {
+	key := route.Method + " " + route.Host + route.RouteName
+	if route.Tenant != "" {
+		return route.Tenant + ":" + key
+	}
+	return key
+}
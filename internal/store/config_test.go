@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// openConfigStore opens the test database with the configuration tables
+func openConfigStore(t *testing.T) *Postgres {
+	t.Helper()
+	postgres := openTestDatabase(t)
+	if _, err := postgres.Migrate(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return postgres
+}
+
+// watchConfig watches the configuration tables until the test ends and
+// returns the change notifications
+func watchConfig(t *testing.T, postgres *Postgres) <-chan struct{} {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := make(chan struct{}, 10)
+	stopped := make(chan error, 1)
+	go func() { stopped <- postgres.Watch(ctx, func() { changed <- struct{}{} }) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-stopped; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the watch to end with the context, got %v", err)
+		}
+	})
+	// Give the listener time to connect before changes are made
+	time.Sleep(500 * time.Millisecond)
+	return changed
+}
+
+func TestRouteKey(t *testing.T) {
+	for _, tc := range []struct {
+		route    types.RouteConfig
+		expected string
+	}{
+		{types.RouteConfig{Method: "GET", RouteName: "/v1/status"}, "GET /v1/status"},
+		{types.RouteConfig{Method: "GET", RouteName: "/v1/status", Host: "api.example.com"}, "GET api.example.com/v1/status"},
+		{types.RouteConfig{Method: "POST", RouteName: "/v1/orders", Tenant: "acme"}, "acme:POST /v1/orders"},
+	} {
+		if key := routeKey(tc.route); key != tc.expected {
+			t.Errorf("Expected key %q, got %q", tc.expected, key)
+		}
+	}
+}
+
+func TestConfigStoreImportAndLoad(t *testing.T) {
+	postgres := openConfigStore(t)
+	ctx := context.Background()
+
+	config := &types.RoutesConfig{
+		Routes: []types.RouteConfig{
+			{RouteName: "/v1/status", Method: "GET"},
+			{RouteName: "/v1/orders", Method: "POST", Tenant: "acme", Policies: []string{"acme_orders"}},
+			{RouteName: "/v1/accounts", Method: "GET", Host: "api.example.com"},
+		},
+		CORS:    &types.CORSConfig{AllowOrigins: []string{"https://app.example.com"}, MaxAgeSeconds: 600},
+		Tenants: []types.TenantConfig{{Name: "acme", Hosts: []string{"acme.example.com"}}},
+	}
+	policies := map[string]string{"acme_orders": "package acme_orders\n\ndefault allow = true\n"}
+	if err := postgres.ImportConfig(ctx, config, policies); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	loaded, err := postgres.LoadRoutes(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, config) {
+		t.Errorf("Expected the imported configuration in import order, got %+v", loaded)
+	}
+	loadedPolicies, err := postgres.LoadPolicies(ctx)
+	if err != nil || !reflect.DeepEqual(loadedPolicies, policies) {
+		t.Errorf("Expected the imported policies, got %v, %v", loadedPolicies, err)
+	}
+
+	// Importing without policies keeps them, and empty settings are removed
+	replacement := &types.RoutesConfig{Routes: []types.RouteConfig{{RouteName: "/v1/health", Method: "GET"}}}
+	if err := postgres.ImportConfig(ctx, replacement, nil); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	loaded, err = postgres.LoadRoutes(ctx)
+	if err != nil || !reflect.DeepEqual(loaded, replacement) {
+		t.Errorf("Expected the routes and settings to be replaced, got %+v, %v", loaded, err)
+	}
+	if loadedPolicies, err := postgres.LoadPolicies(ctx); err != nil || !reflect.DeepEqual(loadedPolicies, policies) {
+		t.Errorf("Expected the policies to be kept, got %v, %v", loadedPolicies, err)
+	}
+
+	if err := postgres.ImportConfig(ctx, replacement, map[string]string{}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if loadedPolicies, err := postgres.LoadPolicies(ctx); err != nil || len(loadedPolicies) != 0 {
+		t.Errorf("Expected an empty policy set to clear the policies, got %v, %v", loadedPolicies, err)
+	}
+}
+
+func TestConfigStoreFailedImportChangesNothing(t *testing.T) {
+	postgres := openConfigStore(t)
+	ctx := context.Background()
+	config := &types.RoutesConfig{Routes: []types.RouteConfig{{RouteName: "/v1/status", Method: "GET"}}}
+	if err := postgres.ImportConfig(ctx, config, map[string]string{}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	changed := watchConfig(t, postgres)
+
+	duplicate := &types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/v1/orders", Method: "POST"},
+		{RouteName: "/v1/orders", Method: "POST", Policies: []string{"orders_policy"}},
+	}}
+	if err := postgres.ImportConfig(ctx, duplicate, map[string]string{"orders_policy": "package orders_policy"}); err == nil {
+		t.Fatal("Expected an import with duplicate routes to fail")
+	}
+	if loaded, err := postgres.LoadRoutes(ctx); err != nil || !reflect.DeepEqual(loaded, config) {
+		t.Errorf("Expected the previous routes to be kept, got %+v, %v", loaded, err)
+	}
+	if loadedPolicies, err := postgres.LoadPolicies(ctx); err != nil || len(loadedPolicies) != 0 {
+		t.Errorf("Expected no policy to be imported, got %v, %v", loadedPolicies, err)
+	}
+	select {
+	case <-changed:
+		t.Error("Expected a rolled back import not to notify watchers")
+	case <-time.After(time.Second):
+	}
+}
+
+func TestConfigStoreNotifiesOncePerImport(t *testing.T) {
+	postgres := openConfigStore(t)
+	ctx := context.Background()
+	changed := watchConfig(t, postgres)
+
+	// The import changes the routes, settings and policies tables
+	config := &types.RoutesConfig{
+		Routes:  []types.RouteConfig{{RouteName: "/v1/status", Method: "GET"}},
+		CORS:    &types.CORSConfig{AllowOrigins: []string{"*"}},
+		Tenants: []types.TenantConfig{{Name: "acme"}},
+	}
+	if err := postgres.ImportConfig(ctx, config, map[string]string{"status_policy": "package status_policy"}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the import to notify watchers")
+	}
+	select {
+	case <-changed:
+		t.Error("Expected the notifications of one import to be coalesced")
+	case <-time.After(2 * notifyDebounce):
+	}
+
+	if err := postgres.ImportConfig(ctx, &types.RoutesConfig{}, nil); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected a later import to notify watchers again")
+	}
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Decision writer settings
+const (
+	decisionQueueSize     = 4096
+	decisionBatchSize     = 100
+	decisionFlushInterval = time.Second
+	writeTimeout          = 5 * time.Second
+)
+
+// decisionColumns are the columns of a decision row, in insert order
+var decisionColumns = []string{"id", "request_id", "recorded_at", "route", "method", "allowed", "shadow", "decision"}
+
+// DecisionWriter writes policy decisions to the decisions table in batches,
+// off the request path. Decisions are dropped while the queue is full.
+type DecisionWriter struct {
+	postgres *Postgres
+	queue    chan types.Decision
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewDecisionWriter creates a writer of decisions to the database
+func (p *Postgres) NewDecisionWriter() *DecisionWriter {
+	return &DecisionWriter{
+		postgres: p,
+		queue:    make(chan types.Decision, decisionQueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Enqueue queues a decision without blocking
+func (dw *DecisionWriter) Enqueue(decision types.Decision) {
+	select {
+	case dw.queue <- decision:
+	default:
+		droppedDecisions.Inc()
+	}
+}
+
+// Pending returns the number of queued decisions
+func (dw *DecisionWriter) Pending() int {
+	return len(dw.queue)
+}
+
+// Start writes queued decisions in the background until Stop is called
+func (dw *DecisionWriter) Start() {
+	go dw.run()
+}
+
+// Stop writes the queued decisions and stops the writer
+func (dw *DecisionWriter) Stop() {
+	dw.once.Do(func() {
+		close(dw.stop)
+		<-dw.done
+	})
+}
+
+// run batches queued decisions, writing a batch once full or every flush
+// interval
+func (dw *DecisionWriter) run() {
+	defer close(dw.done)
+	ticker := time.NewTicker(decisionFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]types.Decision, 0, decisionBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := dw.write(batch); err != nil {
+			droppedDecisions.Add(float64(len(batch)))
+			slog.Error("Failed to write decisions", "decisions", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case decision := <-dw.queue:
+			batch = append(batch, decision)
+			if len(batch) == decisionBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-dw.stop:
+			for {
+				select {
+				case decision := <-dw.queue:
+					batch = append(batch, decision)
+					if len(batch) == decisionBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// write inserts a batch of decisions with a single statement
+func (dw *DecisionWriter) write(batch []types.Decision) error {
+	args := make([]interface{}, 0, len(batch)*len(decisionColumns))
+	for _, decision := range batch {
+		document, err := json.Marshal(decision)
+		if err != nil {
+			return fmt.Errorf("failed to encode decision %s: %w", decision.ID, err)
+		}
+		args = append(args, decision.ID, decision.RequestID, decision.Timestamp, decision.Route,
+			decision.Method, decision.Allowed, decision.Shadow, string(document))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	_, err := dw.postgres.db.ExecContext(ctx, insertDecisionsQuery(len(batch)), args...)
+	return err
+}
+
+// insertDecisionsQuery returns the statement inserting the given number of
+// decisions, skipping those already written
+func insertDecisionsQuery(rows int) string {
+	var query strings.Builder
+	query.WriteString("INSERT INTO decisions (" + strings.Join(decisionColumns, ", ") + ") VALUES ")
+	for row := 0; row < rows; row++ {
+		if row > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for column := range decisionColumns {
+			if column > 0 {
+				query.WriteString(", ")
+			}
+			fmt.Fprintf(&query, "$%d", row*len(decisionColumns)+column+1)
+		}
+		query.WriteString(")")
+	}
+	query.WriteString(" ON CONFLICT (id) DO NOTHING")
+	return query.String()
+}
+
+// AuditSink writes audit entries to the audit_events table
+type AuditSink struct {
+	postgres *Postgres
+}
+
+// AuditSink returns a sink writing audit entries to the database
+func (p *Postgres) AuditSink() *AuditSink {
+	return &AuditSink{postgres: p}
+}
+
+// Name identifies the sink in logs and metrics
+func (as *AuditSink) Name() string {
+	return "postgres"
+}
+
+// Write inserts an audit entry
+func (as *AuditSink) Write(entry types.AuditEntry) error {
+	document, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	_, err = as.postgres.db.ExecContext(ctx, `INSERT INTO audit_events (id, recorded_at, actor, action, resource, outcome, entry)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO NOTHING`,
+		entry.ID, entry.Timestamp, entry.Actor, entry.Action, entry.Resource, entry.Outcome, string(document))
+	if err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
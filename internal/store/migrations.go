@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationLock is the advisory lock held while migrating, so servers
+// starting together apply each migration once
+const migrationLock = 0x64796e63
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is a schema change, applied in version order
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Migrations returns the embedded schema migrations in version order. Files
+// are named NNNN_description.sql.
+func Migrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	seen := make(map[int]string)
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".sql")
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s must start with a positive version number", file)
+		}
+		if other, exists := seen[version]; exists {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, name, version)
+		}
+		seen[version] = name
+
+		source, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(source)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrate applies the pending migrations, each in a transaction of its own,
+// and returns the names of those applied
+func (p *Postgres) Migrate(ctx context.Context) ([]string, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLock); err != nil {
+		return nil, fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLock)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	applied := make(map[int]bool)
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		applied[version] = true
+	}
+	rows.Close()
+
+	var names []string
+	for _, migration := range migrations {
+		if applied[migration.Version] {
+			continue
+		}
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return names, fmt.Errorf("failed to begin migration %s: %w", migration.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, migration.SQL); err != nil {
+			tx.Rollback()
+			return names, fmt.Errorf("failed to apply migration %s: %w", migration.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name); err != nil {
+			tx.Rollback()
+			return names, fmt.Errorf("failed to record migration %s: %w", migration.Name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return names, fmt.Errorf("failed to commit migration %s: %w", migration.Name, err)
+		}
+		slog.Info("Applied migration", "migration", migration.Name)
+		names = append(names, migration.Name)
+	}
+	return names, nil
+}
@@ -0,0 +1,35 @@
+-- Routes, policies and configuration settings. Every change notifies the
+-- dynamiccontrol_config channel, so servers reload without polling.
+CREATE TABLE routes (
+    route_key  TEXT PRIMARY KEY,
+    position   INTEGER NOT NULL DEFAULT 0,
+    config     JSONB NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE policies (
+    name       TEXT PRIMARY KEY,
+    source     TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Settings hold the configuration outside routes, such as cors and tenants
+CREATE TABLE settings (
+    name       TEXT PRIMARY KEY,
+    value      JSONB NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE FUNCTION dynamiccontrol_notify_config() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('dynamiccontrol_config', TG_TABLE_NAME);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER routes_changed AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON routes
+    FOR EACH STATEMENT EXECUTE FUNCTION dynamiccontrol_notify_config();
+CREATE TRIGGER policies_changed AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON policies
+    FOR EACH STATEMENT EXECUTE FUNCTION dynamiccontrol_notify_config();
+CREATE TRIGGER settings_changed AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON settings
+    FOR EACH STATEMENT EXECUTE FUNCTION dynamiccontrol_notify_config();
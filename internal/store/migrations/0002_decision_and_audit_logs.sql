@@ -0,0 +1,28 @@
+-- Policy decisions and audit events, kept as their JSON documents with the
+-- columns they are usually queried by
+CREATE TABLE decisions (
+    id          TEXT PRIMARY KEY,
+    request_id  TEXT NOT NULL DEFAULT '',
+    recorded_at TIMESTAMPTZ NOT NULL,
+    route       TEXT NOT NULL,
+    method      TEXT NOT NULL,
+    allowed     BOOLEAN NOT NULL,
+    shadow      BOOLEAN NOT NULL DEFAULT false,
+    decision    JSONB NOT NULL
+);
+
+CREATE INDEX decisions_recorded_at ON decisions (recorded_at);
+CREATE INDEX decisions_route ON decisions (route, recorded_at);
+
+CREATE TABLE audit_events (
+    id          TEXT PRIMARY KEY,
+    recorded_at TIMESTAMPTZ NOT NULL,
+    actor       TEXT NOT NULL,
+    action      TEXT NOT NULL,
+    resource    TEXT NOT NULL,
+    outcome     TEXT NOT NULL,
+    entry       JSONB NOT NULL
+);
+
+CREATE INDEX audit_events_recorded_at ON audit_events (recorded_at);
+CREATE INDEX audit_events_resource ON audit_events (resource, recorded_at);
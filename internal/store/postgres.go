@@ -0,0 +1,48 @@
+// Package store keeps the control plane state in Postgres: routes, policies
+// and settings served as a configuration store, and the decision and audit
+// logs written by the server.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	// Register the postgres database/sql driver
+	_ "github.com/lib/pq"
+)
+
+var droppedDecisions = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "dynamiccontrol_store_dropped_decisions_total",
+	Help: "Policy decisions that could not be written to postgres",
+})
+
+func init() {
+	prometheus.MustRegister(droppedDecisions)
+}
+
+// Postgres is a connection pool to the control plane database
+type Postgres struct {
+	db  *sql.DB
+	dsn string
+}
+
+// Open connects to the database at a postgres:// URL or key=value DSN
+func Open(ctx context.Context, dsn string) (*Postgres, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	return &Postgres{db: db, dsn: dsn}, nil
+}
+
+// Close closes the connection pool
+func (p *Postgres) Close() error {
+	return p.db.Close()
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Failed to read migrations: %v", err)
+	}
+	if len(migrations) < 2 {
+		t.Fatalf("Expected the embedded migrations, got %d", len(migrations))
+	}
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("Expected migration %s to have version %d", migration.Name, i+1)
+		}
+		if strings.TrimSpace(migration.SQL) == "" {
+			t.Errorf("Expected migration %s to have statements", migration.Name)
+		}
+	}
+}
+
+func TestInsertDecisionsQuery(t *testing.T) {
+	query := insertDecisionsQuery(2)
+	if !strings.Contains(query, "($1, $2, $3, $4, $5, $6, $7, $8), ($9,") || !strings.HasSuffix(query, "($9, $10, $11, $12, $13, $14, $15, $16) ON CONFLICT (id) DO NOTHING") {
+		t.Errorf("Unexpected query %s", query)
+	}
+}
+
+// openTestDatabase connects to the database named by
+// DYNAMICCONTROL_TEST_DATABASE_URL, which the test owns and may clear
+func openTestDatabase(t *testing.T) *Postgres {
+	dsn := os.Getenv("DYNAMICCONTROL_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DYNAMICCONTROL_TEST_DATABASE_URL is not set")
+	}
+	postgres, err := Open(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { postgres.Close() })
+	return postgres
+}
+
+func TestPostgresStore(t *testing.T) {
+	postgres := openTestDatabase(t)
+	ctx := context.Background()
+	if _, err := postgres.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if applied, err := postgres.Migrate(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("Expected migrating twice to apply nothing, got %v, %v", applied, err)
+	}
+
+	config := &types.RoutesConfig{
+		Routes: []types.RouteConfig{
+			{RouteName: "/v1/status", Method: "GET"},
+			{RouteName: "/v1/orders", Method: "POST", Policies: []string{"orders_policy"}},
+		},
+		Tenants: []types.TenantConfig{{Name: "acme", Hosts: []string{"acme.example.com"}}},
+	}
+	policies := map[string]string{"orders_policy": "package orders_policy\n\ndefault allow = true\n"}
+	changed := make(chan struct{}, 1)
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go postgres.Watch(watchCtx, func() { changed <- struct{}{} })
+	time.Sleep(500 * time.Millisecond)
+
+	if err := postgres.ImportConfig(ctx, config, policies); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the import to notify watchers")
+	}
+
+	loaded, err := postgres.LoadRoutes(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+	if len(loaded.Routes) != 2 || loaded.Routes[1].RouteName != "/v1/orders" || len(loaded.Tenants) != 1 {
+		t.Errorf("Expected the imported configuration in order, got %+v", loaded)
+	}
+	if loadedPolicies, err := postgres.LoadPolicies(ctx); err != nil || loadedPolicies["orders_policy"] == "" {
+		t.Errorf("Expected the imported policies, got %v, %v", loadedPolicies, err)
+	}
+
+	writer := postgres.NewDecisionWriter()
+	writer.Start()
+	writer.Enqueue(types.Decision{ID: "dec-test-" + time.Now().Format(time.RFC3339Nano), Timestamp: time.Now(), Route: "GET /v1/status", Method: "GET", Allowed: true})
+	writer.Stop()
+	entry := types.AuditEntry{ID: "aud-test-" + time.Now().Format(time.RFC3339Nano), Timestamp: time.Now(), Actor: "test", Action: "config.import", Resource: "routes", Outcome: "success"}
+	if err := postgres.AuditSink().Write(entry); err != nil {
+		t.Errorf("Failed to write audit entry: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+// Package tenancy resolves the tenant of requests and decides which tenant
+// owns a configuration name, so one deployment can serve several teams.
+package tenancy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+)
+
+// DefaultHeader names the tenant of requests whose host belongs to no tenant
+const DefaultHeader = "X-Tenant-ID"
+
+// tenantName matches tenant names. Names exclude underscores, so the owner
+// of a name such as "acme_orders" is unambiguous.
+var tenantName = regexp.MustCompile(`^[a-z][a-z0-9]{0,31}$`)
+
+// Resolver maps requests and configuration names to tenants
+type Resolver struct {
+	header    string
+	tenants   map[string]types.TenantConfig
+	hosts     map[string]string
+	wildcards map[string]string
+}
+
+// NewResolver creates a resolver for the declared tenants. Requests name
+// their tenant in header when their host belongs to no tenant; an empty
+// header uses DefaultHeader.
+func NewResolver(tenants []types.TenantConfig, header string) (*Resolver, error) {
+	if header == "" {
+		header = DefaultHeader
+	}
+	r := &Resolver{
+		header:    header,
+		tenants:   make(map[string]types.TenantConfig, len(tenants)),
+		hosts:     make(map[string]string),
+		wildcards: make(map[string]string),
+	}
+	for _, tenant := range tenants {
+		if !tenantName.MatchString(tenant.Name) {
+			return nil, fmt.Errorf("invalid tenant name %q, expected lowercase letters and digits", tenant.Name)
+		}
+		if _, exists := r.tenants[tenant.Name]; exists {
+			return nil, fmt.Errorf("duplicate tenant %s", tenant.Name)
+		}
+		r.tenants[tenant.Name] = tenant
+
+		for _, host := range tenant.Hosts {
+			host = strings.ToLower(host)
+			hosts := r.hosts
+			if suffix, ok := strings.CutPrefix(host, "*."); ok {
+				hosts, host = r.wildcards, suffix
+			}
+			if host == "" || strings.ContainsAny(host, "*/:") {
+				return nil, fmt.Errorf("invalid host %q of tenant %s", host, tenant.Name)
+			}
+			if owner, exists := hosts[host]; exists {
+				return nil, fmt.Errorf("host %s belongs to tenants %s and %s", host, owner, tenant.Name)
+			}
+			hosts[host] = tenant.Name
+		}
+	}
+	return r, nil
+}
+
+// Header returns the request header naming the tenant
+func (r *Resolver) Header() string {
+	return r.header
+}
+
+// Names returns the declared tenants in name order
+func (r *Resolver) Names() []string {
+	names := make([]string, 0, len(r.tenants))
+	for name := range r.tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Exists reports whether a tenant is declared
+func (r *Resolver) Exists(name string) bool {
+	_, exists := r.tenants[name]
+	return exists
+}
+
+// Resolve returns the tenant of a request, or "" when it belongs to none.
+// The host decides first, so a request to a tenant's host cannot claim
+// another tenant through the header; the header must name a declared tenant.
+func (r *Resolver) Resolve(req *http.Request) string {
+	if len(r.tenants) == 0 {
+		return ""
+	}
+	host := strings.ToLower(req.Host)
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	if tenant, ok := r.hosts[host]; ok {
+		return tenant
+	}
+	for suffix := host; ; {
+		_, parent, found := strings.Cut(suffix, ".")
+		if !found {
+			break
+		}
+		if tenant, ok := r.wildcards[parent]; ok {
+			return tenant
+		}
+		suffix = parent
+	}
+
+	if tenant := req.Header.Get(r.header); r.Exists(tenant) {
+		return tenant
+	}
+	return ""
+}
+
+// Owner returns the tenant owning a policy or schema name, or "" for shared
+// names
+func (r *Resolver) Owner(name string) string {
+	prefix, _, found := strings.Cut(name, "_")
+	if found && r.Exists(prefix) {
+		return prefix
+	}
+	return ""
+}
+
+// Visible reports whether a tenant may use a name: its own names and shared
+// ones. Requests without a tenant see every name.
+func (r *Resolver) Visible(tenant, name string) bool {
+	if tenant == "" {
+		return true
+	}
+	owner := r.Owner(name)
+	return owner == "" || owner == tenant
+}
+
+// Validate checks that routes only name declared tenants and only use
+// policies and schemas visible to their tenant. Shared routes may not use
+// names owned by a tenant.
+func (r *Resolver) Validate(config *types.RoutesConfig) error {
+	for _, route := range config.Routes {
+		key := route.Method + " " + route.RouteName
+		if route.Tenant != "" && !r.Exists(route.Tenant) {
+			return fmt.Errorf("route %s names undeclared tenant %s", key, route.Tenant)
+		}
+		names := append([]string(nil), route.Policies...)
+		if route.Cache != nil && route.Cache.TTLPolicy != "" {
+			names = append(names, route.Cache.TTLPolicy)
+		}
+		for _, ref := range []string{route.RequestSchemaRef, route.ResponseSchemaRef} {
+			if ref != "" {
+				name, _, _ := strings.Cut(ref, "@")
+				names = append(names, name)
+			}
+		}
+		for _, name := range names {
+			if owner := r.Owner(name); owner != "" && owner != route.Tenant {
+				return fmt.Errorf("route %s may not use %s, which belongs to tenant %s", key, name, owner)
+			}
+		}
+	}
+	return nil
+}
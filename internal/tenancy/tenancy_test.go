@@ -0,0 +1,80 @@
+package tenancy
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestNewResolverRejectsInvalidTenants(t *testing.T) {
+	tests := [][]types.TenantConfig{
+		{{Name: "Acme"}},
+		{{Name: "acme_eu"}},
+		{{Name: "acme"}, {Name: "acme"}},
+		{{Name: "acme", Hosts: []string{"api.example.com"}}, {Name: "globex", Hosts: []string{"API.example.com"}}},
+		{{Name: "acme", Hosts: []string{"*."}}},
+	}
+	for _, tenants := range tests {
+		if _, err := NewResolver(tenants, ""); err == nil {
+			t.Errorf("Expected tenants %+v to be rejected", tenants)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	resolver, err := NewResolver([]types.TenantConfig{
+		{Name: "acme", Hosts: []string{"acme.example.com"}},
+		{Name: "globex", Hosts: []string{"*.globex.example.com"}},
+	}, "X-Team")
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+
+	tests := []struct {
+		host, header, expected string
+	}{
+		{"acme.example.com", "", "acme"},
+		{"ACME.example.com:443", "", "acme"},
+		{"a.b.globex.example.com", "", "globex"},
+		{"globex.example.com", "", ""},
+		{"api.example.com", "globex", "globex"},
+		{"api.example.com", "initech", ""},
+		{"acme.example.com", "globex", "acme"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = test.host
+		req.Header.Set("X-Team", test.header)
+		if tenant := resolver.Resolve(req); tenant != test.expected {
+			t.Errorf("%s with header %q: expected tenant %q, got %q", test.host, test.header, test.expected, tenant)
+		}
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	resolver, err := NewResolver([]types.TenantConfig{{Name: "acme"}}, "")
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+	if owner := resolver.Owner("acme_orders"); owner != "acme" {
+		t.Errorf("Expected acme_orders to belong to acme, got %q", owner)
+	}
+	if owner := resolver.Owner("traffic_policy"); owner != "" {
+		t.Errorf("Expected traffic_policy to be shared, got %q", owner)
+	}
+	if !resolver.Visible("acme", "traffic_policy") || resolver.Visible("globex", "acme_orders") || !resolver.Visible("", "acme_orders") {
+		t.Error("Unexpected visibility")
+	}
+
+	config := &types.RoutesConfig{Routes: []types.RouteConfig{
+		{RouteName: "/orders", Method: "POST", Tenant: "acme", RequestSchemaRef: "acme_order@v1", Cache: &types.CacheConfig{TTLPolicy: "acme_ttl"}},
+	}}
+	if err := resolver.Validate(config); err != nil {
+		t.Errorf("Expected own names to be allowed, got %v", err)
+	}
+	config.Routes[0].Tenant = ""
+	if err := resolver.Validate(config); err == nil {
+		t.Error("Expected a shared route using tenant names to be rejected")
+	}
+}
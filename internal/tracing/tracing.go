@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation names the tracer of the control plane
+const instrumentation = "dynamiccontrol"
+
+func init() {
+	// W3C trace context and baggage are propagated even when no exporter is
+	// configured, so the control plane never breaks a trace
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Setup exports spans over OTLP/HTTP, configured by the standard
+// OTEL_EXPORTER_OTLP_* environment variables. The returned function flushes
+// and stops the exporter.
+func Setup(ctx context.Context, serviceName, version string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+			attribute.String("service.version", version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Middleware starts a server span for every request, continuing the trace
+// and baggage of the caller
+func Middleware() gin.HandlerFunc {
+	tracer := otel.Tracer(instrumentation)
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracer.Start(ctx, c.Request.Method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("http.method", c.Request.Method),
+			attribute.String("http.target", c.Request.URL.Path),
+		))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		span.SetAttributes(attribute.Int("http.status_code", c.Writer.Status()))
+	}
+}
+
+// Annotate names the request span after its route and sets the given attributes on it
+func Annotate(ctx context.Context, route string, attributes map[string]string) {
+	span := trace.SpanFromContext(ctx)
+	span.SetName(route)
+	values := make([]attribute.KeyValue, 0, len(attributes)+1)
+	values = append(values, attribute.String("http.route", route))
+	for key, value := range attributes {
+		values = append(values, attribute.String(key, value))
+	}
+	span.SetAttributes(values...)
+}
+
+// WithBaggage returns a context whose baggage carries the given members in
+// addition to the caller's
+func WithBaggage(ctx context.Context, members map[string]string) (context.Context, error) {
+	bag := baggage.FromContext(ctx)
+	for key, value := range members {
+		member, err := baggage.NewMember(key, url.QueryEscape(value))
+		if err != nil {
+			return ctx, fmt.Errorf("invalid baggage member %s: %w", key, err)
+		}
+		if bag, err = bag.SetMember(member); err != nil {
+			return ctx, fmt.Errorf("failed to add baggage member %s: %w", key, err)
+		}
+	}
+	return baggage.ContextWithBaggage(ctx, bag), nil
+}
+
+// ValidateBaggageKey checks that a key is usable as a baggage member key
+func ValidateBaggageKey(key string) error {
+	if _, err := baggage.NewMember(key, ""); err != nil {
+		return fmt.Errorf("invalid baggage key %q: %w", key, err)
+	}
+	return nil
+}
+
+// Inject writes the trace context and baggage of ctx to outgoing headers
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,312 @@
+package traffic
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/types"
+)
+
+// Traffic types and priorities accepted for rules, priorities from lowest
+// to highest
+var (
+	Types      = []string{"incoming", "outgoing", "internal"}
+	Priorities = []string{"low", "medium", "high", "critical"}
+)
+
+// Changes reported to listeners
+const (
+	ChangeCreated     = "created"
+	ChangeDeleted     = "deleted"
+	ChangeActivated   = "activated"
+	ChangeDeactivated = "deactivated"
+)
+
+// Rule statuses: accepted rules are in effect, pending rules wait for their
+// schedule window to open and expired rules are past its end
+const (
+	StatusAccepted = "accepted"
+	StatusPending  = "pending"
+	StatusExpired  = "expired"
+)
+
+// ErrNotFound is returned for unknown rule IDs
+var ErrNotFound = errors.New("traffic rule not found")
+
+// Rule is an accepted traffic request of a service. Volume is read as
+// requests per second by traffic reconciliation, while the rule is in effect.
+type Rule struct {
+	ID          string                 `json:"id"`
+	ServiceID   string                 `json:"serviceId"`
+	TrafficType string                 `json:"trafficType"`
+	Volume      float64                `json:"volume"`
+	Priority    string                 `json:"priority"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Schedule    *types.TrafficSchedule `json:"schedule,omitempty"`
+	Status      string                 `json:"status"`
+	// OpensAt is when the window of a pending rule opens next
+	OpensAt   *time.Time `json:"opensAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+
+	window *window
+}
+
+// at returns the rule with its status at now
+func (r Rule) at(now time.Time) Rule {
+	r.Status, r.OpensAt = r.window.state(now)
+	return r
+}
+
+// Controller stores the traffic rules of services. Rules are listed highest
+// priority first, then oldest first. Scheduled rules take effect while their
+// window is open.
+type Controller struct {
+	mu       sync.RWMutex
+	rules    map[string][]Rule
+	onChange []func(change string, rule Rule)
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// NewController creates an empty traffic controller
+func NewController() *Controller {
+	return &Controller{rules: make(map[string][]Rule), stop: make(chan struct{})}
+}
+
+// Start activates and deactivates scheduled rules in the background
+func (c *Controller) Start() {
+	go func() {
+		ticker := time.NewTicker(DefaultScheduleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				c.Refresh(now)
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background scheduling
+func (c *Controller) Stop() {
+	c.once.Do(func() { close(c.stop) })
+}
+
+// Refresh updates the status of scheduled rules at now, reporting rules
+// taking or leaving effect to listeners
+func (c *Controller) Refresh(now time.Time) {
+	var activated, deactivated []Rule
+	c.mu.Lock()
+	for _, rules := range c.rules {
+		for i, rule := range rules {
+			current := rule.at(now)
+			if current.Status == rule.Status {
+				rules[i].OpensAt = current.OpensAt
+				continue
+			}
+			rules[i] = current
+			if current.Status == StatusAccepted {
+				activated = append(activated, current)
+			} else if rule.Status == StatusAccepted {
+				deactivated = append(deactivated, current)
+			}
+		}
+	}
+	listeners := c.onChange
+	c.mu.Unlock()
+
+	for _, fn := range listeners {
+		for _, rule := range activated {
+			fn(ChangeActivated, rule)
+		}
+		for _, rule := range deactivated {
+			fn(ChangeDeactivated, rule)
+		}
+	}
+}
+
+// OnChange registers a function called after a rule is created or deleted
+func (c *Controller) OnChange(fn func(change string, rule Rule)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onChange = append(c.onChange, fn)
+}
+
+// Add validates a traffic request and stores it as a rule of the service.
+// Rules with a schedule are pending until their window opens.
+func (c *Controller) Add(service string, request types.TrafficRequest) (Rule, error) {
+	if err := Validate(request); err != nil {
+		return Rule{}, err
+	}
+	if service == "" {
+		return Rule{}, fmt.Errorf("service is required")
+	}
+	now := time.Now().UTC()
+	window, err := parseSchedule(request.Schedule, now)
+	if err != nil {
+		return Rule{}, err
+	}
+	rule := Rule{
+		ID:          newID(),
+		ServiceID:   service,
+		TrafficType: request.TrafficType,
+		Volume:      request.Volume,
+		Priority:    request.Priority,
+		Metadata:    request.Metadata,
+		Schedule:    request.Schedule,
+		CreatedAt:   now,
+		window:      window,
+	}.at(now)
+	if rule.Status == StatusExpired {
+		return Rule{}, fmt.Errorf("schedule window never opens before its end")
+	}
+
+	c.mu.Lock()
+	rules := append(c.rules[service], rule)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rank(rules[i].Priority) > rank(rules[j].Priority)
+	})
+	c.rules[service] = rules
+	metrics.TrafficRules.WithLabelValues(service).Set(float64(len(rules)))
+	listeners := c.onChange
+	c.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(ChangeCreated, rule)
+	}
+	return rule, nil
+}
+
+// Rules returns the rules of a service with their current status
+func (c *Controller) Rules(service string) []Rule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := time.Now()
+	rules := make([]Rule, 0, len(c.rules[service]))
+	for _, rule := range c.rules[service] {
+		rules = append(rules, rule.at(now))
+	}
+	return rules
+}
+
+// Rule returns a single rule of a service with its current status
+func (c *Controller) Rule(service, id string) (Rule, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, rule := range c.rules[service] {
+		if rule.ID == id {
+			return rule.at(time.Now()), nil
+		}
+	}
+	return Rule{}, ErrNotFound
+}
+
+// Delete removes the rule with the given id, or every rule of the service
+// when id is empty, and returns the removed rules
+func (c *Controller) Delete(service, id string) ([]Rule, error) {
+	c.mu.Lock()
+	var removed, kept []Rule
+	for _, rule := range c.rules[service] {
+		if id == "" || rule.ID == id {
+			removed = append(removed, rule)
+		} else {
+			kept = append(kept, rule)
+		}
+	}
+	if id != "" && len(removed) == 0 {
+		c.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	if len(kept) == 0 {
+		delete(c.rules, service)
+		metrics.TrafficRules.DeleteLabelValues(service)
+	} else {
+		c.rules[service] = kept
+		metrics.TrafficRules.WithLabelValues(service).Set(float64(len(kept)))
+	}
+	listeners := c.onChange
+	c.mu.Unlock()
+
+	for _, rule := range removed {
+		for _, fn := range listeners {
+			fn(ChangeDeleted, rule)
+		}
+	}
+	return removed, nil
+}
+
+// Services returns the services with rules, sorted
+func (c *Controller) Services() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	services := make([]string, 0, len(c.rules))
+	for service := range c.rules {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// Volumes sums the volume of the rules in effect of every service, leaving
+// out services whose rules are all pending or expired
+func (c *Controller) Volumes() map[string]float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := time.Now()
+	volumes := make(map[string]float64, len(c.rules))
+	for service, rules := range c.rules {
+		for _, rule := range rules {
+			if rule.at(now).Status == StatusAccepted {
+				volumes[service] += rule.Volume
+			}
+		}
+	}
+	return volumes
+}
+
+// Validate checks the traffic type, volume and priority of a traffic request
+func Validate(request types.TrafficRequest) error {
+	if !contains(Types, request.TrafficType) {
+		return fmt.Errorf("unsupported traffic type %q, expected one of %v", request.TrafficType, Types)
+	}
+	if request.Volume < 0 {
+		return fmt.Errorf("volume must not be negative")
+	}
+	if !contains(Priorities, request.Priority) {
+		return fmt.Errorf("unsupported priority %q, expected one of %v", request.Priority, Priorities)
+	}
+	return nil
+}
+
+// rank orders priorities from lowest to highest
+func rank(priority string) int {
+	return indexOf(Priorities, priority)
+}
+
+func contains(values []string, value string) bool {
+	return indexOf(values, value) >= 0
+}
+
+func indexOf(values []string, value string) int {
+	for i, candidate := range values {
+		if candidate == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// newID returns a random rule ID
+func newID() string {
+	buf := make([]byte, 6)
+	rand.Read(buf)
+	return "rule-" + hex.EncodeToString(buf)
+}
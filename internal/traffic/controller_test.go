@@ -0,0 +1,146 @@
+package traffic
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestRulesAreOrderedByPriority(t *testing.T) {
+	controller := NewController()
+	var changes []string
+	controller.OnChange(func(change string, rule Rule) { changes = append(changes, change+" "+rule.Priority) })
+
+	for _, priority := range []string{"low", "critical", "medium", "critical"} {
+		if _, err := controller.Add("billing", types.TrafficRequest{TrafficType: "incoming", Volume: 10, Priority: priority}); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+	}
+	controller.Add("search", types.TrafficRequest{TrafficType: "outgoing", Volume: 2.5, Priority: "high"})
+
+	rules := controller.Rules("billing")
+	var priorities []string
+	for _, rule := range rules {
+		priorities = append(priorities, rule.Priority)
+	}
+	if len(rules) != 4 || priorities[0] != "critical" || priorities[1] != "critical" || priorities[2] != "medium" || priorities[3] != "low" {
+		t.Errorf("Expected rules highest priority first, got %v", priorities)
+	}
+	if rules[0].CreatedAt.After(rules[1].CreatedAt) {
+		t.Error("Expected rules of the same priority oldest first")
+	}
+	if volumes := controller.Volumes(); volumes["billing"] != 40 || volumes["search"] != 2.5 {
+		t.Errorf("Unexpected volumes %v", volumes)
+	}
+	if len(changes) != 5 || changes[0] != "created low" {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+}
+
+func TestDeleteRules(t *testing.T) {
+	controller := NewController()
+	first, _ := controller.Add("billing", types.TrafficRequest{TrafficType: "incoming", Volume: 1, Priority: "low"})
+	controller.Add("billing", types.TrafficRequest{TrafficType: "internal", Volume: 2, Priority: "high"})
+
+	if rule, err := controller.Rule("billing", first.ID); err != nil || rule.Volume != 1 {
+		t.Errorf("Expected the rule, got %+v, %v", rule, err)
+	}
+	if _, err := controller.Rule("search", first.ID); err != ErrNotFound {
+		t.Errorf("Expected rules of other services to be hidden, got %v", err)
+	}
+	if removed, err := controller.Delete("billing", first.ID); err != nil || len(removed) != 1 {
+		t.Errorf("Expected the rule to be deleted, got %v, %v", removed, err)
+	}
+	if _, err := controller.Delete("billing", first.ID); err != ErrNotFound {
+		t.Errorf("Expected deleting twice to fail, got %v", err)
+	}
+	if removed, err := controller.Delete("billing", ""); err != nil || len(removed) != 1 {
+		t.Errorf("Expected the remaining rule to be deleted, got %v, %v", removed, err)
+	}
+	if services := controller.Services(); len(services) != 0 {
+		t.Errorf("Expected no services left, got %v", services)
+	}
+	if removed, err := controller.Delete("billing", ""); err != nil || len(removed) != 0 {
+		t.Errorf("Expected clearing a service without rules to succeed, got %v, %v", removed, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []types.TrafficRequest{
+		{TrafficType: "sideways", Volume: 1, Priority: "low"},
+		{TrafficType: "incoming", Volume: -1, Priority: "low"},
+		{TrafficType: "incoming", Volume: 1, Priority: "urgent"},
+	}
+	for _, request := range cases {
+		if err := Validate(request); err == nil {
+			t.Errorf("Expected %+v to be invalid", request)
+		}
+	}
+	if err := Validate(types.TrafficRequest{TrafficType: "internal", Volume: 0, Priority: "critical"}); err != nil {
+		t.Errorf("Expected a valid request, got %v", err)
+	}
+}
+
+func TestScheduledRules(t *testing.T) {
+	controller := NewController()
+	var changes []string
+	controller.OnChange(func(change string, rule Rule) { changes = append(changes, change) })
+
+	now := time.Now()
+	start, end := now.Add(time.Hour), now.Add(2*time.Hour)
+	rule, err := controller.Add("billing", types.TrafficRequest{
+		TrafficType: "incoming", Volume: 5, Priority: "high",
+		Schedule: &types.TrafficSchedule{Start: &start, End: &end},
+	})
+	if err != nil || rule.Status != StatusPending || !rule.OpensAt.Equal(start) {
+		t.Fatalf("Expected the rule to be pending until its start, got %+v, %v", rule, err)
+	}
+	if volumes := controller.Volumes(); len(volumes) != 0 {
+		t.Errorf("Expected pending rules not to count, got %v", volumes)
+	}
+
+	controller.Refresh(start.Add(time.Minute))
+	controller.Refresh(start.Add(2 * time.Minute))
+	controller.Refresh(end)
+	if strings.Join(changes, ",") != "created,activated,deactivated" {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+}
+
+func TestCronWindows(t *testing.T) {
+	window, err := parseSchedule(&types.TrafficSchedule{Cron: "0 9 * * *", DurationSeconds: 3600}, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to parse schedule: %v", err)
+	}
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local)
+	cases := []struct {
+		at     time.Time
+		status string
+		opens  time.Time
+	}{
+		{day.Add(8 * time.Hour), StatusPending, day.Add(9 * time.Hour)},
+		{day.Add(9 * time.Hour), StatusAccepted, time.Time{}},
+		{day.Add(9*time.Hour + 59*time.Minute), StatusAccepted, time.Time{}},
+		{day.Add(10 * time.Hour), StatusPending, day.Add(33 * time.Hour)},
+	}
+	for _, c := range cases {
+		status, opens := window.state(c.at)
+		if status != c.status || (opens != nil) != !c.opens.IsZero() || (opens != nil && !opens.Equal(c.opens)) {
+			t.Errorf("At %s: expected %s opening %s, got %s %v", c.at, c.status, c.opens, status, opens)
+		}
+	}
+
+	past := time.Now().Add(-time.Minute)
+	for _, schedule := range []types.TrafficSchedule{
+		{Cron: "0 9 * * *"},
+		{Cron: "every day", DurationSeconds: 60},
+		{DurationSeconds: 60},
+		{End: &past},
+	} {
+		if _, err := parseSchedule(&schedule, time.Now()); err == nil {
+			t.Errorf("Expected %+v to be rejected", schedule)
+		}
+	}
+}
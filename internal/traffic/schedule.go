@@ -0,0 +1,88 @@
+package traffic
+
+import (
+	"fmt"
+	"time"
+
+	"dynamiccontrol/internal/types"
+
+	"github.com/robfig/cron/v3"
+)
+
+// DefaultScheduleInterval is how often scheduled rules are checked for
+// opening or closing windows
+const DefaultScheduleInterval = time.Second
+
+// window is a parsed traffic schedule
+type window struct {
+	start    *time.Time
+	end      *time.Time
+	cron     cron.Schedule
+	duration time.Duration
+}
+
+// parseSchedule validates a schedule at now and parses its cron expression,
+// in the standard five field format
+func parseSchedule(schedule *types.TrafficSchedule, now time.Time) (*window, error) {
+	if schedule == nil {
+		return nil, nil
+	}
+	parsed := &window{start: schedule.Start, end: schedule.End}
+	if schedule.Start != nil && schedule.End != nil && !schedule.End.After(*schedule.Start) {
+		return nil, fmt.Errorf("schedule end must be after its start")
+	}
+	if schedule.End != nil && !schedule.End.After(now) {
+		return nil, fmt.Errorf("schedule end is in the past")
+	}
+	if schedule.Cron == "" {
+		if schedule.DurationSeconds != 0 {
+			return nil, fmt.Errorf("schedule durationSeconds requires a cron expression")
+		}
+		return parsed, nil
+	}
+	expression, err := cron.ParseStandard(schedule.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule cron expression: %w", err)
+	}
+	if schedule.DurationSeconds <= 0 {
+		return nil, fmt.Errorf("schedule cron expression requires a positive durationSeconds")
+	}
+	parsed.cron = expression
+	parsed.duration = time.Duration(schedule.DurationSeconds) * time.Second
+	return parsed, nil
+}
+
+// state returns the status of a rule with the window at now, and for
+// pending rules when the window opens next
+func (w *window) state(now time.Time) (string, *time.Time) {
+	if w == nil {
+		return StatusAccepted, nil
+	}
+	if w.end != nil && !now.Before(*w.end) {
+		return StatusExpired, nil
+	}
+	if w.start != nil && now.Before(*w.start) {
+		opens := *w.start
+		if w.cron != nil {
+			opens = w.cron.Next(opens.Add(-time.Second))
+		}
+		return w.pendingUntil(opens)
+	}
+	if w.cron == nil {
+		return StatusAccepted, nil
+	}
+	// The window is open when the expression matched within the last duration
+	if !w.cron.Next(now.Add(-w.duration)).After(now) {
+		return StatusAccepted, nil
+	}
+	return w.pendingUntil(w.cron.Next(now))
+}
+
+// pendingUntil reports a window opening at opens, or expired when it would
+// open only after its end
+func (w *window) pendingUntil(opens time.Time) (string, *time.Time) {
+	if opens.IsZero() || (w.end != nil && !opens.Before(*w.end)) {
+		return StatusExpired, nil
+	}
+	return StatusPending, &opens
+}
@@ -0,0 +1,107 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ReferencePrefix marks a template string as a reference into the input document
+const ReferencePrefix = "$."
+
+// Apply renders a mapping template against an input document.
+//
+// Objects and arrays in the template are copied recursively. String values
+// starting with "$." are replaced by the value found at that dotted path in
+// the input (e.g. "$.upstreams.profile.body.name"); numeric path segments
+// index into arrays. A leading "$$" escapes a literal dollar sign. All other
+// values are copied unchanged. References that cannot be resolved render as
+// null and are reported in the returned errors.
+func Apply(template interface{}, input map[string]interface{}) (interface{}, []error) {
+	var errors []error
+	result := apply(template, input, "", &errors)
+	return result, errors
+}
+
+// apply renders a single template node, collecting resolution errors
+func apply(template interface{}, input map[string]interface{}, location string, errors *[]error) interface{} {
+	switch value := template.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			result[key] = apply(item, input, joinLocation(location, key), errors)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = apply(item, input, fmt.Sprintf("%s[%d]", location, i), errors)
+		}
+		return result
+	case string:
+		if strings.HasPrefix(value, "$$") {
+			return value[1:]
+		}
+		if !strings.HasPrefix(value, ReferencePrefix) {
+			return value
+		}
+		resolved, err := Lookup(input, strings.TrimPrefix(value, ReferencePrefix))
+		if err != nil {
+			*errors = append(*errors, fmt.Errorf("%s: %w", joinLocation(location, ""), err))
+			return nil
+		}
+		return resolved
+	default:
+		return value
+	}
+}
+
+// joinLocation builds a readable location of a node inside the template
+func joinLocation(location, key string) string {
+	switch {
+	case location == "":
+		if key == "" {
+			return "(root)"
+		}
+		return key
+	case key == "":
+		return location
+	default:
+		return location + "." + key
+	}
+}
+
+// Lookup resolves a dotted path inside a decoded JSON document
+func Lookup(document interface{}, path string) (interface{}, error) {
+	current := document
+	if path == "" {
+		return current, nil
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, exists := node[segment]
+			if !exists {
+				return nil, fmt.Errorf("path %q not found", path)
+			}
+			current = next
+		case map[string]string:
+			next, exists := node[segment]
+			if !exists {
+				return nil, fmt.Errorf("path %q not found", path)
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("invalid index %q in path %q", segment, path)
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("path %q not found", path)
+		}
+	}
+
+	return current, nil
+}
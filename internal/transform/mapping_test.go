@@ -0,0 +1,88 @@
+package transform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func sampleInput() map[string]interface{} {
+	return map[string]interface{}{
+		"request": map[string]interface{}{
+			"params": map[string]string{"serviceId": "service123"},
+		},
+		"upstreams": map[string]interface{}{
+			"profile": map[string]interface{}{
+				"body": map[string]interface{}{
+					"name": "payments",
+					"tags": []interface{}{"critical", "pci"},
+				},
+			},
+		},
+	}
+}
+
+func TestApplyResolvesReferences(t *testing.T) {
+	template := map[string]interface{}{
+		"id":       "$.request.params.serviceId",
+		"name":     "$.upstreams.profile.body.name",
+		"firstTag": "$.upstreams.profile.body.tags.0",
+		"kind":     "service",
+		"price":    "$$5",
+		"count":    float64(2),
+		"nested":   []interface{}{"$.upstreams.profile.body.name", true},
+	}
+
+	output, errs := Apply(template, sampleInput())
+	if len(errs) != 0 {
+		t.Fatalf("Apply() returned errors: %v", errs)
+	}
+
+	expected := map[string]interface{}{
+		"id":       "service123",
+		"name":     "payments",
+		"firstTag": "critical",
+		"kind":     "service",
+		"price":    "$5",
+		"count":    float64(2),
+		"nested":   []interface{}{"payments", true},
+	}
+
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("Apply() = %v, want %v", output, expected)
+	}
+}
+
+func TestApplyReportsUnresolvedReferences(t *testing.T) {
+	template := map[string]interface{}{
+		"missing": "$.upstreams.usage.body",
+		"badIdx":  "$.upstreams.profile.body.tags.5",
+	}
+
+	output, errs := Apply(template, sampleInput())
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+
+	result := output.(map[string]interface{})
+	if result["missing"] != nil || result["badIdx"] != nil {
+		t.Errorf("unresolved references should render as null, got %v", result)
+	}
+}
+
+func TestApplyWholeDocumentReference(t *testing.T) {
+	input := sampleInput()
+	output, errs := Apply("$.", input)
+	if len(errs) != 0 {
+		t.Fatalf("Apply() returned errors: %v", errs)
+	}
+
+	if !reflect.DeepEqual(output, input) {
+		t.Errorf("Apply(\"$.\") should return the whole input")
+	}
+}
+
+func TestLookupRejectsScalarTraversal(t *testing.T) {
+	if _, err := Lookup(map[string]interface{}{"a": "b"}, "a.b"); err == nil {
+		t.Error("Lookup should fail when traversing into a scalar")
+	}
+}
@@ -0,0 +1,265 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+)
+
+// JSON Patch operations
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+	PatchMove    = "move"
+	PatchCopy    = "copy"
+	PatchTest    = "test"
+)
+
+// ValidatePatch checks the operations and pointers of a JSON Patch
+func ValidatePatch(patch []types.PatchOperation) error {
+	for i, operation := range patch {
+		if _, err := parsePointer(operation.Path); err != nil {
+			return fmt.Errorf("patch operation %d: %w", i, err)
+		}
+		switch operation.Op {
+		case PatchAdd, PatchRemove, PatchReplace, PatchTest:
+		case PatchMove, PatchCopy:
+			if _, err := parsePointer(operation.From); err != nil {
+				return fmt.Errorf("patch operation %d: from: %w", i, err)
+			}
+			if operation.Op == PatchMove && strings.HasPrefix(operation.Path+"/", operation.From+"/") {
+				return fmt.Errorf("patch operation %d: cannot move %s into itself", i, operation.From)
+			}
+		default:
+			return fmt.Errorf("patch operation %d: unsupported op %q", i, operation.Op)
+		}
+	}
+	return nil
+}
+
+// ApplyPatch applies a JSON Patch to a copy of a decoded JSON document. The
+// patch is atomic: when an operation fails, including a failed test, the
+// error is returned and the document is left unchanged.
+func ApplyPatch(document interface{}, patch []types.PatchOperation) (interface{}, error) {
+	patched := copyValue(document)
+	for i, operation := range patch {
+		var err error
+		if patched, err = applyOperation(patched, operation); err != nil {
+			return document, fmt.Errorf("patch operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+	return patched, nil
+}
+
+// applyOperation applies a single operation and returns the new document
+func applyOperation(document interface{}, operation types.PatchOperation) (interface{}, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return document, err
+	}
+
+	switch operation.Op {
+	case PatchAdd:
+		return addValue(document, path, copyValue(operation.Value))
+	case PatchRemove:
+		document, _, err = removeValue(document, path)
+		return document, err
+	case PatchReplace:
+		if document, _, err = removeValue(document, path); err != nil {
+			return document, err
+		}
+		return addValue(document, path, copyValue(operation.Value))
+	case PatchMove, PatchCopy:
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return document, err
+		}
+		var value interface{}
+		if operation.Op == PatchMove {
+			document, value, err = removeValue(document, from)
+		} else {
+			value, err = getValue(document, from)
+			value = copyValue(value)
+		}
+		if err != nil {
+			return document, err
+		}
+		return addValue(document, path, value)
+	case PatchTest:
+		value, err := getValue(document, path)
+		if err != nil {
+			return document, err
+		}
+		if !jsonEqual(value, operation.Value) {
+			return document, fmt.Errorf("test failed")
+		}
+		return document, nil
+	default:
+		return document, fmt.Errorf("unsupported op %q", operation.Op)
+	}
+}
+
+// parsePointer splits a JSON Pointer into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// getValue returns the value at a pointer
+func getValue(node interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch typed := node.(type) {
+		case map[string]interface{}:
+			child, exists := typed[token]
+			if !exists {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			node = child
+		case []interface{}:
+			index, err := arrayIndex(token, len(typed)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = typed[index]
+		default:
+			return nil, fmt.Errorf("cannot descend into %q of a scalar", token)
+		}
+	}
+	return node, nil
+}
+
+// addValue adds or replaces an object member or inserts an array element,
+// returning the updated node
+func addValue(node interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	token, last := tokens[0], len(tokens) == 1
+
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		if last {
+			typed[token] = value
+			return typed, nil
+		}
+		child, exists := typed[token]
+		if !exists {
+			return node, fmt.Errorf("member %q not found", token)
+		}
+		updated, err := addValue(child, tokens[1:], value)
+		typed[token] = updated
+		return typed, err
+	case []interface{}:
+		if last {
+			if token == "-" {
+				return append(typed, value), nil
+			}
+			index, err := arrayIndex(token, len(typed))
+			if err != nil {
+				return node, err
+			}
+			typed = append(typed, nil)
+			copy(typed[index+1:], typed[index:])
+			typed[index] = value
+			return typed, nil
+		}
+		index, err := arrayIndex(token, len(typed)-1)
+		if err != nil {
+			return node, err
+		}
+		updated, err := addValue(typed[index], tokens[1:], value)
+		typed[index] = updated
+		return typed, err
+	default:
+		return node, fmt.Errorf("cannot add %q to a scalar", token)
+	}
+}
+
+// removeValue removes the value at a pointer, returning the updated node and
+// the removed value
+func removeValue(node interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, node, nil
+	}
+	token, last := tokens[0], len(tokens) == 1
+
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		child, exists := typed[token]
+		if !exists {
+			return node, nil, fmt.Errorf("member %q not found", token)
+		}
+		if last {
+			delete(typed, token)
+			return typed, child, nil
+		}
+		updated, removed, err := removeValue(child, tokens[1:])
+		typed[token] = updated
+		return typed, removed, err
+	case []interface{}:
+		index, err := arrayIndex(token, len(typed)-1)
+		if err != nil {
+			return node, nil, err
+		}
+		if last {
+			removed := typed[index]
+			return append(typed[:index], typed[index+1:]...), removed, nil
+		}
+		updated, removed, err := removeValue(typed[index], tokens[1:])
+		typed[index] = updated
+		return typed, removed, err
+	default:
+		return node, nil, fmt.Errorf("cannot remove %q from a scalar", token)
+	}
+}
+
+// arrayIndex parses an array index token no greater than max
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return index, nil
+}
+
+// copyValue deep copies a decoded JSON value
+func copyValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(typed))
+		for key, child := range typed {
+			copied[key] = copyValue(child)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(typed))
+		for i, child := range typed {
+			copied[i] = copyValue(child)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// jsonEqual compares two values by their JSON encoding, so numbers of
+// different Go types compare equal
+func jsonEqual(a, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
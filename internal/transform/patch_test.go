@@ -0,0 +1,89 @@
+package transform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+func decode(t *testing.T, document string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		t.Fatalf("Failed to decode %s: %v", document, err)
+	}
+	return value
+}
+
+func TestApplyPatch(t *testing.T) {
+	cases := []struct {
+		name     string
+		document string
+		patch    string
+		expected string
+	}{
+		{"add member", `{"a":1}`, `[{"op":"add","path":"/features","value":{"beta":true}}]`, `{"a":1,"features":{"beta":true}}`},
+		{"insert element", `{"items":[1,3]}`, `[{"op":"add","path":"/items/1","value":2}]`, `{"items":[1,2,3]}`},
+		{"append element", `{"items":[1]}`, `[{"op":"add","path":"/items/-","value":2}]`, `{"items":[1,2]}`},
+		{"remove member", `{"a":1,"legacyId":"x"}`, `[{"op":"remove","path":"/legacyId"}]`, `{"a":1}`},
+		{"remove element", `{"items":[1,2,3]}`, `[{"op":"remove","path":"/items/0"}]`, `{"items":[2,3]}`},
+		{"replace", `{"status":"PENDING"}`, `[{"op":"replace","path":"/status","value":"pending"}]`, `{"status":"pending"}`},
+		{"move", `{"old":{"id":1}}`, `[{"op":"move","from":"/old/id","path":"/id"}]`, `{"id":1,"old":{}}`},
+		{"copy", `{"id":1}`, `[{"op":"copy","from":"/id","path":"/ref"}]`, `{"id":1,"ref":1}`},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"remove","path":"/m~0n"}]`, `{}`},
+		{"passing test", `{"version":2}`, `[{"op":"test","path":"/version","value":2},{"op":"add","path":"/v2","value":true}]`, `{"version":2,"v2":true}`},
+	}
+	for _, tc := range cases {
+		var patch []types.PatchOperation
+		if err := json.Unmarshal([]byte(tc.patch), &patch); err != nil {
+			t.Fatalf("%s: failed to decode patch: %v", tc.name, err)
+		}
+		if err := ValidatePatch(patch); err != nil {
+			t.Errorf("%s: expected a valid patch, got %v", tc.name, err)
+			continue
+		}
+		patched, err := ApplyPatch(decode(t, tc.document), patch)
+		if err != nil {
+			t.Errorf("%s: failed to apply patch: %v", tc.name, err)
+			continue
+		}
+		if !jsonEqual(patched, decode(t, tc.expected)) {
+			encoded, _ := json.Marshal(patched)
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.expected, encoded)
+		}
+	}
+}
+
+func TestApplyPatchIsAtomic(t *testing.T) {
+	document := decode(t, `{"version":1,"items":[1]}`)
+	patch := []types.PatchOperation{
+		{Op: PatchAdd, Path: "/items/-", Value: 2},
+		{Op: PatchTest, Path: "/version", Value: 2},
+	}
+	patched, err := ApplyPatch(document, patch)
+	if err == nil {
+		t.Fatalf("Expected a failed test operation to fail the patch")
+	}
+	if !jsonEqual(patched, decode(t, `{"version":1,"items":[1]}`)) || !jsonEqual(document, patched) {
+		t.Errorf("Expected the document to be unchanged, got %v", patched)
+	}
+
+	if _, err := ApplyPatch(document, []types.PatchOperation{{Op: PatchRemove, Path: "/missing"}}); err == nil {
+		t.Errorf("Expected removing a missing member to fail")
+	}
+}
+
+func TestValidatePatchRejectsInvalidOperations(t *testing.T) {
+	invalid := [][]types.PatchOperation{
+		{{Op: "merge", Path: "/a"}},
+		{{Op: PatchAdd, Path: "a"}},
+		{{Op: PatchMove, From: "/a", Path: "/a/b"}},
+		{{Op: PatchCopy, From: "b", Path: "/a"}},
+	}
+	for _, patch := range invalid {
+		if err := ValidatePatch(patch); err == nil {
+			t.Errorf("Expected patch %+v to be rejected", patch)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// startTime is used to report process uptime from templates
+var startTime = time.Now()
+
+// idSequence keeps generated IDs unique within the same second
+var idSequence uint64
+
+// TemplateData is the data available to mock response templates
+type TemplateData struct {
+	Route   string            `json:"route"`
+	Method  string            `json:"method"`
+	Params  map[string]string `json:"params"`
+	Headers map[string]string `json:"headers"`
+	Query   map[string]string `json:"query"`
+	Body    interface{}       `json:"body"`
+	Record  interface{}       `json:"record,omitempty"`
+	Records interface{}       `json:"records,omitempty"`
+	Vars    map[string]string `json:"vars,omitempty"`
+}
+
+// templateFuncs are the helper functions available to templates
+var templateFuncs = template.FuncMap{
+	"now": func() string {
+		return time.Now().UTC().Format(time.RFC3339)
+	},
+	"uptime": func() int64 {
+		return int64(time.Since(startTime).Seconds())
+	},
+	"id": func(prefix string) string {
+		return fmt.Sprintf("%s-%s%d", prefix, time.Now().Format("20060102150405"), atomic.AddUint64(&idSequence, 1))
+	},
+	"json": func(value interface{}) (string, error) {
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	},
+	"default": func(fallback, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// ParseTemplate compiles a response template
+func ParseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// RenderTemplate executes a compiled template and decodes its output as JSON.
+// Output that is not valid JSON is returned as a string.
+func RenderTemplate(tmpl *template.Template, data TemplateData) (interface{}, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %w", tmpl.Name(), err)
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		return buf.String(), nil
+	}
+	return decoded, nil
+}
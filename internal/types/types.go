@@ -1,6 +1,10 @@
 package types
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -10,12 +14,365 @@ type RouteConfig struct {
 	Method         string                 `json:"method"`
 	RequestSchema  map[string]interface{} `json:"requestSchema"`
 	ResponseSchema map[string]interface{} `json:"responseSchema"`
-	Policies       []string               `json:"policies"`
+	// QuerySchema and HeaderSchema describe the query parameters and the
+	// lowercase request header names of a route as a JSON object
+	QuerySchema  map[string]interface{} `json:"querySchema,omitempty"`
+	HeaderSchema map[string]interface{} `json:"headerSchema,omitempty"`
+	// RequestSchemaRef and ResponseSchemaRef name registered schemas, such
+	// as "traffic-request@v2", used instead of the inline schemas
+	RequestSchemaRef  string `json:"requestSchemaRef,omitempty"`
+	ResponseSchemaRef string `json:"responseSchemaRef,omitempty"`
+	// CandidateRequestSchema is validated alongside RequestSchema without
+	// being enforced, to measure the impact of a schema change before rollout
+	CandidateRequestSchema map[string]interface{} `json:"candidateRequestSchema,omitempty"`
+	Policies               []string               `json:"policies"`
+	Canonicalize           *CanonicalizeConfig    `json:"canonicalize,omitempty"`
+	Handler                string                 `json:"handler,omitempty"`
+	Aggregate              *AggregateConfig       `json:"aggregate,omitempty"`
+	Upstreams              []UpstreamTarget       `json:"upstreams,omitempty"`
+	Retry                  *RetryConfig           `json:"retry,omitempty"`
+	TimeoutMs              int                    `json:"timeoutMs,omitempty"`
+	MockResponse           *MockResponseConfig    `json:"mockResponse,omitempty"`
+	Notifications          *NotificationConfig    `json:"notifications,omitempty"`
+	Faults                 *FaultConfig           `json:"faults,omitempty"`
+	Headers                *HeaderPolicy          `json:"headers,omitempty"`
+	CORS                   *CORSConfig            `json:"cors,omitempty"`
+	// Labels are free-form metadata; routes labeled critical=true may only
+	// be changed during a break-glass window
+	Labels map[string]string `json:"labels,omitempty"`
+	// PolicySettings configures individual policies of the route by name
+	PolicySettings map[string]PolicySettings `json:"policySettings,omitempty"`
+	// PolicyCombination combines the enforced policies of the route,
+	// overriding the global combination. It is configured as policyMode and
+	// is unrelated to the enforce and shadow modes of PolicySettings.
+	PolicyCombination *PolicyCombination `json:"policyMode,omitempty"`
+	// Mirror copies a share of the route's requests to a shadow upstream
+	Mirror *MirrorConfig `json:"mirror,omitempty"`
+	// DependsOn names services or routes ("GET /v1/services") the route
+	// needs; while one is down the route serves DependencyFallback, or 503
+	DependsOn          []string            `json:"dependsOn,omitempty"`
+	DependencyFallback *MockResponseConfig `json:"dependencyFallback,omitempty"`
+	// ResponseSamplePercent is the percentage of proxied responses validated
+	// against ResponseSchema in the background, overriding the global rate
+	ResponseSamplePercent float64 `json:"responseSamplePercent,omitempty"`
+	// Telemetry attaches business context to the route's traces
+	Telemetry *TelemetryConfig `json:"telemetry,omitempty"`
+	// SLO declares the route's service level objectives, which generated
+	// dashboards and alert rules track
+	SLO *SLOConfig `json:"slo,omitempty"`
+	// Cache serves repeated GET requests from cached responses
+	Cache *CacheConfig `json:"cache,omitempty"`
+	// ResponsePatch is applied to successful mock and upstream responses
+	ResponsePatch []PatchOperation `json:"responsePatch,omitempty"`
+	// RateLimit bounds the requests the route accepts per window
+	RateLimit *RateLimitConfig `json:"rateLimit,omitempty"`
+	// Throttles bound the requests whose body matches given values, such as
+	// a lower limit for high priority requests
+	Throttles []ThrottleConfig `json:"throttles,omitempty"`
+	// Tenant scopes the route to requests of one tenant; routes without a
+	// tenant are shared by all requests
+	Tenant string `json:"tenant,omitempty"`
+	// Host scopes the route to requests for one Host header, such as
+	// api.example.com, or for the subdomains of a domain with *.example.com.
+	// Routes without a host serve every host.
+	Host string `json:"host,omitempty"`
+	// SchemaLearning infers a candidate request schema from the traffic of
+	// a route without one
+	SchemaLearning *SchemaLearningConfig `json:"schemaLearning,omitempty"`
+	// MaxBodyBytes bounds the size of request bodies, overriding the route
+	// manager's default limit
+	MaxBodyBytes int64 `json:"maxBodyBytes,omitempty"`
+	// ContentTypes lists the media types the route accepts and renders,
+	// the first being the default; routes without them only speak JSON
+	ContentTypes []string `json:"contentTypes,omitempty"`
+	// Streaming writes list responses as newline-delimited JSON, one item
+	// per line, as they are encoded
+	Streaming bool `json:"streaming,omitempty"`
+}
+
+// SchemaLearningConfig records SamplePercent of request bodies (100 by
+// default), up to MaxSamples, to infer a candidate request schema
+type SchemaLearningConfig struct {
+	SamplePercent float64 `json:"samplePercent,omitempty"`
+	MaxSamples    int     `json:"maxSamples,omitempty"`
+}
+
+// RateLimitConfig limits the requests of a route to Requests per
+// WindowSeconds. Key partitions the limit: "route" (the default) shares one
+// limit between all clients, "ip" limits each client address, and
+// "header:<name>" or "param:<name>" limit each value of a request header or
+// path parameter. Responses describe the limit in RateLimit headers unless
+// DisableHeaders is set.
+type RateLimitConfig struct {
+	Requests       int    `json:"requests"`
+	WindowSeconds  int    `json:"windowSeconds"`
+	Key            string `json:"key,omitempty"`
+	DisableHeaders bool   `json:"disableHeaders,omitempty"`
+}
+
+// ThrottleConfig limits the requests of a route whose body matches Match to
+// Requests per WindowSeconds. Match maps dotted body paths to the values they
+// must hold. Key partitions the limit like RateLimitConfig.Key and also
+// accepts "body:<path>" to limit each value of a body field.
+type ThrottleConfig struct {
+	Name          string                 `json:"name"`
+	Match         map[string]interface{} `json:"match"`
+	Requests      int                    `json:"requests"`
+	WindowSeconds int                    `json:"windowSeconds"`
+	Key           string                 `json:"key,omitempty"`
+}
+
+// PatchOperation is a JSON Patch (RFC 6902) operation: add, remove,
+// replace, move, copy or test. Paths are JSON Pointers such as
+// "/features/beta" or "/items/-".
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// CacheConfig controls response caching of a GET route. Responses are kept
+// for TTLSeconds unless TTLPolicy names a policy whose ttl rule computes the
+// TTL from the request and the response, such as caching settled states
+// longer than pending ones.
+type CacheConfig struct {
+	TTLSeconds int    `json:"ttlSeconds,omitempty"`
+	TTLPolicy  string `json:"ttlPolicy,omitempty"`
+	// Vary lists request headers whose values select separate cached
+	// responses, such as Accept-Language
+	Vary []string `json:"vary,omitempty"`
+	// BypassHeaders lists request headers whose presence skips the cache
+	// entirely, such as Authorization
+	BypassHeaders []string `json:"bypassHeaders,omitempty"`
+}
+
+// TelemetryConfig declares the span attributes and baggage of a route, such
+// as team, tier or data classification. Values may use the ${name}
+// placeholders of added headers.
+type TelemetryConfig struct {
+	// Attributes are set on the request span
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Baggage members are added to the W3C baggage propagated to upstreams
+	Baggage map[string]string `json:"baggage,omitempty"`
+}
+
+// SLOConfig declares the service level objectives of a route
+type SLOConfig struct {
+	// LatencyMs is the latency objective at LatencyPercentile, the 99th
+	// percentile by default
+	LatencyMs         int     `json:"latencyMs,omitempty"`
+	LatencyPercentile float64 `json:"latencyPercentile,omitempty"`
+	// Availability is the percentage of requests that must not fail with a
+	// 5xx status, such as 99.9
+	Availability float64 `json:"availability,omitempty"`
+	// MaxDenyRate is the fraction of requests the route's policies may deny
+	// before alerting, such as 0.2
+	MaxDenyRate float64 `json:"maxDenyRate,omitempty"`
+}
+
+// Policy enforcement modes
+const (
+	PolicyModeEnforce = "enforce"
+	PolicyModeShadow  = "shadow"
+)
+
+// PolicySettings configures how a route applies one of its policies. In
+// shadow mode denials are logged and counted but do not block the request.
+type PolicySettings struct {
+	Mode string `json:"mode,omitempty"`
+}
+
+// CORSConfig controls cross-origin access to routes. Origins are exact
+// origins, "*" or wildcard subdomains such as "https://*.example.com". A
+// route-level configuration replaces the global one; Disabled turns CORS off
+// for a route even when a global configuration exists.
+type CORSConfig struct {
+	AllowOrigins     []string `json:"allowOrigins,omitempty"`
+	AllowMethods     []string `json:"allowMethods,omitempty"`
+	AllowHeaders     []string `json:"allowHeaders,omitempty"`
+	ExposeHeaders    []string `json:"exposeHeaders,omitempty"`
+	AllowCredentials bool     `json:"allowCredentials,omitempty"`
+	MaxAgeSeconds    int      `json:"maxAgeSeconds,omitempty"`
+	Disabled         bool     `json:"disabled,omitempty"`
+}
+
+// HeaderPolicy controls which inbound headers reach a route's upstreams.
+// Forward entries are header names or prefixes ending in "*"; when empty, a
+// default list of content negotiation and tracing headers is forwarded.
+// Credentials such as Authorization and Cookie are only forwarded when named
+// exactly. Add values may reference ${requestId}, ${clientIp}, ${route},
+// ${param.<name>} and ${claim.<name>}.
+type HeaderPolicy struct {
+	Forward []string          `json:"forward,omitempty"`
+	Strip   []string          `json:"strip,omitempty"`
+	Add     map[string]string `json:"add,omitempty"`
+}
+
+// FaultConfig describes faults injected into a route for chaos testing
+type FaultConfig struct {
+	Delay *DelayFault `json:"delay,omitempty"`
+	Abort *AbortFault `json:"abort,omitempty"`
+}
+
+// DelayFault adds latency to a percentage of requests
+type DelayFault struct {
+	FixedMs    int     `json:"fixedMs"`
+	JitterMs   int     `json:"jitterMs,omitempty"`
+	Percentage float64 `json:"percentage"`
+}
+
+// AbortFault fails a percentage of requests with the given status code
+type AbortFault struct {
+	StatusCode int     `json:"statusCode"`
+	Message    string  `json:"message,omitempty"`
+	Percentage float64 `json:"percentage"`
+}
+
+// NotificationConfig defines where route lifecycle events are delivered
+type NotificationConfig struct {
+	WebhookURL string `json:"webhookUrl"`
+}
+
+// MockResponseConfig defines the response returned by a mock route.
+// Template is a Go template with access to .Params, .Headers, .Query, .Body
+// and the route's variables as .Vars.
+type MockResponseConfig struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Template   string            `json:"template"`
+	Store      *MockStoreConfig  `json:"store,omitempty"`
+}
+
+// RouteVariable is an admin-managed value the templates of a route can
+// reference. Secret values are redacted from the admin API; a SecretRef such
+// as "env:API_KEY" or "file:/run/secrets/api-key" is read when a template is
+// rendered instead of being stored.
+type RouteVariable struct {
+	Value     string `json:"value,omitempty"`
+	Secret    bool   `json:"secret,omitempty"`
+	SecretRef string `json:"secretRef,omitempty"`
+}
+
+// MockStoreConfig binds a mock route to a collection of the stateful mock store
+type MockStoreConfig struct {
+	Collection string `json:"collection"`
+	Operation  string `json:"operation"`
+	KeyParam   string `json:"keyParam,omitempty"`
+	IDParam    string `json:"idParam,omitempty"`
+}
+
+// Mock store operations
+const (
+	MockStoreCreate = "create"
+	MockStoreList   = "list"
+	MockStoreGet    = "get"
+	MockStoreDelete = "delete"
+)
+
+// Route handler types
+const (
+	HandlerMock      = "mock"
+	HandlerAggregate = "aggregate"
+	HandlerProxy     = "proxy"
+	HandlerTraffic   = "traffic"
+)
+
+// AggregateConfig describes a route whose response is assembled from several upstream calls
+type AggregateConfig struct {
+	Calls   []UpstreamCall         `json:"calls"`
+	Mapping map[string]interface{} `json:"mapping"`
+	Mode    string                 `json:"mode,omitempty"`
+	Async   bool                   `json:"async,omitempty"`
+}
+
+// Aggregation modes
+const (
+	AggregateModeParallel = "parallel"
+	AggregateModeSaga     = "saga"
+)
+
+// UpstreamCall describes a single request made to an upstream service
+type UpstreamCall struct {
+	Name      string            `json:"name"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	TimeoutMs int               `json:"timeoutMs,omitempty"`
+	Optional  bool              `json:"optional,omitempty"`
+
+	// Compensation undoes this call when a later saga step fails
+	Compensation *UpstreamCall `json:"compensation,omitempty"`
+}
+
+// UpstreamTarget describes a weighted upstream that proxied traffic is split across
+type UpstreamTarget struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Service resolves the target to a registered instance of the named
+	// service on every request, instead of a static URL
+	Service     string             `json:"service,omitempty"`
+	Weight      int                `json:"weight"`
+	HealthCheck *HealthCheckConfig `json:"healthCheck,omitempty"`
+}
+
+// MirrorConfig copies requests to a shadow upstream in the background.
+// Responses of the shadow upstream are discarded.
+type MirrorConfig struct {
+	// URL is the base URL of the shadow upstream
+	URL string `json:"url"`
+	// Percent of the requests mirrored, from above 0 to 100
+	Percent float64 `json:"percent"`
+	// TimeoutMs bounds mirrored requests, 5000 by default
+	TimeoutMs int `json:"timeoutMs,omitempty"`
+}
+
+// HealthCheckConfig describes how an upstream target is probed for health
+type HealthCheckConfig struct {
+	Path               string `json:"path"`
+	IntervalMs         int    `json:"intervalMs,omitempty"`
+	TimeoutMs          int    `json:"timeoutMs,omitempty"`
+	UnhealthyThreshold int    `json:"unhealthyThreshold,omitempty"`
+}
+
+// RetryConfig controls how failed proxied requests are retried
+type RetryConfig struct {
+	MaxAttempts  int   `json:"maxAttempts"`
+	RetryOn      []int `json:"retryOn,omitempty"`
+	BackoffMs    int   `json:"backoffMs,omitempty"`
+	MaxBackoffMs int   `json:"maxBackoffMs,omitempty"`
+}
+
+// UpstreamResult represents the outcome of a single upstream call
+type UpstreamResult struct {
+	Name       string      `json:"name"`
+	StatusCode int         `json:"statusCode"`
+	Body       interface{} `json:"body,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	DurationMs int64       `json:"durationMs"`
+}
+
+// CanonicalizeConfig controls how request bodies are normalized against the request schema
+type CanonicalizeConfig struct {
+	ApplyDefaults     bool `json:"applyDefaults"`
+	CoerceTypes       bool `json:"coerceTypes"`
+	TrimUnknownFields bool `json:"trimUnknownFields"`
 }
 
 // RoutesConfig represents the complete routes configuration
 type RoutesConfig struct {
-	Routes []RouteConfig `json:"routes"`
+	Routes  []RouteConfig  `json:"routes"`
+	CORS    *CORSConfig    `json:"cors,omitempty"`
+	Tenants []TenantConfig `json:"tenants,omitempty"`
+}
+
+// TenantConfig declares a tenant. Requests to one of its Hosts, exact names
+// or wildcard subdomains such as "*.acme.example.com", belong to the tenant.
+// Policies and registered schemas whose names start with the tenant name and
+// an underscore, such as "acme_orders", belong to the tenant as well.
+type TenantConfig struct {
+	Name  string   `json:"name"`
+	Hosts []string `json:"hosts,omitempty"`
 }
 
 // StatusResponse represents the response for the status endpoint
@@ -32,6 +389,17 @@ type TrafficRequest struct {
 	Volume      float64                `json:"volume"`
 	Priority    string                 `json:"priority"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Schedule    *TrafficSchedule       `json:"schedule,omitempty"`
+}
+
+// TrafficSchedule limits a traffic rule to a time window between start and
+// end. With a cron expression the rule is active for durationSeconds from
+// every time the expression matches within the window.
+type TrafficSchedule struct {
+	Start           *time.Time `json:"start,omitempty"`
+	End             *time.Time `json:"end,omitempty"`
+	Cron            string     `json:"cron,omitempty"`
+	DurationSeconds int        `json:"durationSeconds,omitempty"`
 }
 
 // TrafficResponse represents the response for the traffic endpoint
@@ -43,10 +411,314 @@ type TrafficResponse struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// Operation tracks the progress of a multi-step upstream operation
+type Operation struct {
+	ID        string          `json:"id"`
+	Route     string          `json:"route"`
+	Status    string          `json:"status"`
+	Steps     []OperationStep `json:"steps"`
+	Result    interface{}     `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// OperationStep tracks a single step of an operation
+type OperationStep struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Operation and step statuses
+const (
+	OperationPending            = "pending"
+	OperationRunning            = "running"
+	OperationSucceeded          = "succeeded"
+	OperationFailed             = "failed"
+	OperationCompensated        = "compensated"
+	OperationCompensationFailed = "compensation_failed"
+)
+
+// Event is a control plane event delivered to webhooks and subscribers
+type Event struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	Route     string                 `json:"route,omitempty"`
+	Revision  string                 `json:"revision,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Event types
+const (
+	EventRouteFirstSuccess = "route.first_success"
+	EventRouteFirstDenial  = "route.first_denial"
+	EventRouteRegistered   = "route.registered"
+	EventRouteRemoved      = "route.removed"
+	EventPolicyReloaded    = "policy.reloaded"
+	EventPolicyDenied      = "policy.denied"
+	EventTrafficDiverged   = "traffic.diverged"
+	EventTrafficConverged  = "traffic.converged"
+	// Traffic rule events of the traffic controller
+	EventTrafficRuleCreated = "traffic.rule_created"
+	EventTrafficRuleDeleted = "traffic.rule_deleted"
+	// Scheduled traffic rules opening and closing their window
+	EventTrafficRuleActivated   = "traffic.rule_activated"
+	EventTrafficRuleDeactivated = "traffic.rule_deactivated"
+	// Service instance events of the service registry
+	EventServiceRegistered   = "service.registered"
+	EventServiceDeregistered = "service.deregistered"
+	EventServiceExpired      = "service.expired"
+	EventServiceUnhealthy    = "service.unhealthy"
+	EventServiceRecovered    = "service.recovered"
+)
+
+// Decision records the outcome of evaluating a route's policies for one request
+type Decision struct {
+	ID         string    `json:"id"`
+	RequestID  string    `json:"requestId,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	Route      string    `json:"route"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Policies   []string  `json:"policies"`
+	Allowed    bool      `json:"allowed"`
+	Reason     string    `json:"reason,omitempty"`
+	DurationMs float64   `json:"durationMs"`
+	// Shadow marks decisions of shadow policies, which are not enforced
+	Shadow bool `json:"shadow,omitempty"`
+}
+
+// AuditEntry records a change made to the control plane configuration
+type AuditEntry struct {
+	ID        string                 `json:"id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Actor     string                 `json:"actor"`
+	Action    string                 `json:"action"`
+	Resource  string                 `json:"resource"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	// Outcome is success or failure; Status and Error describe the response
+	// to failed admin requests
+	Outcome string `json:"outcome"`
+	Status  int    `json:"status,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Diff lists what the change modified
+	Diff []AuditChange `json:"diff,omitempty"`
+}
+
+// AuditChange is a modification at a JSON Pointer path. Values are replaced
+// with Before and After, or for text such as policy sources described by a
+// unified diff in Patch.
+type AuditChange struct {
+	Path   string      `json:"path"`
+	Op     string      `json:"op"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+	Patch  string      `json:"patch,omitempty"`
+}
+
+// ConfigDiff is the review of a candidate configuration against the active
+// one: routes added, removed and changed by route key, changed policy
+// sources, and policies the candidate references but nobody defines
+type ConfigDiff struct {
+	Added           []RouteDiff   `json:"added"`
+	Removed         []RouteDiff   `json:"removed"`
+	Changed         []RouteDiff   `json:"changed"`
+	Unchanged       int           `json:"unchanged"`
+	Policies        []AuditChange `json:"policies,omitempty"`
+	UnknownPolicies []string      `json:"unknownPolicies,omitempty"`
+}
+
+// RouteDiff describes how a candidate configuration changes a route. Schema
+// changes and policy references are reported apart from the other changes,
+// at JSON Pointer paths within the route.
+type RouteDiff struct {
+	Route           string        `json:"route"`
+	Changes         []AuditChange `json:"changes,omitempty"`
+	SchemaChanges   []AuditChange `json:"schemaChanges,omitempty"`
+	PoliciesAdded   []string      `json:"policiesAdded,omitempty"`
+	PoliciesRemoved []string      `json:"policiesRemoved,omitempty"`
+}
+
 // PolicyResult represents the result of a policy evaluation
 type PolicyResult struct {
 	Allowed bool   `json:"allowed"`
 	Error   string `json:"error,omitempty"`
+	// Reasons holds the reason of every policy that did not allow the
+	// request, when reasons are collected
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Policy combination modes
+const (
+	// PolicyCombineAll allows a request only when every policy allows it
+	PolicyCombineAll = "all"
+	// PolicyCombineAny allows a request when at least one policy allows it
+	PolicyCombineAny = "any"
+	// PolicyCombinePriority applies the first rule of a priority list whose
+	// policy decides the request
+	PolicyCombinePriority = "priority"
+)
+
+// Priority rule effects
+const (
+	PolicyEffectAllow = "allow"
+	PolicyEffectDeny  = "deny"
+)
+
+// PolicyCombination selects how the policies of a route combine. In JSON it
+// is either "all" or "any", or an ordered list of priority rules.
+type PolicyCombination struct {
+	// Combine is PolicyCombineAll, PolicyCombineAny or PolicyCombinePriority
+	Combine  string
+	Priority []PolicyPriority
+}
+
+// PolicyPriority is a rule of a priority list. A rule with the allow effect
+// allows the request when its policy allows it, one with the deny effect
+// denies it when its policy does not; otherwise the next rule applies.
+type PolicyPriority struct {
+	Policy string `json:"policy"`
+	Effect string `json:"effect"`
+}
+
+// MarshalJSON encodes the combination as its name, or as the priority list
+func (c PolicyCombination) MarshalJSON() ([]byte, error) {
+	if c.Combine == PolicyCombinePriority {
+		return json.Marshal(c.Priority)
+	}
+	return json.Marshal(c.Combine)
+}
+
+// UnmarshalJSON decodes a combination name or a priority list
+func (c *PolicyCombination) UnmarshalJSON(data []byte) error {
+	var combine string
+	if err := json.Unmarshal(data, &combine); err == nil {
+		*c = PolicyCombination{Combine: combine}
+		return nil
+	}
+	var priority []PolicyPriority
+	if err := json.Unmarshal(data, &priority); err != nil {
+		return fmt.Errorf("policyMode must be \"all\", \"any\" or a list of priority rules")
+	}
+	*c = PolicyCombination{Combine: PolicyCombinePriority, Priority: priority}
+	return nil
+}
+
+// PolicyEvaluation is the combined decision of a policy set together with
+// the result of every policy in it
+type PolicyEvaluation struct {
+	Allowed bool                   `json:"allowed"`
+	Error   string                 `json:"error,omitempty"`
+	Reasons []string               `json:"reasons,omitempty"`
+	Results []PolicyEvaluationItem `json:"results"`
+}
+
+// PolicyEvaluationItem is the result of a single policy in an evaluation
+type PolicyEvaluationItem struct {
+	Policy  string `json:"policy"`
+	Allowed bool   `json:"allowed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PolicyDryRun compares a candidate policy with the live policy set on the
+// recorded inputs of the routes using it
+type PolicyDryRun struct {
+	Policy    string         `json:"policy"`
+	Samples   int            `json:"samples"`
+	Current   PolicyOutcomes `json:"current"`
+	Candidate PolicyOutcomes `json:"candidate"`
+	Changed   int            `json:"changed"`
+	Routes    []RouteDryRun  `json:"routes"`
+}
+
+// RouteDryRun is the dry-run result of a single route
+type RouteDryRun struct {
+	Route     string           `json:"route"`
+	Samples   int              `json:"samples"`
+	Current   PolicyOutcomes   `json:"current"`
+	Candidate PolicyOutcomes   `json:"candidate"`
+	Changed   int              `json:"changed"`
+	Changes   []DecisionChange `json:"changes,omitempty"`
+}
+
+// PolicyOutcomes counts the outcomes of policy evaluations
+type PolicyOutcomes struct {
+	Allowed int `json:"allowed"`
+	Denied  int `json:"denied"`
+	Errors  int `json:"errors"`
+}
+
+// DecisionChange describes a recorded request whose outcome a candidate policy changes
+type DecisionChange struct {
+	RequestID string    `json:"requestId,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Current   string    `json:"current"`
+	Candidate string    `json:"candidate"`
+}
+
+// Policy outcomes reported by dry runs
+const (
+	OutcomeAllow = "allow"
+	OutcomeDeny  = "deny"
+	OutcomeError = "error"
+)
+
+// PolicyTestReport summarizes a run of Rego unit tests
+type PolicyTestReport struct {
+	Passed  int                `json:"passed"`
+	Failed  int                `json:"failed"`
+	Errors  int                `json:"errors"`
+	Skipped int                `json:"skipped"`
+	Results []PolicyTestResult `json:"results"`
+}
+
+// PolicyTestResult is the outcome of a single Rego test rule
+type PolicyTestResult struct {
+	Package    string  `json:"package"`
+	Name       string  `json:"name"`
+	File       string  `json:"file,omitempty"`
+	Row        int     `json:"row,omitempty"`
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	Output     string  `json:"output,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// Policy test statuses
+const (
+	PolicyTestPass  = "pass"
+	PolicyTestFail  = "fail"
+	PolicyTestError = "error"
+	PolicyTestSkip  = "skip"
+)
+
+// PolicyREPLRequest is a Rego query evaluated in the policy REPL. Modules,
+// keyed by file or policy name, replace loaded policies of the same name and
+// Data replaces top-level documents of the loaded data, for this query only.
+type PolicyREPLRequest struct {
+	Query   string                 `json:"query"`
+	Modules map[string]string      `json:"modules,omitempty"`
+	Input   interface{}            `json:"input,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// PolicyREPLResult holds the results of a policy REPL query; a query without
+// results is undefined
+type PolicyREPLResult struct {
+	Query      string          `json:"query"`
+	Defined    bool            `json:"defined"`
+	Results    []PolicyREPLRow `json:"results"`
+	Output     string          `json:"output,omitempty"`
+	DurationMs float64         `json:"durationMs"`
+}
+
+// PolicyREPLRow is a single result of a policy REPL query: the value of each
+// expression and of each variable the query binds
+type PolicyREPLRow struct {
+	Expressions []interface{}          `json:"expressions"`
+	Bindings    map[string]interface{} `json:"bindings,omitempty"`
 }
 
 // ValidationResult represents the result of request validation
@@ -56,10 +728,15 @@ type ValidationResult struct {
 	Details string   `json:"details,omitempty"`
 }
 
-// MockData provides mock responses for endpoints
+// MockData provides mock responses for endpoints and a stateful in-memory
+// store of records created through mock routes
 type MockData struct {
 	StatusResponses  map[string]StatusResponse
 	TrafficResponses map[string]TrafficResponse
+
+	mu       sync.RWMutex
+	records  map[string]map[string][]map[string]interface{}
+	sequence uint64
 }
 
 // NewMockData creates a new instance of MockData with default values
@@ -82,18 +759,106 @@ func NewMockData() *MockData {
 				Timestamp: time.Now(),
 			},
 		},
+		records: make(map[string]map[string][]map[string]interface{}),
 	}
 }
 
-// GenerateTrafficResponse creates a mock traffic response
-func (md *MockData) GenerateTrafficResponse(serviceID string, request TrafficRequest) TrafficResponse {
-	return TrafficResponse{
-		ID:        generateID(),
-		ServiceID: serviceID,
-		Status:    "accepted",
-		Message:   "Traffic request processed successfully",
-		Timestamp: time.Now(),
+// CreateRecord stores a record in a collection under the given key and returns it
+// with its generated id and creation timestamp
+func (md *MockData) CreateRecord(collection, key string, data interface{}) map[string]interface{} {
+	md.mu.Lock()
+	defer md.mu.Unlock()
+
+	record := make(map[string]interface{})
+	if fields, ok := data.(map[string]interface{}); ok {
+		for field, value := range fields {
+			record[field] = value
+		}
+	} else if data != nil {
+		record["value"] = data
+	}
+
+	md.sequence++
+	record["id"] = fmt.Sprintf("%s-%s%d", collection, time.Now().Format("20060102150405"), md.sequence)
+	record["createdAt"] = time.Now().UTC().Format(time.RFC3339)
+
+	if md.records[collection] == nil {
+		md.records[collection] = make(map[string][]map[string]interface{})
+	}
+	md.records[collection][key] = append(md.records[collection][key], record)
+
+	return copyRecord(record)
+}
+
+// ListRecords returns all records stored in a collection under the given key
+func (md *MockData) ListRecords(collection, key string) []map[string]interface{} {
+	md.mu.RLock()
+	defer md.mu.RUnlock()
+
+	stored := md.records[collection][key]
+	records := make([]map[string]interface{}, 0, len(stored))
+	for _, record := range stored {
+		records = append(records, copyRecord(record))
 	}
+	return records
+}
+
+// RecordKeys returns the keys holding records in a collection
+func (md *MockData) RecordKeys(collection string) []string {
+	md.mu.RLock()
+	defer md.mu.RUnlock()
+
+	keys := make([]string, 0, len(md.records[collection]))
+	for key, records := range md.records[collection] {
+		if len(records) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetRecord returns a single record by id
+func (md *MockData) GetRecord(collection, key, id string) (map[string]interface{}, bool) {
+	md.mu.RLock()
+	defer md.mu.RUnlock()
+
+	for _, record := range md.records[collection][key] {
+		if record["id"] == id {
+			return copyRecord(record), true
+		}
+	}
+	return nil, false
+}
+
+// DeleteRecords removes the record with the given id, or every record under
+// the key when id is empty, and returns the number of records removed
+func (md *MockData) DeleteRecords(collection, key, id string) int {
+	md.mu.Lock()
+	defer md.mu.Unlock()
+
+	stored := md.records[collection][key]
+	if id == "" {
+		delete(md.records[collection], key)
+		return len(stored)
+	}
+
+	for i, record := range stored {
+		if record["id"] == id {
+			md.records[collection][key] = append(stored[:i:i], stored[i+1:]...)
+			return 1
+		}
+	}
+	return 0
+}
+
+// copyRecord returns a shallow copy of a stored record
+func copyRecord(record map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(record))
+	for field, value := range record {
+		result[field] = value
+	}
+	return result
 }
 
 // GenerateStatusResponse creates a mock status response
@@ -105,8 +870,3 @@ func (md *MockData) GenerateStatusResponse() StatusResponse {
 		Uptime:    3600,
 	}
 }
-
-// generateID generates a simple ID for mock responses
-func generateID() string {
-	return "traffic-" + time.Now().Format("20060102150405")
-}
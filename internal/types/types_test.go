@@ -1,6 +1,7 @@
 package types
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 )
@@ -48,47 +49,6 @@ func TestGenerateStatusResponse(t *testing.T) {
 	}
 }
 
-func TestGenerateTrafficResponse(t *testing.T) {
-	mockData := NewMockData()
-	request := TrafficRequest{
-		TrafficType: "incoming",
-		Volume:      100.5,
-		Priority:    "medium",
-		Metadata: map[string]interface{}{
-			"source":      "service-a",
-			"destination": "service-b",
-		},
-	}
-
-	response := mockData.GenerateTrafficResponse("service123", request)
-
-	if response.ID == "" {
-		t.Error("ID should not be empty")
-	}
-
-	if response.ServiceID != "service123" {
-		t.Errorf("ServiceID should be 'service123', got '%s'", response.ServiceID)
-	}
-
-	if response.Status == "" {
-		t.Error("Status should not be empty")
-	}
-
-	if response.Message == "" {
-		t.Error("Message should not be empty")
-	}
-
-	// Check that timestamp is recent
-	now := time.Now()
-	if response.Timestamp.After(now) {
-		t.Error("Timestamp should not be in the future")
-	}
-
-	if response.Timestamp.Before(now.Add(-time.Second)) {
-		t.Error("Timestamp should be recent")
-	}
-}
-
 func TestTrafficRequestValidation(t *testing.T) {
 	validRequest := TrafficRequest{
 		TrafficType: "incoming",
@@ -155,3 +115,69 @@ func TestTrafficResponseValidation(t *testing.T) {
 		t.Error("Message should not be empty")
 	}
 }
+
+func TestMockDataRecordLifecycle(t *testing.T) {
+	mockData := NewMockData()
+
+	created := mockData.CreateRecord("traffic", "service123", map[string]interface{}{
+		"trafficType": "incoming",
+		"volume":      100.5,
+	})
+
+	id, ok := created["id"].(string)
+	if !ok || id == "" {
+		t.Fatal("created record should have an id")
+	}
+
+	if created["createdAt"] == nil {
+		t.Error("created record should have a createdAt timestamp")
+	}
+
+	records := mockData.ListRecords("traffic", "service123")
+	if len(records) != 1 || records[0]["trafficType"] != "incoming" {
+		t.Errorf("expected stored record, got %v", records)
+	}
+
+	if other := mockData.ListRecords("traffic", "service456"); len(other) != 0 {
+		t.Errorf("records should be scoped by key, got %v", other)
+	}
+
+	if _, found := mockData.GetRecord("traffic", "service123", id); !found {
+		t.Error("GetRecord should find the created record")
+	}
+
+	if removed := mockData.DeleteRecords("traffic", "service123", id); removed != 1 {
+		t.Errorf("expected 1 removed record, got %d", removed)
+	}
+
+	if _, found := mockData.GetRecord("traffic", "service123", id); found {
+		t.Error("deleted record should not be found")
+	}
+}
+
+func TestPolicyModeJSON(t *testing.T) {
+	var route RouteConfig
+	if err := json.Unmarshal([]byte(`{"policyMode": "any"}`), &route); err != nil {
+		t.Fatalf("failed to decode mode name: %v", err)
+	}
+	if route.PolicyCombination == nil || route.PolicyCombination.Combine != PolicyCombineAny {
+		t.Errorf("expected any mode, got %+v", route.PolicyCombination)
+	}
+
+	document := `{"policyMode":[{"policy":"blocklist","effect":"deny"},{"policy":"admin","effect":"allow"}]}`
+	route = RouteConfig{}
+	if err := json.Unmarshal([]byte(document), &route); err != nil {
+		t.Fatalf("failed to decode priority list: %v", err)
+	}
+	if route.PolicyCombination.Combine != PolicyCombinePriority || len(route.PolicyCombination.Priority) != 2 || route.PolicyCombination.Priority[1].Policy != "admin" {
+		t.Errorf("unexpected priority mode %+v", route.PolicyCombination)
+	}
+	encoded, _ := json.Marshal(route.PolicyCombination)
+	if string(encoded) != `[{"policy":"blocklist","effect":"deny"},{"policy":"admin","effect":"allow"}]` {
+		t.Errorf("expected the priority list to round-trip, got %s", encoded)
+	}
+
+	if err := json.Unmarshal([]byte(`{"policyMode": 3}`), &route); err == nil {
+		t.Error("expected an invalid policy mode to be rejected")
+	}
+}
@@ -0,0 +1,196 @@
+package upstream
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/metrics"
+	"dynamiccontrol/internal/types"
+)
+
+// Health check defaults
+const (
+	DefaultHealthCheckInterval = 10 * time.Second
+	DefaultHealthCheckTimeout  = 2 * time.Second
+	DefaultUnhealthyThreshold  = 3
+)
+
+// TargetStatus reports the current state of an upstream target
+type TargetStatus struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	Weight  int    `json:"weight"`
+	Healthy bool   `json:"healthy"`
+}
+
+// target tracks the selection and health state of a single upstream
+type target struct {
+	config   types.UpstreamTarget
+	current  int
+	healthy  bool
+	failures int
+}
+
+// Balancer splits traffic across weighted upstream targets using smooth
+// weighted round-robin, so selection is deterministic for a given sequence
+// of requests and honours weights such as a 90/10 canary split
+type Balancer struct {
+	route   string
+	mu      sync.Mutex
+	targets []*target
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewBalancer creates a balancer for the given route targets
+func NewBalancer(route string, targets []types.UpstreamTarget) *Balancer {
+	b := &Balancer{
+		route: route,
+		stop:  make(chan struct{}),
+	}
+
+	for _, config := range targets {
+		if config.Name == "" {
+			config.Name = config.URL
+		}
+		if config.Name == "" {
+			config.Name = config.Service
+		}
+		if config.Weight <= 0 {
+			config.Weight = 1
+		}
+		b.targets = append(b.targets, &target{config: config, healthy: true})
+		metrics.UpstreamHealthy.WithLabelValues(route, config.Name).Set(1)
+	}
+
+	return b
+}
+
+// Next selects the next healthy target
+func (b *Balancer) Next() (types.UpstreamTarget, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var selected *target
+	total := 0
+	for _, t := range b.targets {
+		if !t.healthy {
+			continue
+		}
+		t.current += t.config.Weight
+		total += t.config.Weight
+		if selected == nil || t.current > selected.current {
+			selected = t
+		}
+	}
+
+	if selected == nil {
+		return types.UpstreamTarget{}, false
+	}
+
+	selected.current -= total
+	return selected.config, true
+}
+
+// Status returns the current state of every target
+func (b *Balancer) Status() []TargetStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	statuses := make([]TargetStatus, 0, len(b.targets))
+	for _, t := range b.targets {
+		statuses = append(statuses, TargetStatus{
+			Name:    t.config.Name,
+			URL:     t.config.URL,
+			Weight:  t.config.Weight,
+			Healthy: t.healthy,
+		})
+	}
+	return statuses
+}
+
+// StartHealthChecks probes every target with a health check configuration in the background
+func (b *Balancer) StartHealthChecks(client *http.Client) {
+	for _, t := range b.targets {
+		// Instances of registered services are checked by the registry
+		if t.config.HealthCheck == nil || t.config.Service != "" {
+			continue
+		}
+		go b.runHealthCheck(client, t)
+	}
+}
+
+// Stop terminates background health checks
+func (b *Balancer) Stop() {
+	b.once.Do(func() {
+		close(b.stop)
+	})
+}
+
+// runHealthCheck periodically probes a single target until the balancer is stopped
+func (b *Balancer) runHealthCheck(client *http.Client, t *target) {
+	check := t.config.HealthCheck
+	interval := DefaultHealthCheckInterval
+	if check.IntervalMs > 0 {
+		interval = time.Duration(check.IntervalMs) * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		b.probe(client, t)
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe performs a single health check and updates the target state
+func (b *Balancer) probe(client *http.Client, t *target) {
+	check := t.config.HealthCheck
+	timeout := DefaultHealthCheckTimeout
+	if check.TimeoutMs > 0 {
+		timeout = time.Duration(check.TimeoutMs) * time.Millisecond
+	}
+	threshold := DefaultUnhealthyThreshold
+	if check.UnhealthyThreshold > 0 {
+		threshold = check.UnhealthyThreshold
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ok := false
+	url := strings.TrimSuffix(t.config.URL, "/") + check.Path
+	if req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil); err == nil {
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+			ok = resp.StatusCode < http.StatusInternalServerError
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ok {
+		t.failures = 0
+		t.healthy = true
+	} else {
+		t.failures++
+		if t.failures >= threshold {
+			t.healthy = false
+		}
+	}
+
+	healthy := 0.0
+	if t.healthy {
+		healthy = 1
+	}
+	metrics.UpstreamHealthy.WithLabelValues(b.route, t.config.Name).Set(healthy)
+}
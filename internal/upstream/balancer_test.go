@@ -0,0 +1,65 @@
+package upstream
+
+import (
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestBalancerHonoursWeights(t *testing.T) {
+	balancer := NewBalancer("/v1/test", []types.UpstreamTarget{
+		{Name: "stable", URL: "http://stable", Weight: 9},
+		{Name: "canary", URL: "http://canary", Weight: 1},
+	})
+
+	counts := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		target, ok := balancer.Next()
+		if !ok {
+			t.Fatal("Next() should select a target")
+		}
+		counts[target.Name]++
+	}
+
+	if counts["stable"] != 90 || counts["canary"] != 10 {
+		t.Errorf("expected 90/10 split, got %v", counts)
+	}
+}
+
+func TestBalancerIsDeterministic(t *testing.T) {
+	targets := []types.UpstreamTarget{
+		{Name: "a", URL: "http://a", Weight: 5},
+		{Name: "b", URL: "http://b", Weight: 1},
+		{Name: "c", URL: "http://c", Weight: 1},
+	}
+
+	first := NewBalancer("/v1/test", targets)
+	second := NewBalancer("/v1/test", targets)
+	for i := 0; i < 20; i++ {
+		a, _ := first.Next()
+		b, _ := second.Next()
+		if a.Name != b.Name {
+			t.Fatalf("selection %d differs: %s != %s", i, a.Name, b.Name)
+		}
+	}
+}
+
+func TestBalancerSkipsUnhealthyTargets(t *testing.T) {
+	balancer := NewBalancer("/v1/test", []types.UpstreamTarget{
+		{Name: "a", URL: "http://a", Weight: 1},
+		{Name: "b", URL: "http://b", Weight: 1},
+	})
+	balancer.targets[0].healthy = false
+
+	for i := 0; i < 5; i++ {
+		target, ok := balancer.Next()
+		if !ok || target.Name != "b" {
+			t.Errorf("expected healthy target b, got %v (ok=%v)", target.Name, ok)
+		}
+	}
+
+	balancer.targets[1].healthy = false
+	if _, ok := balancer.Next(); ok {
+		t.Error("Next() should fail when no target is healthy")
+	}
+}
@@ -0,0 +1,147 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/chaos"
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/types"
+)
+
+// DefaultTimeout is used for upstream calls that do not declare their own timeout
+const DefaultTimeout = 5 * time.Second
+
+// Client performs HTTP calls against upstream services
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient creates a new upstream client
+func NewClient() *Client {
+	return &Client{
+		httpClient: &http.Client{},
+	}
+}
+
+// SetChaos routes upstream dials through the chaos injector so DNS failures
+// can be simulated
+func (cl *Client) SetChaos(injector *chaos.Injector) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = injector.DialContext(dialer.DialContext)
+	cl.httpClient.Transport = transport
+}
+
+// Call executes a single upstream call, enforcing its timeout through the context
+func (cl *Client) Call(ctx context.Context, call types.UpstreamCall, params map[string]string, body interface{}) types.UpstreamResult {
+	start := time.Now()
+	result := types.UpstreamResult{Name: call.Name}
+
+	timeout := DefaultTimeout
+	if call.TimeoutMs > 0 {
+		timeout = time.Duration(call.TimeoutMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	method := call.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var reader io.Reader
+	if body != nil && method != http.MethodGet {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to encode request body: %v", err)
+			return result
+		}
+		reader = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, ExpandURL(call.URL, params), reader)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to build request: %v", err)
+		return result
+	}
+	for key, values := range propagatedHeaders(ctx) {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		req.Header.Set(logging.RequestIDHeader, requestID)
+	}
+	for key, value := range call.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := cl.httpClient.Do(req)
+	result.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = fmt.Sprintf("request failed: %v", err)
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	responseBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to read response: %v", err)
+		return result
+	}
+
+	if len(responseBytes) > 0 {
+		var decoded interface{}
+		if err := json.Unmarshal(responseBytes, &decoded); err != nil {
+			decoded = string(responseBytes)
+		}
+		result.Body = decoded
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		result.Error = fmt.Sprintf("upstream returned status %d", resp.StatusCode)
+	}
+
+	return result
+}
+
+// CallAll executes upstream calls in parallel and returns their results keyed by call name
+func (cl *Client) CallAll(ctx context.Context, calls []types.UpstreamCall, params map[string]string, body interface{}) map[string]types.UpstreamResult {
+	results := make(map[string]types.UpstreamResult, len(calls))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, call := range calls {
+		wg.Add(1)
+		go func(call types.UpstreamCall) {
+			defer wg.Done()
+			result := cl.Call(ctx, call, params, body)
+
+			mu.Lock()
+			results[call.Name] = result
+			mu.Unlock()
+		}(call)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// ExpandURL replaces {param} placeholders in an upstream URL with path parameter values
+func ExpandURL(rawURL string, params map[string]string) string {
+	for name, value := range params {
+		rawURL = strings.ReplaceAll(rawURL, "{"+name+"}", value)
+	}
+	return rawURL
+}
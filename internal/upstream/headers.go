@@ -0,0 +1,158 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"regexp"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+)
+
+// DefaultForwardHeaders are forwarded to upstreams when a route declares no forward list
+var DefaultForwardHeaders = []string{
+	"Accept",
+	"Accept-Language",
+	"Content-Type",
+	"User-Agent",
+	"X-Request-ID",
+	"Traceparent",
+	"Tracestate",
+	"Baggage",
+}
+
+// SensitiveHeaders carry credentials and are never matched by forward
+// prefixes; a route must name them exactly to forward them
+var SensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"X-Api-Key",
+	"X-Identity-Assertion",
+}
+
+// headerVariable matches ${name} placeholders in added header values
+var headerVariable = regexp.MustCompile(`\$\{([A-Za-z0-9_.-]+)\}`)
+
+// propagatedHeadersKey is the context key holding the headers propagated to upstream calls
+type propagatedHeadersKey struct{}
+
+// WithPropagatedHeaders returns a context whose upstream calls carry the given headers
+func WithPropagatedHeaders(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, propagatedHeadersKey{}, header)
+}
+
+// propagatedHeaders returns the headers carried by ctx
+func propagatedHeaders(ctx context.Context) http.Header {
+	header, _ := ctx.Value(propagatedHeadersKey{}).(http.Header)
+	return header
+}
+
+// PropagateHeaders selects the inbound headers forwarded to upstreams under a
+// route policy and adds the policy's headers, expanding ${name} placeholders
+// from vars. Hop-by-hop headers are never forwarded.
+func PropagateHeaders(policy *types.HeaderPolicy, inbound http.Header, vars map[string]string) http.Header {
+	forward := DefaultForwardHeaders
+	var strip []string
+	if policy != nil {
+		if len(policy.Forward) > 0 {
+			forward = policy.Forward
+		}
+		strip = policy.Strip
+	}
+
+	header := make(http.Header)
+	for key, values := range inbound {
+		if containsHeader(hopHeaders, key) || containsHeader(strip, key) {
+			continue
+		}
+		if !forwarded(forward, key) {
+			continue
+		}
+		header[key] = append([]string(nil), values...)
+	}
+
+	if policy != nil {
+		for key, value := range policy.Add {
+			header.Set(key, ExpandVariables(value, vars))
+		}
+	}
+	return header
+}
+
+// ExpandVariables replaces ${name} placeholders in value with vars; unknown
+// names expand to the empty string
+func ExpandVariables(value string, vars map[string]string) string {
+	return headerVariable.ReplaceAllStringFunc(value, func(match string) string {
+		return vars[headerVariable.FindStringSubmatch(match)[1]]
+	})
+}
+
+// ValidateHeaderPolicy checks a header policy at registration time
+func ValidateHeaderPolicy(policy *types.HeaderPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	for _, entry := range policy.Forward {
+		// A bare "*" forwards every header except credentials
+		if name := strings.TrimSuffix(entry, "*"); name != "" && !validHeaderName(name) {
+			return fmt.Errorf("invalid forward header %q", entry)
+		}
+	}
+	for _, name := range policy.Strip {
+		if !validHeaderName(name) {
+			return fmt.Errorf("invalid strip header %q", name)
+		}
+	}
+	for name := range policy.Add {
+		if !validHeaderName(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if containsHeader(hopHeaders, name) {
+			return fmt.Errorf("header %s cannot be added", name)
+		}
+	}
+	return nil
+}
+
+// forwarded reports whether a header matches the forward list. Prefix
+// entries never match sensitive headers.
+func forwarded(forward []string, key string) bool {
+	for _, entry := range forward {
+		if prefix, ok := strings.CutSuffix(entry, "*"); ok {
+			if !containsHeader(SensitiveHeaders, key) && strings.HasPrefix(strings.ToLower(key), strings.ToLower(prefix)) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(entry, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsHeader reports whether a header name is in the list, ignoring case
+func containsHeader(list []string, key string) bool {
+	for _, entry := range list {
+		if strings.EqualFold(entry, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// validHeaderName reports whether name is a valid HTTP header field name
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r >= 0x7f || r <= 0x20 || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", r) {
+			return false
+		}
+	}
+	return textproto.CanonicalMIMEHeaderKey(name) != ""
+}
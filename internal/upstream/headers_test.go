@@ -0,0 +1,81 @@
+package upstream
+
+import (
+	"net/http"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+func inboundHeaders() http.Header {
+	header := make(http.Header)
+	header.Set("Accept", "application/json")
+	header.Set("Authorization", "Bearer secret")
+	header.Set("Cookie", "session=1")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Tenant-Id", "acme")
+	header.Set("X-Tenant-Region", "eu")
+	header.Set("X-Debug", "1")
+	return header
+}
+
+func TestPropagateHeadersDefaultsDenyUnknownHeaders(t *testing.T) {
+	header := PropagateHeaders(nil, inboundHeaders(), nil)
+
+	if header.Get("Accept") != "application/json" {
+		t.Error("Expected default headers to be forwarded")
+	}
+	for _, name := range []string{"Authorization", "Cookie", "Connection", "X-Tenant-Id", "X-Debug"} {
+		if header.Get(name) != "" {
+			t.Errorf("Expected %s not to be forwarded by default", name)
+		}
+	}
+}
+
+func TestPropagateHeadersPolicy(t *testing.T) {
+	policy := &types.HeaderPolicy{
+		Forward: []string{"X-Tenant-*", "Authorization", "*"},
+		Strip:   []string{"X-Tenant-Region"},
+		Add: map[string]string{
+			"X-Caller-Route": "${route}",
+			"X-Order":        "order-${param.orderId}",
+		},
+	}
+	header := PropagateHeaders(policy, inboundHeaders(), map[string]string{
+		"route":         "GET /v1/orders/:orderId",
+		"param.orderId": "42",
+	})
+
+	if header.Get("X-Tenant-Id") != "acme" || header.Get("X-Debug") != "1" {
+		t.Error("Expected prefix matches to be forwarded")
+	}
+	if header.Get("X-Tenant-Region") != "" {
+		t.Error("Expected stripped header to be removed")
+	}
+	if header.Get("Authorization") != "Bearer secret" {
+		t.Error("Expected exactly named credential to be forwarded")
+	}
+	if header.Get("Cookie") != "" {
+		t.Error("Expected prefixes not to forward credentials")
+	}
+	if header.Get("X-Caller-Route") != "GET /v1/orders/:orderId" || header.Get("X-Order") != "order-42" {
+		t.Errorf("Unexpected added headers: %v", header)
+	}
+}
+
+func TestValidateHeaderPolicy(t *testing.T) {
+	tests := []struct {
+		policy *types.HeaderPolicy
+		valid  bool
+	}{
+		{&types.HeaderPolicy{Forward: []string{"*", "X-Tenant-*"}}, true},
+		{&types.HeaderPolicy{Forward: []string{"Bad Header"}}, false},
+		{&types.HeaderPolicy{Strip: []string{"X-Bad:Header"}}, false},
+		{&types.HeaderPolicy{Add: map[string]string{"Connection": "close"}}, false},
+	}
+	for _, test := range tests {
+		if err := ValidateHeaderPolicy(test.policy); (err == nil) != test.valid {
+			t.Errorf("ValidateHeaderPolicy(%+v) returned %v", test.policy, err)
+		}
+	}
+}
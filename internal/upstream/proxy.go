@@ -0,0 +1,80 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"dynamiccontrol/internal/logging"
+)
+
+// hopHeaders are connection-specific headers that must not be forwarded
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Forward sends an inbound request to the target base URL, preserving the
+// request path and query string. Only the headers propagated through ctx are
+// sent upstream.
+func (cl *Client) Forward(ctx context.Context, targetURL string, r *http.Request, body []byte) (*http.Response, error) {
+	base, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream URL %s: %w", targetURL, err)
+	}
+
+	outURL := *base
+	outURL.Path = strings.TrimSuffix(base.Path, "/") + r.URL.Path
+	outURL.RawQuery = r.URL.RawQuery
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, outURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build upstream request: %w", err)
+	}
+
+	if header := propagatedHeaders(ctx); header != nil {
+		req.Header = header.Clone()
+	}
+	if len(body) > 0 && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+	}
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		req.Header.Set(logging.RequestIDHeader, requestID)
+	}
+	req.Header.Set("X-Forwarded-Host", r.Host)
+
+	return cl.httpClient.Do(req)
+}
+
+// HTTPClient returns the underlying HTTP client
+func (cl *Client) HTTPClient() *http.Client {
+	return cl.httpClient
+}
+
+// CopyHeaders copies end-to-end response headers into the destination header map
+func CopyHeaders(dst, src http.Header) {
+	for key, values := range src {
+		skip := false
+		for _, header := range hopHeaders {
+			if strings.EqualFold(key, header) {
+				skip = true
+				break
+			}
+		}
+		if skip {
+			continue
+		}
+		for _, value := range values {
+			dst.Add(key, value)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package upstream
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Retry defaults
+const (
+	DefaultBackoff    = 100 * time.Millisecond
+	DefaultMaxBackoff = 2 * time.Second
+)
+
+// defaultRetryOn lists the status codes retried when a route does not declare its own
+var defaultRetryOn = []int{
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// idempotentMethods are the request methods that can be sent again without
+// repeating their effect
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// Retryable reports whether requests with the given method may be retried;
+// non-idempotent methods such as POST and PATCH are sent once
+func Retryable(method string) bool {
+	return idempotentMethods[method]
+}
+
+// MaxAttempts returns the total number of attempts allowed by a retry configuration
+func MaxAttempts(retry *types.RetryConfig) int {
+	if retry == nil || retry.MaxAttempts < 1 {
+		return 1
+	}
+	return retry.MaxAttempts
+}
+
+// ShouldRetry reports whether a response status code is retryable
+func ShouldRetry(retry *types.RetryConfig, statusCode int) bool {
+	if retry == nil {
+		return false
+	}
+
+	retryOn := retry.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = defaultRetryOn
+	}
+	for _, code := range retryOn {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the exponential delay before the given retry attempt (starting at 1)
+func Backoff(retry *types.RetryConfig, attempt int) time.Duration {
+	delay := DefaultBackoff
+	maxDelay := DefaultMaxBackoff
+	if retry != nil && retry.BackoffMs > 0 {
+		delay = time.Duration(retry.BackoffMs) * time.Millisecond
+	}
+	if retry != nil && retry.MaxBackoffMs > 0 {
+		maxDelay = time.Duration(retry.MaxBackoffMs) * time.Millisecond
+	}
+
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// Sleep waits for the given duration or until the context is done
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
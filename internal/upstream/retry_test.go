@@ -0,0 +1,112 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestBackoffGrowsUntilCap(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		retry    *types.RetryConfig
+		expected []time.Duration
+	}{
+		{"defaults", nil, []time.Duration{
+			100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond,
+			1600 * time.Millisecond, 2 * time.Second, 2 * time.Second,
+		}},
+		{"configured", &types.RetryConfig{BackoffMs: 50, MaxBackoffMs: 300}, []time.Duration{
+			50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond,
+		}},
+		{"initial delay above cap", &types.RetryConfig{BackoffMs: 500, MaxBackoffMs: 200}, []time.Duration{
+			200 * time.Millisecond, 200 * time.Millisecond,
+		}},
+	} {
+		for i, expected := range tc.expected {
+			if delay := Backoff(tc.retry, i+1); delay != expected {
+				t.Errorf("%s: expected attempt %d to wait %v, got %v", tc.name, i+1, expected, delay)
+			}
+		}
+	}
+	if delay := Backoff(nil, 1000); delay != DefaultMaxBackoff {
+		t.Errorf("Expected late attempts to wait the maximum backoff, got %v", delay)
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		retry    *types.RetryConfig
+		status   int
+		expected bool
+	}{
+		{"no retry config", nil, http.StatusServiceUnavailable, false},
+		{"default bad gateway", &types.RetryConfig{MaxAttempts: 3}, http.StatusBadGateway, true},
+		{"default unavailable", &types.RetryConfig{MaxAttempts: 3}, http.StatusServiceUnavailable, true},
+		{"default gateway timeout", &types.RetryConfig{MaxAttempts: 3}, http.StatusGatewayTimeout, true},
+		{"default internal error", &types.RetryConfig{MaxAttempts: 3}, http.StatusInternalServerError, false},
+		{"default client error", &types.RetryConfig{MaxAttempts: 3}, http.StatusBadRequest, false},
+		{"default success", &types.RetryConfig{MaxAttempts: 3}, http.StatusOK, false},
+		{"listed status", &types.RetryConfig{RetryOn: []int{429, 500}}, http.StatusTooManyRequests, true},
+		{"unlisted status", &types.RetryConfig{RetryOn: []int{429, 500}}, http.StatusBadGateway, false},
+	} {
+		if got := ShouldRetry(tc.retry, tc.status); got != tc.expected {
+			t.Errorf("%s: expected %v for %d, got %v", tc.name, tc.expected, tc.status, got)
+		}
+	}
+}
+
+func TestMaxAttempts(t *testing.T) {
+	if attempts := MaxAttempts(nil); attempts != 1 {
+		t.Errorf("Expected one attempt without retry config, got %d", attempts)
+	}
+	if attempts := MaxAttempts(&types.RetryConfig{}); attempts != 1 {
+		t.Errorf("Expected one attempt without maxAttempts, got %d", attempts)
+	}
+	if attempts := MaxAttempts(&types.RetryConfig{MaxAttempts: 4}); attempts != 4 {
+		t.Errorf("Expected 4 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryableMethods(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete} {
+		if !Retryable(method) {
+			t.Errorf("Expected %s requests to be retried", method)
+		}
+	}
+	for _, method := range []string{http.MethodPost, http.MethodPatch, http.MethodConnect, "get"} {
+		if Retryable(method) {
+			t.Errorf("Expected %s requests not to be retried", method)
+		}
+	}
+}
+
+func TestSleepHonoursContextCancellation(t *testing.T) {
+	if err := Sleep(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("Expected a completed sleep, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	if err := Sleep(ctx, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the sleep to be cancelled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the sleep to end on cancellation, took %v", elapsed)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Sleep(ctx, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the sleep to end at the deadline, got %v", err)
+	}
+}
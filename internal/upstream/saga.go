@@ -0,0 +1,72 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+
+	"dynamiccontrol/internal/types"
+)
+
+// StepObserver is notified whenever a saga step changes status
+type StepObserver func(step, status, errorMessage string)
+
+// RunSaga executes calls sequentially. When a required call fails, the
+// compensations of every previously completed call run in reverse order,
+// each receiving the response body of the call it undoes. It returns the
+// results of the forward calls and the final operation status.
+func (cl *Client) RunSaga(ctx context.Context, calls []types.UpstreamCall, params map[string]string, body interface{}, observe StepObserver) (map[string]types.UpstreamResult, string) {
+	if observe == nil {
+		observe = func(string, string, string) {}
+	}
+
+	results := make(map[string]types.UpstreamResult, len(calls))
+	completed := make([]types.UpstreamCall, 0, len(calls))
+
+	for _, call := range calls {
+		observe(call.Name, types.OperationRunning, "")
+		result := cl.Call(ctx, call, params, body)
+		results[call.Name] = result
+
+		if result.Error == "" {
+			observe(call.Name, types.OperationSucceeded, "")
+			completed = append(completed, call)
+			continue
+		}
+
+		observe(call.Name, types.OperationFailed, result.Error)
+		if call.Optional {
+			continue
+		}
+
+		return results, cl.compensate(ctx, completed, results, params, observe)
+	}
+
+	return results, types.OperationSucceeded
+}
+
+// compensate runs compensation calls for completed steps in reverse order
+func (cl *Client) compensate(ctx context.Context, completed []types.UpstreamCall, results map[string]types.UpstreamResult, params map[string]string, observe StepObserver) string {
+	status := types.OperationCompensated
+
+	for i := len(completed) - 1; i >= 0; i-- {
+		call := completed[i]
+		if call.Compensation == nil {
+			continue
+		}
+
+		compensation := *call.Compensation
+		if compensation.Name == "" {
+			compensation.Name = call.Name + "-compensation"
+		}
+
+		result := cl.Call(ctx, compensation, params, results[call.Name].Body)
+		if result.Error != "" {
+			observe(call.Name, types.OperationCompensationFailed, fmt.Sprintf("compensation failed: %s", result.Error))
+			status = types.OperationCompensationFailed
+			continue
+		}
+		observe(call.Name, types.OperationCompensated, "")
+	}
+
+	return status
+}
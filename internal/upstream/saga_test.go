@@ -0,0 +1,59 @@
+package upstream
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestRunSagaCompensatesCompletedSteps(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	saga := []types.UpstreamCall{
+		{Name: "reserve", Method: "POST", URL: server.URL + "/reserve",
+			Compensation: &types.UpstreamCall{Method: "POST", URL: server.URL + "/release"}},
+		{Name: "charge", Method: "POST", URL: server.URL + "/charge",
+			Compensation: &types.UpstreamCall{Method: "POST", URL: server.URL + "/refund"}},
+		{Name: "ship", Method: "POST", URL: server.URL + "/fail"},
+	}
+
+	steps := make(map[string]string)
+	_, status := NewClient().RunSaga(context.Background(), saga, nil, nil, func(step, stepStatus, _ string) {
+		steps[step] = stepStatus
+	})
+
+	if status != types.OperationCompensated {
+		t.Errorf("expected status %s, got %s", types.OperationCompensated, status)
+	}
+
+	expected := []string{"POST /reserve", "POST /charge", "POST /fail", "POST /refund", "POST /release"}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected calls %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("call %d: expected %s, got %s", i, expected[i], calls[i])
+		}
+	}
+
+	if steps["reserve"] != types.OperationCompensated || steps["ship"] != types.OperationFailed {
+		t.Errorf("unexpected step statuses: %v", steps)
+	}
+}
@@ -0,0 +1,188 @@
+package validator
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Canonicalize normalizes a request body according to its schema so that
+// policies and upstreams always see the same representation of equivalent input
+func (sv *SchemaValidator) Canonicalize(schema map[string]interface{}, data interface{}, config types.CanonicalizeConfig) interface{} {
+	if len(schema) == 0 {
+		return data
+	}
+	return canonicalizeValue(schema, data, config)
+}
+
+// CanonicalJSON returns a deterministic JSON encoding of a canonicalized value.
+// encoding/json emits map keys in sorted order, which keeps the output stable
+// for use as a cache key.
+func CanonicalJSON(data interface{}) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+// canonicalizeValue normalizes a single value against its (sub)schema
+func canonicalizeValue(schema map[string]interface{}, data interface{}, config types.CanonicalizeConfig) interface{} {
+	if config.CoerceTypes {
+		data = coerceValue(schemaTypes(schema), data)
+	}
+
+	switch value := data.(type) {
+	case map[string]interface{}:
+		return canonicalizeObject(schema, value, config)
+	case []interface{}:
+		items, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			return value
+		}
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = canonicalizeValue(items, item, config)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// canonicalizeObject applies defaults, trims unknown fields and recurses into known properties
+func canonicalizeObject(schema map[string]interface{}, object map[string]interface{}, config types.CanonicalizeConfig) map[string]interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+	result := make(map[string]interface{}, len(object))
+
+	for key, value := range object {
+		propertySchema, known := properties[key].(map[string]interface{})
+		if !known {
+			if config.TrimUnknownFields {
+				continue
+			}
+			result[key] = value
+			continue
+		}
+		result[key] = canonicalizeValue(propertySchema, value, config)
+	}
+
+	if config.ApplyDefaults {
+		for key, property := range properties {
+			propertySchema, ok := property.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if _, present := result[key]; present {
+				continue
+			}
+			if defaultValue, hasDefault := propertySchema["default"]; hasDefault {
+				result[key] = canonicalizeValue(propertySchema, defaultValue, config)
+			}
+		}
+	}
+
+	return result
+}
+
+// schemaTypes returns the declared JSON schema types of a schema node
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		result := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	case []string:
+		return t
+	default:
+		return nil
+	}
+}
+
+// coerceValue converts scalar values into the first declared type they can represent
+func coerceValue(declared []string, data interface{}) interface{} {
+	if len(declared) == 0 || data == nil {
+		return data
+	}
+
+	for _, t := range declared {
+		if matchesType(t, data) {
+			return data
+		}
+	}
+
+	for _, t := range declared {
+		if coerced, ok := coerceTo(t, data); ok {
+			return coerced
+		}
+	}
+
+	return data
+}
+
+// matchesType reports whether a decoded JSON value already has the given schema type
+func matchesType(schemaType string, data interface{}) bool {
+	switch schemaType {
+	case "string":
+		_, ok := data.(string)
+		return ok
+	case "number":
+		_, ok := data.(float64)
+		return ok
+	case "integer":
+		f, ok := data.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := data.(bool)
+		return ok
+	case "object":
+		_, ok := data.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := data.([]interface{})
+		return ok
+	case "null":
+		return data == nil
+	default:
+		return false
+	}
+}
+
+// coerceTo attempts to convert a scalar value to the given schema type
+func coerceTo(schemaType string, data interface{}) (interface{}, bool) {
+	switch schemaType {
+	case "string":
+		switch v := data.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+	case "number":
+		if s, ok := data.(string); ok {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				return f, true
+			}
+		}
+	case "integer":
+		if s, ok := data.(string); ok {
+			if i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+				return float64(i), true
+			}
+		}
+	case "boolean":
+		if s, ok := data.(string); ok {
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				return b, true
+			}
+		}
+	case "array":
+		// A single value, such as a lone repeated XML element, is one item
+		return []interface{}{data}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,87 @@
+package validator
+
+import (
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+func trafficSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"trafficType": map[string]interface{}{"type": "string"},
+			"volume":      map[string]interface{}{"type": "number"},
+			"priority":    map[string]interface{}{"type": "string", "default": "medium"},
+			"metadata": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"retries": map[string]interface{}{"type": "integer"},
+				},
+			},
+		},
+	}
+}
+
+func TestCanonicalizeAppliesDefaultsAndCoercesTypes(t *testing.T) {
+	sv := NewSchemaValidator()
+	body := map[string]interface{}{
+		"trafficType": "incoming",
+		"volume":      "100.5",
+		"metadata": map[string]interface{}{
+			"retries": "3",
+		},
+	}
+
+	result := sv.Canonicalize(trafficSchema(), body, types.CanonicalizeConfig{
+		ApplyDefaults: true,
+		CoerceTypes:   true,
+	}).(map[string]interface{})
+
+	if result["volume"] != 100.5 {
+		t.Errorf("volume should be coerced to 100.5, got %v", result["volume"])
+	}
+
+	if result["priority"] != "medium" {
+		t.Errorf("priority should default to 'medium', got %v", result["priority"])
+	}
+
+	metadata := result["metadata"].(map[string]interface{})
+	if metadata["retries"] != float64(3) {
+		t.Errorf("metadata.retries should be coerced to 3, got %v", metadata["retries"])
+	}
+}
+
+func TestCanonicalizeTrimsUnknownFields(t *testing.T) {
+	sv := NewSchemaValidator()
+	body := map[string]interface{}{
+		"trafficType": "incoming",
+		"unexpected":  true,
+	}
+
+	trimmed := sv.Canonicalize(trafficSchema(), body, types.CanonicalizeConfig{TrimUnknownFields: true}).(map[string]interface{})
+	if _, exists := trimmed["unexpected"]; exists {
+		t.Error("unknown field should be trimmed")
+	}
+
+	kept := sv.Canonicalize(trafficSchema(), body, types.CanonicalizeConfig{}).(map[string]interface{})
+	if _, exists := kept["unexpected"]; !exists {
+		t.Error("unknown field should be kept when trimming is disabled")
+	}
+}
+
+func TestCanonicalJSONIsStable(t *testing.T) {
+	first, err := CanonicalJSON(map[string]interface{}{"b": 1, "a": 2})
+	if err != nil {
+		t.Fatalf("CanonicalJSON() error = %v", err)
+	}
+
+	second, err := CanonicalJSON(map[string]interface{}{"a": 2, "b": 1})
+	if err != nil {
+		t.Fatalf("CanonicalJSON() error = %v", err)
+	}
+
+	if string(first) != string(second) {
+		t.Errorf("CanonicalJSON should be order independent: %s != %s", first, second)
+	}
+}
@@ -0,0 +1,296 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Profiling settings
+const (
+	// DefaultHotspots is the number of keywords reported by a profile
+	DefaultHotspots = 10
+	// profileRuns is how often each keyword is validated to smooth out noise
+	profileRuns = 5
+	// maxProfileDepth bounds the schema walk, including through $ref cycles
+	maxProfileDepth = 32
+	// hotspotShare is the share of the total validation time above which a
+	// keyword gets restructuring advice
+	hotspotShare = 0.2
+	// largeEnum is the enum size above which restructuring is suggested
+	largeEnum = 32
+	// largeArray is the number of evaluated array items above which bounding
+	// the array is suggested
+	largeArray = 100
+)
+
+// annotationKeywords carry no validation cost and are not profiled
+var annotationKeywords = map[string]bool{
+	"$schema": true, "$id": true, "id": true, "$comment": true, "title": true,
+	"description": true, "default": true, "examples": true, "definitions": true,
+	"$defs": true, "readOnly": true, "writeOnly": true,
+}
+
+// KeywordTiming is the time spent validating one keyword of a schema. Times of
+// applicators such as properties, items or anyOf include their subschemas.
+type KeywordTiming struct {
+	// Path is the JSON pointer of the schema object holding the keyword
+	Path        string        `json:"path"`
+	Keyword     string        `json:"keyword"`
+	Duration    time.Duration `json:"duration"`
+	Evaluations int           `json:"evaluations"`
+}
+
+// SchemaProfile breaks down the validation time of a document by schema keyword
+type SchemaProfile struct {
+	Total       time.Duration   `json:"total"`
+	Hotspots    []KeywordTiming `json:"hotspots"`
+	Suggestions []string        `json:"suggestions,omitempty"`
+}
+
+// Profile validates data against every keyword of the schema in isolation
+// and reports the most expensive ones, with suggestions for restructuring
+// them. It is many times slower than a plain validation and is meant for
+// sampled requests and debugging.
+func (sv *SchemaValidator) Profile(schema map[string]interface{}, data interface{}, hotspots int) (*SchemaProfile, error) {
+	if hotspots <= 0 {
+		hotspots = DefaultHotspots
+	}
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema: %w", err)
+	}
+
+	p := &profiler{
+		root:    schema,
+		timings: make(map[string]*KeywordTiming),
+		schemas: make(map[string]map[string]interface{}),
+		visits:  make(map[string]int),
+		cache:   make(map[string]*gojsonschema.Schema),
+	}
+	profile := &SchemaProfile{Total: timeValidation(compiled, data)}
+	p.walk(schema, "#", data, 0)
+
+	for _, timing := range p.timings {
+		profile.Hotspots = append(profile.Hotspots, *timing)
+	}
+	sort.Slice(profile.Hotspots, func(i, j int) bool {
+		if profile.Hotspots[i].Duration != profile.Hotspots[j].Duration {
+			return profile.Hotspots[i].Duration > profile.Hotspots[j].Duration
+		}
+		return profile.Hotspots[i].Path+profile.Hotspots[i].Keyword < profile.Hotspots[j].Path+profile.Hotspots[j].Keyword
+	})
+	if len(profile.Hotspots) > hotspots {
+		profile.Hotspots = profile.Hotspots[:hotspots]
+	}
+	profile.Suggestions = p.suggest(profile)
+	return profile, nil
+}
+
+// profiler accumulates keyword timings while walking a schema alongside the
+// document validated against it
+type profiler struct {
+	root    map[string]interface{}
+	timings map[string]*KeywordTiming
+	schemas map[string]map[string]interface{}
+	visits  map[string]int
+	cache   map[string]*gojsonschema.Schema
+}
+
+// walk times every keyword of a schema object against the instance and
+// descends into the subschemas that apply to it
+func (p *profiler) walk(node map[string]interface{}, path string, instance interface{}, depth int) {
+	if depth > maxProfileDepth {
+		return
+	}
+	p.schemas[path] = node
+	p.visits[path]++
+
+	for keyword, value := range node {
+		if annotationKeywords[keyword] {
+			continue
+		}
+		p.time(node, path, keyword, value, instance)
+	}
+
+	object, isObject := instance.(map[string]interface{})
+	array, isArray := instance.([]interface{})
+
+	if properties, ok := node["properties"].(map[string]interface{}); ok && isObject {
+		for name, child := range properties {
+			if value, exists := object[name]; exists {
+				p.descend(child, path+"/properties/"+escapePointer(name), value, depth)
+			}
+		}
+	}
+	if patterns, ok := node["patternProperties"].(map[string]interface{}); ok && isObject {
+		for pattern, child := range patterns {
+			expression, err := regexp.Compile(pattern)
+			if err != nil {
+				continue
+			}
+			for name, value := range object {
+				if expression.MatchString(name) {
+					p.descend(child, path+"/patternProperties/"+escapePointer(pattern), value, depth)
+				}
+			}
+		}
+	}
+	if additional, ok := node["additionalProperties"].(map[string]interface{}); ok && isObject {
+		properties, _ := node["properties"].(map[string]interface{})
+		for name, value := range object {
+			if _, declared := properties[name]; !declared {
+				p.descend(additional, path+"/additionalProperties", value, depth)
+			}
+		}
+	}
+	if isArray {
+		switch items := node["items"].(type) {
+		case map[string]interface{}:
+			for _, value := range array {
+				p.descend(items, path+"/items", value, depth)
+			}
+		case []interface{}:
+			for i, child := range items {
+				if i < len(array) {
+					p.descend(child, path+"/items/"+strconv.Itoa(i), array[i], depth)
+				}
+			}
+		}
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		if branches, ok := node[keyword].([]interface{}); ok {
+			for i, child := range branches {
+				p.descend(child, path+"/"+keyword+"/"+strconv.Itoa(i), instance, depth)
+			}
+		}
+	}
+	for _, keyword := range []string{"not", "if", "then", "else"} {
+		p.descend(node[keyword], path+"/"+keyword, instance, depth)
+	}
+	if ref, ok := node["$ref"].(string); ok {
+		if target, resolved := p.resolve(ref); resolved {
+			p.descend(target, ref, instance, depth)
+		}
+	}
+}
+
+// descend walks a subschema when it is a schema object
+func (p *profiler) descend(schema interface{}, path string, instance interface{}, depth int) {
+	if child, ok := schema.(map[string]interface{}); ok {
+		p.walk(child, path, instance, depth+1)
+	}
+}
+
+// time validates the instance against a single keyword and records the
+// elapsed time. Keywords that depend on siblings are validated with them.
+func (p *profiler) time(node map[string]interface{}, path, keyword string, value, instance interface{}) {
+	key := path + " " + keyword
+	compiled, cached := p.cache[key]
+	if !cached {
+		isolated := map[string]interface{}{keyword: value}
+		for _, sibling := range keywordSiblings[keyword] {
+			if siblingValue, exists := node[sibling]; exists {
+				isolated[sibling] = siblingValue
+			}
+		}
+		for _, definitions := range []string{"definitions", "$defs"} {
+			if value, exists := p.root[definitions]; exists {
+				isolated[definitions] = value
+			}
+		}
+		compiled, _ = gojsonschema.NewSchema(gojsonschema.NewGoLoader(isolated))
+		p.cache[key] = compiled
+	}
+	if compiled == nil {
+		return
+	}
+
+	timing, exists := p.timings[key]
+	if !exists {
+		timing = &KeywordTiming{Path: path, Keyword: keyword}
+		p.timings[key] = timing
+	}
+	timing.Duration += timeValidation(compiled, instance)
+	timing.Evaluations++
+}
+
+// keywordSiblings are the keywords whose result depends on sibling keywords
+var keywordSiblings = map[string][]string{
+	"additionalProperties": {"properties", "patternProperties"},
+	"additionalItems":      {"items"},
+	"then":                 {"if"},
+	"else":                 {"if"},
+}
+
+// resolve returns the schema referenced by a local JSON pointer
+func (p *profiler) resolve(ref string) (interface{}, bool) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	var current interface{} = p.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		if current, ok = object[token]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// timeValidation returns the average time of validating data against a schema
+func timeValidation(schema *gojsonschema.Schema, data interface{}) time.Duration {
+	loader := gojsonschema.NewGoLoader(data)
+	start := time.Now()
+	for i := 0; i < profileRuns; i++ {
+		schema.Validate(loader)
+	}
+	return time.Since(start) / profileRuns
+}
+
+// escapePointer escapes a property name for use in a JSON pointer
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
+
+// suggest returns restructuring advice for the hotspots that dominate the
+// validation time
+func (p *profiler) suggest(profile *SchemaProfile) []string {
+	var suggestions []string
+	for _, hotspot := range profile.Hotspots {
+		if profile.Total <= 0 || float64(hotspot.Duration) < hotspotShare*float64(profile.Total) {
+			continue
+		}
+		location := hotspot.Path + "/" + hotspot.Keyword
+		switch hotspot.Keyword {
+		case "pattern":
+			suggestions = append(suggestions, fmt.Sprintf("%s: regular expressions are costly; prefer enum, format or length limits, or anchor and simplify the pattern", location))
+		case "patternProperties":
+			suggestions = append(suggestions, fmt.Sprintf("%s: every property name is matched against every pattern; declare known names under properties", location))
+		case "oneOf":
+			suggestions = append(suggestions, fmt.Sprintf("%s: oneOf validates every branch to prove exactly one matches; use anyOf for exclusive branches, or select the branch with if/then on a discriminator property", location))
+		case "anyOf":
+			suggestions = append(suggestions, fmt.Sprintf("%s: anyOf tries branches until one matches; list the most common branch first, or select it with if/then on a discriminator property", location))
+		case "uniqueItems":
+			suggestions = append(suggestions, fmt.Sprintf("%s: uniqueItems compares every pair of items; bound the array with maxItems or enforce uniqueness upstream", location))
+		case "enum":
+			if values, ok := p.schemas[hotspot.Path]["enum"].([]interface{}); ok && len(values) > largeEnum {
+				suggestions = append(suggestions, fmt.Sprintf("%s: the %d enum values are compared one by one; split them by a prefix or validate them with a pattern", location, len(values)))
+			}
+		case "items":
+			if items := p.visits[location]; items > largeArray {
+				suggestions = append(suggestions, fmt.Sprintf("%s: %d array items were validated; bound the array with maxItems", location, items))
+			}
+		}
+	}
+	return suggestions
+}
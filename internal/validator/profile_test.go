@@ -0,0 +1,79 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestProfileReportsKeywordsPerSchemaLocation(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"tags": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"$ref": "#/definitions/tag"},
+			},
+		},
+		"definitions": map[string]interface{}{
+			"tag": map[string]interface{}{"type": "string", "pattern": "^[a-z]+-[0-9]+$"},
+		},
+	}
+	tags := make([]interface{}, 150)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("tag-%d", i)
+	}
+
+	profile, err := NewSchemaValidator().Profile(schema, map[string]interface{}{"tags": tags}, 100)
+	if err != nil {
+		t.Fatalf("Failed to profile schema: %v", err)
+	}
+	if profile.Total <= 0 {
+		t.Errorf("Expected a total validation time, got %v", profile.Total)
+	}
+
+	found := false
+	for _, hotspot := range profile.Hotspots {
+		if hotspot.Keyword == "definitions" {
+			t.Error("Expected annotations not to be profiled")
+		}
+		if hotspot.Path == "#/definitions/tag" && hotspot.Keyword == "pattern" {
+			found = true
+			if hotspot.Evaluations != len(tags) {
+				t.Errorf("Expected the pattern to be evaluated for %d items, got %d", len(tags), hotspot.Evaluations)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected the referenced pattern to be profiled, got %+v", profile.Hotspots)
+	}
+	if len(profile.Suggestions) == 0 || !strings.Contains(strings.Join(profile.Suggestions, "\n"), "maxItems") {
+		t.Errorf("Expected a suggestion to bound the array, got %v", profile.Suggestions)
+	}
+}
+
+func TestProfileLimitsHotspots(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"a"},
+		"properties": map[string]interface{}{
+			"a": map[string]interface{}{"type": "string", "minLength": 1, "maxLength": 10},
+		},
+	}
+	profile, err := NewSchemaValidator().Profile(schema, map[string]interface{}{"a": "value"}, 2)
+	if err != nil {
+		t.Fatalf("Failed to profile schema: %v", err)
+	}
+	if len(profile.Hotspots) != 2 {
+		t.Errorf("Expected 2 hotspots, got %d", len(profile.Hotspots))
+	}
+	if profile.Hotspots[0].Duration < profile.Hotspots[1].Duration {
+		t.Error("Expected hotspots sorted by duration")
+	}
+}
+
+func TestProfileRejectsInvalidSchema(t *testing.T) {
+	if _, err := NewSchemaValidator().Profile(map[string]interface{}{"type": 42}, nil, 0); err == nil {
+		t.Error("Expected an invalid schema to be rejected")
+	}
+}
@@ -0,0 +1,196 @@
+package validator
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// schemaFileExt is the extension of schema version files in the registry
+const schemaFileExt = ".json"
+
+// Registry names and versions are single path segments
+var (
+	registryName    = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+	registryVersion = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// ParseSchemaRef splits a schema reference such as "traffic-request@v2" into
+// its name and version; the version is empty for unversioned references
+func ParseSchemaRef(ref string) (string, string, error) {
+	name, version, versioned := strings.Cut(ref, "@")
+	if !registryName.MatchString(name) {
+		return "", "", fmt.Errorf("invalid schema name %q", name)
+	}
+	if versioned && !registryVersion.MatchString(version) {
+		return "", "", fmt.Errorf("invalid schema version %q", version)
+	}
+	return name, version, nil
+}
+
+// ResolveSchemaRef returns the registered schema a reference names. An
+// unversioned reference resolves to the latest version of the schema.
+func (sv *SchemaValidator) ResolveSchemaRef(ref string) (map[string]interface{}, error) {
+	name, version, err := ParseSchemaRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	if version == "" {
+		versions, err := sv.SchemaVersions(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("schema %s is not registered", name)
+		}
+		version = versions[len(versions)-1]
+	}
+	return sv.RegisteredSchema(name, version)
+}
+
+// RegisteredSchema returns a version of a registered schema
+func (sv *SchemaValidator) RegisteredSchema(name, version string) (map[string]interface{}, error) {
+	path, err := sv.registryPath(name, version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("schema %s@%s is not registered", name, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema %s@%s: %w", name, version, err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema %s@%s: %w", name, version, err)
+	}
+	return schema, nil
+}
+
+// RegisterSchema stores a new version of a schema. The schema must compile,
+// and versions are immutable: registering an existing version fails.
+func (sv *SchemaValidator) RegisterSchema(name, version string, schema map[string]interface{}) error {
+	path, err := sv.registryPath(name, version)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+	if _, err := sv.compile(&PreparedSchema{key: sha256.Sum256(data), encoded: data}); err != nil {
+		return fmt.Errorf("failed to compile schema: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create schema directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if os.IsExist(err) {
+		return fmt.Errorf("schema %s@%s is already registered", name, version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create schema file: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write schema file: %w", err)
+	}
+	return file.Close()
+}
+
+// SchemaVersions returns the registered versions of a schema, oldest first
+func (sv *SchemaValidator) SchemaVersions(name string) ([]string, error) {
+	dir := sv.GetSchemaDir()
+	if dir == "" {
+		return nil, fmt.Errorf("no schema directory is configured")
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schema versions: %w", err)
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		version, ok := strings.CutSuffix(entry.Name(), schemaFileExt)
+		if ok && !entry.IsDir() && registryVersion.MatchString(version) {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versionLess(versions[i], versions[j])
+	})
+	return versions, nil
+}
+
+// RegisteredSchemas returns every registered schema name with its versions
+func (sv *SchemaValidator) RegisteredSchemas() (map[string][]string, error) {
+	dir := sv.GetSchemaDir()
+	if dir == "" {
+		return nil, fmt.Errorf("no schema directory is configured")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schemas: %w", err)
+	}
+
+	schemas := make(map[string][]string)
+	for _, entry := range entries {
+		if !entry.IsDir() || !registryName.MatchString(entry.Name()) {
+			continue
+		}
+		versions, err := sv.SchemaVersions(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) > 0 {
+			schemas[entry.Name()] = versions
+		}
+	}
+	return schemas, nil
+}
+
+// registryPath returns the file holding a schema version
+func (sv *SchemaValidator) registryPath(name, version string) (string, error) {
+	dir := sv.GetSchemaDir()
+	if dir == "" {
+		return "", fmt.Errorf("no schema directory is configured")
+	}
+	if !registryName.MatchString(name) {
+		return "", fmt.Errorf("invalid schema name %q", name)
+	}
+	if !registryVersion.MatchString(version) {
+		return "", fmt.Errorf("invalid schema version %q", version)
+	}
+	return filepath.Join(dir, name, version+schemaFileExt), nil
+}
+
+// versionLess orders versions such as v2 and v10 or 1.2.0 and 1.10.0
+// numerically, falling back to lexical order for other segments
+func versionLess(a, b string) bool {
+	partsA := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	partsB := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		if partsA[i] == partsB[i] {
+			continue
+		}
+		numberA, errA := strconv.Atoi(partsA[i])
+		numberB, errB := strconv.Atoi(partsB[i])
+		if errA == nil && errB == nil {
+			return numberA < numberB
+		}
+		return partsA[i] < partsB[i]
+	}
+	return len(partsA) < len(partsB)
+}
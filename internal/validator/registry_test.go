@@ -0,0 +1,49 @@
+package validator
+
+import (
+	"testing"
+)
+
+func TestSchemaRegistryVersions(t *testing.T) {
+	sv := NewSchemaValidator()
+	if err := sv.SetSchemaDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to set schema directory: %v", err)
+	}
+
+	for _, version := range []string{"v2", "v10", "v1"} {
+		schema := map[string]interface{}{"type": "object", "title": version}
+		if err := sv.RegisterSchema("traffic-request", version, schema); err != nil {
+			t.Fatalf("Failed to register %s: %v", version, err)
+		}
+	}
+	if err := sv.RegisterSchema("traffic-request", "v2", map[string]interface{}{"type": "string"}); err == nil {
+		t.Error("Expected registering an existing version to fail")
+	}
+	if err := sv.RegisterSchema("traffic-request", "v11", map[string]interface{}{"type": 42}); err == nil {
+		t.Error("Expected a schema that does not compile to be rejected")
+	}
+	if err := sv.RegisterSchema("../escape", "v1", map[string]interface{}{}); err == nil {
+		t.Error("Expected an invalid name to be rejected")
+	}
+
+	versions, err := sv.SchemaVersions("traffic-request")
+	if err != nil || len(versions) != 3 || versions[0] != "v1" || versions[2] != "v10" {
+		t.Errorf("Expected versions in numeric order, got %v (%v)", versions, err)
+	}
+
+	cases := map[string]string{"traffic-request@v2": "v2", "traffic-request": "v10"}
+	for ref, title := range cases {
+		schema, err := sv.ResolveSchemaRef(ref)
+		if err != nil || schema["title"] != title {
+			t.Errorf("Expected %s to resolve to %s, got %v (%v)", ref, title, schema, err)
+		}
+	}
+	if _, err := sv.ResolveSchemaRef("traffic-request@v3"); err == nil {
+		t.Error("Expected an unknown version not to resolve")
+	}
+
+	registered, err := sv.RegisteredSchemas()
+	if err != nil || len(registered) != 1 || len(registered["traffic-request"]) != 3 {
+		t.Errorf("Expected one registered schema with three versions, got %v (%v)", registered, err)
+	}
+}
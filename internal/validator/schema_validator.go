@@ -1,44 +1,133 @@
 package validator
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"dynamiccontrol/internal/types"
 
-	"github.com/xeipuuv/gojsonschema"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 )
 
-// SchemaValidator handles JSON schema validation
+// maxCompiledSchemas bounds the compiled schema cache; it is reset when full
+// so schemas of replaced routes do not accumulate
+const maxCompiledSchemas = 1024
+
+// compiledSchemaFactor approximates the memory of a compiled schema as a
+// multiple of its encoded size
+const compiledSchemaFactor = 8
+
+// routeSchemaName is the resource name of an inline route schema; relative
+// references are resolved against it, and so against the schema directory
+const routeSchemaName = "route-schema.json"
+
+// SchemaValidator handles JSON schema validation. Schemas default to draft-07
+// and may declare draft 2019-09 or 2020-12 with $schema. Compiled schemas are
+// cached by content hash, so each distinct schema is compiled once.
 type SchemaValidator struct {
-	schemas map[string]*gojsonschema.Schema
+	mu      sync.RWMutex
+	schemas map[[sha256.Size]byte]compiledSchema
+	dir     string
+}
+
+// compiledSchema is a cached compiled schema with its approximate size
+type compiledSchema struct {
+	schema *jsonschema.Schema
+	size   int64
 }
 
 // NewSchemaValidator creates a new schema validator
 func NewSchemaValidator() *SchemaValidator {
 	return &SchemaValidator{
-		schemas: make(map[string]*gojsonschema.Schema),
+		schemas: make(map[[sha256.Size]byte]compiledSchema),
+	}
+}
+
+// SetSchemaDir sets the directory holding shared schema files. Route schemas
+// reference them with relative $refs such as "common/address.json" or
+// "common/address.json#/$defs/street"; references outside the directory are
+// rejected.
+func (sv *SchemaValidator) SetSchemaDir(dir string) error {
+	absolute, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve schema directory: %w", err)
+	}
+	info, err := os.Stat(absolute)
+	if err != nil {
+		return fmt.Errorf("failed to open schema directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("schema directory %s is not a directory", dir)
+	}
+
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	sv.dir = absolute
+	sv.schemas = make(map[[sha256.Size]byte]compiledSchema)
+	return nil
+}
+
+// GetSchemaDir returns the shared schema directory, or an empty string when none is set
+func (sv *SchemaValidator) GetSchemaDir() string {
+	sv.mu.RLock()
+	defer sv.mu.RUnlock()
+	return sv.dir
+}
+
+// PreparedSchema is a schema encoded once, such as when a route is
+// registered, so validations find its compiled form by key without encoding
+// the schema again
+type PreparedSchema struct {
+	key     [sha256.Size]byte
+	encoded []byte
+	err     error
+}
+
+// PrepareSchema encodes a schema for repeated validation. Empty schemas
+// prepare to nil, which accepts every document.
+func PrepareSchema(schema map[string]interface{}) *PreparedSchema {
+	if len(schema) == 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(schema)
+	if err != nil {
+		return &PreparedSchema{err: err}
 	}
+	return &PreparedSchema{key: sha256.Sum256(encoded), encoded: encoded}
 }
 
-// ValidateRequest validates a request against its schema
+// ValidateRequest validates a request against its schema. Callers
+// validating many documents against the same schema should prepare it once
+// and use ValidatePrepared.
 func (sv *SchemaValidator) ValidateRequest(schema map[string]interface{}, data interface{}) *types.ValidationResult {
-	if schema == nil || len(schema) == 0 {
+	return sv.ValidatePrepared(PrepareSchema(schema), data)
+}
+
+// ValidatePrepared validates a document against a prepared schema
+func (sv *SchemaValidator) ValidatePrepared(schema *PreparedSchema, data interface{}) *types.ValidationResult {
+	if schema == nil {
 		return &types.ValidationResult{
 			Valid: true,
 		}
 	}
-
-	schemaBytes, err := json.Marshal(schema)
-	if err != nil {
+	if schema.err != nil {
 		return &types.ValidationResult{
 			Valid:  false,
-			Errors: []string{fmt.Sprintf("Invalid schema: %v", err)},
+			Errors: []string{fmt.Sprintf("Invalid schema: %v", schema.err)},
 		}
 	}
 
-	dataBytes, err := json.Marshal(data)
+	document, err := decodeInstance(data)
 	if err != nil {
 		return &types.ValidationResult{
 			Valid:  false,
@@ -46,10 +135,7 @@ func (sv *SchemaValidator) ValidateRequest(schema map[string]interface{}, data i
 		}
 	}
 
-	schemaLoader := gojsonschema.NewBytesLoader(schemaBytes)
-	documentLoader := gojsonschema.NewBytesLoader(dataBytes)
-
-	result, err := gojsonschema.Validate(schemaLoader, documentLoader)
+	compiled, err := sv.compile(schema)
 	if err != nil {
 		return &types.ValidationResult{
 			Valid:  false,
@@ -57,27 +143,211 @@ func (sv *SchemaValidator) ValidateRequest(schema map[string]interface{}, data i
 		}
 	}
 
-	if result.Valid() {
+	err = compiled.Validate(document)
+	if err == nil {
 		return &types.ValidationResult{
 			Valid: true,
 		}
 	}
 
-	errors := make([]string, 0, len(result.Errors()))
-	for _, err := range result.Errors() {
-		errors = append(errors, err.String())
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return &types.ValidationResult{
+			Valid:  false,
+			Errors: []string{fmt.Sprintf("Validation error: %v", err)},
+		}
 	}
 
+	messages := validationMessages(validationErr)
 	return &types.ValidationResult{
 		Valid:   false,
-		Errors:  errors,
-		Details: fmt.Sprintf("Validation failed with %d errors", len(errors)),
+		Errors:  messages,
+		Details: fmt.Sprintf("Validation failed with %d errors", len(messages)),
+	}
+}
+
+// decodeInstance converts a value to the generic JSON representation the
+// validator works on, keeping numbers exact
+func decodeInstance(data interface{}) (interface{}, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(dataBytes))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// validationMessages flattens a validation error into one message per
+// failed keyword, prefixed with the dotted location of the offending value
+func validationMessages(err *jsonschema.ValidationError) []string {
+	if len(err.Causes) > 0 {
+		var messages []string
+		for _, cause := range err.Causes {
+			messages = append(messages, validationMessages(cause)...)
+		}
+		return messages
+	}
+
+	location := "(root)"
+	if err.InstanceLocation != "" {
+		location = strings.ReplaceAll(strings.TrimPrefix(err.InstanceLocation, "/"), "/", ".")
+	}
+	return []string{location + ": " + err.Message}
+}
+
+// ValidateParameters validates query parameters or headers against a schema
+// describing them as an object. Values arrive as strings, so they are first
+// coerced to the types declared for them; parameters declared as arrays
+// receive every value, others their first one.
+func (sv *SchemaValidator) ValidateParameters(schema map[string]interface{}, values map[string][]string) *types.ValidationResult {
+	if len(schema) == 0 {
+		return &types.ValidationResult{
+			Valid: true,
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	document := make(map[string]interface{}, len(values))
+	for name, list := range values {
+		if len(list) == 0 {
+			continue
+		}
+		property, _ := properties[name].(map[string]interface{})
+		declared := schemaTypes(property)
+		if containsType(declared, "array") {
+			items, _ := property["items"].(map[string]interface{})
+			elements := make([]interface{}, len(list))
+			for i, value := range list {
+				elements[i] = coerceValue(schemaTypes(items), value)
+			}
+			document[name] = elements
+			continue
+		}
+		document[name] = coerceValue(declared, list[0])
+	}
+	return sv.ValidateRequest(schema, document)
+}
+
+// containsType reports whether a schema type is among the declared ones
+func containsType(declared []string, schemaType string) bool {
+	for _, t := range declared {
+		if t == schemaType {
+			return true
+		}
+	}
+	return false
+}
+
+// compile returns the compiled form of a prepared schema, compiling it on
+// first use
+func (sv *SchemaValidator) compile(schema *PreparedSchema) (*jsonschema.Schema, error) {
+	sv.mu.RLock()
+	cached, exists := sv.schemas[schema.key]
+	dir := sv.dir
+	sv.mu.RUnlock()
+	if exists {
+		return cached.schema, nil
+	}
+
+	root := dir
+	if root == "" {
+		root = string(filepath.Separator)
+	}
+	base := "file://" + filepath.ToSlash(filepath.Join(root, routeSchemaName))
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	compiler.AssertFormat = true
+	compiler.LoadURL = func(ref string) (io.ReadCloser, error) {
+		return loadSchemaFile(dir, ref)
+	}
+	if err := compiler.AddResource(base, bytes.NewReader(schema.encoded)); err != nil {
+		return nil, err
+	}
+	compiled, err := compiler.Compile(base)
+	if err != nil {
+		return nil, err
+	}
+
+	sv.mu.Lock()
+	if len(sv.schemas) >= maxCompiledSchemas {
+		sv.schemas = make(map[[sha256.Size]byte]compiledSchema)
+	}
+	sv.schemas[schema.key] = compiledSchema{schema: compiled, size: int64(len(schema.encoded)) * compiledSchemaFactor}
+	sv.mu.Unlock()
+	return compiled, nil
+}
+
+// Name identifies the compiled schema cache to the memory manager
+func (sv *SchemaValidator) Name() string {
+	return "compiled_schemas"
+}
+
+// SizeBytes returns the approximate memory held by compiled schemas
+func (sv *SchemaValidator) SizeBytes() int64 {
+	sv.mu.RLock()
+	defer sv.mu.RUnlock()
+
+	var size int64
+	for _, cached := range sv.schemas {
+		size += cached.size
+	}
+	return size
+}
+
+// Shrink drops compiled schemas, largest first, until the cache holds at
+// most target bytes. Dropped schemas are compiled again on their next use.
+func (sv *SchemaValidator) Shrink(target int64) int64 {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	keys := make([][sha256.Size]byte, 0, len(sv.schemas))
+	var size int64
+	for key, cached := range sv.schemas {
+		keys = append(keys, key)
+		size += cached.size
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return sv.schemas[keys[i]].size > sv.schemas[keys[j]].size
+	})
+
+	var freed int64
+	for _, key := range keys {
+		if size <= target {
+			break
+		}
+		released := sv.schemas[key].size
+		delete(sv.schemas, key)
+		size -= released
+		freed += released
+	}
+	return freed
+}
+
+// loadSchemaFile opens a referenced schema file, refusing references that
+// leave the schema directory or point to other locations
+func loadSchemaFile(dir, ref string) (io.ReadCloser, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("cannot resolve $ref %s: no schema directory is configured", ref)
+	}
+	parsed, err := url.Parse(ref)
+	if err != nil || parsed.Scheme != "file" {
+		return nil, fmt.Errorf("cannot resolve $ref %s: only schema directory references are supported", ref)
+	}
+	path := filepath.Clean(filepath.FromSlash(parsed.Path))
+	if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return nil, fmt.Errorf("cannot resolve $ref %s: outside the schema directory", ref)
 	}
+	return os.Open(path)
 }
 
 // ValidateResponse validates a response against its schema
 func (sv *SchemaValidator) ValidateResponse(schema map[string]interface{}, data interface{}) *types.ValidationResult {
-	return sv.ValidateRequest(schema, data)
+	return sv.ValidatePrepared(PrepareSchema(schema), data)
 }
 
 // ValidateTrafficRequest validates a traffic request
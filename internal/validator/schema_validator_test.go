@@ -0,0 +1,196 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateRequestCachesCompiledSchemas(t *testing.T) {
+	sv := NewSchemaValidator()
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"serviceId"},
+	}
+
+	if result := sv.ValidateRequest(schema, map[string]interface{}{"serviceId": "svc-1"}); !result.Valid {
+		t.Errorf("Expected a valid request, got %v", result.Errors)
+	}
+	if result := sv.ValidateRequest(schema, map[string]interface{}{}); result.Valid {
+		t.Errorf("Expected a request without serviceId to be invalid")
+	}
+	// An equal schema of another route shares the compiled schema
+	copied := map[string]interface{}{"required": []interface{}{"serviceId"}, "type": "object"}
+	sv.ValidateResponse(copied, map[string]interface{}{"serviceId": "svc-1"})
+	if len(sv.schemas) != 1 {
+		t.Errorf("Expected one compiled schema, got %d", len(sv.schemas))
+	}
+
+	// A changed schema is compiled anew
+	schema["required"] = []interface{}{"serviceId", "trafficType"}
+	if result := sv.ValidateRequest(schema, map[string]interface{}{"serviceId": "svc-1"}); result.Valid {
+		t.Errorf("Expected the changed schema to apply")
+	}
+	if len(sv.schemas) != 2 {
+		t.Errorf("Expected two compiled schemas, got %d", len(sv.schemas))
+	}
+}
+
+func TestShrinkDropsLargestCompiledSchemas(t *testing.T) {
+	sv := NewSchemaValidator()
+	small := map[string]interface{}{"type": "object"}
+	large := map[string]interface{}{
+		"type":       "object",
+		"required":   []interface{}{"serviceId", "trafficType"},
+		"properties": map[string]interface{}{"serviceId": map[string]interface{}{"type": "string"}},
+	}
+	sv.ValidateRequest(small, map[string]interface{}{})
+	sv.ValidateRequest(large, map[string]interface{}{})
+
+	size := sv.SizeBytes()
+	freed := sv.Shrink(size - 1)
+	if freed == 0 || sv.SizeBytes() != size-freed || len(sv.schemas) != 1 {
+		t.Fatalf("Expected one schema to be dropped, freed %d of %d", freed, size)
+	}
+	if _, exists := sv.schemas[PrepareSchema(small).key]; !exists {
+		t.Error("Expected the largest schema to be dropped first")
+	}
+	if result := sv.ValidateRequest(large, map[string]interface{}{}); result.Valid {
+		t.Error("Expected a dropped schema to be compiled again")
+	}
+}
+
+func TestValidatePreparedEncodesSchemaOnce(t *testing.T) {
+	sv := NewSchemaValidator()
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"serviceId"},
+	}
+	prepared := PrepareSchema(schema)
+
+	if result := sv.ValidatePrepared(prepared, map[string]interface{}{}); result.Valid {
+		t.Errorf("Expected a request without serviceId to be invalid")
+	}
+	// The prepared schema keeps the encoding it was prepared with
+	schema["required"] = []interface{}{}
+	if result := sv.ValidatePrepared(prepared, map[string]interface{}{}); result.Valid {
+		t.Errorf("Expected the prepared schema not to be encoded again")
+	}
+	if len(sv.schemas) != 1 {
+		t.Errorf("Expected one compiled schema, got %d", len(sv.schemas))
+	}
+
+	if PrepareSchema(nil) != nil {
+		t.Error("Expected an empty schema to prepare to nil")
+	}
+	if result := sv.ValidatePrepared(nil, "anything"); !result.Valid {
+		t.Error("Expected a nil prepared schema to accept every document")
+	}
+}
+
+func TestValidateRequestRejectsInvalidSchema(t *testing.T) {
+	sv := NewSchemaValidator()
+	result := sv.ValidateRequest(map[string]interface{}{"type": 42}, map[string]interface{}{})
+	if result.Valid || len(result.Errors) == 0 {
+		t.Errorf("Expected an invalid schema to fail validation, got %+v", result)
+	}
+	if len(sv.schemas) != 0 {
+		t.Errorf("Expected invalid schemas not to be cached, got %d", len(sv.schemas))
+	}
+}
+
+func TestValidateParametersCoercesValues(t *testing.T) {
+	sv := NewSchemaValidator()
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"limit":   map[string]interface{}{"type": "integer", "maximum": 100},
+			"verbose": map[string]interface{}{"type": "boolean"},
+			"tag":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+		"required": []interface{}{"limit"},
+	}
+
+	valid := map[string][]string{"limit": {"10"}, "verbose": {"true"}, "tag": {"a", "b"}}
+	if result := sv.ValidateParameters(schema, valid); !result.Valid {
+		t.Errorf("Expected coerced parameters to be valid, got %v", result.Errors)
+	}
+	cases := []map[string][]string{
+		{"verbose": {"true"}},
+		{"limit": {"ten"}},
+		{"limit": {"500"}},
+	}
+	for _, values := range cases {
+		if result := sv.ValidateParameters(schema, values); result.Valid {
+			t.Errorf("Expected %v to be invalid", values)
+		}
+	}
+}
+
+func TestValidateRequestSupportsNewerDrafts(t *testing.T) {
+	sv := NewSchemaValidator()
+	schema := map[string]interface{}{
+		"$schema":           "https://json-schema.org/draft/2020-12/schema",
+		"type":              "object",
+		"dependentRequired": map[string]interface{}{"upstream": []interface{}{"weight"}},
+		"properties": map[string]interface{}{
+			"range": map[string]interface{}{
+				"prefixItems": []interface{}{map[string]interface{}{"type": "integer"}, map[string]interface{}{"type": "integer"}},
+				"items":       false,
+			},
+		},
+	}
+
+	if result := sv.ValidateRequest(schema, map[string]interface{}{"upstream": "a", "weight": 10, "range": []interface{}{1, 2}}); !result.Valid {
+		t.Errorf("Expected a valid request, got %v", result.Errors)
+	}
+	if result := sv.ValidateRequest(schema, map[string]interface{}{"upstream": "a"}); result.Valid {
+		t.Error("Expected dependentRequired to apply")
+	}
+	result := sv.ValidateRequest(schema, map[string]interface{}{"range": []interface{}{1, 2, 3}})
+	if result.Valid {
+		t.Fatal("Expected prefixItems with items false to reject extra elements")
+	}
+	if !strings.HasPrefix(result.Errors[0], "range.2: ") {
+		t.Errorf("Expected the error to name the offending field, got %v", result.Errors)
+	}
+}
+
+func TestValidateRequestResolvesSchemaDirectoryRefs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "common"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	address := `{"$defs": {"zip": {"type": "string", "pattern": "^[0-9]{5}$"}}, "type": "object", "required": ["zip"], "properties": {"zip": {"$ref": "#/$defs/zip"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "common", "address.json"), []byte(address), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sv := NewSchemaValidator()
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"billing": map[string]interface{}{"$ref": "common/address.json"},
+			"zip":     map[string]interface{}{"$ref": "common/address.json#/$defs/zip"},
+		},
+	}
+	if result := sv.ValidateRequest(schema, map[string]interface{}{"zip": "12345"}); result.Valid {
+		t.Error("Expected references to fail without a schema directory")
+	}
+
+	if err := sv.SetSchemaDir(dir); err != nil {
+		t.Fatalf("Failed to set schema directory: %v", err)
+	}
+	if result := sv.ValidateRequest(schema, map[string]interface{}{"billing": map[string]interface{}{"zip": "12345"}, "zip": "54321"}); !result.Valid {
+		t.Errorf("Expected a valid request, got %v", result.Errors)
+	}
+	if result := sv.ValidateRequest(schema, map[string]interface{}{"billing": map[string]interface{}{"zip": "abc"}}); result.Valid {
+		t.Error("Expected the referenced schema to apply")
+	}
+
+	escaping := map[string]interface{}{"$ref": "../outside.json"}
+	if result := sv.ValidateRequest(escaping, map[string]interface{}{}); result.Valid {
+		t.Error("Expected references outside the schema directory to be rejected")
+	}
+}
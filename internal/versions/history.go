@@ -0,0 +1,119 @@
+package versions
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"dynamiccontrol/internal/configstore"
+	"dynamiccontrol/internal/types"
+)
+
+// DefaultCapacity is the number of configuration versions kept in memory
+const DefaultCapacity = 50
+
+// Version is a configuration that was applied: the route configuration
+// together with the policy set it ran with
+type Version struct {
+	ID        int                 `json:"id"`
+	Revision  string              `json:"revision"`
+	AppliedAt time.Time           `json:"appliedAt"`
+	Routes    *types.RoutesConfig `json:"routes"`
+	Policies  map[string]string   `json:"policies"`
+}
+
+// Summary describes a version without its content
+type Summary struct {
+	ID        int       `json:"id"`
+	Revision  string    `json:"revision"`
+	AppliedAt time.Time `json:"appliedAt"`
+	Routes    int       `json:"routes"`
+	Policies  []string  `json:"policies"`
+	Current   bool      `json:"current"`
+}
+
+// History keeps the most recently applied configuration versions. Versions
+// are numbered in the order they were applied.
+type History struct {
+	mu       sync.RWMutex
+	versions []Version
+	capacity int
+	lastID   int
+}
+
+// NewHistory creates a history holding up to capacity versions
+func NewHistory(capacity int) *History {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &History{capacity: capacity}
+}
+
+// Record adds a version for an applied configuration, evicting the oldest
+// version when the history is full. Nothing is recorded when the
+// configuration equals the current version, which is returned instead.
+func (h *History) Record(routes *types.RoutesConfig, policies map[string]string) (Version, error) {
+	snapshot, err := configstore.NewSnapshot(routes, policies)
+	if err != nil {
+		return Version{}, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n := len(h.versions); n > 0 && h.versions[n-1].Revision == snapshot.Revision {
+		return h.versions[n-1], nil
+	}
+
+	h.lastID++
+	version := Version{
+		ID:        h.lastID,
+		Revision:  snapshot.Revision,
+		AppliedAt: time.Now().UTC(),
+		Routes:    routes,
+		Policies:  policies,
+	}
+	if len(h.versions) == h.capacity {
+		h.versions = append(h.versions[:0], h.versions[1:]...)
+	}
+	h.versions = append(h.versions, version)
+	return version, nil
+}
+
+// List summarizes the kept versions, newest first
+func (h *History) List() []Summary {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	summaries := make([]Summary, 0, len(h.versions))
+	for i := len(h.versions) - 1; i >= 0; i-- {
+		version := h.versions[i]
+		summary := Summary{
+			ID:        version.ID,
+			Revision:  version.Revision,
+			AppliedAt: version.AppliedAt,
+			Policies:  make([]string, 0, len(version.Policies)),
+			Current:   i == len(h.versions)-1,
+		}
+		if version.Routes != nil {
+			summary.Routes = len(version.Routes.Routes)
+		}
+		for name := range version.Policies {
+			summary.Policies = append(summary.Policies, name)
+		}
+		sort.Strings(summary.Policies)
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// Get returns a kept version by ID
+func (h *History) Get(id int) (Version, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, version := range h.versions {
+		if version.ID == id {
+			return version, true
+		}
+	}
+	return Version{}, false
+}
@@ -0,0 +1,48 @@
+package versions
+
+import (
+	"testing"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestHistory(t *testing.T) {
+	history := NewHistory(2)
+	routes := func(path string) *types.RoutesConfig {
+		return &types.RoutesConfig{Routes: []types.RouteConfig{{RouteName: path, Method: "GET"}}}
+	}
+	policies := map[string]string{"orders": "package orders\n\nallow := true\n"}
+
+	first, err := history.Record(routes("/v1/orders"), policies)
+	if err != nil {
+		t.Fatalf("Failed to record version: %v", err)
+	}
+	// Applying the same configuration again is not a new version
+	if same, _ := history.Record(routes("/v1/orders"), policies); same.ID != first.ID {
+		t.Errorf("Expected version %d to be current, got %d", first.ID, same.ID)
+	}
+	second, _ := history.Record(routes("/v1/items"), policies)
+	third, _ := history.Record(routes("/v1/orders"), nil)
+	if first.ID != 1 || second.ID != 2 || third.ID != 3 {
+		t.Errorf("Expected sequential IDs, got %d, %d and %d", first.ID, second.ID, third.ID)
+	}
+
+	summaries := history.List()
+	if len(summaries) != 2 || summaries[0].ID != 3 || summaries[1].ID != 2 {
+		t.Fatalf("Expected the two newest versions, got %+v", summaries)
+	}
+	if !summaries[0].Current || summaries[1].Current {
+		t.Errorf("Expected only the newest version to be current, got %+v", summaries)
+	}
+	if summaries[1].Routes != 1 || len(summaries[1].Policies) != 1 || summaries[1].Policies[0] != "orders" {
+		t.Errorf("Unexpected summary %+v", summaries[1])
+	}
+
+	if _, ok := history.Get(1); ok {
+		t.Error("Expected the oldest version to be evicted")
+	}
+	version, ok := history.Get(2)
+	if !ok || version.Routes.Routes[0].RouteName != "/v1/items" || version.Revision != second.Revision {
+		t.Errorf("Unexpected version %+v", version)
+	}
+}
@@ -0,0 +1,232 @@
+package watchdog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Watchdog defaults
+const (
+	DefaultInterval      = 30 * time.Second
+	DefaultMaxGoroutines = 10000
+	DefaultMaxOpenFDs    = 4096
+	DefaultMaxQueueDepth = 1000
+	stackSummaryLimit    = 10
+)
+
+// Config holds the watchdog check interval and thresholds
+type Config struct {
+	Interval      time.Duration `json:"-"`
+	MaxGoroutines int           `json:"maxGoroutines"`
+	MaxOpenFDs    int           `json:"maxOpenFds"`
+	MaxQueueDepth int           `json:"maxQueueDepth"`
+}
+
+// QueueProbe reports the current depth of a pending work queue
+type QueueProbe func() int
+
+// StackSummary counts goroutines sharing the same top frame
+type StackSummary struct {
+	Function string `json:"function"`
+	Count    int    `json:"count"`
+}
+
+// Snapshot is the result of a single watchdog check
+type Snapshot struct {
+	Timestamp  time.Time      `json:"timestamp"`
+	Goroutines int            `json:"goroutines"`
+	OpenFDs    int            `json:"openFds"`
+	Queues     map[string]int `json:"queues"`
+	Thresholds Config         `json:"thresholds"`
+	Violations []string       `json:"violations"`
+	Stacks     []StackSummary `json:"stacks,omitempty"`
+}
+
+// Watchdog tracks goroutines, file descriptors and queue depths against
+// thresholds and logs diagnostics when any of them is exceeded
+type Watchdog struct {
+	config Config
+	mu     sync.Mutex
+	queues map[string]QueueProbe
+	last   Snapshot
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// New creates a watchdog, filling unset thresholds with defaults
+func New(config Config) *Watchdog {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.MaxGoroutines <= 0 {
+		config.MaxGoroutines = DefaultMaxGoroutines
+	}
+	if config.MaxOpenFDs <= 0 {
+		config.MaxOpenFDs = DefaultMaxOpenFDs
+	}
+	if config.MaxQueueDepth <= 0 {
+		config.MaxQueueDepth = DefaultMaxQueueDepth
+	}
+
+	return &Watchdog{
+		config: config,
+		queues: make(map[string]QueueProbe),
+		stop:   make(chan struct{}),
+	}
+}
+
+// RegisterQueue adds a named queue whose depth is checked against the threshold
+func (w *Watchdog) RegisterQueue(name string, probe QueueProbe) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.queues[name] = probe
+}
+
+// Start periodically checks resources until Stop is called
+func (w *Watchdog) Start() {
+	go func() {
+		ticker := time.NewTicker(w.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.Check()
+			}
+		}
+	}()
+}
+
+// Stop terminates the periodic check
+func (w *Watchdog) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+}
+
+// Check samples resource usage, logs a diagnostic with a stack summary when a
+// threshold is exceeded and returns the snapshot
+func (w *Watchdog) Check() Snapshot {
+	w.mu.Lock()
+	probes := make(map[string]QueueProbe, len(w.queues))
+	for name, probe := range w.queues {
+		probes[name] = probe
+	}
+	w.mu.Unlock()
+
+	snapshot := Snapshot{
+		Timestamp:  time.Now().UTC(),
+		Goroutines: runtime.NumGoroutine(),
+		OpenFDs:    openFDs(),
+		Queues:     make(map[string]int, len(probes)),
+		Thresholds: w.config,
+		Violations: []string{},
+	}
+
+	if snapshot.Goroutines > w.config.MaxGoroutines {
+		snapshot.Violations = append(snapshot.Violations, fmt.Sprintf("goroutines %d exceed %d", snapshot.Goroutines, w.config.MaxGoroutines))
+	}
+	if snapshot.OpenFDs > w.config.MaxOpenFDs {
+		snapshot.Violations = append(snapshot.Violations, fmt.Sprintf("open file descriptors %d exceed %d", snapshot.OpenFDs, w.config.MaxOpenFDs))
+	}
+	for name, probe := range probes {
+		depth := probe()
+		snapshot.Queues[name] = depth
+		if depth > w.config.MaxQueueDepth {
+			snapshot.Violations = append(snapshot.Violations, fmt.Sprintf("queue %s depth %d exceeds %d", name, depth, w.config.MaxQueueDepth))
+		}
+	}
+	sort.Strings(snapshot.Violations)
+
+	if len(snapshot.Violations) > 0 {
+		snapshot.Stacks = SummarizeStacks(stackSummaryLimit)
+		slog.Warn("Watchdog thresholds exceeded", "violations", snapshot.Violations)
+		for _, summary := range snapshot.Stacks {
+			slog.Warn("Watchdog goroutine summary", "goroutines", summary.Count, "function", summary.Function)
+		}
+	}
+
+	w.mu.Lock()
+	w.last = snapshot
+	w.mu.Unlock()
+
+	return snapshot
+}
+
+// Last returns the snapshot taken by the most recent check
+func (w *Watchdog) Last() Snapshot {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.last
+}
+
+// SummarizeStacks groups all goroutines by their top frame and returns the
+// most common ones, which is usually enough to spot where a leak piles up
+func SummarizeStacks(limit int) []StackSummary {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	counts := make(map[string]int)
+	for _, goroutine := range strings.Split(string(buf), "\n\n") {
+		if function := topFunction(goroutine); function != "" {
+			counts[function]++
+		}
+	}
+
+	summaries := make([]StackSummary, 0, len(counts))
+	for function, count := range counts {
+		summaries = append(summaries, StackSummary{Function: function, Count: count})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Count != summaries[j].Count {
+			return summaries[i].Count > summaries[j].Count
+		}
+		return summaries[i].Function < summaries[j].Function
+	})
+
+	if limit > 0 && len(summaries) > limit {
+		summaries = summaries[:limit]
+	}
+	return summaries
+}
+
+// topFunction returns the innermost non-runtime function of a goroutine trace
+func topFunction(trace string) string {
+	lines := strings.Split(strings.TrimSpace(trace), "\n")
+	function := ""
+	for i := 1; i < len(lines); i += 2 {
+		function = lines[i]
+		if i := strings.LastIndex(function, "("); i > 0 {
+			function = function[:i]
+		}
+		if !strings.HasPrefix(function, "runtime.") {
+			break
+		}
+	}
+	return function
+}
+
+// openFDs counts the open file descriptors of the process, or returns -1
+// on platforms without /proc
+func openFDs() int {
+	entries, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
@@ -0,0 +1,60 @@
+package watchdog
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckReportsQueueViolation(t *testing.T) {
+	w := New(Config{MaxQueueDepth: 5})
+	w.RegisterQueue("small", func() int { return 2 })
+	w.RegisterQueue("backed_up", func() int { return 8 })
+
+	snapshot := w.Check()
+
+	if snapshot.Queues["small"] != 2 || snapshot.Queues["backed_up"] != 8 {
+		t.Errorf("Unexpected queue depths: %v", snapshot.Queues)
+	}
+	if len(snapshot.Violations) != 1 || !strings.Contains(snapshot.Violations[0], "backed_up") {
+		t.Errorf("Expected a single violation for backed_up, got %v", snapshot.Violations)
+	}
+	if len(snapshot.Stacks) == 0 {
+		t.Error("Expected a stack summary when a threshold is exceeded")
+	}
+	if w.Last().Timestamp != snapshot.Timestamp {
+		t.Error("Expected Last to return the most recent snapshot")
+	}
+}
+
+func TestCheckWithinThresholds(t *testing.T) {
+	w := New(Config{})
+	snapshot := w.Check()
+
+	if len(snapshot.Violations) != 0 {
+		t.Errorf("Expected no violations, got %v", snapshot.Violations)
+	}
+	if snapshot.Stacks != nil {
+		t.Error("Expected no stack summary without violations")
+	}
+	if snapshot.Goroutines <= 0 {
+		t.Errorf("Expected a positive goroutine count, got %d", snapshot.Goroutines)
+	}
+}
+
+func TestSummarizeStacksLimit(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	for i := 0; i < 3; i++ {
+		go func() { <-block }()
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	summaries := SummarizeStacks(2)
+	if len(summaries) > 2 {
+		t.Errorf("Expected at most 2 summaries, got %d", len(summaries))
+	}
+	if summaries[0].Count < 3 {
+		t.Errorf("Expected the blocked goroutines to be the most common stack, got %+v", summaries[0])
+	}
+}
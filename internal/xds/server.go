@@ -0,0 +1,118 @@
+package xds
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"dynamiccontrol/internal/types"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	clusterservice "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	discoverygrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	endpointservice "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
+	listenerservice "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
+	routeservice "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	serverv3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"google.golang.org/grpc"
+)
+
+// nodeGroup is the snapshot key shared by every Envoy node
+const nodeGroup = "dynamiccontrol"
+
+// allNodes serves the same snapshot to every Envoy node
+type allNodes struct{}
+
+// ID returns the shared snapshot key
+func (allNodes) ID(*corev3.Node) string {
+	return nodeGroup
+}
+
+// Server serves the route configuration to Envoy over CDS, EDS, RDS, LDS
+// and the aggregated discovery service
+type Server struct {
+	options    Options
+	cache      cachev3.SnapshotCache
+	grpcServer *grpc.Server
+	cancel     context.CancelFunc
+}
+
+// NewServer creates an xDS server with an empty snapshot cache
+func NewServer(options Options) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	snapshots := cachev3.NewSnapshotCache(true, allNodes{}, logger{})
+	xdsServer := serverv3.NewServer(ctx, snapshots, nil)
+
+	grpcServer := grpc.NewServer()
+	discoverygrpc.RegisterAggregatedDiscoveryServiceServer(grpcServer, xdsServer)
+	clusterservice.RegisterClusterDiscoveryServiceServer(grpcServer, xdsServer)
+	endpointservice.RegisterEndpointDiscoveryServiceServer(grpcServer, xdsServer)
+	routeservice.RegisterRouteDiscoveryServiceServer(grpcServer, xdsServer)
+	listenerservice.RegisterListenerDiscoveryServiceServer(grpcServer, xdsServer)
+
+	return &Server{
+		options:    options,
+		cache:      snapshots,
+		grpcServer: grpcServer,
+		cancel:     cancel,
+	}
+}
+
+// Update translates a route configuration and publishes it to connected
+// Envoy nodes. The snapshot version is derived from the configuration, so
+// reapplying an unchanged configuration does not trigger a push.
+func (s *Server) Update(config *types.RoutesConfig) error {
+	resources, err := Translate(config, s.options)
+	if err != nil {
+		return err
+	}
+
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to hash configuration: %w", err)
+	}
+	sum := sha256.Sum256(configBytes)
+	version := hex.EncodeToString(sum[:6])
+
+	snapshot, err := cachev3.NewSnapshot(version, resources)
+	if err != nil {
+		return fmt.Errorf("failed to build xDS snapshot: %w", err)
+	}
+	if err := snapshot.Consistent(); err != nil {
+		return fmt.Errorf("inconsistent xDS snapshot: %w", err)
+	}
+	if err := s.cache.SetSnapshot(context.Background(), nodeGroup, snapshot); err != nil {
+		return fmt.Errorf("failed to publish xDS snapshot: %w", err)
+	}
+
+	slog.Info("Published xDS snapshot", "version", version, "routes", len(config.Routes))
+	return nil
+}
+
+// Serve accepts xDS connections on the listener until Stop is called
+func (s *Server) Serve(listener net.Listener) error {
+	return s.grpcServer.Serve(listener)
+}
+
+// Stop gracefully stops the server
+func (s *Server) Stop() {
+	s.grpcServer.GracefulStop()
+	s.cancel()
+}
+
+// logger forwards warnings and errors of the snapshot cache to the standard logger
+type logger struct{}
+
+func (logger) Debugf(format string, args ...interface{}) {}
+func (logger) Infof(format string, args ...interface{})  {}
+func (logger) Warnf(format string, args ...interface{}) {
+	slog.Warn(fmt.Sprintf(format, args...), "component", "xds")
+}
+func (logger) Errorf(format string, args ...interface{}) {
+	slog.Error(fmt.Sprintf(format, args...), "component", "xds")
+}
@@ -0,0 +1,482 @@
+package xds
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/cors"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/upstream"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	routerv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	cachetypes "github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Resource names shared by the generated configuration
+const (
+	ListenerName      = "dynamiccontrol"
+	RouteConfigName   = "dynamiccontrol_routes"
+	ControlPlaneName  = "dynamiccontrol_control_plane"
+	DefaultListenPort = 10000
+)
+
+// Options controls how the route configuration is translated
+type Options struct {
+	// ListenPort is the port of the Envoy listener serving the routes
+	ListenPort uint32
+	// ControlPlaneAddress is the host:port where Envoy reaches this server's
+	// HTTP listener for routes the control plane must execute itself
+	ControlPlaneAddress string
+	// IdentityAssertions routes all proxy traffic through the control plane,
+	// which signs the identity assertion header
+	IdentityAssertions bool
+}
+
+// Translate converts a route configuration into Envoy listener, route,
+// cluster and endpoint resources.
+//
+// Proxy routes without policies, request schemas, canonicalization or faults
+// are sent by Envoy straight to their upstreams. Every other route is sent to
+// the control plane, which keeps enforcing policies and validation.
+func Translate(config *types.RoutesConfig, options Options) (map[resourcev3.Type][]cachetypes.Resource, error) {
+	if options.ListenPort == 0 {
+		options.ListenPort = DefaultListenPort
+	}
+
+	controlPlane, err := parseAddress(options.ControlPlaneAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid control plane address: %w", err)
+	}
+
+	clusters := []cachetypes.Resource{newCluster(ControlPlaneName, false, nil)}
+	endpoints := []cachetypes.Resource{newLoadAssignment(ControlPlaneName, []lbTarget{{address: controlPlane, weight: 1}})}
+	routes := make([]*routev3.Route, 0, len(config.Routes))
+
+	// Routes of a tenant or host, and the shared routes with the same method
+	// and path, are dispatched by the control plane
+	scoped := make(map[string]bool)
+	for _, route := range config.Routes {
+		if route.Tenant != "" || route.Host != "" {
+			scoped[route.Method+" "+route.RouteName] = true
+		}
+	}
+
+	for _, route := range config.Routes {
+		match, err := routeMatch(route)
+		if err != nil {
+			return nil, err
+		}
+
+		cluster := ControlPlaneName
+		action := &routev3.RouteAction{}
+		// Targets naming a registered service are resolved by the control plane
+		if route.Handler == types.HandlerProxy && len(route.Upstreams) > 0 && !resolvesServices(route.Upstreams) {
+			name := ClusterName(route)
+			targets, tls, err := upstreamTargets(route.Upstreams)
+			if err != nil {
+				return nil, fmt.Errorf("route %s %s: %w", route.Method, route.RouteName, err)
+			}
+			clusters = append(clusters, newCluster(name, tls, healthCheckOf(route.Upstreams)))
+			endpoints = append(endpoints, newLoadAssignment(name, targets))
+
+			// CORS headers and identity assertions are set by the control plane
+			if servedByDataPlane(route) && !scoped[route.Method+" "+route.RouteName] && cors.Effective(config.CORS, route.CORS) == nil && !options.IdentityAssertions {
+				cluster = name
+				action.RetryPolicy = retryPolicy(route.Retry)
+			}
+		}
+		action.ClusterSpecifier = &routev3.RouteAction_Cluster{Cluster: cluster}
+		if route.TimeoutMs > 0 {
+			action.Timeout = durationpb.New(time.Duration(route.TimeoutMs) * time.Millisecond)
+		}
+
+		envoyRoute := &routev3.Route{
+			Name:   route.Method + " " + route.RouteName,
+			Match:  match,
+			Action: &routev3.Route_Route{Route: action},
+		}
+		if cluster != ControlPlaneName {
+			applyHeaderPolicy(envoyRoute, route.Headers)
+		}
+		routes = append(routes, envoyRoute)
+	}
+
+	// Unmatched requests still reach the control plane, which answers them
+	routes = append(routes, &routev3.Route{
+		Name:  "default",
+		Match: &routev3.RouteMatch{PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: "/"}},
+		Action: &routev3.Route_Route{Route: &routev3.RouteAction{
+			ClusterSpecifier: &routev3.RouteAction_Cluster{Cluster: ControlPlaneName},
+		}},
+	})
+
+	routeConfig := &routev3.RouteConfiguration{
+		Name: RouteConfigName,
+		VirtualHosts: []*routev3.VirtualHost{{
+			Name:    "dynamiccontrol",
+			Domains: []string{"*"},
+			Routes:  routes,
+		}},
+	}
+
+	listener, err := newListener(options.ListenPort)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[resourcev3.Type][]cachetypes.Resource{
+		resourcev3.ClusterType:  clusters,
+		resourcev3.EndpointType: endpoints,
+		resourcev3.RouteType:    {routeConfig},
+		resourcev3.ListenerType: {listener},
+	}, nil
+}
+
+// servedByDataPlane reports whether Envoy may forward a route directly to its
+// upstreams because the control plane has nothing to enforce on it
+func servedByDataPlane(route types.RouteConfig) bool {
+	return len(route.Policies) == 0 &&
+		route.RequestSchema == nil &&
+		route.CandidateRequestSchema == nil &&
+		route.QuerySchema == nil &&
+		route.HeaderSchema == nil &&
+		route.Canonicalize == nil &&
+		route.Faults == nil &&
+		route.Mirror == nil &&
+		staticHeaderPolicy(route.Headers)
+}
+
+// staticHeaderPolicy reports whether Envoy can apply a header policy itself:
+// forward allowlists and templated values need the control plane
+func staticHeaderPolicy(policy *types.HeaderPolicy) bool {
+	if policy == nil {
+		return true
+	}
+	if len(policy.Forward) > 0 {
+		return false
+	}
+	for _, value := range policy.Add {
+		if strings.Contains(value, "${") {
+			return false
+		}
+	}
+	return true
+}
+
+// applyHeaderPolicy strips credentials and the route's strip list and adds
+// its static headers on a route Envoy serves directly
+func applyHeaderPolicy(route *routev3.Route, policy *types.HeaderPolicy) {
+	route.RequestHeadersToRemove = append([]string{}, upstream.SensitiveHeaders...)
+	if policy == nil {
+		return
+	}
+	route.RequestHeadersToRemove = append(route.RequestHeadersToRemove, policy.Strip...)
+
+	names := make([]string, 0, len(policy.Add))
+	for name := range policy.Add {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		route.RequestHeadersToAdd = append(route.RequestHeadersToAdd, &corev3.HeaderValueOption{
+			Header:       &corev3.HeaderValue{Key: name, Value: policy.Add[name]},
+			AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+		})
+	}
+}
+
+// ClusterName returns the Envoy cluster name of a proxied route
+func ClusterName(route types.RouteConfig) string {
+	name := strings.ToLower(route.Method) + strings.NewReplacer("/", "_", ":", "", "*", "").Replace(route.RouteName)
+	return "route_" + strings.Trim(name, "_")
+}
+
+// routeMatch matches the route's method and gin-style path pattern
+func routeMatch(route types.RouteConfig) (*routev3.RouteMatch, error) {
+	match := &routev3.RouteMatch{
+		Headers: []*routev3.HeaderMatcher{{
+			Name: ":method",
+			HeaderMatchSpecifier: &routev3.HeaderMatcher_StringMatch{
+				StringMatch: &matcherv3.StringMatcher{
+					MatchPattern: &matcherv3.StringMatcher_Exact{Exact: route.Method},
+				},
+			},
+		}},
+	}
+
+	if !strings.ContainsAny(route.RouteName, ":*") {
+		match.PathSpecifier = &routev3.RouteMatch_Path{Path: route.RouteName}
+		return match, nil
+	}
+
+	pattern := PathRegex(route.RouteName)
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, fmt.Errorf("failed to translate route %s: %w", route.RouteName, err)
+	}
+	match.PathSpecifier = &routev3.RouteMatch_SafeRegex{
+		SafeRegex: &matcherv3.RegexMatcher{Regex: pattern},
+	}
+	return match, nil
+}
+
+// PathRegex converts a gin-style route pattern into an anchored RE2 expression
+func PathRegex(pattern string) string {
+	parts := strings.Split(pattern, "/")
+	for i, part := range parts {
+		switch {
+		case strings.HasPrefix(part, ":"):
+			parts[i] = "[^/]+"
+		case strings.HasPrefix(part, "*"):
+			parts[i] = ".*"
+		default:
+			parts[i] = regexp.QuoteMeta(part)
+		}
+	}
+	return "^" + strings.Join(parts, "/") + "$"
+}
+
+// retryPolicy translates a route retry configuration
+func retryPolicy(retry *types.RetryConfig) *routev3.RetryPolicy {
+	if retry == nil || retry.MaxAttempts <= 1 {
+		return nil
+	}
+
+	policy := &routev3.RetryPolicy{
+		RetryOn:    "connect-failure,reset,5xx",
+		NumRetries: wrapperspb.UInt32(uint32(retry.MaxAttempts - 1)),
+	}
+	if len(retry.RetryOn) > 0 {
+		policy.RetryOn = "connect-failure,reset,retriable-status-codes"
+		for _, code := range retry.RetryOn {
+			policy.RetriableStatusCodes = append(policy.RetriableStatusCodes, uint32(code))
+		}
+	}
+	if retry.BackoffMs > 0 {
+		policy.RetryBackOff = &routev3.RetryPolicy_RetryBackOff{
+			BaseInterval: durationpb.New(time.Duration(retry.BackoffMs) * time.Millisecond),
+		}
+		if retry.MaxBackoffMs > 0 {
+			policy.RetryBackOff.MaxInterval = durationpb.New(time.Duration(retry.MaxBackoffMs) * time.Millisecond)
+		}
+	}
+	return policy
+}
+
+// lbTarget is an upstream endpoint with its load balancing weight
+type lbTarget struct {
+	address *corev3.SocketAddress
+	weight  int
+}
+
+// resolvesServices reports whether an upstream target names a registered
+// service, whose instances are only known to the control plane
+func resolvesServices(upstreams []types.UpstreamTarget) bool {
+	for _, upstream := range upstreams {
+		if upstream.Service != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// upstreamTargets resolves upstream URLs into endpoint addresses and reports
+// whether the upstreams are reached over TLS
+func upstreamTargets(upstreams []types.UpstreamTarget) ([]lbTarget, bool, error) {
+	targets := make([]lbTarget, 0, len(upstreams))
+	tls := false
+	for _, upstream := range upstreams {
+		parsed, err := url.Parse(upstream.URL)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid upstream URL %s: %w", upstream.URL, err)
+		}
+		host := parsed.Host
+		if parsed.Port() == "" {
+			port := "80"
+			if parsed.Scheme == "https" {
+				port = "443"
+			}
+			host = net.JoinHostPort(parsed.Hostname(), port)
+		}
+		address, err := parseAddress(host)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid upstream URL %s: %w", upstream.URL, err)
+		}
+		if parsed.Scheme == "https" {
+			tls = true
+		}
+
+		weight := upstream.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		targets = append(targets, lbTarget{address: address, weight: weight})
+	}
+	return targets, tls, nil
+}
+
+// healthCheckOf returns the first health check declared by the upstreams;
+// Envoy applies a cluster's health checks to all of its endpoints
+func healthCheckOf(upstreams []types.UpstreamTarget) *types.HealthCheckConfig {
+	for _, upstream := range upstreams {
+		if upstream.HealthCheck != nil {
+			return upstream.HealthCheck
+		}
+	}
+	return nil
+}
+
+// parseAddress converts host:port into an Envoy socket address
+func parseAddress(hostPort string) (*corev3.SocketAddress, error) {
+	host, portText, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portText, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q", portText)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return &corev3.SocketAddress{
+		Address:       host,
+		PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: uint32(port)},
+	}, nil
+}
+
+// newCluster creates an EDS cluster fed over ADS, with active health checks
+// when the route's upstreams declare them
+func newCluster(name string, tls bool, healthCheck *types.HealthCheckConfig) *clusterv3.Cluster {
+	cluster := &clusterv3.Cluster{
+		Name:                 name,
+		ConnectTimeout:       durationpb.New(5 * time.Second),
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{Type: clusterv3.Cluster_EDS},
+		EdsClusterConfig: &clusterv3.Cluster_EdsClusterConfig{
+			EdsConfig: adsSource(),
+		},
+		LbPolicy: clusterv3.Cluster_ROUND_ROBIN,
+	}
+
+	if healthCheck != nil {
+		interval := 10 * time.Second
+		if healthCheck.IntervalMs > 0 {
+			interval = time.Duration(healthCheck.IntervalMs) * time.Millisecond
+		}
+		timeout := 2 * time.Second
+		if healthCheck.TimeoutMs > 0 {
+			timeout = time.Duration(healthCheck.TimeoutMs) * time.Millisecond
+		}
+		threshold := uint32(3)
+		if healthCheck.UnhealthyThreshold > 0 {
+			threshold = uint32(healthCheck.UnhealthyThreshold)
+		}
+		cluster.HealthChecks = []*corev3.HealthCheck{{
+			Interval:           durationpb.New(interval),
+			Timeout:            durationpb.New(timeout),
+			UnhealthyThreshold: wrapperspb.UInt32(threshold),
+			HealthyThreshold:   wrapperspb.UInt32(1),
+			HealthChecker: &corev3.HealthCheck_HttpHealthCheck_{
+				HttpHealthCheck: &corev3.HealthCheck_HttpHealthCheck{Path: healthCheck.Path},
+			},
+		}}
+	}
+
+	if tls {
+		tlsContext, _ := anypb.New(&tlsv3.UpstreamTlsContext{})
+		cluster.TransportSocket = &corev3.TransportSocket{
+			Name:       "envoy.transport_sockets.tls",
+			ConfigType: &corev3.TransportSocket_TypedConfig{TypedConfig: tlsContext},
+		}
+	}
+	return cluster
+}
+
+// newLoadAssignment creates the weighted endpoints of a cluster
+func newLoadAssignment(cluster string, targets []lbTarget) *endpointv3.ClusterLoadAssignment {
+	endpoints := make([]*endpointv3.LbEndpoint, 0, len(targets))
+	for _, target := range targets {
+		endpoints = append(endpoints, &endpointv3.LbEndpoint{
+			HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+				Endpoint: &endpointv3.Endpoint{
+					Address: &corev3.Address{
+						Address: &corev3.Address_SocketAddress{SocketAddress: target.address},
+					},
+				},
+			},
+			LoadBalancingWeight: wrapperspb.UInt32(uint32(target.weight)),
+		})
+	}
+
+	return &endpointv3.ClusterLoadAssignment{
+		ClusterName: cluster,
+		Endpoints:   []*endpointv3.LocalityLbEndpoints{{LbEndpoints: endpoints}},
+	}
+}
+
+// newListener creates the HTTP listener whose routes are served over RDS
+func newListener(port uint32) (*listenerv3.Listener, error) {
+	router, err := anypb.New(&routerv3.Router{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode router filter: %w", err)
+	}
+
+	manager, err := anypb.New(&hcmv3.HttpConnectionManager{
+		StatPrefix: "dynamiccontrol",
+		CodecType:  hcmv3.HttpConnectionManager_AUTO,
+		RouteSpecifier: &hcmv3.HttpConnectionManager_Rds{
+			Rds: &hcmv3.Rds{
+				ConfigSource:    adsSource(),
+				RouteConfigName: RouteConfigName,
+			},
+		},
+		HttpFilters: []*hcmv3.HttpFilter{{
+			Name:       "envoy.filters.http.router",
+			ConfigType: &hcmv3.HttpFilter_TypedConfig{TypedConfig: router},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode http connection manager: %w", err)
+	}
+
+	return &listenerv3.Listener{
+		Name: ListenerName,
+		Address: &corev3.Address{
+			Address: &corev3.Address_SocketAddress{SocketAddress: &corev3.SocketAddress{
+				Address:       "0.0.0.0",
+				PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: port},
+			}},
+		},
+		FilterChains: []*listenerv3.FilterChain{{
+			Filters: []*listenerv3.Filter{{
+				Name:       "envoy.filters.network.http_connection_manager",
+				ConfigType: &listenerv3.Filter_TypedConfig{TypedConfig: manager},
+			}},
+		}},
+	}, nil
+}
+
+// adsSource points resource discovery at the aggregated discovery stream
+func adsSource() *corev3.ConfigSource {
+	return &corev3.ConfigSource{
+		ResourceApiVersion:    corev3.ApiVersion_V3,
+		ConfigSourceSpecifier: &corev3.ConfigSource_Ads{Ads: &corev3.AggregatedConfigSource{}},
+	}
+}
@@ -0,0 +1,109 @@
+package xds
+
+import (
+	"testing"
+
+	"dynamiccontrol/internal/types"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+func testConfig() *types.RoutesConfig {
+	return &types.RoutesConfig{Routes: []types.RouteConfig{
+		{
+			RouteName: "/v1/status",
+			Method:    "GET",
+			Policies:  []string{"status_policy"},
+		},
+		{
+			RouteName: "/v1/orders/:orderId",
+			Method:    "GET",
+			Handler:   types.HandlerProxy,
+			Upstreams: []types.UpstreamTarget{
+				{URL: "http://orders-v1:8080", Weight: 90},
+				{URL: "http://orders-v2:8080", Weight: 10},
+			},
+			Retry: &types.RetryConfig{MaxAttempts: 3, RetryOn: []int{503}},
+		},
+		{
+			RouteName: "/v1/payments",
+			Method:    "POST",
+			Handler:   types.HandlerProxy,
+			Policies:  []string{"traffic_policy"},
+			Upstreams: []types.UpstreamTarget{{URL: "https://payments.internal"}},
+		},
+	}}
+}
+
+func TestTranslateRoutesClusters(t *testing.T) {
+	resources, err := Translate(testConfig(), Options{ControlPlaneAddress: "127.0.0.1:8080"})
+	if err != nil {
+		t.Fatalf("Translate failed: %v", err)
+	}
+
+	routeConfig := resources[resourcev3.RouteType][0].(*routev3.RouteConfiguration)
+	clusterOf := make(map[string]string)
+	for _, route := range routeConfig.VirtualHosts[0].Routes {
+		clusterOf[route.Name] = route.GetRoute().GetCluster()
+	}
+
+	if clusterOf["GET /v1/status"] != ControlPlaneName {
+		t.Errorf("Expected mock route to reach the control plane, got %s", clusterOf["GET /v1/status"])
+	}
+	if clusterOf["GET /v1/orders/:orderId"] != "route_get_v1_orders_orderId" {
+		t.Errorf("Expected unguarded proxy route to reach its upstreams, got %s", clusterOf["GET /v1/orders/:orderId"])
+	}
+	if clusterOf["POST /v1/payments"] != ControlPlaneName {
+		t.Errorf("Expected proxy route with policies to reach the control plane, got %s", clusterOf["POST /v1/payments"])
+	}
+
+	for _, resource := range resources[resourcev3.EndpointType] {
+		assignment := resource.(*endpointv3.ClusterLoadAssignment)
+		if assignment.ClusterName != "route_get_v1_orders_orderId" {
+			continue
+		}
+		endpoints := assignment.Endpoints[0].LbEndpoints
+		if len(endpoints) != 2 || endpoints[0].LoadBalancingWeight.GetValue() != 90 {
+			t.Errorf("Expected weighted endpoints, got %v", endpoints)
+		}
+	}
+
+	for _, resource := range resources[resourcev3.ClusterType] {
+		cluster := resource.(*clusterv3.Cluster)
+		if cluster.Name == "route_post_v1_payments" && cluster.TransportSocket == nil {
+			t.Error("Expected https upstream cluster to use TLS")
+		}
+	}
+}
+
+func TestPathRegex(t *testing.T) {
+	tests := map[string]string{
+		"/v1/services/:serviceId/traffic": `^/v1/services/[^/]+/traffic$`,
+		"/static/*filepath":               `^/static/.*$`,
+		"/v1/a.b":                         `^/v1/a\.b$`,
+	}
+	for pattern, expected := range tests {
+		if got := PathRegex(pattern); got != expected {
+			t.Errorf("PathRegex(%s) = %s, expected %s", pattern, got, expected)
+		}
+	}
+}
+
+func TestUpdatePublishesConsistentSnapshot(t *testing.T) {
+	server := NewServer(Options{ControlPlaneAddress: "127.0.0.1:8080"})
+	defer server.Stop()
+
+	if err := server.Update(testConfig()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	snapshot, err := server.cache.GetSnapshot(nodeGroup)
+	if err != nil {
+		t.Fatalf("Expected published snapshot: %v", err)
+	}
+	if len(snapshot.GetResources(resourcev3.ClusterType)) != 3 {
+		t.Errorf("Expected 3 clusters, got %d", len(snapshot.GetResources(resourcev3.ClusterType)))
+	}
+}
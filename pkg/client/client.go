@@ -0,0 +1,491 @@
+// Package client is a Go client for the control plane API. It covers the
+// data-plane routes served by the default configuration and the admin API
+// for routes and policies, retrying failed calls that are safe to repeat.
+//
+//	cp, err := client.New("http://localhost:8080", client.WithToken(os.Getenv("ADMIN_TOKEN")))
+//	status, err := cp.GetStatus(ctx)
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/traffic"
+	"dynamiccontrol/internal/types"
+)
+
+// Types shared with the server
+type (
+	StatusResponse  = types.StatusResponse
+	TrafficRequest  = types.TrafficRequest
+	TrafficSchedule = types.TrafficSchedule
+	TrafficResponse = types.TrafficResponse
+	TrafficRule     = traffic.Rule
+	Route           = types.RouteConfig
+)
+
+// Retry settings
+const (
+	DefaultMaxAttempts = 3
+	DefaultBackoff     = 100 * time.Millisecond
+	// maxBackoff bounds the wait between attempts, including Retry-After
+	maxBackoff = 30 * time.Second
+	// maxErrorBody bounds the error response read into an Error
+	maxErrorBody = 64 << 10
+)
+
+// Policy is a loaded policy with its Rego source
+type Policy struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+// Error is an error response of the control plane
+type Error struct {
+	StatusCode int
+	Message    string
+	Details    interface{}
+	RequestID  string
+	// RetryAfter is how long the control plane asked to wait before retrying
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+	if e.Details != nil {
+		message = fmt.Sprintf("%s: %v", message, e.Details)
+	}
+	return fmt.Sprintf("control plane returned %d: %s", e.StatusCode, message)
+}
+
+// IsNotFound reports whether an error is a 404 Not Found response
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Client calls the control plane API. It is safe for concurrent use.
+type Client struct {
+	baseURL     *url.URL
+	httpClient  *http.Client
+	header      http.Header
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client sending requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken authenticates requests with a bearer token, such as the admin
+// token or a service account token
+func WithToken(token string) Option {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithHeader sets a header sent with every request, such as a tenant header
+func WithHeader(name, value string) Option {
+	return func(c *Client) {
+		c.header.Set(name, value)
+	}
+}
+
+// WithRetry sets how often a failed call is attempted and the backoff before
+// the first retry, which doubles with every further retry. One attempt
+// disables retries.
+func WithRetry(maxAttempts int, backoff time.Duration) Option {
+	return func(c *Client) {
+		if maxAttempts > 0 {
+			c.maxAttempts = maxAttempts
+		}
+		if backoff >= 0 {
+			c.backoff = backoff
+		}
+	}
+}
+
+// New creates a client for the control plane at baseURL
+func New(baseURL string, options ...Option) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid control plane URL %q", baseURL)
+	}
+	c := &Client{
+		baseURL:     parsed,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		header:      make(http.Header),
+		maxAttempts: DefaultMaxAttempts,
+		backoff:     DefaultBackoff,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c, nil
+}
+
+// GetStatus returns the status of the control plane
+func (c *Client) GetStatus(ctx context.Context) (*StatusResponse, error) {
+	var status StatusResponse
+	if err := c.do(ctx, http.MethodGet, "/v1/status", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// SubmitTraffic submits a traffic rule for a service. The request carries a
+// generated Idempotency-Key, so retries store the rule at most once.
+func (c *Client) SubmitTraffic(ctx context.Context, serviceID string, request TrafficRequest) (*TrafficResponse, error) {
+	key, err := idempotencyKey()
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{"Idempotency-Key": []string{key}}
+	var response TrafficResponse
+	if err := c.do(ctx, http.MethodPost, servicePath(serviceID, "traffic"), header, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ListTrafficRules returns the traffic rules of a service, highest priority
+// first. Streamed responses are read line by line.
+func (c *Client) ListTrafficRules(ctx context.Context, serviceID string) ([]TrafficRule, error) {
+	var rules []TrafficRule
+	if err := c.do(ctx, http.MethodGet, servicePath(serviceID, "traffic"), nil, nil, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetTrafficRule returns a traffic rule of a service
+func (c *Client) GetTrafficRule(ctx context.Context, serviceID, ruleID string) (*TrafficRule, error) {
+	var rule TrafficRule
+	if err := c.do(ctx, http.MethodGet, servicePath(serviceID, "traffic", ruleID), nil, nil, &rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// DeleteTrafficRule deletes a traffic rule of a service
+func (c *Client) DeleteTrafficRule(ctx context.Context, serviceID, ruleID string) error {
+	return c.do(ctx, http.MethodDelete, servicePath(serviceID, "traffic", ruleID), nil, nil, nil)
+}
+
+// ListRoutes returns the active routes, reading every page
+func (c *Client) ListRoutes(ctx context.Context) ([]Route, error) {
+	var routes []Route
+	err := c.list(ctx, "/admin/routes", nil, func(page json.RawMessage) error {
+		var items []Route
+		if err := json.Unmarshal(page, &items); err != nil {
+			return err
+		}
+		routes = append(routes, items...)
+		return nil
+	})
+	return routes, err
+}
+
+// PutRoute creates or replaces a route at runtime and returns the applied
+// route
+func (c *Client) PutRoute(ctx context.Context, route Route) (*Route, error) {
+	var applied Route
+	if err := c.do(ctx, http.MethodPut, "/admin/routes", nil, route, &applied); err != nil {
+		return nil, err
+	}
+	return &applied, nil
+}
+
+// DeleteRoute deletes a route created at runtime, identified by the method,
+// path, host and tenant of route, and returns the deleted route
+func (c *Client) DeleteRoute(ctx context.Context, route Route) (*Route, error) {
+	request := map[string]string{
+		"method": route.Method,
+		"route":  route.RouteName,
+		"host":   route.Host,
+		"tenant": route.Tenant,
+	}
+	var deleted Route
+	if err := c.do(ctx, http.MethodDelete, "/admin/routes", nil, request, &deleted); err != nil {
+		return nil, err
+	}
+	return &deleted, nil
+}
+
+// ListPolicies returns the loaded policies, reading every page
+func (c *Client) ListPolicies(ctx context.Context) ([]Policy, error) {
+	return c.listPolicies(ctx, nil)
+}
+
+// GetPolicy returns a loaded policy; IsNotFound reports a missing policy
+func (c *Client) GetPolicy(ctx context.Context, name string) (*Policy, error) {
+	policies, err := c.listPolicies(ctx, url.Values{"filter[name]": []string{name}})
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range policies {
+		if policy.Name == name {
+			return &policy, nil
+		}
+	}
+	return nil, &Error{StatusCode: http.StatusNotFound, Message: "Policy not found", Details: name}
+}
+
+// PutPolicy installs the Rego source of a policy
+func (c *Client) PutPolicy(ctx context.Context, name, source string) (*Policy, error) {
+	var policy Policy
+	if err := c.do(ctx, http.MethodPut, "/admin/policies/"+url.PathEscape(name), nil, rawBody(source), &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// listPolicies reads every page of the policies matching query
+func (c *Client) listPolicies(ctx context.Context, query url.Values) ([]Policy, error) {
+	var policies []Policy
+	err := c.list(ctx, "/admin/policies", query, func(page json.RawMessage) error {
+		var items []Policy
+		if err := json.Unmarshal(page, &items); err != nil {
+			return err
+		}
+		policies = append(policies, items...)
+		return nil
+	})
+	return policies, err
+}
+
+// list reads every page of an admin collection, passing the items of each
+// page to add
+func (c *Client) list(ctx context.Context, path string, query url.Values, add func(json.RawMessage) error) error {
+	values := url.Values{}
+	for name, value := range query {
+		values[name] = value
+	}
+	for {
+		var page struct {
+			Items      json.RawMessage `json:"items"`
+			NextCursor string          `json:"nextCursor"`
+		}
+		target := path
+		if encoded := values.Encode(); encoded != "" {
+			target += "?" + encoded
+		}
+		if err := c.do(ctx, http.MethodGet, target, nil, nil, &page); err != nil {
+			return err
+		}
+		if err := add(page.Items); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		values.Set("cursor", page.NextCursor)
+	}
+}
+
+// rawBody is a request body sent as it is, such as Rego source
+type rawBody string
+
+// do sends a request, retrying it while that is safe, and decodes the JSON
+// response into out
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, body, out interface{}) error {
+	var payload []byte
+	contentType := ""
+	switch body := body.(type) {
+	case nil:
+	case rawBody:
+		payload, contentType = []byte(body), "text/plain"
+	default:
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		payload, contentType = encoded, "application/json"
+	}
+	retryable := method != http.MethodPost || header.Get("Idempotency-Key") != ""
+
+	var lastErr error
+	for attempt := 0; attempt < c.maxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := c.wait(ctx, attempt, lastErr); err != nil {
+				return err
+			}
+		}
+		resp, err := c.send(ctx, method, path, header, payload, contentType)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lastErr = fmt.Errorf("failed to call %s %s: %w", method, path, err)
+			if !retryable {
+				return lastErr
+			}
+			continue
+		}
+		lastErr = decodeResponse(resp, out)
+		if lastErr == nil || !retryable || !isRetryable(lastErr) {
+			return lastErr
+		}
+	}
+	return lastErr
+}
+
+// send sends a single attempt of a request
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, payload []byte, contentType string) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.String()+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range c.header {
+		req.Header[name] = values
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	return c.httpClient.Do(req)
+}
+
+// wait sleeps before a retry: for the Retry-After of a rejected attempt, or
+// for the doubling backoff
+func (c *Client) wait(ctx context.Context, attempt int, lastErr error) error {
+	delay := c.backoff << (attempt - 1)
+	var apiErr *Error
+	if errors.As(lastErr, &apiErr) && apiErr.RetryAfter > 0 {
+		delay = apiErr.RetryAfter
+	}
+	delay = min(delay, maxBackoff)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// decodeResponse decodes a successful JSON or newline-delimited JSON
+// response into out, or returns the error response as an *Error
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return readError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/x-ndjson") {
+		return decodeLines(resp.Body, out)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// decodeLines decodes a newline-delimited JSON response as a JSON array
+func decodeLines(body io.Reader, out interface{}) error {
+	var items []json.RawMessage
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			items = append(items, append(json.RawMessage(nil), line...))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read streamed response: %w", err)
+	}
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(encoded, out); err != nil {
+		return fmt.Errorf("failed to decode streamed response: %w", err)
+	}
+	return nil
+}
+
+// readError reads an error response of the control plane
+func readError(resp *http.Response) error {
+	var body struct {
+		Error     string      `json:"error"`
+		Details   interface{} `json:"details"`
+		RequestID string      `json:"requestId"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	json.Unmarshal(data, &body)
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		Message:    body.Error,
+		Details:    body.Details,
+		RequestID:  body.RequestID,
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+// isRetryable reports whether a failed attempt may succeed when repeated
+// soon: rate limits and unavailable upstreams. Rejections asking to wait
+// longer than maxBackoff, such as exhausted quotas, are returned instead.
+func isRetryable(err error) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter > maxBackoff {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// servicePath builds the path of a service resource from escaped segments
+func servicePath(serviceID string, segments ...string) string {
+	path := "/v1/services/" + url.PathEscape(serviceID)
+	for _, segment := range segments {
+		path += "/" + url.PathEscape(segment)
+	}
+	return path
+}
+
+// idempotencyKey generates a random Idempotency-Key
+func idempotencyKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	return hex.EncodeToString(key), nil
+}
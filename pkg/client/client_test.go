@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSubmitTrafficRetriesWithSameIdempotencyKey(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Expected the token to be sent, got %q", r.Header.Get("Authorization"))
+		}
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var request TrafficRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(TrafficResponse{ID: "rule-1", ServiceID: "billing", Status: "accepted", Message: request.Priority})
+	}))
+	defer server.Close()
+
+	cp, err := New(server.URL, WithToken("secret"), WithRetry(3, time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	response, err := cp.SubmitTraffic(context.Background(), "billing", TrafficRequest{TrafficType: "incoming", Volume: 5, Priority: "high"})
+	if err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+	if response.ID != "rule-1" || response.Message != "high" {
+		t.Errorf("Unexpected response %+v", response)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("Expected both attempts to carry the same idempotency key, got %v", keys)
+	}
+}
+
+func TestErrorsAreTypedAndNotRetriedWhenFinal(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `{"error": "Request denied by policy", "requestId": "req-1"}`)
+	}))
+	defer server.Close()
+
+	cp, _ := New(server.URL, WithRetry(3, time.Millisecond))
+	_, err := cp.GetStatus(context.Background())
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.StatusCode != http.StatusForbidden || apiErr.RequestID != "req-1" {
+		t.Fatalf("Expected a typed error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Expected a denial not to be retried, got %d attempts", attempts)
+	}
+}
+
+func TestListsReadEveryPageAndStreams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin/policies":
+			if r.URL.Query().Get("cursor") == "" {
+				fmt.Fprint(w, `{"items": [{"name": "a", "source": "package a"}], "total": 2, "nextCursor": "next"}`)
+				return
+			}
+			fmt.Fprint(w, `{"items": [{"name": "b", "source": "package b"}], "total": 2}`)
+		case "/v1/services/billing/traffic":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			fmt.Fprint(w, "{\"id\": \"rule-1\", \"serviceId\": \"billing\"}\n{\"id\": \"rule-2\", \"serviceId\": \"billing\"}\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cp, _ := New(server.URL)
+	policies, err := cp.ListPolicies(context.Background())
+	if err != nil || len(policies) != 2 || policies[1].Name != "b" {
+		t.Errorf("Expected both pages of policies, got %v: %v", policies, err)
+	}
+	if _, err := cp.GetPolicy(context.Background(), "missing"); !IsNotFound(err) {
+		t.Errorf("Expected a missing policy to be not found, got %v", err)
+	}
+	rules, err := cp.ListTrafficRules(context.Background(), "billing")
+	if err != nil || len(rules) != 2 || rules[1].ID != "rule-2" {
+		t.Errorf("Expected the streamed rules, got %v: %v", rules, err)
+	}
+}
+
+func TestContextCancelsRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	cp, _ := New(server.URL, WithRetry(5, time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := cp.GetStatus(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected the deadline to end the retries, got %v", err)
+	}
+}
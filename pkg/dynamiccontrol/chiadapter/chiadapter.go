@@ -0,0 +1,18 @@
+// Package chiadapter serves the routes of a dynamiccontrol Engine from a chi
+// router. The engine matches requests on their full path, so routes keep
+// their full paths when mounted under a prefix.
+package chiadapter
+
+import (
+	"dynamiccontrol/pkg/dynamiccontrol"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Mount serves the configured routes under a pattern of a chi router, such
+// as "/v1". The engine answers every request under the pattern, including
+// unknown paths, while the NotFound and MethodNotAllowed handlers of the
+// router keep serving the rest.
+func Mount(router chi.Router, pattern string, engine *dynamiccontrol.Engine) {
+	router.Mount(pattern, engine)
+}
@@ -0,0 +1,58 @@
+package chiadapter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dynamiccontrol/pkg/dynamiccontrol"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMountServesEngineRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := dynamiccontrol.New()
+	defer engine.Stop()
+	err := engine.SetPolicy("allow_all", "package allow_all\n\ndefault allow = true\n")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	err = engine.ApplyConfig(&dynamiccontrol.Config{Routes: []dynamiccontrol.Route{
+		{
+			RouteName:    "/v1/items/:id",
+			Method:       "GET",
+			Policies:     []string{"allow_all"},
+			MockResponse: &dynamiccontrol.MockResponse{StatusCode: http.StatusOK, Template: `{"id": "{{ .Params.id }}"}`},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	router := chi.NewRouter()
+	router.Get("/healthz", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "host not found", http.StatusNotFound)
+	})
+	Mount(router, "/v1", engine)
+
+	for _, tc := range []struct {
+		method, path string
+		expected     int
+		body         string
+	}{
+		{http.MethodGet, "/healthz", http.StatusOK, "ok"},
+		{http.MethodGet, "/v1/items/42", http.StatusOK, `"id":"42"`},
+		{http.MethodGet, "/v1/missing", http.StatusNotFound, "Route not found"},
+		{http.MethodGet, "/missing", http.StatusNotFound, "host not found"},
+	} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.path, strings.NewReader("{}")))
+		if recorder.Code != tc.expected || !strings.Contains(recorder.Body.String(), tc.body) {
+			t.Errorf("Expected %d %q for %s %s, got %d: %s", tc.expected, tc.body, tc.method, tc.path, recorder.Code, recorder.Body.String())
+		}
+	}
+}
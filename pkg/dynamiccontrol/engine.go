@@ -0,0 +1,164 @@
+// Package dynamiccontrol embeds the control plane in another Go service. An
+// Engine owns the policies, schemas and route table that the standalone
+// server builds from its environment, and serves the configured routes as an
+// http.Handler, with a native adapter for gin.
+package dynamiccontrol
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"dynamiccontrol/internal/configstore"
+	"dynamiccontrol/internal/logging"
+	"dynamiccontrol/internal/opa"
+	"dynamiccontrol/internal/router"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+)
+
+// Config is the route configuration, as read from routes.json
+type Config = types.RoutesConfig
+
+// Route is the configuration of a single route
+type Route = types.RouteConfig
+
+// MockResponse is the response of a mock route
+type MockResponse = types.MockResponseConfig
+
+// PolicyResult is the outcome of a policy evaluation
+type PolicyResult = types.PolicyResult
+
+// ValidationResult is the outcome of a schema validation
+type ValidationResult = types.ValidationResult
+
+// Builtin is a custom function policies can call, declared with
+// rego.Function and implemented with rego.BuiltinDyn
+type Builtin = opa.Builtin
+
+// Policies loads and evaluates the Rego policies routes enforce
+type Policies interface {
+	// RegisterBuiltin makes a custom function available to the policies
+	// loaded after it
+	RegisterBuiltin(builtin Builtin) error
+	// SetPolicy compiles and loads a policy, replacing one of the same name
+	SetPolicy(name, source string) error
+	// LoadPolicies loads the .rego files of a directory
+	LoadPolicies(dir string) error
+	// ListLoadedPolicies returns the names of the loaded policies
+	ListLoadedPolicies() []string
+	// EvaluatePolicy evaluates the allow rule of a policy
+	EvaluatePolicy(name string, input map[string]interface{}) (*PolicyResult, error)
+	// EvaluatePolicies allows the input only when every policy allows it
+	EvaluatePolicies(names []string, input map[string]interface{}) (*PolicyResult, error)
+}
+
+// Schemas validates documents against JSON schemas and stores the named
+// schemas routes reference
+type Schemas interface {
+	// RegisterSchema stores a version of a named schema
+	RegisterSchema(name, version string, schema map[string]interface{}) error
+	// ValidateRequest validates a request document against a schema
+	ValidateRequest(schema map[string]interface{}, data interface{}) *ValidationResult
+	// ValidateResponse validates a response document against a schema
+	ValidateResponse(schema map[string]interface{}, data interface{}) *ValidationResult
+}
+
+// Routes holds the active route table
+type Routes interface {
+	// ApplyConfig compiles a configuration and atomically replaces the
+	// active route table
+	ApplyConfig(config *Config) error
+	// GetConfig returns the active configuration
+	GetConfig() *Config
+	// OnApply registers a listener called with every applied configuration
+	OnApply(listener func(*Config))
+}
+
+// The internal managers implement the interfaces of this package
+var (
+	_ Policies = (*opa.PolicyManager)(nil)
+	_ Schemas  = (*validator.SchemaValidator)(nil)
+	_ Routes   = (*router.RouteManager)(nil)
+)
+
+// Engine serves a route configuration with its policies and schemas. It
+// starts with an empty route table; load or apply a configuration before
+// serving traffic.
+type Engine struct {
+	policies *opa.PolicyManager
+	schemas  *validator.SchemaValidator
+	routes   *router.RouteManager
+	handler  http.Handler
+}
+
+// New creates an engine with no policies and an empty route table. Call Stop
+// to release its background workers.
+func New() *Engine {
+	policies := opa.NewPolicyManager()
+	schemas := validator.NewSchemaValidator()
+	routes := router.NewRouteManager(policies, schemas)
+
+	e := &Engine{policies: policies, schemas: schemas, routes: routes, handler: logging.Handler(routes)}
+	// An empty table answers every request with 404 until a configuration
+	// is applied
+	routes.ApplyConfig(&Config{})
+	return e
+}
+
+// Policies returns the policies of the engine
+func (e *Engine) Policies() Policies {
+	return e.policies
+}
+
+// Schemas returns the schema validator of the engine
+func (e *Engine) Schemas() Schemas {
+	return e.schemas
+}
+
+// Routes returns the route table of the engine
+func (e *Engine) Routes() Routes {
+	return e.routes
+}
+
+// RegisterBuiltin makes a custom function available to policies. Register
+// built-ins before loading the policies that call them.
+func (e *Engine) RegisterBuiltin(builtin Builtin) error {
+	return e.policies.RegisterBuiltin(builtin)
+}
+
+// SetPolicy compiles and loads a policy
+func (e *Engine) SetPolicy(name, source string) error {
+	return e.policies.SetPolicy(name, source)
+}
+
+// ApplyConfig replaces the served route configuration. Load the policies its
+// routes enforce first.
+func (e *Engine) ApplyConfig(config *Config) error {
+	return e.routes.ApplyConfig(config)
+}
+
+// LoadFiles loads the policies of a directory and applies the route
+// configuration of a JSON or YAML file, like the standalone server's file
+// config store
+func (e *Engine) LoadFiles(ctx context.Context, routesFile, policiesDir string) error {
+	if err := e.routes.LoadFromStore(ctx, configstore.NewFileStore(routesFile, policiesDir)); err != nil {
+		return err
+	}
+	if err := e.routes.ApplyConfig(e.routes.GetConfig()); err != nil {
+		return fmt.Errorf("failed to apply configuration: %w", err)
+	}
+	return nil
+}
+
+// ServeHTTP serves the configured routes, with request IDs and access logs.
+// It is the framework-agnostic entry point of the engine: mount it on a
+// net/http ServeMux, or with the chiadapter package on a chi router.
+func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.handler.ServeHTTP(w, r)
+}
+
+// Stop releases the background workers of the engine
+func (e *Engine) Stop() {
+	e.routes.Stop()
+}
@@ -0,0 +1,118 @@
+package dynamiccontrol
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const allowGets = `package allow_gets
+
+import future.keywords.if
+
+default allow = false
+
+allow if input.method == "GET"
+`
+
+func greetingConfig() *Config {
+	return &Config{Routes: []Route{{
+		RouteName: "/v1/greeting",
+		Method:    "GET",
+		Policies:  []string{"allow_gets"},
+		MockResponse: &MockResponse{
+			StatusCode: http.StatusOK,
+			Template:   `{"message": "hello"}`,
+		},
+	}}}
+}
+
+func TestEngineServesAppliedConfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := New()
+	defer e.Stop()
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/greeting", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("Expected an empty engine to answer 404, got %d", recorder.Code)
+	}
+
+	if err := e.SetPolicy("allow_gets", allowGets); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if err := e.ApplyConfig(greetingConfig()); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	recorder = httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/greeting", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != `{"message":"hello"}` {
+		t.Errorf("Unexpected response %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder.Header().Get("X-Request-ID") == "" {
+		t.Error("Expected the engine to assign a request ID")
+	}
+}
+
+func TestEngineMountsIntoGinEngine(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := New()
+	defer e.Stop()
+	if err := e.SetPolicy("allow_gets", allowGets); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if err := e.ApplyConfig(greetingConfig()); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	host := gin.New()
+	host.GET("/healthz", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	e.Mount(host)
+
+	for path, expected := range map[string]int{
+		"/healthz":     http.StatusOK,
+		"/v1/greeting": http.StatusOK,
+		"/v1/missing":  http.StatusNotFound,
+	} {
+		recorder := httptest.NewRecorder()
+		host.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != expected {
+			t.Errorf("Expected %d for %s, got %d", expected, path, recorder.Code)
+		}
+	}
+}
+
+func TestEngineLoadsFiles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	policiesDir := filepath.Join(dir, "policies")
+	os.Mkdir(policiesDir, 0o755)
+	os.WriteFile(filepath.Join(policiesDir, "allow_gets.rego"), []byte(allowGets), 0o644)
+	routesFile := filepath.Join(dir, "routes.json")
+	os.WriteFile(routesFile, []byte(`{"routes": [{
+		"routeName": "/v1/greeting",
+		"method": "GET",
+		"policies": ["allow_gets"],
+		"mockResponse": {"statusCode": 200, "template": "{\"message\": \"hello\"}"}
+	}]}`), 0o644)
+
+	e := New()
+	defer e.Stop()
+	if err := e.LoadFiles(context.Background(), routesFile, policiesDir); err != nil {
+		t.Fatalf("Failed to load files: %v", err)
+	}
+	if policies := e.Policies().ListLoadedPolicies(); len(policies) != 1 {
+		t.Errorf("Expected one loaded policy, got %v", policies)
+	}
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/greeting", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected the loaded route to be served, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,21 @@
+package dynamiccontrol
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// GinHandler returns a gin handler serving the configured routes. Unlike
+// wrapping the engine with gin.WrapH, it serves the route table without the
+// engine's own request IDs and access logs, so those of the host router
+// apply and requests are not logged twice, and client addresses follow the
+// host's trusted proxies.
+func (e *Engine) GinHandler() gin.HandlerFunc {
+	return e.routes.Dispatch
+}
+
+// Mount serves the configured routes from an existing gin engine, for
+// requests that match none of its own routes. Its middleware runs before
+// them.
+func (e *Engine) Mount(engine *gin.Engine) {
+	engine.NoRoute(e.GinHandler())
+}
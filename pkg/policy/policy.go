@@ -0,0 +1,66 @@
+// Package policy describes the contract between the control plane and the
+// policies it evaluates: the input document every policy sees and the rules
+// the control plane queries. Policy authors and external decision points can
+// code against these types, or against the JSON Schemas generated from a
+// route configuration, which change only with Version.
+package policy
+
+// Version is the version of the policy contract. Changes that remove or
+// retype fields of the input or result documents bump it.
+const Version = "v1"
+
+// Rules queried in a policy package
+const (
+	// AllowRule decides whether a request may proceed and must be a boolean
+	AllowRule = "allow"
+	// TTLRule computes the cache TTL of a response, in seconds or as a
+	// duration string, for policies named by a route's cache.ttlPolicy
+	TTLRule = "ttl"
+)
+
+// Input is the input document of a policy evaluation
+type Input struct {
+	RequestID string `json:"requestId"`
+	Method    string `json:"method"`
+	// Path is the route pattern, such as /v1/services/:serviceId
+	Path string `json:"path"`
+	// RequestPath is the concrete path of the request
+	RequestPath string `json:"requestPath"`
+	// Headers, Params and Query hold the first value of each header, path
+	// parameter and query parameter. Header names are canonicalized.
+	Headers map[string]string `json:"headers"`
+	Params  map[string]string `json:"params"`
+	Query   map[string]string `json:"query"`
+	Client  Client            `json:"client"`
+	// Tenant is the tenant the request was resolved to, if any
+	Tenant string `json:"tenant,omitempty"`
+	// Claims holds identity attributes of authenticated callers
+	Claims map[string]interface{} `json:"claims,omitempty"`
+	// Body is the decoded JSON request body, when it is an object
+	Body map[string]interface{} `json:"body,omitempty"`
+	// Response is only set when evaluating TTLRule
+	Response *Response `json:"response,omitempty"`
+}
+
+// Client describes the caller of a request
+type Client struct {
+	IP        string `json:"ip"`
+	UserAgent string `json:"userAgent,omitempty"`
+}
+
+// Response is the upstream response a TTL rule computes the cache TTL of
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	// Body is the decoded JSON response body, when it is JSON
+	Body interface{} `json:"body,omitempty"`
+}
+
+// Result is the document of a policy package as seen by the control plane.
+// Allow is required; an undefined or non-boolean allow rule denies the
+// request.
+type Result struct {
+	Allow bool `json:"allow"`
+	// TTL is a number of seconds or a duration string such as "10m"
+	TTL interface{} `json:"ttl,omitempty"`
+}
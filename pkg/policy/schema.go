@@ -0,0 +1,179 @@
+package policy
+
+import (
+	"net/http"
+	"strings"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Schema is a JSON Schema document
+type Schema = map[string]interface{}
+
+// SchemaDialect is the JSON Schema draft of generated schemas
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema identifiers, versioned with the contract
+const (
+	InputSchemaID  = "urn:dynamiccontrol:policy:" + Version + ":input"
+	ResultSchemaID = "urn:dynamiccontrol:policy:" + Version + ":result"
+)
+
+// stringMap is an object of string values
+var stringMap = Schema{
+	"type":                 "object",
+	"additionalProperties": Schema{"type": "string"},
+}
+
+// responseSchema describes the upstream response seen by TTL rules
+var responseSchema = Schema{
+	"type":     "object",
+	"required": []interface{}{"status", "headers"},
+	"properties": Schema{
+		"status":  Schema{"type": "integer"},
+		"headers": stringMap,
+		"body":    Schema{},
+	},
+}
+
+// InputSchema describes the input document of any route
+func InputSchema() Schema {
+	return inputSchema(Schema{"type": "string"}, Schema{"type": "string"},
+		stringMap, stringMap, stringMap, Schema{"type": "object"}, responseSchema)
+}
+
+// RouteInputSchema describes the input document policies see for a route.
+// Path parameters are derived from the route pattern, and parameters declared
+// by the query and header schemas are listed as strings, because policies see
+// their raw first values. The request schema describes the body.
+func RouteInputSchema(route types.RouteConfig) Schema {
+	body := Schema{"type": "object"}
+	if route.RequestSchema != nil {
+		body = route.RequestSchema
+	}
+	// Only TTL policies see the response
+	response := Schema{"not": Schema{}}
+	if route.Cache != nil && route.Cache.TTLPolicy != "" {
+		response = responseSchema
+	}
+	schema := inputSchema(
+		Schema{"const": strings.ToUpper(route.Method)},
+		Schema{"const": route.RouteName},
+		parameterSchema(route.HeaderSchema, http.CanonicalHeaderKey),
+		paramsSchema(route.RouteName),
+		parameterSchema(route.QuerySchema, nil),
+		body,
+		response,
+	)
+	schema["title"] = "Policy input of " + strings.ToUpper(route.Method) + " " + route.RouteName
+	if route.Tenant != "" {
+		schema["properties"].(Schema)["tenant"] = Schema{"const": route.Tenant}
+		schema["required"] = append(schema["required"].([]interface{}), "tenant")
+	}
+	return schema
+}
+
+// inputSchema assembles the input document schema from its parts
+func inputSchema(method, path, headers, params, query, body, response Schema) Schema {
+	return Schema{
+		"$schema": SchemaDialect,
+		"$id":     InputSchemaID,
+		"title":   "Policy input",
+		"type":    "object",
+		"required": []interface{}{
+			"requestId", "method", "path", "requestPath", "headers", "params", "query", "client",
+		},
+		"properties": Schema{
+			"requestId":   Schema{"type": "string"},
+			"method":      method,
+			"path":        path,
+			"requestPath": Schema{"type": "string"},
+			"headers":     headers,
+			"params":      params,
+			"query":       query,
+			"client": Schema{
+				"type":     "object",
+				"required": []interface{}{"ip"},
+				"properties": Schema{
+					"ip":        Schema{"type": "string"},
+					"userAgent": Schema{"type": "string"},
+				},
+			},
+			"tenant":   Schema{"type": "string"},
+			"claims":   Schema{"type": "object"},
+			"body":     body,
+			"response": response,
+		},
+	}
+}
+
+// paramsSchema lists the path parameters of a route pattern
+func paramsSchema(pattern string) Schema {
+	properties := Schema{}
+	required := []interface{}{}
+	for _, segment := range strings.Split(pattern, "/") {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		name := segment[1:]
+		properties[name] = Schema{"type": "string"}
+		required = append(required, name)
+	}
+	return Schema{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// parameterSchema lists the parameters declared by a query or header schema
+// as strings, keeping which are required. rename maps schema names to the
+// names policies see.
+func parameterSchema(schema map[string]interface{}, rename func(string) string) Schema {
+	if rename == nil {
+		rename = func(name string) string { return name }
+	}
+	declared, _ := schema["properties"].(map[string]interface{})
+	properties := Schema{}
+	for name := range declared {
+		properties[rename(name)] = Schema{"type": "string"}
+	}
+	required := []interface{}{}
+	switch names := schema["required"].(type) {
+	case []interface{}:
+		for _, name := range names {
+			if s, ok := name.(string); ok {
+				required = append(required, rename(s))
+			}
+		}
+	case []string:
+		for _, name := range names {
+			required = append(required, rename(name))
+		}
+	}
+	return Schema{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": Schema{"type": "string"},
+	}
+}
+
+// ResultSchema describes the rules a policy package defines
+func ResultSchema() Schema {
+	return Schema{
+		"$schema":  SchemaDialect,
+		"$id":      ResultSchemaID,
+		"title":    "Policy result",
+		"type":     "object",
+		"required": []interface{}{AllowRule},
+		"properties": Schema{
+			AllowRule: Schema{"type": "boolean"},
+			TTLRule: Schema{"oneOf": []interface{}{
+				Schema{"type": "number", "minimum": 0},
+				Schema{"type": "string"},
+			}},
+		},
+	}
+}
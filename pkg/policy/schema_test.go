@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"dynamiccontrol/internal/reqctx"
+	"dynamiccontrol/internal/types"
+	"dynamiccontrol/internal/validator"
+)
+
+func TestRouteInputSchemaDescribesPolicyInput(t *testing.T) {
+	route := types.RouteConfig{
+		RouteName: "/v1/services/:serviceId/traffic",
+		Method:    "post",
+		RequestSchema: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"trafficType"},
+		},
+		HeaderSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"x-tenant-id": map[string]interface{}{"type": "string"}},
+			"required":   []interface{}{"x-tenant-id"},
+		},
+		QuerySchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"limit": map[string]interface{}{"type": "integer"}},
+		},
+	}
+	rc := &reqctx.RequestContext{
+		RequestID: "req-1",
+		Path:      "/v1/services/billing/traffic",
+		Client:    reqctx.ClientInfo{IP: "10.0.0.1"},
+		Headers:   map[string]string{"X-Tenant-Id": "acme"},
+		Params:    map[string]string{"serviceId": "billing"},
+		Query:     map[string]string{"limit": "5"},
+		Body:      map[string]interface{}{"trafficType": "canary"},
+	}
+	input := rc.PolicyInput("POST", route.RouteName)
+
+	sv := validator.NewSchemaValidator()
+	schema := RouteInputSchema(route)
+	if result := sv.ValidateRequest(schema, input); !result.Valid {
+		t.Fatalf("expected input to match its route schema, got %v", result.Errors)
+	}
+	if result := sv.ValidateRequest(InputSchema(), input); !result.Valid {
+		t.Fatalf("expected input to match the generic schema, got %v", result.Errors)
+	}
+
+	delete(rc.Headers, "X-Tenant-Id")
+	if result := sv.ValidateRequest(schema, rc.PolicyInput("POST", route.RouteName)); result.Valid {
+		t.Fatal("expected a missing required header to be rejected")
+	}
+	input["response"] = map[string]interface{}{"status": 200, "headers": map[string]interface{}{}}
+	if result := sv.ValidateRequest(schema, input); result.Valid {
+		t.Fatal("expected a response on a route without a TTL policy to be rejected")
+	}
+
+	// The Go types decode every field of the input
+	encoded, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("failed to encode input: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	var decoded Input
+	if err := decoder.Decode(&decoded); err != nil {
+		t.Fatalf("failed to decode input: %v", err)
+	}
+	if decoded.Params["serviceId"] != "billing" || decoded.Client.IP != "10.0.0.1" || decoded.Response.Status != 200 {
+		t.Fatalf("unexpected decoded input %+v", decoded)
+	}
+}
+
+func TestResultSchema(t *testing.T) {
+	sv := validator.NewSchemaValidator()
+	tests := []struct {
+		result interface{}
+		valid  bool
+	}{
+		{Result{Allow: true}, true},
+		{Result{Allow: false, TTL: "10m"}, true},
+		{map[string]interface{}{"allow": true, "ttl": 30}, true},
+		{map[string]interface{}{"allow": "yes"}, false},
+		{map[string]interface{}{"ttl": 30}, false},
+	}
+	for _, test := range tests {
+		if result := sv.ValidateRequest(ResultSchema(), test.result); result.Valid != test.valid {
+			t.Errorf("expected valid=%v for %+v, got %v", test.valid, test.result, result.Errors)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package route provides a typed builder for registering routes from Go code.
+// Routes built here produce the same RouteConfig as the JSON configuration.
+package route
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+// Config is the route representation shared with the JSON configuration
+type Config = types.RouteConfig
+
+// Schema is a JSON schema document
+type Schema = map[string]interface{}
+
+// Schemas holds named schemas referenced by RequestSchemaRef and ResponseSchemaRef
+type Schemas map[string]Schema
+
+// Method is an HTTP method supported by dynamic routes
+type Method string
+
+// Supported HTTP methods
+const (
+	GET    Method = "GET"
+	POST   Method = "POST"
+	PUT    Method = "PUT"
+	DELETE Method = "DELETE"
+)
+
+// Builder assembles a route configuration. Methods record the first error
+// they encounter, which is returned by Build.
+type Builder struct {
+	config      Config
+	requestRef  string
+	responseRef string
+	err         error
+}
+
+// NewRoute starts building a route for the given path pattern
+func NewRoute(path string) *Builder {
+	b := &Builder{config: Config{RouteName: path, Method: string(GET)}}
+	if !strings.HasPrefix(path, "/") {
+		b.fail(fmt.Errorf("route path %q must start with /", path))
+	}
+	return b
+}
+
+// Method sets the HTTP method of the route
+func (b *Builder) Method(method Method) *Builder {
+	b.config.Method = string(method)
+	return b
+}
+
+// RequestSchema sets the request body schema
+func (b *Builder) RequestSchema(schema Schema) *Builder {
+	b.config.RequestSchema = schema
+	return b
+}
+
+// RequestSchemaRef uses a named schema, resolved at build time, for the request body
+func (b *Builder) RequestSchemaRef(name string) *Builder {
+	b.requestRef = name
+	return b
+}
+
+// ResponseSchema sets the response body schema
+func (b *Builder) ResponseSchema(schema Schema) *Builder {
+	b.config.ResponseSchema = schema
+	return b
+}
+
+// ResponseSchemaRef uses a named schema, resolved at build time, for the response body
+func (b *Builder) ResponseSchemaRef(name string) *Builder {
+	b.responseRef = name
+	return b
+}
+
+// Policies sets the OPA policies evaluated for every request
+func (b *Builder) Policies(names ...string) *Builder {
+	b.config.Policies = append([]string(nil), names...)
+	return b
+}
+
+// ShadowPolicies adds OPA policies that are evaluated and logged for every
+// request but never deny it, to observe new policies before enforcing them
+func (b *Builder) ShadowPolicies(names ...string) *Builder {
+	if b.config.PolicySettings == nil {
+		b.config.PolicySettings = make(map[string]types.PolicySettings)
+	}
+	for _, name := range names {
+		b.config.Policies = append(b.config.Policies, name)
+		b.config.PolicySettings[name] = types.PolicySettings{Mode: types.PolicyModeShadow}
+	}
+	return b
+}
+
+// Canonicalize normalizes request bodies against the request schema
+func (b *Builder) Canonicalize(config types.CanonicalizeConfig) *Builder {
+	b.config.Canonicalize = &config
+	return b
+}
+
+// Mock serves a templated mock response
+func (b *Builder) Mock(statusCode int, template string) *Builder {
+	b.setHandler(types.HandlerMock)
+	b.config.Handler = ""
+	b.config.MockResponse = &types.MockResponseConfig{StatusCode: statusCode, Template: template}
+	return b
+}
+
+// Proxy forwards requests to a single upstream
+func (b *Builder) Proxy(upstreamURL string) *Builder {
+	return b.Upstream(upstreamURL, upstreamURL, 1)
+}
+
+// Upstream adds a weighted upstream target and makes the route a proxy route
+func (b *Builder) Upstream(name, upstreamURL string, weight int) *Builder {
+	b.setHandler(types.HandlerProxy)
+	if parsed, err := url.Parse(upstreamURL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		b.fail(fmt.Errorf("invalid upstream URL %q", upstreamURL))
+	}
+	b.config.Upstreams = append(b.config.Upstreams, types.UpstreamTarget{Name: name, URL: upstreamURL, Weight: weight})
+	return b
+}
+
+// Aggregate assembles the response from upstream calls using a mapping template
+func (b *Builder) Aggregate(mapping map[string]interface{}, calls ...types.UpstreamCall) *Builder {
+	b.setHandler(types.HandlerAggregate)
+	b.config.Aggregate = &types.AggregateConfig{Calls: calls, Mapping: mapping}
+	return b
+}
+
+// Traffic serves the traffic rules of the service named by the :serviceId
+// path parameter
+func (b *Builder) Traffic() *Builder {
+	b.setHandler(types.HandlerTraffic)
+	return b
+}
+
+// Retry retries failed proxied requests with exponential backoff
+func (b *Builder) Retry(maxAttempts int, backoff time.Duration) *Builder {
+	b.config.Retry = &types.RetryConfig{MaxAttempts: maxAttempts, BackoffMs: int(backoff / time.Millisecond)}
+	return b
+}
+
+// Timeout bounds the time spent waiting for upstreams
+func (b *Builder) Timeout(timeout time.Duration) *Builder {
+	b.config.TimeoutMs = int(timeout / time.Millisecond)
+	return b
+}
+
+// Notify delivers route lifecycle events to a webhook
+func (b *Builder) Notify(webhookURL string) *Builder {
+	b.config.Notifications = &types.NotificationConfig{WebhookURL: webhookURL}
+	return b
+}
+
+// Build resolves schema references and returns the route configuration
+func (b *Builder) Build(schemas Schemas) (Config, error) {
+	if b.err != nil {
+		return Config{}, b.err
+	}
+
+	config := b.config
+	if b.requestRef != "" {
+		schema, exists := schemas[b.requestRef]
+		if !exists {
+			return Config{}, fmt.Errorf("route %s %s references unknown request schema %q", config.Method, config.RouteName, b.requestRef)
+		}
+		config.RequestSchema = schema
+	}
+	if b.responseRef != "" {
+		schema, exists := schemas[b.responseRef]
+		if !exists {
+			return Config{}, fmt.Errorf("route %s %s references unknown response schema %q", config.Method, config.RouteName, b.responseRef)
+		}
+		config.ResponseSchema = schema
+	}
+	return config, nil
+}
+
+// NewConfig builds a complete route configuration from builders
+func NewConfig(schemas Schemas, builders ...*Builder) (*types.RoutesConfig, error) {
+	config := &types.RoutesConfig{}
+	for _, builder := range builders {
+		route, err := builder.Build(schemas)
+		if err != nil {
+			return nil, err
+		}
+		config.Routes = append(config.Routes, route)
+	}
+	return config, nil
+}
+
+// setHandler records the handler type, rejecting routes that mix handler types
+func (b *Builder) setHandler(handler string) {
+	current := b.config.Handler
+	if current == "" && b.config.MockResponse != nil {
+		current = types.HandlerMock
+	}
+	if current != "" && current != handler {
+		b.fail(fmt.Errorf("route %s cannot be both %s and %s", b.config.RouteName, current, handler))
+		return
+	}
+	b.config.Handler = handler
+}
+
+// fail records the first builder error
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
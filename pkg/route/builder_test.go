@@ -0,0 +1,163 @@
+package route
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"dynamiccontrol/internal/types"
+)
+
+func TestBuilderMatchesJSONConfig(t *testing.T) {
+	schemas := Schemas{
+		"Traffic": {"type": "object", "required": []interface{}{"action"}},
+	}
+
+	built, err := NewRoute("/v1/services/:serviceId/traffic").
+		Method(POST).
+		RequestSchemaRef("Traffic").
+		Policies("traffic_policy", "service_policy").
+		Upstream("primary", "http://primary:8080", 90).
+		Upstream("canary", "http://canary:8080", 10).
+		Retry(3, 100*time.Millisecond).
+		Timeout(2 * time.Second).
+		Build(schemas)
+	if err != nil {
+		t.Fatalf("Failed to build route: %v", err)
+	}
+
+	var fromJSON types.RouteConfig
+	err = json.Unmarshal([]byte(`{
+		"routeName": "/v1/services/:serviceId/traffic",
+		"method": "POST",
+		"requestSchema": {"type": "object", "required": ["action"]},
+		"responseSchema": null,
+		"policies": ["traffic_policy", "service_policy"],
+		"handler": "proxy",
+		"upstreams": [
+			{"name": "primary", "url": "http://primary:8080", "weight": 90},
+			{"name": "canary", "url": "http://canary:8080", "weight": 10}
+		],
+		"retry": {"maxAttempts": 3, "backoffMs": 100},
+		"timeoutMs": 2000
+	}`), &fromJSON)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON route: %v", err)
+	}
+
+	if !reflect.DeepEqual(built, fromJSON) {
+		t.Errorf("Built route differs from JSON config:\n%+v\n%+v", built, fromJSON)
+	}
+}
+
+func TestBuilderAggregateMatchesJSONConfig(t *testing.T) {
+	schemas := Schemas{
+		"Profile": {"type": "object", "required": []interface{}{"id", "name"}},
+	}
+
+	built, err := NewRoute("/v1/profiles/:id").
+		RequestSchema(Schema{"type": "object"}).
+		ResponseSchemaRef("Profile").
+		Policies("profile_policy").
+		ShadowPolicies("audit_policy", "tenant_policy").
+		Canonicalize(types.CanonicalizeConfig{ApplyDefaults: true, TrimUnknownFields: true}).
+		Aggregate(
+			map[string]interface{}{"id": "$.request.params.id", "name": "$.upstreams.user.body.name"},
+			types.UpstreamCall{Name: "user", Method: "GET", URL: "http://users:8080/users/{id}", TimeoutMs: 500},
+			types.UpstreamCall{Name: "orders", Method: "GET", URL: "http://orders:8080/orders", Optional: true},
+		).
+		Notify("https://hooks.example.com/routes").
+		Build(schemas)
+	if err != nil {
+		t.Fatalf("Failed to build route: %v", err)
+	}
+
+	var fromJSON types.RouteConfig
+	err = json.Unmarshal([]byte(`{
+		"routeName": "/v1/profiles/:id",
+		"method": "GET",
+		"requestSchema": {"type": "object"},
+		"responseSchema": {"type": "object", "required": ["id", "name"]},
+		"policies": ["profile_policy", "audit_policy", "tenant_policy"],
+		"policySettings": {"audit_policy": {"mode": "shadow"}, "tenant_policy": {"mode": "shadow"}},
+		"canonicalize": {"applyDefaults": true, "coerceTypes": false, "trimUnknownFields": true},
+		"handler": "aggregate",
+		"aggregate": {
+			"calls": [
+				{"name": "user", "method": "GET", "url": "http://users:8080/users/{id}", "timeoutMs": 500},
+				{"name": "orders", "method": "GET", "url": "http://orders:8080/orders", "optional": true}
+			],
+			"mapping": {"id": "$.request.params.id", "name": "$.upstreams.user.body.name"}
+		},
+		"notifications": {"webhookUrl": "https://hooks.example.com/routes"}
+	}`), &fromJSON)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON route: %v", err)
+	}
+
+	if !reflect.DeepEqual(built, fromJSON) {
+		t.Errorf("Built route differs from JSON config:\n%+v\n%+v", built, fromJSON)
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	schemas := Schemas{"Status": {"type": "object"}}
+	status := NewRoute("/v1/status").Mock(200, `{"status": "ok"}`).ResponseSchemaRef("Status")
+	traffic := NewRoute("/v1/services/:serviceId/traffic").Method(PUT).ResponseSchema(Schema{"type": "array"}).Traffic()
+
+	config, err := NewConfig(schemas, status, traffic)
+	if err != nil {
+		t.Fatalf("Failed to build config: %v", err)
+	}
+	expected := &types.RoutesConfig{Routes: []types.RouteConfig{
+		{
+			RouteName:      "/v1/status",
+			Method:         "GET",
+			ResponseSchema: map[string]interface{}{"type": "object"},
+			MockResponse:   &types.MockResponseConfig{StatusCode: 200, Template: `{"status": "ok"}`},
+		},
+		{
+			RouteName:      "/v1/services/:serviceId/traffic",
+			Method:         "PUT",
+			ResponseSchema: map[string]interface{}{"type": "array"},
+			Handler:        types.HandlerTraffic,
+		},
+	}}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Expected config %+v, got %+v", expected, config)
+	}
+
+	// The first builder error fails the whole configuration
+	if _, err := NewConfig(nil, traffic, status); err == nil || !strings.Contains(err.Error(), `unknown response schema "Status"`) {
+		t.Errorf("Expected the unresolved schema to fail the config, got %v", err)
+	}
+	if _, err := NewConfig(nil, NewRoute("/v1/x").Traffic().Proxy("http://upstream:8080")); err == nil || !strings.Contains(err.Error(), "cannot be both traffic and proxy") {
+		t.Errorf("Expected mixed handlers to fail the config, got %v", err)
+	}
+	if config, err := NewConfig(nil); err != nil || len(config.Routes) != 0 {
+		t.Errorf("Expected an empty config without builders, got %+v, %v", config, err)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		builder *Builder
+		error   string
+	}{
+		{"relative path", NewRoute("v1/status"), "must start with /"},
+		{"unknown schema", NewRoute("/v1/x").RequestSchemaRef("Missing"), "unknown request schema"},
+		{"unknown response schema", NewRoute("/v1/x").ResponseSchemaRef("Missing"), "unknown response schema"},
+		{"mixed handlers", NewRoute("/v1/x").Mock(200, `{}`).Proxy("http://upstream"), "cannot be both"},
+		{"invalid upstream", NewRoute("/v1/x").Proxy("not a url"), "invalid upstream URL"},
+	}
+
+	for _, tc := range cases {
+		_, err := tc.builder.Build(nil)
+		if err == nil || !strings.Contains(err.Error(), tc.error) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.error, err)
+		}
+	}
+}
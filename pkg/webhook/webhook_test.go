@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestVerifier(now time.Time) *Verifier {
+	verifier := NewVerifier("s3cret", time.Minute)
+	verifier.now = func() time.Time { return now }
+	return verifier
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	verifier := newTestVerifier(now)
+	payload := []byte(`{"type":"route.first_success"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := Sign([]byte("s3cret"), timestamp, payload)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		payload   []byte
+		want      error
+	}{
+		{"valid", timestamp, signature, payload, nil},
+		{"missing signature", timestamp, "", payload, ErrMissingSignature},
+		{"missing timestamp", "", signature, payload, ErrMissingSignature},
+		{"tampered payload", timestamp, signature, []byte(`{"type":"other"}`), ErrInvalidSignature},
+		{"wrong secret", timestamp, Sign([]byte("other"), timestamp, payload), payload, ErrInvalidSignature},
+		{"unsupported algorithm", timestamp, "sha1=" + strings.TrimPrefix(signature, "sha256="), payload, ErrInvalidSignature},
+		{"malformed timestamp", "yesterday", signature, payload, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifier.Verify(tt.timestamp, tt.signature, tt.payload); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsReplays(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	verifier := newTestVerifier(now)
+	payload := []byte(`{}`)
+
+	for _, offset := range []time.Duration{-2 * time.Minute, 2 * time.Minute} {
+		timestamp := strconv.FormatInt(now.Add(offset).Unix(), 10)
+		err := verifier.Verify(timestamp, Sign([]byte("s3cret"), timestamp, payload), payload)
+		if !errors.Is(err, ErrExpired) {
+			t.Errorf("Expected delivery signed at %s offset to expire, got %v", offset, err)
+		}
+	}
+
+	timestamp := strconv.FormatInt(now.Add(-30*time.Second).Unix(), 10)
+	if err := verifier.Verify(timestamp, Sign([]byte("s3cret"), timestamp, payload), payload); err != nil {
+		t.Errorf("Expected delivery within the window to verify, got %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	verifier := newTestVerifier(now)
+	handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+
+	payload := `{"id":"evt-1"}`
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(payload))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign([]byte("s3cret"), timestamp, []byte(payload)))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != payload {
+		t.Errorf("Expected verified delivery to reach the handler with its body, got %d %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(payload))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected unsigned delivery to be rejected with 401, got %d", w.Code)
+	}
+}
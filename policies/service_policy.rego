@@ -11,4 +11,15 @@ allow if {
     input.method == "POST"
     startswith(input.path, "/v1/services/")
     endswith(input.path, "/traffic")
-} 
\ No newline at end of file
+} 
+# Allow reading and clearing recorded traffic for a service
+allow if {
+    input.method in ["GET", "DELETE"]
+    startswith(input.path, "/v1/services/")
+    endswith(input.path, "/traffic")
+}
+# Allow reading and deleting a single traffic rule of a service
+allow if {
+    input.method in ["GET", "DELETE"]
+    regex.match(`^/v1/services/[^/]+/traffic/[^/]+$`, input.path)
+}